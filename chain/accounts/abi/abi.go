@@ -17,6 +17,7 @@
 package abi
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -126,6 +127,19 @@ func (abi ABI) InputUnpack(v []interface{}, name string, input []byte) (err erro
 	return errors.New("abi: could not locate named method")
 }
 
+//根据调用数据开头4个字节的方法ID查找方法
+func (abi *ABI) MethodById(sigdata []byte) (*Method, error) {
+	if len(sigdata) < 4 {
+		return nil, fmt.Errorf("abi: data of %d bytes too short to contain a method id", len(sigdata))
+	}
+	for _, method := range abi.Methods {
+		if bytes.Equal(method.Id(), sigdata[:4]) {
+			return &method, nil
+		}
+	}
+	return nil, fmt.Errorf("abi: no method with id %x", sigdata[:4])
+}
+
 func (abi *ABI) UnmarshalJSON(data []byte) error {
 	var fields []struct {
 		Type      string
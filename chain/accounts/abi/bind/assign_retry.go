@@ -0,0 +1,77 @@
+package bind
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
+)
+
+var (
+	assignRetryLimit  = 5                //分配通证交易最多尝试的次数
+	assignRetryDelay  = 2 * time.Second  //两次尝试之间的间隔
+	assignRetryWindow = 30 * time.Second //从第一次尝试开始允许重试的时间，必须小于分币节点的时间片
+
+	assignRetryCounter  = metrics.NewCounter("bind/assign/retry")
+	assignFailedCounter = metrics.NewCounter("bind/assign/failed")
+)
+
+var (
+	errTokenNoderLookup = errors.New("get assign token error")
+	errNotTokenNoder    = errors.New("current assign token not is from account")
+)
+
+//交易池因为Nonce冲突拒绝的交易，重新获取pending nonce之后可以重新提交
+func retriableAssignError(err error) bool {
+	if err == errTokenNoderLookup || err == errNotTokenNoder {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "nonce too low") || strings.Contains(msg, "replacement transaction underpriced")
+}
+
+//提交分配通证交易，Nonce过期或者暂时不是分币节点时，在时间窗口内重新获取pending nonce、
+//重新检查分币节点并重新提交
+func (c *BoundContract) assignTransactRetry(opts *TransactOpts, boker BokerBackend, payload []byte, extra []byte, now int64) (*types.Transaction, error) {
+
+	var (
+		deadline = time.Unix(now, 0).Add(assignRetryWindow)
+		ctx      = ensureContext(opts.Context)
+		err      error
+	)
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			log.Warn("Retrying token assignment", "from", opts.From, "attempt", attempt, "err", err)
+			assignRetryCounter.Inc(1)
+
+			select {
+			case <-time.After(assignRetryDelay):
+			case <-ctx.Done():
+				assignFailedCounter.Inc(1)
+				return nil, ctx.Err()
+			}
+			now = time.Now().Unix()
+
+			//指定的Nonce已经不可用，重新从交易池获取
+			retryOpts := *opts
+			retryOpts.Nonce = nil
+			opts = &retryOpts
+		}
+		if err = c.checkTokenNoder(opts, boker, now); err == nil {
+			var tx *types.Transaction
+			if tx, err = c.assginTransact(opts, &c.address, payload, extra, protocol.AssignToken, now); err == nil {
+				return tx, nil
+			}
+		}
+		if !retriableAssignError(err) || attempt+1 >= assignRetryLimit || time.Now().Add(assignRetryDelay).After(deadline) {
+			break
+		}
+	}
+	assignFailedCounter.Inc(1)
+	log.Error("Token assignment failed", "from", opts.From, "err", err)
+	return nil, err
+}
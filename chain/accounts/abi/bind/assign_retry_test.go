@@ -0,0 +1,125 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain"
+	"github.com/Bokerchain/Boker/chain/accounts/abi"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+)
+
+//模拟交易池和分币节点查询，按顺序返回预设的结果
+type assignTestBackend struct {
+	noders []common.Address //每次查询分币节点返回的账号，用完后返回最后一个
+	errors []error          //每次提交交易返回的错误，用完后提交成功
+	nonce  uint64
+	nonces []uint64 //每次提交的交易的Nonce
+}
+
+func (b *assignTestBackend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (b *assignTestBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return b.nonce, nil
+}
+
+func (b *assignTestBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (b *assignTestBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (*big.Int, error) {
+	return big.NewInt(21000), nil
+}
+
+func (b *assignTestBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.nonces = append(b.nonces, tx.Nonce())
+	if len(b.errors) > 0 {
+		err := b.errors[0]
+		b.errors = b.errors[1:]
+		b.nonce++
+		return err
+	}
+	return nil
+}
+
+func (b *assignTestBackend) GetContractType(address common.Address) (protocol.ContractType, error) {
+	return protocol.SystemContract, nil
+}
+
+func (b *assignTestBackend) CurrentTokenNoder(now int64) (common.Address, error) {
+	noder := b.noders[0]
+	if len(b.noders) > 1 {
+		b.noders = b.noders[1:]
+	}
+	return noder, nil
+}
+
+func (b *assignTestBackend) CurrentProducer(now int64) (common.Address, error) {
+	return common.Address{}, nil
+}
+
+func newAssignTestContract(t *testing.T, backend *assignTestBackend) (*BoundContract, *TransactOpts) {
+	parsed, err := abi.JSON(strings.NewReader(`[{"constant":false,"inputs":[],"name":"assignToken","outputs":[],"type":"function"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := crypto.GenerateKey()
+	opts := NewKeyedTransactor(key)
+	return NewBoundContract(common.Address{1}, parsed, nil, backend), opts
+}
+
+//缩短重试间隔，返回恢复原设置的函数
+func setAssignRetryTiming(delay, window time.Duration) func() {
+	oldDelay, oldWindow := assignRetryDelay, assignRetryWindow
+	assignRetryDelay, assignRetryWindow = delay, window
+	return func() { assignRetryDelay, assignRetryWindow = oldDelay, oldWindow }
+}
+
+//测试Nonce过期和暂时失去分币时间片之后重新获取Nonce并重新提交
+func TestAssignTransactRetry(t *testing.T) {
+	defer setAssignRetryTiming(time.Millisecond, time.Minute)()
+
+	backend := &assignTestBackend{errors: []error{errors.New("nonce too low")}}
+	contract, opts := newAssignTestContract(t, backend)
+	backend.noders = []common.Address{{9}, opts.From}
+	opts.Nonce = big.NewInt(0)
+
+	tx, err := contract.TryTransact(opts, time.Now().Unix(), protocol.AssignTokenMethod)
+	if err != nil {
+		t.Fatalf("assignment failed: %v", err)
+	}
+	if tx.Type() != protocol.AssignToken || tx.Nonce() != 1 {
+		t.Errorf("transaction mismatch: type %v, nonce %d", tx.Type(), tx.Nonce())
+	}
+	if len(backend.nonces) != 2 || backend.nonces[0] != 0 || backend.nonces[1] != 1 {
+		t.Errorf("submitted nonces mismatch: have %v, want [0 1]", backend.nonces)
+	}
+}
+
+//测试不可重试的错误以及超出尝试次数时返回错误
+func TestAssignTransactGiveUp(t *testing.T) {
+	defer setAssignRetryTiming(time.Millisecond, time.Minute)()
+
+	backend := &assignTestBackend{errors: []error{errors.New("insufficient funds")}}
+	contract, opts := newAssignTestContract(t, backend)
+	backend.noders = []common.Address{opts.From}
+
+	if _, err := contract.TryTransact(opts, time.Now().Unix(), protocol.AssignTokenMethod); err == nil || len(backend.nonces) != 1 {
+		t.Errorf("non-retriable error: have err %v after %d submissions", err, len(backend.nonces))
+	}
+	backend = &assignTestBackend{noders: []common.Address{{9}}}
+	contract, opts = newAssignTestContract(t, backend)
+
+	if _, err := contract.TryTransact(opts, time.Now().Unix(), protocol.AssignTokenMethod); err != errNotTokenNoder || len(backend.nonces) != 0 {
+		t.Errorf("lost slot: have err %v after %d submissions", err, len(backend.nonces))
+	}
+}
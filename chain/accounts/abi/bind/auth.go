@@ -79,7 +79,7 @@ func NewPasswordTransactor(ethereum *eth.Ethereum, addr common.Address) *Transac
 			}
 
 			var chainID *big.Int
-			if config := ethereum.ApiBackend.ChainConfig(); config.IsEIP155(ethereum.ApiBackend.CurrentBlock().Number()) {
+			if config := ethereum.ApiBackend.ChainConfig(); config.IsReplayProtected(ethereum.ApiBackend.CurrentBlock().Number()) {
 				chainID = config.ChainId
 			}
 
@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/Bokerchain/Boker/chain"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/types"
 )
@@ -39,6 +40,10 @@ var (
 	// This error is returned by WaitDeployed if contract creation leaves an
 	// empty contract behind.
 	ErrNoCodeAfterDeploy = errors.New("no contract code after deployment")
+
+	// ErrNoBokerBackend is returned when a base contract transaction is requested
+	// but neither the transactor nor the TransactOpts provide a BokerBackend.
+	ErrNoBokerBackend = errors.New("backend does not support boker base contracts")
 )
 
 // ContractCaller defines the methods needed to allow operating with contract on a read
@@ -68,6 +73,16 @@ type PendingContractCaller interface {
 	PendingCallContract(ctx context.Context, call ethereum.CallMsg) ([]byte, error)
 }
 
+// BatchContractCaller defines methods to execute several contract calls against
+// the same state in a single round trip. CallBatch will try to discover this
+// interface and falls back to consecutive calls if the backend lacks it.
+type BatchContractCaller interface {
+	// CallContractBatch executes the calls against the state of the given block,
+	// returning the output or the error of every call. The returned error is only
+	// set if the batch as a whole could not be executed.
+	CallContractBatch(ctx context.Context, calls []ethereum.CallMsg, blockNumber *big.Int) ([][]byte, []error, error)
+}
+
 // ContractTransactor defines the methods needed to allow operating with contract
 // on a write only basis. Beside the transacting method, the remainder are helpers
 // used when the user does not provide some needed values, but rather leaves it up
@@ -86,6 +101,30 @@ type ContractTransactor interface {
 	SendTransaction(ctx context.Context, tx *types.Transaction) error
 }
 
+// ChainIDReader is implemented by transactors that know the chain ID transactions
+// should be bound to. Transact will try to discover this interface and sign with
+// EIP155 replay protection when a chain ID is returned; a nil chain ID (or a
+// transactor not implementing the interface) selects legacy homestead signing.
+type ChainIDReader interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// BokerBackend defines the Boker chain specific lookups needed to route a bound
+// contract transaction to the correct base contract transaction type. The bound
+// contract will try to discover this interface on its transactor; it can also be
+// supplied explicitly through TransactOpts.
+type BokerBackend interface {
+	// GetContractType returns the base contract type registered for an address,
+	// or protocol.BinaryContract for ordinary contracts.
+	GetContractType(address common.Address) (protocol.ContractType, error)
+	// CurrentTokenNoder returns the account scheduled to assign tokens at the
+	// given unix time.
+	CurrentTokenNoder(now int64) (common.Address, error)
+	// CurrentProducer returns the account scheduled to produce blocks at the
+	// given unix time.
+	CurrentProducer(now int64) (common.Address, error)
+}
+
 // ContractBackend defines the methods needed to work with contracts on a read-write basis.
 type ContractBackend interface {
 	ContractCaller
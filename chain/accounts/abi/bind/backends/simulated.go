@@ -14,6 +14,8 @@ import (
 	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/math"
+	"github.com/Bokerchain/Boker/chain/consensus"
+	"github.com/Bokerchain/Boker/chain/consensus/dpos"
 	"github.com/Bokerchain/Boker/chain/consensus/ethash"
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/core/state"
@@ -41,7 +43,7 @@ type SimulatedBackend struct {
 	boker        bokerapi.Api        //播客链接口
 }
 
-//创建一个新的用于进行测试的后台模拟链
+//创建一个新的用于进行测试的后台模拟链，boker可以为nil（此时只能执行普通交易）
 func NewSimulatedBackend(alloc core.GenesisAlloc, boker bokerapi.Api) *SimulatedBackend {
 
 	database, _ := ethdb.NewMemDatabase()
@@ -51,7 +53,7 @@ func NewSimulatedBackend(alloc core.GenesisAlloc, boker bokerapi.Api) *Simulated
 	}
 	genesis.MustCommit(database)
 
-	blockchain, _ := core.NewBlockChain(database, genesis.Config, ethash.NewFaker(), vm.Config{})
+	blockchain, _ := core.NewBlockChain(database, genesis.Config, simulatedEngine{ethash.NewFullFaker()}, vm.Config{})
 	blockchain.SetBoker(boker)
 	backend := &SimulatedBackend{
 		database:   database,
@@ -85,7 +87,9 @@ func (b *SimulatedBackend) Rollback() {
 
 func (b *SimulatedBackend) rollback() {
 
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), b.database, 1, b.boker, func(int, *core.BlockGen) {})
+	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), b.database, 1, b.boker, func(number int, block *core.BlockGen) {
+		block.SetBlockChain(b.blockchain)
+	})
 	b.pendingBlock = blocks[0]
 	b.pendingState, _ = state.New(b.pendingBlock.Root(), state.NewDatabase(b.database))
 }
@@ -189,6 +193,18 @@ func (b *SimulatedBackend) PendingNonceAt(ctx context.Context, account common.Ad
 	return b.pendingState.GetOrNewStateObject(account).Nonce(), nil
 }
 
+// ChainID implements bind.ChainIDReader, returning the chain ID transactions are
+// replay protected with, or nil if the chain uses legacy signing.
+func (b *SimulatedBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.config.IsReplayProtected(b.pendingBlock.Number()) {
+		return nil, nil
+	}
+	return new(big.Int).Set(b.config.ChainId), nil
+}
+
 // SuggestGasPrice implements ContractTransactor.SuggestGasPrice. Since the simulated
 // chain doens't have miners, we just return a gas price of 1 for any call.
 func (b *SimulatedBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
@@ -202,6 +218,15 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	//基础合约交易不经过EVM计费，直接返回交易类型固定消耗的Gas
+	if call.TxType != protocol.Binary {
+		gas, ok := core.FixedGas(call.TxType)
+		if !ok {
+			return nil, protocol.ErrInvalidType
+		}
+		return gas, nil
+	}
+
 	// Determine the lowest and highest possible gas limits to binary search in between
 	var (
 		lo  uint64 = params.TxGas - 1
@@ -271,8 +296,8 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallM
 	vmenv := vm.NewEVM(evmContext, statedb, b.config, vm.Config{})
 	gaspool := new(core.GasPool).AddGas(math.MaxBig256)
 
-	//创建这个交易的状态对象
-	ret, gasUsed, _, failed, _, err := core.NewStateTransition(vmenv, msg, gaspool).TransitionDb(b.blockchain.Boker())
+	//使用与出块节点相同的普通交易执行路径
+	ret, _, gasUsed, failed, err := core.BinaryMessage(vmenv, msg, gaspool, b.boker)
 	log.Info("callContract", "ret", ret, "gasUsed", gasUsed)
 
 	return ret, gasUsed, failed, err
@@ -283,7 +308,7 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	sender, err := types.Sender(types.HomesteadSigner{}, tx)
+	sender, err := types.Sender(types.MakeSigner(b.config, b.pendingBlock.Number()), tx)
 	if err != nil {
 		panic(fmt.Errorf("invalid transaction: %v", err))
 	}
@@ -293,8 +318,9 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
 	}
 
-	//
+	//将挂起的交易和新交易重新打包到挂起区块中（基础合约交易按类型分发执行）
 	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), b.database, 1, b.boker, func(number int, block *core.BlockGen) {
+		block.SetBlockChain(b.blockchain)
 		for _, tx := range b.pendingBlock.Transactions() {
 			block.AddTx(tx, b.boker)
 		}
@@ -314,6 +340,7 @@ func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 
 	//获取当前区块
 	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), b.database, 1, b.boker, func(number int, block *core.BlockGen) {
+		block.SetBlockChain(b.blockchain)
 		for _, tx := range b.pendingBlock.Transactions() {
 			block.AddTx(tx, b.boker)
 		}
@@ -325,6 +352,26 @@ func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 	return nil
 }
 
+//simulatedEngine跳过工作量证明的校验，并按照Dpos的规则计算出块报酬，
+//使模拟链导入的区块与core.GenerateChain生成的区块状态一致
+type simulatedEngine struct {
+	*ethash.Ethash
+}
+
+func (e simulatedEngine) Finalize(chain consensus.ChainReader,
+	header *types.Header,
+	state *state.StateDB,
+	txs []*types.Transaction,
+	uncles []*types.Header,
+	receipts []*types.Receipt,
+	ctx *types.DposContext,
+	boker bokerapi.Api) (*types.Block, error) {
+
+	dpos.AccumulateRewards(chain.Config(), state, header, uncles, boker)
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+	return types.NewBlock(header, txs, uncles, receipts), nil
+}
+
 // callmsg implements core.Message to allow passing it as a transaction simulator.
 type callmsg struct {
 	ethereum.CallMsg
@@ -339,4 +386,4 @@ func (m callmsg) Gas() *big.Int           { return m.CallMsg.Gas }
 func (m callmsg) Value() *big.Int         { return m.CallMsg.Value }
 func (m callmsg) Data() []byte            { return m.CallMsg.Data }
 func (m callmsg) Extra() []byte           { return m.CallMsg.Extra }
-func (m callmsg) TxType() protocol.TxType { return protocol.Binary }
+func (m callmsg) TxType() protocol.TxType { return m.CallMsg.TxType }
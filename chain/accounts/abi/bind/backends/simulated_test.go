@@ -0,0 +1,71 @@
+package backends
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+)
+
+var testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+// Tests that gas-free base transactions are dispatched by type and mined by the
+// simulated backend, with the Boker metadata recorded in their receipts.
+func TestSimulatedBaseTransaction(t *testing.T) {
+	from := crypto.PubkeyToAddress(testKey.PublicKey)
+	backend := NewSimulatedBackend(core.GenesisAlloc{from: {Balance: big.NewInt(10000000000)}}, nil)
+
+	ctx := context.Background()
+	nonce, err := backend.PendingNonceAt(ctx, from)
+	if err != nil {
+		t.Fatalf("failed to retrieve pending nonce: %v", err)
+	}
+	tx := types.NewBaseTransaction(protocol.RegisterCandidate, nonce, common.HexToAddress("0x01"), big.NewInt(0), nil)
+	tx, err = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if err := backend.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	backend.Commit()
+
+	receipt, err := backend.TransactionReceipt(ctx, tx.Hash())
+	if err != nil || receipt == nil {
+		t.Fatalf("receipt not found: %v", err)
+	}
+	if receipt.TxType != protocol.RegisterCandidate {
+		t.Errorf("receipt tx type mismatch: have %d, want %d", receipt.TxType, protocol.RegisterCandidate)
+	}
+	if receipt.GasUsed.Sign() != 0 {
+		t.Errorf("base transaction used gas: %v", receipt.GasUsed)
+	}
+	if nonce, _ := backend.NonceAt(ctx, from, nil); nonce != 1 {
+		t.Errorf("sender nonce mismatch: have %d, want 1", nonce)
+	}
+}
+
+// Tests that gas estimation of base transactions returns their fixed cost
+// instead of running the binary search, and rejects unknown types.
+func TestSimulatedEstimateBaseGas(t *testing.T) {
+	from := crypto.PubkeyToAddress(testKey.PublicKey)
+	backend := NewSimulatedBackend(core.GenesisAlloc{from: {Balance: big.NewInt(10000000000)}}, nil)
+
+	to := common.HexToAddress("0x01")
+	gas, err := backend.EstimateGas(context.Background(), ethereum.CallMsg{From: from, To: &to, TxType: protocol.VoteUser})
+	if err != nil {
+		t.Fatalf("failed to estimate gas: %v", err)
+	}
+	if gas.Sign() != 0 {
+		t.Errorf("base transaction gas mismatch: have %v, want 0", gas)
+	}
+	if _, err := backend.EstimateGas(context.Background(), ethereum.CallMsg{From: from, To: &to, TxType: protocol.VoteCancel}); err != protocol.ErrInvalidType {
+		t.Errorf("unknown transaction type error mismatch: have %v, want %v", err, protocol.ErrInvalidType)
+	}
+}
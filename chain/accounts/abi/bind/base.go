@@ -14,9 +14,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/crypto"
-	"github.com/Bokerchain/Boker/chain/eth"
 	"github.com/Bokerchain/Boker/chain/log"
-	"github.com/Bokerchain/Boker/chain/node"
 )
 
 // SignerFn is a signer function callback when a contract requires a method to
@@ -39,6 +37,7 @@ type TransactOpts struct {
 	GasPrice *big.Int        // Gas price to use for the transaction execution (nil = gas price oracle)
 	GasLimit *big.Int        // Gas limit to set for the transaction execution (nil = estimate + 10%)
 	Context  context.Context // Network context to support cancellation and timeouts (nil = no timeout)
+	Boker    BokerBackend    // Boker chain lookups for base contracts (nil = discovered from the transactor)
 }
 
 //BoundContract定义以太坊合约的基础包装器对象 它包含一组由方法使用的方法更高级别的合同绑定操作。
@@ -47,20 +46,22 @@ type BoundContract struct {
 	abi        abi.ABI            // Reflect based ABI to access the correct Ethereum methods
 	caller     ContractCaller     // Read interface to interact with the blockchain
 	transactor ContractTransactor // Write interface to interact with the blockchain
+	boker      BokerBackend       // Boker chain lookups, if the transactor provides them
 }
 
-var GethNode *node.Node
-
 //NewBoundContract 创建一个通过其调用的低级合约接口并且交易可以通过。
+//如果transactor同时实现了BokerBackend，则基础合约的交易会按照合约类型进行分发。
 func NewBoundContract(address common.Address,
 	abi abi.ABI,
 	caller ContractCaller,
 	transactor ContractTransactor) *BoundContract {
+	boker, _ := transactor.(BokerBackend)
 	return &BoundContract{
 		address:    address,
 		abi:        abi,
 		caller:     caller,
 		transactor: transactor,
+		boker:      boker,
 	}
 }
 
@@ -143,52 +144,38 @@ func (c *BoundContract) Call(opts *CallOpts, result interface{}, method string,
 	return c.abi.Unpack(result, method, output)
 }
 
-//得到当前分币帐号
-func (c *BoundContract) getTokenNoder(opts *TransactOpts) (common.Address, error) {
-
-	var ether *eth.Ethereum
-	if err := GethNode.Service(&ether); err != nil {
-		return common.Address{}, err
-	}
+//得到交易使用的播客链接口，TransactOpts中指定的优先
+func (c *BoundContract) bokerBackend(opts *TransactOpts) BokerBackend {
 
-	if ether.BlockChain().CurrentBlock() == nil {
-		return common.Address{}, errors.New("failed to lookup token node")
+	if opts != nil && opts.Boker != nil {
+		return opts.Boker
 	}
-
-	firstTimer := ether.BlockChain().GetBlockByNumber(0).Time().Int64()
-	return ether.BlockChain().CurrentBlock().DposCtx().GetCurrentTokenNoder(firstTimer)
+	return c.boker
 }
 
-//得到当前分币帐号
-func (c *BoundContract) getNowTokenNoder(opts *TransactOpts, now int64) (common.Address, error) {
+//根据transactor提供的链ID选择交易签名者（没有链ID时使用Homestead签名）
+func (c *BoundContract) signer(opts *TransactOpts) types.Signer {
 
-	var ether *eth.Ethereum
-	if err := GethNode.Service(&ether); err != nil {
-		return common.Address{}, err
-	}
-
-	if ether.BlockChain().CurrentBlock() == nil {
-		return common.Address{}, errors.New("failed to lookup token node")
+	if reader, ok := c.transactor.(ChainIDReader); ok {
+		chainID, err := reader.ChainID(ensureContext(opts.Context))
+		if err == nil && chainID != nil {
+			return types.NewEIP155Signer(chainID)
+		}
 	}
-
-	firstTimer := ether.BlockChain().GetBlockByNumber(0).Time().Int64()
-	return ether.BlockChain().CurrentBlock().DposCtx().GetNowTokenNoder(firstTimer, now)
+	return types.HomesteadSigner{}
 }
 
-//得到当前的验证者帐号
-func (c *BoundContract) getProducer(opts *TransactOpts) (common.Address, error) {
+//判断指定时间的分币帐号是否是交易的发送者
+func (c *BoundContract) checkTokenNoder(opts *TransactOpts, boker BokerBackend, now int64) error {
 
-	var ether *eth.Ethereum
-	if err := GethNode.Service(&ether); err != nil {
-		return common.Address{}, err
+	tokennoder, err := boker.CurrentTokenNoder(now)
+	if err != nil {
+		return errTokenNoderLookup
 	}
-
-	if ether.BlockChain().CurrentBlock() == nil {
-		return common.Address{}, errors.New("failed to lookup token node")
+	if tokennoder != opts.From {
+		return errNotTokenNoder
 	}
-
-	firstTimer := ether.BlockChain().GetBlockByNumber(0).Time().Int64()
-	return ether.BlockChain().CurrentBlock().DposCtx().GetCurrentProducer(firstTimer)
+	return nil
 }
 
 //得到参数类型
@@ -214,16 +201,11 @@ func (c *BoundContract) Transact(opts *TransactOpts, method string, params ...in
 		return nil, err
 	}
 
-	//判断节点是否已经启动
-	if GethNode != nil {
-
-		var e *eth.Ethereum
-		if err := GethNode.Service(&e); err != nil {
-			return nil, err
-		}
+	//判断是否可以查询播客链的基础合约信息
+	if boker := c.bokerBackend(opts); boker != nil {
 
 		//得到合约类型
-		contractType, err := e.Boker().GetContract(c.address)
+		contractType, err := boker.GetContractType(c.address)
 		if err != nil {
 			return nil, err
 		}
@@ -250,28 +232,14 @@ func (c *BoundContract) Transact(opts *TransactOpts, method string, params ...in
 			//由基础链触发的基础合约，不收取Gas费用
 			if method == protocol.AssignTokenMethod {
 
-				//得到当前的分币节点
-				tokennoder, err := c.getNowTokenNoder(opts, now)
-				if err != nil {
-					return nil, errors.New("get assign token error")
-				}
-				if tokennoder != opts.From {
-					return nil, errors.New("current assign token not is from account")
-				}
-
-				return c.assginTransact(opts, &c.address, input, extra, protocol.AssignToken, now)
-
-				//return c.transact(opts, &c.address, input, extra, protocol.AssignToken)
+				//判断当前的分币节点并提交，失败时在时间片内重试
+				return c.assignTransactRetry(opts, boker, input, extra, now)
 
 			} else if method == protocol.RotateVoteMethod {
 
-				//得到当前的分币节点
-				tokennoder, err := c.getTokenNoder(opts)
-				if err != nil {
-					return nil, errors.New("get rotate vote error")
-				}
-				if tokennoder != opts.From {
-					return nil, errors.New("current rotate vote not is from account")
+				//判断当前的分币节点
+				if err := c.checkTokenNoder(opts, boker, now); err != nil {
+					return nil, err
 				}
 				return c.transact(opts, &c.address, input, extra, protocol.VoteEpoch)
 			}
@@ -299,51 +267,34 @@ func (c *BoundContract) TryTransact(opts *TransactOpts, now int64, method string
 		return nil, err
 	}
 
-	if GethNode != nil {
+	boker := c.bokerBackend(opts)
+	if boker == nil {
+		return nil, ErrNoBokerBackend
+	}
 
-		var e *eth.Ethereum
-		if err := GethNode.Service(&e); err != nil {
-			return nil, err
-		}
+	if method == protocol.AssignTokenMethod {
+		return c.assignTransactRetry(opts, boker, input, []byte(""), now)
 
-		if method == protocol.AssignTokenMethod {
+	} else if method == protocol.RotateVoteMethod {
 
-			tokennoder, err := c.getNowTokenNoder(opts, now)
-			if err != nil {
-				return nil, errors.New("get assign token error")
-			}
-			if tokennoder != opts.From {
-				return nil, errors.New("current assign token not is from account")
-			}
-			return c.assginTransact(opts, &c.address, input, []byte(""), protocol.AssignToken, now)
-
-		} else if method == protocol.RotateVoteMethod {
-
-			tokennoder, err := c.getTokenNoder(opts)
-			if err != nil {
-				return nil, errors.New("get rotate vote error")
-			}
-			if tokennoder != opts.From {
-				return nil, errors.New("current rotate vote not is from account")
-			}
-			return c.transact(opts, &c.address, input, []byte(""), protocol.VoteEpoch)
-		} else {
-			return nil, errors.New("unknown system contract method name")
+		if err := c.checkTokenNoder(opts, boker, time.Now().Unix()); err != nil {
+			return nil, err
 		}
+		return c.transact(opts, &c.address, input, []byte(""), protocol.VoteEpoch)
 	}
-	return nil, errors.New("node not run")
+	return nil, errors.New("unknown system contract method name")
 }
 
 func (c *BoundContract) Transfer(opts *TransactOpts) (*types.Transaction, error) {
 
 	log.Info("(c *BoundContract) Transfer")
 
-	var e *eth.Ethereum
-	if err := GethNode.Service(&e); err != nil {
-		return nil, err
+	boker := c.bokerBackend(opts)
+	if boker == nil {
+		return c.transact(opts, &c.address, nil, []byte(""), protocol.Binary)
 	}
 
-	txType, err := e.Boker().GetContract(c.address)
+	txType, err := boker.GetContractType(c.address)
 	if err != nil {
 		return nil, err
 	}
@@ -388,7 +339,7 @@ func (c *BoundContract) baseTransact(opts *TransactOpts, contract *common.Addres
 	}
 
 	//进行签名
-	signedTx, err := opts.Signer(types.HomesteadSigner{}, opts.From, rawTx)
+	signedTx, err := opts.Signer(c.signer(opts), opts.From, rawTx)
 	if err != nil {
 		return nil, err
 	}
@@ -432,7 +383,7 @@ func (c *BoundContract) assginTransact(opts *TransactOpts, contract *common.Addr
 		return nil, errors.New("no signer to authorize the transaction with")
 	}
 
-	signedTx, err := opts.Signer(types.HomesteadSigner{}, opts.From, rawTx)
+	signedTx, err := opts.Signer(c.signer(opts), opts.From, rawTx)
 	if err != nil {
 		return nil, err
 	}
@@ -515,7 +466,7 @@ func (c *BoundContract) normalTransact(opts *TransactOpts, contract *common.Addr
 	}
 
 	//进行签名
-	signedTx, err := opts.Signer(types.HomesteadSigner{}, opts.From, rawTx)
+	signedTx, err := opts.Signer(c.signer(opts), opts.From, rawTx)
 	if err != nil {
 		return nil, err
 	}
@@ -535,7 +486,7 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, p
 
 		//普通交易
 		return c.normalTransact(opts, contract, payload, extra, transactTypes)
-	} else if (transactTypes >= protocol.SetValidator) && (transactTypes <= protocol.AssignToken) {
+	} else if (transactTypes >= protocol.SetValidator) && (transactTypes <= protocol.UpdateContract) {
 
 		//基础合约交易
 		return c.baseTransact(opts, contract, payload, extra, transactTypes)
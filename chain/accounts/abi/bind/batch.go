@@ -0,0 +1,79 @@
+package bind
+
+import (
+	"github.com/Bokerchain/Boker/chain"
+)
+
+//CallBatch中的一个只读合约调用
+type BatchCall struct {
+	Method string        //合约方法名
+	Params []interface{} //方法参数
+	Result interface{}   //解码后的返回值
+	Err    error         //本次调用的错误，单个调用失败不影响其它调用
+}
+
+//在一次请求中执行多个只读合约调用，所有调用基于同一个区块状态。后台不支持批量调用或者请求pending状态时逐个调用，
+//返回的错误表示整个批量调用失败，单个调用的错误保存在BatchCall.Err中
+func (c *BoundContract) CallBatch(opts *CallOpts, calls []*BatchCall) error {
+
+	if opts == nil {
+		opts = new(CallOpts)
+	}
+	bc, ok := c.caller.(BatchContractCaller)
+	if !ok || opts.Pending {
+		for _, call := range calls {
+			call.Err = c.Call(opts, call.Result, call.Method, call.Params...)
+		}
+		return nil
+	}
+
+	//打包所有调用的输入
+	msgs := make([]ethereum.CallMsg, 0, len(calls))
+	packed := make([]*BatchCall, 0, len(calls))
+	for _, call := range calls {
+		input, err := c.abi.Pack(call.Method, call.Params...)
+		if err != nil {
+			call.Err = err
+			continue
+		}
+		msgs = append(msgs, ethereum.CallMsg{From: opts.From, To: &c.address, Data: input})
+		packed = append(packed, call)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	ctx := ensureContext(opts.Context)
+	outputs, errs, err := bc.CallContractBatch(ctx, msgs, nil)
+	if err != nil {
+		return err
+	}
+
+	//返回为空时确认合约存在，合约不存在时所有返回为空的调用都失败
+	var (
+		codeChecked bool
+		codeErr     error
+	)
+	for i, call := range packed {
+		if errs[i] != nil {
+			call.Err = errs[i]
+			continue
+		}
+		if len(outputs[i]) == 0 {
+			if !codeChecked {
+				if code, err := c.caller.CodeAt(ctx, c.address, nil); err != nil {
+					codeErr = err
+				} else if len(code) == 0 {
+					codeErr = ErrNoCode
+				}
+				codeChecked = true
+			}
+			if codeErr != nil {
+				call.Err = codeErr
+				continue
+			}
+		}
+		call.Err = c.abi.Unpack(call.Result, call.Method, outputs[i])
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package bind
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain"
+	"github.com/Bokerchain/Boker/chain/accounts/abi"
+	"github.com/Bokerchain/Boker/chain/common"
+)
+
+//模拟支持批量调用的后台，按调用的输入返回预设的结果
+type batchTestCaller struct {
+	outputs map[string][]byte //每个方法输入对应的返回
+	code    []byte
+	batches int //批量调用的次数
+	calls   int //单个调用的次数
+}
+
+func (c *batchTestCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return c.code, nil
+}
+
+func (c *batchTestCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.calls++
+	return c.outputs[string(call.Data)], nil
+}
+
+func (c *batchTestCaller) CallContractBatch(ctx context.Context, calls []ethereum.CallMsg, blockNumber *big.Int) ([][]byte, []error, error) {
+	c.batches++
+	outputs, errs := make([][]byte, len(calls)), make([]error, len(calls))
+	for i, call := range calls {
+		output, ok := c.outputs[string(call.Data)]
+		if !ok {
+			errs[i] = errors.New("execution failed")
+			continue
+		}
+		outputs[i] = output
+	}
+	return outputs, errs, nil
+}
+
+func TestCallBatch(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[
+		{"constant":true,"inputs":[{"name":"id","type":"uint256"}],"name":"votes","outputs":[{"name":"","type":"uint256"}],"type":"function"},
+		{"constant":true,"inputs":[],"name":"broken","outputs":[{"name":"","type":"uint256"}],"type":"function"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := parsed.Pack("votes", big.NewInt(1))
+	second, _ := parsed.Pack("votes", big.NewInt(2))
+	caller := &batchTestCaller{
+		outputs: map[string][]byte{
+			string(first):  common.LeftPadBytes([]byte{7}, 32),
+			string(second): common.LeftPadBytes([]byte{9}, 32),
+		},
+		code: []byte{1},
+	}
+	contract := NewBoundContract(common.Address{1}, parsed, caller, nil)
+
+	var votes1, votes2, broken *big.Int
+	calls := []*BatchCall{
+		{Method: "votes", Params: []interface{}{big.NewInt(1)}, Result: &votes1},
+		{Method: "votes", Params: []interface{}{big.NewInt(2)}, Result: &votes2},
+		{Method: "broken", Result: &broken},
+		{Method: "missing", Result: new(*big.Int)},
+	}
+	if err := contract.CallBatch(nil, calls); err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if caller.batches != 1 || caller.calls != 0 {
+		t.Fatalf("round trips mismatch: have %d batches and %d calls, want 1 batch", caller.batches, caller.calls)
+	}
+	if calls[0].Err != nil || votes1.Uint64() != 7 {
+		t.Errorf("first call mismatch: have %v (err %v), want 7", votes1, calls[0].Err)
+	}
+	if calls[1].Err != nil || votes2.Uint64() != 9 {
+		t.Errorf("second call mismatch: have %v (err %v), want 9", votes2, calls[1].Err)
+	}
+	if calls[2].Err == nil {
+		t.Errorf("failing call succeeded")
+	}
+	if calls[3].Err == nil {
+		t.Errorf("unknown method packed")
+	}
+}
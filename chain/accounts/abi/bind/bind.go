@@ -115,6 +115,7 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 		"namedtype":    namedType[lang],
 		"capitalise":   capitalise,
 		"decapitalise": decapitalise,
+		"zerovalue":    zeroValueObjC,
 	}
 	tmpl := template.Must(template.New("").Funcs(funcs).Parse(tmplSource[lang]))
 	if err := tmpl.Execute(buffer, data); err != nil {
@@ -137,6 +138,7 @@ func Bind(types []string, abis []string, bytecodes []string, pkg string, lang La
 var bindType = map[Lang]func(kind abi.Type) string{
 	LangGo:   bindTypeGo,
 	LangJava: bindTypeJava,
+	LangObjC: bindTypeObjC,
 }
 
 // bindTypeGo converts a Solidity type to a Go one. Since there is no clear mapping
@@ -254,11 +256,81 @@ func bindTypeJava(kind abi.Type) string {
 	}
 }
 
+// bindTypeObjC converts a Solidity type to an Objective-C one, based on the types
+// exported by the gomobile generated Geth framework. Since there is no clear mapping
+// from all Solidity types to Objective-C ones (e.g. uint17), those that cannot be
+// exactly mapped will use an upscaled type (e.g. GethBigInt).
+func bindTypeObjC(kind abi.Type) string {
+	stringKind := kind.String()
+
+	switch {
+	case strings.HasPrefix(stringKind, "address"):
+		parts := regexp.MustCompile(`address(\[[0-9]*\])?`).FindStringSubmatch(stringKind)
+		if len(parts) != 2 {
+			return stringKind
+		}
+		if parts[1] == "" {
+			return "GethAddress*"
+		}
+		return "GethAddresses*"
+
+	case strings.HasPrefix(stringKind, "bytes"):
+		parts := regexp.MustCompile(`bytes([0-9]*)(\[[0-9]*\])?`).FindStringSubmatch(stringKind)
+		if len(parts) != 3 {
+			return stringKind
+		}
+		if parts[2] != "" {
+			return "NSArray*"
+		}
+		return "NSData*"
+
+	case strings.HasPrefix(stringKind, "int") || strings.HasPrefix(stringKind, "uint"):
+		parts := regexp.MustCompile(`(u)?int([0-9]*)(\[[0-9]*\])?`).FindStringSubmatch(stringKind)
+		if len(parts) != 4 {
+			return stringKind
+		}
+		if parts[3] != "" {
+			return "GethBigInts*"
+		}
+		switch parts[2] {
+		case "8", "16", "32", "64":
+			if parts[1] == "" {
+				return fmt.Sprintf("int%s_t", parts[2])
+			}
+		}
+		return "GethBigInt*"
+
+	case strings.HasPrefix(stringKind, "bool"):
+		parts := regexp.MustCompile(`bool(\[[0-9]*\])?`).FindStringSubmatch(stringKind)
+		if len(parts) != 2 {
+			return stringKind
+		}
+		if parts[1] == "" {
+			return "BOOL"
+		}
+		return "NSArray*"
+
+	case strings.HasPrefix(stringKind, "string"):
+		parts := regexp.MustCompile(`string(\[[0-9]*\])?`).FindStringSubmatch(stringKind)
+		if len(parts) != 2 {
+			return stringKind
+		}
+		if parts[1] == "" {
+			return "NSString*"
+		}
+		return "GethStrings*"
+
+	default:
+		return stringKind
+	}
+}
+
 // namedType is a set of functions that transform language specific types to
 // named versions that my be used inside method names.
 var namedType = map[Lang]func(string, abi.Type) string{
 	LangGo:   func(string, abi.Type) string { panic("this shouldn't be needed") },
 	LangJava: namedTypeJava,
+	LangObjC: namedTypeObjC,
 }
 
 // namedTypeJava converts some primitive data types to named variants that can
@@ -297,11 +369,66 @@ func namedTypeJava(javaKind string, solKind abi.Type) string {
 	}
 }
 
+// namedTypeObjC converts the Objective-C data types to the named variants used by
+// the setter and getter selectors of GethInterface.
+func namedTypeObjC(objcKind string, solKind abi.Type) string {
+	switch objcKind {
+	case "GethAddress*":
+		return "Address"
+	case "GethAddresses*":
+		return "Addresses"
+	case "NSData*":
+		return "Binary"
+	case "NSString*":
+		return "String"
+	case "GethStrings*":
+		return "Strings"
+	case "BOOL":
+		return "Bool"
+	case "GethBigInts*":
+		return "BigInts"
+	case "NSArray*":
+		if strings.HasPrefix(solKind.String(), "bool") {
+			return "Bools"
+		}
+		return "Binaries"
+	case "GethBigInt*":
+		parts := regexp.MustCompile(`(u)?int([0-9]*)(\[[0-9]*\])?`).FindStringSubmatch(solKind.String())
+		if len(parts) != 4 {
+			return "BigInt"
+		}
+		switch parts[2] {
+		case "8", "16", "32", "64":
+			return capitalise(fmt.Sprintf("%sint%s", parts[1], parts[2]))
+		}
+		return "BigInt"
+	default:
+		if parts := regexp.MustCompile(`int([0-9]+)_t`).FindStringSubmatch(objcKind); len(parts) == 2 {
+			return "Int" + parts[1]
+		}
+		return objcKind
+	}
+}
+
+// zeroValueObjC returns the value an Objective-C method of the given return type
+// yields when the call fails.
+func zeroValueObjC(objcKind string) string {
+	switch {
+	case strings.HasSuffix(objcKind, "*"):
+		return "nil"
+	case objcKind == "BOOL":
+		return "NO"
+	default:
+		return "0"
+	}
+}
+
 // methodNormalizer is a name transformer that modifies Solidity method names to
 // conform to target language naming concentions.
 var methodNormalizer = map[Lang]func(string) string{
 	LangGo:   capitalise,
 	LangJava: decapitalise,
+	LangObjC: decapitalise,
 }
 
 // capitalise makes the first character of a string upper case.
@@ -504,3 +504,17 @@ func TestBindings(t *testing.T) {
 		t.Fatalf("failed to run binding test: %v\n%s", err, out)
 	}
 }
+
+// Tests that Objective-C bindings can be generated for all the test contracts,
+// declaring a prefixed class for each of them.
+func TestBindingsObjC(t *testing.T) {
+	for i, tt := range bindTests {
+		bind, err := Bind([]string{tt.name}, []string{tt.abi}, []string{tt.bytecode}, "bindtest", LangObjC)
+		if err != nil {
+			t.Fatalf("test %d: failed to generate binding: %v", i, err)
+		}
+		if want := fmt.Sprintf("@interface bindtest%s : NSObject", tt.name); !strings.Contains(bind, want) {
+			t.Errorf("test %d: binding missing class declaration %q", i, want)
+		}
+	}
+}
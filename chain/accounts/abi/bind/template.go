@@ -47,6 +47,7 @@ type tmplMethod struct {
 var tmplSource = map[Lang]string{
 	LangGo:   tmplSourceGo,
 	LangJava: tmplSourceJava,
+	LangObjC: tmplSourceObjC,
 }
 
 // tmplSourceGo is the Go source template use to generate the contract binding
@@ -367,3 +368,154 @@ import org.ethereum.geth.internal.*;
 	}
 {{end}}
 `
+
+// tmplSourceObjC is the Objective-C source template use to generate the contract
+// binding based on. The binding builds on the Geth framework generated by gomobile
+// from the mobile package, the package name is used as the class name prefix.
+const tmplSourceObjC = `
+// This file is an automatically generated Objective-C binding. Do not modify as
+// any change will likely be lost upon the next re-generation!
+
+#import <Foundation/Foundation.h>
+#import <Geth/Geth.h>
+
+{{range $contract := .Contracts}}
+	{{$class := printf "%s%s" $.Package .Type}}
+	{{range .Calls}}
+		{{if gt (len .Normalized.Outputs) 1}}
+		// {{$class}}{{capitalise .Normalized.Name}}Results is the output of a call to {{.Normalized.Name}}.
+		@interface {{$class}}{{capitalise .Normalized.Name}}Results : NSObject
+		{{range $index, $item := .Normalized.Outputs}}@property (nonatomic) {{bindtype .Type}} {{if ne .Name ""}}{{decapitalise .Name}}{{else}}return{{$index}}{{end}};
+		{{end}}
+		@end
+
+		@implementation {{$class}}{{capitalise .Normalized.Name}}Results
+		@end
+		{{end}}
+	{{end}}
+
+	@interface {{$class}} : NSObject
+
+	// Ethereum address where this contract is located at.
+	@property (nonatomic, readonly) GethAddress* address;
+
+	// Ethereum transaction in which this contract was deployed (if known!).
+	@property (nonatomic, readonly) GethTransaction* deployer;
+
+	// abi returns the input ABI used to generate the binding from.
+	+ (NSString*)abi;
+
+	{{if .InputBin}}
+		// bytecode returns the compiled bytecode used for deploying new contracts.
+		+ (NSData*)bytecode;
+
+		// deploy deploys a new Ethereum contract, binding an instance of {{$class}} to it.
+		+ (instancetype)deploy:(GethTransactOpts*)auth client:(GethEthereumClient*)client{{range .Constructor.Inputs}} {{.Name}}:({{bindtype .Type}}){{.Name}}{{end}} error:(NSError**)error;
+	{{end}}
+
+	// initWithAddress creates a new instance of {{$class}}, bound to a specific deployed contract.
+	- (instancetype)initWithAddress:(GethAddress*)address client:(GethEthereumClient*)client error:(NSError**)error;
+
+	{{range .Calls}}
+		// {{.Normalized.Name}} is a free data retrieval call binding the contract method 0x{{printf "%x" .Original.Id}}.
+		//
+		// Solidity: {{.Original.String}}
+		- ({{if gt (len .Normalized.Outputs) 1}}{{$class}}{{capitalise .Normalized.Name}}Results*{{else}}{{range .Normalized.Outputs}}{{bindtype .Type}}{{end}}{{end}}){{.Normalized.Name}}:(GethCallOpts*)opts{{range .Normalized.Inputs}} {{.Name}}:({{bindtype .Type}}){{.Name}}{{end}} error:(NSError**)error;
+	{{end}}
+
+	{{range .Transacts}}
+		// {{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.Id}}.
+		//
+		// Solidity: {{.Original.String}}
+		- (GethTransaction*){{.Normalized.Name}}:(GethTransactOpts*)opts{{range .Normalized.Inputs}} {{.Name}}:({{bindtype .Type}}){{.Name}}{{end}} error:(NSError**)error;
+	{{end}}
+	@end
+
+	@implementation {{$class}} {
+		// Contract instance bound to a blockchain address.
+		GethBoundContract* _contract;
+	}
+
+	+ (NSString*)abi {
+		return @"{{.InputABI}}";
+	}
+
+	{{if .InputBin}}
+		+ (NSData*)bytecode {
+			return GethDecodeFromHex(@"{{.InputBin}}", nil);
+		}
+
+		+ (instancetype)deploy:(GethTransactOpts*)auth client:(GethEthereumClient*)client{{range .Constructor.Inputs}} {{.Name}}:({{bindtype .Type}}){{.Name}}{{end}} error:(NSError**)error {
+			GethInterfaces* args = GethNewInterfaces({{(len .Constructor.Inputs)}});
+			{{range $index, $element := .Constructor.Inputs}}
+			  GethInterface* arg{{$index}} = GethNewInterface(); [arg{{$index}} set{{namedtype (bindtype .Type) .Type}}:{{.Name}}];
+			  if (![args set:{{$index}} object:arg{{$index}} error:error]) { return nil; }
+			{{end}}
+			GethBoundContract* deployment = GethDeployContract(auth, [self abi], [self bytecode], client, args, error);
+			if (deployment == nil) {
+				return nil;
+			}
+			return [[self alloc] initWithContract:deployment];
+		}
+	{{end}}
+
+	// Internal initializer used by contract deployment and binding.
+	- (instancetype)initWithContract:(GethBoundContract*)contract {
+		if ((self = [super init])) {
+			_contract = contract;
+			_address  = [contract getAddress];
+			_deployer = [contract getDeployer];
+		}
+		return self;
+	}
+
+	- (instancetype)initWithAddress:(GethAddress*)address client:(GethEthereumClient*)client error:(NSError**)error {
+		GethBoundContract* contract = GethBindContract(address, [{{$class}} abi], client, error);
+		if (contract == nil) {
+			return nil;
+		}
+		return [self initWithContract:contract];
+	}
+
+	{{range .Calls}}
+		{{$zero := "nil"}}{{if lt (len .Normalized.Outputs) 2}}{{range .Normalized.Outputs}}{{$zero = zerovalue (bindtype .Type)}}{{end}}{{end}}
+		- ({{if gt (len .Normalized.Outputs) 1}}{{$class}}{{capitalise .Normalized.Name}}Results*{{else}}{{range .Normalized.Outputs}}{{bindtype .Type}}{{end}}{{end}}){{.Normalized.Name}}:(GethCallOpts*)opts{{range .Normalized.Inputs}} {{.Name}}:({{bindtype .Type}}){{.Name}}{{end}} error:(NSError**)error {
+			GethInterfaces* args = GethNewInterfaces({{(len .Normalized.Inputs)}});
+			{{range $index, $item := .Normalized.Inputs}}GethInterface* arg{{$index}} = GethNewInterface(); [arg{{$index}} set{{namedtype (bindtype .Type) .Type}}:{{.Name}}];
+			if (![args set:{{$index}} object:arg{{$index}} error:error]) { return {{$zero}}; }
+			{{end}}
+
+			GethInterfaces* results = GethNewInterfaces({{(len .Normalized.Outputs)}});
+			{{range $index, $item := .Normalized.Outputs}}GethInterface* result{{$index}} = GethNewInterface(); [result{{$index}} setDefault{{namedtype (bindtype .Type) .Type}}];
+			if (![results set:{{$index}} object:result{{$index}} error:error]) { return {{$zero}}; }
+			{{end}}
+
+			if (opts == nil) {
+				opts = GethNewCallOpts();
+			}
+			if (![_contract call:opts out:results method:@"{{.Original.Name}}" args:args error:error]) {
+				return {{$zero}};
+			}
+			{{if gt (len .Normalized.Outputs) 1}}
+				{{$class}}{{capitalise .Normalized.Name}}Results* result = [[{{$class}}{{capitalise .Normalized.Name}}Results alloc] init];
+				{{range $index, $item := .Normalized.Outputs}}result.{{if ne .Name ""}}{{decapitalise .Name}}{{else}}return{{$index}}{{end}} = [result{{$index}} get{{namedtype (bindtype .Type) .Type}}];
+				{{end}}
+				return result;
+			{{else}}{{range .Normalized.Outputs}}return [result0 get{{namedtype (bindtype .Type) .Type}}];{{end}}
+			{{end}}
+		}
+	{{end}}
+
+	{{range .Transacts}}
+		- (GethTransaction*){{.Normalized.Name}}:(GethTransactOpts*)opts{{range .Normalized.Inputs}} {{.Name}}:({{bindtype .Type}}){{.Name}}{{end}} error:(NSError**)error {
+			GethInterfaces* args = GethNewInterfaces({{(len .Normalized.Inputs)}});
+			{{range $index, $item := .Normalized.Inputs}}GethInterface* arg{{$index}} = GethNewInterface(); [arg{{$index}} set{{namedtype (bindtype .Type) .Type}}:{{.Name}}];
+			if (![args set:{{$index}} object:arg{{$index}} error:error]) { return nil; }
+			{{end}}
+
+			return [_contract transact:opts method:@"{{.Original.Name}}" args:args error:error];
+		}
+	{{end}}
+	@end
+{{end}}
+`
@@ -21,50 +21,32 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/log"
 )
 
+const (
+	minQueryInterval = time.Second                                                //首次查询回执的间隔
+	maxQueryInterval = time.Duration(protocol.ProducerInterval) * 2 * time.Second //查询回执的最大间隔
+)
+
 // WaitMined waits for tx to be mined on the blockchain.
 // It stops waiting when the context is canceled.
 func WaitMined(ctx context.Context, b DeployBackend, tx *types.Transaction) (*types.Receipt, error) {
-	queryTicker := time.NewTicker(time.Second)
-	defer queryTicker.Stop()
-
-	logger := log.New("hash", tx.Hash())
-	for {
-		receipt, err := b.TransactionReceipt(ctx, tx.Hash())
-		if receipt != nil {
-			return receipt, nil
-		}
-		if err != nil {
-			logger.Trace("Receipt retrieval failed", "err", err)
-		} else {
-			logger.Trace("Transaction not yet mined")
-		}
-		// Wait for the next round.
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-queryTicker.C:
-		}
-	}
+	return WaitTransaction(ctx, b, tx.Hash())
 }
 
+// WaitTransaction waits for the transaction with the given hash to be mined,
+// polling for its receipt with an exponential backoff capped at two block
+// production intervals. It stops waiting when the context is canceled.
 func WaitTransaction(ctx context.Context, b DeployBackend, txHash common.Hash) (*types.Receipt, error) {
+	logger := log.New("hash", txHash)
 
-	log.Info("WaitTransaction", "txHash", txHash)
-
-	queryTicker := time.NewTicker(time.Second)
-	defer queryTicker.Stop()
-
-	logger := log.New("txHash", txHash)
-
+	interval := minQueryInterval
 	for {
-
-		log.Info("WaitTransaction", "txHash", txHash)
-
 		receipt, err := b.TransactionReceipt(ctx, txHash)
 		if receipt != nil {
 			return receipt, nil
@@ -75,10 +57,15 @@ func WaitTransaction(ctx context.Context, b DeployBackend, txHash common.Hash) (
 			logger.Trace("Transaction not yet mined")
 		}
 		// Wait for the next round.
+		timer := time.NewTimer(interval)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return nil, ctx.Err()
-		case <-queryTicker.C:
+		case <-timer.C:
+		}
+		if interval *= 2; interval > maxQueryInterval {
+			interval = maxQueryInterval
 		}
 	}
 }
@@ -96,6 +83,17 @@ func WaitDeployed(ctx context.Context, b DeployBackend, tx *types.Transaction) (
 	if receipt.ContractAddress == (common.Address{}) {
 		return common.Address{}, fmt.Errorf("zero address")
 	}
+	// Make sure the receipt reports the same address DeployContract handed out,
+	// which is derived from the sender and the tx nonce.
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	if from, err := types.Sender(signer, tx); err == nil {
+		if address := crypto.CreateAddress(from, tx.Nonce()); address != receipt.ContractAddress {
+			return common.Address{}, fmt.Errorf("contract address mismatch: have %x, want %x", receipt.ContractAddress, address)
+		}
+	}
 	// Check that code has indeed been deployed at the address.
 	// This matters on pre-Homestead chains: OOG in the constructor
 	// could leave an empty account behind.
@@ -251,6 +251,28 @@ func (method Method) multInputUnpack(v []interface{}, input []byte) error {
 	return nil
 }
 
+//解码调用数据中方法ID之后的参数，按参数顺序返回对应的Go类型的值
+func (method Method) UnpackInputs(input []byte) ([]interface{}, error) {
+	if len(input)%32 != 0 {
+		return nil, errors.New("abi: improperly formatted input")
+	}
+	values := make([]interface{}, 0, len(method.Inputs))
+	index := 0
+	for _, arg := range method.Inputs {
+		value, err := toGoType(index*32, arg.Type, input)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if arg.Type.T == ArrayTy {
+			index += arg.Type.Size
+		} else {
+			index++
+		}
+	}
+	return values, nil
+}
+
 func (method Method) singleInputUnpack(v interface{}, input []byte) error {
 
 	log.Info("singleInputUnpack")
@@ -0,0 +1,277 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements an account backend delegating all signing to an
+// external signer process, so that the keys never reside in the node.
+//
+// The signer is reached over IPC or HTTP and has to serve the following
+// methods of the account namespace:
+//
+//	account_version()                                 string
+//	account_list()                                    []address
+//	account_signData(contentType, address, data)      signature
+//	account_signTransaction(txargs)                   {raw}
+//
+// signData is called with one of the content types MimetypeSealHash, for the
+// seal hashes of the blocks produced by a DPoS validator, and MimetypeHash for
+// any other hash, which lets the signer apply a separate policy for sealing.
+// The signature is in the [R || S || V] format where V is 0 or 1.
+package external
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sync"
+
+	ethereum "github.com/Bokerchain/Boker/chain"
+	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/event"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/rpc"
+)
+
+// BackendType is the reflect type of the external signer backend.
+var BackendType = reflect.TypeOf(&ExternalBackend{})
+
+const (
+	// MimetypeSealHash is the content type of block seal hashes
+	MimetypeSealHash = "application/x-dpos-sealhash"
+
+	// MimetypeHash is the content type of all other hashes signed
+	MimetypeHash = "application/x-hash"
+)
+
+// ExternalBackend is an account backend consisting of a single external signer.
+type ExternalBackend struct {
+	signers []accounts.Wallet
+}
+
+// NewExternalBackend connects to the external signer at the given IPC path
+// or HTTP URL.
+func NewExternalBackend(endpoint string) (*ExternalBackend, error) {
+	signer, err := NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalBackend{signers: []accounts.Wallet{signer}}, nil
+}
+
+// Wallets implements accounts.Backend, returning the external signer.
+func (eb *ExternalBackend) Wallets() []accounts.Wallet {
+	return eb.signers
+}
+
+// Subscribe implements accounts.Backend. The external signer is always
+// present, so no wallet events are ever sent.
+func (eb *ExternalBackend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// ExternalSigner is a wallet backed by an external signer.
+type ExternalSigner struct {
+	client   *rpc.Client
+	endpoint string
+	status   string
+
+	cache   []accounts.Account
+	cacheMu sync.RWMutex
+}
+
+// NewExternalSigner connects to the external signer at the given IPC path or
+// HTTP URL and checks that it is available.
+func NewExternalSigner(endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	signer := &ExternalSigner{client: client, endpoint: endpoint}
+
+	var version string
+	if err := client.Call(&version, "account_version"); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("external signer %s unavailable: %v", endpoint, err)
+	}
+	signer.status = fmt.Sprintf("ok [version=%v]", version)
+	log.Info("Connected to external signer", "endpoint", endpoint, "version", version)
+	return signer, nil
+}
+
+// URL implements accounts.Wallet, returning the endpoint of the signer.
+func (api *ExternalSigner) URL() accounts.URL {
+	return accounts.URL{Scheme: "extapi", Path: api.endpoint}
+}
+
+// Status implements accounts.Wallet, returning the version of the signer.
+func (api *ExternalSigner) Status() (string, error) {
+	return api.status, nil
+}
+
+// Open implements accounts.Wallet, the signer authenticates its requests
+// itself so there is nothing to open.
+func (api *ExternalSigner) Open(passphrase string) error {
+	return accounts.ErrNotSupported
+}
+
+// Close implements accounts.Wallet.
+func (api *ExternalSigner) Close() error {
+	return accounts.ErrNotSupported
+}
+
+// Accounts implements accounts.Wallet, returning the accounts of the signer.
+// The list is cached after the first successful request.
+func (api *ExternalSigner) Accounts() []accounts.Account {
+	api.cacheMu.RLock()
+	cached := api.cache
+	api.cacheMu.RUnlock()
+	if cached != nil {
+		return cached
+	}
+
+	var addresses []common.Address
+	if err := api.client.Call(&addresses, "account_list"); err != nil {
+		log.Error("Failed to list accounts of external signer", "err", err)
+		return nil
+	}
+	list := make([]accounts.Account, 0, len(addresses))
+	for _, addr := range addresses {
+		list = append(list, accounts.Account{Address: addr, URL: api.URL()})
+	}
+	api.cacheMu.Lock()
+	api.cache = list
+	api.cacheMu.Unlock()
+	return list
+}
+
+// Contains implements accounts.Wallet.
+func (api *ExternalSigner) Contains(account accounts.Account) bool {
+	for _, a := range api.Accounts() {
+		if a.Address == account.Address && (account.URL == (accounts.URL{}) || account.URL == api.URL()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet, accounts are managed by the signer.
+func (api *ExternalSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, accounts are managed by the signer.
+func (api *ExternalSigner) SelfDerive(base accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	log.Error("Operation not supported on external signers")
+}
+
+// signData requests the signature of the hash with the given content type.
+func (api *ExternalSigner) signData(account accounts.Account, contentType string, hash []byte) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := api.client.Call(&sig, "account_signData", contentType, account.Address, hexutil.Bytes(hash)); err != nil {
+		return nil, err
+	}
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("external signer returned invalid signature length %d", len(sig))
+	}
+	return sig, nil
+}
+
+// SignHash implements accounts.Wallet, requesting the signature of the hash
+// as MimetypeHash.
+func (api *ExternalSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return api.signData(account, MimetypeHash, hash)
+}
+
+// SignSealHash requests the signature of the seal hash of a block header as
+// MimetypeSealHash, it is used as the signing function of the DPoS engine.
+func (api *ExternalSigner) SignSealHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return api.signData(account, MimetypeSealHash, hash)
+}
+
+// SendTxArgs are the arguments of account_signTransaction.
+type SendTxArgs struct {
+	From      common.Address  `json:"from"`
+	To        *common.Address `json:"to"`
+	Gas       *hexutil.Big    `json:"gas"`
+	GasPrice  *hexutil.Big    `json:"gasPrice"`
+	Value     *hexutil.Big    `json:"value"`
+	Nonce     hexutil.Uint64  `json:"nonce"`
+	Data      hexutil.Bytes   `json:"data"`
+	Type      protocol.TxType `json:"type"`
+	Timestamp *hexutil.Big    `json:"timestamp"`
+	ChainID   *hexutil.Big    `json:"chainId,omitempty"`
+}
+
+// signTransactionResult is the response of account_signTransaction.
+type signTransactionResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+// SignTx implements accounts.Wallet, requesting the signature of the
+// transaction and checking that the signer signed it unmodified.
+func (api *ExternalSigner) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := &SendTxArgs{
+		From:      account.Address,
+		To:        tx.To(),
+		Gas:       (*hexutil.Big)(tx.Gas()),
+		GasPrice:  (*hexutil.Big)(tx.GasPrice()),
+		Value:     (*hexutil.Big)(tx.Value()),
+		Nonce:     hexutil.Uint64(tx.Nonce()),
+		Data:      tx.Data(),
+		Type:      tx.Type(),
+		Timestamp: (*hexutil.Big)(tx.Time()),
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if chainID != nil {
+		args.ChainID = (*hexutil.Big)(chainID)
+		signer = types.NewEIP155Signer(chainID)
+	}
+
+	var res signTransactionResult
+	if err := api.client.Call(&res, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+	signed, err := types.DecodeRawTransaction(res.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("external signer returned invalid transaction: %v", err)
+	}
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, errors.New("external signer modified the transaction")
+	}
+	if from, err := types.Sender(signer, signed); err != nil || from != account.Address {
+		return nil, errors.New("external signer returned transaction signed by another account")
+	}
+	return signed, nil
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, the signer does not
+// accept passphrases from the node.
+func (api *ExternalSigner) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, the signer does not
+// accept passphrases from the node.
+func (api *ExternalSigner) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
@@ -0,0 +1,151 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	"github.com/Bokerchain/Boker/chain/rpc"
+)
+
+// MockSigner is a minimal external signer holding a single key.
+type MockSigner struct {
+	key          *ecdsa.PrivateKey
+	contentTypes []string
+}
+
+func (s *MockSigner) Version() string {
+	return "1.0.0"
+}
+
+func (s *MockSigner) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PublicKey)}
+}
+
+func (s *MockSigner) SignData(contentType string, addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	s.contentTypes = append(s.contentTypes, contentType)
+	return crypto.Sign(data, s.key)
+}
+
+func (s *MockSigner) SignTransaction(args SendTxArgs) (map[string]interface{}, error) {
+	if args.To == nil {
+		return nil, errors.New("contract creation not allowed")
+	}
+	tx := types.NewTransaction(args.Type, uint64(args.Nonce), *args.To, args.Value.ToInt(), args.Gas.ToInt(), args.GasPrice.ToInt(), args.Data)
+	tx.SetTimestamp(args.Timestamp.ToInt().Int64())
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(args.ChainID.ToInt()), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": hexutil.Bytes(raw)}, nil
+}
+
+func newTestSigner(t *testing.T) (*MockSigner, *ExternalBackend, func()) {
+	key, _ := crypto.GenerateKey()
+	service := &MockSigner{key: key}
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", service); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	backend, err := NewExternalBackend(httpServer.URL)
+	if err != nil {
+		httpServer.Close()
+		t.Fatal(err)
+	}
+	return service, backend, httpServer.Close
+}
+
+func TestExternalSigner(t *testing.T) {
+	service, backend, stop := newTestSigner(t)
+	defer stop()
+
+	address := crypto.PubkeyToAddress(service.key.PublicKey)
+	wallets := backend.Wallets()
+	if len(wallets) != 1 {
+		t.Fatalf("wallet count mismatch: have %d, want 1", len(wallets))
+	}
+	signer := wallets[0].(*ExternalSigner)
+	if status, _ := signer.Status(); status != "ok [version=1.0.0]" {
+		t.Errorf("status mismatch: have %q", status)
+	}
+	if accs := signer.Accounts(); len(accs) != 1 || accs[0].Address != address {
+		t.Fatalf("accounts mismatch: have %v, want %x", accs, address)
+	}
+	account := accounts.Account{Address: address}
+	if !signer.Contains(account) {
+		t.Fatalf("signer does not contain its account")
+	}
+
+	// Seal hashes and other hashes are signed with distinct content types
+	hash := crypto.Keccak256([]byte("header"))
+	sig, err := signer.SignSealHash(account, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != address {
+		t.Errorf("seal signature not by the signer account")
+	}
+	if _, err := signer.SignHash(account, hash); err != nil {
+		t.Fatal(err)
+	}
+	if len(service.contentTypes) != 2 || service.contentTypes[0] != MimetypeSealHash || service.contentTypes[1] != MimetypeHash {
+		t.Errorf("content types mismatch: have %v", service.contentTypes)
+	}
+
+	// Transactions are signed by the signer and checked by the wallet
+	chainID := big.NewInt(1)
+	tx := types.NewTransaction(protocol.Binary, 3, common.HexToAddress("0x01"), big.NewInt(10), big.NewInt(90000), big.NewInt(1), []byte{0xaa})
+	tx.SetTimestamp(1514764800)
+	signed, err := signer.SignTx(account, tx, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := types.Sender(types.NewEIP155Signer(chainID), signed)
+	if err != nil || from != address {
+		t.Errorf("transaction sender mismatch: have %x, want %x (%v)", from, address, err)
+	}
+	if signed.Nonce() != 3 || signed.Time().Int64() != 1514764800 || !bytes.Equal(signed.Data(), []byte{0xaa}) {
+		t.Errorf("signed transaction differs from the request")
+	}
+
+	// Requests rejected by the signer fail
+	creation := types.NewContractCreation(0, big.NewInt(0), big.NewInt(90000), big.NewInt(1), nil)
+	if _, err := signer.SignTx(account, creation, chainID); err == nil {
+		t.Errorf("expected error for rejected transaction")
+	}
+	if _, err := signer.SignHashWithPassphrase(account, "", hash); err != accounts.ErrNotSupported {
+		t.Errorf("expected ErrNotSupported for passphrase signing, got %v", err)
+	}
+}
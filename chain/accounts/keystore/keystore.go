@@ -45,6 +45,10 @@ var (
 	ErrLocked  = accounts.NewAuthNeededError("password or unlock")
 	ErrNoMatch = errors.New("no key for given address or file")
 	ErrDecrypt = errors.New("could not decrypt key with given passphrase")
+
+	// ErrSealOnly is returned when signing anything but a block seal with an
+	// account unlocked by TimedUnlockSealOnly.
+	ErrSealOnly = errors.New("account is unlocked for block sealing only")
 )
 
 // KeyStoreType is the reflect type of a keystore backend.
@@ -73,7 +77,8 @@ type KeyStore struct {
 
 type unlocked struct {
 	*Key
-	abort chan struct{}
+	abort    chan struct{}
+	sealOnly bool // the key may only be used by SignSealHash
 }
 
 // NewKeyStore creates a keystore for the given directory.
@@ -264,10 +269,26 @@ func (ks *KeyStore) SignHash(a accounts.Account, hash []byte) ([]byte, error) {
 	if !found {
 		return nil, ErrLocked
 	}
+	if unlockedKey.sealOnly {
+		return nil, ErrSealOnly
+	}
 	// Sign the hash using plain ECDSA operations
 	return crypto.Sign(hash, unlockedKey.PrivateKey)
 }
 
+// SignSealHash signs the seal hash of a block header. Unlike SignHash it also
+// accepts accounts unlocked for block sealing only.
+func (ks *KeyStore) SignSealHash(a accounts.Account, hash []byte) ([]byte, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	unlockedKey, found := ks.unlocked[a.Address]
+	if !found {
+		return nil, ErrLocked
+	}
+	return crypto.Sign(hash, unlockedKey.PrivateKey)
+}
+
 // SignTx signs the given transaction with the requested account.
 //SignTx使用请求帐户签署指定的交易。
 func (ks *KeyStore) SignTx(a accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
@@ -279,10 +300,13 @@ func (ks *KeyStore) SignTx(a accounts.Account, tx *types.Transaction, chainID *b
 	if !found {
 		return nil, ErrLocked
 	}
+	if unlockedKey.sealOnly {
+		return nil, ErrSealOnly
+	}
 	// Depending on the presence of the chain ID, sign with EIP155 or homestead
-	/*if chainID != nil {
+	if chainID != nil {
 		return types.SignTx(tx, types.NewEIP155Signer(chainID), unlockedKey.PrivateKey)
-	}*/
+	}
 	return types.SignTx(tx, types.HomesteadSigner{}, unlockedKey.PrivateKey)
 }
 
@@ -309,10 +333,9 @@ func (ks *KeyStore) SignTxWithPassphrase(a accounts.Account, passphrase string,
 	defer zeroKey(key.PrivateKey)
 
 	// Depending on the presence of the chain ID, sign with EIP155 or homestead
-	/*if chainID != nil {
+	if chainID != nil {
 		return types.SignTx(tx, types.NewEIP155Signer(chainID), key.PrivateKey)
-	}*/
-	//log.Info("SignTxWithPassphrase types.HomesteadSigner")
+	}
 	return types.SignTx(tx, types.HomesteadSigner{}, key.PrivateKey)
 }
 
@@ -341,6 +364,19 @@ func (ks *KeyStore) Lock(addr common.Address) error {
 // shortens the active unlock timeout. If the address was previously unlocked
 // indefinitely the timeout is not altered.
 func (ks *KeyStore) TimedUnlock(a accounts.Account, passphrase string, timeout time.Duration) error {
+	return ks.timedUnlock(a, passphrase, timeout, false)
+}
+
+// TimedUnlockSealOnly unlocks the given account like TimedUnlock, but the key
+// can only be used to seal blocks through SignSealHash, signing arbitrary
+// hashes or transactions with it fails with ErrSealOnly. This limits what a
+// compromised node can do with the key of a block producer, which has to stay
+// unlocked while producing blocks.
+func (ks *KeyStore) TimedUnlockSealOnly(a accounts.Account, passphrase string, timeout time.Duration) error {
+	return ks.timedUnlock(a, passphrase, timeout, true)
+}
+
+func (ks *KeyStore) timedUnlock(a accounts.Account, passphrase string, timeout time.Duration, sealOnly bool) error {
 	a, key, err := ks.getDecryptedKey(a, passphrase)
 	if err != nil {
 		return err
@@ -350,20 +386,23 @@ func (ks *KeyStore) TimedUnlock(a accounts.Account, passphrase string, timeout t
 	defer ks.mu.Unlock()
 	u, found := ks.unlocked[a.Address]
 	if found {
-		if u.abort == nil {
+		if u.abort == nil && (sealOnly || !u.sealOnly) {
 			// The address was unlocked indefinitely, so unlocking
-			// it with a timeout would be confusing.
+			// it with a timeout would be confusing. An indefinite seal
+			// only unlock is replaced by a full unlock though.
 			zeroKey(key.PrivateKey)
 			return nil
 		}
 		// Terminate the expire goroutine and replace it below.
-		close(u.abort)
+		if u.abort != nil {
+			close(u.abort)
+		}
 	}
 	if timeout > 0 {
-		u = &unlocked{Key: key, abort: make(chan struct{})}
+		u = &unlocked{Key: key, abort: make(chan struct{}), sealOnly: sealOnly}
 		go ks.expire(a.Address, u, timeout)
 	} else {
-		u = &unlocked{Key: key}
+		u = &unlocked{Key: key, sealOnly: sealOnly}
 	}
 	ks.unlocked[a.Address] = u
 	return nil
@@ -427,13 +466,26 @@ func (ks *KeyStore) Export(a accounts.Account, passphrase, newPassphrase string)
 	if err != nil {
 		return nil, err
 	}
-	var N, P int
+	N, P := ks.ScryptParams()
+	return EncryptKey(key, newPassphrase, N, P)
+}
+
+// ExportECDSA decrypts the key of the given account with the passphrase and
+// returns the raw private key.
+func (ks *KeyStore) ExportECDSA(a accounts.Account, passphrase string) (*ecdsa.PrivateKey, error) {
+	_, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey, nil
+}
+
+// ScryptParams returns the scrypt N and P parameters new keys are encrypted with.
+func (ks *KeyStore) ScryptParams() (int, int) {
 	if store, ok := ks.storage.(*keyStorePassphrase); ok {
-		N, P = store.scryptN, store.scryptP
-	} else {
-		N, P = StandardScryptN, StandardScryptP
+		return store.scryptN, store.scryptP
 	}
-	return EncryptKey(key, newPassphrase, N, P)
+	return StandardScryptN, StandardScryptP
 }
 
 // Import stores the given encrypted JSON key into the key directory.
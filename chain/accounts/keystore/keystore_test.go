@@ -18,6 +18,7 @@ package keystore
 
 import (
 	"io/ioutil"
+	"math/big"
 	"math/rand"
 	"os"
 	"runtime"
@@ -27,7 +28,9 @@ import (
 	"time"
 
 	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/event"
 )
 
@@ -113,6 +116,51 @@ func TestSignWithPassphrase(t *testing.T) {
 	}
 }
 
+func TestExportImportKey(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	acc, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyJSON, err := ks.Export(acc, "foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Import into a keystore with different scrypt parameters
+	dir2, err := ioutil.TempDir("", "eth-keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir2)
+	ks2 := NewKeyStore(dir2, veryLightScryptN*2, veryLightScryptP)
+	if n, p := ks2.ScryptParams(); n != veryLightScryptN*2 || p != veryLightScryptP {
+		t.Fatalf("scrypt params mismatch: have (%d, %d), want (%d, %d)", n, p, veryLightScryptN*2, veryLightScryptP)
+	}
+	imported, err := ks2.Import(keyJSON, "bar", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Address != acc.Address {
+		t.Fatalf("imported address mismatch: have %x, want %x", imported.Address, acc.Address)
+	}
+	want, err := ks.ExportECDSA(acc, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := ks2.ExportECDSA(imported, "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have.D.Cmp(want.D) != 0 {
+		t.Fatal("imported private key mismatch")
+	}
+	if _, err := ks2.ExportECDSA(imported, "bar"); err != ErrDecrypt {
+		t.Fatalf("wrong error for invalid passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+}
+
 func TestTimedUnlock(t *testing.T) {
 	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
@@ -188,6 +236,49 @@ func TestOverrideUnlock(t *testing.T) {
 	}
 }
 
+func TestTimedUnlockSealOnly(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	pass := "foo"
+	a1, err := ks.NewAccount(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.TimedUnlockSealOnly(a1, pass, 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only seal signing is allowed while unlocked for sealing
+	if _, err := ks.SignSealHash(accounts.Account{Address: a1.Address}, testSigData); err != nil {
+		t.Fatal("Seal signing shouldn't return an error after unlocking, got ", err)
+	}
+	if _, err := ks.SignHash(accounts.Account{Address: a1.Address}, testSigData); err != ErrSealOnly {
+		t.Fatal("Signing should've failed with ErrSealOnly, got ", err)
+	}
+	tx := types.NewTransaction(protocol.Binary, 0, common.Address{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil)
+	if _, err := ks.SignTx(accounts.Account{Address: a1.Address}, tx, nil); err != ErrSealOnly {
+		t.Fatal("Transaction signing should've failed with ErrSealOnly, got ", err)
+	}
+
+	// The seal only unlock expires like a regular one
+	time.Sleep(250 * time.Millisecond)
+	if _, err := ks.SignSealHash(accounts.Account{Address: a1.Address}, testSigData); err != ErrLocked {
+		t.Fatal("Seal signing should've failed with ErrLocked timeout expired, got ", err)
+	}
+
+	// A full unlock replaces an indefinite seal only unlock
+	if err := ks.TimedUnlockSealOnly(a1, pass, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.TimedUnlock(a1, pass, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.SignHash(accounts.Account{Address: a1.Address}, testSigData); err != nil {
+		t.Fatal("Signing shouldn't return an error after a full unlock, got ", err)
+	}
+}
+
 // This test should fail under -race if signing races the expiration goroutine.
 func TestSignRace(t *testing.T) {
 	dir, ks := tmpKeyStore(t, false)
@@ -0,0 +1,417 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/common/math"
+	"github.com/Bokerchain/Boker/chain/crypto"
+)
+
+// EIP712DomainType is the name of the struct type describing the signing domain.
+const EIP712DomainType = "EIP712Domain"
+
+var (
+	errTypedDataNoPrimary = errors.New("typed data: missing primary type")
+
+	typedArrayRegexp = regexp.MustCompile(`^(.*)\[([0-9]*)\]$`)
+	typedIntRegexp   = regexp.MustCompile(`^(u?)int([0-9]*)$`)
+	typedBytesRegexp = regexp.MustCompile(`^bytes([0-9]+)$`)
+)
+
+// TypedData is the EIP-712 structured data to be hashed and signed, as sent by
+// eth_signTypedData_v4.
+type TypedData struct {
+	Types       TypedDataTypes         `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      TypedDataDomain        `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// TypedDataTypes maps struct type names to their ordered member definitions.
+type TypedDataTypes map[string][]TypedDataField
+
+// TypedDataField is a single named member of a struct type.
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedDataDomain is the EIP-712 domain separator data. Only the fields which
+// are set are included in the domain hash.
+type TypedDataDomain struct {
+	Name              string                `json:"name,omitempty"`
+	Version           string                `json:"version,omitempty"`
+	ChainId           *math.HexOrDecimal256 `json:"chainId,omitempty"`
+	VerifyingContract string                `json:"verifyingContract,omitempty"`
+	Salt              string                `json:"salt,omitempty"`
+}
+
+// UnmarshalJSON decodes the domain, accepting the chain ID either as a JSON
+// number or as a decimal or hex string.
+func (d *TypedDataDomain) UnmarshalJSON(input []byte) error {
+	type domain TypedDataDomain
+	var dec struct {
+		domain
+		ChainId json.RawMessage `json:"chainId,omitempty"`
+	}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	*d = TypedDataDomain(dec.domain)
+	if len(dec.ChainId) == 0 || string(dec.ChainId) == "null" {
+		return nil
+	}
+	text := strings.Trim(string(dec.ChainId), `"`)
+	chainId, ok := math.ParseBig256(text)
+	if !ok {
+		return fmt.Errorf("typed data: invalid chainId %s", dec.ChainId)
+	}
+	d.ChainId = (*math.HexOrDecimal256)(chainId)
+	return nil
+}
+
+// fields returns the EIP712Domain type definition of the populated domain fields.
+func (d *TypedDataDomain) fields() []TypedDataField {
+	var fields []TypedDataField
+	if d.Name != "" {
+		fields = append(fields, TypedDataField{Name: "name", Type: "string"})
+	}
+	if d.Version != "" {
+		fields = append(fields, TypedDataField{Name: "version", Type: "string"})
+	}
+	if d.ChainId != nil {
+		fields = append(fields, TypedDataField{Name: "chainId", Type: "uint256"})
+	}
+	if d.VerifyingContract != "" {
+		fields = append(fields, TypedDataField{Name: "verifyingContract", Type: "address"})
+	}
+	if d.Salt != "" {
+		fields = append(fields, TypedDataField{Name: "salt", Type: "bytes32"})
+	}
+	return fields
+}
+
+// values returns the populated domain fields as a generic message.
+func (d *TypedDataDomain) values() map[string]interface{} {
+	values := make(map[string]interface{})
+	if d.Name != "" {
+		values["name"] = d.Name
+	}
+	if d.Version != "" {
+		values["version"] = d.Version
+	}
+	if d.ChainId != nil {
+		values["chainId"] = (*big.Int)(d.ChainId)
+	}
+	if d.VerifyingContract != "" {
+		values["verifyingContract"] = d.VerifyingContract
+	}
+	if d.Salt != "" {
+		values["salt"] = d.Salt
+	}
+	return values
+}
+
+// SigHash returns the hash to be signed for the typed data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message)).
+func (typedData *TypedData) SigHash() ([]byte, error) {
+	if typedData.PrimaryType == "" {
+		return nil, errTypedDataNoPrimary
+	}
+	domainSeparator, err := typedData.DomainSeparator()
+	if err != nil {
+		return nil, err
+	}
+	var hash []byte
+	if typedData.PrimaryType != EIP712DomainType {
+		if hash, err = typedData.HashStruct(typedData.PrimaryType, typedData.Message); err != nil {
+			return nil, err
+		}
+	}
+	return crypto.Keccak256([]byte("\x19\x01"), domainSeparator, hash), nil
+}
+
+// DomainSeparator returns the hash of the signing domain. If the types do not
+// define EIP712Domain explicitly, it is derived from the populated domain fields.
+func (typedData *TypedData) DomainSeparator() ([]byte, error) {
+	if _, ok := typedData.Types[EIP712DomainType]; !ok {
+		types := make(TypedDataTypes, len(typedData.Types)+1)
+		for name, fields := range typedData.Types {
+			types[name] = fields
+		}
+		types[EIP712DomainType] = typedData.Domain.fields()
+		typedData = &TypedData{Types: types, PrimaryType: typedData.PrimaryType, Domain: typedData.Domain, Message: typedData.Message}
+	}
+	return typedData.HashStruct(EIP712DomainType, typedData.Domain.values())
+}
+
+// HashStruct returns hashStruct(s) = keccak256(typeHash ‖ encodeData(s)).
+func (typedData *TypedData) HashStruct(primaryType string, data map[string]interface{}) ([]byte, error) {
+	encoded, err := typedData.EncodeData(primaryType, data)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+// TypeHash returns keccak256(encodeType(primaryType)).
+func (typedData *TypedData) TypeHash(primaryType string) ([]byte, error) {
+	encoded, err := typedData.EncodeType(primaryType)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256([]byte(encoded)), nil
+}
+
+// EncodeType returns the canonical type string of primaryType, followed by the
+// definitions of all the struct types it references sorted by name, e.g.
+// "Mail(Person from,Person to,string contents)Person(string name,address wallet)".
+func (typedData *TypedData) EncodeType(primaryType string) (string, error) {
+	if _, ok := typedData.Types[primaryType]; !ok {
+		return "", fmt.Errorf("typed data: unknown type %q", primaryType)
+	}
+	deps := typedData.dependencies(primaryType, nil)
+	sort.Strings(deps[1:])
+
+	var buffer bytes.Buffer
+	for _, dep := range deps {
+		buffer.WriteString(dep)
+		buffer.WriteString("(")
+		for i, field := range typedData.Types[dep] {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(field.Type)
+			buffer.WriteString(" ")
+			buffer.WriteString(field.Name)
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.String(), nil
+}
+
+// dependencies returns primaryType and, recursively, every struct type it uses.
+func (typedData *TypedData) dependencies(primaryType string, found []string) []string {
+	primaryType = typedElementType(primaryType)
+	for _, dep := range found {
+		if dep == primaryType {
+			return found
+		}
+	}
+	if _, ok := typedData.Types[primaryType]; !ok {
+		return found
+	}
+	found = append(found, primaryType)
+	for _, field := range typedData.Types[primaryType] {
+		found = typedData.dependencies(field.Type, found)
+	}
+	return found
+}
+
+// EncodeData returns typeHash ‖ enc(value₁) ‖ enc(value₂) ‖ … for the members
+// of primaryType in definition order.
+func (typedData *TypedData) EncodeData(primaryType string, data map[string]interface{}) ([]byte, error) {
+	typeHash, err := typedData.TypeHash(primaryType)
+	if err != nil {
+		return nil, err
+	}
+	buffer := bytes.NewBuffer(typeHash)
+	for _, field := range typedData.Types[primaryType] {
+		encoded, err := typedData.encodeValue(field.Type, data[field.Name])
+		if err != nil {
+			return nil, fmt.Errorf("typed data: %s.%s: %v", primaryType, field.Name, err)
+		}
+		buffer.Write(encoded)
+	}
+	return buffer.Bytes(), nil
+}
+
+// encodeValue returns the 32 byte encoding of a single member value.
+func (typedData *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	// Arrays are encoded as the hash of their concatenated element encodings
+	if match := typedArrayRegexp.FindStringSubmatch(typ); match != nil {
+		if value == nil {
+			return make([]byte, 32), nil
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid array value %v", value)
+		}
+		if match[2] != "" {
+			if size, _ := strconv.Atoi(match[2]); size != len(items) {
+				return nil, fmt.Errorf("array length mismatch: have %d, want %d", len(items), size)
+			}
+		}
+		var buffer bytes.Buffer
+		for _, item := range items {
+			encoded, err := typedData.encodeValue(match[1], item)
+			if err != nil {
+				return nil, err
+			}
+			buffer.Write(encoded)
+		}
+		return crypto.Keccak256(buffer.Bytes()), nil
+	}
+	// Nested structs are encoded as their struct hash
+	if _, ok := typedData.Types[typ]; ok {
+		if value == nil {
+			return make([]byte, 32), nil
+		}
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		return typedData.HashStruct(typ, data)
+	}
+	return encodeTypedAtomic(typ, value)
+}
+
+// encodeTypedAtomic encodes the elementary solidity types.
+func encodeTypedAtomic(typ string, value interface{}) ([]byte, error) {
+	switch typ {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string value %v", value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+
+	case "bytes":
+		blob, err := typedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(blob), nil
+
+	case "bool":
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool value %v", value)
+		}
+		if flag {
+			return math.PaddedBigBytes(common.Big1, 32), nil
+		}
+		return make([]byte, 32), nil
+
+	case "address":
+		str, ok := value.(string)
+		if !ok || !common.IsHexAddress(str) {
+			return nil, fmt.Errorf("invalid address value %v", value)
+		}
+		return common.LeftPadBytes(common.HexToAddress(str).Bytes(), 32), nil
+	}
+	if match := typedBytesRegexp.FindStringSubmatch(typ); match != nil {
+		size, _ := strconv.Atoi(match[1])
+		if size == 0 || size > 32 {
+			return nil, fmt.Errorf("invalid type %s", typ)
+		}
+		blob, err := typedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(blob) > size {
+			return nil, fmt.Errorf("%s value too long: %d bytes", typ, len(blob))
+		}
+		return common.RightPadBytes(blob, 32), nil
+	}
+	if match := typedIntRegexp.FindStringSubmatch(typ); match != nil {
+		bits := 256
+		if match[2] != "" {
+			bits, _ = strconv.Atoi(match[2])
+		}
+		if bits == 0 || bits > 256 || bits%8 != 0 {
+			return nil, fmt.Errorf("invalid type %s", typ)
+		}
+		num, err := typedInteger(value)
+		if err != nil {
+			return nil, err
+		}
+		if match[1] == "u" {
+			if num.Sign() < 0 || num.BitLen() > bits {
+				return nil, fmt.Errorf("%s value out of range: %v", typ, num)
+			}
+			return math.PaddedBigBytes(num, 32), nil
+		}
+		limit := new(big.Int).Lsh(common.Big1, uint(bits-1))
+		if num.Cmp(limit) >= 0 || num.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("%s value out of range: %v", typ, num)
+		}
+		return math.PaddedBigBytes(math.U256(num), 32), nil
+	}
+	return nil, fmt.Errorf("unknown type %s", typ)
+}
+
+// typedBytes parses a hex encoded byte blob.
+func typedBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return hexutil.Decode(v)
+	}
+	return nil, fmt.Errorf("invalid bytes value %v", value)
+}
+
+// typedInteger parses an integer given as a JSON number, or as a decimal or hex string.
+func typedInteger(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return new(big.Int).Set(v), nil
+	case float64:
+		num, accuracy := new(big.Float).SetFloat64(v).Int(nil)
+		if accuracy != big.Exact {
+			return nil, fmt.Errorf("invalid integer value %v", v)
+		}
+		return num, nil
+	case json.Number:
+		return typedInteger(string(v))
+	case string:
+		negative := strings.HasPrefix(v, "-")
+		num, ok := math.ParseBig256(strings.TrimPrefix(v, "-"))
+		if !ok {
+			return nil, fmt.Errorf("invalid integer value %q", v)
+		}
+		if negative {
+			num.Neg(num)
+		}
+		return num, nil
+	}
+	return nil, fmt.Errorf("invalid integer value %v", value)
+}
+
+// typedElementType strips any array suffixes from a type name.
+func typedElementType(typ string) string {
+	for {
+		match := typedArrayRegexp.FindStringSubmatch(typ)
+		if match == nil {
+			return typ
+		}
+		typ = match[1]
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/crypto"
+)
+
+// The example message of the EIP-712 specification.
+const typedMailJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+// Tests that typed data is hashed and signed according to the EIP-712 test vectors.
+func TestTypedDataSigHash(t *testing.T) {
+	var typedData TypedData
+	if err := json.Unmarshal([]byte(typedMailJSON), &typedData); err != nil {
+		t.Fatalf("failed to decode typed data: %v", err)
+	}
+	encoded, err := typedData.EncodeType("Mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; encoded != want {
+		t.Errorf("type encoding mismatch: have %s, want %s", encoded, want)
+	}
+	domain, err := typedData.DomainSeparator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"; hexutil.Encode(domain) != want {
+		t.Errorf("domain separator mismatch: have %x, want %s", domain, want)
+	}
+	// The domain type should be derived from the domain when not given explicitly
+	delete(typedData.Types, EIP712DomainType)
+	if derived, err := typedData.DomainSeparator(); err != nil || hexutil.Encode(derived) != hexutil.Encode(domain) {
+		t.Errorf("derived domain separator mismatch: have %x, want %x (err %v)", derived, domain, err)
+	}
+	hash, err := typedData.SigHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"; hexutil.Encode(hash) != want {
+		t.Fatalf("signing hash mismatch: have %x, want %s", hash, want)
+	}
+	key := crypto.ToECDSAUnsafe(crypto.Keccak256([]byte("cow")))
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[64] += 27
+	if want := "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b915621c"; hexutil.Encode(sig) != want {
+		t.Errorf("signature mismatch: have %x, want %s", sig, want)
+	}
+}
+
+// Tests that invalid member values are rejected.
+func TestTypedDataInvalidValues(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value interface{}
+	}{
+		{"uint8", float64(256)},
+		{"uint256", "-1"},
+		{"int8", "0x80"},
+		{"bytes4", "0x0102030405"},
+		{"address", "0x1234"},
+		{"bool", "true"},
+		{"uint256[2]", []interface{}{float64(1)}},
+		{"foo", "bar"},
+	}
+	typedData := &TypedData{Types: TypedDataTypes{}}
+	for i, tt := range tests {
+		if _, err := typedData.encodeValue(tt.typ, tt.value); err == nil {
+			t.Errorf("test %d: expected error encoding %v as %s", i, tt.value, tt.typ)
+		}
+	}
+}
@@ -2,11 +2,15 @@ package bokerapi
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/trie"
 )
 
@@ -14,8 +18,12 @@ import (
 type Api interface {
 	GetAccount(account common.Address) ([]protocol.TxType, error)                                                //得到账号级别
 	GetContract(address common.Address) (protocol.ContractType, error)                                           //得到合约级别
+	GetContracts() map[common.Address]protocol.ContractType                                                      //得到所有基础合约及其级别
 	SetContract(address common.Address, contractType protocol.ContractType, isCancel bool, abiJson string) error //设置合约级别
 	CancelContract(address common.Address) error
+	ScheduleContractUpdate(update protocol.ContractUpdate) error                                                                     //记录验证者签名的基础合约替换
+	ApplyContractUpdates(number uint64)                                                                                              //执行在指定区块生效的基础合约替换
+	RegistryHash() common.Hash                                                                                                       //基础合约注册表的Hash
 	GetContractAddr(protocol.ContractType) (common.Address, error)                                                                   //得到合约帐号
 	SubmitBokerTransaction(ctx context.Context, txType protocol.TxType, to common.Address, extra string) (*types.Transaction, error) //产生一个设置验证者交易
 	IsValidator(address common.Address) bool                                                                                         //必须是特殊账号
@@ -23,6 +31,50 @@ type Api interface {
 	GetMethodName(txType protocol.TxType) (string, string, error)                                                                    //根据交易类型得到方法名称（只适用于基础合约）
 }
 
+//替换基础合约交易携带的数据，交易的接收地址为新的合约地址
+type UpdateContractPayload struct {
+	Old          common.Address
+	ContractType protocol.ContractType
+	Number       uint64   //替换生效的区块号
+	Signatures   [][]byte //验证者对UpdateContractHash的签名
+}
+
+//验证者为替换基础合约地址签名的内容，包含当前注册表的Hash和生效的区块号，注册表修改后签名不能被重放
+func UpdateContractHash(registry common.Hash, old common.Address, new common.Address, contractType protocol.ContractType, number uint64) common.Hash {
+
+	var num [8]byte
+	binary.BigEndian.PutUint64(num[:], number)
+	return crypto.Keccak256Hash([]byte("boker-update-contract"), registry.Bytes(), old.Bytes(), new.Bytes(), []byte{byte(contractType)}, num[:])
+}
+
+//校验签名来自超过三分之二的不同验证者
+func VerifyValidatorSignatures(hash common.Hash, signatures [][]byte, validators []common.Address) error {
+
+	valid := make(map[common.Address]bool, len(validators))
+	for _, validator := range validators {
+		valid[validator] = true
+	}
+	signed := make(map[common.Address]bool, len(signatures))
+	for _, sig := range signatures {
+		if len(sig) != 65 {
+			return errors.New("invalid signature length")
+		}
+		pubkey, err := crypto.SigToPub(hash.Bytes(), sig)
+		if err != nil {
+			return err
+		}
+		signer := crypto.PubkeyToAddress(*pubkey)
+		if !valid[signer] {
+			return fmt.Errorf("signer %s is not a validator", signer.Hex())
+		}
+		signed[signer] = true
+	}
+	if quorum := len(valid)*2/3 + 1; len(signed) < quorum {
+		return fmt.Errorf("signed by %d validators, %d required", len(signed), quorum)
+	}
+	return nil
+}
+
 func ExistsTxType(txType protocol.TxType, txTypes []protocol.TxType) bool {
 
 	if len(txTypes) <= 0 {
@@ -0,0 +1,105 @@
+package bokerapi
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/crypto"
+)
+
+//生成指定数量的验证者私钥和地址
+func testValidators(t *testing.T, n int) ([]*ecdsa.PrivateKey, []common.Address) {
+
+	keys := make([]*ecdsa.PrivateKey, n)
+	addrs := make([]common.Address, n)
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		keys[i], addrs[i] = key, crypto.PubkeyToAddress(key.PublicKey)
+	}
+	return keys, addrs
+}
+
+func validatorSigs(t *testing.T, hash common.Hash, keys ...*ecdsa.PrivateKey) [][]byte {
+
+	sigs := make([][]byte, len(keys))
+	for i, key := range keys {
+		sig, err := crypto.Sign(hash.Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		sigs[i] = sig
+	}
+	return sigs
+}
+
+func TestVerifyValidatorSignatures(t *testing.T) {
+
+	keys, validators := testValidators(t, 6)
+	outsider, _ := crypto.GenerateKey()
+	hash := UpdateContractHash(common.Hash{1}, common.Address{2}, common.Address{3}, protocol.SystemContract, 10)
+
+	//6个验证者需要6*2/3+1=5个签名
+	tests := []struct {
+		name string
+		sigs [][]byte
+		ok   bool
+	}{
+		{"no signatures", nil, false},
+		{"below quorum", validatorSigs(t, hash, keys[:4]...), false},
+		{"quorum", validatorSigs(t, hash, keys[:5]...), true},
+		{"all validators", validatorSigs(t, hash, keys...), true},
+		{"duplicate signer", validatorSigs(t, hash, keys[0], keys[1], keys[2], keys[3], keys[3]), false},
+		{"non-validator signer", validatorSigs(t, hash, append(keys[:5:5], outsider)...), false},
+		{"wrong hash", validatorSigs(t, common.Hash{4}, keys...), false},
+		{"invalid length", [][]byte{make([]byte, 64)}, false},
+	}
+	for _, tt := range tests {
+		err := VerifyValidatorSignatures(hash, tt.sigs, validators)
+		if tt.ok && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestVerifyValidatorSignaturesSmallSet(t *testing.T) {
+
+	//单个验证者时需要它自己的签名，两个验证者时需要全部签名
+	keys, validators := testValidators(t, 2)
+	hash := common.Hash{1}
+
+	if err := VerifyValidatorSignatures(hash, validatorSigs(t, hash, keys[0]), validators[:1]); err != nil {
+		t.Errorf("single validator: unexpected error: %v", err)
+	}
+	if err := VerifyValidatorSignatures(hash, validatorSigs(t, hash, keys[0]), validators); err == nil {
+		t.Errorf("one of two validators: expected error")
+	}
+	if err := VerifyValidatorSignatures(hash, validatorSigs(t, hash, keys...), validators); err != nil {
+		t.Errorf("two validators: unexpected error: %v", err)
+	}
+}
+
+func TestUpdateContractHash(t *testing.T) {
+
+	//签名内容包含注册表Hash和生效区块号，任何一项不同签名都不能重用
+	hash := UpdateContractHash(common.Hash{1}, common.Address{2}, common.Address{3}, protocol.SystemContract, 10)
+	others := []common.Hash{
+		UpdateContractHash(common.Hash{9}, common.Address{2}, common.Address{3}, protocol.SystemContract, 10),
+		UpdateContractHash(common.Hash{1}, common.Address{9}, common.Address{3}, protocol.SystemContract, 10),
+		UpdateContractHash(common.Hash{1}, common.Address{2}, common.Address{9}, protocol.SystemContract, 10),
+		UpdateContractHash(common.Hash{1}, common.Address{2}, common.Address{3}, protocol.PersonalContract, 10),
+		UpdateContractHash(common.Hash{1}, common.Address{2}, common.Address{3}, protocol.SystemContract, 11),
+	}
+	for i, other := range others {
+		if other == hash {
+			t.Errorf("case %d: hash not bound to its input", i)
+		}
+	}
+}
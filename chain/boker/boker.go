@@ -151,6 +151,11 @@ func (boker *BokerBackend) GetContract(address common.Address) (protocol.Contrac
 	return boker.contracts.GetContract(address)
 }
 
+//GetContracts 得到所有基础合约地址及其等级
+func (boker *BokerBackend) GetContracts() map[common.Address]protocol.ContractType {
+	return boker.contracts.GetContracts()
+}
+
 //SetContract 回写合约信息
 func (boker *BokerBackend) SetContract(address common.Address, contractType protocol.ContractType, isCancel bool, abiJson string) error {
 
@@ -169,6 +174,41 @@ func (boker *BokerBackend) SetContract(address common.Address, contractType prot
 	return nil
 }
 
+//ScheduleContractUpdate 记录验证者签名的基础合约替换并提交合约树
+func (boker *BokerBackend) ScheduleContractUpdate(update protocol.ContractUpdate) error {
+
+	if err := boker.contracts.ScheduleUpdate(update); err != nil {
+		return err
+	}
+	bokerProto, err := boker.CommitTrie()
+	if err != nil {
+		return err
+	}
+	log.Info("(boker *BokerBackend) ScheduleContractUpdate", "ContractsHash", bokerProto.ContractsHash.String())
+	return nil
+}
+
+//ApplyContractUpdates 执行在指定区块生效的基础合约替换，有替换执行时提交合约树
+func (boker *BokerBackend) ApplyContractUpdates(number uint64) {
+
+	applied, err := boker.contracts.ApplyUpdates(number)
+	if err != nil {
+		log.Error("(boker *BokerBackend) ApplyContractUpdates", "number", number, "err", err)
+		return
+	}
+	if applied == 0 {
+		return
+	}
+	if _, err := boker.CommitTrie(); err != nil {
+		log.Error("(boker *BokerBackend) ApplyContractUpdates CommitTrie", "number", number, "err", err)
+	}
+}
+
+//RegistryHash 基础合约注册表的Hash
+func (boker *BokerBackend) RegistryHash() common.Hash {
+	return boker.contracts.RegistryHash()
+}
+
 func (boker *BokerBackend) CancelContract(address common.Address) error {
 	return boker.contracts.CancelContract(address)
 }
@@ -194,18 +234,19 @@ func (boker *BokerBackend) CommitTrie() (*protocol.BokerBackendProto, error) {
 
 	log.Info("(boker *BokerBackend) CommitTrie")
 
-	//提交基础合约交易
-	contractsRoot, err := boker.contracts.contractsTrie.CommitTo(boker.ethereum.ChainDb())
+	//提交基础合约交易，替换合约地址时树会被整体替换，需要通过加锁的方法获取
+	singleTrie, contractsTrie, abiTrie := boker.contracts.GetContractTrie()
+	contractsRoot, err := contractsTrie.CommitTo(boker.ethereum.ChainDb())
 	if err != nil {
 		return nil, err
 	}
 
-	singleRoot, err := boker.contracts.singleTrie.CommitTo(boker.ethereum.ChainDb())
+	singleRoot, err := singleTrie.CommitTo(boker.ethereum.ChainDb())
 	if err != nil {
 		return nil, err
 	}
 
-	abiRoot, err := boker.contracts.abiTrie.CommitTo(boker.ethereum.ChainDb())
+	abiRoot, err := abiTrie.CommitTo(boker.ethereum.ChainDb())
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@ package boker
 
 import (
 	"strconv"
+	"sync"
 
 	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
@@ -39,7 +40,9 @@ type BokerContracts struct {
 	transactions  *BokerTransaction                        //播客链交易对象
 	db            ethdb.Database                           //数据库
 	contracts     map[common.Address]protocol.ContractType //基础合约的Map
+	updates       []protocol.ContractUpdate                //等待生效的基础合约替换，按照记录的顺序保存在基础合约列表保存树中
 	services      ContractService                          //合约服务类
+	mu            sync.RWMutex                             //保护基础合约注册信息，保证替换合约地址是原子的
 }
 
 func NewContract(db ethdb.Database, ethereum *eth.Ethereum, transactions *BokerTransaction, bokerProto *protocol.BokerBackendProto) (*BokerContracts, error) {
@@ -77,9 +80,14 @@ func NewContract(db ethdb.Database, ethereum *eth.Ethereum, transactions *BokerT
 	tmpContracts, _ := c.getContractsTrie()
 	log.Info("NewContract c.getContractsTrie", "len(tmpContracts)", len(tmpContracts))
 
+	//加载等待生效的基础合约替换
+	var err error
+	if c.updates, err = readContractUpdates(c.contractsTrie); err != nil {
+		log.Error("Load Bokerchain Base Contract Updates", "error", err)
+	}
+
 	//从树中加载合约信息
 	log.Info("Load Bokerchain Base Contract Config")
-	var err error
 	if err = c.loadTrieContract(); err != nil {
 		log.Error("Load Bokerchain Base Contract Trie", "error", err)
 		return c, nil
@@ -125,6 +133,9 @@ func NewContractAbiTrie(root common.Hash, db ethdb.Database) (*trie.Trie, error)
 //设置合约到Hash树中
 func (c *BokerContracts) SetContract(address common.Address, contractType protocol.ContractType, isCancel bool, abiJson string) error {
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	//设置基础合约
 	log.Info("(c *BokerContracts) SetContract", "address", address.String(), "contractType", contractType, "len(c.contracts)", len(c.contracts))
 
@@ -182,43 +193,63 @@ func (c *BokerContracts) CancelContract(address common.Address) error {
 
 	//检测合约是否存在
 	log.Info("(c *BokerContracts) CancelContract", "address", address.String())
-	contractType, err := c.GetContract(address)
+	service, err := c.cancelContract(address)
 	if err != nil {
 		return err
 	}
 
-	//从Hash树中删除
-	if err := c.singleTrie.TryDelete(address.Bytes()); err != nil {
-		return err
+	//终止合约运行，服务的协程会读取注册表，需要在释放锁之后终止
+	if service != nil && service.IsStart() {
+		log.Info("(c *BokerContracts) CancelContract Stop Contract")
+		service.Stop()
 	}
-	delete(c.contracts, address)
+	return nil
+}
 
-	//更新基础合约列表保存树
-	if err = c.setContractsTrie(); err != nil {
-		return protocol.ErrSaveContractTrie
-	}
+//从注册表中删除合约，返回需要终止的系统合约服务
+func (c *BokerContracts) cancelContract(address common.Address) (*boker_contract.BokerInterfaceService, error) {
 
-	//终止合约运行
-	if contractType == protocol.SystemContract {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	contractType, err := c.getContractType(address)
+	if err != nil {
+		return nil, err
+	}
 
-		if (c.services.contract != nil) && c.services.contract.IsStart() {
+	//在树的副本上修改，全部成功后再替换，中途失败时注册表保持不变
+	singleTrie, contractsTrie := *c.singleTrie, *c.contractsTrie
+	if err := singleTrie.TryDelete(address.Bytes()); err != nil {
+		return nil, err
+	}
+	contracts := c.copyContracts()
+	delete(contracts, address)
+	if err := updateContractsTrie(&contractsTrie, contracts); err != nil {
+		return nil, protocol.ErrSaveContractTrie
+	}
+	c.singleTrie, c.contractsTrie, c.contracts = &singleTrie, &contractsTrie, contracts
 
-			//终止
-			log.Info("(c *BokerContracts) SetContract Stop Contract")
-			c.services.contract.Stop()
-		}
+	if contractType != protocol.SystemContract {
+		return nil, nil
 	}
-	return nil
+	service := c.services.contract
+	c.services.contract = nil
+	return service, nil
 }
 
 //将所有的合约地址进行设置
 func (c *BokerContracts) setContractsTrie() error {
 
-	//转换成切片数组
 	log.Info("(c *BokerContracts) setContractsTrie")
+	return updateContractsTrie(c.contractsTrie, c.contracts)
+}
+
+//将合约地址列表写入基础合约列表保存树
+func updateContractsTrie(contractsTrie *trie.Trie, contractsMap map[common.Address]protocol.ContractType) error {
 
+	//转换成切片数组
 	var contracts []common.Address = make([]common.Address, 0)
-	for k, _ := range c.contracts {
+	for k, _ := range contractsMap {
 		contracts = append(contracts, k)
 	}
 	contractsRLP, err := rlp.EncodeToBytes(contracts)
@@ -227,14 +258,22 @@ func (c *BokerContracts) setContractsTrie() error {
 		log.Error("failed to encode contracts to rlp", "error", err)
 		return err
 	}
-	log.Info("(c *BokerContracts) setContractsTrie",
+	log.Info("updateContractsTrie",
 		"protocol.Contracts", protocol.Contracts,
 		"contracts", len(contracts),
-		"hash", c.contractsTrie.Hash())
+		"hash", contractsTrie.Hash())
 
-	c.contractsTrie.Update(protocol.Contracts, contractsRLP)
+	return contractsTrie.TryUpdate(protocol.Contracts, contractsRLP)
+}
 
-	return nil
+//得到基础合约Map的副本，调用者需要持有锁
+func (c *BokerContracts) copyContracts() map[common.Address]protocol.ContractType {
+
+	contracts := make(map[common.Address]protocol.ContractType, len(c.contracts))
+	for k, v := range c.contracts {
+		contracts[k] = v
+	}
+	return contracts
 }
 
 //得到所有的合约地址
@@ -334,6 +373,9 @@ func (c *BokerContracts) loadTrieContract() error {
 //查找合约账户
 func (c *BokerContracts) GetContract(address common.Address) (protocol.ContractType, error) {
 
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	if len(c.contracts) > 0 {
 		value, exist := c.contracts[address]
 		if exist {
@@ -343,6 +385,14 @@ func (c *BokerContracts) GetContract(address common.Address) (protocol.ContractT
 	return protocol.BinaryContract, nil
 }
 
+//得到所有基础合约的副本
+func (c *BokerContracts) GetContracts() map[common.Address]protocol.ContractType {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.copyContracts()
+}
+
 //判断此合约是否已经存在
 func (c *BokerContracts) existContract(address common.Address) (bool, error) {
 
@@ -355,11 +405,16 @@ func (c *BokerContracts) existContract(address common.Address) (bool, error) {
 
 func (c *BokerContracts) GetContractTrie() (*trie.Trie, *trie.Trie, *trie.Trie) {
 
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.singleTrie, c.contractsTrie, c.abiTrie
 }
 
 func (c *BokerContracts) GetContractAddr(contractType protocol.ContractType) (common.Address, error) {
 
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	for k, v := range c.contracts {
 
 		if v == contractType {
@@ -369,3 +424,195 @@ func (c *BokerContracts) GetContractAddr(contractType protocol.ContractType) (co
 
 	return common.Address{}, protocol.ErrNotFoundContract
 }
+
+//基础合约注册表的Hash，每次修改注册表后都会变化
+func (c *BokerContracts) RegistryHash() common.Hash {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.singleTrie.Hash()
+}
+
+//将基础合约从旧地址替换为新地址，合约类型和abi保持不变，系统合约的服务会切换到新地址
+func (c *BokerContracts) UpdateContract(old common.Address, new common.Address, contractType protocol.ContractType) error {
+
+	log.Info("(c *BokerContracts) UpdateContract", "old", old.String(), "new", new.String(), "contractType", contractType)
+
+	//系统合约需要先创建新地址的服务，失败时不修改注册表
+	var service *boker_contract.BokerInterfaceService
+	if contractType == protocol.SystemContract {
+		var err error
+		if service, err = boker_contract.NewBokerInterfaceService(c.ethereum, new); err != nil {
+			log.Error("(c *BokerContracts) UpdateContract NewBokerInterfaceService failed", "err", err)
+			return protocol.ErrNewContractService
+		}
+	}
+	stopped, err := c.updateContract(old, new, contractType, service)
+	if err != nil {
+		return err
+	}
+
+	//服务的协程会读取注册表，旧服务需要在释放锁之后终止
+	if stopped != nil && stopped.IsStart() {
+		log.Info("(c *BokerContracts) UpdateContract Stop Contract", "address", old.String())
+		stopped.Stop()
+	}
+	if service != nil {
+		log.Info("(c *BokerContracts) UpdateContract Start Contract", "address", new.String())
+		service.Start()
+	}
+	return nil
+}
+
+//在注册表中替换合约地址，返回被替换的系统合约服务
+func (c *BokerContracts) updateContract(old common.Address, new common.Address, contractType protocol.ContractType, service *boker_contract.BokerInterfaceService) (*boker_contract.BokerInterfaceService, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current, exist := c.contracts[old]; !exist || current != contractType {
+		return nil, protocol.ErrNotFoundContract
+	}
+	if _, exist := c.contracts[new]; exist {
+		return nil, protocol.ErrContractExist
+	}
+	abiJson, err := c.abiTrie.TryGet(old.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	//在树的副本上修改，全部成功后再替换，中途失败时注册表保持不变
+	singleTrie, abiTrie, contractsTrie := *c.singleTrie, *c.abiTrie, *c.contractsTrie
+	if err := singleTrie.TryUpdate(new.Bytes(), []byte(strconv.Itoa(int(contractType)))); err != nil {
+		return nil, err
+	}
+	if err := singleTrie.TryDelete(old.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := abiTrie.TryUpdate(new.Bytes(), abiJson); err != nil {
+		return nil, err
+	}
+	if err := abiTrie.TryDelete(old.Bytes()); err != nil {
+		return nil, err
+	}
+	contracts := c.copyContracts()
+	delete(contracts, old)
+	contracts[new] = contractType
+	if err := updateContractsTrie(&contractsTrie, contracts); err != nil {
+		return nil, protocol.ErrSaveContractTrie
+	}
+	c.singleTrie, c.abiTrie, c.contractsTrie, c.contracts = &singleTrie, &abiTrie, &contractsTrie, contracts
+
+	if service == nil {
+		return nil, nil
+	}
+	stopped := c.services.contract
+	c.services.contract = service
+	return stopped, nil
+}
+
+//从基础合约列表保存树中读取等待生效的基础合约替换
+func readContractUpdates(contractsTrie *trie.Trie) ([]protocol.ContractUpdate, error) {
+
+	updatesRLP, err := contractsTrie.TryGet(protocol.ContractUpdates)
+	if err != nil || len(updatesRLP) == 0 {
+		return nil, err
+	}
+	var updates []protocol.ContractUpdate
+	if err := rlp.DecodeBytes(updatesRLP, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+//将等待生效的基础合约替换写入基础合约列表保存树，没有等待的替换时删除
+func updateContractUpdates(contractsTrie *trie.Trie, updates []protocol.ContractUpdate) error {
+
+	if len(updates) == 0 {
+		return contractsTrie.TryDelete(protocol.ContractUpdates)
+	}
+	updatesRLP, err := rlp.EncodeToBytes(updates)
+	if err != nil {
+		return err
+	}
+	return contractsTrie.TryUpdate(protocol.ContractUpdates, updatesRLP)
+}
+
+//记录验证者签名的基础合约替换，在指定区块结束时生效。重复记录相同的替换时忽略
+func (c *BokerContracts) ScheduleUpdate(update protocol.ContractUpdate) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current, exist := c.contracts[update.Old]; !exist || current != update.ContractType {
+		return protocol.ErrNotFoundContract
+	}
+	if _, exist := c.contracts[update.New]; exist {
+		return protocol.ErrContractExist
+	}
+	for _, scheduled := range c.updates {
+		if scheduled == update {
+			return nil
+		}
+		if scheduled.Old == update.Old || scheduled.New == update.New {
+			return protocol.ErrUpdateScheduled
+		}
+	}
+	updates := append(append([]protocol.ContractUpdate{}, c.updates...), update)
+
+	contractsTrie := *c.contractsTrie
+	if err := updateContractUpdates(&contractsTrie, updates); err != nil {
+		return protocol.ErrSaveContractTrie
+	}
+	c.contractsTrie, c.updates = &contractsTrie, updates
+	log.Info("(c *BokerContracts) ScheduleUpdate", "old", update.Old.String(), "new", update.New.String(), "contractType", update.ContractType, "number", update.Number)
+	return nil
+}
+
+//执行在指定区块及之前生效的基础合约替换，返回执行的数量。原合约已经被撤销的替换直接丢弃
+func (c *BokerContracts) ApplyUpdates(number uint64) (int, error) {
+
+	due, err := c.takeUpdates(number)
+	if err != nil {
+		return 0, err
+	}
+	for _, update := range due {
+		if err := c.UpdateContract(update.Old, update.New, update.ContractType); err != nil {
+			log.Warn("(c *BokerContracts) ApplyUpdates", "old", update.Old.String(), "new", update.New.String(), "number", update.Number, "err", err)
+		}
+	}
+	return len(due), nil
+}
+
+//从等待列表中取出已经到达生效区块的替换
+func (c *BokerContracts) takeUpdates(number uint64) ([]protocol.ContractUpdate, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var due, pending []protocol.ContractUpdate
+	for _, update := range c.updates {
+		if update.Number <= number {
+			due = append(due, update)
+		} else {
+			pending = append(pending, update)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	contractsTrie := *c.contractsTrie
+	if err := updateContractUpdates(&contractsTrie, pending); err != nil {
+		return nil, protocol.ErrSaveContractTrie
+	}
+	c.contractsTrie, c.updates = &contractsTrie, pending
+	return due, nil
+}
+
+//得到等待生效的基础合约替换
+func (c *BokerContracts) GetUpdates() []protocol.ContractUpdate {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]protocol.ContractUpdate{}, c.updates...)
+}
@@ -0,0 +1,191 @@
+package boker
+
+import (
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+)
+
+func newTestContracts(t *testing.T, db ethdb.Database, proto *protocol.BokerBackendProto) *BokerContracts {
+
+	c, err := NewContract(db, nil, nil, proto)
+	if err != nil {
+		t.Fatalf("failed to create contracts: %v", err)
+	}
+	return c
+}
+
+//提交三棵合约树，返回可以重新加载注册表的根
+func commitTestContracts(t *testing.T, c *BokerContracts, db ethdb.Database) *protocol.BokerBackendProto {
+
+	singleTrie, contractsTrie, abiTrie := c.GetContractTrie()
+	proto := new(protocol.BokerBackendProto)
+	var err error
+	if proto.SingleHash, err = singleTrie.CommitTo(db); err != nil {
+		t.Fatalf("failed to commit single trie: %v", err)
+	}
+	if proto.ContractsHash, err = contractsTrie.CommitTo(db); err != nil {
+		t.Fatalf("failed to commit contracts trie: %v", err)
+	}
+	if proto.ContracAbiHash, err = abiTrie.CommitTo(db); err != nil {
+		t.Fatalf("failed to commit abi trie: %v", err)
+	}
+	return proto
+}
+
+func TestUpdateCancelContract(t *testing.T) {
+
+	db, _ := ethdb.NewMemDatabase()
+	c := newTestContracts(t, db, &protocol.BokerBackendProto{})
+
+	old, new := common.Address{1}, common.Address{2}
+	abi := `[{"type":"function","name":"test"}]`
+	if err := c.SetContract(old, protocol.PersonalContract, false, abi); err != nil {
+		t.Fatalf("failed to set contract: %v", err)
+	}
+	registry := c.RegistryHash()
+
+	//类型不匹配、旧地址不存在和新地址已存在时都不修改注册表
+	if err := c.UpdateContract(old, new, protocol.BinaryContract); err != protocol.ErrNotFoundContract {
+		t.Errorf("wrong type: have %v, want %v", err, protocol.ErrNotFoundContract)
+	}
+	if err := c.UpdateContract(new, old, protocol.PersonalContract); err != protocol.ErrNotFoundContract {
+		t.Errorf("missing old address: have %v, want %v", err, protocol.ErrNotFoundContract)
+	}
+	if err := c.UpdateContract(old, old, protocol.PersonalContract); err != protocol.ErrContractExist {
+		t.Errorf("existing new address: have %v, want %v", err, protocol.ErrContractExist)
+	}
+	if c.RegistryHash() != registry {
+		t.Fatalf("registry changed by failed updates")
+	}
+
+	//替换地址后类型和abi转移到新地址
+	if err := c.UpdateContract(old, new, protocol.PersonalContract); err != nil {
+		t.Fatalf("failed to update contract: %v", err)
+	}
+	if c.RegistryHash() == registry {
+		t.Errorf("registry hash not changed by update")
+	}
+	if contracts := c.GetContracts(); len(contracts) != 1 || contracts[new] != protocol.PersonalContract {
+		t.Errorf("contracts mismatch after update: %v", contracts)
+	}
+	_, _, abiTrie := c.GetContractTrie()
+	if have, _ := abiTrie.TryGet(new.Bytes()); string(have) != abi {
+		t.Errorf("abi of new address mismatch: have %q, want %q", have, abi)
+	}
+	if have, _ := abiTrie.TryGet(old.Bytes()); have != nil {
+		t.Errorf("abi of old address not removed: %q", have)
+	}
+
+	//提交后重新加载，注册表和替换后一致
+	reloaded := newTestContracts(t, db, commitTestContracts(t, c, db))
+	if contracts := reloaded.GetContracts(); len(contracts) != 1 || contracts[new] != protocol.PersonalContract {
+		t.Errorf("contracts mismatch after reload: %v", contracts)
+	}
+	if reloaded.RegistryHash() != c.RegistryHash() {
+		t.Errorf("registry hash mismatch after reload")
+	}
+
+	//撤销合约后注册表为空，重新加载后依然为空
+	if err := reloaded.CancelContract(new); err != nil {
+		t.Fatalf("failed to cancel contract: %v", err)
+	}
+	if contracts := reloaded.GetContracts(); len(contracts) != 0 {
+		t.Errorf("contracts left after cancel: %v", contracts)
+	}
+	if addr, err := reloaded.GetContractAddr(protocol.PersonalContract); err != protocol.ErrNotFoundContract {
+		t.Errorf("cancelled contract still found: %x", addr)
+	}
+	cancelled := newTestContracts(t, db, commitTestContracts(t, reloaded, db))
+	if contracts := cancelled.GetContracts(); len(contracts) != 0 {
+		t.Errorf("contracts left after reload: %v", contracts)
+	}
+}
+
+func TestScheduleContractUpdate(t *testing.T) {
+
+	db, _ := ethdb.NewMemDatabase()
+	c := newTestContracts(t, db, &protocol.BokerBackendProto{})
+
+	old, new := common.Address{1}, common.Address{2}
+	if err := c.SetContract(old, protocol.PersonalContract, false, ""); err != nil {
+		t.Fatalf("failed to set contract: %v", err)
+	}
+	update := protocol.ContractUpdate{Old: old, New: new, ContractType: protocol.PersonalContract, Number: 10}
+
+	//旧地址类型不符、新地址已经注册时不记录
+	if err := c.ScheduleUpdate(protocol.ContractUpdate{Old: old, New: new, ContractType: protocol.SystemContract, Number: 10}); err != protocol.ErrNotFoundContract {
+		t.Errorf("wrong type: have %v, want %v", err, protocol.ErrNotFoundContract)
+	}
+	if err := c.ScheduleUpdate(protocol.ContractUpdate{Old: old, New: old, ContractType: protocol.PersonalContract, Number: 10}); err != protocol.ErrContractExist {
+		t.Errorf("existing new address: have %v, want %v", err, protocol.ErrContractExist)
+	}
+
+	//重复记录相同的替换时忽略，与已记录的替换冲突时拒绝
+	if err := c.ScheduleUpdate(update); err != nil {
+		t.Fatalf("failed to schedule update: %v", err)
+	}
+	if err := c.ScheduleUpdate(update); err != nil {
+		t.Errorf("duplicate update: unexpected error: %v", err)
+	}
+	if err := c.ScheduleUpdate(protocol.ContractUpdate{Old: old, New: common.Address{3}, ContractType: protocol.PersonalContract, Number: 12}); err != protocol.ErrUpdateScheduled {
+		t.Errorf("conflicting update: have %v, want %v", err, protocol.ErrUpdateScheduled)
+	}
+	if updates := c.GetUpdates(); len(updates) != 1 || updates[0] != update {
+		t.Fatalf("scheduled updates mismatch: have %v, want [%v]", updates, update)
+	}
+}
+
+func TestApplyContractUpdates(t *testing.T) {
+
+	db, _ := ethdb.NewMemDatabase()
+	c := newTestContracts(t, db, &protocol.BokerBackendProto{})
+
+	old, new := common.Address{1}, common.Address{2}
+	if err := c.SetContract(old, protocol.PersonalContract, false, ""); err != nil {
+		t.Fatalf("failed to set contract: %v", err)
+	}
+	update := protocol.ContractUpdate{Old: old, New: new, ContractType: protocol.PersonalContract, Number: 10}
+	if err := c.ScheduleUpdate(update); err != nil {
+		t.Fatalf("failed to schedule update: %v", err)
+	}
+
+	//等待生效的替换包含在合约树中，重新加载后仍然存在
+	reloaded := newTestContracts(t, db, commitTestContracts(t, c, db))
+	if updates := reloaded.GetUpdates(); len(updates) != 1 || updates[0] != update {
+		t.Fatalf("reloaded updates mismatch: have %v, want [%v]", updates, update)
+	}
+
+	//生效区块之前不修改注册表
+	if applied, err := reloaded.ApplyUpdates(9); err != nil || applied != 0 {
+		t.Fatalf("applied before activation: have %d/%v, want 0/nil", applied, err)
+	}
+	if contractType, _ := reloaded.GetContract(old); contractType != protocol.PersonalContract {
+		t.Fatalf("old contract changed before activation: %v", contractType)
+	}
+
+	//生效区块替换合约地址并清空等待列表
+	if applied, err := reloaded.ApplyUpdates(10); err != nil || applied != 1 {
+		t.Fatalf("applied at activation: have %d/%v, want 1/nil", applied, err)
+	}
+	if contractType, _ := reloaded.GetContract(old); contractType != protocol.BinaryContract {
+		t.Errorf("old contract still registered as %v", contractType)
+	}
+	if contractType, err := reloaded.GetContract(new); err != nil || contractType != protocol.PersonalContract {
+		t.Errorf("new contract mismatch: have %v/%v, want %v", contractType, err, protocol.PersonalContract)
+	}
+	if updates := reloaded.GetUpdates(); len(updates) != 0 {
+		t.Errorf("updates left after activation: %v", updates)
+	}
+
+	//替换结果和清空的等待列表同样可以重新加载
+	again := newTestContracts(t, db, commitTestContracts(t, reloaded, db))
+	if contractType, err := again.GetContract(new); err != nil || contractType != protocol.PersonalContract {
+		t.Errorf("reloaded new contract mismatch: have %v/%v, want %v", contractType, err, protocol.PersonalContract)
+	}
+	if updates := again.GetUpdates(); len(updates) != 0 {
+		t.Errorf("reloaded updates left after activation: %v", updates)
+	}
+}
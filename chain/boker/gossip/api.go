@@ -0,0 +1,36 @@
+package gossip
+
+import (
+	"time"
+)
+
+//验证者消息的公开接口
+type PublicGossipAPI struct {
+	g *Gossip
+}
+
+//当前验证者的存活状态以及尚未结束的维护公告
+func (api *PublicGossipAPI) Validators() ([]*ValidatorStatus, error) {
+	return api.g.statuses()
+}
+
+//验证者消息的私有接口
+type PrivateGossipAPI struct {
+	g *Gossip
+}
+
+//以本节点的出块账号发布停机维护公告，start和end为维护开始和结束的Unix时间
+func (api *PrivateGossipAPI) Announce(text string, start uint64, end uint64) (*Message, error) {
+
+	if len(text) > maxTextLength {
+		return nil, errTextTooLong
+	}
+	if end <= start || int64(end) <= time.Now().Unix() {
+		return nil, errMaintenance
+	}
+	msg := &Message{Kind: Maintenance, Start: start, End: end, Text: text}
+	if err := api.g.post(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
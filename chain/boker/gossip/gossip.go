@@ -0,0 +1,331 @@
+//验证者之间通过Whisper交换的链下消息：定期的存活消息以及停机维护公告
+package gossip
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/consensus/dpos"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/eth"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/p2p"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	"github.com/Bokerchain/Boker/chain/rpc"
+	whisper "github.com/Bokerchain/Boker/chain/whisper/whisperv6"
+)
+
+const (
+	Ping        = "ping"        //存活消息
+	Maintenance = "maintenance" //停机维护公告
+
+	pingInterval    = time.Minute      //发送存活消息的时间间隔
+	pollInterval    = time.Second      //读取收到的消息的时间间隔
+	aliveTimeout    = 3 * pingInterval //超过该时间没有收到存活消息则认为验证者离线
+	maxClockDrift   = int64(300)       //消息时间与本地时间最多相差的秒数
+	maxTextLength   = 256              //维护公告说明的最大长度
+	messageTTL      = uint32(120)      //消息在Whisper网络中的存活时间（秒）
+	messageWorkTime = uint32(2)        //计算消息工作量证明的时间（秒）
+)
+
+var (
+	topic         = whisper.BytesToTopic([]byte("bkvg")) //验证者消息的Whisper主题
+	messagePrefix = []byte("boker-validator-gossip")     //消息签名内容的前缀，与区块签名区分
+
+	errNotDpos      = errors.New("validator gossip requires the dpos engine")
+	errNotValidator = errors.New("local signer is not a validator")
+	errSignature    = errors.New("message signature does not match the validator")
+	errUnknownKind  = errors.New("unknown message kind")
+	errMessageTime  = errors.New("message time too far from local time")
+	errMaintenance  = errors.New("maintenance window must end after it starts and in the future")
+	errTextTooLong  = fmt.Errorf("maintenance text longer than %d bytes", maxTextLength)
+)
+
+//验证者之间交换的消息
+type Message struct {
+	Kind      string         `json:"kind"`            //消息类型
+	Validator common.Address `json:"validator"`       //发送消息的验证者
+	Number    uint64         `json:"number"`          //发送时验证者的区块高度
+	Hash      common.Hash    `json:"hash"`            //发送时验证者的区块Hash
+	Time      uint64         `json:"time"`            //发送时间
+	Start     uint64         `json:"start,omitempty"` //维护开始时间
+	End       uint64         `json:"end,omitempty"`   //维护结束时间
+	Text      string         `json:"text,omitempty"`  //维护说明
+	Signature hexutil.Bytes  `json:"signature"`       //验证者出块账号的签名
+}
+
+//消息签名的内容
+func (m *Message) sigHash() []byte {
+
+	enc, _ := rlp.EncodeToBytes([]interface{}{m.Kind, m.Validator, m.Number, m.Hash, m.Time, m.Start, m.End, m.Text})
+	return crypto.Keccak256(messagePrefix, enc)
+}
+
+//校验消息的格式和签名
+func (m *Message) verify(now int64) error {
+
+	switch m.Kind {
+	case Ping:
+	case Maintenance:
+		if m.End <= m.Start || len(m.Text) > maxTextLength {
+			return errMaintenance
+		}
+	default:
+		return errUnknownKind
+	}
+	if drift := now - int64(m.Time); drift > maxClockDrift || drift < -maxClockDrift {
+		return errMessageTime
+	}
+	pubkey, err := crypto.SigToPub(m.sigHash(), m.Signature)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != m.Validator {
+		return errSignature
+	}
+	return nil
+}
+
+//从其他验证者收到的最新状态
+type ValidatorStatus struct {
+	Validator   common.Address `json:"validator"`
+	Alive       bool           `json:"alive"`                 //最近是否收到过存活消息
+	LastPing    *Message       `json:"lastPing,omitempty"`    //最新的存活消息
+	Received    int64          `json:"received,omitempty"`    //收到最新存活消息的本地时间
+	Maintenance *Message       `json:"maintenance,omitempty"` //尚未结束的维护公告
+}
+
+//验证者消息服务
+type Gossip struct {
+	shh    *whisper.Whisper
+	eth    *eth.Ethereum
+	engine *dpos.Dpos
+
+	symKey   []byte //由链的创世区块得到的对称密钥
+	filterID string
+
+	pings       map[common.Address]*Message
+	received    map[common.Address]int64
+	maintenance map[common.Address]*Message
+	mu          sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+//创建验证者消息服务
+func New(shh *whisper.Whisper, ethereum *eth.Ethereum) (*Gossip, error) {
+
+	engine, ok := ethereum.Engine().(*dpos.Dpos)
+	if !ok {
+		return nil, errNotDpos
+	}
+	return &Gossip{
+		shh:         shh,
+		eth:         ethereum,
+		engine:      engine,
+		pings:       make(map[common.Address]*Message),
+		received:    make(map[common.Address]int64),
+		maintenance: make(map[common.Address]*Message),
+	}, nil
+}
+
+func (g *Gossip) Protocols() []p2p.Protocol { return nil }
+
+func (g *Gossip) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "gossip",
+			Version:   "1.0",
+			Service:   &PublicGossipAPI{g},
+			Public:    true,
+		}, {
+			Namespace: "gossip",
+			Version:   "1.0",
+			Service:   &PrivateGossipAPI{g},
+		},
+	}
+}
+
+//订阅验证者消息并开始定期发送存活消息
+func (g *Gossip) Start(server *p2p.Server) error {
+
+	//同一条链的节点使用相同的对称密钥，消息的真实性由验证者签名保证
+	genesis := g.eth.BlockChain().Genesis().Hash()
+	keyID, err := g.shh.AddSymKeyFromPassword("boker-validator-gossip-" + genesis.Hex())
+	if err != nil {
+		return err
+	}
+	if g.symKey, err = g.shh.GetSymKey(keyID); err != nil {
+		return err
+	}
+	filter := &whisper.Filter{
+		KeySym:   g.symKey,
+		Topics:   [][]byte{topic[:]},
+		PoW:      g.shh.MinPow(),
+		AllowP2P: false,
+	}
+	if g.filterID, err = g.shh.Subscribe(filter); err != nil {
+		return err
+	}
+
+	g.quit = make(chan struct{})
+	g.wg.Add(1)
+	go g.loop()
+	log.Info("Validator gossip started", "topic", topic.String())
+	return nil
+}
+
+func (g *Gossip) Stop() error {
+
+	close(g.quit)
+	g.wg.Wait()
+	g.shh.Unsubscribe(g.filterID)
+	log.Info("Validator gossip stopped")
+	return nil
+}
+
+func (g *Gossip) loop() {
+
+	defer g.wg.Done()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+
+	g.sendPing()
+	for {
+		select {
+		case <-ping.C:
+			g.sendPing()
+		case <-poll.C:
+			for _, msg := range g.shh.Messages(g.filterID) {
+				g.handle(msg.Payload)
+			}
+		case <-g.quit:
+			return
+		}
+	}
+}
+
+//本节点的出块账号是验证者时发送存活消息
+func (g *Gossip) sendPing() {
+
+	if err := g.post(&Message{Kind: Ping}); err != nil {
+		log.Trace("Validator ping not sent", "err", err)
+	}
+}
+
+//签名并发送消息
+func (g *Gossip) post(msg *Message) error {
+
+	head := g.eth.BlockChain().CurrentHeader()
+	msg.Number, msg.Hash = head.Number.Uint64(), head.Hash()
+	msg.Time = uint64(time.Now().Unix())
+
+	validators, err := g.engine.Validators(head)
+	if err != nil {
+		return err
+	}
+	msg.Validator = g.engine.Signer()
+	if !containsAddress(validators, msg.Validator) {
+		return errNotValidator
+	}
+	if msg.Signature, err = g.engine.SignHash(msg.sigHash()); err != nil {
+		return err
+	}
+	payload, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return err
+	}
+	params := &whisper.MessageParams{
+		TTL:      messageTTL,
+		KeySym:   g.symKey,
+		Topic:    topic,
+		Payload:  payload,
+		PoW:      g.shh.MinPow(),
+		WorkTime: messageWorkTime,
+	}
+	sent, err := whisper.NewSentMessage(params)
+	if err != nil {
+		return err
+	}
+	env, err := sent.Wrap(params)
+	if err != nil {
+		return err
+	}
+	return g.shh.Send(env)
+}
+
+//处理收到的消息，只接受当前验证者签名的消息
+func (g *Gossip) handle(payload []byte) {
+
+	msg := new(Message)
+	if err := rlp.DecodeBytes(payload, msg); err != nil {
+		log.Debug("Invalid validator gossip message", "err", err)
+		return
+	}
+	now := time.Now().Unix()
+	if err := msg.verify(now); err != nil {
+		log.Debug("Invalid validator gossip message", "validator", msg.Validator, "err", err)
+		return
+	}
+	validators, err := g.engine.Validators(g.eth.BlockChain().CurrentHeader())
+	if err != nil || !containsAddress(validators, msg.Validator) {
+		log.Debug("Validator gossip message from non validator", "address", msg.Validator)
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch msg.Kind {
+	case Ping:
+		if last := g.pings[msg.Validator]; last == nil || last.Time < msg.Time {
+			g.pings[msg.Validator], g.received[msg.Validator] = msg, now
+		}
+	case Maintenance:
+		if last := g.maintenance[msg.Validator]; last == nil || last.Time < msg.Time {
+			g.maintenance[msg.Validator] = msg
+			log.Info("Validator announced maintenance", "validator", msg.Validator, "start", time.Unix(int64(msg.Start), 0), "end", time.Unix(int64(msg.End), 0), "text", msg.Text)
+		}
+	}
+}
+
+//当前验证者的最新状态
+func (g *Gossip) statuses() ([]*ValidatorStatus, error) {
+
+	validators, err := g.engine.Validators(g.eth.BlockChain().CurrentHeader())
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	statuses := make([]*ValidatorStatus, 0, len(validators))
+	for _, validator := range validators {
+		status := &ValidatorStatus{Validator: validator, LastPing: g.pings[validator]}
+		if status.LastPing != nil {
+			status.Received = g.received[validator]
+			status.Alive = now-status.Received < int64(aliveTimeout/time.Second)
+		}
+		if m := g.maintenance[validator]; m != nil && int64(m.End) > now {
+			status.Maintenance = m
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
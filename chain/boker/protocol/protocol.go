@@ -18,19 +18,21 @@ import (
 )
 
 const (
-	ExtraVanity        = 32               //扩展字段的前缀字节数量
-	ExtraSeal          = 65               //扩展字段的后缀字节数量
-	InmemorySignatures = 4096             //保留在内存中的最近块签名的数量
-	ProducerInterval   = int64(5)         //打包时间间隔（秒）
-	TokenNoderInterval = int64(300)       //分配通证时间间隔(秒)
-	EpochInterval      = int64(86400)     //一个周期的时间（86400秒 = 1天）
-	MaxValidatorSize   = 1                //DPOS的验证者数量
-	SafeSize           = 1                //安全的验证者数量
-	ConsensusSize      = 1                //共识确认验证者数量
-	BokerInterval      = time.Second      //分配通证时间间隔(秒)
-	AssignTickInterval = time.Millisecond //分配通证时间间隔(秒)
-	AssignInterval     = time.Minute      //分配通证时间间隔单位
-	AssignTimer        = 5
+	ExtraVanity            = 32               //扩展字段的前缀字节数量
+	ExtraSeal              = 65               //扩展字段的后缀字节数量
+	InmemorySignatures     = 4096             //保留在内存中的最近块签名的数量
+	InmemoryEpochSnapshots = 128              //保留在内存中的最近周期验证者快照的数量
+	ProducerInterval       = int64(5)         //打包时间间隔（秒）
+	TokenNoderInterval     = int64(300)       //分配通证时间间隔(秒)
+	EpochInterval          = int64(86400)     //一个周期的时间（86400秒 = 1天）
+	MaxValidatorSize       = 1                //DPOS的验证者数量
+	SafeSize               = 1                //安全的验证者数量
+	ConsensusSize          = 1                //共识确认验证者数量
+	CheckpointWindow       = 12               //周期边界区块的检查点签名可以在其后多少个区块内被打包
+	BokerInterval          = time.Second      //分配通证时间间隔(秒)
+	AssignTickInterval     = time.Millisecond //分配通证时间间隔(秒)
+	AssignInterval         = time.Minute      //分配通证时间间隔单位
+	AssignTimer            = 5
 )
 
 //新增多个交易类型
@@ -59,8 +61,57 @@ const (
 
 	/****系统基础合约交易类型****/
 	AssignToken //分配通证(每次分配通证的时候触发)
+
+	//替换基础合约地址(需要超过三分之二的验证者签名，在指定区块生效)
+	UpdateContract
 )
 
+var txTypeNames = [...]string{
+	Binary:                 "Binary",
+	SetValidator:           "SetValidator",
+	SetPersonalContract:    "SetPersonalContract",
+	CancelPersonalContract: "CancelPersonalContract",
+	SetSystemContract:      "SetSystemContract",
+	CancelSystemContract:   "CancelSystemContract",
+	RegisterCandidate:      "RegisterCandidate",
+	VoteUser:               "VoteUser",
+	VoteCancel:             "VoteCancel",
+	VoteEpoch:              "VoteEpoch",
+	UserEvent:              "UserEvent",
+	AssignToken:            "AssignToken",
+	UpdateContract:         "UpdateContract",
+}
+
+//交易类型的名称，未知类型输出数值
+func (t TxType) String() string {
+	if int(t) < len(txTypeNames) {
+		return txTypeNames[t]
+	}
+	return fmt.Sprintf("TxType(%d)", uint8(t))
+}
+
+//判断是否是由用户发起的免Gas基础合约交易（这类交易不收取Gas，需要进行限流）
+func IsGasFreeUserTx(txType TxType) bool {
+
+	switch txType {
+	case RegisterCandidate, VoteUser, VoteCancel, UserEvent:
+		return true
+	default:
+		return false
+	}
+}
+
+//判断是否是由分币节点发起的免Gas系统基础合约交易（这类交易只在发起时所在的分币时间片内有效）
+func IsGasFreeSystemTx(txType TxType) bool {
+
+	switch txType {
+	case VoteEpoch, AssignToken:
+		return true
+	default:
+		return false
+	}
+}
+
 //新增合约类型
 type ContractType uint8
 
@@ -97,13 +148,22 @@ var (
 )
 
 var (
-	EpochPrefix     = []byte("epoch-")      //存放周期信息
-	ValidatorPrefix = []byte("validator-")  //存放验证者投票信息
-	BlockCntPrefix  = []byte("blockCnt-")   //存放投票数量
-	SinglePrefix    = []byte("single-")     //
-	AbiPrefix       = []byte("abi-")        //
-	ValidatorsKey   = []byte("validators-") //存放所有的验证者列表
-	Contracts       = []byte("contracts-")  //
+	//基础合约交易执行成功后生成的事件日志主题，第二个主题为交易发起人的地址
+	VoteCastTopic            = eventTopic("VoteCast(address,bytes)")            //用户投票，数据为投票调用的输入
+	CandidateRegisteredTopic = eventTopic("CandidateRegistered(address,bytes)") //注册候选人，数据为注册调用的输入
+	TokenAssignedTopic       = eventTopic("TokenAssigned(address,uint256)")     //分配通证，数据为分配的通证数量
+)
+
+var (
+	EpochPrefix         = []byte("epoch-")      //存放周期信息
+	ValidatorPrefix     = []byte("validator-")  //存放验证者投票信息
+	BlockCntPrefix      = []byte("blockCnt-")   //存放投票数量
+	SinglePrefix        = []byte("single-")     //
+	AbiPrefix           = []byte("abi-")        //
+	ValidatorsKey       = []byte("validators-") //存放所有的验证者列表
+	EpochSnapshotPrefix = []byte("dpos-epoch-") //存放每个周期的验证者快照（周期号 -> 快照）
+	Contracts           = []byte("contracts-")  //
+	ContractUpdates     = []byte("updates-")    //存放等待生效的基础合约替换
 )
 
 var (
@@ -145,8 +205,18 @@ var (
 	ErrEpochTrieNil               = errors.New("failed to producers length is zero")
 	ErrToIsNil                    = errors.New("setValidator block header to is nil")
 	ErrTxType                     = errors.New("failed to tx type")
+	ErrUpdateScheduled            = errors.New("base contract update already scheduled")     //同一个合约地址已经有等待生效的替换
+	ErrUpdateExpired              = errors.New("base contract update block already reached") //替换生效的区块已经过去
 )
 
+//验证者签名的基础合约地址替换，在Number区块结束时生效
+type ContractUpdate struct {
+	Old          common.Address
+	New          common.Address
+	ContractType ContractType
+	Number       uint64
+}
+
 //设置播客链配置
 type BokerConfig struct {
 	Address common.Address
@@ -158,6 +228,30 @@ type BokerBackendProto struct {
 	ContracAbiHash common.Hash `json:"ContractABIRoot"    gencodec:"required"`
 }
 
+//计算事件签名对应的日志主题
+func eventTopic(signature string) (h common.Hash) {
+
+	hw := sha3.NewKeccak256()
+	hw.Write([]byte(signature))
+	hw.Sum(h[:0])
+	return h
+}
+
+//得到基础合约交易生成的事件日志主题，不生成事件日志的交易类型返回false
+func BaseEventTopic(txType TxType) (common.Hash, bool) {
+
+	switch txType {
+	case VoteUser:
+		return VoteCastTopic, true
+	case RegisterCandidate:
+		return CandidateRegisteredTopic, true
+	case AssignToken:
+		return TokenAssignedTopic, true
+	default:
+		return common.Hash{}, false
+	}
+}
+
 func (p *BokerBackendProto) Root() (h common.Hash) {
 
 	hw := sha3.NewKeccak256()
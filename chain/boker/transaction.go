@@ -6,6 +6,7 @@ import (
 	"math/big"
 
 	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/accounts/keystore"
 	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
@@ -72,14 +73,14 @@ func (t *BokerTransaction) SubmitBokerTransaction(ctx context.Context, txType pr
 		tx := types.NewBaseTransaction(args.Type, (uint64)(*args.Nonce), (common.Address)(*args.To), (*big.Int)(args.Value), input)
 
 		var chainID *big.Int
-		if config := t.ethereum.ApiBackend.ChainConfig(); config.IsEIP155(t.ethereum.ApiBackend.CurrentBlock().Number()) {
+		if config := t.ethereum.ApiBackend.ChainConfig(); config.IsReplayProtected(t.ethereum.ApiBackend.CurrentBlock().Number()) {
 			chainID = config.ChainId
 		}
 
 		//对该笔交易签名来确保该笔交易的真实有效性
-		signed, err := wallet.SignTxWithPassphrase(account, t.ethereum.Password(), tx, chainID)
+		signed, err := signBokerTransaction(wallet, account, t.ethereum.Password(), tx, chainID)
 		if err != nil {
-			log.Error("(t *BokerTransaction) SubmitBokerTransaction SignTxWithPassphrase", "error", err)
+			log.Error("(t *BokerTransaction) SubmitBokerTransaction signBokerTransaction", "error", err)
 			return nil, err
 		}
 
@@ -92,3 +93,13 @@ func (t *BokerTransaction) SubmitBokerTransaction(ctx context.Context, txType pr
 	}
 	return nil, protocol.ErrInvalidSystem
 }
+
+//对交易签名，外部签名器和已经解锁的账号直接签名，本地账号没有解锁或者只解锁了出块签名时使用挖矿账号的密码签名
+func signBokerTransaction(wallet accounts.Wallet, account accounts.Account, password string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+
+	signed, err := wallet.SignTx(account, tx, chainID)
+	if err != keystore.ErrLocked && err != keystore.ErrSealOnly {
+		return signed, err
+	}
+	return wallet.SignTxWithPassphrase(account, password, tx, chainID)
+}
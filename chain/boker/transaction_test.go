@@ -0,0 +1,134 @@
+package boker
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/accounts/external"
+	"github.com/Bokerchain/Boker/chain/accounts/keystore"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	"github.com/Bokerchain/Boker/chain/rpc"
+)
+
+//只持有一个私钥的外部签名器
+type MockExternalSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *MockExternalSigner) Version() string {
+	return "1.0.0"
+}
+
+func (s *MockExternalSigner) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PublicKey)}
+}
+
+func (s *MockExternalSigner) SignTransaction(args external.SendTxArgs) (map[string]interface{}, error) {
+	if args.To == nil {
+		return nil, errors.New("contract creation not allowed")
+	}
+	tx := types.NewTransaction(args.Type, uint64(args.Nonce), *args.To, args.Value.ToInt(), args.Gas.ToInt(), args.GasPrice.ToInt(), args.Data)
+	tx.SetTimestamp(args.Timestamp.ToInt().Int64())
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(args.ChainID.ToInt()), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": hexutil.Bytes(raw)}, nil
+}
+
+//检查交易由指定账号签名
+func checkBokerTxSender(t *testing.T, name string, signed *types.Transaction, chainID *big.Int, want common.Address) {
+
+	if from, err := types.Sender(types.NewEIP155Signer(chainID), signed); err != nil || from != want {
+		t.Errorf("%s: sender mismatch: have %x, want %x (%v)", name, from, want, err)
+	}
+}
+
+//外部签名器不支持使用密码签名，基础合约交易直接由外部签名器签名
+func TestSignBokerTransactionExternal(t *testing.T) {
+
+	key, _ := crypto.GenerateKey()
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", &MockExternalSigner{key: key}); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	backend, err := external.NewExternalBackend(httpServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := backend.Wallets()[0]
+	account := accounts.Account{Address: crypto.PubkeyToAddress(key.PublicKey)}
+
+	chainID := big.NewInt(1)
+	tx := types.NewBaseTransaction(protocol.SetSystemContract, 0, common.Address{1}, new(big.Int), nil)
+	signed, err := signBokerTransaction(wallet, account, "", tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign with external signer: %v", err)
+	}
+	checkBokerTxSender(t, "external", signed, chainID, account.Address)
+}
+
+//本地账号解锁时直接签名，没有解锁或者只解锁出块签名时使用密码签名
+func TestSignBokerTransactionKeystore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "boker-keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := ks.Wallets()[0]
+	chainID := big.NewInt(1)
+	tx := types.NewBaseTransaction(protocol.SetSystemContract, 0, common.Address{1}, new(big.Int), nil)
+
+	//没有解锁时使用密码签名，密码错误时失败
+	signed, err := signBokerTransaction(wallet, account, "password", tx, chainID)
+	if err != nil {
+		t.Fatalf("locked: failed to sign with password: %v", err)
+	}
+	checkBokerTxSender(t, "locked", signed, chainID, account.Address)
+	if _, err := signBokerTransaction(wallet, account, "wrong", tx, chainID); err == nil {
+		t.Errorf("locked: expected error for wrong password")
+	}
+
+	//只解锁出块签名时同样使用密码签名
+	if err := ks.TimedUnlockSealOnly(account, "password", 0); err != nil {
+		t.Fatal(err)
+	}
+	if signed, err = signBokerTransaction(wallet, account, "password", tx, chainID); err != nil {
+		t.Fatalf("seal only: failed to sign with password: %v", err)
+	}
+	checkBokerTxSender(t, "seal only", signed, chainID, account.Address)
+
+	//解锁之后不需要密码
+	if err := ks.Unlock(account, "password"); err != nil {
+		t.Fatal(err)
+	}
+	if signed, err = signBokerTransaction(wallet, account, "", tx, chainID); err != nil {
+		t.Fatalf("unlocked: failed to sign: %v", err)
+	}
+	checkBokerTxSender(t, "unlocked", signed, chainID, account.Address)
+}
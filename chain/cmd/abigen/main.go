@@ -21,7 +21,7 @@ var (
 	solcFlag = flag.String("solc", "solc", "Solidity compiler to use if source builds are requested")
 	excFlag  = flag.String("exc", "", "Comma separated types to exclude from binding")
 
-	pkgFlag  = flag.String("pkg", "", "Package name to generate the binding into")
+	pkgFlag  = flag.String("pkg", "", "Package name to generate the binding into (class name prefix for objc)")
 	outFlag  = flag.String("out", "", "Output file for the generated binding (default = stdout)")
 	langFlag = flag.String("lang", "go", "Destination language for the bindings (go, java, objc)")
 )
@@ -28,9 +28,11 @@ import (
 	"flag"
 	"fmt"
 	"html/template"
+	"io"
 	"io/ioutil"
 	"math"
 	"math/big"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -45,6 +47,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/accounts/keystore"
 	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/eth"
@@ -59,6 +62,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/p2p/discv5"
 	"github.com/Bokerchain/Boker/chain/p2p/nat"
 	"github.com/Bokerchain/Boker/chain/params"
+	"github.com/Bokerchain/Boker/chain/rpc"
 	"golang.org/x/net/websocket"
 )
 
@@ -69,6 +73,7 @@ var (
 	bootFlag      = flag.String("bootnodes", "", "Comma separated bootnode enode URLs to seed with")
 	netFlag       = flag.Uint64("network", 0, "Network ID to use for the Ethereum protocol")
 	statsFlag     = flag.String("ethstats", "", "Ethstats network monitoring auth string")
+	rpcFlag       = flag.String("rpc", "", "WebSocket or IPC endpoint of a Boker node to use instead of a light client")
 	netnameFlag   = flag.String("faucet.name", "", "Network name to assign to the faucet")
 	payoutFlag    = flag.Int("faucet.amount", 1, "Number of Ethers to pay out per user request")
 	minutesFlag   = flag.Int("faucet.minutes", 1440, "Number of minutes to wait between funding rounds")
@@ -79,11 +84,18 @@ var (
 	githubToken   = flag.String("github.token", "", "GitHub personal token to access Gists with")
 	captchaToken  = flag.String("captcha.token", "", "Recaptcha site key to authenticate client side")
 	captchaSecret = flag.String("captcha.secret", "", "Recaptcha secret key to authenticate server side")
+	directFlag    = flag.Bool("faucet.direct", false, "Accept funding requests for plain addresses over the HTTP API")
+	proxiedFlag   = flag.Bool("faucet.proxied", false, "Trust the X-Forwarded-For header of a reverse proxy for IP cooldowns")
 	logFlag       = flag.Int("loglevel", 3, "Log level to use for Ethereum and the faucet")
 )
 
 var (
 	ether = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+	errRobot = errors.New("Beep-bop, you're a robot!")
+
+	// captchaVerifyURL is the endpoint checking reCaptcha responses.
+	captchaVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
 )
 
 func main() {
@@ -91,6 +103,10 @@ func main() {
 	flag.Parse()
 	log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(*logFlag), log.StreamHandler(os.Stderr, log.TerminalFormat(true))))
 
+	if err := checkFlags(); err != nil {
+		log.Crit("Invalid faucet configuration", "err", err)
+	}
+
 	// Construct the payout tiers
 	amounts := make([]string, *tiersFlag)
 	periods := make([]string, *tiersFlag)
@@ -166,8 +182,13 @@ func main() {
 	}
 	ks.Unlock(acc, pass)
 
-	// Assemble and start the faucet light service
-	faucet, err := newFaucet(genesis, *ethPortFlag, enodes, *netFlag, *statsFlag, ks, website.Bytes())
+	// Assemble and start the faucet light service, or attach to a remote node
+	var faucet *faucet
+	if *rpcFlag != "" {
+		faucet, err = dialFaucet(genesis, *rpcFlag, ks, website.Bytes())
+	} else {
+		faucet, err = newFaucet(genesis, *ethPortFlag, enodes, *netFlag, *statsFlag, ks, website.Bytes())
+	}
 	if err != nil {
 		log.Crit("Failed to start faucet", "err", err)
 	}
@@ -178,6 +199,16 @@ func main() {
 	}
 }
 
+// checkFlags rejects flag combinations that would leave the faucet open to
+// abuse. Direct funding requests carry no social network identity to rate
+// limit, so the API is only served with captcha verifications enabled.
+func checkFlags() error {
+	if *directFlag && (*captchaToken == "" || *captchaSecret == "") {
+		return errors.New("--faucet.direct requires --captcha.token and --captcha.secret")
+	}
+	return nil
+}
+
 // request represents an accepted funding request.
 type request struct {
 	Avatar  string             `json:"avatar"`  // Avatar URL to make the UI nicer
@@ -186,10 +217,12 @@ type request struct {
 	Tx      *types.Transaction `json:"tx"`      // Transaction funding the account
 }
 
-// faucet represents a crypto faucet backed by an Ethereum light client.
+// faucet represents a crypto faucet backed by an Ethereum light client or by
+// the RPC endpoint of a remote node.
 type faucet struct {
 	config *params.ChainConfig // Chain configurations for signing
-	stack  *node.Node          // Ethereum protocol stack
+	stack  *node.Node          // Ethereum protocol stack (nil if attached over RPC)
+	rpc    *rpc.Client         // RPC connection to the Ethereum node
 	client *ethclient.Client   // Client connection to the Ethereum chain
 	index  []byte              // Index page to serve up on the web
 
@@ -264,6 +297,7 @@ func newFaucet(genesis *core.Genesis, port int, enodes []*discv5.Node, network u
 	return &faucet{
 		config:   genesis.Config,
 		stack:    stack,
+		rpc:      api,
 		client:   client,
 		index:    index,
 		keystore: ks,
@@ -273,11 +307,50 @@ func newFaucet(genesis *core.Genesis, port int, enodes []*discv5.Node, network u
 	}, nil
 }
 
+// dialFaucet creates a faucet funding requests through the RPC endpoint of an
+// already running node. The endpoint needs to support subscriptions, so only
+// WebSocket and IPC endpoints are usable.
+func dialFaucet(genesis *core.Genesis, endpoint string, ks *keystore.KeyStore, index []byte) (*faucet, error) {
+	api, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &faucet{
+		config:   genesis.Config,
+		rpc:      api,
+		client:   ethclient.NewClient(api),
+		index:    index,
+		keystore: ks,
+		account:  ks.Accounts()[0],
+		timeouts: make(map[string]time.Time),
+		update:   make(chan struct{}, 1),
+	}, nil
+}
+
 // close terminates the Ethereum connection and tears down the faucet.
 func (f *faucet) close() error {
+	if f.stack == nil {
+		f.rpc.Close()
+		return nil
+	}
 	return f.stack.Stop()
 }
 
+// peers returns the number of peers the backing node is connected to.
+func (f *faucet) peers() int {
+	if f.stack != nil {
+		return f.stack.Server().PeerCount()
+	}
+	var count hexutil.Uint
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := f.rpc.CallContext(ctx, &count, "net_peerCount"); err != nil {
+		log.Warn("Failed to retrieve peer count", "err", err)
+	}
+	return int(count)
+}
+
 // listenAndServe registers the HTTP handlers for the faucet and boots it up
 // for service user funding requests.
 func (f *faucet) listenAndServe(port int) error {
@@ -285,6 +358,9 @@ func (f *faucet) listenAndServe(port int) error {
 
 	http.HandleFunc("/", f.webHandler)
 	http.Handle("/api", websocket.Handler(f.apiHandler))
+	if *directFlag {
+		http.HandleFunc("/api/fund", f.fundHandler)
+	}
 
 	return http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
 }
@@ -353,7 +429,7 @@ func (f *faucet) apiHandler(conn *websocket.Conn) {
 	if err = send(conn, map[string]interface{}{
 		"funds":    balance.Div(balance, ether),
 		"funded":   nonce,
-		"peers":    f.stack.Server().PeerCount(),
+		"peers":    f.peers(),
 		"requests": f.reqs,
 	}, 3*time.Second); err != nil {
 		log.Warn("Failed to send initial stats to client", "err", err)
@@ -392,40 +468,12 @@ func (f *faucet) apiHandler(conn *websocket.Conn) {
 		log.Info("Faucet funds requested", "url", msg.URL, "tier", msg.Tier)
 
 		// If captcha verifications are enabled, make sure we're not dealing with a robot
-		if *captchaToken != "" {
-			form := url.Values{}
-			form.Add("secret", *captchaSecret)
-			form.Add("response", msg.Captcha)
-
-			res, err := http.PostForm("https://www.google.com/recaptcha/api/siteverify", form)
-			if err != nil {
-				if err = sendError(conn, err); err != nil {
-					log.Warn("Failed to send captcha post error to client", "err", err)
-					return
-				}
-				continue
-			}
-			var result struct {
-				Success bool            `json:"success"`
-				Errors  json.RawMessage `json:"error-codes"`
-			}
-			err = json.NewDecoder(res.Body).Decode(&result)
-			res.Body.Close()
-			if err != nil {
-				if err = sendError(conn, err); err != nil {
-					log.Warn("Failed to send captcha decode error to client", "err", err)
-					return
-				}
-				continue
-			}
-			if !result.Success {
-				log.Warn("Captcha verification failed", "err", string(result.Errors))
-				if err = sendError(conn, errors.New("Beep-bop, you're a robot!")); err != nil {
-					log.Warn("Failed to send captcha failure to client", "err", err)
-					return
-				}
-				continue
+		if err = verifyCaptcha(msg.Captcha); err != nil {
+			if err = sendError(conn, err); err != nil {
+				log.Warn("Failed to send captcha error to client", "err", err)
+				return
 			}
+			continue
 		}
 		// Retrieve the Ethereum address to fund, the requesting user and a profile picture
 		var (
@@ -454,52 +502,9 @@ func (f *faucet) apiHandler(conn *websocket.Conn) {
 		}
 		log.Info("Faucet request valid", "url", msg.URL, "tier", msg.Tier, "user", username, "address", address)
 
-		// Ensure the user didn't request funds too recently
-		f.lock.Lock()
-		var (
-			fund    bool
-			timeout time.Time
-		)
-		if timeout = f.timeouts[username]; time.Now().After(timeout) {
-			// User wasn't funded recently, create the funding transaction
-			amount := new(big.Int).Mul(big.NewInt(int64(*payoutFlag)), ether)
-			amount = new(big.Int).Mul(amount, new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(msg.Tier)), nil))
-			amount = new(big.Int).Div(amount, new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(msg.Tier)), nil))
-
-			tx := types.NewTransaction(protocol.Binary, f.nonce+uint64(len(f.reqs)), address, amount, big.NewInt(21000), f.price, nil)
-			signed, err := f.keystore.SignTx(f.account, tx, f.config.ChainId)
-			if err != nil {
-				f.lock.Unlock()
-				if err = sendError(conn, err); err != nil {
-					log.Warn("Failed to send transaction creation error to client", "err", err)
-					return
-				}
-				continue
-			}
-
-			//如果成功，提交交易并标记为资助
-			if err := f.client.SendTransaction(context.Background(), signed); err != nil {
-				f.lock.Unlock()
-				if err = sendError(conn, err); err != nil {
-					log.Warn("Failed to send transaction transmission error to client", "err", err)
-					return
-				}
-				continue
-			}
-			f.reqs = append(f.reqs, &request{
-				Avatar:  avatar,
-				Account: address,
-				Time:    time.Now(),
-				Tx:      signed,
-			})
-			f.timeouts[username] = time.Now().Add(time.Duration(*minutesFlag*int(math.Pow(3, float64(msg.Tier)))) * time.Minute)
-			fund = true
-		}
-		f.lock.Unlock()
-
-		// Send an error if too frequent funding, othewise a success
-		if !fund {
-			if err = sendError(conn, fmt.Errorf("%s left until next allowance", common.PrettyDuration(timeout.Sub(time.Now())))); err != nil {
+		// Ensure neither the user, the address nor the IP requested funds too recently
+		if err = f.fund(username, avatar, address, requestIP(conn.Request()), msg.Tier); err != nil {
+			if err = sendError(conn, err); err != nil {
 				log.Warn("Failed to send funding error to client", "err", err)
 				return
 			}
@@ -516,6 +521,98 @@ func (f *faucet) apiHandler(conn *websocket.Conn) {
 	}
 }
 
+// fundHandler handles funding requests for plain addresses made over the HTTP
+// API. Without a social network account to rate limit, requests are throttled
+// by the funded address and the requesting IP only, so the API is only served
+// with captcha verifications enabled (see checkFlags).
+func (f *faucet) fundHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST requests are accepted", http.StatusMethodNotAllowed)
+		return
+	}
+	var msg struct {
+		Address common.Address `json:"address"`
+		Tier    uint           `json:"tier"`
+		Captcha string         `json:"captcha"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&msg); err != nil {
+		replyError(w, http.StatusBadRequest, err)
+		return
+	}
+	if msg.Address == (common.Address{}) {
+		replyError(w, http.StatusBadRequest, errors.New("No address to fund"))
+		return
+	}
+	if msg.Tier >= uint(*tiersFlag) {
+		replyError(w, http.StatusBadRequest, errors.New("Invalid funding tier requested"))
+		return
+	}
+	if err := verifyCaptcha(msg.Captcha); err != nil {
+		replyError(w, http.StatusForbidden, err)
+		return
+	}
+	ip := requestIP(r)
+	log.Info("Faucet funds requested", "address", msg.Address, "tier", msg.Tier, "ip", ip)
+
+	if err := f.fund(msg.Address.Hex(), "", msg.Address, ip, msg.Tier); err != nil {
+		replyError(w, http.StatusTooManyRequests, err)
+		return
+	}
+	select {
+	case f.update <- struct{}{}:
+	default:
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"success": fmt.Sprintf("Funding request accepted for %s", msg.Address.Hex())})
+}
+
+// fund sends the amount of the given tier to an address, unless the requesting
+// user, the funded address or the requesting IP was funded too recently.
+func (f *faucet) fund(username string, avatar string, address common.Address, ip string, tier uint) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// Find the latest timeout of any of the identities behind the request
+	keys := []string{"user:" + username, "address:" + address.Hex()}
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	var timeout time.Time
+	for _, key := range keys {
+		if t := f.timeouts[key]; t.After(timeout) {
+			timeout = t
+		}
+	}
+	if time.Now().Before(timeout) {
+		return fmt.Errorf("%s left until next allowance", common.PrettyDuration(timeout.Sub(time.Now())))
+	}
+	// Nobody was funded recently, create the funding transaction
+	amount := new(big.Int).Mul(big.NewInt(int64(*payoutFlag)), ether)
+	amount = new(big.Int).Mul(amount, new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(tier)), nil))
+	amount = new(big.Int).Div(amount, new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(tier)), nil))
+
+	tx := types.NewTransaction(protocol.Binary, f.nonce+uint64(len(f.reqs)), address, amount, big.NewInt(21000), f.price, nil)
+	signed, err := f.keystore.SignTx(f.account, tx, f.config.ChainId)
+	if err != nil {
+		return err
+	}
+	// Submit the transaction and mark all identities as funded if successful
+	if err := f.client.SendTransaction(context.Background(), signed); err != nil {
+		return err
+	}
+	f.reqs = append(f.reqs, &request{
+		Avatar:  avatar,
+		Account: address,
+		Time:    time.Now(),
+		Tx:      signed,
+	})
+	timeout = time.Now().Add(time.Duration(*minutesFlag*int(math.Pow(3, float64(tier)))) * time.Minute)
+	for _, key := range keys {
+		f.timeouts[key] = timeout
+	}
+	return nil
+}
+
 // loop keeps waiting for interesting events and pushes them out to connected
 // websockets.
 func (f *faucet) loop() {
@@ -569,7 +666,7 @@ func (f *faucet) loop() {
 				if err := send(conn, map[string]interface{}{
 					"funds":    balance,
 					"funded":   f.nonce,
-					"peers":    f.stack.Server().PeerCount(),
+					"peers":    f.peers(),
 					"requests": f.reqs,
 				}, time.Second); err != nil {
 					log.Warn("Failed to send stats to client", "err", err)
@@ -619,6 +716,59 @@ func sendSuccess(conn *websocket.Conn, msg string) error {
 	return send(conn, map[string]string{"success": msg}, time.Second)
 }
 
+// replyError responds to an HTTP API request with the given status and error.
+func replyError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// requestIP returns the IP address a request originates from. The address set
+// by a reverse proxy is only trusted if the faucet is configured to run behind
+// one, otherwise clients could dodge the IP cooldown by forging the header.
+func requestIP(r *http.Request) string {
+	if *proxiedFlag {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// verifyCaptcha checks a reCaptcha response against the Google servers if the
+// captcha verifications are enabled.
+func verifyCaptcha(response string) error {
+	if *captchaToken == "" {
+		return nil
+	}
+	form := url.Values{}
+	form.Add("secret", *captchaSecret)
+	form.Add("response", response)
+
+	res, err := http.PostForm(captchaVerifyURL, form)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var result struct {
+		Success bool            `json:"success"`
+		Errors  json.RawMessage `json:"error-codes"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.Success {
+		log.Warn("Captcha verification failed", "err", string(result.Errors))
+		return errRobot
+	}
+	return nil
+}
+
 // authGitHub tries to authenticate a faucet request using GitHub gists, returning
 // the username, avatar URL and Ethereum address to fund on success.
 func authGitHub(url string) (string, string, common.Address, error) {
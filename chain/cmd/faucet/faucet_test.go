@@ -0,0 +1,263 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/accounts/keystore"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/ethclient"
+	"github.com/Bokerchain/Boker/chain/params"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	"github.com/Bokerchain/Boker/chain/rpc"
+)
+
+// TestEthService is a minimal eth namespace collecting the funding transactions
+// submitted by the faucet.
+type TestEthService struct {
+	lock sync.Mutex
+	txs  []*types.Transaction
+}
+
+func (s *TestEthService) SendRawTransaction(encodedTx hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.txs = append(s.txs, tx)
+	return tx.Hash(), nil
+}
+
+func (s *TestEthService) sent() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.txs)
+}
+
+// newTestFaucet creates a faucet funding requests from an unlocked keystore
+// account and submitting the transactions to an in-process eth service.
+func newTestFaucet(t *testing.T) (*faucet, *TestEthService, func()) {
+	dir, err := ioutil.TempDir("", "faucet-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatal(err)
+	}
+	service := new(TestEthService)
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+
+	f := &faucet{
+		config:   params.TestChainConfig,
+		rpc:      client,
+		client:   ethclient.NewClient(client),
+		keystore: ks,
+		account:  account,
+		price:    big.NewInt(1),
+		timeouts: make(map[string]time.Time),
+		update:   make(chan struct{}, 1),
+	}
+	return f, service, func() {
+		client.Close()
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+// withFlags overrides the captcha and direct funding flags, returning a function
+// restoring the original values.
+func withFlags(direct bool, token, secret string) func() {
+	oldDirect, oldToken, oldSecret := *directFlag, *captchaToken, *captchaSecret
+	*directFlag, *captchaToken, *captchaSecret = direct, token, secret
+
+	return func() {
+		*directFlag, *captchaToken, *captchaSecret = oldDirect, oldToken, oldSecret
+	}
+}
+
+// Tests that direct funding requests cannot be enabled without captcha
+// verifications.
+func TestCheckFlags(t *testing.T) {
+	tests := []struct {
+		direct bool
+		token  string
+		secret string
+		ok     bool
+	}{
+		{false, "", "", true},
+		{false, "token", "secret", true},
+		{true, "token", "secret", true},
+		{true, "", "", false},
+		{true, "token", "", false},
+		{true, "", "secret", false},
+	}
+	for i, tt := range tests {
+		restore := withFlags(tt.direct, tt.token, tt.secret)
+		err := checkFlags()
+		restore()
+
+		if (err == nil) != tt.ok {
+			t.Errorf("test %d: error mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+	}
+}
+
+// Tests that funding a request puts the user, the address and the IP on
+// cooldown, and that any of them being on cooldown refuses new requests.
+func TestFundCooldownKeys(t *testing.T) {
+	f, service, teardown := newTestFaucet(t)
+	defer teardown()
+
+	var (
+		addr   = common.HexToAddress("0x01")
+		other  = common.HexToAddress("0x02")
+		third  = common.HexToAddress("0x03")
+		fourth = common.HexToAddress("0x04")
+	)
+	if err := f.fund("alice", "", addr, "10.0.0.1", 0); err != nil {
+		t.Fatalf("failed to fund first request: %v", err)
+	}
+	for _, key := range []string{"user:alice", "address:" + addr.Hex(), "ip:10.0.0.1"} {
+		if _, ok := f.timeouts[key]; !ok {
+			t.Errorf("cooldown key %q missing", key)
+		}
+	}
+	// Any identity of the funded request being reused must be refused
+	refused := []struct {
+		user string
+		addr common.Address
+		ip   string
+	}{
+		{"alice", other, "10.0.0.2"}, // same user
+		{"bob", addr, "10.0.0.2"},    // same address
+		{"bob", other, "10.0.0.1"},   // same IP
+	}
+	for i, tt := range refused {
+		if err := f.fund(tt.user, "", tt.addr, tt.ip, 0); err == nil {
+			t.Errorf("request %d: funded despite cooldown", i)
+		}
+	}
+	// Requests without an IP must not be throttled by an empty IP key
+	if err := f.fund("bob", "", other, "", 0); err != nil {
+		t.Fatalf("failed to fund request without IP: %v", err)
+	}
+	if _, ok := f.timeouts["ip:"]; ok {
+		t.Errorf("cooldown set for empty IP")
+	}
+	if err := f.fund("carol", "", third, "", 0); err != nil {
+		t.Fatalf("failed to fund second request without IP: %v", err)
+	}
+	// Expired cooldowns must allow funding again
+	f.timeouts["user:dave"] = time.Now().Add(-time.Minute)
+	if err := f.fund("dave", "", fourth, "", 0); err != nil {
+		t.Fatalf("failed to fund request with expired cooldown: %v", err)
+	}
+	if have, want := service.sent(), 4; have != want {
+		t.Errorf("submitted transaction count mismatch: have %d, want %d", have, want)
+	}
+	if have, want := len(f.reqs), 4; have != want {
+		t.Errorf("pending request count mismatch: have %d, want %d", have, want)
+	}
+	for i, req := range f.reqs {
+		if req.Tx.Nonce() != uint64(i) {
+			t.Errorf("request %d: nonce mismatch: have %d, want %d", i, req.Tx.Nonce(), i)
+		}
+	}
+}
+
+// newTestCaptcha starts a fake reCaptcha verifier accepting only the given
+// response, returning a function restoring the original verifier.
+func newTestCaptcha(valid string) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": r.FormValue("secret") == "secret" && r.FormValue("response") == valid,
+		})
+	}))
+	oldURL := captchaVerifyURL
+	captchaVerifyURL = server.URL
+
+	return func() {
+		captchaVerifyURL = oldURL
+		server.Close()
+	}
+}
+
+// Tests the direct funding API, rejecting malformed and robot requests and
+// throttling repeated ones.
+func TestFundHandler(t *testing.T) {
+	defer withFlags(true, "token", "secret")()
+	defer newTestCaptcha("human")()
+
+	f, service, teardown := newTestFaucet(t)
+	defer teardown()
+
+	tests := []struct {
+		method string
+		body   string
+		ip     string
+		status int
+	}{
+		{"GET", "", "10.0.0.1", http.StatusMethodNotAllowed},
+		{"POST", "{", "10.0.0.1", http.StatusBadRequest},
+		{"POST", `{"tier": 0, "captcha": "human"}`, "10.0.0.1", http.StatusBadRequest},
+		{"POST", `{"address": "0x0000000000000000000000000000000000000001", "tier": 99, "captcha": "human"}`, "10.0.0.1", http.StatusBadRequest},
+		{"POST", `{"address": "0x0000000000000000000000000000000000000001", "tier": 0, "captcha": "robot"}`, "10.0.0.1", http.StatusForbidden},
+		{"POST", `{"address": "0x0000000000000000000000000000000000000001", "tier": 0, "captcha": "human"}`, "10.0.0.1", http.StatusOK},
+		{"POST", `{"address": "0x0000000000000000000000000000000000000001", "tier": 0, "captcha": "human"}`, "10.0.0.2", http.StatusTooManyRequests},
+		{"POST", `{"address": "0x0000000000000000000000000000000000000002", "tier": 0, "captcha": "human"}`, "10.0.0.1", http.StatusTooManyRequests},
+		{"POST", `{"address": "0x0000000000000000000000000000000000000002", "tier": 0, "captcha": "human"}`, "10.0.0.2", http.StatusOK},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/fund", strings.NewReader(tt.body))
+		req.RemoteAddr = tt.ip + ":1234"
+
+		rec := httptest.NewRecorder()
+		f.fundHandler(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d (%s)", i, rec.Code, tt.status, bytes.TrimSpace(rec.Body.Bytes()))
+		}
+	}
+	if have, want := service.sent(), 2; have != want {
+		t.Errorf("submitted transaction count mismatch: have %d, want %d", have, want)
+	}
+}
@@ -54,6 +54,8 @@ passwordfile as argument containing the wallet password in plaintext.`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
 				},
 				Description: `
 	geth wallet [options] /path/to/my/presale.wallet
@@ -110,6 +112,8 @@ Print a short summary of all accounts`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
 				},
 				Description: `
     geth account new
@@ -135,6 +139,8 @@ password to file or expose in any other way.
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
 				},
 				Description: `
     geth account update <address>
@@ -164,6 +170,8 @@ changing your password is only possible interactively.
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
 				},
 				ArgsUsage: "<keyFile>",
 				Description: `
@@ -237,6 +245,29 @@ func unlockAccount(ctx *cli.Context, ks *keystore.KeyStore, address string, i in
 	return accounts.Account{}, ""
 }
 
+//解锁出块账号，解锁后的账号只能用于签名区块而不能签名交易或任意数据
+func unlockSealAccount(ks *keystore.KeyStore, address string, i int, passwords []string) accounts.Account {
+
+	account, err := utils.MakeAddress(ks, address)
+	if err != nil {
+		utils.Fatalf("Could not list accounts: %v", err)
+	}
+	for trials := 0; trials < 3; trials++ {
+		prompt := fmt.Sprintf("Unlocking account %s for block sealing | Attempt %d/%d", address, trials+1, 3)
+		password := getPassPhrase(prompt, false, i, passwords)
+		err = ks.TimedUnlockSealOnly(account, password, 0)
+		if err == nil {
+			log.Info("Unlocked account for block sealing only", "address", account.Address.Hex())
+			return account
+		}
+		if err != keystore.ErrDecrypt {
+			break
+		}
+	}
+	utils.Fatalf("Failed to unlock account %s for block sealing (%v)", address, err)
+	return accounts.Account{}
+}
+
 // getPassPhrase retrieves the password associated with an account, either fetched
 // from a list of preloaded passphrases, or requested interactively from the user.
 func getPassPhrase(prompt string, confirmation bool, i int, passwords []string) string {
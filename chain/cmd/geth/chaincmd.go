@@ -11,14 +11,17 @@ import (
 
 	"github.com/Bokerchain/Boker/chain/cmd/utils"
 	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/consensus/dpos"
 	"github.com/Bokerchain/Boker/chain/console"
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/core/state"
 	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/core/vm"
 	"github.com/Bokerchain/Boker/chain/eth/downloader"
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/event"
 	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/params"
 	"github.com/Bokerchain/Boker/chain/trie"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"gopkg.in/urfave/cli.v1"
@@ -43,6 +46,20 @@ participating.
 It expects the genesis file as argument.`,
 	}
 
+	genesisHashCommand = cli.Command{
+		Action:    utils.MigrateFlags(genesisHash),
+		Name:      "genesis-hash",
+		Usage:     "Compute the genesis block hash of a genesis file",
+		ArgsUsage: "<genesisPath>",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+The genesis-hash command computes the genesis block of the given genesis file
+without touching any database, and prints its hash together with the state,
+DPoS and Boker trie roots. The base contracts and validators declared in the
+file are included, so operators can compare the hash with the one published
+for the network before initializing and syncing a node.`,
+	}
+
 	importCommand = cli.Command{
 		Action:    utils.MigrateFlags(importChain),
 		Name:      "import",
@@ -120,6 +137,24 @@ Remove blockchain and state databases`,
 The arguments are interpreted as block numbers or hashes.
 Use "ethereum dump 0" to dump the genesis block.`,
 	}
+	verifyChainCommand = cli.Command{
+		Action:    utils.MigrateFlags(verifyChain),
+		Name:      "verify-chain",
+		Usage:     "Re-validate a range of canonical blocks in the database",
+		ArgsUsage: "[<from> [<to>]]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.DBEngineFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Re-checks every canonical block from <from> (default 0) to <to> (default the
+current head): the header and its DPoS producer signature, the transaction and
+receipt roots, and the root nodes of the DPoS tries. Verification stops at the
+first corrupt block. A JSON report is written to stdout and the command exits
+with a non-zero status if corruption was found.`,
+	}
 )
 
 //将初始化给定的JSON格式genesis文件,并将其写为如果不能成功，那么零区（即创世纪）或将会失败
@@ -145,6 +180,11 @@ func initGenesis(ctx *cli.Context) error {
 		utils.Fatalf("invalid genesis file: %v", err)
 	}
 
+	//校验DPOS的验证者配置以及基础合约代码，避免写入无法出块的创世区块
+	if err := genesis.VerifyDpos(); err != nil {
+		utils.Fatalf("invalid DPoS genesis: %v", err)
+	}
+
 	//打开全数据库和轻型数据库的初始化
 	stack := makeFullNode(ctx)
 
@@ -163,6 +203,41 @@ func initGenesis(ctx *cli.Context) error {
 	return nil
 }
 
+//计算创世配置文件对应的创世区块Hash，只在内存中构建创世区块，不写入数据库
+func genesisHash(ctx *cli.Context) error {
+
+	genesisPath := ctx.Args().First()
+	if len(genesisPath) == 0 {
+		utils.Fatalf("Must supply path to genesis JSON file")
+	}
+	file, err := os.Open(genesisPath)
+	if err != nil {
+		utils.Fatalf("Failed to read genesis file: %v", err)
+	}
+	defer file.Close()
+
+	genesis := new(core.Genesis)
+	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+		utils.Fatalf("invalid genesis file: %v", err)
+	}
+	//与geth init使用相同的校验，无法初始化的创世配置不输出Hash
+	if err := genesis.VerifyDpos(); err != nil {
+		utils.Fatalf("invalid DPoS genesis: %v", err)
+	}
+	block, _, _, _, _ := genesis.ToBlock()
+	if block == nil || block.DposContext == nil {
+		utils.Fatalf("Failed to build genesis block")
+	}
+	header := block.Header()
+
+	fmt.Printf("Genesis hash: %s\n", block.Hash().Hex())
+	fmt.Printf("Chain ID:     %v\n", genesis.Config.ChainId)
+	fmt.Printf("State root:   %s\n", header.Root.Hex())
+	fmt.Printf("DPoS root:    %s\n", header.DposProto.Root().Hex())
+	fmt.Printf("Boker root:   %s\n", header.BokerProto.Root().Hex())
+	return nil
+}
+
 //导入区块
 func importChain(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
@@ -205,13 +280,14 @@ func importChain(ctx *cli.Context) error {
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
 	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*ethdb.LDBDatabase)
-
-	stats, err := db.LDB().GetProperty("leveldb.stats")
-	if err != nil {
-		utils.Fatalf("Failed to read database stats: %v", err)
+	db, isLevelDB := levelDB(chainDb)
+	if isLevelDB {
+		stats, err := db.LDB().GetProperty("leveldb.stats")
+		if err != nil {
+			utils.Fatalf("Failed to read database stats: %v", err)
+		}
+		fmt.Println(stats)
 	}
-	fmt.Println(stats)
 	fmt.Printf("Trie cache misses:  %d\n", trie.CacheMisses())
 	fmt.Printf("Trie cache unloads: %d\n\n", trie.CacheUnloads())
 
@@ -224,19 +300,19 @@ func importChain(ctx *cli.Context) error {
 	fmt.Printf("Allocations:   %.3f million\n", float64(mem.Mallocs)/1000000)
 	fmt.Printf("GC pause:      %v\n\n", time.Duration(mem.PauseTotalNs))
 
-	if ctx.GlobalIsSet(utils.NoCompactionFlag.Name) {
+	if ctx.GlobalIsSet(utils.NoCompactionFlag.Name) || !isLevelDB {
 		return nil
 	}
 
 	// Compact the entire database to more accurately measure disk io and print the stats
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = db.LDB().CompactRange(util.Range{}); err != nil {
+	if err := db.LDB().CompactRange(util.Range{}); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 
-	stats, err = db.LDB().GetProperty("leveldb.stats")
+	stats, err := db.LDB().GetProperty("leveldb.stats")
 	if err != nil {
 		utils.Fatalf("Failed to read database stats: %v", err)
 	}
@@ -318,8 +394,10 @@ func copyDb(ctx *cli.Context) error {
 	// Compact the entire database to remove any sync overhead
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*ethdb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
-		utils.Fatalf("Compaction failed: %v", err)
+	if db, ok := levelDB(chainDb); ok {
+		if err = db.LDB().CompactRange(util.Range{}); err != nil {
+			utils.Fatalf("Compaction failed: %v", err)
+		}
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 
@@ -381,6 +459,67 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+//检查规范链中一段区块的完整性，以JSON格式输出第一个损坏的区块
+func verifyChain(ctx *cli.Context) error {
+	if len(ctx.Args()) > 2 {
+		utils.Fatalf("This command accepts at most two arguments.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+
+	//只做检查不处理交易，不需要启动以太坊服务
+	config, _, err := core.SetupGenesisBlock(chainDb, nil)
+	if err != nil {
+		utils.Fatalf("Failed to load chain config: %v", err)
+	}
+	chain, err := core.NewBlockChain(chainDb, config, dpos.New(&params.DposConfig{}, chainDb), vm.Config{})
+	if err != nil {
+		utils.Fatalf("Can't create BlockChain: %v", err)
+	}
+	from, to := uint64(0), chain.CurrentBlock().NumberU64()
+	if len(ctx.Args()) > 0 {
+		if from, err = strconv.ParseUint(ctx.Args().Get(0), 10, 64); err != nil {
+			utils.Fatalf("Invalid first block number: %v", err)
+		}
+	}
+	if len(ctx.Args()) > 1 {
+		if to, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			utils.Fatalf("Invalid last block number: %v", err)
+		}
+	}
+	if head := chain.CurrentBlock().NumberU64(); to > head {
+		log.Warn("Last block is beyond the current head", "last", to, "head", head)
+		to = head
+	}
+	if from > to {
+		utils.Fatalf("First block %d is after last block %d", from, to)
+	}
+	start := time.Now()
+	report := core.VerifyChain(chain, from, to, func(number uint64) {
+		log.Info("Verifying blocks", "number", number, "elapsed", common.PrettyDuration(time.Since(start)))
+	})
+	chain.Stop()
+	chainDb.Close()
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+	if report.Corrupt != nil {
+		log.Error("Corrupt block found", "number", report.Corrupt.Number, "check", report.Corrupt.Check, "err", report.Corrupt.Error)
+		os.Exit(1)
+	}
+	log.Info("Blocks verified", "count", report.Checked, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+//返回链数据库底层的LevelDB实例，冻结库不参与统计和压缩
+func levelDB(db ethdb.Database) (*ethdb.LDBDatabase, bool) {
+	if fdb, ok := db.(*ethdb.FreezerDatabase); ok {
+		db = fdb.Database
+	}
+	ldb, ok := db.(*ethdb.LDBDatabase)
+	return ldb, ok
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
@@ -21,7 +21,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/log"
 	"github.com/Bokerchain/Boker/chain/node"
 	"github.com/Bokerchain/Boker/chain/params"
-	whisper "github.com/Bokerchain/Boker/chain/whisper/whisperv5"
+	whisper "github.com/Bokerchain/Boker/chain/whisper/whisperv6"
 	"github.com/naoina/toml"
 )
 
@@ -63,12 +63,20 @@ type ethstatsConfig struct {
 	URL string `toml:",omitempty"`
 }
 
+//可选服务的开关，命令行中对应服务的标志同样会打开这些服务
+type servicesConfig struct {
+	Whisper         bool `toml:",omitempty"` //启动Whisper服务
+	ValidatorGossip bool `toml:",omitempty"` //通过Whisper与其他验证者交换存活消息和维护公告，同时启动Whisper服务
+	Dashboard       bool `toml:",omitempty"` //启动监控面板
+}
+
 type gethConfig struct {
 	Eth       eth.Config     //Eth配置
 	Shh       whisper.Config //
 	Node      node.Config    //节点配置
 	Ethstats  ethstatsConfig
 	Dashboard dashboard.Config
+	Services  servicesConfig //可选服务
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -113,7 +121,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	//加载默认配置文件()
 	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
 		if err := loadConfig(file, &cfg); err != nil {
-			log.Error("%v", err)
+			utils.Fatalf("%v", err)
 		}
 	}
 	log.Info("makeConfigNode GlobalString")
@@ -125,7 +133,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	//根据配置信息生成一个节点
 	stack, err := node.New(&cfg.Node)
 	if err != nil {
-		log.Error("Failed to create the protocol stack: %v", err)
+		utils.Fatalf("Failed to create the protocol stack: %v", err)
 	}
 	log.Info("makeConfigNode node.New")
 
@@ -144,6 +152,8 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	utils.SetDashboardConfig(ctx, &cfg.Dashboard)
 	log.Info("makeConfigNode SetDashboardConfig")
 
+	setServicesConfig(ctx, &cfg.Services)
+
 	return stack, cfg
 }
 
@@ -157,6 +167,19 @@ func enableWhisper(ctx *cli.Context) bool {
 	return false
 }
 
+//将打开可选服务的命令行标志应用于配置
+func setServicesConfig(ctx *cli.Context, cfg *servicesConfig) {
+	if enableWhisper(ctx) {
+		cfg.Whisper = true
+	}
+	if ctx.GlobalBool(utils.WhisperValidatorGossipFlag.Name) {
+		cfg.ValidatorGossip = true
+	}
+	if ctx.GlobalBool(utils.DashboardEnabledFlag.Name) {
+		cfg.Dashboard = true
+	}
+}
+
 //产生一个全节点
 func makeFullNode(ctx *cli.Context) *node.Node {
 
@@ -164,19 +187,21 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	stack, cfg := makeConfigNode(ctx)
 	utils.RegisterEthService(stack, &cfg.Eth)
 
-	if ctx.GlobalBool(utils.DashboardEnabledFlag.Name) {
+	if cfg.Services.Dashboard {
 		utils.RegisterDashboardService(stack, &cfg.Dashboard)
 	}
 	// Whisper must be explicitly enabled by specifying at least 1 whisper flag
-	shhEnabled := enableWhisper(ctx)
-	if shhEnabled {
-		if ctx.GlobalIsSet(utils.WhisperMaxMessageSizeFlag.Name) {
-			cfg.Shh.MaxMessageSize = uint32(ctx.Int(utils.WhisperMaxMessageSizeFlag.Name))
-		}
-		if ctx.GlobalIsSet(utils.WhisperMinPOWFlag.Name) {
-			cfg.Shh.MinimumAcceptedPOW = ctx.Float64(utils.WhisperMinPOWFlag.Name)
-		}
+	// or in the services section of the config file
+	if cfg.Services.Whisper || cfg.Services.ValidatorGossip {
 		utils.RegisterShhService(stack, &cfg.Shh)
+		if cfg.Services.ValidatorGossip {
+			utils.RegisterValidatorGossipService(stack)
+		}
+	}
+
+	// Add the GraphQL server if requested.
+	if endpoint := cfg.Node.GraphQLEndpoint(); endpoint != "" {
+		utils.RegisterGraphQLService(stack, endpoint, cfg.Node.GraphQLCors)
 	}
 
 	// Add the Ethereum Stats daemon if requested.
@@ -13,7 +13,7 @@ import (
 )
 
 var (
-	consoleFlags = []cli.Flag{utils.JSpathFlag, utils.ExecFlag, utils.PreloadJSFlag}
+	consoleFlags = []cli.Flag{utils.JSpathFlag, utils.ExecFlag, utils.ExecJSONFlag, utils.PreloadJSFlag}
 
 	consoleCommand = cli.Command{
 		Action:   utils.MigrateFlags(localConsole),
@@ -73,6 +73,11 @@ func localConsole(ctx *cli.Context) error {
 		Client:  client,
 		Preload: utils.MakeConsolePreloads(ctx),
 	}
+	// In JSON mode stdout only receives the result, any other output goes to stderr
+	jsonScript := ctx.GlobalString(utils.ExecJSONFlag.Name)
+	if jsonScript != "" {
+		config.Printer = os.Stderr
+	}
 
 	console, err := console.New(config)
 	if err != nil {
@@ -81,6 +86,9 @@ func localConsole(ctx *cli.Context) error {
 	defer console.Stop(false)
 
 	// If only a short execution was requested, evaluate and return
+	if jsonScript != "" {
+		return console.EvaluateJSON(jsonScript, os.Stdout)
+	}
 	if script := ctx.GlobalString(utils.ExecFlag.Name); script != "" {
 		console.Evaluate(script)
 		return nil
@@ -106,6 +114,11 @@ func remoteConsole(ctx *cli.Context) error {
 		Client:  client,
 		Preload: utils.MakeConsolePreloads(ctx),
 	}
+	// In JSON mode stdout only receives the result, any other output goes to stderr
+	jsonScript := ctx.GlobalString(utils.ExecJSONFlag.Name)
+	if jsonScript != "" {
+		config.Printer = os.Stderr
+	}
 
 	console, err := console.New(config)
 	if err != nil {
@@ -113,6 +126,9 @@ func remoteConsole(ctx *cli.Context) error {
 	}
 	defer console.Stop(false)
 
+	if jsonScript != "" {
+		return console.EvaluateJSON(jsonScript, os.Stdout)
+	}
 	if script := ctx.GlobalString(utils.ExecFlag.Name); script != "" {
 		console.Evaluate(script)
 		return nil
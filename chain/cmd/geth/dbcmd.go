@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/cmd/utils"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/urfave/cli.v1"
+)
+
+//离线迁移时数据库允许打开的文件数量
+const migrateDatabaseHandles = 1024
+
+var (
+	dbFlags = []cli.Flag{
+		utils.DataDirFlag,
+		utils.DBEngineFlag,
+		utils.CacheFlag,
+		utils.LightModeFlag,
+	}
+	dbCommand = cli.Command{
+		Name:      "db",
+		Usage:     "Manage the blockchain databases",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+    geth db inspect
+    geth db stats
+    geth db compact
+    geth db verify-freezer
+    geth db prune-index
+    geth db migrate badger
+
+inspect and maintain the chain database, or convert it to another storage engine.`,
+		Subcommands: []cli.Command{
+			{
+				Name:   "inspect",
+				Usage:  "Show key counts and sizes of every data category",
+				Action: utils.MigrateFlags(inspectDB),
+				Flags:  dbFlags,
+				Description: `
+Iterates over the whole chain database and groups the keys by their prefix,
+printing the number of entries and their size for each category, followed by
+the tables of the ancient store.`,
+			},
+			{
+				Name:   "stats",
+				Usage:  "Print the storage engine statistics",
+				Action: utils.MigrateFlags(statDB),
+				Flags:  dbFlags,
+				Description: `
+Prints the internal statistics of the storage engine, such as the files and
+sizes per LevelDB level.`,
+			},
+			{
+				Name:   "compact",
+				Usage:  "Compact the whole chain database",
+				Action: utils.MigrateFlags(compactDB),
+				Flags:  dbFlags,
+				Description: `
+Runs a full compaction of the chain database, reclaiming the space of deleted
+and overwritten entries. The node must not be running.`,
+			},
+			{
+				Name:   "verify-freezer",
+				Usage:  "Check the integrity of the ancient store",
+				Action: utils.MigrateFlags(verifyFreezer),
+				Flags:  dbFlags,
+				Description: `
+Checks that every block in the ancient store matches the canonical chain and
+that its header, body and receipts decode and match the header roots.`,
+			},
+			{
+				Name:   "prune-index",
+				Usage:  "Delete transaction lookup entries of non-canonical blocks",
+				Action: utils.MigrateFlags(pruneIndex),
+				Flags:  dbFlags,
+				Description: `
+Deletes the transaction lookup entries left behind by reorganisations that
+point to blocks which are no longer part of the canonical chain.`,
+			},
+			{
+				Name:      "migrate",
+				Usage:     "Convert the chain databases to another storage engine",
+				ArgsUsage: "<engine>",
+				Action:    utils.MigrateFlags(migrateDB),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+    geth db migrate <engine>
+
+Copies every entry of the chaindata and lightchaindata databases into a new
+database using the given engine, then swaps it in and keeps the original one
+with a .bak suffix. The ancient store is moved over as is. The node must not
+be running while the migration is in progress. Start the node with
+--db.engine=<engine> afterwards.`,
+			},
+		},
+	}
+)
+
+//将链数据库迁移到指定的存储引擎
+func migrateDB(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the target engine as its argument.")
+	}
+	target := ctx.Args().First()
+
+	supported := false
+	for _, name := range ethdb.Engines() {
+		supported = supported || name == target
+	}
+	if !supported {
+		utils.Fatalf("Database engine %q not available, compiled in: %v", target, ethdb.Engines())
+	}
+	stack, _ := makeConfigNode(ctx)
+	cache := ctx.GlobalInt(utils.CacheFlag.Name)
+
+	for _, name := range []string{"chaindata", "lightchaindata"} {
+		logger := log.New("database", name)
+
+		dbdir := stack.ResolvePath(name)
+		if !common.FileExist(dbdir) {
+			logger.Info("Database doesn't exist, skipping", "path", dbdir)
+			continue
+		}
+		source := ethdb.DetectEngine(dbdir)
+		if source == target {
+			logger.Info("Database already uses engine, skipping", "engine", target)
+			continue
+		}
+		if err := migrateDatabase(logger, dbdir, source, target, cache); err != nil {
+			utils.Fatalf("Failed to migrate %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+//将dbdir中的数据库复制到临时目录，完成后替换原目录，原数据库保留为.bak
+func migrateDatabase(logger log.Logger, dbdir, source, target string, cache int) error {
+	var (
+		tmpdir = dbdir + ".migrating"
+		bakdir = dbdir + ".bak"
+	)
+	if common.FileExist(bakdir) {
+		return fmt.Errorf("backup %s already exists, remove it first", bakdir)
+	}
+	//上次中断的迁移留下的数据不可信，重新开始
+	if err := os.RemoveAll(tmpdir); err != nil {
+		return err
+	}
+	src, err := ethdb.Open(source, dbdir, cache/2, migrateDatabaseHandles)
+	if err != nil {
+		return err
+	}
+	dst, err := ethdb.Open(target, tmpdir, cache/2, migrateDatabaseHandles)
+	if err != nil {
+		src.Close()
+		return err
+	}
+	logger.Info("Migrating database", "from", source, "to", target)
+
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	err = ethdb.Migrate(src, dst, func(keys, size uint64) {
+		if time.Since(logged) > 8*time.Second {
+			logger.Info("Migrating database", "keys", keys, "size", common.StorageSize(size), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	})
+	src.Close()
+	dst.Close()
+
+	if err != nil {
+		os.RemoveAll(tmpdir)
+		return err
+	}
+	if err := os.Rename(dbdir, bakdir); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpdir, dbdir); err != nil {
+		return err
+	}
+	//冻结库与存储引擎无关，直接移到新的数据库目录
+	if ancient := core.FreezerDir(bakdir); common.FileExist(ancient) {
+		if err := os.Rename(ancient, core.FreezerDir(dbdir)); err != nil {
+			return err
+		}
+	}
+	logger.Info("Database migrated", "engine", target, "backup", bakdir, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+//按类别输出数据库中键的数量和大小
+func inspectDB(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	stats, err := core.InspectDatabase(chainDb, func(keys uint64) {
+		log.Info("Inspecting database", "keys", keys, "elapsed", common.PrettyDuration(time.Since(start)))
+	})
+	if err != nil {
+		utils.Fatalf("Failed to inspect database: %v", err)
+	}
+	var (
+		table = tablewriter.NewWriter(os.Stdout)
+		count uint64
+		size  common.StorageSize
+	)
+	table.SetHeader([]string{"Category", "Items", "Size"})
+	for _, stat := range stats {
+		table.Append([]string{stat.Category, fmt.Sprintf("%d", stat.Count), stat.Size.String()})
+		count += stat.Count
+		size += stat.Size
+	}
+	table.SetFooter([]string{"Total", fmt.Sprintf("%d", count), size.String()})
+	table.Render()
+	return nil
+}
+
+//输出存储引擎的内部统计信息
+func statDB(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	stater, ok := chainDb.(ethdb.Stater)
+	if !ok {
+		utils.Fatalf("Database does not provide statistics")
+	}
+	stats, err := stater.Stat()
+	if err != nil {
+		utils.Fatalf("Failed to read database stats: %v", err)
+	}
+	fmt.Println(stats)
+	return nil
+}
+
+//压缩整个链数据库
+func compactDB(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	compacter, ok := chainDb.(ethdb.Compacter)
+	if !ok {
+		utils.Fatalf("Database does not support compaction")
+	}
+	start := time.Now()
+	log.Info("Compacting database")
+	if err := compacter.Compact(); err != nil {
+		utils.Fatalf("Compaction failed: %v", err)
+	}
+	log.Info("Database compacted", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+//检查冻结库的完整性
+func verifyFreezer(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	verified, err := core.VerifyAncients(chainDb, func(number uint64) {
+		log.Info("Verifying ancient blocks", "number", number, "elapsed", common.PrettyDuration(time.Since(start)))
+	})
+	if err != nil {
+		utils.Fatalf("Ancient store corrupted after %d blocks: %v", verified, err)
+	}
+	log.Info("Ancient store verified", "blocks", verified, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+//删除指向非规范区块的交易查找索引
+func pruneIndex(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	pruned, err := core.PruneTxLookups(chainDb, func(scanned, pruned uint64) {
+		log.Info("Pruning stale lookup entries", "scanned", scanned, "pruned", pruned, "elapsed", common.PrettyDuration(time.Since(start)))
+	})
+	if err != nil {
+		utils.Fatalf("Failed to prune lookup entries: %v", err)
+	}
+	log.Info("Pruned stale lookup entries", "pruned", pruned, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
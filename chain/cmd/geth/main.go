@@ -15,7 +15,6 @@ import (
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/console"
 
-	"github.com/Bokerchain/Boker/chain/accounts/abi/bind"
 	"github.com/Bokerchain/Boker/chain/boker"
 	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/eth"
@@ -46,13 +45,16 @@ var (
 	nodeFlags = []cli.Flag{
 		utils.IdentityFlag,
 		utils.UnlockedAccountFlag,
+		utils.UnlockSealOnlyFlag,
 		utils.PasswordFileFlag,
 		utils.BootnodesFlag,
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.DBEngineFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
+		utils.ExternalSignerFlag,
 		utils.DashboardEnabledFlag,
 		utils.DashboardAddrFlag,
 		utils.DashboardPortFlag,
@@ -70,10 +72,21 @@ var (
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
+		utils.AddressIndexFlag,
+		utils.BalanceIndexFlag,
+		utils.TokenIndexFlag,
+		utils.InternalTxIndexFlag,
+		utils.CheckpointFlag,
+		utils.CheckpointDisableFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
+		utils.LightCapacityFlag,
 		utils.LightKDFFlag,
+		utils.KeyStoreScryptNFlag,
+		utils.KeyStoreScryptPFlag,
 		utils.CacheFlag,
+		utils.TrieNodeCacheFlag,
+		utils.FreezerThresholdFlag,
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
@@ -86,18 +99,30 @@ var (
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
+		utils.DiscoveryDNSFlag,
 		utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.VMEnableDebugFlag,
+		utils.VMEnableFusionFlag,
+		utils.VMParallelTxFlag,
+		utils.VMTracersDirFlag,
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.EthStatsURLFlag,
 		utils.MetricsEnabledFlag,
+		utils.MetricsPrometheusFlag,
+		utils.MetricsPrometheusAddrFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.GpoMaxHistoryFlag,
 		utils.ExtraDataFlag,
+		utils.MinerSlotMarginFlag,
+		utils.DposWatchdogFlag,
+		utils.DposClockDriftFlag,
+		utils.DposClockStrictFlag,
+		utils.DposNTPServersFlag,
 		configFileFlag,
 	}
 
@@ -106,11 +131,32 @@ var (
 		utils.RPCListenAddrFlag,
 		utils.RPCPortFlag,
 		utils.RPCApiFlag,
+		utils.RPCVirtualHostsFlag,
+		utils.RPCAuthApiFlag,
+		utils.RPCAPIKeyFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCResponseLimitFlag,
+		utils.RPCTimeoutFlag,
+		utils.RPCMethodTimeoutsFlag,
+		utils.RPCLogsMaxResultsFlag,
+		utils.RPCLogsTimeoutFlag,
+		utils.RPCFiltersMaxFlag,
+		utils.RPCFiltersTimeoutFlag,
+		utils.RPCSubscriptionsMaxFlag,
+		utils.RPCCallCacheFlag,
+		utils.RPCCallCacheTTLFlag,
+		utils.RPCGasCapFlag,
+		utils.RPCTxFeeCapFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.GraphQLEnabledFlag,
+		utils.GraphQLListenAddrFlag,
+		utils.GraphQLPortFlag,
+		utils.GraphQLCORSDomainFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -119,6 +165,7 @@ var (
 		utils.WhisperEnabledFlag,
 		utils.WhisperMaxMessageSizeFlag,
 		utils.WhisperMinPOWFlag,
+		utils.WhisperValidatorGossipFlag,
 	}
 )
 
@@ -143,7 +190,10 @@ func init() {
 		exportCommand, //导出链到指定文件
 		copydbCommand,
 		removedbCommand,
+		dbCommand,
 		dumpCommand,
+		verifyChainCommand,
+		genesisHashCommand,
 
 		//注册监控CMD指令，可以查看monitorcmd.go
 		monitorCommand,
@@ -161,6 +211,8 @@ func init() {
 		versionCommand,
 		bugCommand,
 		licenseCommand,
+		decodeTxCommand,
+		signTxCommand,
 
 		//注册调试config指令，可以查看config.go
 		dumpConfigCommand,
@@ -185,6 +237,9 @@ func init() {
 		//创建一个goroutine，每3秒监测一次系统的RAM和DISK状态
 		go metrics.CollectProcessMetrics(3 * time.Second)
 
+		//如果开启了Prometheus度量导出，则启动HTTP导出服务
+		utils.SetupMetrics(ctx)
+
 		//配置gas limit值
 		utils.SetupNetwork(ctx)
 		return nil
@@ -210,16 +265,15 @@ func geth(ctx *cli.Context) error {
 	//生成一个*node.Node对象stack
 	log.Info("****geth****")
 
-	//这里的gethNode是一个全局变量
-	bind.GethNode = makeFullNode(ctx)
+	node := makeFullNode(ctx)
 	log.Info("Full Node Create Completed")
 
 	//启动这个节点
-	startNode(ctx, bind.GethNode)
+	startNode(ctx, node)
 	log.Info("Start Node Completed")
 
 	//节点进入等待
-	bind.GethNode.Wait()
+	node.Wait()
 	return nil
 }
 
@@ -246,6 +300,18 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 			unlockAccount(ctx, ks, trimmed, i, passwords)
 		}
 	}
+
+	//出块账号只解锁用于签名区块，密码紧接着--unlock账号的密码
+	offset := 0
+	if ctx.GlobalString(utils.UnlockedAccountFlag.Name) != "" {
+		offset = len(unlocks)
+	}
+	sealers := strings.Split(ctx.GlobalString(utils.UnlockSealOnlyFlag.Name), ",")
+	for i, account := range sealers {
+		if trimmed := strings.TrimSpace(account); trimmed != "" {
+			unlockSealAccount(ks, trimmed, offset+i, passwords)
+		}
+	}
 	log.Info("PasswordList unlockAccount")
 
 	//注册钱包事件处理程序以打开和自动派生钱包
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/accounts/abi"
+	"github.com/Bokerchain/Boker/chain/accounts/keystore"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/cmd/utils"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/common/math"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	signTxInputFlag = cli.StringFlag{
+		Name:  "input",
+		Usage: "JSON file containing the transaction to sign",
+	}
+	signTxOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "File to write the hex encoded signed transaction to (default = stdout)",
+	}
+	signTxCommand = cli.Command{
+		Action:    utils.MigrateFlags(signTx),
+		Name:      "signtx",
+		Usage:     "Sign a transaction offline with a keystore account",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.KeyStoreDirFlag,
+			utils.PasswordFileFlag,
+			signTxInputFlag,
+			signTxOutputFlag,
+		},
+		Category: "ACCOUNT COMMANDS",
+		Description: `
+    geth signtx --keystore <dir> --input tx.json [--output signed.rlp]
+
+Signs the transaction described by the input file with the key of its sender,
+without connecting to a node, and writes the hex encoded RLP of the signed
+transaction, which can be submitted with eth_sendRawTransaction. The input
+contains the fields
+
+    {"from": "0x..", "to": "0x..", "nonce": "0", "type": 0, "value": "0",
+     "gas": "90000", "gasPrice": "1000000000", "input": "0x..",
+     "timestamp": "1514764800", "chainId": "1"}
+
+where numbers are strings holding decimal or 0x prefixed hex values. The type
+is the numeric Boker transaction type, gas and gasPrice default to the values
+of the gas-free base contract transactions for types other than 0, the
+timestamp defaults to the current time and transactions without chainId are
+signed without replay protection.`,
+	}
+
+	decodeTxABIFlag = cli.StringFlag{
+		Name:  "abi",
+		Usage: "JSON ABI file used to decode the transaction input",
+	}
+	decodeTxCommand = cli.Command{
+		Action:    utils.MigrateFlags(decodeTx),
+		Name:      "decode-tx",
+		Usage:     "Decode and verify a raw transaction offline",
+		ArgsUsage: "<rlp-hex>",
+		Flags:     []cli.Flag{decodeTxABIFlag},
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+    geth decode-tx [--abi <file>] <rlp-hex>
+
+Decodes an RLP encoded transaction, including the Boker transaction type and
+timestamp, recovers the sender from the signature and prints all fields. If
+an ABI file is given the input is decoded as a call of one of its methods.
+The command fails if the signature or the transaction type is invalid.`,
+	}
+)
+
+//解码并校验一个RLP编码的交易
+func decodeTx(ctx *cli.Context) error {
+
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the RLP encoded transaction as argument.")
+	}
+	data, err := hexutil.Decode(ensureHexPrefix(strings.TrimSpace(ctx.Args().First())))
+	if err != nil {
+		utils.Fatalf("Invalid transaction hex: %v", err)
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(data, tx); err != nil {
+		utils.Fatalf("Failed to decode transaction: %v", err)
+	}
+
+	//EIP155交易使用签名中的链ID恢复发送者
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, sigErr := types.Sender(signer, tx)
+
+	fmt.Printf("Hash:      %s\n", tx.Hash().Hex())
+	fmt.Printf("Type:      %s (%d)\n", tx.Type(), uint8(tx.Type()))
+	if sigErr != nil {
+		fmt.Printf("From:      invalid signature: %v\n", sigErr)
+	} else if tx.Protected() {
+		fmt.Printf("From:      %s (chain id %v)\n", from.Hex(), tx.ChainId())
+	} else {
+		fmt.Printf("From:      %s (no chain id)\n", from.Hex())
+	}
+	if to := tx.To(); to != nil {
+		fmt.Printf("To:        %s\n", to.Hex())
+	} else {
+		fmt.Printf("To:        contract creation\n")
+	}
+	fmt.Printf("Nonce:     %d\n", tx.Nonce())
+	fmt.Printf("Value:     %v\n", tx.Value())
+	fmt.Printf("Gas price: %v\n", tx.GasPrice())
+	fmt.Printf("Gas limit: %v\n", tx.Gas())
+	if t := tx.Time(); t != nil && t.Sign() > 0 {
+		fmt.Printf("Timestamp: %v (%s)\n", t, time.Unix(t.Int64(), 0).UTC().Format(time.RFC3339))
+	} else {
+		fmt.Printf("Timestamp: %v\n", t)
+	}
+	fmt.Printf("Extra:     %s\n", hexutil.Encode(tx.Extra()))
+	fmt.Printf("Input:     %s\n", hexutil.Encode(tx.Data()))
+
+	if file := ctx.String(decodeTxABIFlag.Name); file != "" && len(tx.Data()) > 0 {
+		if err := printTxCall(file, tx.Data()); err != nil {
+			fmt.Printf("Call:      %v\n", err)
+		}
+	}
+
+	if sigErr != nil {
+		return sigErr
+	}
+	return tx.Validate()
+}
+
+//根据ABI文件解码交易的调用数据并输出方法和参数
+func printTxCall(file string, input []byte) error {
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	contract, err := abi.JSON(f)
+	if err != nil {
+		return fmt.Errorf("invalid ABI file %s: %v", file, err)
+	}
+	method, err := contract.MethodById(input)
+	if err != nil {
+		return err
+	}
+	args, err := method.UnpackInputs(input[4:])
+	if err != nil {
+		return fmt.Errorf("failed to decode arguments of %s: %v", method.Sig(), err)
+	}
+	fmt.Printf("Call:      %s\n", method.Sig())
+	for i, arg := range method.Inputs {
+		name := arg.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		fmt.Printf("  %s %s: %s\n", arg.Type, name, formatTxArg(args[i]))
+	}
+	return nil
+}
+
+func formatTxArg(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return hexutil.Encode(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+//允许输入的十六进制数据省略0x前缀
+func ensureHexPrefix(s string) string {
+	return "0x" + strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+}
+
+//离线签名的交易描述
+type signTxInput struct {
+	From      common.Address        `json:"from"`
+	To        *common.Address       `json:"to"`
+	Nonce     math.HexOrDecimal64   `json:"nonce"`
+	Type      protocol.TxType       `json:"type"`
+	Value     *math.HexOrDecimal256 `json:"value"`
+	Gas       *math.HexOrDecimal256 `json:"gas"`
+	GasPrice  *math.HexOrDecimal256 `json:"gasPrice"`
+	Input     hexutil.Bytes         `json:"input"`
+	Timestamp *math.HexOrDecimal64  `json:"timestamp"`
+	ChainId   *math.HexOrDecimal256 `json:"chainId"`
+}
+
+//根据描述构造未签名的交易
+func (in *signTxInput) transaction() (*types.Transaction, error) {
+
+	if in.Type > protocol.UpdateContract {
+		return nil, fmt.Errorf("unknown transaction type %d", in.Type)
+	}
+	value := new(big.Int)
+	if in.Value != nil {
+		value = (*big.Int)(in.Value)
+	}
+
+	//基础合约交易默认使用免Gas交易的Gas设置
+	gas, gasPrice := big.NewInt(90000), (*big.Int)(nil)
+	if in.Type != protocol.Binary {
+		gas, gasPrice = protocol.MaxGasLimit, protocol.MaxGasPrice
+	}
+	if in.Gas != nil {
+		gas = (*big.Int)(in.Gas)
+	}
+	if in.GasPrice != nil {
+		gasPrice = (*big.Int)(in.GasPrice)
+	}
+	if gasPrice == nil {
+		return nil, errors.New("gasPrice is required for binary transactions")
+	}
+
+	var tx *types.Transaction
+	if in.To == nil {
+		if in.Type != protocol.Binary {
+			return nil, errors.New("base contract transactions require a to address")
+		}
+		tx = types.NewContractCreation(uint64(in.Nonce), value, gas, gasPrice, in.Input)
+	} else {
+		tx = types.NewTransaction(in.Type, uint64(in.Nonce), *in.To, value, gas, gasPrice, in.Input)
+	}
+	if in.Timestamp != nil {
+		tx.SetTimestamp(int64(*in.Timestamp))
+	}
+	return tx, nil
+}
+
+//使用密钥库中的账号离线签名交易
+func signTx(ctx *cli.Context) error {
+
+	file := ctx.String(signTxInputFlag.Name)
+	if file == "" {
+		utils.Fatalf("The --%s flag is required.", signTxInputFlag.Name)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		utils.Fatalf("Failed to read transaction: %v", err)
+	}
+	input := new(signTxInput)
+	if err := json.Unmarshal(data, input); err != nil {
+		utils.Fatalf("Invalid transaction %s: %v", file, err)
+	}
+	tx, err := input.transaction()
+	if err != nil {
+		utils.Fatalf("Invalid transaction %s: %v", file, err)
+	}
+
+	keydir := ctx.GlobalString(utils.KeyStoreDirFlag.Name)
+	if keydir == "" {
+		keydir = filepath.Join(utils.MakeDataDir(ctx), "keystore")
+	}
+	ks := keystore.NewKeyStore(keydir, keystore.StandardScryptN, keystore.StandardScryptP)
+	account, err := ks.Find(accounts.Account{Address: input.From})
+	if err != nil {
+		utils.Fatalf("Account %s not found in %s: %v", input.From.Hex(), keydir, err)
+	}
+	password := getPassPhrase(fmt.Sprintf("Signing transaction from %s", input.From.Hex()), false, 0, utils.MakePasswordList(ctx))
+	signed, err := ks.SignTxWithPassphrase(account, password, tx, (*big.Int)(input.ChainId))
+	if err != nil {
+		utils.Fatalf("Failed to sign transaction: %v", err)
+	}
+	enc, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		utils.Fatalf("Failed to encode transaction: %v", err)
+	}
+
+	out := hexutil.Encode(enc) + "\n"
+	if path := ctx.String(signTxOutputFlag.Name); path != "" {
+		if err := ioutil.WriteFile(path, []byte(out), 0644); err != nil {
+			utils.Fatalf("Failed to write signed transaction: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Signed transaction %s written to %s\n", signed.Hash().Hex(), path)
+		return nil
+	}
+	fmt.Print(out)
+	return nil
+}
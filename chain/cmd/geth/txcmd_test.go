@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/accounts/abi"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/rlp"
+)
+
+const decodeTxTestABI = `[{"name":"voteUser","type":"function","inputs":[{"name":"candidate","type":"address"},{"name":"votes","type":"uint256"}],"outputs":[]}]`
+
+//测试解码带有时间戳的Boker交易并按照ABI解码调用数据
+func TestDecodeTx(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	candidate := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+	parsed, err := abi.JSON(strings.NewReader(decodeTxTestABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, err := parsed.Pack("voteUser", candidate, big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.NewAssginTransaction(protocol.VoteUser, 7, common.HexToAddress("0x01"), big.NewInt(0), input, 1514764800)
+	tx, err = types.SignTx(tx, types.NewEIP155Signer(big.NewInt(1)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := tmpdir(t)
+	defer os.RemoveAll(dir)
+	abiFile := filepath.Join(dir, "vote.abi")
+	if err := ioutil.WriteFile(abiFile, []byte(decodeTxTestABI), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	geth := runGeth(t, "decode-tx", "--abi", abiFile, common.Bytes2Hex(enc))
+	defer geth.ExpectExit()
+	geth.Expect(fmt.Sprintf(`Hash:      %s
+Type:      VoteUser (7)
+From:      %s (chain id 1)
+To:        0x0000000000000000000000000000000000000001
+Nonce:     7
+Value:     0
+Gas price: %v
+Gas limit: %v
+Timestamp: 1514764800 (2018-01-01T00:00:00Z)
+Extra:     0x
+Input:     %s
+Call:      voteUser(address,uint256)
+  address candidate: %s
+  uint256 votes: 42
+`, tx.Hash().Hex(), from.Hex(), protocol.MaxGasPrice, protocol.MaxGasLimit, common.ToHex(input), candidate.Hex()))
+}
+
+//测试使用密钥库离线签名基础合约交易
+func TestSignTx(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	defer os.RemoveAll(datadir)
+
+	from := common.HexToAddress("0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8")
+	input := filepath.Join(datadir, "tx.json")
+	output := filepath.Join(datadir, "signed.rlp")
+	password := filepath.Join(datadir, "password")
+	tx := `{"from": "0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8", "to": "0x0000000000000000000000000000000000000001", "nonce": "3",
+		"type": 7, "input": "0xaabb", "timestamp": "1514764800", "chainId": "0x1"}`
+	if err := ioutil.WriteFile(input, []byte(tx), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(password, []byte("foobar\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	geth := runGeth(t, "signtx", "--datadir", datadir, "--password", password, "--input", input, "--output", output)
+	geth.ExpectExit()
+
+	data, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := types.DecodeRawTransaction(common.FromHex(strings.TrimSpace(string(data))))
+	if err != nil {
+		t.Fatalf("failed to decode signed transaction: %v", err)
+	}
+	sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(1)), signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender != from {
+		t.Errorf("sender mismatch: have %x, want %x", sender, from)
+	}
+	if signed.Type() != protocol.VoteUser || signed.Nonce() != 3 || signed.Time().Int64() != 1514764800 {
+		t.Errorf("unexpected transaction: type %v nonce %d time %v", signed.Type(), signed.Nonce(), signed.Time())
+	}
+	if signed.GasPrice().Cmp(protocol.MaxGasPrice) != 0 || signed.Gas().Cmp(protocol.MaxGasLimit) != 0 {
+		t.Errorf("unexpected gas settings: price %v limit %v", signed.GasPrice(), signed.Gas())
+	}
+}
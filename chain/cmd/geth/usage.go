@@ -50,15 +50,26 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			configFileFlag,
 			utils.DataDirFlag,
+			utils.DBEngineFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
+			utils.ExternalSignerFlag,
 			utils.NetworkIdFlag,
 			utils.SyncModeFlag,
+			utils.AddressIndexFlag,
+			utils.BalanceIndexFlag,
+			utils.TokenIndexFlag,
+			utils.InternalTxIndexFlag,
+			utils.CheckpointFlag,
+			utils.CheckpointDisableFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
+			utils.LightCapacityFlag,
 			utils.LightKDFFlag,
+			utils.KeyStoreScryptNFlag,
+			utils.KeyStoreScryptPFlag,
 		},
 	},
 	//{
@@ -90,6 +101,8 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "PERFORMANCE TUNING",
 		Flags: []cli.Flag{
 			utils.CacheFlag,
+			utils.TrieNodeCacheFlag,
+			utils.FreezerThresholdFlag,
 			utils.TrieCacheGenFlag,
 		},
 	},
@@ -97,6 +110,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "ACCOUNT",
 		Flags: []cli.Flag{
 			utils.UnlockedAccountFlag,
+			utils.UnlockSealOnlyFlag,
 			utils.PasswordFileFlag,
 		},
 	},
@@ -107,16 +121,38 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCListenAddrFlag,
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
+			utils.RPCVirtualHostsFlag,
+			utils.RPCAuthApiFlag,
+			utils.RPCAPIKeyFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCResponseLimitFlag,
+			utils.RPCTimeoutFlag,
+			utils.RPCMethodTimeoutsFlag,
+			utils.RPCLogsMaxResultsFlag,
+			utils.RPCLogsTimeoutFlag,
+			utils.RPCFiltersMaxFlag,
+			utils.RPCFiltersTimeoutFlag,
+			utils.RPCSubscriptionsMaxFlag,
+			utils.RPCCallCacheFlag,
+			utils.RPCCallCacheTTLFlag,
+			utils.RPCGasCapFlag,
+			utils.RPCTxFeeCapFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.GraphQLEnabledFlag,
+			utils.GraphQLListenAddrFlag,
+			utils.GraphQLPortFlag,
+			utils.GraphQLCORSDomainFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
+			utils.ExecJSONFlag,
 			utils.PreloadJSFlag,
 		},
 	},
@@ -132,6 +168,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
+			utils.DiscoveryDNSFlag,
 			utils.NetrestrictFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
@@ -146,6 +183,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
+			utils.MinerSlotMarginFlag,
+			utils.DposWatchdogFlag,
+			utils.DposClockDriftFlag,
+			utils.DposClockStrictFlag,
+			utils.DposNTPServersFlag,
 		},
 	},
 	{
@@ -153,12 +195,16 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GpoBlocksFlag,
 			utils.GpoPercentileFlag,
+			utils.GpoMaxHistoryFlag,
 		},
 	},
 	{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.VMEnableFusionFlag,
+			utils.VMParallelTxFlag,
+			utils.VMTracersDirFlag,
 		},
 	},
 	{
@@ -45,6 +45,20 @@ var (
 	ErrNotExposed = errors.New("service not exposed, nor proxied")
 )
 
+// bokerBuilder returns the Dockerfile build stage compiling the given Boker
+// command from source. Services running Boker code copy the resulting binary
+// from /go/bin of the "builder" stage into their own image.
+func bokerBuilder(command string) string {
+	return fmt.Sprintf(`
+FROM golang:1.10-alpine AS builder
+
+RUN \
+  apk add --update git make gcc musl-dev linux-headers && \
+  git clone --depth=1 https://github.com/Bokerchain/Boker $GOPATH/src/github.com/Bokerchain/Boker && \
+  go install github.com/Bokerchain/Boker/chain/cmd/%s
+`, command)
+}
+
 // containerInfos is a heavily reduced version of the huge inspection dataset
 // returned from docker inspect, parsed into a form easily usable by puppeth.
 type containerInfos struct {
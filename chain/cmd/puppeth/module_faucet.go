@@ -30,19 +30,11 @@ import (
 
 // faucetDockerfile is the Dockerfile required to build an faucet container to
 // grant crypto tokens based on GitHub authentications.
-var faucetDockerfile = `
+var faucetDockerfile = bokerBuilder("faucet") + `
 FROM alpine:latest
 
-RUN mkdir /go
-ENV GOPATH /go
-
-RUN \
-  apk add --update git go make gcc musl-dev ca-certificates linux-headers                             && \
-	mkdir -p $GOPATH/src/github.com/ethereum                                                            && \
-	(cd $GOPATH/src/github.com/ethereum && git clone --depth=1 https://github.com/boker/go-ethereum) && \
-  go build -v github.com/Bokerchain/Boker/chain/cmd/faucet                                              && \
-  apk del git go make gcc musl-dev linux-headers                                                      && \
-  rm -rf $GOPATH && rm -rf /var/cache/apk/*
+RUN apk add --update ca-certificates && rm -rf /var/cache/apk/*
+COPY --from=builder /go/bin/faucet /faucet
 
 ADD genesis.json /genesis.json
 ADD account.json /account.json
@@ -54,6 +46,8 @@ CMD [ \
 	"/faucet", "--genesis", "/genesis.json", "--network", "{{.NetworkID}}", "--bootnodes", "{{.Bootnodes}}", "--ethstats", "{{.Ethstats}}", "--ethport", "{{.EthPort}}", \
 	"--faucet.name", "{{.FaucetName}}", "--faucet.amount", "{{.FaucetAmount}}", "--faucet.minutes", "{{.FaucetMinutes}}", "--faucet.tiers", "{{.FaucetTiers}}",          \
 	"--github.user", "{{.GitHubUser}}", "--github.token", "{{.GitHubToken}}", "--account.json", "/account.json", "--account.pass", "/account.pass"                       \
+	{{if .RPC}}, "--rpc", "{{.RPC}}"{{end}}                                                                                                                              \
+	{{if .Direct}}, "--faucet.direct"{{end}}{{if .VHost}}, "--faucet.proxied"{{end}}                                                                                     \
 	{{if .CaptchaToken}}, "--captcha.token", "{{.CaptchaToken}}", "--captcha.secret", "{{.CaptchaSecret}}"{{end}}                                                        \
 ]`
 
@@ -73,9 +67,11 @@ services:
     environment:
       - ETH_PORT={{.EthPort}}
       - ETH_NAME={{.EthName}}
+      - RPC_ENDPOINT={{.RPC}}
       - FAUCET_AMOUNT={{.FaucetAmount}}
       - FAUCET_MINUTES={{.FaucetMinutes}}
       - FAUCET_TIERS={{.FaucetTiers}}
+      - FAUCET_DIRECT={{.Direct}}
       - GITHUB_USER={{.GitHubUser}}
       - GITHUB_TOKEN={{.GitHubToken}}
       - CAPTCHA_TOKEN={{.CaptchaToken}}
@@ -104,6 +100,9 @@ func deployFaucet(client *sshClient, network string, bootnodes []string, config
 		"Bootnodes":     strings.Join(bootnodes, ","),
 		"Ethstats":      config.node.ethstats,
 		"EthPort":       config.node.portFull,
+		"RPC":           config.rpc,
+		"Direct":        config.direct,
+		"VHost":         config.host,
 		"GitHubUser":    config.githubUser,
 		"GitHubToken":   config.githubToken,
 		"CaptchaToken":  config.captchaToken,
@@ -123,6 +122,7 @@ func deployFaucet(client *sshClient, network string, bootnodes []string, config
 		"ApiPort":       config.port,
 		"EthPort":       config.node.portFull,
 		"EthName":       config.node.ethstats[:strings.Index(config.node.ethstats, ":")],
+		"RPC":           config.rpc,
 		"GitHubUser":    config.githubUser,
 		"GitHubToken":   config.githubToken,
 		"CaptchaToken":  config.captchaToken,
@@ -130,6 +130,7 @@ func deployFaucet(client *sshClient, network string, bootnodes []string, config
 		"FaucetAmount":  config.amount,
 		"FaucetMinutes": config.minutes,
 		"FaucetTiers":   config.tiers,
+		"Direct":        config.direct,
 	})
 	files[filepath.Join(workdir, "docker-compose.yaml")] = composefile.Bytes()
 
@@ -153,9 +154,11 @@ type faucetInfos struct {
 	node          *nodeInfos
 	host          string
 	port          int
+	rpc           string
 	amount        int
 	minutes       int
 	tiers         int
+	direct        bool
 	githubUser    string
 	githubToken   string
 	captchaToken  string
@@ -164,7 +167,11 @@ type faucetInfos struct {
 
 // String implements the stringer interface.
 func (info *faucetInfos) String() string {
-	return fmt.Sprintf("host=%s, api=%d, eth=%d, amount=%d, minutes=%d, tiers=%d, github=%s, captcha=%v, ethstats=%s", info.host, info.port, info.node.portFull, info.amount, info.minutes, info.tiers, info.githubUser, info.captchaToken != "", info.node.ethstats)
+	backend := fmt.Sprintf("eth=%d", info.node.portFull)
+	if info.rpc != "" {
+		backend = fmt.Sprintf("rpc=%s", info.rpc)
+	}
+	return fmt.Sprintf("host=%s, api=%d, %s, amount=%d, minutes=%d, tiers=%d, direct=%v, github=%s, captcha=%v, ethstats=%s", info.host, info.port, backend, info.amount, info.minutes, info.tiers, info.direct, info.githubUser, info.captchaToken != "", info.node.ethstats)
 }
 
 // checkFaucet does a health-check against an faucet server to verify whether
@@ -221,9 +228,11 @@ func checkFaucet(client *sshClient, network string) (*faucetInfos, error) {
 		},
 		host:          host,
 		port:          port,
+		rpc:           infos.envvars["RPC_ENDPOINT"],
 		amount:        amount,
 		minutes:       minutes,
 		tiers:         tiers,
+		direct:        infos.envvars["FAUCET_DIRECT"] == "true",
 		githubUser:    infos.envvars["GITHUB_USER"],
 		githubToken:   infos.envvars["GITHUB_TOKEN"],
 		captchaToken:  infos.envvars["CAPTCHA_TOKEN"],
@@ -28,9 +28,12 @@ import (
 	"github.com/Bokerchain/Boker/chain/log"
 )
 
-// nodeDockerfile is the Dockerfile required to run an Ethereum node.
-var nodeDockerfile = `
-FROM ethereum/client-go:latest
+// nodeDockerfile is the Dockerfile required to run a Boker node.
+var nodeDockerfile = bokerBuilder("geth") + `
+FROM alpine:latest
+
+RUN apk add --update ca-certificates && rm -rf /var/cache/apk/*
+COPY --from=builder /go/bin/geth /usr/local/bin/
 
 ADD genesis.json /genesis.json
 {{if .Unlock}}
@@ -40,13 +43,13 @@ ADD genesis.json /genesis.json
 RUN \
   echo 'geth init /genesis.json' > geth.sh && \{{if .Unlock}}
 	echo 'mkdir -p /root/.ethereum/keystore/ && cp /signer.json /root/.ethereum/keystore/' >> geth.sh && \{{end}}
-	echo $'geth --networkid {{.NetworkID}} --cache 512 --port {{.Port}} --maxpeers {{.Peers}} {{.LightFlag}} --ethstats \'{{.Ethstats}}\' {{if .BootV4}}--bootnodesv4 {{.BootV4}}{{end}} {{if .BootV5}}--bootnodesv5 {{.BootV5}}{{end}} {{if .Coinbase}}--coinbase {{.Coinbase}} --mine{{end}}{{if .Unlock}}--unlock 0 --password /signer.pass --mine{{end}} --targetgaslimit {{.GasTarget}} --gasprice {{.GasPrice}}' >> geth.sh
+	echo $'geth --networkid {{.NetworkID}} --cache 512 --port {{.Port}} --maxpeers {{.Peers}} {{.LightFlag}} --ethstats \'{{.Ethstats}}\' {{if .BootV4}}--bootnodesv4 {{.BootV4}}{{end}} {{if .BootV5}}--bootnodesv5 {{.BootV5}}{{end}} {{if .Unlock}}--coinbase {{.Coinbase}} --unlock {{.Coinbase}} --password /signer.pass --mine{{end}} {{if .WSPort}}--ws --wsaddr 0.0.0.0 --wsport {{.WSPort}} --wsorigins \'*\'{{end}} --targetgaslimit {{.GasTarget}} --gasprice {{.GasPrice}}' >> geth.sh
 
 ENTRYPOINT ["/bin/sh", "geth.sh"]
 `
 
 // nodeComposefile is the docker-compose.yml file required to deploy and maintain
-// an Ethereum node (bootnode or miner for now).
+// a Boker node (bootnode or validator).
 var nodeComposefile = `
 version: '2'
 services:
@@ -56,7 +59,8 @@ services:
     ports:
       - "{{.FullPort}}:{{.FullPort}}"
       - "{{.FullPort}}:{{.FullPort}}/udp"{{if .Light}}
-      - "{{.LightPort}}:{{.LightPort}}/udp"{{end}}
+      - "{{.LightPort}}:{{.LightPort}}/udp"{{end}}{{if .WSPort}}
+      - "{{.WSPort}}:{{.WSPort}}"{{end}}
     volumes:
       - {{.Datadir}}:/root/.ethereum
     environment:
@@ -64,6 +68,7 @@ services:
       - LIGHT_PORT={{.LightPort}}/udp
       - TOTAL_PEERS={{.TotalPeers}}
       - LIGHT_PEERS={{.LightPeers}}
+      - WS_PORT={{.WSPort}}
       - STATS_NAME={{.Ethstats}}
       - MINER_NAME={{.Coinbase}}
       - GAS_TARGET={{.GasTarget}}
@@ -76,7 +81,7 @@ services:
     restart: always
 `
 
-// deployNode deploys a new Ethereum node container to a remote machine via SSH,
+// deployNode deploys a new Boker node container to a remote machine via SSH,
 // docker and docker-compose. If an instance with the specified network name
 // already exists there, it will be overwritten!
 func deployNode(client *sshClient, network string, bootv4, bootv5 []string, config *nodeInfos) ([]byte, error) {
@@ -104,6 +109,7 @@ func deployNode(client *sshClient, network string, bootv4, bootv5 []string, conf
 		"BootV5":    strings.Join(bootv5, ","),
 		"Ethstats":  config.ethstats,
 		"Coinbase":  config.coinbase,
+		"WSPort":    config.portWS,
 		"GasTarget": uint64(1000000 * config.gasTarget),
 		"GasPrice":  uint64(1000000000 * config.gasPrice),
 		"Unlock":    config.keyJSON != "",
@@ -120,6 +126,7 @@ func deployNode(client *sshClient, network string, bootv4, bootv5 []string, conf
 		"Light":      config.peersLight > 0,
 		"LightPort":  config.portFull + 1,
 		"LightPeers": config.peersLight,
+		"WSPort":     config.portWS,
 		"Ethstats":   config.ethstats[:strings.Index(config.ethstats, ":")],
 		"Coinbase":   config.coinbase,
 		"GasTarget":  config.gasTarget,
@@ -153,6 +160,7 @@ type nodeInfos struct {
 	ethstats   string
 	portFull   int
 	portLight  int
+	portWS     int
 	enodeFull  string
 	enodeLight string
 	peersTotal int
@@ -170,8 +178,16 @@ func (info *nodeInfos) String() string {
 	if info.peersLight > 0 {
 		discv5 = fmt.Sprintf(", portv5=%d", info.portLight)
 	}
-	return fmt.Sprintf("port=%d%s, datadir=%s, peers=%d, lights=%d, ethstats=%s, gastarget=%0.3f MGas, gasprice=%0.3f GWei",
-		info.portFull, discv5, info.datadir, info.peersTotal, info.peersLight, info.ethstats, info.gasTarget, info.gasPrice)
+	ws := ""
+	if info.portWS > 0 {
+		ws = fmt.Sprintf(", ws=%d", info.portWS)
+	}
+	validator := ""
+	if info.coinbase != "" {
+		validator = fmt.Sprintf(", coinbase=%s", info.coinbase)
+	}
+	return fmt.Sprintf("port=%d%s%s, datadir=%s, peers=%d, lights=%d, ethstats=%s%s, gastarget=%0.3f MGas, gasprice=%0.3f GWei",
+		info.portFull, discv5, ws, info.datadir, info.peersTotal, info.peersLight, info.ethstats, validator, info.gasTarget, info.gasPrice)
 }
 
 // checkNode does a health-check against an boot or seal node server to verify
@@ -192,6 +208,7 @@ func checkNode(client *sshClient, network string, boot bool) (*nodeInfos, error)
 	// Resolve a few types from the environmental variables
 	totalPeers, _ := strconv.Atoi(infos.envvars["TOTAL_PEERS"])
 	lightPeers, _ := strconv.Atoi(infos.envvars["LIGHT_PEERS"])
+	wsPort, _ := strconv.Atoi(infos.envvars["WS_PORT"])
 	gasTarget, _ := strconv.ParseFloat(infos.envvars["GAS_TARGET"], 64)
 	gasPrice, _ := strconv.ParseFloat(infos.envvars["GAS_PRICE"], 64)
 
@@ -225,6 +242,7 @@ func checkNode(client *sshClient, network string, boot bool) (*nodeInfos, error)
 		datadir:    infos.volumes["/root/.ethereum"],
 		portFull:   infos.portmap[infos.envvars["FULL_PORT"]],
 		portLight:  infos.portmap[infos.envvars["LIGHT_PORT"]],
+		portWS:     wsPort,
 		peersTotal: totalPeers,
 		peersLight: lightPeers,
 		ethstats:   infos.envvars["STATS_NAME"],
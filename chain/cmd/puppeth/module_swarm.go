@@ -0,0 +1,205 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+// swarmDockerfile is the Dockerfile required to run a swarm gateway serving the
+// bzz HTTP API of the network.
+var swarmDockerfile = bokerBuilder("swarm") + `
+FROM alpine:latest
+
+RUN apk add --update ca-certificates && rm -rf /var/cache/apk/*
+COPY --from=builder /go/bin/swarm /usr/local/bin/
+
+ADD account.json /account.json
+ADD account.pass /account.pass
+
+RUN \
+  echo 'mkdir -p /root/.ethereum/keystore/ && cp /account.json /root/.ethereum/keystore/' > swarm.sh && \
+	echo $'swarm --datadir /root/.ethereum --bzzaccount {{.Account}} --password /account.pass --bzznetworkid {{.NetworkID}} --port {{.Port}} --httpaddr 0.0.0.0 --bzzport 8500 --corsdomain \'*\' {{if .Bootnodes}}--bootnodes {{.Bootnodes}}{{end}} {{if .ENSEndpoint}}--swarm.ens-endpoint {{.ENSEndpoint}}{{else}}--ens-api \'\'{{end}} {{if .ENSRegistry}}--swarm.ens-registry {{.ENSRegistry}}{{end}}' >> swarm.sh
+
+EXPOSE 8500
+
+ENTRYPOINT ["/bin/sh", "swarm.sh"]
+`
+
+// swarmComposefile is the docker-compose.yml file required to deploy and
+// maintain a swarm gateway.
+var swarmComposefile = `
+version: '2'
+services:
+  swarm:
+    build: .
+    image: {{.Network}}/swarm
+    ports:
+      - "{{.Port}}:{{.Port}}"
+      - "{{.Port}}:{{.Port}}/udp"{{if not .VHost}}
+      - "{{.HTTPPort}}:8500"{{end}}
+    volumes:
+      - {{.Datadir}}:/root/.ethereum
+    environment:
+      - P2P_PORT={{.Port}}/tcp
+      - BZZ_ACCOUNT={{.Account}}
+      - ENS_ENDPOINT={{.ENSEndpoint}}
+      - ENS_REGISTRY={{.ENSRegistry}}{{if .VHost}}
+      - VIRTUAL_HOST={{.VHost}}
+      - VIRTUAL_PORT=8500{{end}}
+    logging:
+      driver: "json-file"
+      options:
+        max-size: "1m"
+        max-file: "10"
+    restart: always
+`
+
+// deploySwarm deploys a new swarm gateway container to a remote machine via SSH,
+// docker and docker-compose. If an instance with the specified network name
+// already exists there, it will be overwritten!
+func deploySwarm(client *sshClient, network string, bootnodes []string, config *swarmInfos) ([]byte, error) {
+	// Generate the content to upload to the server
+	workdir := fmt.Sprintf("%d", rand.Int63())
+	files := make(map[string][]byte)
+
+	dockerfile := new(bytes.Buffer)
+	template.Must(template.New("").Parse(swarmDockerfile)).Execute(dockerfile, map[string]interface{}{
+		"NetworkID":   config.network,
+		"Port":        config.portFull,
+		"Account":     config.account,
+		"Bootnodes":   strings.Join(bootnodes, ","),
+		"ENSEndpoint": config.ensEndpoint,
+		"ENSRegistry": config.ensRegistry,
+	})
+	files[filepath.Join(workdir, "Dockerfile")] = dockerfile.Bytes()
+
+	composefile := new(bytes.Buffer)
+	template.Must(template.New("").Parse(swarmComposefile)).Execute(composefile, map[string]interface{}{
+		"Network":     network,
+		"Datadir":     config.datadir,
+		"VHost":       config.host,
+		"HTTPPort":    config.port,
+		"Port":        config.portFull,
+		"Account":     config.account,
+		"ENSEndpoint": config.ensEndpoint,
+		"ENSRegistry": config.ensRegistry,
+	})
+	files[filepath.Join(workdir, "docker-compose.yaml")] = composefile.Bytes()
+
+	files[filepath.Join(workdir, "account.json")] = []byte(config.keyJSON)
+	files[filepath.Join(workdir, "account.pass")] = []byte(config.keyPass)
+
+	// Upload the deployment files to the remote server (and clean up afterwards)
+	if out, err := client.Upload(files); err != nil {
+		return out, err
+	}
+	defer client.Run("rm -rf " + workdir)
+
+	// Build and deploy the swarm service
+	return nil, client.Stream(fmt.Sprintf("cd %s && docker-compose -p %s up -d --build", workdir, network))
+}
+
+// swarmInfos is returned from a swarm gateway status check to allow reporting
+// various configuration parameters.
+type swarmInfos struct {
+	network     int64
+	datadir     string
+	host        string
+	port        int
+	portFull    int
+	account     string
+	keyJSON     string
+	keyPass     string
+	ensEndpoint string
+	ensRegistry string
+}
+
+// String implements the stringer interface.
+func (info *swarmInfos) String() string {
+	ens := "disabled"
+	if info.ensEndpoint != "" {
+		ens = info.ensEndpoint
+		if info.ensRegistry != "" {
+			ens += "@" + info.ensRegistry
+		}
+	}
+	return fmt.Sprintf("host=%s, api=%d, port=%d, datadir=%s, account=%s, ens=%s", info.host, info.port, info.portFull, info.datadir, info.account, ens)
+}
+
+// checkSwarm does a health-check against a swarm gateway to verify whether it's
+// running, and if yes, gathering a collection of useful infos about it.
+func checkSwarm(client *sshClient, network string) (*swarmInfos, error) {
+	// Inspect a possible swarm container on the host
+	infos, err := inspectContainer(client, fmt.Sprintf("%s_swarm_1", network))
+	if err != nil {
+		return nil, err
+	}
+	if !infos.running {
+		return nil, ErrServiceOffline
+	}
+	// Resolve the port from the host, or the reverse proxy
+	port := infos.portmap["8500/tcp"]
+	if port == 0 {
+		if proxy, _ := checkNginx(client, network); proxy != nil {
+			port = proxy.port
+		}
+	}
+	if port == 0 {
+		return nil, ErrNotExposed
+	}
+	// Resolve the host from the reverse-proxy and the config values
+	host := infos.envvars["VIRTUAL_HOST"]
+	if host == "" {
+		host = client.server
+	}
+	portFull, _ := strconv.Atoi(strings.TrimSuffix(infos.envvars["P2P_PORT"], "/tcp"))
+
+	// Retrieve the gateway account informations
+	var out []byte
+	keyJSON, keyPass := "", ""
+	if out, err = client.Run(fmt.Sprintf("docker exec %s_swarm_1 cat /account.json", network)); err == nil {
+		keyJSON = string(bytes.TrimSpace(out))
+	}
+	if out, err = client.Run(fmt.Sprintf("docker exec %s_swarm_1 cat /account.pass", network)); err == nil {
+		keyPass = string(bytes.TrimSpace(out))
+	}
+	// Run a sanity check to see if the port is reachable
+	if err = checkPort(host, port); err != nil {
+		log.Warn("Swarm gateway seems unreachable", "server", host, "port", port, "err", err)
+	}
+	// Container available, assemble and return the useful infos
+	return &swarmInfos{
+		datadir:     infos.volumes["/root/.ethereum"],
+		host:        host,
+		port:        port,
+		portFull:    portFull,
+		account:     infos.envvars["BZZ_ACCOUNT"],
+		keyJSON:     keyJSON,
+		keyPass:     keyPass,
+		ensEndpoint: infos.envvars["ENS_ENDPOINT"],
+		ensRegistry: infos.envvars["ENS_REGISTRY"],
+	}, nil
+}
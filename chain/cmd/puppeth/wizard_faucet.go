@@ -140,6 +140,23 @@ func (w *wizard) deployFaucet() {
 			infos.captchaSecret = w.readPassword()
 		}
 	}
+	// Plain address requests are only throttled per address and IP, make it opt-in
+	// and only available behind reCaptcha (the faucet refuses to start otherwise)
+	if infos.captchaToken == "" {
+		if infos.direct {
+			log.Warn("Plain address requests disabled, they require reCaptcha protection")
+		}
+		infos.direct = false
+	} else {
+		fmt.Println()
+		if infos.direct {
+			fmt.Println("Accept funding requests for plain addresses over the HTTP API (y/n)? (default = yes)")
+			infos.direct = w.readDefaultString("y") == "y"
+		} else {
+			fmt.Println("Accept funding requests for plain addresses over the HTTP API (y/n)? (default = no)")
+			infos.direct = w.readDefaultString("n") == "y"
+		}
+	}
 	// Figure out where the user wants to store the persistent data
 	fmt.Println()
 	if infos.node.datadir == "" {
@@ -149,11 +166,23 @@ func (w *wizard) deployFaucet() {
 		fmt.Printf("Where should data be stored on the remote machine? (default = %s)\n", infos.node.datadir)
 		infos.node.datadir = w.readDefaultString(infos.node.datadir)
 	}
-	// Figure out which port to listen on
+	// Figure out whether to fund requests through a Boker node or a light client
 	fmt.Println()
-	fmt.Printf("Which TCP/UDP port should the light client listen on? (default = %d)\n", infos.node.portFull)
-	infos.node.portFull = w.readDefaultInt(infos.node.portFull)
-
+	if infos.rpc == "" {
+		fmt.Printf("Which WebSocket RPC endpoint of a Boker node should fund requests (empty = embedded light client)?\n")
+		infos.rpc = w.readDefaultString("")
+	} else {
+		fmt.Printf("Which WebSocket RPC endpoint of a Boker node should fund requests (none = embedded light client)? (default = %s)\n", infos.rpc)
+		if infos.rpc = w.readDefaultString(infos.rpc); infos.rpc == "none" {
+			infos.rpc = ""
+		}
+	}
+	// Figure out which port to listen on
+	if infos.rpc == "" {
+		fmt.Println()
+		fmt.Printf("Which TCP/UDP port should the light client listen on? (default = %d)\n", infos.node.portFull)
+		infos.node.portFull = w.readDefaultInt(infos.node.portFull)
+	}
 	// Set a proper name to report on the stats page
 	fmt.Println()
 	if infos.node.ethstats == "" {
@@ -106,6 +106,14 @@ func (w *wizard) networkStats(tips bool) {
 		} else {
 			services["faucet"] = infos.String()
 		}
+		logger.Debug("Checking for swarm availability")
+		if infos, err := checkSwarm(client, w.network); err != nil {
+			if err != ErrServiceUnknown {
+				services["swarm"] = err.Error()
+			}
+		} else {
+			services["swarm"] = infos.String()
+		}
 		logger.Debug("Checking for dashboard availability")
 		if infos, err := checkDashboard(client, w.network); err != nil {
 			if err != ErrServiceUnknown {
@@ -173,10 +173,11 @@ func (w *wizard) deployComponent() {
 	fmt.Println("What would you like to deploy? (recommended order)")
 	fmt.Println(" 1. Ethstats  - Network monitoring tool")
 	fmt.Println(" 2. Bootnode  - Entry point of the network")
-	fmt.Println(" 3. Sealer    - Full node minting new blocks")
+	fmt.Println(" 3. Validator - Full node minting new blocks")
 	fmt.Println(" 4. Wallet    - Browser wallet for quick sends (todo)")
 	fmt.Println(" 5. Faucet    - Crypto faucet to give away funds")
-	fmt.Println(" 6. Dashboard - Website listing above web-services")
+	fmt.Println(" 6. Swarm     - Gateway to the swarm storage network")
+	fmt.Println(" 7. Dashboard - Website listing above web-services")
 
 	switch w.read() {
 	case "1":
@@ -189,6 +190,8 @@ func (w *wizard) deployComponent() {
 	case "5":
 		w.deployFaucet()
 	case "6":
+		w.deploySwarm()
+	case "7":
 		w.deployDashboard()
 	default:
 		log.Error("That's not something I can do")
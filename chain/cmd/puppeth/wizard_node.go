@@ -21,7 +21,7 @@ import (
 	"fmt"
 	"time"
 
-	_ "github.com/Bokerchain/Boker/chain/accounts/keystore"
+	"github.com/Bokerchain/Boker/chain/accounts/keystore"
 	"github.com/Bokerchain/Boker/chain/log"
 )
 
@@ -79,6 +79,11 @@ func (w *wizard) deployNode(boot bool) {
 	fmt.Printf("How many light peers to allow connecting? (default = %d)\n", infos.peersLight)
 	infos.peersLight = w.readDefaultInt(infos.peersLight)
 
+	// Figure out whether to expose the WebSocket RPC (e.g. for a faucet to use)
+	fmt.Println()
+	fmt.Printf("Which port should the WebSocket RPC listen on (0 = disabled)? (default = %d)\n", infos.portWS)
+	infos.portWS = w.readDefaultInt(infos.portWS)
+
 	// Set a proper name to report on the stats page
 	fmt.Println()
 	if infos.ethstats == "" {
@@ -91,35 +96,36 @@ func (w *wizard) deployNode(boot bool) {
 	// If the node is a miner/signer, load up needed credentials
 	if !boot {
 
-		/*if w.conf.genesis.Config.Dpos != nil {
-			// If a previous signer was already set, offer to reuse it
-			if infos.keyJSON != "" {
-				if key, err := keystore.DecryptKey([]byte(infos.keyJSON), infos.keyPass); err != nil {
+		// If a previous validator account was already set, offer to reuse it
+		if infos.keyJSON != "" {
+			if key, err := keystore.DecryptKey([]byte(infos.keyJSON), infos.keyPass); err != nil {
+				infos.keyJSON, infos.keyPass = "", ""
+			} else {
+				fmt.Println()
+				fmt.Printf("Reuse previous (%s) validator account (y/n)? (default = yes)\n", key.Address.Hex())
+				if w.readDefaultString("y") != "y" {
 					infos.keyJSON, infos.keyPass = "", ""
-				} else {
-					fmt.Println()
-					fmt.Printf("Reuse previous (%s) signing account (y/n)? (default = yes)\n", key.Address.Hex())
-					if w.readDefaultString("y") != "y" {
-						infos.keyJSON, infos.keyPass = "", ""
-					}
 				}
 			}
-			// Clique based signers need a keyfile and unlock password, ask if unavailable
-			if infos.keyJSON == "" {
-				fmt.Println()
-				fmt.Println("Please paste the signer's key JSON:")
-				infos.keyJSON = w.readJSON()
+		}
+		// DPoS validators need a keyfile and unlock password, ask if unavailable
+		if infos.keyJSON == "" {
+			fmt.Println()
+			fmt.Println("Please paste the validator's key JSON:")
+			infos.keyJSON = w.readJSON()
 
-				fmt.Println()
-				fmt.Println("What's the unlock password for the account? (won't be echoed)")
-				infos.keyPass = w.readPassword()
+			fmt.Println()
+			fmt.Println("What's the unlock password for the account? (won't be echoed)")
+			infos.keyPass = w.readPassword()
+		}
+		// The validator mints blocks with its own account as the coinbase
+		key, err := keystore.DecryptKey([]byte(infos.keyJSON), infos.keyPass)
+		if err != nil {
+			log.Error("Failed to decrypt key with given passphrase")
+			return
+		}
+		infos.coinbase = key.Address.Hex()
 
-				if _, err := keystore.DecryptKey([]byte(infos.keyJSON), infos.keyPass); err != nil {
-					log.Error("Failed to decrypt key with given passphrase")
-					return
-				}
-			}
-		}*/
 		// Establish the gas dynamics to be enforced by the signer
 		fmt.Println()
 		fmt.Printf("What gas limit should empty blocks target (MGas)? (default = %0.3f)\n", infos.gasTarget)
@@ -131,7 +137,7 @@ func (w *wizard) deployNode(boot bool) {
 	}
 	// Try to deploy the full node on the host
 	if out, err := deployNode(client, w.network, w.conf.bootFull, w.conf.bootLight, infos); err != nil {
-		log.Error("Failed to deploy Ethereum node container", "err", err)
+		log.Error("Failed to deploy Boker node container", "err", err)
 		if len(out) > 0 {
 			fmt.Printf("%s\n", out)
 		}
@@ -0,0 +1,145 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/accounts/keystore"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+// deploySwarm queries the user for various input on deploying a swarm gateway,
+// after which it executes it.
+func (w *wizard) deploySwarm() {
+	// Do some sanity check before the user wastes time on input
+	if w.conf.genesis == nil {
+		log.Error("No genesis block configured")
+		return
+	}
+	// Select the server to interact with
+	server := w.selectServer()
+	if server == "" {
+		return
+	}
+	client := w.servers[server]
+
+	// Retrieve any active swarm configurations from the server
+	infos, err := checkSwarm(client, w.network)
+	if err != nil {
+		infos = &swarmInfos{
+			port:     80,
+			host:     client.server,
+			portFull: 30399,
+		}
+	}
+	infos.network = w.conf.genesis.Config.ChainId.Int64()
+
+	// Figure out which port to serve the bzz HTTP API on
+	fmt.Println()
+	fmt.Printf("Which port should the swarm gateway listen on? (default = %d)\n", infos.port)
+	infos.port = w.readDefaultInt(infos.port)
+
+	// Figure which virtual-host to deploy the gateway on
+	if infos.host, err = w.ensureVirtualHost(client, infos.port, infos.host); err != nil {
+		log.Error("Failed to decide on swarm host", "err", err)
+		return
+	}
+	// Figure out where the user wants to store the persistent data
+	fmt.Println()
+	if infos.datadir == "" {
+		fmt.Printf("Where should data be stored on the remote machine?\n")
+		infos.datadir = w.readString()
+	} else {
+		fmt.Printf("Where should data be stored on the remote machine? (default = %s)\n", infos.datadir)
+		infos.datadir = w.readDefaultString(infos.datadir)
+	}
+	// Figure out which port to listen on
+	fmt.Println()
+	fmt.Printf("Which TCP/UDP port should swarm listen on? (default = %d)\n", infos.portFull)
+	infos.portFull = w.readDefaultInt(infos.portFull)
+
+	// Names are resolved through the registry contract of a Boker node, if any
+	fmt.Println()
+	if infos.ensEndpoint == "" {
+		fmt.Printf("Which RPC endpoint of a Boker node should resolve names (empty = disabled)?\n")
+		infos.ensEndpoint = w.readDefaultString("")
+	} else {
+		fmt.Printf("Which RPC endpoint of a Boker node should resolve names (none = disabled)? (default = %s)\n", infos.ensEndpoint)
+		if infos.ensEndpoint = w.readDefaultString(infos.ensEndpoint); infos.ensEndpoint == "none" {
+			infos.ensEndpoint = ""
+		}
+	}
+	if infos.ensEndpoint == "" {
+		infos.ensRegistry = ""
+	} else {
+		var registry common.Address
+		if infos.ensRegistry != "" {
+			registry = common.HexToAddress(infos.ensRegistry)
+		}
+		fmt.Println()
+		fmt.Printf("What is the address of the name registry contract? (default = %s)\n", registry.Hex())
+		if registry = w.readDefaultAddress(registry); registry != (common.Address{}) {
+			infos.ensRegistry = registry.Hex()
+		} else {
+			infos.ensRegistry = ""
+		}
+	}
+	// Load up the account the gateway runs with
+	if infos.keyJSON != "" {
+		if key, err := keystore.DecryptKey([]byte(infos.keyJSON), infos.keyPass); err != nil {
+			infos.keyJSON, infos.keyPass = "", ""
+		} else {
+			fmt.Println()
+			fmt.Printf("Reuse previous (%s) swarm account (y/n)? (default = yes)\n", key.Address.Hex())
+			if w.readDefaultString("y") != "y" {
+				infos.keyJSON, infos.keyPass = "", ""
+			}
+		}
+	}
+	if infos.keyJSON == "" {
+		fmt.Println()
+		fmt.Println("Please paste the swarm account's key JSON:")
+		infos.keyJSON = w.readJSON()
+
+		fmt.Println()
+		fmt.Println("What's the unlock password for the account? (won't be echoed)")
+		infos.keyPass = w.readPassword()
+	}
+	key, err := keystore.DecryptKey([]byte(infos.keyJSON), infos.keyPass)
+	if err != nil {
+		log.Error("Failed to decrypt key with given passphrase")
+		return
+	}
+	infos.account = key.Address.Hex()
+
+	// Try to deploy the swarm gateway on the host
+	if out, err := deploySwarm(client, w.network, w.conf.bootFull, infos); err != nil {
+		log.Error("Failed to deploy swarm container", "err", err)
+		if len(out) > 0 {
+			fmt.Printf("%s\n", out)
+		}
+		return
+	}
+	// All ok, run a network scan to pick any changes up
+	log.Info("Waiting for swarm to finish booting")
+	time.Sleep(3 * time.Second)
+
+	w.networkStats(false)
+}
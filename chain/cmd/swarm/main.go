@@ -110,6 +110,14 @@ var (
 		Name:  "ens-addr",
 		Usage: "ENS contract address (default is detected as testnet or mainnet using --ens-api)",
 	}
+	SwarmEnsEndpointFlag = cli.StringFlag{
+		Name:  "swarm.ens-endpoint",
+		Usage: "URL of the Boker chain API used to resolve names through the registry contract (overrides --ens-api)",
+	}
+	SwarmEnsRegistryFlag = cli.StringFlag{
+		Name:  "swarm.ens-registry",
+		Usage: "Address of the name registry contract on the Boker chain (overrides --ens-addr)",
+	}
 	SwarmApiFlag = cli.StringFlag{
 		Name:  "bzzapi",
 		Usage: "Swarm HTTP endpoint",
@@ -135,6 +143,18 @@ var (
 		Name:  "mime",
 		Usage: "force mime type",
 	}
+	SwarmEncryptedFlag = cli.BoolFlag{
+		Name:  "encrypt",
+		Usage: "use encrypted upload, the returned hash contains the decryption key",
+	}
+	SwarmByteRateLimitFlag = cli.Uint64Flag{
+		Name:  "ratelimit.bytes",
+		Usage: "Bytes per second served to each HTTP API client (API key or IP address), 0 = unlimited",
+	}
+	SwarmRequestRateLimitFlag = cli.Uint64Flag{
+		Name:  "ratelimit.requests",
+		Usage: "Requests per second of each HTTP API client (API key or IP address), 0 = unlimited",
+	}
 	CorsStringFlag = cli.StringFlag{
 		Name:  "corsdomain",
 		Usage: "Domain on which to send Access-Control-Allow-Origin header (multiple domains can be supplied separated by a ',')",
@@ -323,8 +343,12 @@ DEPRECATED: use 'swarm db clean'.
 		utils.PasswordFileFlag,
 		// bzzd-specific flags
 		CorsStringFlag,
+		SwarmByteRateLimitFlag,
+		SwarmRequestRateLimitFlag,
 		EnsAPIFlag,
 		EnsAddrFlag,
+		SwarmEnsEndpointFlag,
+		SwarmEnsRegistryFlag,
 		SwarmConfigPathFlag,
 		SwarmSwapEnabledFlag,
 		SwarmSwapAPIFlag,
@@ -341,6 +365,7 @@ DEPRECATED: use 'swarm db clean'.
 		SwarmUploadDefaultPath,
 		SwarmUpFromStdinFlag,
 		SwarmUploadMimeType,
+		SwarmEncryptedFlag,
 		//deprecated flags
 		DeprecatedEthAPIFlag,
 	}
@@ -473,7 +498,23 @@ func registerBzzService(ctx *cli.Context, stack *node.Node) {
 	}
 
 	ensapi := ctx.GlobalString(EnsAPIFlag.Name)
+	if ctx.GlobalIsSet(SwarmEnsEndpointFlag.Name) {
+		ensapi = ctx.GlobalString(SwarmEnsEndpointFlag.Name)
+	}
 	ensAddr := ctx.GlobalString(EnsAddrFlag.Name)
+	if ctx.GlobalIsSet(SwarmEnsRegistryFlag.Name) {
+		ensAddr = ctx.GlobalString(SwarmEnsRegistryFlag.Name)
+	}
+	if ensAddr != "" && !common.IsHexAddress(ensAddr) {
+		utils.Fatalf("Invalid name registry address %q", ensAddr)
+	}
+
+	if ctx.GlobalIsSet(SwarmByteRateLimitFlag.Name) {
+		bzzconfig.ByteRateLimit = ctx.GlobalUint64(SwarmByteRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(SwarmRequestRateLimitFlag.Name) {
+		bzzconfig.RequestRateLimit = ctx.GlobalUint64(SwarmRequestRateLimitFlag.Name)
+	}
 
 	cors := ctx.GlobalString(CorsStringFlag.Name)
 
@@ -503,7 +544,7 @@ func registerBzzService(ctx *cli.Context, stack *node.Node) {
 				if err == nil {
 					bzzconfig.EnsRoot = ensAddr
 				} else {
-					log.Warn(fmt.Sprintf("could not determine ENS contract address, using default %s", bzzconfig.EnsRoot), "err", err)
+					log.Warn(fmt.Sprintf("could not determine ENS contract address, using default %s (set --%s for the Boker chain)", bzzconfig.EnsRoot, SwarmEnsRegistryFlag.Name), "err", err)
 				}
 			}
 		}
@@ -45,6 +45,7 @@ func upload(ctx *cli.Context) {
 		defaultPath  = ctx.GlobalString(SwarmUploadDefaultPath.Name)
 		fromStdin    = ctx.GlobalBool(SwarmUpFromStdinFlag.Name)
 		mimeType     = ctx.GlobalString(SwarmUploadMimeType.Name)
+		encrypt      = ctx.GlobalBool(SwarmEncryptedFlag.Name)
 		client       = swarm.NewClient(bzzapi)
 		file         string
 	)
@@ -76,7 +77,11 @@ func upload(ctx *cli.Context) {
 			utils.Fatalf("Error opening file: %s", err)
 		}
 		defer f.Close()
-		hash, err := client.UploadRaw(f, f.Size)
+		upload := client.UploadRaw
+		if encrypt {
+			upload = client.UploadRawEncrypted
+		}
+		hash, err := upload(f, f.Size)
 		if err != nil {
 			utils.Fatalf("Upload failed: %s", err)
 		}
@@ -97,6 +102,9 @@ func upload(ctx *cli.Context) {
 			if !recursive {
 				return "", errors.New("Argument is a directory and recursive upload is disabled")
 			}
+			if encrypt {
+				return client.UploadDirectoryEncrypted(file, defaultPath)
+			}
 			return client.UploadDirectory(file, defaultPath, "")
 		}
 	} else {
@@ -110,6 +118,9 @@ func upload(ctx *cli.Context) {
 				mimeType = detectMimeType(file)
 			}
 			f.ContentType = mimeType
+			if encrypt {
+				return client.UploadEncrypted(f)
+			}
 			return client.Upload(f, "")
 		}
 	}
@@ -19,6 +19,7 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -26,9 +27,11 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/accounts"
 	"github.com/Bokerchain/Boker/chain/accounts/keystore"
+	"github.com/Bokerchain/Boker/chain/boker/gossip"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/consensus/dpos"
 	"github.com/Bokerchain/Boker/chain/core"
@@ -38,12 +41,16 @@ import (
 	"github.com/Bokerchain/Boker/chain/dashboard"
 	"github.com/Bokerchain/Boker/chain/eth"
 	"github.com/Bokerchain/Boker/chain/eth/downloader"
+	"github.com/Bokerchain/Boker/chain/eth/filters"
 	"github.com/Bokerchain/Boker/chain/eth/gasprice"
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/ethstats"
+	"github.com/Bokerchain/Boker/chain/internal/ethapi"
+	"github.com/Bokerchain/Boker/chain/internal/graphql"
 	"github.com/Bokerchain/Boker/chain/les"
 	"github.com/Bokerchain/Boker/chain/log"
 	"github.com/Bokerchain/Boker/chain/metrics"
+	"github.com/Bokerchain/Boker/chain/metrics/prometheus"
 	"github.com/Bokerchain/Boker/chain/node"
 	"github.com/Bokerchain/Boker/chain/p2p"
 	"github.com/Bokerchain/Boker/chain/p2p/discover"
@@ -51,7 +58,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/p2p/nat"
 	"github.com/Bokerchain/Boker/chain/p2p/netutil"
 	"github.com/Bokerchain/Boker/chain/params"
-	whisper "github.com/Bokerchain/Boker/chain/whisper/whisperv5"
+	whisper "github.com/Bokerchain/Boker/chain/whisper/whisperv6"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -114,6 +121,11 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Key-value store backing the databases (\"leveldb\" or \"badger\", badger requires -tags badger)",
+		Value: ethdb.DefaultEngine,
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -122,6 +134,10 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer used for block seals and transactions (IPC path or HTTP URL)",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby)",
@@ -151,6 +167,31 @@ var (
 		Value: &defaultSyncMode,
 	}
 
+	AddressIndexFlag = cli.BoolFlag{
+		Name:  "addrindex",
+		Usage: "Maintain an address to transaction index (backfilled in the background) for eth_getTransactionsByAddress",
+	}
+	BalanceIndexFlag = cli.BoolFlag{
+		Name:  "balanceindex",
+		Usage: "Record account balance changes of processed blocks for boker_getBalanceHistory",
+	}
+	TokenIndexFlag = cli.BoolFlag{
+		Name:  "tokenindex",
+		Usage: "Maintain an index of ERC-20 Transfer events (backfilled in the background) for boker_getTokenTransfers and boker_getTokenBalances",
+	}
+	InternalTxIndexFlag = cli.BoolFlag{
+		Name:  "internaltxindex",
+		Usage: "Trace value transfers made by contracts while processing blocks for debug_getInternalTransactions",
+	}
+	CheckpointFlag = cli.StringFlag{
+		Name:  "checkpoint",
+		Usage: "Trusted checkpoint overriding the built-in one (<section>:<head hash>:<cht root>[:<bloom root>])",
+	}
+	CheckpointDisableFlag = cli.BoolFlag{
+		Name:  "checkpoint.disable",
+		Usage: "Disable the trusted checkpoint and allow syncing from peers that do not contain it",
+	}
+
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -161,10 +202,22 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: 20,
 	}
+	LightCapacityFlag = cli.Uint64Flag{
+		Name:  "lightcapacity",
+		Usage: "Total request capacity shared by LES clients, in cost units per millisecond (0 = unlimited)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	KeyStoreScryptNFlag = cli.IntFlag{
+		Name:  "keystore.scryptn",
+		Usage: "Scrypt N parameter used to encrypt new keys (overrides the standard/light preset)",
+	}
+	KeyStoreScryptPFlag = cli.IntFlag{
+		Name:  "keystore.scryptp",
+		Usage: "Scrypt P parameter used to encrypt new keys (overrides the standard/light preset)",
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  "dashboard",
@@ -241,6 +294,16 @@ var (
 		Usage: "Megabytes of memory allocated to internal caching (min 16MB / database forced)",
 		Value: 128,
 	}
+	TrieNodeCacheFlag = cli.IntFlag{
+		Name:  "cache.trie",
+		Usage: "Megabytes of memory allocated to the shared trie node cache (3/4 clean, 1/4 dirty)",
+		Value: eth.DefaultConfig.TrieCache,
+	}
+	FreezerThresholdFlag = cli.Uint64Flag{
+		Name:  "freezer.threshold",
+		Usage: "Number of recent blocks kept in the key-value store, older ones move to the ancient store (0 = disabled)",
+		Value: eth.DefaultConfig.FreezerThreshold,
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
@@ -253,7 +316,7 @@ var (
 	}
 	TargetGasLimitFlag = cli.Uint64Flag{
 		Name:  "targetgaslimit",
-		Usage: "Target gas limit sets the artificial target gas floor for the blocks to mine",
+		Usage: "Target gas limit the produced blocks move their gas limit towards (changeable via miner_setGasLimitTarget)",
 		Value: params.GenesisGasLimit.Uint64(),
 	}
 	ValidatorFlag = cli.StringFlag{
@@ -275,12 +338,38 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	MinerSlotMarginFlag = cli.DurationFlag{
+		Name:  "miner.slotmargin",
+		Usage: "Time before the producer slot to start assembling the block (e.g. 500ms)",
+	}
+	DposWatchdogFlag = cli.BoolFlag{
+		Name:  "dpos.watchdog",
+		Usage: "Log an error and count a metric whenever the local producer misses its slot",
+	}
+	DposClockDriftFlag = cli.DurationFlag{
+		Name:  "dpos.clockdrift",
+		Usage: "Maximum local clock drift against NTP time, enables the clock check and slot time compensation (e.g. 500ms)",
+	}
+	DposClockStrictFlag = cli.BoolFlag{
+		Name:  "dpos.clockstrict",
+		Usage: "Refuse to seal blocks while the local clock drift exceeds --dpos.clockdrift",
+	}
+	DposNTPServersFlag = cli.StringFlag{
+		Name:  "dpos.ntpservers",
+		Usage: "Comma separated NTP servers used for the clock check",
+		Value: strings.Join(dpos.DefaultNTPServers, ","),
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
 		Usage: "Comma separated list of accounts to unlock",
 		Value: "",
 	}
+	UnlockSealOnlyFlag = cli.StringFlag{
+		Name:  "unlock.sealonly",
+		Usage: "Comma separated list of producer accounts to unlock for signing blocks only",
+		Value: "",
+	}
 	PasswordFileFlag = cli.StringFlag{
 		Name:  "password",
 		Usage: "Password file to use for non-interactive password input",
@@ -291,6 +380,18 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	VMEnableFusionFlag = cli.BoolFlag{
+		Name:  "vmfusion",
+		Usage: "Execute common VM instruction pairs as single instructions",
+	}
+	VMParallelTxFlag = cli.IntFlag{
+		Name:  "vmparallel",
+		Usage: "Number of workers executing non-conflicting block transactions concurrently (0 = serial)",
+	}
+	VMTracersDirFlag = DirectoryFlag{
+		Name:  "vm.tracers-dir",
+		Usage: "Directory of custom tracers (.js files or .so Go plugins) registered by file name",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -300,6 +401,15 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsPrometheusFlag = cli.BoolFlag{
+		Name:  metrics.MetricsPrometheusFlag,
+		Usage: "Enable the Prometheus metrics HTTP exporter (implies --metrics)",
+	}
+	MetricsPrometheusAddrFlag = cli.StringFlag{
+		Name:  "metrics.prometheus.addr",
+		Usage: "Listening address of the Prometheus metrics exporter",
+		Value: "127.0.0.1:6061",
+	}
 	NoCompactionFlag = cli.BoolFlag{
 		Name:  "nocompaction",
 		Usage: "Disables db compaction after import",
@@ -361,10 +471,118 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	RPCVirtualHostsFlag = cli.StringFlag{
+		Name:  "rpcvhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
+	}
+	RPCAuthApiFlag = cli.StringFlag{
+		Name:  "rpc.authapi",
+		Usage: "API's requiring credentials over the HTTP-RPC interface once an API key or JWT secret is set",
+		Value: strings.Join(node.DefaultConfig.HTTPAuthModules, ","),
+	}
+	RPCAPIKeyFlag = cli.StringFlag{
+		Name:  "rpc.apikey",
+		Usage: "Shared secret clients must send as bearer token or X-API-Key header to call the protected HTTP-RPC API's",
+		Value: "",
+	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwtsecret",
+		Usage: "Path to a hex encoded secret the JWT tokens calling the protected HTTP-RPC API's must be signed with",
+		Value: "",
+	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in an HTTP/WS-RPC batch (0 = unlimited)",
+		Value: node.DefaultRPCBatchLimit,
+	}
+	RPCResponseLimitFlag = cli.IntFlag{
+		Name:  "rpc.responselimit",
+		Usage: "Maximum size in bytes of an HTTP/WS-RPC call result (0 = unlimited)",
+		Value: node.DefaultRPCResponseLimit,
+	}
+	RPCTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.timeout",
+		Usage: "Execution timeout of context aware HTTP/WS-RPC methods (0 = unlimited)",
+		Value: node.DefaultRPCTimeout,
+	}
+	RPCMethodTimeoutsFlag = cli.StringFlag{
+		Name:  "rpc.methodtimeouts",
+		Usage: "Comma separated per method execution timeouts (e.g. eth_call=5s,eth_getLogs=1m)",
+		Value: "",
+	}
+	RPCLogsMaxResultsFlag = cli.IntFlag{
+		Name:  "rpc.logs.maxresults",
+		Usage: "Number of logs after which a log query stops and returns a continuation cursor (0 = unlimited)",
+		Value: eth.DefaultConfig.FilterLimits.MaxResults,
+	}
+	RPCLogsTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.logs.timeout",
+		Usage: "Approximate time after which a log query stops and returns a continuation cursor (0 = unlimited)",
+		Value: eth.DefaultConfig.FilterLimits.Timeout,
+	}
+	RPCFiltersMaxFlag = cli.IntFlag{
+		Name:  "rpc.filters.max",
+		Usage: "Maximum number of polling filters installed per connection, HTTP requests share one quota (0 = unlimited)",
+		Value: eth.DefaultConfig.FilterQuotas.MaxFilters,
+	}
+	RPCFiltersTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.filters.timeout",
+		Usage: "Time after which a polling filter that is not polled is removed",
+		Value: eth.DefaultConfig.FilterQuotas.FilterTimeout,
+	}
+	RPCSubscriptionsMaxFlag = cli.IntFlag{
+		Name:  "rpc.subscriptions.max",
+		Usage: "Maximum number of active subscriptions per WS/IPC connection (0 = unlimited)",
+		Value: eth.DefaultConfig.FilterQuotas.MaxSubscriptions,
+	}
+	RPCCallCacheFlag = cli.IntFlag{
+		Name:  "rpc.callcache",
+		Usage: "Number of eth_call results cached for view calls on sealed blocks (0 = disabled)",
+		Value: eth.DefaultConfig.CallCache.Size,
+	}
+	RPCCallCacheTTLFlag = cli.DurationFlag{
+		Name:  "rpc.callcache.ttl",
+		Usage: "Maximum time an eth_call result stays cached (0 = until the next block)",
+		Value: eth.DefaultConfig.CallCache.TTL,
+	}
+	RPCGasCapFlag = cli.Uint64Flag{
+		Name:  "rpc.gascap",
+		Usage: "Gas limit of eth_call and eth_estimateGas, calls are gas metered when set (0 = no cap)",
+		Value: eth.DefaultConfig.RPCGasCap,
+	}
+	RPCTxFeeCapFlag = cli.Float64Flag{
+		Name:  "rpc.txfeecap",
+		Usage: "Maximum fee in ether of transactions sent or signed over RPC (0 = no cap)",
+		Value: eth.DefaultConfig.RPCTxFeeCap,
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: node.DefaultGraphQLHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: node.DefaultGraphQLPort,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin requests (browser enforced)",
+		Value: "",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
 	}
+	ExecJSONFlag = cli.StringFlag{
+		Name:  "exec.json",
+		Usage: "Execute JavaScript statement and print the result as JSON (console output goes to stderr)",
+	}
 	PreloadJSFlag = cli.StringFlag{
 		Name:  "preload",
 		Usage: "Comma separated list of JavaScript files to preload into the console",
@@ -422,6 +640,11 @@ var (
 		Name:  "v5disc",
 		Usage: "Enables the experimental RLPx V5 (Topic Discovery) mechanism",
 	}
+	DiscoveryDNSFlag = cli.StringFlag{
+		Name:  "discovery.dns",
+		Usage: "Comma separated enrtree:// URLs of DNS published node lists used for bootstrapping",
+		Value: "",
+	}
 	NetrestrictFlag = cli.StringFlag{
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
@@ -445,6 +668,11 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: eth.DefaultConfig.GPO.Percentile,
 	}
+	GpoMaxHistoryFlag = cli.IntFlag{
+		Name:  "gpomaxhistory",
+		Usage: "Maximum number of blocks served by a single eth_gasPriceHistory request",
+		Value: eth.DefaultConfig.GPO.MaxHistory,
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -459,6 +687,10 @@ var (
 		Usage: "Minimum POW accepted",
 		Value: whisper.DefaultMinimumPoW,
 	}
+	WhisperValidatorGossipFlag = cli.BoolFlag{
+		Name:  "shh.gossip",
+		Usage: "Exchange liveness pings and maintenance announcements with the other validators over Whisper",
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -610,6 +842,18 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCApiFlag.Name) {
 		cfg.HTTPModules = splitAndTrim(ctx.GlobalString(RPCApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
+		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCAuthApiFlag.Name) {
+		cfg.HTTPAuthModules = splitAndTrim(ctx.GlobalString(RPCAuthApiFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCAPIKeyFlag.Name) {
+		cfg.HTTPAPIKey = ctx.GlobalString(RPCAPIKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.HTTPJWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -633,6 +877,52 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCLimits applies the batch, response size and execution time limits of
+// the HTTP and websocket RPC servers from the set command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.RPCBatchLimit = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseLimitFlag.Name) {
+		cfg.RPCResponseLimit = ctx.GlobalInt(RPCResponseLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTimeoutFlag.Name) {
+		cfg.RPCTimeout = ctx.GlobalDuration(RPCTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodTimeoutsFlag.Name) {
+		cfg.RPCMethodTimeouts = make(map[string]time.Duration)
+		for _, entry := range splitAndTrim(ctx.GlobalString(RPCMethodTimeoutsFlag.Name)) {
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 {
+				Fatalf("Invalid method timeout %q, expected method=duration", entry)
+			}
+			timeout, err := time.ParseDuration(parts[1])
+			if err != nil {
+				Fatalf("Invalid method timeout %q: %v", entry, err)
+			}
+			cfg.RPCMethodTimeouts[strings.TrimSpace(parts[0])] = timeout
+		}
+	}
+}
+
+// setGraphQL creates the GraphQL listener interface string from the set
+// command line flags, returning empty if the GraphQL endpoint is disabled.
+func setGraphQL(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(GraphQLEnabledFlag.Name) && cfg.GraphQLHost == "" {
+		cfg.GraphQLHost = "127.0.0.1"
+		if ctx.GlobalIsSet(GraphQLListenAddrFlag.Name) {
+			cfg.GraphQLHost = ctx.GlobalString(GraphQLListenAddrFlag.Name)
+		}
+	}
+
+	if ctx.GlobalIsSet(GraphQLPortFlag.Name) {
+		cfg.GraphQLPort = ctx.GlobalInt(GraphQLPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLCORSDomainFlag.Name) {
+		cfg.GraphQLCors = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -772,6 +1062,19 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.DiscoveryV5 = true
 	}
 
+	if ctx.GlobalIsSet(DiscoveryDNSFlag.Name) {
+		cfg.DiscoveryDNS = nil
+		for _, url := range strings.Split(ctx.GlobalString(DiscoveryDNSFlag.Name), ",") {
+			if url = strings.TrimSpace(url); url == "" {
+				continue
+			}
+			if err := discv5.ParseDNSURL(url); err != nil {
+				Fatalf("Option %q: invalid URL %q: %v", DiscoveryDNSFlag.Name, url, err)
+			}
+			cfg.DiscoveryDNS = append(cfg.DiscoveryDNS, url)
+		}
+	}
+
 	if netrestrict := ctx.GlobalString(NetrestrictFlag.Name); netrestrict != "" {
 		list, err := netutil.ParseNetlist(netrestrict)
 		if err != nil {
@@ -787,12 +1090,17 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setGraphQL(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
 	case ctx.GlobalIsSet(DataDirFlag.Name):
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
 	}
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		cfg.DBEngine = ctx.GlobalString(DBEngineFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
@@ -800,9 +1108,18 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(LightKDFFlag.Name) {
 		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	}
+	if ctx.GlobalIsSet(KeyStoreScryptNFlag.Name) {
+		cfg.KeyStoreScryptN = ctx.GlobalInt(KeyStoreScryptNFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreScryptPFlag.Name) {
+		cfg.KeyStoreScryptP = ctx.GlobalInt(KeyStoreScryptPFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
 }
 
 func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
@@ -812,6 +1129,39 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
 		cfg.Percentile = ctx.GlobalInt(GpoPercentileFlag.Name)
 	}
+	if ctx.GlobalIsSet(GpoMaxHistoryFlag.Name) {
+		cfg.MaxHistory = ctx.GlobalInt(GpoMaxHistoryFlag.Name)
+	}
+}
+
+func setFilterLimits(ctx *cli.Context, cfg *filters.Limits) {
+	if ctx.GlobalIsSet(RPCLogsMaxResultsFlag.Name) {
+		cfg.MaxResults = ctx.GlobalInt(RPCLogsMaxResultsFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCLogsTimeoutFlag.Name) {
+		cfg.Timeout = ctx.GlobalDuration(RPCLogsTimeoutFlag.Name)
+	}
+}
+
+func setFilterQuotas(ctx *cli.Context, cfg *filters.Quotas) {
+	if ctx.GlobalIsSet(RPCFiltersMaxFlag.Name) {
+		cfg.MaxFilters = ctx.GlobalInt(RPCFiltersMaxFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCFiltersTimeoutFlag.Name) {
+		cfg.FilterTimeout = ctx.GlobalDuration(RPCFiltersTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCSubscriptionsMaxFlag.Name) {
+		cfg.MaxSubscriptions = ctx.GlobalInt(RPCSubscriptionsMaxFlag.Name)
+	}
+}
+
+func setCallCache(ctx *cli.Context, cfg *ethapi.CallCacheConfig) {
+	if ctx.GlobalIsSet(RPCCallCacheFlag.Name) {
+		cfg.Size = ctx.GlobalInt(RPCCallCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCCallCacheTTLFlag.Name) {
+		cfg.TTL = ctx.GlobalDuration(RPCCallCacheTTLFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -879,6 +1229,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	//setValidator(ctx, ks, cfg)
 	setCoinbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
+	setFilterLimits(ctx, &cfg.FilterLimits)
+	setFilterQuotas(ctx, &cfg.FilterQuotas)
+	setCallCache(ctx, &cfg.CallCache)
 	setTxPool(ctx, &cfg.TxPool)
 
 	switch {
@@ -889,12 +1242,33 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	case ctx.GlobalBool(LightModeFlag.Name):
 		cfg.SyncMode = downloader.LightSync
 	}
+	if ctx.GlobalIsSet(AddressIndexFlag.Name) {
+		cfg.AddressIndex = ctx.GlobalBool(AddressIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(BalanceIndexFlag.Name) {
+		cfg.BalanceIndex = ctx.GlobalBool(BalanceIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(TokenIndexFlag.Name) {
+		cfg.TokenIndex = ctx.GlobalBool(TokenIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(InternalTxIndexFlag.Name) {
+		cfg.InternalTxIndex = ctx.GlobalBool(InternalTxIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCGasCapFlag.Name) {
+		cfg.RPCGasCap = ctx.GlobalUint64(RPCGasCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTxFeeCapFlag.Name) {
+		cfg.RPCTxFeeCap = ctx.GlobalFloat64(RPCTxFeeCapFlag.Name)
+	}
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(LightCapacityFlag.Name) {
+		cfg.LightCapacity = ctx.GlobalUint64(LightCapacityFlag.Name)
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -903,6 +1277,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name)
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
+	if ctx.GlobalIsSet(TrieNodeCacheFlag.Name) {
+		cfg.TrieCache = ctx.GlobalInt(TrieNodeCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(FreezerThresholdFlag.Name) {
+		cfg.FreezerThreshold = ctx.GlobalUint64(FreezerThresholdFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
 		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
@@ -910,6 +1290,31 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(ExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.GlobalString(ExtraDataFlag.Name))
 	}
+	if ctx.GlobalIsSet(MinerSlotMarginFlag.Name) {
+		cfg.MinerSlotMargin = ctx.GlobalDuration(MinerSlotMarginFlag.Name)
+	}
+	if ctx.GlobalIsSet(DposWatchdogFlag.Name) {
+		cfg.DposWatchdog = ctx.GlobalBool(DposWatchdogFlag.Name)
+	}
+	if ctx.GlobalIsSet(DposClockDriftFlag.Name) {
+		cfg.DposClockDrift = ctx.GlobalDuration(DposClockDriftFlag.Name)
+	}
+	if ctx.GlobalIsSet(DposClockStrictFlag.Name) {
+		cfg.DposClockStrict = ctx.GlobalBool(DposClockStrictFlag.Name)
+	}
+	if ctx.GlobalIsSet(DposNTPServersFlag.Name) {
+		cfg.DposNTPServers = strings.Split(ctx.GlobalString(DposNTPServersFlag.Name), ",")
+	}
+	if ctx.GlobalIsSet(CheckpointFlag.Name) {
+		checkpoint, err := params.ParseCheckpoint(ctx.GlobalString(CheckpointFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", CheckpointFlag.Name, err)
+		}
+		cfg.Checkpoint = checkpoint
+	}
+	if ctx.GlobalIsSet(CheckpointDisableFlag.Name) {
+		cfg.NoCheckpoint = ctx.GlobalBool(CheckpointDisableFlag.Name)
+	}
 	if ctx.GlobalIsSet(GasPriceFlag.Name) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
@@ -917,6 +1322,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(VMEnableFusionFlag.Name) {
+		cfg.EnableVMFusion = ctx.GlobalBool(VMEnableFusionFlag.Name)
+	}
+	if ctx.GlobalIsSet(VMParallelTxFlag.Name) {
+		cfg.ParallelTxWorkers = ctx.GlobalInt(VMParallelTxFlag.Name)
+	}
+	if ctx.GlobalIsSet(VMTracersDirFlag.Name) {
+		cfg.TracersDir = ctx.GlobalString(VMTracersDirFlag.Name)
+	}
 }
 
 // SetDashboardConfig applies dashboard related command line flags to the config.
@@ -969,6 +1383,24 @@ func RegisterShhService(stack *node.Node, cfg *whisper.Config) {
 	}
 }
 
+// RegisterValidatorGossipService adds the validator gossip service, exchanging
+// liveness pings and maintenance announcements over Whisper, to the given node.
+func RegisterValidatorGossipService(stack *node.Node) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var shhServ *whisper.Whisper
+		if err := ctx.Service(&shhServ); err != nil {
+			return nil, err
+		}
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err != nil {
+			return nil, err
+		}
+		return gossip.New(shhServ, ethServ)
+	}); err != nil {
+		Fatalf("Failed to register the validator gossip service: %v", err)
+	}
+}
+
 // RegisterEthStatsService configures the Ethereum Stats daemon and adds it to
 // th egiven node.
 func RegisterEthStatsService(stack *node.Node, url string) {
@@ -986,12 +1418,41 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// RegisterGraphQLService adds the GraphQL service, answering queries against
+// either the full or the light Ethereum service, to the stack.
+func RegisterGraphQLService(stack *node.Node, endpoint string, cors []string) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err == nil {
+			return graphql.New(ethServ.ApiBackend, endpoint, cors)
+		}
+		var lesServ *les.LightEthereum
+		if err := ctx.Service(&lesServ); err == nil {
+			return graphql.New(lesServ.ApiBackend, endpoint, cors)
+		}
+		return nil, errors.New("no Ethereum service")
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
 	params.TargetGasLimit = new(big.Int).SetUint64(ctx.GlobalUint64(TargetGasLimitFlag.Name))
 }
 
+// SetupMetrics starts the Prometheus metrics exporter if it was requested.
+func SetupMetrics(ctx *cli.Context) {
+	if !ctx.GlobalBool(MetricsPrometheusFlag.Name) {
+		return
+	}
+	if !metrics.Enabled {
+		log.Warn("Metrics collection is disabled, Prometheus exporter will report no data")
+	}
+	prometheus.Start(ctx.GlobalString(MetricsPrometheusAddrFlag.Name))
+}
+
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 	var (
@@ -1006,6 +1467,11 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
+	if dir := stack.ResolvePath(name); dir != "" && name == "chaindata" {
+		if chainDb, err = core.NewFreezerDatabase(chainDb, core.FreezerDir(dir)); err != nil {
+			Fatalf("Could not open ancient database: %v", err)
+		}
+	}
 	return chainDb
 }
 
@@ -1020,7 +1486,10 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	}
 	//engine := dpos.New(config.Dpos, chainDb)
 	engine := dpos.New(&params.DposConfig{}, chainDb)
-	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	vmcfg := vm.Config{
+		EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name),
+		EnableFusion:            ctx.GlobalBool(VMEnableFusionFlag.Name),
+	}
 
 	var ethereum *eth.Ethereum
 	if err := stack.Service(&ethereum); err != nil {
@@ -1032,6 +1501,10 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		Fatalf("Can't create BlockChain: %v", err)
 	}
 	chain.SetBoker(ethereum.Boker())
+	chain.SetParallelWorkers(ctx.GlobalInt(VMParallelTxFlag.Name))
+	if trieCache := ctx.GlobalInt(TrieNodeCacheFlag.Name); trieCache > 0 {
+		chain.SetTrieCache(trieCache*1024*1024*3/4, trieCache*1024*1024/4)
+	}
 	return chain, chainDb
 }
 
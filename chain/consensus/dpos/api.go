@@ -1,11 +1,15 @@
 package dpos
 
 import (
+	"fmt"
+
 	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/consensus"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/rpc"
+	"github.com/Bokerchain/Boker/chain/trie"
 
 	"math/big"
 )
@@ -29,28 +33,142 @@ func (api *API) GetValidators(number *rpc.BlockNumber) ([]common.Address, error)
 		return nil, protocol.ErrUnknownBlock
 	}
 
-	epochTrie, err := types.NewEpochTrie(header.DposProto.EpochHash, api.dpos.db)
+	return api.dpos.Validators(header)
+}
+
+// GetConfirmedBlockNumber retrieves the latest irreversible block
+func (api *API) GetConfirmedBlockNumber() (*big.Int, error) {
+	header, err := api.dpos.confirmedHeader(api.chain)
 	if err != nil {
 		return nil, err
 	}
-	dposContext := types.DposContext{}
-	dposContext.SetEpoch(epochTrie)
-	validators, err := dposContext.GetEpochTrie()
+	return header.Number, nil
+}
+
+// GetIrreversibility retrieves the latest irreversible block header together with
+// the number of distinct validators that signed blocks on top of it and how many
+// more are needed before finality advances.
+func (api *API) GetIrreversibility() (*Irreversibility, error) {
+	return api.dpos.irreversibility(api.chain)
+}
+
+// SelfCheck reports whether the given account (the authorized signer by default)
+// is in the current validator set, whether the keystore can sign for it, the last
+// block it produced, the estimated clock skew against peers and the producer
+// slots it missed within the last hour.
+func (api *API) SelfCheck(coinbase *common.Address) (*SelfCheck, error) {
+	account, _ := api.dpos.authorized()
+	if coinbase != nil {
+		account = *coinbase
+	}
+	return api.dpos.selfCheck(api.chain, account)
+}
+
+// ContextProof is the merkle proof of a key in one of the DPoS context tries
+// of a block. The key is the key looked up in the trie including the prefix
+// of the trie, the proof has to be verified against it and the root, which is
+// part of the dposContext field of the block header.
+type ContextProof struct {
+	Trie  string        `json:"trie"`
+	Root  common.Hash   `json:"root"`
+	Key   hexutil.Bytes `json:"key"`
+	Value hexutil.Bytes `json:"value"`
+	Proof []string      `json:"proof"`
+}
+
+// proofList collects the encoded nodes of a merkle proof in path order.
+type proofList []string
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, hexutil.Encode(value))
+	return nil
+}
+
+// GetProof returns the merkle proof of the key in the given DPoS context trie
+// ("epoch", "validator" or "blockCnt") at the specified block.
+func (api *API) GetProof(name string, key hexutil.Bytes, number *rpc.BlockNumber) (*ContextProof, error) {
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil || header.DposProto == nil {
+		return nil, protocol.ErrUnknownBlock
+	}
+
+	var (
+		root   common.Hash
+		prefix []byte
+	)
+	switch name {
+	case "epoch":
+		root, prefix = header.DposProto.EpochHash, protocol.EpochPrefix
+	case "validator":
+		root, prefix = header.DposProto.ValidatorHash, protocol.ValidatorPrefix
+	case "blockCnt":
+		root, prefix = header.DposProto.BlockCntHash, protocol.BlockCntPrefix
+	default:
+		return nil, fmt.Errorf("unknown dpos context trie %q", name)
+	}
+	tr, err := trie.NewTrieWithPrefix(root, prefix, api.dpos.db)
+	if err != nil {
+		return nil, err
+	}
+	value, err := tr.TryGet(key)
 	if err != nil {
 		return nil, err
 	}
-	return validators, nil
+	var proof proofList
+	if err := tr.Prove(key, 0, &proof); err != nil {
+		return nil, err
+	}
+	return &ContextProof{
+		Trie:  name,
+		Root:  root,
+		Key:   append(append([]byte{}, prefix...), key...),
+		Value: value,
+		Proof: proof,
+	}, nil
 }
 
-// GetConfirmedBlockNumber retrieves the latest irreversible block
-func (api *API) GetConfirmedBlockNumber() (*big.Int, error) {
-	var err error
-	header := api.dpos.confirmedBlockHeader
+// checkpointHeader retrieves the header of the given epoch boundary block, the
+// last block of an epoch on the canonical chain.
+func (api *API) checkpointHeader(hash common.Hash) (*types.Header, error) {
+	header := api.chain.GetHeaderByHash(hash)
 	if header == nil {
-		header, err = api.dpos.loadConfirmedBlockHeader(api.chain)
-		if err != nil {
-			return nil, err
-		}
+		return nil, protocol.ErrUnknownBlock
 	}
-	return header.Number, nil
+	child := api.chain.GetHeaderByNumber(header.Number.Uint64() + 1)
+	if child == nil || child.ParentHash != hash || !isEpochBoundary(header, child) {
+		return nil, errNotEpochBoundary
+	}
+	return header, nil
+}
+
+// SignCheckpoint signs the given epoch boundary block with the authorized
+// validator and adds the signature to the local checkpoint pool. The returned
+// signature can be relayed to the upcoming producers via addCheckpointSignature.
+func (api *API) SignCheckpoint(hash common.Hash) (hexutil.Bytes, error) {
+	header, err := api.checkpointHeader(hash)
+	if err != nil {
+		return nil, err
+	}
+	return api.dpos.signCheckpoint(header)
+}
+
+// AddCheckpointSignature adds the signature of another validator on the given
+// epoch boundary block to the local checkpoint pool, returning the signer.
+func (api *API) AddCheckpointSignature(hash common.Hash, sig hexutil.Bytes) (common.Address, error) {
+	header, err := api.checkpointHeader(hash)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return api.dpos.addCheckpointSignature(header, sig)
+}
+
+// GetCheckpointSignatures returns the validator signatures collected locally
+// for the given epoch boundary block.
+func (api *API) GetCheckpointSignatures(hash common.Hash) []hexutil.Bytes {
+	return api.dpos.checkpoints.signatures(hash)
 }
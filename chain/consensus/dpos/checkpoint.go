@@ -0,0 +1,274 @@
+package dpos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/consensus"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+const maxPooledCheckpoints = 8 //签名池中最多保存的检查点数量
+
+var (
+	errInvalidCheckpoint    = errors.New("invalid checkpoint")
+	errNotEpochBoundary     = errors.New("checkpoint is not the last block of an epoch")
+	errCheckpointSignature  = errors.New("invalid checkpoint signature")
+	errCheckpointSigner     = errors.New("checkpoint signed by non validator")
+	errCheckpointDuplicated = errors.New("checkpoint signed twice by the same validator")
+	errCheckpointQuorum     = errors.New("checkpoint signed by too few validators")
+
+	checkpointPrefix = []byte("boker-checkpoint") //检查点签名内容的前缀，与区块签名区分
+)
+
+//验证者为检查点签名的内容
+func CheckpointSigHash(number uint64, hash common.Hash) common.Hash {
+
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	return crypto.Keccak256Hash(checkpointPrefix, enc[:], hash.Bytes())
+}
+
+//检查点需要的最少验证者签名数量（超过三分之二）
+func CheckpointQuorum(validators int) int {
+	return validators*2/3 + 1
+}
+
+//校验检查点的签名来自足够多的不同验证者，轻节点和跨链桥可以用它独立校验检查点
+func VerifyCheckpointSignatures(cp *types.Checkpoint, validators []common.Address) error {
+
+	valid := make(map[common.Address]bool, len(validators))
+	for _, validator := range validators {
+		valid[validator] = true
+	}
+
+	hash := CheckpointSigHash(cp.Number, cp.Hash)
+	signed := make(map[common.Address]bool, len(cp.Signatures))
+	for _, sig := range cp.Signatures {
+		signer, err := checkpointSigner(hash, sig)
+		if err != nil {
+			return err
+		}
+		if !valid[signer] {
+			return errCheckpointSigner
+		}
+		if signed[signer] {
+			return errCheckpointDuplicated
+		}
+		signed[signer] = true
+	}
+	if len(signed) < CheckpointQuorum(len(valid)) {
+		return errCheckpointQuorum
+	}
+	return nil
+}
+
+//根据签名恢复检查点的签名者
+func checkpointSigner(hash common.Hash, sig []byte) (common.Address, error) {
+
+	if len(sig) != protocol.ExtraSeal {
+		return common.Address{}, errCheckpointSignature
+	}
+	pubkey, err := crypto.SigToPub(hash.Bytes(), sig)
+	if err != nil {
+		return common.Address{}, errCheckpointSignature
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+//收集验证者对周期边界区块的签名，出块时从中打包检查点
+type checkpointPool struct {
+	sigs  map[common.Hash]map[common.Address][]byte //检查点区块Hash -> 验证者 -> 签名
+	order []common.Hash                             //检查点加入的顺序，超出数量时丢弃最早的
+	mu    sync.Mutex
+}
+
+func (p *checkpointPool) add(hash common.Hash, signer common.Address, sig []byte) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.sigs == nil {
+		p.sigs = make(map[common.Hash]map[common.Address][]byte)
+	}
+	if _, ok := p.sigs[hash]; !ok {
+		if len(p.order) >= maxPooledCheckpoints {
+			delete(p.sigs, p.order[0])
+			p.order = p.order[1:]
+		}
+		p.sigs[hash] = make(map[common.Address][]byte)
+		p.order = append(p.order, hash)
+	}
+	p.sigs[hash][signer] = common.CopyBytes(sig)
+}
+
+//得到检查点的全部签名，按签名者地址排序保证打包结果确定
+func (p *checkpointPool) signatures(hash common.Hash) []hexutil.Bytes {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	signers := make([]common.Address, 0, len(p.sigs[hash]))
+	for signer := range p.sigs[hash] {
+		signers = append(signers, signer)
+	}
+	sort.Slice(signers, func(i, j int) bool { return bytes.Compare(signers[i][:], signers[j][:]) < 0 })
+
+	sigs := make([]hexutil.Bytes, 0, len(signers))
+	for _, signer := range signers {
+		sigs = append(sigs, common.CopyBytes(p.sigs[hash][signer]))
+	}
+	return sigs
+}
+
+//得到区块的父区块，优先从正在校验的区块中查找
+func parentHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header) *types.Header {
+
+	for i := len(parents) - 1; i >= 0; i-- {
+		if parents[i].Hash() == header.ParentHash {
+			return parents[i]
+		}
+	}
+	return chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+}
+
+//判断区块是否是一个周期的最后一个区块
+func isEpochBoundary(header, child *types.Header) bool {
+	return header.Number.Sign() > 0 && epochNumber(child) > epochNumber(header)
+}
+
+//校验区块头中的检查点：必须是窗口内祖先中的周期边界区块，并有足够的验证者签名
+func (d *Dpos) verifyCheckpoint(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+
+	if len(header.Checkpoints) == 0 {
+		return nil
+	}
+	//检查点分叉之前的区块不能包含检查点
+	if !chain.Config().IsCheckpoint(header.Number) || len(header.Checkpoints) > 1 {
+		return errInvalidCheckpoint
+	}
+	cp := header.Checkpoints[0]
+	number := header.Number.Uint64()
+	if cp.Number >= number || number-cp.Number > protocol.CheckpointWindow {
+		return errInvalidCheckpoint
+	}
+
+	//回溯到检查点区块以及它的子区块
+	child := header
+	for child.Number.Uint64() > cp.Number+1 {
+		if child = parentHeader(chain, child, parents); child == nil {
+			return consensus.ErrUnknownAncestor
+		}
+	}
+	target := parentHeader(chain, child, parents)
+	if target == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if target.Hash() != cp.Hash {
+		return errInvalidCheckpoint
+	}
+	if !isEpochBoundary(target, child) {
+		return errNotEpochBoundary
+	}
+
+	//签名必须来自检查点区块所在周期的验证者
+	snap, err := d.epochs.snapshot(target)
+	if err != nil {
+		return err
+	}
+	return VerifyCheckpointSignatures(cp, snap.Validators)
+}
+
+//为正在打包的区块准备检查点，窗口内有尚未被打包的周期边界区块并且签名足够时返回检查点
+func (d *Dpos) pendingCheckpoint(chain consensus.ChainReader, header *types.Header) *types.Checkpoint {
+
+	child := header
+	for i := 0; i < protocol.CheckpointWindow; i++ {
+		target := parentHeader(chain, child, nil)
+		if target == nil {
+			return nil
+		}
+		if isEpochBoundary(target, child) {
+			return d.collectCheckpoint(chain, target, header)
+		}
+		//检查点已经被窗口内的区块打包
+		if len(target.Checkpoints) > 0 {
+			return nil
+		}
+		child = target
+	}
+	return nil
+}
+
+//收集周期边界区块的签名，本节点是验证者时同时加入自己的签名
+func (d *Dpos) collectCheckpoint(chain consensus.ChainReader, target *types.Header, header *types.Header) *types.Checkpoint {
+
+	snap, err := d.epochs.snapshot(target)
+	if err != nil {
+		return nil
+	}
+	if _, err := d.signCheckpoint(target); err != nil && err != errCheckpointSigner {
+		log.Debug("Failed to sign checkpoint", "number", target.Number, "err", err)
+	}
+
+	cp := &types.Checkpoint{
+		Number:     target.Number.Uint64(),
+		Hash:       target.Hash(),
+		Signatures: d.checkpoints.signatures(target.Hash()),
+	}
+	if err := VerifyCheckpointSignatures(cp, snap.Validators); err != nil {
+		log.Debug("Checkpoint not included", "number", cp.Number, "hash", cp.Hash, "signatures", len(cp.Signatures), "err", err)
+		return nil
+	}
+	log.Info("Including checkpoint", "number", header.Number, "checkpoint", cp.Number, "hash", cp.Hash, "signatures", len(cp.Signatures))
+	return cp
+}
+
+//本节点的验证者为周期边界区块签名，并将签名加入签名池
+func (d *Dpos) signCheckpoint(target *types.Header) ([]byte, error) {
+
+	signer, signFn := d.authorized()
+	if signFn == nil {
+		return nil, errSignerNotAuthorized
+	}
+	snap, err := d.epochs.snapshot(target)
+	if err != nil {
+		return nil, err
+	}
+	if !snap.isValidator(signer) {
+		return nil, errCheckpointSigner
+	}
+	sig, err := signFn(accounts.Account{Address: signer}, CheckpointSigHash(target.Number.Uint64(), target.Hash()).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	d.checkpoints.add(target.Hash(), signer, sig)
+	return sig, nil
+}
+
+//加入其他验证者对周期边界区块的签名
+func (d *Dpos) addCheckpointSignature(target *types.Header, sig []byte) (common.Address, error) {
+
+	signer, err := checkpointSigner(CheckpointSigHash(target.Number.Uint64(), target.Hash()), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	snap, err := d.epochs.snapshot(target)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if !snap.isValidator(signer) {
+		return common.Address{}, errCheckpointSigner
+	}
+	d.checkpoints.add(target.Hash(), signer, sig)
+	return signer, nil
+}
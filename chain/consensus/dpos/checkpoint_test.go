@@ -0,0 +1,286 @@
+package dpos
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/params"
+)
+
+//测试用的区块链，只保存区块头
+type testChain struct {
+	config  *params.ChainConfig
+	headers []*types.Header
+}
+
+func (c *testChain) Config() *params.ChainConfig  { return c.config }
+func (c *testChain) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+func (c *testChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+func (c *testChain) GetBlock(hash common.Hash, number uint64) *types.Block { return nil }
+
+//创建n个区块头的测试链，boundary为第一个周期的最后一个区块，之后的区块属于下一个周期
+func newTestChain(config *params.ChainConfig, n int, boundary uint64) *testChain {
+
+	chain := &testChain{config: config}
+	for i := 0; i < n; i++ {
+		number := uint64(i)
+		time := int64(number) * protocol.ProducerInterval
+		if number > boundary {
+			time += protocol.EpochInterval
+		}
+		header := &types.Header{
+			UncleHash:  types.EmptyUncleHash,
+			Number:     new(big.Int).SetUint64(number),
+			Time:       big.NewInt(time),
+			Difficulty: big.NewInt(1),
+			GasLimit:   new(big.Int).Set(params.GenesisGasLimit),
+			GasUsed:    big.NewInt(0),
+			Extra:      make([]byte, protocol.ExtraVanity+protocol.ExtraSeal),
+			DposProto:  &types.DposContextProto{},
+			BokerProto: &protocol.BokerBackendProto{},
+		}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	return chain
+}
+
+func newTestValidators(t *testing.T, n int) ([]*ecdsa.PrivateKey, []common.Address) {
+
+	keys := make([]*ecdsa.PrivateKey, n)
+	addrs := make([]common.Address, n)
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		keys[i], addrs[i] = key, crypto.PubkeyToAddress(key.PublicKey)
+	}
+	return keys, addrs
+}
+
+func signCheckpoint(t *testing.T, number uint64, hash common.Hash, keys ...*ecdsa.PrivateKey) []hexutil.Bytes {
+
+	sigs := make([]hexutil.Bytes, len(keys))
+	for i, key := range keys {
+		sig, err := crypto.Sign(CheckpointSigHash(number, hash).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign checkpoint: %v", err)
+		}
+		sigs[i] = sig
+	}
+	return sigs
+}
+
+//创建Dpos对象，并将检查点区块所在周期的验证者放入快照缓存
+func newTestDpos(target *types.Header, validators []common.Address) *Dpos {
+
+	db, _ := ethdb.NewMemDatabase()
+	d := New(&params.DposConfig{}, db)
+	epoch := epochNumber(target)
+	d.epochs.cache.Add(epoch, newEpochSnapshot(epoch, target.DposProto.EpochHash, validators))
+	return d
+}
+
+func TestVerifyCheckpointSignatures(t *testing.T) {
+
+	keys, validators := newTestValidators(t, 4)
+	outsider, _ := crypto.GenerateKey()
+	number, hash := uint64(10), common.Hash{1}
+
+	//4个验证者需要4*2/3+1=3个签名
+	tests := []struct {
+		name string
+		sigs []hexutil.Bytes
+		err  error
+	}{
+		{"quorum", signCheckpoint(t, number, hash, keys[:3]...), nil},
+		{"all validators", signCheckpoint(t, number, hash, keys...), nil},
+		{"below quorum", signCheckpoint(t, number, hash, keys[:2]...), errCheckpointQuorum},
+		{"duplicate signer", signCheckpoint(t, number, hash, keys[0], keys[1], keys[1]), errCheckpointDuplicated},
+		{"non-validator signer", signCheckpoint(t, number, hash, keys[0], keys[1], outsider), errCheckpointSigner},
+		{"other block", signCheckpoint(t, number+1, hash, keys...), errCheckpointSigner},
+		{"invalid length", []hexutil.Bytes{make([]byte, protocol.ExtraSeal-1)}, errCheckpointSignature},
+	}
+	for _, tt := range tests {
+		cp := &types.Checkpoint{Number: number, Hash: hash, Signatures: tt.sigs}
+		if err := VerifyCheckpointSignatures(cp, validators); err != tt.err {
+			t.Errorf("%s: have %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if quorum := CheckpointQuorum(1); quorum != 1 {
+		t.Errorf("quorum of one validator: have %d, want 1", quorum)
+	}
+	if quorum := CheckpointQuorum(21); quorum != 15 {
+		t.Errorf("quorum of 21 validators: have %d, want 15", quorum)
+	}
+}
+
+func TestVerifyCheckpoint(t *testing.T) {
+
+	const boundary = 3
+	config := &params.ChainConfig{ChainId: big.NewInt(1), CheckpointBlock: big.NewInt(0)}
+	chain := newTestChain(config, boundary+protocol.CheckpointWindow+3, boundary)
+	target := chain.headers[boundary]
+
+	keys, validators := newTestValidators(t, 3)
+	d := newTestDpos(target, validators)
+
+	checkpoint := func(header *types.Header, sigs []hexutil.Bytes) *types.Checkpoint {
+		return &types.Checkpoint{Number: header.Number.Uint64(), Hash: header.Hash(), Signatures: sigs}
+	}
+	valid := checkpoint(target, signCheckpoint(t, boundary, target.Hash(), keys...))
+	inner := chain.headers[boundary-1]
+
+	tests := []struct {
+		name        string
+		number      uint64
+		checkpoints []*types.Checkpoint
+		err         error
+	}{
+		{"no checkpoint", boundary + 1, nil, nil},
+		{"next block", boundary + 1, []*types.Checkpoint{valid}, nil},
+		{"window edge", boundary + protocol.CheckpointWindow, []*types.Checkpoint{valid}, nil},
+		{"beyond window", boundary + protocol.CheckpointWindow + 1, []*types.Checkpoint{valid}, errInvalidCheckpoint},
+		{"self checkpoint", boundary, []*types.Checkpoint{valid}, errInvalidCheckpoint},
+		{"two checkpoints", boundary + 2, []*types.Checkpoint{valid, valid}, errInvalidCheckpoint},
+		{"wrong hash", boundary + 2, []*types.Checkpoint{{Number: boundary, Hash: common.Hash{1}, Signatures: valid.Signatures}}, errInvalidCheckpoint},
+		{"not epoch boundary", boundary + 2, []*types.Checkpoint{checkpoint(inner, signCheckpoint(t, boundary-1, inner.Hash(), keys...))}, errNotEpochBoundary},
+		{"below quorum", boundary + 2, []*types.Checkpoint{checkpoint(target, valid.Signatures[:1])}, errCheckpointQuorum},
+	}
+	for _, tt := range tests {
+		header := types.CopyHeader(chain.headers[tt.number])
+		header.Checkpoints = tt.checkpoints
+		if err := d.verifyCheckpoint(chain, header, nil); err != tt.err {
+			t.Errorf("%s: have %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	//检查点分叉之前的区块不能包含检查点
+	config.CheckpointBlock = big.NewInt(boundary + 2)
+	header := types.CopyHeader(chain.headers[boundary+1])
+	header.Checkpoints = []*types.Checkpoint{valid}
+	if err := d.verifyCheckpoint(chain, header, nil); err != errInvalidCheckpoint {
+		t.Errorf("before fork: have %v, want %v", err, errInvalidCheckpoint)
+	}
+	header = types.CopyHeader(chain.headers[boundary+2])
+	header.Checkpoints = []*types.Checkpoint{valid}
+	if err := d.verifyCheckpoint(chain, header, nil); err != nil {
+		t.Errorf("at fork: unexpected error: %v", err)
+	}
+}
+
+func TestIsEpochBoundary(t *testing.T) {
+
+	chain := newTestChain(params.TestChainConfig, 6, 3)
+	for i := 0; i+1 < len(chain.headers); i++ {
+		if have, want := isEpochBoundary(chain.headers[i], chain.headers[i+1]), i == 3; have != want {
+			t.Errorf("block %d: have %v, want %v", i, have, want)
+		}
+	}
+
+	//创世区块不作为检查点
+	genesis := newTestChain(params.TestChainConfig, 2, 0)
+	if isEpochBoundary(genesis.headers[0], genesis.headers[1]) {
+		t.Errorf("genesis block treated as epoch boundary")
+	}
+}
+
+func TestPrepareCheckpoint(t *testing.T) {
+
+	const boundary = 3
+	config := &params.ChainConfig{ChainId: big.NewInt(1)}
+	chain := newTestChain(config, boundary+3, boundary)
+	target := chain.headers[boundary]
+
+	keys, validators := newTestValidators(t, 3)
+	d := newTestDpos(target, validators)
+	for i, sig := range signCheckpoint(t, boundary, target.Hash(), keys...) {
+		d.checkpoints.add(target.Hash(), validators[i], sig)
+	}
+
+	prepare := func() *types.Header {
+		parent := chain.headers[boundary+2]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       new(big.Int).Add(parent.Time, big.NewInt(protocol.ProducerInterval)),
+		}
+		if err := d.Prepare(chain, header); err != nil {
+			t.Fatalf("failed to prepare header: %v", err)
+		}
+		return header
+	}
+
+	//检查点分叉之前不打包检查点
+	if header := prepare(); len(header.Checkpoints) != 0 {
+		t.Errorf("checkpoint included before fork: %v", header.Checkpoints)
+	}
+	config.CheckpointBlock = big.NewInt(0)
+	header := prepare()
+	if len(header.Checkpoints) != 1 {
+		t.Fatalf("checkpoint count mismatch: have %d, want 1", len(header.Checkpoints))
+	}
+	if cp := header.Checkpoints[0]; cp.Number != boundary || cp.Hash != target.Hash() || len(cp.Signatures) != len(keys) {
+		t.Errorf("checkpoint mismatch: have %d %x with %d signatures", cp.Number, cp.Hash, len(cp.Signatures))
+	}
+}
+
+func TestCheckpointPoolEviction(t *testing.T) {
+
+	var pool checkpointPool
+	signers := []common.Address{{2}, {1}}
+	for i := 0; i <= maxPooledCheckpoints; i++ {
+		for _, signer := range signers {
+			pool.add(common.Hash{byte(i)}, signer, []byte{byte(i), signer[0]})
+		}
+	}
+
+	//超出数量时丢弃最早加入的检查点
+	if sigs := pool.signatures(common.Hash{0}); len(sigs) != 0 {
+		t.Errorf("oldest checkpoint not evicted: %v", sigs)
+	}
+	for i := 1; i <= maxPooledCheckpoints; i++ {
+		if sigs := pool.signatures(common.Hash{byte(i)}); len(sigs) != len(signers) {
+			t.Errorf("checkpoint %d: have %d signatures, want %d", i, len(sigs), len(signers))
+		}
+	}
+
+	//已有检查点的新签名不会触发丢弃，签名按签名者地址排序
+	pool.add(common.Hash{1}, common.Address{3}, []byte{1, 3})
+	if len(pool.order) != maxPooledCheckpoints {
+		t.Errorf("pooled checkpoints: have %d, want %d", len(pool.order), maxPooledCheckpoints)
+	}
+	sigs := pool.signatures(common.Hash{1})
+	if len(sigs) != 3 || sigs[0][1] != 1 || sigs[1][1] != 2 || sigs[2][1] != 3 {
+		t.Errorf("signatures not sorted by signer: %v", sigs)
+	}
+}
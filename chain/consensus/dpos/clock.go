@@ -0,0 +1,285 @@
+package dpos
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
+)
+
+const (
+	clockCheckInterval = 10 * time.Minute //两次NTP检查之间的间隔
+	ntpTimeout         = 5 * time.Second  //单个NTP请求的超时时间
+	ntpMeasurements    = 3                //每个NTP服务器的测量次数
+)
+
+var DefaultNTPServers = []string{"pool.ntp.org"} //默认使用的NTP服务器
+
+var (
+	errClockDrift   = errors.New("local clock drift exceeds the threshold")
+	errNoNTPReplies = errors.New("no NTP server replied")
+
+	clockDriftGauge = metrics.NewGauge("dpos/clock/drift") //本地时间与网络时间的偏差（毫秒）
+)
+
+//本地时钟相对网络时间的偏差信息
+type ClockStatus struct {
+	Enabled   bool      `json:"enabled"`         //是否启用NTP检查
+	Servers   []string  `json:"servers"`         //使用的NTP服务器
+	Threshold string    `json:"threshold"`       //允许的最大偏差
+	Strict    bool      `json:"strict"`          //偏差超过阈值时是否拒绝封装区块
+	Drift     string    `json:"drift"`           //本地时间减网络时间，出块时间按该偏差进行补偿
+	Exceeded  bool      `json:"exceeded"`        //偏差是否超过阈值
+	Replies   int       `json:"replies"`         //最近一次检查中应答的测量次数
+	Checked   time.Time `json:"checked"`         //最近一次成功检查的时间
+	Error     string    `json:"error,omitempty"` //最近一次检查的错误
+}
+
+//定期通过NTP测量本地时钟的偏差，用于补偿出块时间以及在偏差过大时拒绝封装区块
+type clockMonitor struct {
+	servers   []string
+	threshold time.Duration
+	strict    bool
+
+	drift   time.Duration //本地时间减网络时间
+	replies int
+	checked time.Time
+	err     error
+
+	check chan chan error
+	quit  chan struct{}
+	mu    sync.RWMutex
+}
+
+//设置时钟偏差检查，threshold为0时不检查也不补偿
+func (d *Dpos) SetClockMonitor(servers []string, threshold time.Duration, strict bool) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if threshold <= 0 {
+		d.clockMonitor = nil
+		return
+	}
+	if len(servers) == 0 {
+		servers = DefaultNTPServers
+	}
+	d.clockMonitor = &clockMonitor{
+		servers:   servers,
+		threshold: threshold,
+		strict:    strict,
+		check:     make(chan chan error),
+		quit:      make(chan struct{}),
+	}
+}
+
+func (d *Dpos) monitor() *clockMonitor {
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.clockMonitor
+}
+
+//启动时钟偏差检查
+func (d *Dpos) StartClockMonitor() {
+	if m := d.monitor(); m != nil {
+		go m.loop()
+	}
+}
+
+//停止时钟偏差检查
+func (d *Dpos) StopClockMonitor() {
+	if m := d.monitor(); m != nil {
+		close(m.quit)
+	}
+}
+
+//立即进行一次时钟偏差检查并返回结果
+func (d *Dpos) CheckClock() (*ClockStatus, error) {
+
+	m := d.monitor()
+	if m == nil {
+		return d.ClockStatus(), nil
+	}
+	errc := make(chan error, 1)
+	select {
+	case m.check <- errc:
+	case <-m.quit:
+		return nil, errors.New("clock monitor stopped")
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+	return d.ClockStatus(), nil
+}
+
+//得到时钟偏差信息
+func (d *Dpos) ClockStatus() *ClockStatus {
+
+	m := d.monitor()
+	if m == nil {
+		return &ClockStatus{Servers: []string{}, Drift: time.Duration(0).String(), Threshold: time.Duration(0).String()}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := &ClockStatus{
+		Enabled:   true,
+		Servers:   m.servers,
+		Threshold: m.threshold.String(),
+		Strict:    m.strict,
+		Drift:     m.drift.String(),
+		Exceeded:  m.exceeded(),
+		Replies:   m.replies,
+		Checked:   m.checked,
+	}
+	if m.err != nil {
+		status.Error = m.err.Error()
+	}
+	return status
+}
+
+//按网络时间补偿后的当前时间
+func (d *Dpos) now() time.Time {
+
+	m := d.monitor()
+	if m == nil {
+		return time.Now()
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return time.Now().Add(-m.drift)
+}
+
+//按网络时间补偿本地时间的偏差，供矿工计算出块时间
+func (d *Dpos) ClockOffset() time.Duration {
+	return d.now().Sub(time.Now()).Round(time.Millisecond)
+}
+
+//严格模式下偏差超过阈值时拒绝封装区块
+func (d *Dpos) checkClockDrift() error {
+
+	m := d.monitor()
+	if m == nil || !m.strict {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.exceeded() {
+		return fmt.Errorf("%v: drift %v, threshold %v", errClockDrift, m.drift, m.threshold)
+	}
+	return nil
+}
+
+//调用时必须持有读锁
+func (m *clockMonitor) exceeded() bool {
+	return m.drift > m.threshold || m.drift < -m.threshold
+}
+
+func (m *clockMonitor) loop() {
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		var errc chan error
+		select {
+		case <-m.quit:
+			return
+		case <-timer.C:
+		case errc = <-m.check:
+			timer.Stop()
+		}
+		err := m.measure()
+		if errc != nil {
+			errc <- err
+		}
+		timer.Reset(clockCheckInterval)
+	}
+}
+
+//向所有NTP服务器测量偏差，使用所有测量结果的中位数
+func (m *clockMonitor) measure() error {
+
+	var drifts []time.Duration
+	for _, server := range m.servers {
+		for i := 0; i < ntpMeasurements; i++ {
+			drift, err := sntpDrift(server)
+			if err != nil {
+				log.Debug("NTP measurement failed", "server", server, "err", err)
+				break
+			}
+			drifts = append(drifts, drift)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.replies = len(drifts)
+	if len(drifts) == 0 {
+		m.err = errNoNTPReplies
+		log.Warn("Failed to check the clock drift", "servers", m.servers, "err", m.err)
+		return m.err
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i] < drifts[j] })
+	m.drift, m.checked, m.err = drifts[len(drifts)/2], time.Now(), nil
+	clockDriftGauge.Update(int64(m.drift / time.Millisecond))
+
+	if m.exceeded() {
+		log.Warn("System clock seems off, slot timing is compensated", "drift", m.drift, "threshold", m.threshold, "strict", m.strict)
+		log.Warn("Please enable network time synchronisation in system settings.")
+	} else {
+		log.Debug("NTP clock check done", "drift", m.drift)
+	}
+	return nil
+}
+
+//通过SNTP(https://tools.ietf.org/html/rfc4330)测量本地时间减服务器时间的偏差，假设应答时间为往返时间的一半
+func sntpDrift(server string) (time.Duration, error) {
+
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	addr, err := net.ResolveUDPAddr("udp", server)
+	if err != nil {
+		return 0, err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	//请求中只设置版本号3和客户端模式3
+	request := make([]byte, 48)
+	request[0] = 3<<3 | 3
+
+	conn.SetDeadline(time.Now().Add(ntpTimeout))
+	sent := time.Now()
+	if _, err = conn.Write(request); err != nil {
+		return 0, err
+	}
+	reply := make([]byte, 48)
+	if _, err = conn.Read(reply); err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(sent)
+
+	//从应答的发送时间戳中恢复服务器时间
+	sec := uint64(reply[43]) | uint64(reply[42])<<8 | uint64(reply[41])<<16 | uint64(reply[40])<<24
+	frac := uint64(reply[47]) | uint64(reply[46])<<8 | uint64(reply[45])<<16 | uint64(reply[44])<<24
+	if sec == 0 {
+		return 0, errors.New("empty NTP reply")
+	}
+	nanosec := sec*1e9 + (frac*1e9)>>32
+	t := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(nanosec))
+
+	return sent.Sub(t) + elapsed/2, nil
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/crypto/sha3"
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
 	"github.com/Bokerchain/Boker/chain/params"
 	"github.com/Bokerchain/Boker/chain/rlp"
 	"github.com/Bokerchain/Boker/chain/rpc"
@@ -37,19 +38,48 @@ var (
 	ErrInvalidTimestamp  = errors.New("invalid timestamp")                           //出块时间不正确
 	ErrWaitForPrevBlock  = errors.New("wait for last block arrived")                 //等待最后一个区块到达
 	ErrMintFutureBlock   = errors.New("mint the future block")                       //根据时间计算是一个未来的区块
+	errInvalidRevision   = errors.New("invalid header revision")                     //区块头的编码版本与链配置不符
+	errInvalidSlot       = errors.New("invalid header slot")                         //区块头的时间槽与出块时间不符
 )
 var (
 	uncleHash = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
 )
 
+//不可逆区块(最终确认)的度量标准
+var (
+	confirmedNumberGauge  = metrics.NewGauge("dpos/irreversible/number")  //最新不可逆区块号
+	confirmedLagGauge     = metrics.NewGauge("dpos/irreversible/lag")     //当前区块与不可逆区块的差距
+	confirmedSignerGauge  = metrics.NewGauge("dpos/irreversible/signers") //不可逆区块之后已经签名的不同验证者数量
+	confirmedAdvanceCount = metrics.NewCounter("dpos/irreversible/advance")
+
+	//出块节点的度量标准
+	sealedBlockCounter   = metrics.NewCounter("dpos/producer/sealed")   //本节点封装的区块数量
+	sealFailureCounter   = metrics.NewCounter("dpos/producer/failures") //本节点封装区块失败的数量
+	verifiedBlockCounter = metrics.NewCounter("dpos/producer/verified") //校验通过的其他节点区块数量
+)
+
+//不可逆区块的跟踪信息
+type Irreversibility struct {
+	Confirmed            *types.Header `json:"confirmed"`            //最新的不可逆区块头
+	Head                 *big.Int      `json:"head"`                 //当前区块号
+	Lag                  uint64        `json:"lag"`                  //当前区块与不可逆区块之间的区块数量
+	Signers              int           `json:"signers"`              //不可逆区块之后签名的不同验证者数量
+	PendingConfirmations int           `json:"pendingConfirmations"` //还需要多少个不同的验证者签名才能推进不可逆区块
+}
+
 type Dpos struct {
 	db                   ethdb.Database //数据库对象
 	signer               common.Address //签名者地址
 	signFn               SignerFn       //签名处理函数
 	signatures           *lru.ARCCache  //最近的块签名加快采矿
+	epochs               *epochCache    //每个周期的验证者快照
+	checkpoints          checkpointPool //验证者对周期边界区块的签名
 	confirmedBlockHeader *types.Header
+	confirmedLock        sync.RWMutex  //保护确认的区块头，更新时持有写锁直到回溯结束
+	clock                clockTracker  //其他节点区块的到达时间，用于估计时钟偏差
+	clockMonitor         *clockMonitor //通过NTP检查本地时钟的偏差
 	mu                   sync.RWMutex
-	stop                 chan bool
+	stop                 chan bool //停止看门狗
 }
 
 type SignerFn func(accounts.Account, []byte) ([]byte, error)
@@ -57,7 +87,33 @@ type SignerFn func(accounts.Account, []byte) ([]byte, error)
 func sigHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewKeccak256()
 
-	rlp.Encode(hasher, []interface{}{
+	//紧凑格式的区块头不包含叔块Hash、难度、MixDigest以及Nonce，签名内容加入版本号和时间槽
+	if header.Revision == types.HeaderRevisionCompact {
+		fields := []interface{}{
+			header.Revision,
+			header.ParentHash,
+			header.Validator,
+			header.Coinbase,
+			header.Root,
+			header.TxHash,
+			header.ReceiptHash,
+			header.Bloom,
+			header.Number,
+			header.GasLimit,
+			header.GasUsed,
+			header.Time,
+			header.Slot,
+			header.Extra[:len(header.Extra)-65],
+			header.DposProto.Root(),
+			header.BokerProto.Root(),
+			header.Checkpoints,
+		}
+		rlp.Encode(hasher, fields)
+		hasher.Sum(hash[:0])
+		return hash
+	}
+
+	fields := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
 		header.Validator,
@@ -76,7 +132,13 @@ func sigHash(header *types.Header) (hash common.Hash) {
 		header.Nonce,
 		header.DposProto.Root(),
 		header.BokerProto.Root(),
-	})
+	}
+
+	//只有包含检查点时才加入签名内容，保持原有区块的签名不变
+	if len(header.Checkpoints) > 0 {
+		fields = append(fields, header.Checkpoints)
+	}
+	rlp.Encode(hasher, fields)
 	hasher.Sum(hash[:0])
 	return hash
 }
@@ -88,6 +150,7 @@ func New(config *params.DposConfig, db ethdb.Database) *Dpos {
 	return &Dpos{
 		db:         db,
 		signatures: signatures,
+		epochs:     newEpochCache(db),
 	}
 }
 
@@ -109,8 +172,14 @@ func (d *Dpos) verifyHeader(chain consensus.ChainReader, header *types.Header, p
 	}
 	number := header.Number.Uint64()
 
+	//记录其他节点区块的到达时间
+	now := d.now().Unix()
+	if signer, _ := d.authorized(); header.Validator != signer {
+		d.clock.record(header, now)
+	}
+
 	//用区块头中的时间和当前时间对比，如果大于当前时间则属于未来的区块（还没有出现的区块），报错
-	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
+	if header.Time.Cmp(big.NewInt(now)) > 0 {
 		return consensus.ErrFutureBlock
 	}
 
@@ -139,6 +208,18 @@ func (d *Dpos) verifyHeader(chain consensus.ChainReader, header *types.Header, p
 		return errInvalidUncleHash
 	}
 
+	//区块头的编码版本由链配置决定，紧凑格式的时间槽必须与出块时间一致
+	if chain.Config().IsHeaderRevision(header.Number) {
+		if header.Revision != types.HeaderRevisionCompact {
+			return errInvalidRevision
+		}
+		if header.Slot != header.Time.Uint64()/uint64(protocol.ProducerInterval) {
+			return errInvalidSlot
+		}
+	} else if header.Revision != types.HeaderRevisionLegacy || header.Slot != 0 {
+		return errInvalidRevision
+	}
+
 	//检测硬分叉的特殊字段判断是否是硬分叉
 	if err := misc.VerifyForkHashes(chain.Config(), header, false); err != nil {
 		return err
@@ -157,7 +238,14 @@ func (d *Dpos) verifyHeader(chain consensus.ChainReader, header *types.Header, p
 	if parent.Time.Uint64()+uint64(protocol.ProducerInterval) > header.Time.Uint64() {
 		return ErrInvalidTimestamp
 	}
-	return nil
+
+	//区块的Gas限制每个区块只能向出块者的目标移动有限的幅度
+	if err := misc.VerifyGasLimit(parent, header); err != nil {
+		return err
+	}
+
+	//校验验证者对周期边界区块的联合签名
+	return d.verifyCheckpoint(chain, header, parents)
 }
 
 //验证区块头
@@ -194,6 +282,23 @@ func (d *Dpos) VerifySeal(chain consensus.ChainReader, header *types.Header) err
 
 func (d *Dpos) verifySeal(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
 
+	//创世区块不需要验证，也不更新已确认的区块
+	if header.Number.Uint64() == 0 {
+		return nil
+	}
+	if err := d.verifyProducer(chain, header, parents); err != nil {
+		return err
+	}
+	return d.updateConfirmedBlockHeader(chain)
+}
+
+//验证区块由父区块Dpos状态中当前时间的出块者签名，与VerifySeal不同，不会更新已确认的区块，可用于离线检查历史区块
+func (d *Dpos) VerifyProducer(chain consensus.ChainReader, header *types.Header) error {
+	return d.verifyProducer(chain, header, nil)
+}
+
+func (d *Dpos) verifyProducer(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+
 	const (
 		genesisNumber uint64 = 0 //创世区块
 		firstNumber   uint64 = 1 //首区块
@@ -211,9 +316,12 @@ func (d *Dpos) verifySeal(chain consensus.ChainReader, header *types.Header, par
 	} else {
 		parent = chain.GetHeader(header.ParentHash, number-1)
 	}
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
 
-	//根据父区块创建一个新的Dpos对象
-	dposContext, err := types.NewDposContextFromProto(d.db, parent.DposProto)
+	//得到父区块所在周期的验证者快照
+	snap, err := d.epochs.snapshot(parent)
 	if err != nil {
 		return err
 	}
@@ -224,8 +332,8 @@ func (d *Dpos) verifySeal(chain consensus.ChainReader, header *types.Header, par
 		parentHeader := chain.GetHeader(header.ParentHash, number-1)
 		firstTimer := parentHeader.Time.Int64()
 
-		//根据Dpos对象创建一个周期对象
-		producer, err := dposContext.GetProducer(header.Time.Int64(), firstTimer)
+		//根据周期快照得到出块时间对应的出块者
+		producer, err := snap.slotProducer(header.Time.Int64(), firstTimer)
 		if err != nil {
 
 			log.Error("Check Producer Failed", "time", header.Time.Int64(), "parentTime", firstTimer)
@@ -236,8 +344,9 @@ func (d *Dpos) verifySeal(chain consensus.ChainReader, header *types.Header, par
 		if err := d.verifyBlockSigner(producer, header); err != nil {
 			return err
 		}
+		verifiedBlockCounter.Inc(1)
 	}
-	return d.updateConfirmedBlockHeader(chain)
+	return nil
 }
 
 //验证区块签名
@@ -261,9 +370,37 @@ func (d *Dpos) verifyBlockSigner(producer common.Address, header *types.Header)
 	return nil
 }
 
+//判断区块验证失败是否因为区块不是由当前时间的出块者签名，这类区块只能是节点伪造或者错误转发的
+func IsSealError(err error) bool {
+	switch err {
+	case protocol.ErrInvalidProducer, protocol.ErrInvalidProducerTime, protocol.ErrMismatchSignerAndValidator, errMissingSignature:
+		return true
+	}
+	return false
+}
+
+//只验证区块签名者与区块头中的验证者一致，不依赖父区块的Dpos状态，可用于在导入区块前快速筛查无效的区块
+func (d *Dpos) VerifySigner(header *types.Header) error {
+
+	if header.Number.Uint64() == 0 {
+		return nil
+	}
+	signer, err := ecrecover(header, d.signatures)
+	if err != nil {
+		return err
+	}
+	if bytes.Compare(signer.Bytes(), header.Validator.Bytes()) != 0 {
+		return protocol.ErrMismatchSignerAndValidator
+	}
+	return nil
+}
+
 //更新确认的区块头
 func (d *Dpos) updateConfirmedBlockHeader(chain consensus.ChainReader) error {
 
+	d.confirmedLock.Lock()
+	defer d.confirmedLock.Unlock()
+
 	//判断确认区块头为空
 	if d.confirmedBlockHeader == nil {
 		header, err := d.loadConfirmedBlockHeader(chain)
@@ -301,6 +438,7 @@ func (d *Dpos) updateConfirmedBlockHeader(chain consensus.ChainReader) error {
 		if curHeader.Number.Int64()-d.confirmedBlockHeader.Number.Int64() < int64(protocol.ConsensusSize-len(validatorMap)) {
 
 			log.Info("Dpos fast return", "current", curHeader.Number.String(), "confirmed", d.confirmedBlockHeader.Number.String(), "witnessCount", len(validatorMap))
+			d.reportIrreversibility(chain, d.confirmedBlockHeader)
 			return nil
 		}
 
@@ -312,6 +450,8 @@ func (d *Dpos) updateConfirmedBlockHeader(chain consensus.ChainReader) error {
 			if err := d.storeConfirmedBlockHeader(d.db); err != nil {
 				return err
 			}
+			confirmedAdvanceCount.Inc(1)
+			d.reportIrreversibility(chain, d.confirmedBlockHeader)
 
 			log.Info("Dpos set confirmed block header success", "currentHeader", curHeader.Number.String())
 			return nil
@@ -324,6 +464,73 @@ func (d *Dpos) updateConfirmedBlockHeader(chain consensus.ChainReader) error {
 	return nil
 }
 
+//得到确认的区块头，内存中还没有时从数据库加载
+func (d *Dpos) confirmedHeader(chain consensus.ChainReader) (*types.Header, error) {
+
+	d.confirmedLock.RLock()
+	confirmed := d.confirmedBlockHeader
+	d.confirmedLock.RUnlock()
+
+	if confirmed != nil {
+		return confirmed, nil
+	}
+	return d.loadConfirmedBlockHeader(chain)
+}
+
+//计算从不可逆区块到当前区块的确认情况
+func (d *Dpos) irreversibility(chain consensus.ChainReader) (*Irreversibility, error) {
+
+	confirmed, err := d.confirmedHeader(chain)
+	if err != nil {
+		return nil, err
+	}
+	return irreversibilityFrom(chain, confirmed)
+}
+
+//计算从给定的不可逆区块到当前区块的确认情况
+func irreversibilityFrom(chain consensus.ChainReader, confirmed *types.Header) (*Irreversibility, error) {
+
+	//从当前区块回溯到不可逆区块，统计签名的不同验证者
+	head := chain.CurrentHeader()
+	signers := make(map[common.Address]bool)
+	for header := head; header != nil && header.Number.Cmp(confirmed.Number) > 0; {
+		signers[header.Validator] = true
+		header = chain.GetHeaderByHash(header.ParentHash)
+		if header == nil {
+			return nil, protocol.ErrNilBlockHeader
+		}
+	}
+
+	result := &Irreversibility{
+		Confirmed: confirmed,
+		Head:      new(big.Int).Set(head.Number),
+		Signers:   len(signers),
+	}
+	if head.Number.Cmp(confirmed.Number) > 0 {
+		result.Lag = new(big.Int).Sub(head.Number, confirmed.Number).Uint64()
+	}
+	if pending := protocol.ConsensusSize - len(signers); pending > 0 {
+		result.PendingConfirmations = pending
+	}
+	return result, nil
+}
+
+//更新不可逆区块的度量数据(只在开启度量时计算)，调用者持有确认区块头的锁
+func (d *Dpos) reportIrreversibility(chain consensus.ChainReader, confirmed *types.Header) {
+
+	if !metrics.Enabled {
+		return
+	}
+	result, err := irreversibilityFrom(chain, confirmed)
+	if err != nil {
+		log.Debug("Failed to compute irreversibility", "err", err)
+		return
+	}
+	confirmedNumberGauge.Update(result.Confirmed.Number.Int64())
+	confirmedLagGauge.Update(int64(result.Lag))
+	confirmedSignerGauge.Update(int64(result.Signers))
+}
+
 //加载确认区块头
 func (s *Dpos) loadConfirmedBlockHeader(chain consensus.ChainReader) (*types.Header, error) {
 
@@ -370,6 +577,22 @@ func (d *Dpos) Prepare(chain consensus.ChainReader, header *types.Header) error
 
 	//设置区块头的验证者的签名
 	header.Validator = d.signer
+
+	//根据链配置选择区块头的编码版本
+	header.Revision, header.Slot = types.HeaderRevisionLegacy, 0
+	if chain.Config().IsHeaderRevision(header.Number) {
+		header.Revision = types.HeaderRevisionCompact
+		header.Slot = header.Time.Uint64() / uint64(protocol.ProducerInterval)
+	}
+
+	//检查点分叉之后打包周期边界区块的检查点
+	header.Checkpoints = nil
+	if !chain.Config().IsCheckpoint(header.Number) {
+		return nil
+	}
+	if cp := d.pendingCheckpoint(chain, header); cp != nil {
+		header.Checkpoints = []*types.Checkpoint{cp}
+	}
 	return nil
 }
 
@@ -385,6 +608,11 @@ func AccumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 	state.AddBalance(header.Coinbase, reward)
 	//log.Info("Block Award", "Coinbase", header.Coinbase, "reward", reward)
 
+	//没有播客链接口时（例如模拟链）不产生分配通证账号的报酬
+	if boker == nil {
+		return
+	}
+
 	//得到合约的账号地址
 	addr, err := boker.GetContractAddr(protocol.SystemContract)
 	if err != nil {
@@ -419,6 +647,8 @@ func (d *Dpos) Finalize(chain consensus.ChainReader, header *types.Header, state
 	updateMintCnt(parent.Time.Int64(), header.Time.Int64(), header.Validator, dposContext)
 	header.DposProto = dposContext.ToProto()
 
+	//执行在本区块生效的基础合约替换，区块头中的注册表根包含替换的结果
+	boker.ApplyContractUpdates(header.Number.Uint64())
 	singleTrie, contractsTrie, abiTrie := boker.GetContractTrie()
 	header.BokerProto = protocol.ToBokerProto(singleTrie.Hash(), contractsTrie.Hash(), abiTrie.Hash())
 	log.Info("Get Bokerchain Trie",
@@ -457,13 +687,13 @@ func (d *Dpos) CheckDeadline(lastBlock *types.Block, now int64, firstTimer int64
 //检测当前区块头中是否是当前的打包节点
 func (d *Dpos) CheckProducer(lastBlock *types.Block, now int64, firstTimer int64) error {
 
-	dposContext, err := types.NewDposContextFromProto(d.db, lastBlock.Header().DposProto)
+	snap, err := d.epochs.snapshot(lastBlock.Header())
 	if err != nil {
 		return err
 	}
 
 	//lastTimer := lastBlock.Header().Time.Int64()
-	producer, err := dposContext.GetProducer(now, firstTimer)
+	producer, err := snap.slotProducer(now, firstTimer)
 	if err != nil {
 		return err
 	}
@@ -502,17 +732,13 @@ func (d *Dpos) SelfProducer(lastBlock *types.Block, producer common.Address) err
 //得到当前出块节点的数量
 func (d *Dpos) GetProducerSize(lastBlock *types.Block, producer common.Address) (uint64, error) {
 
-	dposContext, err := types.NewDposContextFromProto(d.db, lastBlock.Header().DposProto)
-	if err != nil {
-		return uint64(0), err
-	}
-	producers, err := dposContext.GetEpochTrie()
+	snap, err := d.epochs.snapshot(lastBlock.Header())
 	if err != nil {
 
 		log.Error("get current producer ", "error", err)
 		return uint64(0), err
 	}
-	return uint64(len(producers)), nil
+	return uint64(len(snap.Validators)), nil
 }
 
 //封装区块
@@ -523,7 +749,13 @@ func (d *Dpos) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan
 	if number == 0 {
 		return nil, protocol.ErrUnknownBlock
 	}
-	now := time.Now().Unix()
+
+	//本地时钟偏差过大时出块时间不可信
+	if err := d.checkClockDrift(); err != nil {
+		sealFailureCounter.Inc(1)
+		return nil, err
+	}
+	now := d.now().Unix()
 
 	firstHeader := chain.GetHeaderByNumber(0)
 
@@ -536,14 +768,16 @@ func (d *Dpos) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan
 		case <-time.After(time.Duration(delay) * time.Second):
 		}
 	}
-	block.Header().Time.SetInt64(time.Now().Unix())
+	block.Header().Time.SetInt64(d.now().Unix())
 
 	//对区块进行签名
 	sighash, err := d.signFn(accounts.Account{Address: d.signer}, sigHash(header).Bytes())
 	if err != nil {
+		sealFailureCounter.Inc(1)
 		return nil, err
 	}
 	copy(header.Extra[len(header.Extra)-protocol.ExtraSeal:], sighash)
+	sealedBlockCounter.Inc(1)
 	return block.WithSeal(header), nil
 }
 
@@ -570,6 +804,23 @@ func (d *Dpos) Authorize(signer common.Address, signFn SignerFn) {
 	d.mu.Unlock()
 }
 
+//本节点授权的出块账号，没有开始挖矿时为空
+func (d *Dpos) Signer() common.Address {
+
+	signer, _ := d.authorized()
+	return signer
+}
+
+//使用本节点授权的出块账号签名，供验证者之间的链下消息使用，调用者需要在签名内容中加入自己的前缀以区别于区块签名
+func (d *Dpos) SignHash(hash []byte) ([]byte, error) {
+
+	signer, signFn := d.authorized()
+	if signFn == nil {
+		return nil, errSignerNotAuthorized
+	}
+	return signFn(accounts.Account{Address: signer}, hash)
+}
+
 //根据签名头获取到用户账号
 func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, error) {
 
@@ -1,13 +1,17 @@
 package dpos
 
 import (
+	"math/big"
+	"sync"
 	"testing"
 
 	"encoding/binary"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/params"
 	"github.com/Bokerchain/Boker/chain/trie"
 	"github.com/stretchr/testify/assert"
 )
@@ -43,35 +47,28 @@ func mockNewDposContext(db ethdb.Database) *types.DposContext {
 	if err != nil {
 		return nil
 	}
-	delegator := []byte{}
-	candidate := []byte{}
 	addresses := []common.Address{}
-	for i := 0; i < maxValidatorSize; i++ {
+	for i := 0; i < protocol.MaxValidatorSize; i++ {
 		addresses = append(addresses, common.HexToAddress(MockEpoch[i]))
 	}
-	dposContext.SetValidators(addresses)
-	for j := 0; j < len(MockEpoch); j++ {
-		delegator = common.HexToAddress(MockEpoch[j]).Bytes()
-		candidate = common.HexToAddress(MockEpoch[j]).Bytes()
-		dposContext.DelegateTrie().TryUpdate(append(candidate, delegator...), candidate)
-		dposContext.CandidateTrie().TryUpdate(candidate, candidate)
-		dposContext.VoteTrie().TryUpdate(candidate, candidate)
+	if err := dposContext.SetEpochTrie(addresses); err != nil {
+		return nil
 	}
 	return dposContext
 }
 
-func setMintCntTrie(epochID int64, candidate common.Address, mintCntTrie *trie.Trie, count int64) {
+func setMintCntTrie(epochID int64, candidate common.Address, blockCntTrie *trie.Trie, count int64) {
 	key := make([]byte, 8)
 	binary.BigEndian.PutUint64(key, uint64(epochID))
 	cntBytes := make([]byte, 8)
 	binary.BigEndian.PutUint64(cntBytes, uint64(count))
-	mintCntTrie.TryUpdate(append(key, candidate.Bytes()...), cntBytes)
+	blockCntTrie.TryUpdate(append(key, candidate.Bytes()...), cntBytes)
 }
 
-func getMintCnt(epochID int64, candidate common.Address, mintCntTrie *trie.Trie) int64 {
+func getMintCnt(epochID int64, candidate common.Address, blockCntTrie *trie.Trie) int64 {
 	key := make([]byte, 8)
 	binary.BigEndian.PutUint64(key, uint64(epochID))
-	cntBytes := mintCntTrie.Get(append(key, candidate.Bytes()...))
+	cntBytes := blockCntTrie.Get(append(key, candidate.Bytes()...))
 	if cntBytes == nil {
 		return 0
 	} else {
@@ -84,35 +81,167 @@ func TestUpdateMintCnt(t *testing.T) {
 	dposContext := mockNewDposContext(db)
 
 	// new block still in the same epoch with current block, but newMiner is the first time to mint in the epoch
-	lastTime := int64(epochInterval)
+	lastTime := int64(protocol.EpochInterval)
 
 	miner := common.HexToAddress("0xa60a3886b552ff9992cfcd208ec1152079e046c2")
-	blockTime := int64(epochInterval + blockInterval)
+	blockTime := int64(protocol.EpochInterval + protocol.ProducerInterval)
 
-	beforeUpdateCnt := getMintCnt(blockTime/epochInterval, miner, dposContext.MintCntTrie())
+	beforeUpdateCnt := getMintCnt(blockTime/protocol.EpochInterval, miner, dposContext.BlockCntTrie())
 	updateMintCnt(lastTime, blockTime, miner, dposContext)
-	afterUpdateCnt := getMintCnt(blockTime/epochInterval, miner, dposContext.MintCntTrie())
+	afterUpdateCnt := getMintCnt(blockTime/protocol.EpochInterval, miner, dposContext.BlockCntTrie())
 	assert.Equal(t, int64(0), beforeUpdateCnt)
 	assert.Equal(t, int64(1), afterUpdateCnt)
 
 	// new block still in the same epoch with current block, and newMiner has mint block before in the epoch
-	setMintCntTrie(blockTime/epochInterval, miner, dposContext.MintCntTrie(), int64(1))
+	setMintCntTrie(blockTime/protocol.EpochInterval, miner, dposContext.BlockCntTrie(), int64(1))
 
-	blockTime = epochInterval + blockInterval*4
+	blockTime = protocol.EpochInterval + protocol.ProducerInterval*4
 
 	// currentBlock has recorded the count for the newMiner before UpdateMintCnt
-	beforeUpdateCnt = getMintCnt(blockTime/epochInterval, miner, dposContext.MintCntTrie())
+	beforeUpdateCnt = getMintCnt(blockTime/protocol.EpochInterval, miner, dposContext.BlockCntTrie())
 	updateMintCnt(lastTime, blockTime, miner, dposContext)
-	afterUpdateCnt = getMintCnt(blockTime/epochInterval, miner, dposContext.MintCntTrie())
+	afterUpdateCnt = getMintCnt(blockTime/protocol.EpochInterval, miner, dposContext.BlockCntTrie())
 	assert.Equal(t, int64(1), beforeUpdateCnt)
 	assert.Equal(t, int64(2), afterUpdateCnt)
 
 	// new block come to a new epoch
-	blockTime = epochInterval * 2
+	blockTime = protocol.EpochInterval * 2
 
-	beforeUpdateCnt = getMintCnt(blockTime/epochInterval, miner, dposContext.MintCntTrie())
+	beforeUpdateCnt = getMintCnt(blockTime/protocol.EpochInterval, miner, dposContext.BlockCntTrie())
 	updateMintCnt(lastTime, blockTime, miner, dposContext)
-	afterUpdateCnt = getMintCnt(blockTime/epochInterval, miner, dposContext.MintCntTrie())
+	afterUpdateCnt = getMintCnt(blockTime/protocol.EpochInterval, miner, dposContext.BlockCntTrie())
 	assert.Equal(t, int64(0), beforeUpdateCnt)
 	assert.Equal(t, int64(1), afterUpdateCnt)
 }
+
+func TestVerifyHeaderRevision(t *testing.T) {
+
+	const fork = 3
+	config := &params.ChainConfig{ChainId: big.NewInt(1), HeaderRevisionBlock: big.NewInt(fork)}
+	chain := newTestChain(config, fork+2, fork+2)
+	for _, header := range chain.headers[fork:] {
+		header.Revision = types.HeaderRevisionCompact
+		header.Slot = header.Time.Uint64() / uint64(protocol.ProducerInterval)
+	}
+	for i := 1; i < len(chain.headers); i++ {
+		chain.headers[i].ParentHash = chain.headers[i-1].Hash()
+	}
+	db, _ := ethdb.NewMemDatabase()
+	d := New(&params.DposConfig{}, db)
+
+	tests := []struct {
+		name   string
+		number int
+		modify func(header *types.Header)
+		err    error
+	}{
+		{"legacy before fork", fork - 1, func(header *types.Header) {}, nil},
+		{"compact at fork", fork, func(header *types.Header) {}, nil},
+		{"compact after fork", fork + 1, func(header *types.Header) {}, nil},
+		{"compact before fork", fork - 1, func(header *types.Header) {
+			header.Revision = types.HeaderRevisionCompact
+			header.Slot = header.Time.Uint64() / uint64(protocol.ProducerInterval)
+		}, errInvalidRevision},
+		{"legacy slot before fork", fork - 1, func(header *types.Header) { header.Slot = 1 }, errInvalidRevision},
+		{"legacy after fork", fork, func(header *types.Header) { header.Revision, header.Slot = types.HeaderRevisionLegacy, 0 }, errInvalidRevision},
+		{"next slot", fork, func(header *types.Header) { header.Slot++ }, errInvalidSlot},
+		{"previous slot", fork + 1, func(header *types.Header) { header.Slot-- }, errInvalidSlot},
+		{"zero slot", fork, func(header *types.Header) { header.Slot = 0 }, errInvalidSlot},
+	}
+	for _, tt := range tests {
+		header := types.CopyHeader(chain.headers[tt.number])
+		tt.modify(header)
+		if err := d.verifyHeader(chain, header, nil); err != tt.err {
+			t.Errorf("%s: have %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+//创建区块头依次由给定验证者签名的测试链
+func newValidatorChain(n int, validators ...common.Address) *testChain {
+
+	chain := newTestChain(&params.ChainConfig{ChainId: big.NewInt(1)}, n, uint64(n))
+	for i := 1; i < len(chain.headers); i++ {
+		chain.headers[i].Validator = validators[(i-1)%len(validators)]
+		chain.headers[i].ParentHash = chain.headers[i-1].Hash()
+	}
+	return chain
+}
+
+func TestIrreversibility(t *testing.T) {
+
+	_, addrs := newTestValidators(t, 3)
+	chain := newValidatorChain(5, addrs...)
+	db, _ := ethdb.NewMemDatabase()
+	d := New(&params.DposConfig{}, db)
+
+	//还没有确认过区块时无法计算
+	if _, err := d.irreversibility(chain); err == nil {
+		t.Fatalf("irreversibility computed without confirmed block")
+	}
+	if err := d.updateConfirmedBlockHeader(chain); err != nil {
+		t.Fatalf("failed to update confirmed block: %v", err)
+	}
+	head := chain.CurrentHeader()
+	result, err := d.irreversibility(chain)
+	if err != nil {
+		t.Fatalf("failed to compute irreversibility: %v", err)
+	}
+	assert.Equal(t, head.Hash(), result.Confirmed.Hash())
+	assert.Equal(t, uint64(0), result.Lag)
+	assert.Equal(t, 0, result.Signers)
+	assert.Equal(t, protocol.ConsensusSize, result.PendingConfirmations)
+
+	//重新启动后从数据库加载确认的区块头
+	api := &API{chain: chain, dpos: New(&params.DposConfig{}, db)}
+	number, err := api.GetConfirmedBlockNumber()
+	if err != nil {
+		t.Fatalf("failed to load confirmed block: %v", err)
+	}
+	assert.Equal(t, head.Number.Uint64(), number.Uint64())
+
+	//确认的区块之后的新区块计入延迟和签名的验证者
+	longer := newValidatorChain(8, addrs...)
+	result, err = irreversibilityFrom(longer, longer.headers[4])
+	if err != nil {
+		t.Fatalf("failed to compute irreversibility: %v", err)
+	}
+	assert.Equal(t, uint64(3), result.Lag)
+	assert.Equal(t, 3, result.Signers)
+	assert.Equal(t, int64(7), result.Head.Int64())
+}
+
+//确认的区块头在验证区块时更新，同时可以通过API读取，需要用-race运行
+func TestConfirmedHeaderConcurrency(t *testing.T) {
+
+	_, addrs := newTestValidators(t, 3)
+	chain := newValidatorChain(16, addrs...)
+
+	for i := 0; i < 32; i++ {
+		db, _ := ethdb.NewMemDatabase()
+		d := New(&params.DposConfig{}, db)
+		api := &API{chain: chain, dpos: d}
+
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if err := d.updateConfirmedBlockHeader(chain); err != nil {
+				t.Errorf("failed to update confirmed block: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			api.GetIrreversibility()
+		}()
+		go func() {
+			defer wg.Done()
+			api.GetConfirmedBlockNumber()
+		}()
+		wg.Wait()
+
+		if number, err := api.GetConfirmedBlockNumber(); err != nil || number.Cmp(chain.CurrentHeader().Number) != 0 {
+			t.Fatalf("confirmed block mismatch: have %v (%v), want %v", number, err, chain.CurrentHeader().Number)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package dpos
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/consensus"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
+)
+
+const (
+	selfCheckWindow  = int64(3600) //统计错过出块时间片的时间范围（秒）
+	selfCheckDepth   = 17280       //查找本节点最后出块时最多回溯的区块数量（一个周期）
+	clockSampleSize  = 64          //用于估计时钟偏差的最近区块数量
+	clockSampleRange = int64(60)   //只统计与本地时间相差不超过该值（秒）的区块，避免同步历史区块时的干扰
+)
+
+var (
+	errSignerNotAuthorized = errors.New("signer not authorized, start mining to unlock the coinbase")
+	errSignerMismatch      = errors.New("signature recovered to a different account")
+
+	missedSlotCounter = metrics.NewCounter("dpos/producer/missed") //看门狗发现的本节点错过的出块时间片数量
+)
+
+//本节点出块状态的自检结果
+type SelfCheck struct {
+	Coinbase       common.Address `json:"coinbase"`                  //检查的出块账号
+	IsValidator    bool           `json:"isValidator"`               //是否在当前周期的验证者中
+	Validators     int            `json:"validators"`                //当前周期的验证者数量
+	CanSign        bool           `json:"canSign"`                   //密钥库是否可以为该账号签名
+	SignError      string         `json:"signError,omitempty"`       //无法签名的原因
+	LastProduced   *ProducedBlock `json:"lastProducedBlock"`         //本节点最后生产的区块，最近一个周期内没有出块时为空
+	ClockSkew      int64          `json:"clockSkew"`                 //最近其他节点的区块到达时与区块时间的差值中位数（秒），负数表示本地时钟偏慢
+	ClockSamples   int            `json:"clockSamples"`              //用于估计时钟偏差的区块数量
+	FutureBlocks   uint64         `json:"futureBlocks"`              //因为区块时间晚于本地时间被拒绝的区块数量
+	Window         int64          `json:"window"`                    //统计出块时间片的时间范围（秒）
+	ScheduledSlots int            `json:"scheduledSlots"`            //时间范围内轮到本节点出块的时间片数量
+	MissedSlots    int            `json:"missedSlots"`               //时间范围内本节点错过的时间片数量
+	MissedSlotTime []int64        `json:"missedSlotTimes,omitempty"` //错过的时间片的开始时间
+}
+
+//本节点生产的区块
+type ProducedBlock struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Time   int64       `json:"time"`
+}
+
+//根据其他节点的区块到达时间估计本地时钟与网络的偏差
+type clockTracker struct {
+	samples []int64 //区块到达时本地时间减区块时间
+	next    int
+	future  uint64
+	mu      sync.Mutex
+}
+
+//记录一个其他节点区块的到达时间
+func (c *clockTracker) record(header *types.Header, now int64) {
+
+	delay := now - header.Time.Int64()
+	if delay > clockSampleRange || delay < -clockSampleRange {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if delay < 0 {
+		c.future++
+	}
+	if len(c.samples) < clockSampleSize {
+		c.samples = append(c.samples, delay)
+	} else {
+		c.samples[c.next] = delay
+		c.next = (c.next + 1) % clockSampleSize
+	}
+}
+
+//返回到达延迟的中位数、样本数量以及未来区块的数量
+func (c *clockTracker) skew() (int64, int, uint64) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.samples) == 0 {
+		return 0, 0, c.future
+	}
+	sorted := make([]int64, len(c.samples))
+	copy(sorted, c.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2], len(sorted), c.future
+}
+
+//得到当前授权的签名者和签名函数
+func (d *Dpos) authorized() (common.Address, SignerFn) {
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.signer, d.signFn
+}
+
+//检查密钥库是否可以为账号签名，并且签名可以恢复出该账号
+func (d *Dpos) checkSign(coinbase common.Address) error {
+
+	signer, signFn := d.authorized()
+	if signFn == nil || signer != coinbase {
+		return errSignerNotAuthorized
+	}
+	hash := crypto.Keccak256([]byte("dpos self check"))
+	sig, err := signFn(accounts.Account{Address: signer}, hash)
+	if err != nil {
+		return err
+	}
+	pubkey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return err
+	}
+	if crypto.PubkeyToAddress(*pubkey) != signer {
+		return errSignerMismatch
+	}
+	return nil
+}
+
+//统计从from开始、到to为止已经结束的时间片中轮到producer出块的数量以及producer没有出块的时间片
+func (d *Dpos) missedSlots(chain consensus.ChainReader, producer common.Address, from, to int64) (int, []int64, error) {
+
+	genesis := chain.GetHeaderByNumber(0)
+	if genesis == nil {
+		return 0, nil, protocol.ErrUnknownBlock
+	}
+	firstTimer := genesis.Time.Int64()
+
+	//区间内的第一个时间片
+	first := firstTimer + NextSlot(from-firstTimer)
+	if first < firstTimer {
+		first = firstTimer
+	}
+
+	var (
+		scheduled int
+		missed    []int64
+		child     *types.Header
+	)
+	for header := chain.CurrentHeader(); header != nil; header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1) {
+
+		//header之后、child之前（或者到to为止）的时间片都由header的Dpos状态决定出块者
+		end := to
+		if child != nil && child.Time.Int64() < end {
+			end = child.Time.Int64()
+		}
+		start := header.Time.Int64() + 1
+		if start < first {
+			start = first
+		}
+		if start < end {
+			snap, err := d.epochs.snapshot(header)
+			if err != nil {
+				return scheduled, missed, err
+			}
+			//与挖矿时使用同样的时间起点（创世区块时间）计算每个时间片的出块者
+			if len(snap.Validators) > 0 {
+				for slot := firstTimer + NextSlot(start-firstTimer); slot+protocol.ProducerInterval <= end; slot += protocol.ProducerInterval {
+					if expected, _ := snap.producer(slot, firstTimer); expected == producer {
+						scheduled++
+						missed = append(missed, slot)
+					}
+				}
+			}
+		}
+
+		//header本身所在的时间片
+		if header.Number.Uint64() == 0 || header.Time.Int64() < first {
+			break
+		}
+		if header.Validator == producer && header.Time.Int64() < to {
+			scheduled++
+		}
+		child = header
+	}
+	return scheduled, missed, nil
+}
+
+//检查本节点是否可以正常出块
+func (d *Dpos) selfCheck(chain consensus.ChainReader, coinbase common.Address) (*SelfCheck, error) {
+
+	head := chain.CurrentHeader()
+	if head == nil {
+		return nil, protocol.ErrUnknownBlock
+	}
+	check := &SelfCheck{Coinbase: coinbase, Window: selfCheckWindow}
+
+	//当前周期的验证者
+	snap, err := d.epochs.snapshot(head)
+	if err != nil {
+		return nil, err
+	}
+	check.Validators = len(snap.Validators)
+	check.IsValidator = snap.isValidator(coinbase)
+
+	//签名能力
+	if err := d.checkSign(coinbase); err != nil {
+		check.SignError = err.Error()
+	} else {
+		check.CanSign = true
+	}
+
+	//最后生产的区块
+	for header, depth := head, 0; header != nil && header.Number.Uint64() > 0 && depth < selfCheckDepth; depth++ {
+		if header.Validator == coinbase {
+			check.LastProduced = &ProducedBlock{Number: header.Number.Uint64(), Hash: header.Hash(), Time: header.Time.Int64()}
+			break
+		}
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+
+	//时钟偏差
+	check.ClockSkew, check.ClockSamples, check.FutureBlocks = d.clock.skew()
+
+	//错过的时间片
+	now := d.now().Unix()
+	scheduled, missed, err := d.missedSlots(chain, coinbase, now-selfCheckWindow, now)
+	if err != nil {
+		return nil, err
+	}
+	check.ScheduledSlots, check.MissedSlots, check.MissedSlotTime = scheduled, len(missed), missed
+	return check, nil
+}
+
+//启动看门狗，本节点作为出块者错过时间片时记录错误日志并增加度量计数
+func (d *Dpos) StartWatchdog(chain consensus.ChainReader) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stop != nil {
+		return
+	}
+	d.stop = make(chan bool)
+	go d.watchdog(chain, d.stop)
+}
+
+//停止看门狗
+func (d *Dpos) StopWatchdog() {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stop != nil {
+		close(d.stop)
+		d.stop = nil
+	}
+}
+
+func (d *Dpos) watchdog(chain consensus.ChainReader, stop chan bool) {
+
+	ticker := time.NewTicker(time.Duration(protocol.ProducerInterval) * time.Second)
+	defer ticker.Stop()
+
+	//多等待一个时间片，给本节点的区块写入链的时间
+	checked := d.now().Unix()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		signer, _ := d.authorized()
+		until := d.now().Unix() - protocol.ProducerInterval
+		if (signer == common.Address{}) {
+			checked = until - protocol.ProducerInterval + 1
+			continue
+		}
+		_, missed, err := d.missedSlots(chain, signer, checked, until)
+		if err != nil {
+			log.Debug("Dpos watchdog check failed", "err", err)
+			continue
+		}
+		for _, slot := range missed {
+			missedSlotCounter.Inc(1)
+			log.Error("Missed producer slot", "signer", signer, "slot", slot, "head", chain.CurrentHeader().Number)
+		}
+		//下一次从还没有结束的时间片开始检查
+		checked = until - protocol.ProducerInterval + 1
+	}
+}
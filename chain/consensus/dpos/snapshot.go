@@ -0,0 +1,183 @@
+package dpos
+
+import (
+	"encoding/binary"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+var (
+	epochCacheHitCounter  = metrics.NewCounter("dpos/epoch/cache/hit")  //内存中命中的周期快照数量
+	epochCacheDiskCounter = metrics.NewCounter("dpos/epoch/cache/disk") //从磁盘加载的周期快照数量
+	epochCacheMissCounter = metrics.NewCounter("dpos/epoch/cache/miss") //需要重新读取周期树的数量
+)
+
+//一个周期的验证者快照，Root为周期树的根，不同分叉上同一周期的验证者不同，查找时必须同时匹配周期号和Root
+type epochSnapshot struct {
+	Epoch      uint64
+	Root       common.Hash
+	Validators []common.Address
+
+	index map[common.Address]int //验证者在列表中的位置
+}
+
+func newEpochSnapshot(epoch uint64, root common.Hash, validators []common.Address) *epochSnapshot {
+
+	snap := &epochSnapshot{
+		Epoch:      epoch,
+		Root:       root,
+		Validators: validators,
+		index:      make(map[common.Address]int, len(validators)),
+	}
+	for i, validator := range validators {
+		if _, ok := snap.index[validator]; !ok {
+			snap.index[validator] = i
+		}
+	}
+	return snap
+}
+
+//是否是本周期的验证者
+func (s *epochSnapshot) isValidator(address common.Address) bool {
+	_, ok := s.index[address]
+	return ok
+}
+
+//根据时间得到当时的出块者，与DposContext.GetNowProducer的计算方式相同
+func (s *epochSnapshot) producer(now int64, firstTimer int64) (common.Address, error) {
+
+	if len(s.Validators) == 0 {
+		return common.Address{}, protocol.ErrEpochTrieNil
+	}
+	offset := (now - firstTimer) % protocol.EpochInterval
+	offset /= protocol.ProducerInterval
+	return s.Validators[offset%int64(len(s.Validators))], nil
+}
+
+//根据出块时间得到出块者，时间必须在时间片的开始，与DposContext.GetProducer的校验方式相同
+func (s *epochSnapshot) slotProducer(now int64, firstTimer int64) (common.Address, error) {
+
+	if (now-firstTimer)%protocol.EpochInterval%protocol.ProducerInterval != 0 {
+		return common.Address{}, protocol.ErrInvalidProducerTime
+	}
+	if len(s.Validators) == 0 {
+		return common.Address{}, protocol.ErrInvalidProducer
+	}
+	return s.producer(now, firstTimer)
+}
+
+//区块所在的周期
+func epochNumber(header *types.Header) uint64 {
+	return uint64(header.Time.Int64() / protocol.EpochInterval)
+}
+
+func epochSnapshotKey(epoch uint64) []byte {
+
+	key := make([]byte, len(protocol.EpochSnapshotPrefix)+8)
+	copy(key, protocol.EpochSnapshotPrefix)
+	binary.BigEndian.PutUint64(key[len(protocol.EpochSnapshotPrefix):], epoch)
+	return key
+}
+
+//按周期号缓存验证者快照，内存中保存最近的周期，同时写入数据库以便重启后直接使用
+type epochCache struct {
+	db    ethdb.Database
+	cache *lru.ARCCache //周期号 -> *epochSnapshot
+}
+
+func newEpochCache(db ethdb.Database) *epochCache {
+
+	cache, _ := lru.NewARC(protocol.InmemoryEpochSnapshots)
+	return &epochCache{db: db, cache: cache}
+}
+
+//得到区块所在周期的验证者快照。缓存中的快照与区块的周期树根不一致时说明发生了重组或者周期树已更新，
+//此时重新读取周期树并替换内存和磁盘中的快照
+func (c *epochCache) snapshot(header *types.Header) (*epochSnapshot, error) {
+
+	var (
+		epoch = epochNumber(header)
+		root  = header.DposProto.EpochHash
+	)
+	if cached, ok := c.cache.Get(epoch); ok {
+		if snap := cached.(*epochSnapshot); snap.Root == root {
+			epochCacheHitCounter.Inc(1)
+			return snap, nil
+		}
+	}
+	if snap := c.load(epoch); snap != nil && snap.Root == root {
+		epochCacheDiskCounter.Inc(1)
+		c.cache.Add(epoch, snap)
+		return snap, nil
+	}
+	epochCacheMissCounter.Inc(1)
+
+	epochTrie, err := types.NewEpochTrie(root, c.db)
+	if err != nil {
+		return nil, err
+	}
+	dposContext := types.DposContext{}
+	dposContext.SetEpoch(epochTrie)
+	validators, err := dposContext.GetEpochTrie()
+	if err != nil {
+		return nil, err
+	}
+	snap := newEpochSnapshot(epoch, root, validators)
+	c.cache.Add(epoch, snap)
+	c.store(snap)
+	return snap, nil
+}
+
+//从数据库读取周期快照
+func (c *epochCache) load(epoch uint64) *epochSnapshot {
+
+	data, _ := c.db.Get(epochSnapshotKey(epoch))
+	if len(data) == 0 {
+		return nil
+	}
+	var stored epochSnapshot
+	if err := rlp.DecodeBytes(data, &stored); err != nil {
+		log.Warn("Invalid epoch snapshot", "epoch", epoch, "err", err)
+		return nil
+	}
+	return newEpochSnapshot(stored.Epoch, stored.Root, stored.Validators)
+}
+
+//将周期快照写入数据库，写入失败不影响使用，下次重新读取周期树
+func (c *epochCache) store(snap *epochSnapshot) {
+
+	data, err := rlp.EncodeToBytes(snap)
+	if err != nil {
+		return
+	}
+	if err := c.db.Put(epochSnapshotKey(snap.Epoch), data); err != nil {
+		log.Warn("Failed to store epoch snapshot", "epoch", snap.Epoch, "err", err)
+	}
+}
+
+//得到区块所在周期的验证者列表，返回的列表可以修改
+func (d *Dpos) Validators(header *types.Header) ([]common.Address, error) {
+
+	snap, err := d.epochs.snapshot(header)
+	if err != nil {
+		return nil, err
+	}
+	return append([]common.Address(nil), snap.Validators...), nil
+}
+
+//根据区块的周期快照得到指定时间的出块者
+func (d *Dpos) ProducerAt(header *types.Header, now int64, firstTimer int64) (common.Address, error) {
+
+	snap, err := d.epochs.snapshot(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return snap.producer(now, firstTimer)
+}
@@ -530,18 +530,23 @@ func (ethash *Ethash) Finalize(chain consensus.ChainReader,
 //计算报酬，注意由于采用的Dpos因此不会出现叔块的可能性，而最新版的以太坊中此处是按照循环来进行叔块报酬计算的
 func AccumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header, boker bokerapi.Api) {
 
-	//得到合约的账号地址
-	addr, err := boker.GetContractAddr(protocol.SystemContract)
-	if err != nil {
-		return
-	}
-
 	//给出块节点的报酬(1 * 220 = 220 单位:Bobby)
 	blockReward := big.NewInt(1)
 	blockReward.Mul(protocol.BobbyUnit, protocol.BobbyMultiple)
 	reward := new(big.Int).Set(blockReward)
 	state.AddBalance(header.Coinbase, reward)
 
+	//与Dpos一致，没有播客链接口时（例如模拟链）不产生分配通证账号的报酬
+	if boker == nil {
+		return
+	}
+
+	//得到合约的账号地址
+	addr, err := boker.GetContractAddr(protocol.SystemContract)
+	if err != nil {
+		return
+	}
+
 	//给指定账号产生报酬，此账号用于分配通证(1 * 330 = 330 单位:Bobby)
 	blockTransfer := big.NewInt(1)
 	blockTransfer.Mul(protocol.BobbyUnit, protocol.TransferMultiple)
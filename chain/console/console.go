@@ -1,6 +1,7 @@
 package console
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -142,6 +143,19 @@ func (c *Console) init(preload []string) error {
 	if _, err = c.jsre.Run(flatten); err != nil {
 		return fmt.Errorf("namespace flattening: %v", err)
 	}
+
+	//为Boker特有的模块添加help()方法，输出方法和属性的用法
+	for api := range apis {
+		help, ok := web3ext.Help[api]
+		if !ok {
+			continue
+		}
+		obj, err := c.jsre.Get(api)
+		if err != nil || !obj.IsObject() {
+			continue
+		}
+		obj.Object().Set("help", c.moduleHelp(api, help))
+	}
 	// Initialize the global name register (disabled for now)
 	//c.jsre.Run(`var GlobalRegistrar = eth.contract(` + registrar.GlobalRegistrarAbi + `);   registrar = GlobalRegistrar.at("` + registrar.GlobalRegistrarAddr + `");`)
 
@@ -220,6 +234,31 @@ func (c *Console) consoleOutput(call otto.FunctionCall) otto.Value {
 	return otto.Value{}
 }
 
+//返回输出模块用法的help方法，没有参数时输出所有方法和属性，否则只输出指定的方法或属性
+func (c *Console) moduleHelp(api string, help map[string]string) func(call otto.FunctionCall) otto.Value {
+
+	return func(call otto.FunctionCall) otto.Value {
+		if len(call.ArgumentList) > 0 {
+			name := call.Argument(0).String()
+			if usage, ok := help[name]; ok {
+				fmt.Fprintf(c.printer, "%s.%s\n", api, usage)
+			} else {
+				fmt.Fprintf(c.printer, "%s has no method or property %q\n", api, name)
+			}
+			return otto.Value{}
+		}
+		names := make([]string, 0, len(help))
+		for name := range help {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(c.printer, "%s.%s\n", api, help[name])
+		}
+		return otto.Value{}
+	}
+}
+
 // AutoCompleteInput is a pre-assembled word completer to be used by the user
 // input prompter to provide hints to the user about the methods available.
 func (c *Console) AutoCompleteInput(line string, pos int) (string, []string, string) {
@@ -281,6 +320,17 @@ func (c *Console) Welcome() {
 		}
 		sort.Strings(modules)
 		fmt.Fprintln(c.printer, "modules:", strings.Join(modules, " "))
+
+		helps := make([]string, 0, len(web3ext.Help))
+		for api := range web3ext.Help {
+			if _, ok := apis[api]; ok {
+				helps = append(helps, api+".help()")
+			}
+		}
+		if len(helps) > 0 {
+			sort.Strings(helps)
+			fmt.Fprintln(c.printer, "usage:", strings.Join(helps, " "))
+		}
 	}
 	fmt.Fprintln(c.printer)
 }
@@ -297,6 +347,20 @@ func (c *Console) Evaluate(statement string) error {
 	return c.jsre.Evaluate(statement, c.printer)
 }
 
+//执行语句并将结果以一行JSON写入w，失败时写入{"error": 错误信息}并返回错误，用于脚本调用
+func (c *Console) EvaluateJSON(statement string, w io.Writer) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("[native] error: %v", r)
+		}
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		}
+	}()
+
+	return c.jsre.EvaluateJSON(statement, w)
+}
+
 // Interactive starts an interactive user session, where input is propted from
 // the configured user prompter.
 func (c *Console) Interactive() {
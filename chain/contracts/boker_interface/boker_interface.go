@@ -71,20 +71,22 @@ func (s *BokerInterfaceService) Start() {
 	go s.assignToken()
 }
 
+//停止服务，依次通知tick、getEpoch和assignToken三个协程退出并等待它们返回
 func (s *BokerInterfaceService) Stop() error {
 
+	if !s.start {
+		return nil
+	}
 	s.start = false
 
-	errTick := make(chan error)
-	s.tickQuit <- errTick
-
-	errEpoch := make(chan error)
-	s.epochQuit <- errEpoch
-
-	errc := make(chan error)
-	s.quit <- errc
-
-	return <-errc
+	for _, quit := range []chan chan error{s.tickQuit, s.epochQuit, s.quit} {
+		errc := make(chan error)
+		quit <- errc
+		if err := <-errc; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *BokerInterfaceService) tick() {
@@ -97,7 +99,7 @@ func (s *BokerInterfaceService) tick() {
 		case <-timer.C:
 			s.business()
 			timer.Reset(protocol.BokerInterval * 1)
-		case errc := <-s.quit:
+		case errc := <-s.tickQuit:
 			errc <- nil
 			return
 		}
@@ -226,61 +228,71 @@ func (s *BokerInterfaceService) vote() {
 
 }
 
+//定期分配通证，分配失败时只记录日志，等待下一次定时，保证Stop时能够收到退出信号
 func (s *BokerInterfaceService) assignToken() {
 
 	var lastTxTime int64 = 0
 
-	for {
-
-		time.Sleep(time.Duration(500) * protocol.AssignTickInterval)
+	timer := time.NewTimer(time.Duration(500) * protocol.AssignTickInterval)
+	defer timer.Stop()
 
-		if lastTxTime != 0 && lastTxTime == time.Now().Unix() {
-			continue
-		}
+	for {
+		select {
+		case <-timer.C:
+			lastTxTime = s.tryAssignToken(lastTxTime)
+			timer.Reset(time.Duration(500) * protocol.AssignTickInterval)
 
-		//得到第一个区块
-		blocks := s.ethereum.BlockChain().GetBlockByNumber(0)
-		if blocks == nil {
-			continue
+		case errc := <-s.quit:
+			errc <- nil
+			return
 		}
-		//得到第一个区块的时间
-		firstTimer := blocks.Time().Int64()
-		now := time.Now().Unix()
-		offset := now - firstTimer
+	}
+}
 
-		if offset%protocol.TokenNoderInterval == 0 {
+//到达分配时间时发起通证分配交易，返回最后一次分配交易的时间
+func (s *BokerInterfaceService) tryAssignToken(lastTxTime int64) int64 {
 
-			log.Info("Bokerchain Assign Token Start", "Now", now, "firstTimer", firstTimer)
+	if lastTxTime != 0 && lastTxTime == time.Now().Unix() {
+		return lastTxTime
+	}
 
-			//通证分配节点是否是当前节点
-			if err := s.getCurrentTokenNoder(); err != nil {
-				log.Error("Failed to Assign Token", "err", err)
-				return
-			}
-			log.Info("Bokerchain Assign Token Noder Check Success")
+	//得到第一个区块
+	blocks := s.ethereum.BlockChain().GetBlockByNumber(0)
+	if blocks == nil {
+		return lastTxTime
+	}
+	//得到第一个区块的时间
+	firstTimer := blocks.Time().Int64()
+	now := time.Now().Unix()
+	offset := now - firstTimer
 
-			opts := s.createTransactOpts()
-			tx, err := s.bokerInterface.BokerInterfaceTransactor.AssignToken(opts, now)
-			if err != nil {
-				if err == bind.ErrNoCode {
-					log.Info("Bokerchain Assign Token Address Not Found", "Contract", s.addr)
-				} else {
-					log.Error("Bokerchain Assign Token Failed", "err", err)
-				}
-				return
-			} else {
+	if offset%protocol.TokenNoderInterval == 0 {
 
-				if tx != nil {
+		log.Info("Bokerchain Assign Token Start", "Now", now, "firstTimer", firstTimer)
 
-					lastTxTime = tx.Time().Int64()
-				}
+		//通证分配节点是否是当前节点
+		if err := s.getCurrentTokenNoder(); err != nil {
+			log.Error("Failed to Assign Token", "err", err)
+			return lastTxTime
+		}
+		log.Info("Bokerchain Assign Token Noder Check Success")
 
-				log.Info("Bokerchain Assign Token End")
+		opts := s.createTransactOpts()
+		tx, err := s.bokerInterface.BokerInterfaceTransactor.AssignToken(opts, now)
+		if err != nil {
+			if err == bind.ErrNoCode {
+				log.Info("Bokerchain Assign Token Address Not Found", "Contract", s.addr)
+			} else {
+				log.Error("Bokerchain Assign Token Failed", "err", err)
 			}
-
+			return lastTxTime
 		}
+		if tx != nil {
+			lastTxTime = tx.Time().Int64()
+		}
+		log.Info("Bokerchain Assign Token End")
 	}
-
+	return lastTxTime
 }
 
 //通证分配函数
@@ -0,0 +1,36 @@
+package boker_contract
+
+import (
+	"testing"
+	"time"
+)
+
+//Stop需要让三个协程都退出，不能阻塞在没有协程接收的退出chan上
+func TestStopService(t *testing.T) {
+
+	s := &BokerInterfaceService{
+		tickQuit:  make(chan chan error),
+		epochQuit: make(chan chan error),
+		quit:      make(chan chan error),
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatalf("stop before start: %v", err)
+	}
+
+	//定时器触发之前停止，不会访问为空的以太坊对象
+	s.Start()
+	done := make(chan error)
+	go func() { done <- s.Stop() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("stop failed: %v", err)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatalf("stop blocked")
+	}
+	if s.IsStart() {
+		t.Errorf("service still marked as started")
+	}
+}
@@ -0,0 +1,266 @@
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+const (
+	addrIndexChunk     = 1024             //后台补建索引时每次持有链锁处理的区块数量
+	addrIndexLiveLimit = 16               //写入新区块时最多顺带索引的区块数量
+	addrIndexRecheck   = 10 * time.Second //后台补建索引完成后重新检查的间隔
+)
+
+var (
+	ErrAddressIndexDisabled = errors.New("address transaction index not enabled")   //地址交易索引没有开启
+	errAddressIndexMissing  = errors.New("address transaction index block missing") //索引需要的区块数据不存在
+)
+
+//开启地址到交易的索引，并在后台从上次索引的位置补建到当前区块
+func (bc *BlockChain) StartAddressIndex() {
+
+	if !atomic.CompareAndSwapInt32(&bc.addrIndex, 0, 1) {
+		return
+	}
+	bc.wg.Add(1)
+	go bc.addrIndexLoop()
+}
+
+//判断是否开启了地址交易索引
+func (bc *BlockChain) AddressIndexEnabled() bool {
+	return atomic.LoadInt32(&bc.addrIndex) == 1
+}
+
+//后台补建地址交易索引，补建完成后定期检查是否有遗漏的区块(例如快速同步导入的区块)
+func (bc *BlockChain) addrIndexLoop() {
+	defer bc.wg.Done()
+
+	ticker := time.NewTicker(addrIndexRecheck)
+	defer ticker.Stop()
+
+	for {
+		bc.mu.Lock()
+		head, done, err := bc.updateAddressIndex(addrIndexChunk)
+		bc.mu.Unlock()
+
+		if err != nil {
+			log.Error("Failed to update address transaction index", "err", err)
+			done = true
+		}
+		if !done {
+			log.Info("Backfilling address transaction index", "indexed", head, "head", bc.CurrentBlock().NumberU64())
+			select {
+			case <-bc.quit:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+//将地址交易索引更新到当前的规范链：先回退已经不在规范链上的区块，再最多向前索引limit个区块，
+//返回索引到的区块号以及是否已经追上链头，调用者需要持有bc.mu锁
+func (bc *BlockChain) updateAddressIndex(limit int) (uint64, bool, error) {
+
+	head, err := bc.addressIndexHead()
+	if err != nil {
+		return 0, false, err
+	}
+
+	//回退分叉后不在规范链上的区块
+	for GetCanonicalHash(bc.chainDb, head.Number.Uint64()) != head.Hash() {
+		if head, err = bc.unindexAddressTxs(head); err != nil {
+			return 0, false, err
+		}
+	}
+
+	//向前索引规范链上的区块
+	number, current := head.Number.Uint64(), bc.currentBlock.NumberU64()
+	for ; number < current && limit > 0; limit-- {
+		block := bc.GetBlockByNumber(number + 1)
+		if block == nil {
+			return number, false, errAddressIndexMissing
+		}
+		if err := bc.indexAddressTxs(block); err != nil {
+			return number, false, err
+		}
+		number++
+	}
+	return number, number >= current, nil
+}
+
+//将地址交易索引回退到指定的区块号，区块数据被删除前调用，调用者需要持有bc.mu锁
+func (bc *BlockChain) rewindAddressIndex(number uint64) error {
+
+	if GetAddressTxIndexHead(bc.chainDb) == (common.Hash{}) {
+		return nil
+	}
+	head, err := bc.addressIndexHead()
+	if err != nil {
+		return err
+	}
+	for head.Number.Uint64() > number {
+		if head, err = bc.unindexAddressTxs(head); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//获取地址交易索引最后索引的区块头，从未建立过索引时从创世块开始
+func (bc *BlockChain) addressIndexHead() (*types.Header, error) {
+
+	hash := GetAddressTxIndexHead(bc.chainDb)
+	if hash == (common.Hash{}) {
+		WriteAddressTxIndexHead(bc.chainDb, bc.genesisBlock.Hash())
+		return bc.genesisBlock.Header(), nil
+	}
+	head := bc.GetHeaderByHash(hash)
+	if head == nil {
+		return nil, errAddressIndexMissing
+	}
+	return head, nil
+}
+
+//将区块中的交易加入发送方和接收方的索引
+func (bc *BlockChain) indexAddressTxs(block *types.Block) error {
+
+	var (
+		batch  = bc.chainDb.NewBatch()
+		counts = make(map[common.Address]uint64)
+		signer = types.MakeSigner(bc.config, block.Number())
+	)
+	for _, tx := range block.Transactions() {
+		for _, addr := range txAddresses(signer, tx) {
+			count, ok := counts[addr]
+			if !ok {
+				count = GetAddressTxCount(bc.chainDb, addr)
+			}
+			entry := &AddressTxEntry{BlockNumber: block.NumberU64(), TxHash: tx.Hash()}
+			if err := WriteAddressTxEntry(batch, addr, count, entry); err != nil {
+				return err
+			}
+			counts[addr] = count + 1
+		}
+	}
+	for addr, count := range counts {
+		if err := WriteAddressTxCount(batch, addr, count); err != nil {
+			return err
+		}
+	}
+	WriteAddressTxIndexHead(batch, block.Hash())
+	return batch.Write()
+}
+
+//将区块中的交易从索引中删除，返回父区块头作为新的索引位置
+func (bc *BlockChain) unindexAddressTxs(header *types.Header) (*types.Header, error) {
+
+	parent := bc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	block := bc.GetBlock(header.Hash(), header.Number.Uint64())
+	if parent == nil || block == nil {
+		return nil, errAddressIndexMissing
+	}
+	var (
+		batch  = bc.chainDb.NewBatch()
+		counts = make(map[common.Address]uint64)
+		signer = types.MakeSigner(bc.config, block.Number())
+	)
+	for _, tx := range block.Transactions() {
+		for _, addr := range txAddresses(signer, tx) {
+			count, ok := counts[addr]
+			if !ok {
+				count = GetAddressTxCount(bc.chainDb, addr)
+			}
+			if count > 0 {
+				counts[addr] = count - 1
+			}
+		}
+	}
+
+	//先更新计数再删除条目，中途退出时多余的条目会在下次索引时被覆盖
+	for addr, count := range counts {
+		if err := WriteAddressTxCount(batch, addr, count); err != nil {
+			return nil, err
+		}
+	}
+	WriteAddressTxIndexHead(batch, parent.Hash())
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	for addr, count := range counts {
+		for seq := count; ; seq++ {
+			entry := GetAddressTxEntry(bc.chainDb, addr, seq)
+			if entry == nil {
+				break
+			}
+			DeleteAddressTxEntry(bc.chainDb, addr, seq)
+		}
+	}
+	return parent, nil
+}
+
+//获取交易涉及的地址：发送方和接收方，创建合约的交易接收方为新合约的地址
+func txAddresses(signer types.Signer, tx *types.Transaction) []common.Address {
+
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		if to := tx.To(); to != nil {
+			return []common.Address{*to}
+		}
+		return nil
+	}
+	to := crypto.CreateAddress(from, tx.Nonce())
+	if tx.To() != nil {
+		to = *tx.To()
+	}
+	if to == from {
+		return []common.Address{from}
+	}
+	return []common.Address{from, to}
+}
+
+//按区块范围分页查询地址的交易索引，start为上一页返回的位置(为空时从区块范围的起点开始)，
+//返回最多limit个条目，以及还有更多条目时下一页的起始位置
+func GetAddressTxs(db DatabaseReader, addr common.Address, from, to uint64, start *uint64, limit int) ([]*AddressTxEntry, *uint64) {
+
+	count := GetAddressTxCount(db, addr)
+
+	var seq uint64
+	if start != nil {
+		seq = *start
+	} else {
+		seq = uint64(sort.Search(int(count), func(i int) bool {
+			entry := GetAddressTxEntry(db, addr, uint64(i))
+			return entry == nil || entry.BlockNumber >= from
+		}))
+	}
+	var entries []*AddressTxEntry
+	for ; seq < count; seq++ {
+		entry := GetAddressTxEntry(db, addr, seq)
+		if entry == nil || entry.BlockNumber > to {
+			return entries, nil
+		}
+		if entry.BlockNumber < from {
+			continue
+		}
+		if len(entries) >= limit {
+			next := seq
+			return entries, &next
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/core/vm"
+)
+
+//记录分配通证交易执行过程中的转账，被回退的调用中的转账同时被丢弃
+type transferRecorder struct {
+	vm.StateDB
+
+	noder     common.Address //分配通证的节点，节点转给合约的金额不属于分配
+	marks     map[int]int    //快照编号对应的转账数量
+	transfers []*types.TokenTransfer
+}
+
+func newTransferRecorder(statedb vm.StateDB, noder common.Address) *transferRecorder {
+	return &transferRecorder{
+		StateDB: statedb,
+		noder:   noder,
+		marks:   make(map[int]int),
+	}
+}
+
+func (r *transferRecorder) Snapshot() int {
+	id := r.StateDB.Snapshot()
+	r.marks[id] = len(r.transfers)
+	return id
+}
+
+func (r *transferRecorder) RevertToSnapshot(id int) {
+	if n, ok := r.marks[id]; ok {
+		r.transfers = r.transfers[:n]
+	}
+	r.StateDB.RevertToSnapshot(id)
+}
+
+//替换EVM上下文中的转账函数
+func (r *transferRecorder) transfer(db vm.StateDB, sender, recipient common.Address, amount *big.Int) {
+	if amount.Sign() > 0 && sender != r.noder {
+		r.transfers = append(r.transfers, &types.TokenTransfer{From: sender, To: recipient, Amount: new(big.Int).Set(amount)})
+	}
+	Transfer(db, sender, recipient, amount)
+}
+
+//根据区块中的交易和回执生成分配通证的记录
+func tokenAssignments(signer types.Signer, block *types.Block, receipts types.Receipts) []*TokenAssignment {
+
+	var assignments []*TokenAssignment
+	for i, tx := range block.Transactions() {
+		if tx.Type() != protocol.AssignToken || i >= len(receipts) {
+			continue
+		}
+		noder, _ := types.Sender(signer, tx)
+		assignments = append(assignments, &TokenAssignment{
+			TxHash:    tx.Hash(),
+			TxIndex:   uint64(i),
+			Noder:     noder,
+			Amount:    tx.Value(),
+			Failed:    receipts[i].Status == types.ReceiptStatusFailed,
+			Transfers: receipts[i].Transfers,
+		})
+	}
+	return assignments
+}
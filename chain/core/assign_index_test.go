@@ -0,0 +1,90 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+)
+
+//测试只记录合约转出的金额，被回退的调用中的转账同时被丢弃
+func TestTransferRecorder(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	var (
+		noder    = common.Address{1}
+		contract = common.Address{2}
+		alice    = common.Address{3}
+		bob      = common.Address{4}
+	)
+	statedb.AddBalance(noder, big.NewInt(100))
+	recorder := newTransferRecorder(statedb, noder)
+
+	recorder.transfer(recorder, noder, contract, big.NewInt(100))
+	recorder.transfer(recorder, contract, alice, big.NewInt(30))
+	snapshot := recorder.Snapshot()
+	recorder.transfer(recorder, contract, bob, big.NewInt(20))
+	recorder.RevertToSnapshot(snapshot)
+	recorder.transfer(recorder, contract, bob, big.NewInt(0))
+	recorder.transfer(recorder, contract, bob, big.NewInt(10))
+
+	if len(recorder.transfers) != 2 {
+		t.Fatalf("transfer count mismatch: have %d, want 2", len(recorder.transfers))
+	}
+	if tr := recorder.transfers[0]; tr.To != alice || tr.Amount.Int64() != 30 {
+		t.Errorf("first transfer mismatch: have %x %v", tr.To, tr.Amount)
+	}
+	if tr := recorder.transfers[1]; tr.To != bob || tr.Amount.Int64() != 10 {
+		t.Errorf("second transfer mismatch: have %x %v", tr.To, tr.Amount)
+	}
+	if balance := statedb.GetBalance(contract); balance.Int64() != 60 {
+		t.Errorf("contract balance mismatch: have %v, want 60", balance)
+	}
+}
+
+//测试分配通证记录的生成和读写
+func TestTokenAssignments(t *testing.T) {
+	var (
+		signer = types.HomesteadSigner{}
+		to     = common.Address{2}
+	)
+	assign := types.NewTransaction(protocol.AssignToken, 0, to, big.NewInt(100), big.NewInt(21000), big.NewInt(0), nil)
+	binary := types.NewTransaction(protocol.Binary, 1, to, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil)
+
+	receipts := types.Receipts{
+		types.NewReceipt(nil, false, big.NewInt(0)),
+		types.NewReceipt(nil, false, big.NewInt(21000)),
+	}
+	receipts[0].Transfers = []*types.TokenTransfer{{From: to, To: common.Address{3}, Amount: big.NewInt(40)}}
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(7)}, []*types.Transaction{binary}, nil, nil)
+	if assignments := tokenAssignments(signer, block, receipts); len(assignments) != 0 {
+		t.Fatalf("binary transaction indexed: %v", assignments)
+	}
+	block = types.NewBlock(&types.Header{Number: big.NewInt(7)}, []*types.Transaction{assign, binary}, nil, nil)
+	assignments := tokenAssignments(signer, block, receipts)
+	if len(assignments) != 1 || assignments[0].TxHash != assign.Hash() || assignments[0].TxIndex != 0 {
+		t.Fatalf("assignments mismatch: have %v", assignments)
+	}
+
+	db, _ := ethdb.NewMemDatabase()
+	if err := WriteTokenAssignments(db, block.Hash(), 7, assignments); err != nil {
+		t.Fatal(err)
+	}
+	stored := GetTokenAssignments(db, block.Hash(), 7)
+	if len(stored) != 1 || stored[0].Amount.Int64() != 100 || len(stored[0].Transfers) != 1 {
+		t.Fatalf("stored assignments mismatch: have %v", stored)
+	}
+	if tr := stored[0].Transfers[0]; tr.To != (common.Address{3}) || tr.Amount.Int64() != 40 {
+		t.Errorf("stored transfer mismatch: have %x %v", tr.To, tr.Amount)
+	}
+	DeleteBlock(db, block.Hash(), 7)
+	if GetTokenAssignments(db, block.Hash(), 7) != nil {
+		t.Errorf("assignments not deleted with the block")
+	}
+}
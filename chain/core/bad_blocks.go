@@ -0,0 +1,65 @@
+package core
+
+import (
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+//验证失败的完整区块以及失败原因，保存在数据库中，节点重启后仍然可以查询
+type BadBlock struct {
+	Block *types.Block
+	Error string //验证失败的错误信息
+	Peer  string //发送该区块的节点，本地导入时为空
+	Time  uint64 //发现该区块的时间
+}
+
+//debug_getBadBlocks返回的坏区块信息
+type BadBlockArgs struct {
+	Hash   common.Hash   `json:"hash"`
+	Header *types.Header `json:"header"`
+	Error  string        `json:"error"`
+	Peer   string        `json:"peer"`
+	Time   uint64        `json:"time"`
+}
+
+//返回最近发现的坏区块(从新到旧)，最多保留badBlockLimit个
+func (bc *BlockChain) BadBlocks() ([]BadBlockArgs, error) {
+
+	bad := GetBadBlocks(bc.chainDb)
+	blocks := make([]BadBlockArgs, 0, len(bad))
+	for _, b := range bad {
+		blocks = append(blocks, BadBlockArgs{Hash: b.Block.Hash(), Header: b.Block.Header(), Error: b.Error, Peer: b.Peer, Time: b.Time})
+	}
+	return blocks, nil
+}
+
+//根据Hash得到保存的坏区块
+func (bc *BlockChain) GetBadBlock(hash common.Hash) *BadBlock {
+
+	for _, b := range GetBadBlocks(bc.chainDb) {
+		if b.Block.Hash() == hash {
+			return b
+		}
+	}
+	return nil
+}
+
+//保存坏区块，同一区块再次出现时只保留最新的记录，调用者需要持有bc.chainmu
+func (bc *BlockChain) addBadBlock(block *types.Block, peer string, err error) {
+
+	blocks := []*BadBlock{{Block: block, Error: err.Error(), Peer: peer, Time: uint64(time.Now().Unix())}}
+	for _, b := range GetBadBlocks(bc.chainDb) {
+		if len(blocks) >= badBlockLimit {
+			break
+		}
+		if b.Block.Hash() != block.Hash() {
+			blocks = append(blocks, b)
+		}
+	}
+	if err := WriteBadBlocks(bc.chainDb, blocks); err != nil {
+		log.Error("Failed to store bad block", "hash", block.Hash(), "err", err)
+	}
+}
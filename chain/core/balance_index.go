@@ -0,0 +1,206 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"sync/atomic"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+const balanceIndexLiveLimit = 1024 //写入新区块时最多向前索引的区块数量
+
+var (
+	ErrBalanceIndexDisabled = errors.New("balance history index not enabled")   //余额历史索引没有开启
+	errBalanceIndexMissing  = errors.New("balance history index block missing") //索引需要的区块数据不存在
+)
+
+//开启余额历史索引，只记录开启之后执行的区块，第一次开启时从当前区块之后开始索引
+func (bc *BlockChain) StartBalanceIndex() {
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if GetBalanceIndexHead(bc.chainDb) == (common.Hash{}) {
+		WriteBalanceIndexTail(bc.chainDb, bc.currentBlock.NumberU64()+1)
+		WriteBalanceIndexHead(bc.chainDb, bc.currentBlock.Hash())
+	}
+	atomic.StoreInt32(&bc.balanceIndex, 1)
+}
+
+//判断是否开启了余额历史索引
+func (bc *BlockChain) BalanceIndexEnabled() bool {
+	return atomic.LoadInt32(&bc.balanceIndex) == 1
+}
+
+//对比父区块的状态，得到区块执行后余额发生变化的账户，需要在状态提交之前调用
+func (bc *BlockChain) balanceChanges(block *types.Block, statedb *state.StateDB) ([]*BalanceChange, error) {
+
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, errBalanceIndexMissing
+	}
+	prevState, err := bc.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]*BalanceChange, 0)
+	for _, addr := range statedb.DirtyAccounts() {
+		prev, balance := prevState.GetBalance(addr), statedb.GetBalance(addr)
+		if prev.Cmp(balance) != 0 {
+			changes = append(changes, &BalanceChange{Address: addr, Prev: new(big.Int).Set(prev), Balance: new(big.Int).Set(balance)})
+		}
+	}
+	//按地址排序，保证写入的内容与遍历顺序无关
+	sort.Slice(changes, func(i, j int) bool { return bytes.Compare(changes[i].Address[:], changes[j].Address[:]) < 0 })
+	return changes, nil
+}
+
+//将余额历史索引更新到当前的规范链：先回退已经不在规范链上的区块，再最多向前索引limit个区块，
+//没有余额记录的区块(索引关闭期间或者快速同步导入的区块)之前的余额不再可靠，索引的起点移到其之后，调用者需要持有bc.mu锁
+func (bc *BlockChain) updateBalanceIndex(limit int) error {
+
+	head, err := bc.balanceIndexHead()
+	if err != nil {
+		return err
+	}
+
+	//回退分叉后不在规范链上的区块
+	for GetCanonicalHash(bc.chainDb, head.Number.Uint64()) != head.Hash() {
+		if head, err = bc.unindexBalances(head); err != nil {
+			return err
+		}
+	}
+
+	//向前索引规范链上的区块
+	number, current := head.Number.Uint64(), bc.currentBlock.NumberU64()
+	for ; number < current && limit > 0; limit-- {
+		header := bc.GetHeaderByNumber(number + 1)
+		if header == nil {
+			return errBalanceIndexMissing
+		}
+		if err := bc.indexBalances(header); err != nil {
+			return err
+		}
+		number++
+	}
+	return nil
+}
+
+//将余额历史索引回退到指定的区块号，区块数据被删除前调用，调用者需要持有bc.mu锁
+func (bc *BlockChain) rewindBalanceIndex(number uint64) error {
+
+	if GetBalanceIndexHead(bc.chainDb) == (common.Hash{}) {
+		return nil
+	}
+	head, err := bc.balanceIndexHead()
+	if err != nil {
+		return err
+	}
+	for head.Number.Uint64() > number {
+		if head, err = bc.unindexBalances(head); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//获取余额历史索引最后索引的区块头
+func (bc *BlockChain) balanceIndexHead() (*types.Header, error) {
+
+	head := bc.GetHeaderByHash(GetBalanceIndexHead(bc.chainDb))
+	if head == nil {
+		return nil, errBalanceIndexMissing
+	}
+	return head, nil
+}
+
+//将区块修改的余额加入各个账户的索引
+func (bc *BlockChain) indexBalances(header *types.Header) error {
+
+	number := header.Number.Uint64()
+	changes, ok := GetBalanceChanges(bc.chainDb, header.Hash(), number)
+	if !ok {
+		log.Debug("Balance changes missing, moving balance index tail", "number", number)
+		WriteBalanceIndexTail(bc.chainDb, number+1)
+		return WriteBalanceIndexHead(bc.chainDb, header.Hash())
+	}
+	batch := bc.chainDb.NewBatch()
+	for _, change := range changes {
+		count := GetBalanceIndexCount(bc.chainDb, change.Address)
+		entry := &BalanceIndexEntry{BlockNumber: number, Prev: change.Prev, Balance: change.Balance}
+		if err := WriteBalanceIndexEntry(batch, change.Address, count, entry); err != nil {
+			return err
+		}
+		if err := WriteBalanceIndexCount(batch, change.Address, count+1); err != nil {
+			return err
+		}
+	}
+	WriteBalanceIndexHead(batch, header.Hash())
+	return batch.Write()
+}
+
+//将区块修改的余额从索引中删除，返回父区块头作为新的索引位置
+func (bc *BlockChain) unindexBalances(header *types.Header) (*types.Header, error) {
+
+	number := header.Number.Uint64()
+	parent := bc.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return nil, errBalanceIndexMissing
+	}
+	changes, _ := GetBalanceChanges(bc.chainDb, header.Hash(), number)
+
+	//每个账户在一个区块中只有一条索引，位于该账户索引的末尾
+	var (
+		batch   = bc.chainDb.NewBatch()
+		removed = make(map[common.Address]uint64)
+	)
+	for _, change := range changes {
+		count := GetBalanceIndexCount(bc.chainDb, change.Address)
+		if count == 0 {
+			continue
+		}
+		if entry := GetBalanceIndexEntry(bc.chainDb, change.Address, count-1); entry == nil || entry.BlockNumber != number {
+			continue
+		}
+		if err := WriteBalanceIndexCount(batch, change.Address, count-1); err != nil {
+			return nil, err
+		}
+		removed[change.Address] = count - 1
+	}
+	WriteBalanceIndexHead(batch, parent.Hash())
+	if tail := GetBalanceIndexTail(bc.chainDb); tail > number {
+		WriteBalanceIndexTail(batch, number)
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	for addr, seq := range removed {
+		DeleteBalanceIndexEntry(bc.chainDb, addr, seq)
+	}
+	return parent, nil
+}
+
+//查询账户在指定区块执行之后的余额：余额等于该区块之后第一次变化之前的余额，
+//返回false表示索引到的区块之后没有变化，余额与索引最后一个区块的余额相同
+func GetBalanceAt(db DatabaseReader, addr common.Address, number uint64) (*big.Int, bool) {
+
+	count := GetBalanceIndexCount(db, addr)
+	seq := uint64(sort.Search(int(count), func(i int) bool {
+		entry := GetBalanceIndexEntry(db, addr, uint64(i))
+		return entry == nil || entry.BlockNumber > number
+	}))
+	if seq >= count {
+		return nil, false
+	}
+	entry := GetBalanceIndexEntry(db, addr, seq)
+	if entry == nil {
+		return nil, false
+	}
+	return entry.Prev, true
+}
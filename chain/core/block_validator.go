@@ -58,9 +58,24 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+
+	//区块中所有交易的大小不能超过链配置中的区块大小限制
+	if size, limit := TransactionsSize(block.Transactions()), v.config.MaxBlockSize(header.Number); size > limit {
+		return fmt.Errorf("%v: transactions size %d, limit %d", ErrBlockSizeLimit, size, limit)
+	}
 	return nil
 }
 
+//交易RLP编码后的总字节数，用于检查区块大小限制
+func TransactionsSize(txs types.Transactions) uint64 {
+
+	var size uint64
+	for _, tx := range txs {
+		size += uint64(tx.Size())
+	}
+	return size
+}
+
 //验证者状态后发生的各种更改转换，例如用过的Gas，收据根和状态根本身 如果验证者成功，ValidateState将返回数据库批处理否则为nil并返回错误。
 func (v *BlockValidator) ValidateState(block, parent *types.Block,
 	statedb *state.StateDB,
@@ -105,7 +120,14 @@ func (v *BlockValidator) ValidateDposState(block *types.Block) error {
 	return nil
 }
 
+//计算下一个区块的Gas限制，以全局的目标Gas限制为目标
 func CalcGasLimit(parent *types.Block) *big.Int {
+	return CalcGasLimitTarget(parent, params.TargetGasLimit)
+}
+
+//计算下一个区块的Gas限制，每个区块最多向出块者的目标Gas限制移动父区块Gas限制的1/GasLimitBoundDivisor，
+//各个出块者设置的目标不同时，区块Gas限制在它们之间按出块顺序调整，相当于出块者对网络容量进行投票
+func CalcGasLimitTarget(parent *types.Block, target *big.Int) *big.Int {
 
 	contrib := new(big.Int).Mul(parent.GasUsed(), big.NewInt(3))
 	contrib = contrib.Div(contrib, big.NewInt(2))
@@ -120,9 +142,13 @@ func CalcGasLimit(parent *types.Block) *big.Int {
 
 	gl.Set(math.BigMax(gl, params.MinGasLimit))
 
-	if gl.Cmp(params.TargetGasLimit) < 0 {
+	switch {
+	case gl.Cmp(target) < 0:
 		gl.Add(parent.GasLimit(), decay)
-		gl.Set(math.BigMin(gl, params.TargetGasLimit))
+		gl.Set(math.BigMin(gl, target))
+	case gl.Cmp(target) > 0:
+		gl.Sub(parent.GasLimit(), decay)
+		gl.Set(math.BigMax(gl, math.BigMax(target, params.MinGasLimit)))
 	}
 	return gl
 }
@@ -17,17 +17,27 @@
 package core
 
 import (
+	"math/big"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/consensus/ethash"
+	"github.com/Bokerchain/Boker/chain/consensus/misc"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/core/vm"
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/params"
 )
 
+//GenerateChain沿用父区块的难度（DPOS的难度始终是1），使用ethash校验的测试需要重新计算区块难度
+func ethashDifficulty(i int, gen *BlockGen) {
+	gen.OffsetTime(0)
+}
+
 // Tests that simple header verification works, for both good and bad blocks.
 func TestHeaderVerification(t *testing.T) {
 	// Create a simple chain to verify
@@ -35,7 +45,7 @@ func TestHeaderVerification(t *testing.T) {
 		testdb, _ = ethdb.NewMemDatabase()
 		gspec     = &Genesis{Config: params.TestChainConfig}
 		genesis   = gspec.MustCommit(testdb)
-		blocks, _ = GenerateChain(params.TestChainConfig, genesis, testdb, 8, nil)
+		blocks, _ = GenerateChain(params.TestChainConfig, genesis, testdb, 8, nil, ethashDifficulty)
 	)
 	headers := make([]*types.Header, len(blocks))
 	for i, block := range blocks {
@@ -87,7 +97,7 @@ func testHeaderConcurrentVerification(t *testing.T, threads int) {
 		testdb, _ = ethdb.NewMemDatabase()
 		gspec     = &Genesis{Config: params.TestChainConfig}
 		genesis   = gspec.MustCommit(testdb)
-		blocks, _ = GenerateChain(params.TestChainConfig, genesis, testdb, 8, nil)
+		blocks, _ = GenerateChain(params.TestChainConfig, genesis, testdb, 8, nil, ethashDifficulty)
 	)
 	headers := make([]*types.Header, len(blocks))
 	seals := make([]bool, len(blocks))
@@ -159,7 +169,7 @@ func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 		testdb, _ = ethdb.NewMemDatabase()
 		gspec     = &Genesis{Config: params.TestChainConfig}
 		genesis   = gspec.MustCommit(testdb)
-		blocks, _ = GenerateChain(params.TestChainConfig, genesis, testdb, 1024, nil)
+		blocks, _ = GenerateChain(params.TestChainConfig, genesis, testdb, 1024, nil, ethashDifficulty)
 	)
 	headers := make([]*types.Header, len(blocks))
 	seals := make([]bool, len(blocks))
@@ -197,3 +207,72 @@ func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 		t.Errorf("verification count too large: have %d, want below %d", verified, 2*threads)
 	}
 }
+
+// Tests that the gas limit converges to the producer's target from both sides
+// while every step stays within the bounds enforced by the header validation.
+func TestGasLimitTarget(t *testing.T) {
+	tests := []struct {
+		parent, target uint64
+	}{
+		{0xffffffff, params.GenesisGasLimit.Uint64()},
+		{params.GenesisGasLimit.Uint64(), 8000000},
+		{8000000, params.MinGasLimit.Uint64()},
+		{8000000, 8000000},
+	}
+	for i, tt := range tests {
+		parent := &types.Header{GasLimit: new(big.Int).SetUint64(tt.parent), GasUsed: new(big.Int), Number: big.NewInt(1)}
+		target := new(big.Int).SetUint64(tt.target)
+
+		for step := 0; parent.GasLimit.Cmp(target) != 0; step++ {
+			if step > 10000 {
+				t.Fatalf("test %d: gas limit did not converge: have %v, want %v", i, parent.GasLimit, target)
+			}
+			header := &types.Header{GasLimit: CalcGasLimitTarget(types.NewBlockWithHeader(parent), target), GasUsed: new(big.Int), Number: big.NewInt(1)}
+			if err := misc.VerifyGasLimit(parent, header); err != nil {
+				t.Fatalf("test %d, step %d: invalid gas limit: %v", i, step, err)
+			}
+			parent = header
+		}
+	}
+}
+
+// Tests that blocks whose transactions exceed the configured size limit are
+// rejected once the limit activates, and accepted before it.
+func TestBlockSizeLimit(t *testing.T) {
+	config := *params.TestChainConfig
+	config.SizeLimits = &params.SizeConfig{Number: big.NewInt(2), Block: 1024}
+
+	var (
+		testdb, _ = ethdb.NewMemDatabase()
+		gspec     = &Genesis{Config: &config}
+		genesis   = gspec.MustCommit(testdb)
+		blocks, _ = GenerateChain(&config, genesis, testdb, 1, nil, ethashDifficulty)
+	)
+	chain, _ := NewBlockChain(testdb, &config, ethash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	txs := types.Transactions{types.NewTransaction(protocol.Binary, 0, common.Address{}, new(big.Int), new(big.Int), new(big.Int), make([]byte, 2048))}
+
+	tests := []struct {
+		parent *types.Block
+		err    error
+	}{
+		{genesis, nil},
+		{blocks[0], ErrBlockSizeLimit},
+	}
+	for i, tt := range tests {
+		header := &types.Header{ParentHash: tt.parent.Hash(), Number: new(big.Int).Add(tt.parent.Number(), common.Big1), Extra: []byte("oversized")}
+		block := types.NewBlock(header, txs, nil, nil)
+
+		err := chain.Validator().ValidateBody(block)
+		if tt.err == nil && err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if tt.err != nil && (err == nil || !strings.HasPrefix(err.Error(), tt.err.Error())) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
@@ -31,6 +31,7 @@ import (
 
 var (
 	blockInsertTimer = metrics.NewTimer("chain/inserts")
+	headBlockGauge   = metrics.NewGauge("chain/head/block")
 	ErrNoGenesis     = errors.New("Genesis not found in chain")
 )
 
@@ -41,6 +42,10 @@ const (
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
 	BlockChainVersion   = 3 //BlockChainVersion 确保不兼容的数据库从头开始强制重新同步
+
+	trieCleanCacheLimit = 128 * 1024 * 1024 //默认的trie节点clean缓存大小
+	trieDirtyCacheLimit = 64 * 1024 * 1024  //默认的trie节点dirty缓存大小
+	trieFlushInterval   = time.Minute       //dirty缓存中的节点最长多久写入磁盘一次
 )
 
 type BlockChain struct {
@@ -52,6 +57,7 @@ type BlockChain struct {
 	chainSideFeed    event.Feed
 	chainHeadFeed    event.Feed
 	logsFeed         event.Feed
+	reorgFeed        event.Feed
 	scope            event.SubscriptionScope
 	genesisBlock     *types.Block
 	mu               sync.RWMutex     // global mutex for locking chain operations
@@ -61,6 +67,8 @@ type BlockChain struct {
 	currentBlock     *types.Block     // Current head of the block chain
 	currentFastBlock *types.Block     // Current head of the fast-sync chain (may be above the block chain!)
 	stateCache       state.Database   // State database to reuse between imports (contains state cache)
+	nodeCache        *trie.NodeCache  //状态数据库共享的trie节点缓存
+	lastFlush        time.Time        //trie节点缓存最后一次写入磁盘的时间
 	bodyCache        *lru.Cache       // Cache for the most recent block bodies
 	bodyRLPCache     *lru.Cache       // Cache for the most recent block bodies in RLP encoded format
 	blockCache       *lru.Cache       // Cache for the most recent entire blocks
@@ -73,8 +81,12 @@ type BlockChain struct {
 	processor        Processor        //区块处理器接口
 	validator        Validator        //区块验证接口
 	vmConfig         vm.Config        //虚拟机配置
-	badBlocks        *lru.Cache       // Bad block cache
 	boker            bokerapi.Api     //播客链的接口类
+	addrIndex        int32            //是否开启地址交易索引(atomic)
+	balanceIndex     int32            //是否开启余额历史索引(atomic)
+	tokenIndex       int32            //是否开启ERC-20转账索引(atomic)
+	internalTxIndex  int32            //是否开启内部交易索引(atomic)
+	freezeThreshold  uint64           //冻结区块的深度，为0时不冻结(atomic)
 }
 
 //返回初始化后的块链， 它初始化默认的以太坊验证器和处理器
@@ -85,16 +97,23 @@ func NewBlockChain(chainDb ethdb.Database,
 
 	log.Info("****NewBlockChain****")
 
+	if err := vm.ValidatePrecompiles(config); err != nil {
+		return nil, err
+	}
+
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
-	badBlocks, _ := lru.New(badBlockLimit)
+
+	nodeCache := trie.NewNodeCache(chainDb, trieCleanCacheLimit, trieDirtyCacheLimit)
 
 	bc := &BlockChain{
 		config:       config,
 		chainDb:      chainDb,
-		stateCache:   state.NewDatabase(chainDb),
+		stateCache:   state.NewDatabaseWithCache(nodeCache),
+		nodeCache:    nodeCache,
+		lastFlush:    time.Now(),
 		quit:         make(chan struct{}),
 		bodyCache:    bodyCache,
 		bodyRLPCache: bodyRLPCache,
@@ -102,7 +121,6 @@ func NewBlockChain(chainDb ethdb.Database,
 		futureBlocks: futureBlocks,
 		engine:       engine,
 		vmConfig:     vmConfig,
-		badBlocks:    badBlocks,
 	}
 	log.Info("New Block Chain")
 
@@ -147,6 +165,9 @@ func NewBlockChain(chainDb ethdb.Database,
 
 	// Take ownership of this particular state
 	go bc.update()
+
+	bc.wg.Add(1)
+	go bc.freezeLoop()
 	return bc, nil
 }
 
@@ -174,11 +195,14 @@ func (bc *BlockChain) loadLastState() error {
 		return bc.Reset()
 	}
 
-	// Make sure the state associated with the block is available
+	//trie节点缓存中尚未写入磁盘的状态在异常退出时会丢失，回退到状态完整的区块
 	if _, err := state.New(currentBlock.Root(), bc.stateCache); err != nil {
-		// Dangling block without a state associated, init from scratch
-		log.Warn("Head state missing, resetting chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
-		return bc.Reset()
+		log.Warn("Head state missing, repairing chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
+		if err := bc.repair(&currentBlock); err != nil {
+			// Dangling block without a state associated, init from scratch
+			log.Warn("Head state missing, resetting chain", "err", err)
+			return bc.Reset()
+		}
 	}
 
 	// Everything seems to be fine, set as the head block
@@ -219,6 +243,21 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
+	//回退后的区块状态需要从磁盘读取，先将缓存中的节点写入磁盘
+	if err := bc.nodeCache.Commit(); err != nil {
+		return err
+	}
+
+	// Rewind the address transaction index while the block bodies are still there
+	if err := bc.rewindAddressIndex(head); err != nil {
+		log.Error("Failed to rewind address transaction index", "err", err)
+	}
+	if err := bc.rewindBalanceIndex(head); err != nil {
+		log.Error("Failed to rewind balance history index", "err", err)
+	}
+	if err := bc.rewindTokenIndex(head); err != nil {
+		log.Error("Failed to rewind token transfer index", "err", err)
+	}
 	// Rewind the header chain, deleting all block bodies until then
 	delFn := func(hash common.Hash, num uint64) {
 		DeleteBody(bc.chainDb, hash, num)
@@ -226,6 +265,10 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.hc.SetHead(head, delFn)
 	currentHeader := bc.hc.CurrentHeader()
 
+	if err := bc.truncateAncients(currentHeader.Number.Uint64()); err != nil {
+		log.Error("Failed to truncate ancient blocks", "err", err)
+	}
+
 	// Clear out any stale content from the caches
 	bc.bodyCache.Purge()
 	bc.bodyRLPCache.Purge()
@@ -279,6 +322,13 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	if _, err := trie.NewSecure(block.Root(), bc.chainDb, 0); err != nil {
 		return err
 	}
+	// The DPoS context tries are needed to process the following blocks
+	if block.Header().DposProto == nil {
+		return fmt.Errorf("missing dpos context in block [%x…]", hash[:4])
+	}
+	if _, err := types.NewDposContextFromProto(bc.chainDb, block.Header().DposProto); err != nil {
+		return err
+	}
 	// If all checks out, manually set the head block
 	bc.mu.Lock()
 	bc.currentBlock = block
@@ -372,6 +422,15 @@ func (bc *BlockChain) SetBoker(boker bokerapi.Api) {
 	bc.processor.SetBoker(boker)
 }
 
+//设置并行执行区块中交易的线程数量，只对默认的状态处理器生效
+func (bc *BlockChain) SetParallelWorkers(workers int) {
+	bc.procmu.Lock()
+	defer bc.procmu.Unlock()
+	if processor, ok := bc.processor.(*StateProcessor); ok {
+		processor.SetParallel(workers)
+	}
+}
+
 //得到播客链接口
 func (bc *BlockChain) Boker() bokerapi.Api {
 	bc.procmu.RLock()
@@ -389,6 +448,48 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache)
 }
 
+//沿父区块回退，直到找到状态完整的区块
+func (bc *BlockChain) repair(head **types.Block) error {
+	for {
+		if _, err := state.New((*head).Root(), bc.stateCache); err == nil {
+			log.Info("Rewound blockchain to past state", "number", (*head).Number(), "hash", (*head).Hash())
+			return nil
+		}
+		block := bc.GetBlock((*head).ParentHash(), (*head).NumberU64()-1)
+		if block == nil {
+			return fmt.Errorf("missing block %d [%x]", (*head).NumberU64()-1, (*head).ParentHash())
+		}
+		*head = block
+	}
+}
+
+//从共享的trie节点缓存或者磁盘读取状态树节点
+func (bc *BlockChain) TrieNode(hash common.Hash) ([]byte, error) {
+	return bc.nodeCache.Get(hash.Bytes())
+}
+
+//返回状态数据库共享的trie节点缓存
+func (bc *BlockChain) TrieDB() trie.Database {
+	return bc.nodeCache
+}
+
+//将trie节点缓存中尚未写入磁盘的节点写入磁盘
+func (bc *BlockChain) CommitTrieCache() error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if err := bc.nodeCache.Commit(); err != nil {
+		return err
+	}
+	bc.lastFlush = time.Now()
+	return nil
+}
+
+//设置trie节点clean缓存和dirty缓存的大小(字节)
+func (bc *BlockChain) SetTrieCache(clean, dirty int) {
+	bc.nodeCache.SetLimits(clean, dirty)
+}
+
 // Reset purges the entire blockchain, restoring it to its genesis state.
 func (bc *BlockChain) Reset() error {
 	return bc.ResetWithGenesisBlock(bc.genesisBlock)
@@ -468,6 +569,7 @@ func (bc *BlockChain) insert(block *types.Block) {
 		log.Crit("Failed to insert head block hash", "err", err)
 	}
 	bc.currentBlock = block
+	headBlockGauge.Update(int64(block.NumberU64()))
 
 	// If the block is better than out head or is on a different chain, force update heads
 	if updateHeads {
@@ -610,6 +712,10 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+
+	if err := bc.nodeCache.Commit(); err != nil {
+		log.Error("Failed to commit trie node cache", "err", err)
+	}
 	log.Info("Blockchain manager stopped")
 }
 
@@ -786,8 +892,8 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 	return 0, nil
 }
 
-//将区块和状态信息写入数据库
-func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.Receipt, state *state.StateDB) (status WriteStatus, err error) {
+//将区块和状态信息写入数据库，internalTxs为执行区块时使用的内部交易追踪器，没有追踪时为nil
+func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.Receipt, state *state.StateDB, internalTxs *InternalTxTracer) (status WriteStatus, err error) {
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 
@@ -819,7 +925,17 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	if _, err := block.DposContext.CommitTo(batch); err != nil {
 		return NonStatTy, err
 	}
-	if _, err := state.CommitTo(batch, bc.config.IsEIP158(block.Number())); err != nil {
+	//被修改的账户在状态提交后被清空，需要提前获取
+	var balanceChanges []*BalanceChange
+	if bc.BalanceIndexEnabled() {
+		changes, err := bc.balanceChanges(block, state)
+		if err != nil {
+			return NonStatTy, err
+		}
+		balanceChanges = changes
+	}
+	//状态树节点写入共享的节点缓存，由缓存批量写入磁盘
+	if _, err := state.CommitTo(bc.nodeCache, bc.config.IsEIP158(block.Number())); err != nil {
 		return NonStatTy, err
 	}
 
@@ -827,6 +943,24 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	if err := WriteBlockReceipts(batch, block.Hash(), block.NumberU64(), receipts); err != nil {
 		return NonStatTy, err
 	}
+	//记录分配通证交易及其转账，供审计查询
+	if assignments := tokenAssignments(types.MakeSigner(bc.config, block.Number()), block, receipts); len(assignments) > 0 {
+		if err := WriteTokenAssignments(batch, block.Hash(), block.NumberU64(), assignments); err != nil {
+			return NonStatTy, err
+		}
+	}
+	//记录区块修改的账户余额
+	if balanceChanges != nil {
+		if err := WriteBalanceChanges(batch, block.Hash(), block.NumberU64(), balanceChanges); err != nil {
+			return NonStatTy, err
+		}
+	}
+	//记录执行区块时合约发起的转账
+	if internalTxs != nil {
+		if err := WriteInternalTransfers(batch, block.Hash(), block.NumberU64(), internalTxs.Transfers(receipts)); err != nil {
+			return NonStatTy, err
+		}
+	}
 
 	// If the total difficulty is higher than our known, add it to the canonical chain
 	// Second clause in the if statement reduces the vulnerability to selfish mining.
@@ -867,8 +1001,32 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+
+		//更新地址交易索引，落后较多时由后台补建
+		if bc.AddressIndexEnabled() {
+			if _, _, err := bc.updateAddressIndex(addrIndexLiveLimit); err != nil {
+				log.Error("Failed to update address transaction index", "err", err)
+			}
+		}
+		if bc.BalanceIndexEnabled() {
+			if err := bc.updateBalanceIndex(balanceIndexLiveLimit); err != nil {
+				log.Error("Failed to update balance history index", "err", err)
+			}
+		}
+		if bc.TokenIndexEnabled() {
+			if _, _, err := bc.updateTokenIndex(tokenIndexLiveLimit); err != nil {
+				log.Error("Failed to update token transfer index", "err", err)
+			}
+		}
 	}
 	bc.futureBlocks.Remove(block.Hash())
+
+	if time.Since(bc.lastFlush) > trieFlushInterval {
+		if err := bc.nodeCache.Commit(); err != nil {
+			return NonStatTy, err
+		}
+		bc.lastFlush = time.Now()
+	}
 	return status, nil
 }
 
@@ -879,13 +1037,18 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 //
 // After insertion is done, all accumulated events will be fired.
 func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
-	n, events, logs, err := bc.insertChain(chain)
+	return bc.InsertChainFromPeer(chain, "")
+}
+
+//与InsertChain相同，区块验证失败时在坏区块记录中保存发送这些区块的节点
+func (bc *BlockChain) InsertChainFromPeer(chain types.Blocks, peer string) (int, error) {
+	n, events, logs, err := bc.insertChain(chain, peer)
 	bc.PostChainEvents(events, logs)
 	return n, err
 }
 
 //将区块插入到链中
-func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*types.Log, error) {
+func (bc *BlockChain) insertChain(chain types.Blocks, peer string) (int, []interface{}, []*types.Log, error) {
 
 	log.Info("(bc *BlockChain) insertChain")
 
@@ -955,7 +1118,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		}
 		// If the header is a banned one, straight out abort
 		if BadHashes[block.Hash()] {
-			bc.reportBlock(block, nil, ErrBlacklistedHash)
+			bc.reportBlock(block, nil, peer, ErrBlacklistedHash)
 			return i, events, coalescedLogs, ErrBlacklistedHash
 		}
 		// Wait for the block's verification to complete
@@ -990,7 +1153,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 				continue
 			}
 
-			bc.reportBlock(block, nil, err)
+			bc.reportBlock(block, nil, peer, err)
 			return i, events, coalescedLogs, err
 		}
 		// Create a new statedb using the parent block and report an
@@ -1012,11 +1175,12 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 
 		// Process block using the parent state as reference point.
 		log.Info("Process Block", "Number", block.Number())
-		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
+		internalTxs := bc.NewInternalTxTracer(state)
+		receipts, logs, usedGas, err := bc.processor.Process(block, state, internalTxs.VMConfig(bc.vmConfig))
 		if err != nil {
 
 			log.Error("Process Block", "Number", block.Number(), "err", err)
-			bc.reportBlock(block, receipts, err)
+			bc.reportBlock(block, receipts, peer, err)
 			return i, events, coalescedLogs, err
 		}
 
@@ -1026,7 +1190,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		if err != nil {
 
 			log.Error("Validator", "Number", block.Number(), "err", err)
-			bc.reportBlock(block, receipts, err)
+			bc.reportBlock(block, receipts, peer, err)
 			return i, events, coalescedLogs, err
 		}
 
@@ -1036,7 +1200,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		if err != nil {
 
 			log.Error("ValidateDposState", "Number", block.Number(), "err", err)
-			bc.reportBlock(block, receipts, err)
+			bc.reportBlock(block, receipts, peer, err)
 			return i, events, coalescedLogs, err
 		}
 
@@ -1049,14 +1213,14 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			if err != nil {
 
 				log.Error("VerifySeal", "Number", block.Number(), "err", err)
-				bc.reportBlock(block, receipts, err)
+				bc.reportBlock(block, receipts, peer, err)
 				return i, events, coalescedLogs, err
 			}
 		}
 
 		// Validate the dpos state using the default validator
 		// Write the block to the chain and get the status.
-		status, err := bc.WriteBlockAndState(block, receipts, state)
+		status, err := bc.WriteBlockAndState(block, receipts, state, internalTxs)
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
@@ -1212,6 +1376,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		}
 		logFn("Chain split detected", "number", commonBlock.Number(), "hash", commonBlock.Hash(),
 			"drop", len(oldChain), "dropfrom", oldChain[0].Hash(), "add", len(newChain), "addfrom", newChain[0].Hash())
+		bc.journalReorg(commonBlock, oldChain, newChain)
 	} else {
 		log.Error("Impossible reorg, please file an issue", "oldnum", oldBlock.Number(), "oldhash", oldBlock.Hash(), "newnum", newBlock.Number(), "newhash", newBlock.Hash())
 	}
@@ -1272,32 +1437,10 @@ func (bc *BlockChain) update() {
 	}
 }
 
-// BadBlockArgs represents the entries in the list returned when bad blocks are queried.
-type BadBlockArgs struct {
-	Hash   common.Hash   `json:"hash"`
-	Header *types.Header `json:"header"`
-}
-
-// BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
-func (bc *BlockChain) BadBlocks() ([]BadBlockArgs, error) {
-	headers := make([]BadBlockArgs, 0, bc.badBlocks.Len())
-	for _, hash := range bc.badBlocks.Keys() {
-		if hdr, exist := bc.badBlocks.Peek(hash); exist {
-			header := hdr.(*types.Header)
-			headers = append(headers, BadBlockArgs{header.Hash(), header})
-		}
-	}
-	return headers, nil
-}
-
-// addBadBlock adds a bad block to the bad-block LRU cache
-func (bc *BlockChain) addBadBlock(block *types.Block) {
-	bc.badBlocks.Add(block.Header().Hash(), block.Header())
-}
-
-// reportBlock logs a bad block error.
-func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
-	bc.addBadBlock(block)
+// reportBlock logs a bad block error and records the block along with the peer
+// it was received from.
+func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, peer string, err error) {
+	bc.addBadBlock(block, peer, err)
 
 	var receiptString string
 	for _, receipt := range receipts {
@@ -1309,11 +1452,12 @@ Chain config: %v
 
 Number: %v
 Hash: 0x%x
+Peer: %v
 %v
 
 Error: %v
 ##############################
-`, bc.config, block.Number(), block.Hash(), receiptString, err))
+`, bc.config, block.Number(), block.Hash(), peer, receiptString, err))
 }
 
 // InsertHeaderChain attempts to insert the given header chain in to the local
@@ -38,6 +38,9 @@ type BlockGen struct {
 	receipts []*types.Receipt     //回执数组
 	uncles   []*types.Header      //叔块数组
 
+	dposContext *types.DposContext //区块的Dpos上下文
+	bc          *BlockChain        //可选的规范链（用于执行需要读取链数据的播客链交易）
+
 	config *params.ChainConfig
 }
 
@@ -53,6 +56,11 @@ func (b *BlockGen) SetCoinbase(addr common.Address) {
 	b.gasPool = new(GasPool).AddGas(b.header.GasLimit)
 }
 
+//设置执行播客链交易时使用的规范链（分配通证和设置验证者交易需要读取创世区块）
+func (b *BlockGen) SetBlockChain(bc *BlockChain) {
+	b.bc = bc
+}
+
 //设置生成的块的额外数据字段
 func (b *BlockGen) SetExtra(data []byte) {
 	b.header.Extra = data
@@ -73,8 +81,8 @@ func (b *BlockGen) AddTx(tx *types.Transaction, boker bokerapi.Api) {
 
 	//应用交易，并返回回执
 	receipt, _, err := ApplyTransaction(b.config,
-		nil,
-		nil,
+		b.dposContext,
+		b.bc,
 		&b.header.Coinbase,
 		b.gasPool,
 		b.statedb,
@@ -157,13 +165,19 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, db ethdb.Dat
 	//
 	genblock := func(i int, h *types.Header, statedb *state.StateDB) (*types.Block, types.Receipts) {
 
+		//从父区块中恢复Dpos上下文
+		dposContext, err := types.NewDposContextFromProto(db, parent.Header().DposProto)
+		if err != nil {
+			panic(fmt.Sprintf("dpos context error: %v", err))
+		}
 		b := &BlockGen{
-			parent:  parent,
-			i:       i,
-			chain:   blocks,
-			header:  h,
-			statedb: statedb,
-			config:  config,
+			parent:      parent,
+			i:           i,
+			chain:       blocks,
+			header:      h,
+			statedb:     statedb,
+			dposContext: dposContext,
+			config:      config,
 		}
 
 		// Mutate the state and block according to any hard-fork specs
@@ -193,9 +207,14 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, db ethdb.Dat
 			panic(fmt.Sprintf("state write error: %v", err))
 		}
 		h.Root = root
-		h.DposProto = parent.Header().DposProto
+		if h.DposProto, err = dposContext.CommitTo(db); err != nil {
+			panic(fmt.Sprintf("dpos context write error: %v", err))
+		}
 		h.BokerProto = parent.Header().BokerProto
-		return types.NewBlock(h, b.txs, b.uncles, b.receipts), b.receipts
+
+		block := types.NewBlock(h, b.txs, b.uncles, b.receipts)
+		block.DposContext = dposContext
+		return block, b.receipts
 	}
 
 	//
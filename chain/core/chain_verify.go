@@ -0,0 +1,157 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/consensus"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+)
+
+//区块检查失败的类别
+const (
+	VerifyCheckCanonical   = "canonical"    //规范链中缺少区块哈希
+	VerifyCheckHeader      = "header"       //区块头缺失、哈希不一致或者未通过共识引擎的检查
+	VerifyCheckParent      = "parent"       //父区块哈希与规范链不一致
+	VerifyCheckSeal        = "seal"         //区块签名者不是当时的出块者
+	VerifyCheckBody        = "body"         //区块体缺失
+	VerifyCheckTxRoot      = "tx-root"      //交易根哈希不一致
+	VerifyCheckUncles      = "uncles"       //叔块哈希不一致
+	VerifyCheckReceiptRoot = "receipt-root" //收据缺失或者收据根哈希不一致
+	VerifyCheckDposTrie    = "dpos-trie"    //Dpos树的根节点缺失或者损坏
+)
+
+//检查区块签名者而不产生副作用的共识引擎(Dpos)
+type producerVerifier interface {
+	VerifyProducer(chain consensus.ChainReader, header *types.Header) error
+}
+
+//第一个损坏的区块
+type CorruptBlock struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Check  string      `json:"check"`
+	Error  string      `json:"error"`
+}
+
+//区块范围检查的结果
+type ChainVerifyReport struct {
+	From    uint64        `json:"from"`
+	To      uint64        `json:"to"`
+	Checked uint64        `json:"checked"`
+	Status  string        `json:"status"` //ok或者corrupt
+	Corrupt *CorruptBlock `json:"corrupt,omitempty"`
+}
+
+//重新检查规范链中[from, to]范围内每个区块的区块头、Dpos签名、交易根、收据根以及Dpos树的根节点，
+//遇到第一个损坏的区块时停止。progress在检查过程中定期调用
+func VerifyChain(bc *BlockChain, from, to uint64, progress func(number uint64)) *ChainVerifyReport {
+
+	report := &ChainVerifyReport{From: from, To: to, Status: "ok"}
+
+	var parent common.Hash
+	if from > 0 {
+		parent = GetCanonicalHash(bc.chainDb, from-1)
+	}
+	for number := from; number <= to; number++ {
+		hash, check, err := verifyChainBlock(bc, number, parent)
+		if err != nil {
+			report.Status = "corrupt"
+			report.Corrupt = &CorruptBlock{Number: number, Hash: hash, Check: check, Error: err.Error()}
+			return report
+		}
+		parent = hash
+		report.Checked++
+
+		if number%10000 == 0 && progress != nil {
+			progress(number)
+		}
+	}
+	return report
+}
+
+//检查一个规范区块，返回区块哈希，失败时同时返回检查的类别
+func verifyChainBlock(bc *BlockChain, number uint64, parent common.Hash) (common.Hash, string, error) {
+
+	db := bc.chainDb
+	hash := GetCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		return hash, VerifyCheckCanonical, fmt.Errorf("no canonical block")
+	}
+	header := GetHeader(db, hash, number)
+	if header == nil {
+		return hash, VerifyCheckHeader, fmt.Errorf("header missing")
+	}
+	if header.Hash() != hash {
+		return hash, VerifyCheckHeader, fmt.Errorf("header hash %x", header.Hash())
+	}
+	if number > 0 {
+		if header.ParentHash != parent {
+			return hash, VerifyCheckParent, fmt.Errorf("parent hash %x, canonical %x", header.ParentHash, parent)
+		}
+		if err := bc.engine.VerifyHeader(bc, header, false); err != nil {
+			return hash, VerifyCheckHeader, err
+		}
+		var err error
+		if verifier, ok := bc.engine.(producerVerifier); ok {
+			err = verifier.VerifyProducer(bc, header)
+		} else {
+			err = bc.engine.VerifySeal(bc, header)
+		}
+		if err != nil {
+			return hash, VerifyCheckSeal, err
+		}
+	}
+	body := GetBody(db, hash, number)
+	if body == nil {
+		return hash, VerifyCheckBody, fmt.Errorf("body missing")
+	}
+	if root := types.DeriveSha(types.Transactions(body.Transactions)); root != header.TxHash {
+		return hash, VerifyCheckTxRoot, fmt.Errorf("transaction root %x, want %x", root, header.TxHash)
+	}
+	if uncles := types.CalcUncleHash(body.Uncles); uncles != header.UncleHash {
+		return hash, VerifyCheckUncles, fmt.Errorf("uncle hash %x, want %x", uncles, header.UncleHash)
+	}
+	//没有交易的区块可能没有保存收据
+	receipts := GetBlockReceipts(db, hash, number)
+	if receipts == nil && header.ReceiptHash != types.EmptyRootHash {
+		return hash, VerifyCheckReceiptRoot, fmt.Errorf("receipts missing")
+	}
+	if root := types.DeriveSha(receipts); root != header.ReceiptHash {
+		return hash, VerifyCheckReceiptRoot, fmt.Errorf("receipt root %x, want %x", root, header.ReceiptHash)
+	}
+	if proto := header.DposProto; proto != nil {
+		tries := []struct {
+			name string
+			root common.Hash
+		}{
+			{"epoch", proto.EpochHash},
+			{"validator", proto.ValidatorHash},
+			{"block count", proto.BlockCntHash},
+		}
+		for _, trie := range tries {
+			if err := verifyTrieRoot(db, trie.root); err != nil {
+				return hash, VerifyCheckDposTrie, fmt.Errorf("%s trie: %v", trie.name, err)
+			}
+		}
+	}
+	return hash, "", nil
+}
+
+//检查树的根节点存在并且内容与哈希一致
+func verifyTrieRoot(db ethdb.Database, root common.Hash) error {
+
+	if root == (common.Hash{}) || root == types.EmptyRootHash {
+		return nil
+	}
+	blob, err := db.Get(root.Bytes())
+	if err != nil || len(blob) == 0 {
+		return fmt.Errorf("root node %x missing", root)
+	}
+	if have := crypto.Keccak256Hash(blob); have != root {
+		return fmt.Errorf("root node %x corrupted, content hash %x", root, have)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/consensus/ethash"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/core/vm"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/params"
+)
+
+//写入n个区块的规范链，第5个区块的Dpos纪元树根指向一个单独写入的节点
+func newVerifyTestChain(t *testing.T, n int) (*BlockChain, ethdb.Database, []*types.Block, common.Hash) {
+	db, _ := ethdb.NewMemDatabase()
+	genesis := (&Genesis{Config: params.TestChainConfig}).MustCommit(db)
+
+	node := []byte{0xc2, 0x80, 0x80}
+	root := crypto.Keccak256Hash(node)
+	db.Put(root.Bytes(), node)
+
+	blocks := []*types.Block{genesis}
+	for i := 1; i <= n; i++ {
+		parent := blocks[len(blocks)-1]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			Root:       genesis.Root(),
+			Difficulty: big.NewInt(1),
+			Time:       big.NewInt(int64(i)),
+			DposProto:  genesis.Header().DposProto,
+		}
+		if i == 5 {
+			header.DposProto = &types.DposContextProto{EpochHash: root}
+		}
+		tx := types.NewTransaction(protocol.Binary, uint64(i), common.Address{}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil)
+		receipt := types.NewReceipt(nil, false, big.NewInt(21000))
+		receipt.TxHash = tx.Hash()
+		block := types.NewBlock(header, []*types.Transaction{tx}, nil, []*types.Receipt{receipt})
+
+		WriteBlock(db, block)
+		WriteTd(db, block.Hash(), block.NumberU64(), big.NewInt(int64(i+1)))
+		WriteBlockReceipts(db, block.Hash(), block.NumberU64(), types.Receipts{receipt})
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		blocks = append(blocks, block)
+	}
+	head := blocks[len(blocks)-1]
+	WriteHeadBlockHash(db, head.Hash())
+	WriteHeadHeaderHash(db, head.Hash())
+
+	chain, err := NewBlockChain(db, params.TestChainConfig, ethash.NewFullFaker(), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain, db, blocks, root
+}
+
+//测试完整的链通过检查，损坏的数据在对应的区块被发现
+func TestVerifyChain(t *testing.T) {
+	chain, db, blocks, root := newVerifyTestChain(t, 10)
+	defer chain.Stop()
+
+	report := VerifyChain(chain, 0, 10, nil)
+	if report.Status != "ok" || report.Checked != 11 || report.Corrupt != nil {
+		t.Fatalf("intact chain: have %+v", report)
+	}
+	//损坏Dpos树的根节点
+	db.Put(root.Bytes(), []byte{0xc0})
+	report = VerifyChain(chain, 2, 10, nil)
+	if report.Corrupt == nil || report.Corrupt.Number != 5 || report.Corrupt.Check != VerifyCheckDposTrie {
+		t.Fatalf("corrupt dpos trie: have %+v", report.Corrupt)
+	}
+	if report.Checked != 3 || report.Corrupt.Hash != blocks[5].Hash() {
+		t.Errorf("corrupt dpos trie: checked %d, hash %x", report.Checked, report.Corrupt.Hash)
+	}
+	//从第6个区块开始检查，删除第8个区块的收据
+	DeleteBlockReceipts(db, blocks[8].Hash(), 8)
+	report = VerifyChain(chain, 6, 10, nil)
+	if report.Corrupt == nil || report.Corrupt.Number != 8 || report.Corrupt.Check != VerifyCheckReceiptRoot {
+		t.Fatalf("missing receipts: have %+v", report.Corrupt)
+	}
+	//用其他区块的交易替换第7个区块的区块体
+	WriteBody(db, blocks[7].Hash(), 7, blocks[6].Body())
+	report = VerifyChain(chain, 6, 10, nil)
+	if report.Corrupt == nil || report.Corrupt.Number != 7 || report.Corrupt.Check != VerifyCheckTxRoot {
+		t.Fatalf("corrupt body: have %+v", report.Corrupt)
+	}
+	//规范链中的哈希指向其他区块
+	WriteCanonicalHash(db, blocks[6].Hash(), 3)
+	report = VerifyChain(chain, 3, 10, nil)
+	if report.Corrupt == nil || report.Corrupt.Number != 3 || report.Corrupt.Check != VerifyCheckHeader {
+		t.Fatalf("wrong canonical hash: have %+v", report.Corrupt)
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/Bokerchain/Boker/chain/metrics"
+	"github.com/golang/snappy"
+)
+
+//压缩后的区块体和收据条目以版本标志字节开头，没有压缩的旧条目是以0xc0以上字节开头的RLP列表，读取时可以区分
+const (
+	entrySnappyV1 byte = 0x01 //snappy压缩的RLP编码数据
+	entryRLPList  byte = 0xc0 //RLP列表的最小起始字节
+)
+
+var (
+	compressedBytesMeter   = metrics.NewMeter("chain/db/compress/input")  //压缩前写入的字节数
+	compressedStoredMeter  = metrics.NewMeter("chain/db/compress/output") //压缩后实际写入的字节数
+	decompressFailureMeter = metrics.NewMeter("chain/db/compress/failures")
+)
+
+//压缩RLP编码的区块体或收据，压缩后没有变小时保存原始数据
+func compressEntry(data []byte) []byte {
+
+	compressedBytesMeter.Mark(int64(len(data)))
+
+	enc := make([]byte, 1+snappy.MaxEncodedLen(len(data)))
+	enc[0] = entrySnappyV1
+	enc = enc[:1+len(snappy.Encode(enc[1:], data))]
+	if len(enc) >= len(data) {
+		compressedStoredMeter.Mark(int64(len(data)))
+		return data
+	}
+	compressedStoredMeter.Mark(int64(len(enc)))
+	return enc
+}
+
+//还原数据库中的区块体或收据条目，兼容没有压缩的旧条目
+func decompressEntry(data []byte) ([]byte, error) {
+
+	if len(data) == 0 || data[0] >= entryRLPList {
+		return data, nil
+	}
+	switch data[0] {
+	case entrySnappyV1:
+		dec, err := snappy.Decode(nil, data[1:])
+		if err != nil {
+			decompressFailureMeter.Mark(1)
+			return nil, err
+		}
+		return dec, nil
+	default:
+		decompressFailureMeter.Mark(1)
+		return nil, fmt.Errorf("unknown entry encoding %#x", data[0])
+	}
+}
@@ -0,0 +1,233 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/rlp"
+)
+
+var (
+	errNotIterable = errors.New("database does not support iteration")
+	errNoAncients  = errors.New("database has no ancient store")
+)
+
+//数据库中一类数据的数量和占用的空间
+type DatabaseStat struct {
+	Category string
+	Count    uint64
+	Size     common.StorageSize
+}
+
+//按键的格式划分数据类别，依次匹配，都不匹配的归入其他
+var databaseCategories = []struct {
+	name  string
+	match func(key []byte) bool
+}{
+	{"Headers", func(key []byte) bool { return bytes.HasPrefix(key, headerPrefix) && len(key) == 1+8+common.HashLength }},
+	{"Total difficulties", func(key []byte) bool {
+		return bytes.HasPrefix(key, headerPrefix) && len(key) == 1+8+common.HashLength+len(tdSuffix) && bytes.HasSuffix(key, tdSuffix)
+	}},
+	{"Canonical hashes", func(key []byte) bool {
+		return bytes.HasPrefix(key, headerPrefix) && len(key) == 1+8+len(numSuffix) && bytes.HasSuffix(key, numSuffix)
+	}},
+	{"Hash to number", func(key []byte) bool { return bytes.HasPrefix(key, blockHashPrefix) && len(key) == 1+common.HashLength }},
+	{"Bodies", func(key []byte) bool { return bytes.HasPrefix(key, bodyPrefix) && len(key) == 1+8+common.HashLength }},
+	{"Receipts", func(key []byte) bool {
+		return bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == 1+8+common.HashLength
+	}},
+	{"Transaction lookups", func(key []byte) bool { return bytes.HasPrefix(key, lookupPrefix) && len(key) == 1+common.HashLength }},
+	{"Bloom bits", func(key []byte) bool {
+		return bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == 1+2+8+common.HashLength
+	}},
+	{"Address index", func(key []byte) bool {
+		return (bytes.HasPrefix(key, addrTxPrefix) && len(key) == 1+common.AddressLength+8) ||
+			(bytes.HasPrefix(key, addrTxCountPrefix) && len(key) == 1+common.AddressLength)
+	}},
+	{"Reorg journal", func(key []byte) bool { return bytes.HasPrefix(key, reorgPrefix) && len(key) == 1+8 }},
+	{"Token assignments", func(key []byte) bool { return bytes.HasPrefix(key, assignPrefix) && len(key) == 1+8+common.HashLength }},
+	{"Balance changes", func(key []byte) bool {
+		return bytes.HasPrefix(key, balChangePrefix) && len(key) == 1+8+common.HashLength
+	}},
+	{"Internal transfers", func(key []byte) bool {
+		return bytes.HasPrefix(key, internalTxPrefix) && len(key) == 1+8+common.HashLength
+	}},
+	{"Balance index", func(key []byte) bool {
+		return (bytes.HasPrefix(key, balIndexPrefix) && len(key) == 1+common.AddressLength+8) ||
+			(bytes.HasPrefix(key, balCountPrefix) && len(key) == 1+common.AddressLength)
+	}},
+	{"Token transfer index", func(key []byte) bool {
+		return ((bytes.HasPrefix(key, tokenTxPrefix) || bytes.HasPrefix(key, tokenSetPrefix)) && len(key) == 1+common.AddressLength+8) ||
+			((bytes.HasPrefix(key, tokenTxCountPrefix) || bytes.HasPrefix(key, tokenSetCountPrefix)) && len(key) == 1+common.AddressLength)
+	}},
+	{"Dpos epoch snapshots", func(key []byte) bool {
+		return bytes.HasPrefix(key, protocol.EpochSnapshotPrefix) && len(key) == len(protocol.EpochSnapshotPrefix)+8
+	}},
+	{"Preimages", func(key []byte) bool { return bytes.HasPrefix(key, []byte(preimagePrefix)) }},
+	{"Trie nodes and code", func(key []byte) bool { return len(key) == common.HashLength }},
+}
+
+//冻结库中的表以及统计时的名称
+var ancientCategories = []struct {
+	name  string
+	table string
+}{
+	{"Ancient hashes", freezerHashTable},
+	{"Ancient headers", freezerHeaderTable},
+	{"Ancient bodies", freezerBodiesTable},
+	{"Ancient receipts", freezerReceiptTable},
+}
+
+//遍历链数据库，按键的格式统计各类数据的数量和大小，冻结库按表统计。progress在遍历过程中定期调用
+func InspectDatabase(db ethdb.Database, progress func(keys uint64)) ([]*DatabaseStat, error) {
+
+	iteratee, ok := db.(ethdb.Iteratee)
+	if !ok {
+		return nil, errNotIterable
+	}
+	stats := make([]*DatabaseStat, len(databaseCategories)+1)
+	for i, category := range databaseCategories {
+		stats[i] = &DatabaseStat{Category: category.name}
+	}
+	other := &DatabaseStat{Category: "Other"}
+	stats[len(databaseCategories)] = other
+
+	it := iteratee.Iterate(nil)
+	defer it.Release()
+
+	var keys uint64
+	for it.Next() {
+		key := it.Key()
+		stat := other
+		for i, category := range databaseCategories {
+			if category.match(key) {
+				stat = stats[i]
+				break
+			}
+		}
+		stat.Count++
+		stat.Size += common.StorageSize(len(key) + len(it.Value()))
+
+		if keys++; keys%100000 == 0 && progress != nil {
+			progress(keys)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if ancients, ok := db.(ethdb.AncientReader); ok {
+		for _, category := range ancientCategories {
+			size, err := ancients.AncientSize(category.table)
+			if err != nil {
+				return nil, err
+			}
+			stats = append(stats, &DatabaseStat{Category: category.name, Count: ancients.Ancients(), Size: common.StorageSize(size)})
+		}
+	}
+	return stats, nil
+}
+
+//检查冻结库中每个区块的哈希与规范链一致，区块头、区块体以及收据可以解码并且与区块头中的根哈希一致。
+//progress在检查过程中定期调用，返回检查通过的区块数量
+func VerifyAncients(db ethdb.Database, progress func(number uint64)) (uint64, error) {
+
+	ancients, ok := db.(ethdb.AncientReader)
+	if !ok {
+		return 0, errNoAncients
+	}
+	frozen := ancients.Ancients()
+	for number := uint64(0); number < frozen; number++ {
+		if err := verifyAncient(db, ancients, number); err != nil {
+			return number, fmt.Errorf("ancient block %d: %v", number, err)
+		}
+		if number%10000 == 0 && progress != nil {
+			progress(number)
+		}
+	}
+	return frozen, nil
+}
+
+func verifyAncient(db ethdb.Database, ancients ethdb.AncientReader, number uint64) error {
+
+	blob, err := ancients.Ancient(freezerHashTable, number)
+	if err != nil {
+		return err
+	}
+	hash := common.BytesToHash(blob)
+	if canonical := GetCanonicalHash(db, number); canonical != hash {
+		return fmt.Errorf("hash %x, canonical %x", hash, canonical)
+	}
+	if blob, err = ancients.Ancient(freezerHeaderTable, number); err != nil {
+		return err
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(blob, header); err != nil {
+		return fmt.Errorf("invalid header: %v", err)
+	}
+	if header.Hash() != hash {
+		return fmt.Errorf("header hash %x, want %x", header.Hash(), hash)
+	}
+	if blob, err = ancients.Ancient(freezerBodiesTable, number); err != nil {
+		return err
+	}
+	body := new(types.Body)
+	if err := rlp.DecodeBytes(blob, body); err != nil {
+		return fmt.Errorf("invalid body: %v", err)
+	}
+	if root := types.DeriveSha(types.Transactions(body.Transactions)); root != header.TxHash {
+		return fmt.Errorf("transaction root %x, want %x", root, header.TxHash)
+	}
+	if uncles := types.CalcUncleHash(body.Uncles); uncles != header.UncleHash {
+		return fmt.Errorf("uncle hash %x, want %x", uncles, header.UncleHash)
+	}
+	//没有收据的区块冻结时保存空数据
+	if blob, err = ancients.Ancient(freezerReceiptTable, number); err != nil || len(blob) == 0 {
+		return err
+	}
+	var storageReceipts []*types.ReceiptForStorage
+	if err := rlp.DecodeBytes(blob, &storageReceipts); err != nil {
+		return fmt.Errorf("invalid receipts: %v", err)
+	}
+	receipts := make(types.Receipts, len(storageReceipts))
+	for i, receipt := range storageReceipts {
+		receipts[i] = (*types.Receipt)(receipt)
+	}
+	if root := types.DeriveSha(receipts); root != header.ReceiptHash {
+		return fmt.Errorf("receipt root %x, want %x", root, header.ReceiptHash)
+	}
+	return nil
+}
+
+//删除指向非规范区块的交易查找索引，返回删除的数量。progress在遍历过程中定期调用
+func PruneTxLookups(db ethdb.Database, progress func(scanned, pruned uint64)) (uint64, error) {
+
+	iteratee, ok := db.(ethdb.Iteratee)
+	if !ok {
+		return 0, errNotIterable
+	}
+	it := iteratee.Iterate(lookupPrefix)
+	defer it.Release()
+
+	var scanned, pruned uint64
+	for it.Next() {
+		if len(it.Key()) != len(lookupPrefix)+common.HashLength {
+			continue
+		}
+		var entry TxLookupEntry
+		if err := rlp.DecodeBytes(it.Value(), &entry); err != nil || GetCanonicalHash(db, entry.BlockIndex) != entry.BlockHash {
+			if err := db.Delete(common.CopyBytes(it.Key())); err != nil {
+				return pruned, err
+			}
+			pruned++
+		}
+		if scanned++; scanned%100000 == 0 && progress != nil {
+			progress(scanned, pruned)
+		}
+	}
+	return pruned, it.Error()
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+)
+
+func newInspectTestBlock(number int64, extra string, txs int) *types.Block {
+	header := &types.Header{Number: big.NewInt(number), Extra: []byte(extra)}
+	var transactions []*types.Transaction
+	for i := 0; i < txs; i++ {
+		transactions = append(transactions, types.NewTransaction(protocol.Binary, uint64(i), common.Address{}, big.NewInt(number), big.NewInt(21000), big.NewInt(1), []byte(extra)))
+	}
+	return types.NewBlock(header, transactions, nil, nil)
+}
+
+//测试按键的格式统计各类数据
+func TestInspectDatabase(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+
+	block := newInspectTestBlock(1, "", 3)
+	WriteBlock(db, block)
+	WriteTd(db, block.Hash(), 1, big.NewInt(1))
+	WriteCanonicalHash(db, block.Hash(), 1)
+	WriteTxLookupEntries(db, block)
+	WritePreimages(db, 1, map[common.Hash][]byte{{1}: {1}})
+	db.Put(common.Hash{2}.Bytes(), []byte{2})
+	db.Put([]byte("LastBlock"), block.Hash().Bytes())
+
+	stats, err := InspectDatabase(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]uint64{
+		"Headers":             1,
+		"Bodies":              1,
+		"Total difficulties":  1,
+		"Canonical hashes":    1,
+		"Hash to number":      1,
+		"Transaction lookups": 3,
+		"Preimages":           1,
+		"Trie nodes and code": 1,
+		"Other":               1,
+	}
+	for _, stat := range stats {
+		if stat.Count != want[stat.Category] {
+			t.Errorf("%s: count mismatch: have %d, want %d", stat.Category, stat.Count, want[stat.Category])
+		}
+		if (stat.Size == 0) != (stat.Count == 0) {
+			t.Errorf("%s: size %v for %d items", stat.Category, stat.Size, stat.Count)
+		}
+	}
+}
+
+//测试只删除指向非规范区块的交易查找索引
+func TestPruneTxLookups(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+
+	canonical := newInspectTestBlock(1, "canonical", 2)
+	side := newInspectTestBlock(1, "side", 2)
+	WriteCanonicalHash(db, canonical.Hash(), 1)
+	WriteTxLookupEntries(db, side)
+	WriteTxLookupEntries(db, canonical)
+
+	pruned, err := PruneTxLookups(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 2 {
+		t.Fatalf("pruned entries mismatch: have %d, want 2", pruned)
+	}
+	for _, tx := range canonical.Transactions() {
+		if hash, _, _ := GetTxLookupEntry(db, tx.Hash()); hash != canonical.Hash() {
+			t.Errorf("canonical lookup entry %x removed", tx.Hash())
+		}
+	}
+	for _, tx := range side.Transactions() {
+		if hash, _, _ := GetTxLookupEntry(db, tx.Hash()); hash != (common.Hash{}) {
+			t.Errorf("stale lookup entry %x kept", tx.Hash())
+		}
+	}
+}
@@ -48,6 +48,13 @@ var (
 	headBlockKey  = []byte("LastBlock")
 	headFastKey   = []byte("LastFast")
 
+	addrTxIndexHeadKey = []byte("LastAddressTxIndex")
+	balIndexHeadKey    = []byte("LastBalanceIndex")
+	balIndexTailKey    = []byte("BalanceIndexTail")
+	tokenIndexHeadKey  = []byte("LastTokenIndex")
+	reorgJournalKey    = []byte("LastReorg")
+	badBlocksKey       = []byte("InvalidBlock")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`).
 	headerPrefix        = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	tdSuffix            = []byte("t") // headerPrefix + num (uint64 big endian) + hash + tdSuffix -> td
@@ -57,6 +64,18 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	lookupPrefix        = []byte("l") // lookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix     = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	addrTxPrefix        = []byte("x") // addrTxPrefix + address + seq (uint64 big endian) -> address transaction index entry
+	addrTxCountPrefix   = []byte("X") // addrTxCountPrefix + address -> number of indexed address transactions (uint64 big endian)
+	reorgPrefix         = []byte("R") // reorgPrefix + seq (uint64 big endian) -> chain reorganisation journal entry
+	assignPrefix        = []byte("a") // assignPrefix + num (uint64 big endian) + hash -> token assignments of the block
+	balChangePrefix     = []byte("c") // balChangePrefix + num (uint64 big endian) + hash -> balance changes of the block
+	balIndexPrefix      = []byte("w") // balIndexPrefix + address + seq (uint64 big endian) -> balance index entry
+	balCountPrefix      = []byte("W") // balCountPrefix + address -> number of indexed balance changes (uint64 big endian)
+	tokenTxPrefix       = []byte("y") // tokenTxPrefix + address + seq (uint64 big endian) -> token transfer index entry
+	tokenTxCountPrefix  = []byte("Y") // tokenTxCountPrefix + address -> number of indexed token transfers (uint64 big endian)
+	tokenSetPrefix      = []byte("z") // tokenSetPrefix + address + seq (uint64 big endian) -> token contract transferred by the address
+	tokenSetCountPrefix = []byte("Z") // tokenSetCountPrefix + address -> number of token contracts transferred by the address (uint64 big endian)
+	internalTxPrefix    = []byte("v") // internalTxPrefix + num (uint64 big endian) + hash -> internal value transfers of the block
 
 	preimagePrefix = "secure-key-"              // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
@@ -82,6 +101,64 @@ type TxLookupEntry struct {
 	Index      uint64
 }
 
+// AddressTxEntry is a positional entry of the address transaction index,
+// referencing a transaction sent from or to an address.
+type AddressTxEntry struct {
+	BlockNumber uint64
+	TxHash      common.Hash
+}
+
+// TokenAssignment is an entry of the token assignment index, describing an
+// AssignToken transaction of a block and the transfers made while executing it.
+type TokenAssignment struct {
+	TxHash    common.Hash
+	TxIndex   uint64
+	Noder     common.Address
+	Amount    *big.Int
+	Failed    bool
+	Transfers []*types.TokenTransfer
+}
+
+// BalanceChange is the balance of an account before and after a block, recorded
+// for every account whose balance was changed by the block.
+type BalanceChange struct {
+	Address common.Address
+	Prev    *big.Int
+	Balance *big.Int
+}
+
+// InternalTransfer is a value transfer made by a contract while executing a
+// transaction, recorded by the internal transaction index. Transfers of failed
+// transactions and reverted calls are not recorded.
+type InternalTransfer struct {
+	TxIndex uint64
+	Type    string // CALL, CREATE, CREATE2 or SELFDESTRUCT
+	From    common.Address
+	To      common.Address
+	Value   *big.Int
+	Depth   uint64
+}
+
+// BalanceIndexEntry is a positional entry of the balance history index,
+// recording the balance of an account before and after a block changing it.
+type BalanceIndexEntry struct {
+	BlockNumber uint64
+	Prev        *big.Int
+	Balance     *big.Int
+}
+
+// TokenTransferEntry is a positional entry of the token transfer index,
+// describing an ERC-20 Transfer event sent from or to an address.
+type TokenTransferEntry struct {
+	BlockNumber uint64
+	TxHash      common.Hash
+	LogIndex    uint64
+	Token       common.Address
+	From        common.Address
+	To          common.Address
+	Value       *big.Int
+}
+
 // encodeBlockNumber encodes a block number as big endian uint64
 func encodeBlockNumber(number uint64) []byte {
 	enc := make([]byte, 8)
@@ -168,9 +245,15 @@ func GetHeader(db DatabaseReader, hash common.Hash, number uint64) *types.Header
 	return header
 }
 
-// GetBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
+// GetBodyRLP retrieves the block body (transactions and uncles) in RLP encoding,
+// decompressing it if it was stored compressed.
 func GetBodyRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(blockBodyKey(hash, number))
+	data, err := decompressEntry(data)
+	if err != nil {
+		log.Error("Invalid compressed block body", "hash", hash, "err", err)
+		return nil
+	}
 	return data
 }
 
@@ -256,7 +339,7 @@ func getDposContextTrie(db ethdb.Database, header *types.Header) *types.DposCont
 // GetBlockReceipts retrieves the receipts generated by the transactions included
 // in a block given by its hash.
 func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
-	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	data := getBlockReceiptsRLP(db, hash, number)
 	if len(data) == 0 {
 		return nil
 	}
@@ -272,6 +355,18 @@ func GetBlockReceipts(db DatabaseReader, hash common.Hash, number uint64) types.
 	return receipts
 }
 
+// getBlockReceiptsRLP retrieves the receipts of a block in their storage RLP
+// encoding, decompressing them if they were stored compressed.
+func getBlockReceiptsRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
+	data, _ := db.Get(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	data, err := decompressEntry(data)
+	if err != nil {
+		log.Error("Invalid compressed receipt array", "hash", hash, "err", err)
+		return nil
+	}
+	return data
+}
+
 // GetTxLookupEntry retrieves the positional metadata associated with a transaction
 // hash to allow retrieving the transaction or receipt by hash.
 func GetTxLookupEntry(db DatabaseReader, hash common.Hash) (common.Hash, uint64, uint64) {
@@ -289,6 +384,39 @@ func GetTxLookupEntry(db DatabaseReader, hash common.Hash) (common.Hash, uint64,
 	return entry.BlockHash, entry.BlockIndex, entry.Index
 }
 
+// GetAddressTxIndexHead retrieves the hash of the last block indexed by the
+// address transaction index, or the zero hash if the index was never built.
+func GetAddressTxIndexHead(db DatabaseReader) common.Hash {
+	data, _ := db.Get(addrTxIndexHeadKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// GetAddressTxCount retrieves the number of transactions indexed for an address.
+func GetAddressTxCount(db DatabaseReader, addr common.Address) uint64 {
+	data, _ := db.Get(append(addrTxCountPrefix, addr.Bytes()...))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// GetAddressTxEntry retrieves the seq-th transaction indexed for an address.
+func GetAddressTxEntry(db DatabaseReader, addr common.Address, seq uint64) *AddressTxEntry {
+	data, _ := db.Get(append(append(addrTxPrefix, addr.Bytes()...), encodeBlockNumber(seq)...))
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(AddressTxEntry)
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		log.Error("Invalid address transaction entry RLP", "address", addr, "seq", seq, "err", err)
+		return nil
+	}
+	return entry
+}
+
 // GetTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func GetTransaction(db DatabaseReader, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
@@ -424,10 +552,11 @@ func WriteBody(db ethdb.Putter, hash common.Hash, number uint64, body *types.Bod
 	return WriteBodyRLP(db, hash, number, data)
 }
 
-// WriteBodyRLP writes a serialized body of a block into the database.
+// WriteBodyRLP writes a serialized body of a block into the database, compressing
+// it on the way.
 func WriteBodyRLP(db ethdb.Putter, hash common.Hash, number uint64, rlp rlp.RawValue) error {
 	key := append(append(bodyPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
-	if err := db.Put(key, rlp); err != nil {
+	if err := db.Put(key, compressEntry(rlp)); err != nil {
 		log.Crit("Failed to store block body", "err", err)
 	}
 	return nil
@@ -472,9 +601,9 @@ func WriteBlockReceipts(db ethdb.Putter, hash common.Hash, number uint64, receip
 	if err != nil {
 		return err
 	}
-	// Store the flattened receipt slice
+	// Store the flattened receipt slice, compressed
 	key := append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
-	if err := db.Put(key, bytes); err != nil {
+	if err := db.Put(key, compressEntry(bytes)); err != nil {
 		log.Crit("Failed to store block receipts", "err", err)
 	}
 	return nil
@@ -501,6 +630,331 @@ func WriteTxLookupEntries(db ethdb.Putter, block *types.Block) error {
 	return nil
 }
 
+// WriteAddressTxIndexHead stores the hash of the last block indexed by the
+// address transaction index.
+func WriteAddressTxIndexHead(db ethdb.Putter, hash common.Hash) error {
+	if err := db.Put(addrTxIndexHeadKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store address transaction index head", "err", err)
+	}
+	return nil
+}
+
+// WriteAddressTxCount stores the number of transactions indexed for an address.
+func WriteAddressTxCount(db ethdb.Putter, addr common.Address, count uint64) error {
+	return db.Put(append(addrTxCountPrefix, addr.Bytes()...), encodeBlockNumber(count))
+}
+
+// WriteAddressTxEntry stores the seq-th transaction indexed for an address.
+func WriteAddressTxEntry(db ethdb.Putter, addr common.Address, seq uint64, entry *AddressTxEntry) error {
+	data, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(append(addrTxPrefix, addr.Bytes()...), encodeBlockNumber(seq)...), data)
+}
+
+// GetTokenAssignments retrieves the token assignments recorded for a block,
+// or nil if the block contains none or was never executed locally.
+func GetTokenAssignments(db DatabaseReader, hash common.Hash, number uint64) []*TokenAssignment {
+	data, _ := db.Get(append(append(assignPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	var assignments []*TokenAssignment
+	if err := rlp.DecodeBytes(data, &assignments); err != nil {
+		log.Error("Invalid token assignments RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return assignments
+}
+
+// WriteTokenAssignments stores the token assignments of a block.
+func WriteTokenAssignments(db ethdb.Putter, hash common.Hash, number uint64, assignments []*TokenAssignment) error {
+	data, err := rlp.EncodeToBytes(assignments)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(append(assignPrefix, encodeBlockNumber(number)...), hash.Bytes()...), data)
+}
+
+// GetBalanceChanges retrieves the balance changes recorded for a block. The
+// second return value is false if the block was not executed while the balance
+// history index was enabled.
+func GetBalanceChanges(db DatabaseReader, hash common.Hash, number uint64) ([]*BalanceChange, bool) {
+	data, _ := db.Get(append(append(balChangePrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		return nil, false
+	}
+	var changes []*BalanceChange
+	if err := rlp.DecodeBytes(data, &changes); err != nil {
+		log.Error("Invalid balance changes RLP", "hash", hash, "err", err)
+		return nil, false
+	}
+	return changes, true
+}
+
+// WriteBalanceChanges stores the balance changes of a block.
+func WriteBalanceChanges(db ethdb.Putter, hash common.Hash, number uint64, changes []*BalanceChange) error {
+	data, err := rlp.EncodeToBytes(changes)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(append(balChangePrefix, encodeBlockNumber(number)...), hash.Bytes()...), data)
+}
+
+// GetInternalTransfers retrieves the internal value transfers recorded for a
+// block. The second return value is false if the block was not executed while
+// the internal transaction index was enabled.
+func GetInternalTransfers(db DatabaseReader, hash common.Hash, number uint64) ([]*InternalTransfer, bool) {
+	data, _ := db.Get(append(append(internalTxPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		return nil, false
+	}
+	var transfers []*InternalTransfer
+	if err := rlp.DecodeBytes(data, &transfers); err != nil {
+		log.Error("Invalid internal transfers RLP", "hash", hash, "err", err)
+		return nil, false
+	}
+	return transfers, true
+}
+
+// WriteInternalTransfers stores the internal value transfers of a block.
+func WriteInternalTransfers(db ethdb.Putter, hash common.Hash, number uint64, transfers []*InternalTransfer) error {
+	data, err := rlp.EncodeToBytes(transfers)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(append(internalTxPrefix, encodeBlockNumber(number)...), hash.Bytes()...), data)
+}
+
+// GetBalanceIndexHead retrieves the hash of the last block indexed by the
+// balance history index, or the zero hash if the index was never enabled.
+func GetBalanceIndexHead(db DatabaseReader) common.Hash {
+	data, _ := db.Get(balIndexHeadKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteBalanceIndexHead stores the hash of the last block indexed by the
+// balance history index.
+func WriteBalanceIndexHead(db ethdb.Putter, hash common.Hash) error {
+	return db.Put(balIndexHeadKey, hash.Bytes())
+}
+
+// GetBalanceIndexTail retrieves the number of the first block from which on
+// every balance change of the canonical chain is indexed.
+func GetBalanceIndexTail(db DatabaseReader) uint64 {
+	data, _ := db.Get(balIndexTailKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteBalanceIndexTail stores the number of the first block from which on
+// every balance change of the canonical chain is indexed.
+func WriteBalanceIndexTail(db ethdb.Putter, number uint64) error {
+	return db.Put(balIndexTailKey, encodeBlockNumber(number))
+}
+
+// GetBalanceIndexCount retrieves the number of balance changes indexed for an address.
+func GetBalanceIndexCount(db DatabaseReader, addr common.Address) uint64 {
+	data, _ := db.Get(append(balCountPrefix, addr.Bytes()...))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteBalanceIndexCount stores the number of balance changes indexed for an address.
+func WriteBalanceIndexCount(db ethdb.Putter, addr common.Address, count uint64) error {
+	return db.Put(append(balCountPrefix, addr.Bytes()...), encodeBlockNumber(count))
+}
+
+// GetBalanceIndexEntry retrieves the seq-th balance change indexed for an address.
+func GetBalanceIndexEntry(db DatabaseReader, addr common.Address, seq uint64) *BalanceIndexEntry {
+	data, _ := db.Get(append(append(balIndexPrefix, addr.Bytes()...), encodeBlockNumber(seq)...))
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(BalanceIndexEntry)
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		log.Error("Invalid balance index entry RLP", "address", addr, "seq", seq, "err", err)
+		return nil
+	}
+	return entry
+}
+
+// WriteBalanceIndexEntry stores the seq-th balance change indexed for an address.
+func WriteBalanceIndexEntry(db ethdb.Putter, addr common.Address, seq uint64, entry *BalanceIndexEntry) error {
+	data, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(append(balIndexPrefix, addr.Bytes()...), encodeBlockNumber(seq)...), data)
+}
+
+// GetTokenIndexHead retrieves the hash of the last block indexed by the token
+// transfer index, or the zero hash if the index was never built.
+func GetTokenIndexHead(db DatabaseReader) common.Hash {
+	data, _ := db.Get(tokenIndexHeadKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteTokenIndexHead stores the hash of the last block indexed by the token
+// transfer index.
+func WriteTokenIndexHead(db ethdb.Putter, hash common.Hash) error {
+	if err := db.Put(tokenIndexHeadKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store token transfer index head", "err", err)
+	}
+	return nil
+}
+
+// GetTokenTransferCount retrieves the number of token transfers indexed for an address.
+func GetTokenTransferCount(db DatabaseReader, addr common.Address) uint64 {
+	data, _ := db.Get(append(tokenTxCountPrefix, addr.Bytes()...))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteTokenTransferCount stores the number of token transfers indexed for an address.
+func WriteTokenTransferCount(db ethdb.Putter, addr common.Address, count uint64) error {
+	return db.Put(append(tokenTxCountPrefix, addr.Bytes()...), encodeBlockNumber(count))
+}
+
+// GetTokenTransferEntry retrieves the seq-th token transfer indexed for an address.
+func GetTokenTransferEntry(db DatabaseReader, addr common.Address, seq uint64) *TokenTransferEntry {
+	data, _ := db.Get(append(append(tokenTxPrefix, addr.Bytes()...), encodeBlockNumber(seq)...))
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(TokenTransferEntry)
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		log.Error("Invalid token transfer entry RLP", "address", addr, "seq", seq, "err", err)
+		return nil
+	}
+	return entry
+}
+
+// WriteTokenTransferEntry stores the seq-th token transfer indexed for an address.
+func WriteTokenTransferEntry(db ethdb.Putter, addr common.Address, seq uint64, entry *TokenTransferEntry) error {
+	data, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(append(tokenTxPrefix, addr.Bytes()...), encodeBlockNumber(seq)...), data)
+}
+
+// GetHeldTokenCount retrieves the number of token contracts an address ever
+// sent or received tokens of.
+func GetHeldTokenCount(db DatabaseReader, addr common.Address) uint64 {
+	data, _ := db.Get(append(tokenSetCountPrefix, addr.Bytes()...))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteHeldTokenCount stores the number of token contracts an address ever
+// sent or received tokens of.
+func WriteHeldTokenCount(db ethdb.Putter, addr common.Address, count uint64) error {
+	return db.Put(append(tokenSetCountPrefix, addr.Bytes()...), encodeBlockNumber(count))
+}
+
+// GetHeldTokens retrieves the token contracts an address ever sent or received
+// tokens of, in the order they were first seen.
+func GetHeldTokens(db DatabaseReader, addr common.Address) []common.Address {
+	count := GetHeldTokenCount(db, addr)
+	tokens := make([]common.Address, 0, count)
+	for seq := uint64(0); seq < count; seq++ {
+		data, _ := db.Get(append(append(tokenSetPrefix, addr.Bytes()...), encodeBlockNumber(seq)...))
+		if len(data) != common.AddressLength {
+			continue
+		}
+		tokens = append(tokens, common.BytesToAddress(data))
+	}
+	return tokens
+}
+
+// WriteHeldToken stores the seq-th token contract an address sent or received
+// tokens of.
+func WriteHeldToken(db ethdb.Putter, addr common.Address, seq uint64, token common.Address) error {
+	return db.Put(append(append(tokenSetPrefix, addr.Bytes()...), encodeBlockNumber(seq)...), token.Bytes())
+}
+
+// GetReorgJournalSize retrieves the number of chain reorganisations recorded
+// in the journal since it was created.
+func GetReorgJournalSize(db DatabaseReader) uint64 {
+	data, _ := db.Get(reorgJournalKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// GetReorgEntry retrieves the seq-th chain reorganisation from the journal.
+func GetReorgEntry(db DatabaseReader, seq uint64) *ReorgEntry {
+	data, _ := db.Get(append(reorgPrefix, encodeBlockNumber(seq)...))
+	if len(data) == 0 {
+		return nil
+	}
+	entry := new(ReorgEntry)
+	if err := rlp.DecodeBytes(data, entry); err != nil {
+		log.Error("Invalid reorg journal entry RLP", "seq", seq, "err", err)
+		return nil
+	}
+	return entry
+}
+
+// WriteReorgJournalSize stores the number of chain reorganisations recorded.
+func WriteReorgJournalSize(db ethdb.Putter, size uint64) error {
+	return db.Put(reorgJournalKey, encodeBlockNumber(size))
+}
+
+// WriteReorgEntry stores a chain reorganisation in the journal.
+func WriteReorgEntry(db ethdb.Putter, entry *ReorgEntry) error {
+	data, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(reorgPrefix, encodeBlockNumber(entry.Seq)...), data)
+}
+
+// DeleteReorgEntry removes a chain reorganisation from the journal.
+func DeleteReorgEntry(db DatabaseDeleter, seq uint64) {
+	db.Delete(append(reorgPrefix, encodeBlockNumber(seq)...))
+}
+
+// GetBadBlocks retrieves the recorded bad blocks, most recent first.
+func GetBadBlocks(db DatabaseReader) []*BadBlock {
+	data, _ := db.Get(badBlocksKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var blocks []*BadBlock
+	if err := rlp.DecodeBytes(data, &blocks); err != nil {
+		log.Error("Invalid bad blocks RLP", "err", err)
+		return nil
+	}
+	return blocks
+}
+
+// WriteBadBlocks stores the list of recorded bad blocks.
+func WriteBadBlocks(db ethdb.Putter, blocks []*BadBlock) error {
+	data, err := rlp.EncodeToBytes(blocks)
+	if err != nil {
+		return err
+	}
+	return db.Put(badBlocksKey, data)
+}
+
 // WriteBloomBits writes the compressed bloom bits vector belonging to the given
 // section and bit index.
 func WriteBloomBits(db ethdb.Putter, bit uint, section uint64, head common.Hash, bits []byte) {
@@ -538,6 +992,9 @@ func DeleteTd(db DatabaseDeleter, hash common.Hash, number uint64) {
 // DeleteBlock removes all block data associated with a hash.
 func DeleteBlock(db DatabaseDeleter, hash common.Hash, number uint64) {
 	DeleteBlockReceipts(db, hash, number)
+	DeleteTokenAssignments(db, hash, number)
+	DeleteBalanceChanges(db, hash, number)
+	DeleteInternalTransfers(db, hash, number)
 	DeleteHeader(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
@@ -548,11 +1005,41 @@ func DeleteBlockReceipts(db DatabaseDeleter, hash common.Hash, number uint64) {
 	db.Delete(append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
 }
 
+// DeleteTokenAssignments removes the token assignments recorded for a block.
+func DeleteTokenAssignments(db DatabaseDeleter, hash common.Hash, number uint64) {
+	db.Delete(append(append(assignPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+}
+
+// DeleteBalanceChanges removes the balance changes recorded for a block.
+func DeleteBalanceChanges(db DatabaseDeleter, hash common.Hash, number uint64) {
+	db.Delete(append(append(balChangePrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+}
+
+// DeleteInternalTransfers removes the internal value transfers recorded for a block.
+func DeleteInternalTransfers(db DatabaseDeleter, hash common.Hash, number uint64) {
+	db.Delete(append(append(internalTxPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+}
+
 // DeleteTxLookupEntry removes all transaction data associated with a hash.
 func DeleteTxLookupEntry(db DatabaseDeleter, hash common.Hash) {
 	db.Delete(append(lookupPrefix, hash.Bytes()...))
 }
 
+// DeleteAddressTxEntry removes the seq-th transaction indexed for an address.
+func DeleteAddressTxEntry(db DatabaseDeleter, addr common.Address, seq uint64) {
+	db.Delete(append(append(addrTxPrefix, addr.Bytes()...), encodeBlockNumber(seq)...))
+}
+
+// DeleteBalanceIndexEntry removes the seq-th balance change indexed for an address.
+func DeleteBalanceIndexEntry(db DatabaseDeleter, addr common.Address, seq uint64) {
+	db.Delete(append(append(balIndexPrefix, addr.Bytes()...), encodeBlockNumber(seq)...))
+}
+
+// DeleteTokenTransferEntry removes the seq-th token transfer indexed for an address.
+func DeleteTokenTransferEntry(db DatabaseDeleter, addr common.Address, seq uint64) {
+	db.Delete(append(append(tokenTxPrefix, addr.Bytes()...), encodeBlockNumber(seq)...))
+}
+
 // PreimageTable returns a Database instance with the key prefix for preimage entries.
 func PreimageTable(db ethdb.Database) ethdb.Database {
 	return ethdb.NewTable(db, preimagePrefix)
@@ -21,6 +21,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/crypto/sha3"
@@ -34,7 +35,7 @@ func TestHeaderStorage(t *testing.T) {
 
 	// Create a test header to move around the database and make sure it's really new
 	dposCtx, _ := types.NewDposContext(db)
-	header := &types.Header{Number: big.NewInt(42), Extra: []byte("test header"), DposContext: dposCtx.ToProto()}
+	header := &types.Header{Number: big.NewInt(42), Extra: []byte("test header"), DposProto: dposCtx.ToProto(), BokerProto: &protocol.BokerBackendProto{}}
 	if entry := GetHeader(db, header.Hash(), header.Number.Uint64()); entry != nil {
 		t.Fatalf("Non existent header returned: %v", entry)
 	}
@@ -70,7 +71,7 @@ func TestBodyStorage(t *testing.T) {
 
 	// Create a test body to move around the database and make sure it's really new
 	dposCtx, _ := types.NewDposContext(db)
-	body := &types.Body{Uncles: []*types.Header{{Extra: []byte("test header"), DposContext: dposCtx.ToProto()}}}
+	body := &types.Body{Uncles: []*types.Header{{Extra: []byte("test header"), DposProto: dposCtx.ToProto(), BokerProto: &protocol.BokerBackendProto{}}}}
 
 	hasher := sha3.NewKeccak256()
 	rlp.Encode(hasher, body)
@@ -105,6 +106,34 @@ func TestBodyStorage(t *testing.T) {
 	}
 }
 
+// Tests that bodies are stored compressed and that uncompressed bodies written
+// by older versions can still be read.
+func TestBodyCompression(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+
+	body := &types.Body{Uncles: []*types.Header{{Extra: bytes.Repeat([]byte("boker"), 100)}}}
+	blob, _ := rlp.EncodeToBytes(body)
+
+	// Write a compressed and a legacy uncompressed body
+	compressed, legacy := common.Hash{1}, common.Hash{2}
+	WriteBodyRLP(db, compressed, 1, blob)
+	db.Put(blockBodyKey(legacy, 2), blob)
+
+	if stored, _ := db.Get(blockBodyKey(compressed, 1)); len(stored) >= len(blob) || stored[0] != entrySnappyV1 {
+		t.Fatalf("Body not compressed: have %d bytes, plain %d bytes", len(stored), len(blob))
+	}
+	for i, hash := range []common.Hash{compressed, legacy} {
+		if entry := GetBodyRLP(db, hash, uint64(i+1)); !bytes.Equal(entry, blob) {
+			t.Fatalf("Body %d RLP mismatch: have %x, want %x", i, entry, blob)
+		}
+	}
+	// Unknown encodings must be rejected instead of decoded as garbage
+	db.Put(blockBodyKey(legacy, 3), append([]byte{0x7f}, blob...))
+	if entry := GetBodyRLP(db, legacy, 3); entry != nil {
+		t.Fatalf("Body with unknown encoding returned: %x", entry)
+	}
+}
+
 // Tests block storage and retrieval operations.
 func TestBlockStorage(t *testing.T) {
 	db, _ := ethdb.NewMemDatabase()
@@ -292,9 +321,9 @@ func TestHeadStorage(t *testing.T) {
 func TestLookupStorage(t *testing.T) {
 	db, _ := ethdb.NewMemDatabase()
 
-	tx1 := types.NewTransaction(types.Binary, 1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), big.NewInt(1111), big.NewInt(11111), []byte{0x11, 0x11, 0x11})
-	tx2 := types.NewTransaction(types.Binary, 2, common.BytesToAddress([]byte{0x22}), big.NewInt(222), big.NewInt(2222), big.NewInt(22222), []byte{0x22, 0x22, 0x22})
-	tx3 := types.NewTransaction(types.Binary, 3, common.BytesToAddress([]byte{0x33}), big.NewInt(333), big.NewInt(3333), big.NewInt(33333), []byte{0x33, 0x33, 0x33})
+	tx1 := types.NewTransaction(protocol.Binary, 1, common.BytesToAddress([]byte{0x11}), big.NewInt(111), big.NewInt(1111), big.NewInt(11111), []byte{0x11, 0x11, 0x11})
+	tx2 := types.NewTransaction(protocol.Binary, 2, common.BytesToAddress([]byte{0x22}), big.NewInt(222), big.NewInt(2222), big.NewInt(22222), []byte{0x22, 0x22, 0x22})
+	tx3 := types.NewTransaction(protocol.Binary, 3, common.BytesToAddress([]byte{0x33}), big.NewInt(333), big.NewInt(3333), big.NewInt(33333), []byte{0x33, 0x33, 0x33})
 	txs := []*types.Transaction{tx1, tx2, tx3}
 
 	block := types.NewBlock(&types.Header{Number: big.NewInt(314)}, txs, nil, nil)
@@ -26,6 +26,10 @@ var (
 	// by a transaction is higher than what's left in the block.
 	ErrGasLimitReached = errors.New("gas limit reached")
 
+	// ErrBlockSizeLimit is returned if the transactions of a block exceed the
+	// block size limit of the chain configuration.
+	ErrBlockSizeLimit = errors.New("block size limit exceeded")
+
 	// ErrBlacklistedHash is returned if a block to import is on the blacklist.
 	ErrBlacklistedHash = errors.New("blacklisted hash")
 
@@ -38,6 +38,9 @@ type NewMinedBlockEvent struct{ Block *types.Block }
 // RemovedTransactionEvent is posted when a reorg happens
 type RemovedTransactionEvent struct{ Txs types.Transactions }
 
+// ChainReorgEvent is posted when a reorg happens, after it was journaled.
+type ChainReorgEvent struct{ Reorg *ReorgEntry }
+
 // RemovedLogsEvent is posted when a reorg happens
 type RemovedLogsEvent struct{ Logs []*types.Log }
 
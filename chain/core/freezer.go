@@ -0,0 +1,205 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
+)
+
+//冻结库中的表
+const (
+	freezerHashTable     = "hashes"
+	freezerHeaderTable   = "headers"
+	freezerBodiesTable   = "bodies"
+	freezerReceiptTable  = "receipts"
+	freezerDirectoryName = "ancient" //冻结库在链数据库目录中的子目录
+)
+
+//冻结库中的表以及是否压缩
+var freezerTables = map[string]bool{
+	freezerHashTable:    false,
+	freezerHeaderTable:  false,
+	freezerBodiesTable:  true,
+	freezerReceiptTable: true,
+}
+
+const (
+	freezeInterval   = time.Minute //检查是否有需要冻结的区块的间隔
+	freezeBatchLimit = 1000        //每次持有链锁时冻结的区块数量
+)
+
+var (
+	errFreezeMissing = errors.New("missing canonical block data")
+
+	freezeBlockMeter = metrics.NewMeter("chain/freezer/blocks")
+	freezeTimer      = metrics.NewTimer("chain/freezer/time")
+)
+
+//在链数据库之上打开dir目录下的冻结库，冻结的区块头、区块体以及收据可以透明地通过返回的数据库读取
+func NewFreezerDatabase(db ethdb.Database, dir string) (ethdb.Database, error) {
+	freezer, err := ethdb.NewFreezer(dir, freezerTables)
+	if err != nil {
+		return nil, err
+	}
+	return ethdb.NewFreezerDatabase(db, freezer, resolveAncient), nil
+}
+
+//返回链数据库目录中冻结库的位置
+func FreezerDir(chaindata string) string {
+	return filepath.Join(chaindata, freezerDirectoryName)
+}
+
+//将区块头、区块体以及收据的键映射到冻结库，只有区块哈希与冻结的规范区块一致时才返回数据
+func resolveAncient(ancients ethdb.AncientReader, key []byte) ([]byte, bool) {
+
+	if len(key) != 1+8+common.HashLength {
+		return nil, false
+	}
+	var table string
+	switch key[0] {
+	case headerPrefix[0]:
+		table = freezerHeaderTable
+	case bodyPrefix[0]:
+		table = freezerBodiesTable
+	case blockReceiptsPrefix[0]:
+		table = freezerReceiptTable
+	default:
+		return nil, false
+	}
+	number := binary.BigEndian.Uint64(key[1:9])
+	if number >= ancients.Ancients() {
+		return nil, false
+	}
+	hash, err := ancients.Ancient(freezerHashTable, number)
+	if err != nil || !bytes.Equal(hash, key[9:]) {
+		return nil, false
+	}
+	data, err := ancients.Ancient(table, number)
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	return data, true
+}
+
+//设置冻结区块的深度，超过该深度的规范区块从键值数据库移到冻结库，为0时不冻结
+func (bc *BlockChain) SetFreezerThreshold(threshold uint64) {
+	atomic.StoreUint64(&bc.freezeThreshold, threshold)
+}
+
+//定期冻结足够深的区块
+func (bc *BlockChain) freezeLoop() {
+	defer bc.wg.Done()
+
+	ticker := time.NewTicker(freezeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := bc.freeze(); err != nil {
+				log.Error("Failed to freeze ancient blocks", "err", err)
+			}
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+//将深度超过阈值的规范区块写入冻结库，并从键值数据库中删除
+func (bc *BlockChain) freeze() error {
+
+	store, ok := bc.chainDb.(ethdb.AncientStore)
+	threshold := atomic.LoadUint64(&bc.freezeThreshold)
+	if !ok || threshold == 0 {
+		return nil
+	}
+	for {
+		select {
+		case <-bc.quit:
+			return nil
+		default:
+		}
+		frozen, err := bc.freezeBatch(store, threshold)
+		if err != nil || frozen < freezeBatchLimit {
+			return err
+		}
+	}
+}
+
+//冻结一批区块，返回冻结的区块数量
+func (bc *BlockChain) freezeBatch(store ethdb.AncientStore, threshold uint64) (int, error) {
+
+	//持有链锁，防止冻结过程中链被回退
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	head := bc.currentBlock.NumberU64()
+	if head <= threshold {
+		return 0, nil
+	}
+	var (
+		start  = time.Now()
+		first  = store.Ancients()
+		limit  = head - threshold
+		hashes []common.Hash
+		err    error
+	)
+	for number := first; number < limit && len(hashes) < freezeBatchLimit; number++ {
+		hash := GetCanonicalHash(store, number)
+		header := GetHeaderRLP(store, hash, number)
+		body := GetBodyRLP(store, hash, number)
+		if hash == (common.Hash{}) || len(header) == 0 || len(body) == 0 {
+			err = errFreezeMissing
+			break
+		}
+		//没有收据的区块(例如快速同步之前的区块)保存空数据，读取时视为不存在
+		receipts := getBlockReceiptsRLP(store, hash, number)
+		items := map[string][]byte{
+			freezerHashTable:    hash.Bytes(),
+			freezerHeaderTable:  header,
+			freezerBodiesTable:  body,
+			freezerReceiptTable: receipts,
+		}
+		if err = store.AppendAncient(number, items); err != nil {
+			break
+		}
+		hashes = append(hashes, hash)
+	}
+	if len(hashes) == 0 {
+		return 0, err
+	}
+	//冻结数据写入磁盘之后才能从键值数据库中删除
+	if err := store.SyncAncient(); err != nil {
+		return 0, err
+	}
+	for i, hash := range hashes {
+		number := first + uint64(i)
+		//哈希到区块号的映射保留在键值数据库中
+		store.Delete(headerKey(hash, number))
+		DeleteBody(store, hash, number)
+		DeleteBlockReceipts(store, hash, number)
+	}
+	freezeBlockMeter.Mark(int64(len(hashes)))
+	freezeTimer.UpdateSince(start)
+
+	log.Info("Froze ancient blocks", "from", first, "count", len(hashes), "elapsed", common.PrettyDuration(time.Since(start)))
+	return len(hashes), err
+}
+
+//链回退到冻结区块之前时截断冻结库
+func (bc *BlockChain) truncateAncients(head uint64) error {
+	store, ok := bc.chainDb.(ethdb.AncientStore)
+	if !ok || store.Ancients() <= head+1 {
+		return nil
+	}
+	log.Warn("Truncating ancient blocks", "frozen", store.Ancients(), "head", head)
+	return store.TruncateAncients(head + 1)
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/consensus/ethash"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/core/vm"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/params"
+)
+
+//测试冻结后的区块可以透明读取，并且已经从键值数据库中删除，链回退时截断冻结库
+func TestFreezeBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ancient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb, _ := ethdb.NewMemDatabase()
+	db, err := NewFreezerDatabase(kvdb, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := (&Genesis{Config: params.TestChainConfig}).MustCommit(db)
+
+	//所有区块共享创世区块的状态，只需要写入区块数据
+	blocks := []*types.Block{genesis}
+	for i := 1; i <= 20; i++ {
+		parent := blocks[len(blocks)-1]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			Root:       genesis.Root(),
+			Difficulty: big.NewInt(1),
+			Time:       big.NewInt(int64(i)),
+		}
+		block := types.NewBlock(header, nil, nil, nil)
+		WriteBlock(db, block)
+		WriteTd(db, block.Hash(), block.NumberU64(), big.NewInt(int64(i+1)))
+		WriteBlockReceipts(db, block.Hash(), block.NumberU64(), types.Receipts{})
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		blocks = append(blocks, block)
+	}
+	head := blocks[len(blocks)-1]
+	WriteHeadBlockHash(db, head.Hash())
+	WriteHeadHeaderHash(db, head.Hash())
+
+	chain, err := NewBlockChain(db, params.TestChainConfig, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	chain.SetFreezerThreshold(5)
+	if err := chain.freeze(); err != nil {
+		t.Fatalf("failed to freeze blocks: %v", err)
+	}
+	store := db.(ethdb.AncientStore)
+	if frozen := store.Ancients(); frozen != 15 {
+		t.Fatalf("frozen blocks mismatch: have %d, want 15", frozen)
+	}
+	for _, block := range blocks {
+		number := block.NumberU64()
+		if have, _ := kvdb.Get(blockBodyKey(block.Hash(), number)); (len(have) == 0) != (number < 15) {
+			t.Errorf("block %d: body in key-value store %v", number, len(have) != 0)
+		}
+		if have := GetBlock(db, block.Hash(), number); have == nil || have.Hash() != block.Hash() {
+			t.Fatalf("block %d not readable", number)
+		}
+		if GetBlockReceipts(db, block.Hash(), number) == nil {
+			t.Errorf("block %d: receipts not readable", number)
+		}
+		if GetBlockNumber(db, block.Hash()) != number {
+			t.Errorf("block %d: hash to number mapping lost", number)
+		}
+	}
+	//其他哈希不能读取冻结的数据
+	if GetHeader(db, common.Hash{1}, 3) != nil {
+		t.Errorf("ancient header returned for unknown hash")
+	}
+	if verified, err := VerifyAncients(db, nil); err != nil || verified != 15 {
+		t.Fatalf("ancient verification failed: %d blocks, %v", verified, err)
+	}
+	//回退到冻结区块之前
+	if err := chain.SetHead(10); err != nil {
+		t.Fatal(err)
+	}
+	if frozen := store.Ancients(); frozen != 11 {
+		t.Fatalf("frozen blocks after rewind: have %d, want 11", frozen)
+	}
+	if chain.CurrentBlock().NumberU64() != 10 {
+		t.Fatalf("head mismatch after rewind: %d", chain.CurrentBlock().NumberU64())
+	}
+	//冻结的区块与规范链不一致
+	WriteCanonicalHash(db, common.Hash{1}, 7)
+	if verified, err := VerifyAncients(db, nil); err == nil || verified != 7 {
+		t.Fatalf("corruption not detected: %d blocks, %v", verified, err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"strconv"
 	"strings"
 	_ "time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/common/math"
 	"github.com/Bokerchain/Boker/chain/core/state"
 	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/log"
 	"github.com/Bokerchain/Boker/chain/params"
@@ -25,8 +27,23 @@ import (
 
 //go:generate gencodec -type Genesis -field-override genesisSpecMarshaling -out gen_genesis.go
 //go:generate gencodec -type GenesisAccount -field-override genesisAccountMarshaling -out gen_genesis_account.go
-
-var errGenesisNoConfig = errors.New("genesis has no chain configuration")
+//go:generate gencodec -type GenesisContract -field-override genesisContractMarshaling -out gen_genesis_contract.go
+
+var (
+	errGenesisNoConfig           = errors.New("genesis has no chain configuration")
+	errGenesisContractType       = errors.New("genesis contract must be a system or personal contract")
+	errGenesisContractCode       = errors.New("genesis contract has no code")
+	errGenesisContractDuplicated = errors.New("genesis contract declared twice")
+	errGenesisContractAlloc      = errors.New("genesis contract address also declared in alloc")
+	errGenesisSystemContracts    = errors.New("genesis declares more than one system contract")
+	errGenesisContractCodeHash   = errors.New("genesis contract code does not match its code hash")
+
+	errGenesisNoValidators        = errors.New("genesis declares no validators, list the initial block producers in config.dpos.validators")
+	errGenesisZeroValidator       = errors.New("genesis declares the zero address as validator")
+	errGenesisDuplicatedValidator = errors.New("genesis declares a validator twice")
+	errGenesisCoinbaseValidator   = errors.New("genesis config.coinbase is not one of the declared validators")
+	errGenesisNoChainId           = errors.New("genesis declares no chainId, blocks and transactions can't be signed without it")
+)
 
 //创世区块配置定义
 type Genesis struct {
@@ -39,6 +56,7 @@ type Genesis struct {
 	Mixhash    common.Hash         `json:"mixHash"`
 	Coinbase   common.Address      `json:"coinbase"`
 	Alloc      GenesisAlloc        `json:"alloc"      gencodec:"required"`
+	Contracts  []GenesisContract   `json:"contracts,omitempty"` //创世时预先部署并注册的基础合约
 
 	//这些字段用于一致性测试，请不要使用它们在实际的创世块中.
 	Number     uint64      `json:"number"`
@@ -70,6 +88,17 @@ type GenesisAccount struct {
 	PrivateKey []byte                      `json:"secretKey,omitempty"`         //用户私钥
 }
 
+//创世区块中预先部署的基础合约，部署后直接登记到播客链的基础合约表中
+type GenesisContract struct {
+	Address  common.Address              `json:"address" gencodec:"required"` //合约地址
+	Type     protocol.ContractType       `json:"type"    gencodec:"required"` //基础合约类型（系统基础合约或个人基础合约）
+	Code     []byte                      `json:"code"    gencodec:"required"` //合约的运行时字节码
+	CodeHash common.Hash                 `json:"codeHash,omitempty"`          //期望的字节码Hash，设置后必须与字节码一致
+	Storage  map[common.Hash]common.Hash `json:"storage,omitempty"`           //合约的初始存储
+	Balance  *big.Int                    `json:"balance,omitempty"`           //合约的初始余额
+	Abi      string                      `json:"abi,omitempty"`               //合约abi
+}
+
 // field type overrides for gencodec
 type genesisSpecMarshaling struct {
 	Nonce      math.HexOrDecimal64
@@ -90,6 +119,12 @@ type genesisAccountMarshaling struct {
 	PrivateKey hexutil.Bytes
 }
 
+type genesisContractMarshaling struct {
+	Code    hexutil.Bytes
+	Storage map[storageJSON]storageJSON
+	Balance *math.HexOrDecimal256
+}
+
 // storageJSON represents a 256 bit byte array, but allows less than 256 bits when
 // unmarshaling from hex.
 type storageJSON common.Hash
@@ -143,6 +178,14 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	if genesis != nil && genesis.Config == nil {
 		return params.DposChainConfig, common.Hash{}, errGenesisNoConfig
 	}
+	if genesis != nil {
+		if err := genesis.verifyValidators(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+		if err := genesis.verifyContracts(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
 
 	//如果没有存储的genesis块，只需提交新块
 	stored := GetCanonicalHash(db, 0)
@@ -239,6 +282,16 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB, *trie.Trie, *trie.Tri
 			statedb.SetState(addr, key, value)
 		}
 	}
+	//部署基础合约
+	for _, contract := range g.Contracts {
+		if contract.Balance != nil {
+			statedb.AddBalance(contract.Address, contract.Balance)
+		}
+		statedb.SetCode(contract.Address, contract.Code)
+		for key, value := range contract.Storage {
+			statedb.SetState(contract.Address, key, value)
+		}
+	}
 	root := statedb.IntermediateRoot(false)
 
 	//添加Dpos配置
@@ -247,9 +300,9 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB, *trie.Trie, *trie.Tri
 	log.Info("ToProto", "root", dposContextProto.Root().String())
 
 	//添加播客链的设置
-	singleTrie, contractsTrie, abiTrie, err := initBoker(db)
+	singleTrie, contractsTrie, abiTrie, err := initBoker(db, g.Contracts)
 	if err != nil {
-		fmt.Errorf("initGenesisBoker error")
+		log.Error("initGenesisBoker error", "err", err)
 		return nil, statedb, nil, nil, nil
 	}
 	bokerProto := protocol.ToBokerProto(singleTrie.Hash(), contractsTrie.Hash(), abiTrie.Hash())
@@ -288,6 +341,12 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB, *trie.Trie, *trie.Tri
 // The block is committed as the canonical head block.
 func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
 
+	if err := g.verifyValidators(); err != nil {
+		return nil, err
+	}
+	if err := g.verifyContracts(); err != nil {
+		return nil, err
+	}
 	block, statedb, singleTrie, contractsTrie, abiTrie := g.ToBlock()
 
 	// add dposcontext
@@ -371,6 +430,60 @@ func decodePrealloc(data string) GenesisAlloc {
 	return ga
 }
 
+//校验创世配置能够产生可以出块的DPOS链：必须声明验证者并且配置前后一致，基础合约的字节码必须与声明的Hash一致，
+//geth init在写入创世区块前调用，避免初始化出一条永远无法出块的链
+func (g *Genesis) VerifyDpos() error {
+
+	if g.Config == nil {
+		return errGenesisNoConfig
+	}
+	if g.Config.ChainId == nil {
+		return errGenesisNoChainId
+	}
+	if g.Config.Dpos == nil || len(g.Config.Dpos.Validators) == 0 {
+		return errGenesisNoValidators
+	}
+	if err := g.verifyValidators(); err != nil {
+		return err
+	}
+	if (g.Config.Coinbase != common.Address{}) && !containsAddress(g.Config.Dpos.Validators, g.Config.Coinbase) {
+		return fmt.Errorf("%v: %x", errGenesisCoinbaseValidator, g.Config.Coinbase)
+	}
+	return g.verifyContracts()
+}
+
+//校验创世配置中声明的验证者：数量不能超过上限，不能有零地址或重复的地址
+func (g *Genesis) verifyValidators() error {
+
+	if g.Config == nil || g.Config.Dpos == nil {
+		return nil
+	}
+	validators := g.Config.Dpos.Validators
+	if len(validators) > protocol.MaxValidatorSize {
+		return fmt.Errorf("genesis declares %d validators, at most %d are supported", len(validators), protocol.MaxValidatorSize)
+	}
+	seen := make(map[common.Address]bool, len(validators))
+	for _, validator := range validators {
+		if (validator == common.Address{}) {
+			return errGenesisZeroValidator
+		}
+		if seen[validator] {
+			return fmt.Errorf("%v: %x", errGenesisDuplicatedValidator, validator)
+		}
+		seen[validator] = true
+	}
+	return nil
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
 //DPOS的初始化设置
 func initGenesisDposContext(g *Genesis, db ethdb.Database) *types.DposContext {
 
@@ -383,6 +496,16 @@ func initGenesisDposContext(g *Genesis, db ethdb.Database) *types.DposContext {
 	var validators []common.Address = make([]common.Address, 0)
 	dc.SetEpochTrie(validators)
 
+	//写入创世配置中声明的验证者，没有声明时由特殊账号出块
+	if g.Config != nil && g.Config.Dpos != nil {
+		for _, validator := range g.Config.Dpos.Validators {
+			if err := dc.InsertValidator(validator, protocol.SetValidatorVotes); err != nil {
+				log.Error("Failed to insert genesis validator", "validator", validator, "err", err)
+				return nil
+			}
+		}
+	}
+
 	var producers []common.Address
 	validatorsRLP := dc.EpochTrie().Get(protocol.ValidatorsKey)
 	if err := rlp.DecodeBytes(validatorsRLP, &producers); err != nil {
@@ -395,7 +518,7 @@ func initGenesisDposContext(g *Genesis, db ethdb.Database) *types.DposContext {
 }
 
 //****创建播客链相关Hash树信息****//
-func initBoker(db ethdb.Database) (*trie.Trie, *trie.Trie, *trie.Trie, error) {
+func initBoker(db ethdb.Database, bases []GenesisContract) (*trie.Trie, *trie.Trie, *trie.Trie, error) {
 
 	log.Info("****initBoker****")
 
@@ -407,8 +530,8 @@ func initBoker(db ethdb.Database) (*trie.Trie, *trie.Trie, *trie.Trie, error) {
 	if singleTrie, err = trie.NewTrieWithPrefix(root, protocol.SinglePrefix, db); err != nil {
 		return nil, nil, nil, err
 	}
-	var bases []common.Address = make([]common.Address, 0)
-	basesRLP, err := rlp.EncodeToBytes(bases)
+	var empty []common.Address = make([]common.Address, 0)
+	basesRLP, err := rlp.EncodeToBytes(empty)
 	if err != nil {
 		log.Error("failed to encode contract to rlp", "error", err)
 		return nil, nil, nil, err
@@ -420,6 +543,9 @@ func initBoker(db ethdb.Database) (*trie.Trie, *trie.Trie, *trie.Trie, error) {
 		return nil, nil, nil, err
 	}
 	var contracts []common.Address = make([]common.Address, 0)
+	for _, base := range bases {
+		contracts = append(contracts, base.Address)
+	}
 	contractsRLP, err := rlp.EncodeToBytes(contracts)
 	if err != nil {
 		log.Error("failed to encode contracts to rlp", "error", err)
@@ -439,9 +565,50 @@ func initBoker(db ethdb.Database) (*trie.Trie, *trie.Trie, *trie.Trie, error) {
 	}
 	abiTrie.Update(protocol.AbiPrefix, abiRLP)
 
+	//登记预先部署的基础合约，与SetContract写入的内容一致
+	for _, base := range bases {
+		singleTrie.Update(base.Address.Bytes(), []byte(strconv.Itoa(int(base.Type))))
+		abiTrie.Update(base.Address.Bytes(), []byte(base.Abi))
+		log.Info("Register genesis base contract", "address", base.Address, "type", base.Type)
+	}
 	return singleTrie, contractsTrie, abiTrie, nil
 }
 
+//校验预先部署的基础合约：类型必须是系统或个人基础合约，地址不能重复，最多一个系统基础合约
+func (g *Genesis) verifyContracts() error {
+
+	seen := make(map[common.Address]bool, len(g.Contracts))
+	system := 0
+	for _, contract := range g.Contracts {
+		switch contract.Type {
+		case protocol.SystemContract:
+			system++
+		case protocol.PersonalContract:
+		default:
+			return fmt.Errorf("%v: %x", errGenesisContractType, contract.Address)
+		}
+		if len(contract.Code) == 0 {
+			return fmt.Errorf("%v: %x", errGenesisContractCode, contract.Address)
+		}
+		if (contract.CodeHash != common.Hash{}) {
+			if hash := crypto.Keccak256Hash(contract.Code); hash != contract.CodeHash {
+				return fmt.Errorf("%v: %x (have %x, want %x)", errGenesisContractCodeHash, contract.Address, hash, contract.CodeHash)
+			}
+		}
+		if seen[contract.Address] {
+			return fmt.Errorf("%v: %x", errGenesisContractDuplicated, contract.Address)
+		}
+		if _, ok := g.Alloc[contract.Address]; ok {
+			return fmt.Errorf("%v: %x", errGenesisContractAlloc, contract.Address)
+		}
+		seen[contract.Address] = true
+	}
+	if system > 1 {
+		return errGenesisSystemContracts
+	}
+	return nil
+}
+
 //****创建播客链相关Hash树信息****//
 func commitBoker(singleTrie *trie.Trie, contractsTrie *trie.Trie, abiTrie *trie.Trie, db ethdb.Database) error {
 
@@ -19,20 +19,21 @@ package core
 import (
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
-	"github.com/Bokerchain/Boker/chain/consensus/ethash"
-	"github.com/Bokerchain/Boker/chain/core/vm"
+	"github.com/Bokerchain/Boker/chain/core/state"
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/params"
+	"github.com/Bokerchain/Boker/chain/trie"
 )
 
 func TestSetupGenesis(t *testing.T) {
 	var (
-		customghash = common.HexToHash("0xa4813c30e66d854572733941743e6736f20c0487d32c6b2fe1233946b036f6f5")
-		customg     = Genesis{
+		customg = Genesis{
 			Config: &params.ChainConfig{HomesteadBlock: big.NewInt(3)},
 			Alloc: GenesisAlloc{
 				{1}: {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{{1}: {1}}},
@@ -41,6 +42,11 @@ func TestSetupGenesis(t *testing.T) {
 		oldcustomg = customg
 	)
 	oldcustomg.Config = &params.ChainConfig{HomesteadBlock: big.NewInt(2)}
+
+	//创世块包含Dpos和播客链的上下文，哈希与以太坊不同，直接由创世配置计算
+	customgblock, _, _, _, _ := customg.ToBlock()
+	customghash := customgblock.Hash()
+
 	tests := []struct {
 		name       string
 		fn         func(ethdb.Database) (*params.ChainConfig, common.Hash, error)
@@ -72,11 +78,12 @@ func TestSetupGenesis(t *testing.T) {
 				// Commit the 'old' genesis block with Homestead transition at #2.
 				// Advance to block #4, past the homestead transition block of customg.
 				genesis := oldcustomg.MustCommit(db)
-				bc, _ := NewBlockChain(db, oldcustomg.Config, ethash.NewFullFaker(), vm.Config{})
-				defer bc.Stop()
-				bc.SetValidator(bproc{})
-				bc.InsertChain(makeBlockChainWithDiff(genesis, []int{2, 3, 4, 5}, 0))
-				bc.CurrentBlock()
+				blocks, _ := GenerateChain(oldcustomg.Config, genesis, db, 4, nil, nil)
+				for _, block := range blocks {
+					WriteHeader(db, block.Header())
+					WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+				}
+				WriteHeadHeaderHash(db, blocks[len(blocks)-1].Hash())
 				// This should return a compatibility error.
 				return SetupGenesisBlock(db, &customg)
 			},
@@ -113,3 +120,49 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+func TestGenesisContracts(t *testing.T) {
+	system := GenesisContract{
+		Address: common.Address{0x10},
+		Type:    protocol.SystemContract,
+		Code:    []byte{0x60, 0x01},
+		Storage: map[common.Hash]common.Hash{{1}: {2}},
+		Abi:     "[]",
+	}
+	genesis := &Genesis{Config: params.DposChainConfig, Contracts: []GenesisContract{system}}
+
+	db, _ := ethdb.NewMemDatabase()
+	block, err := genesis.Commit(db)
+	if err != nil {
+		t.Fatalf("failed to commit genesis: %v", err)
+	}
+	statedb, _ := state.New(block.Root(), state.NewDatabase(db))
+	if code := statedb.GetCode(system.Address); !reflect.DeepEqual(code, system.Code) {
+		t.Errorf("contract code mismatch: have %x, want %x", code, system.Code)
+	}
+	if value := statedb.GetState(system.Address, common.Hash{1}); value != (common.Hash{2}) {
+		t.Errorf("contract storage mismatch: have %x, want %x", value, common.Hash{2})
+	}
+	single, _ := trie.NewTrieWithPrefix(block.Header().BokerProto.SingleHash, protocol.SinglePrefix, db)
+	if kind := single.Get(system.Address.Bytes()); string(kind) != "1" {
+		t.Errorf("contract type mismatch: have %q, want %q", kind, "1")
+	}
+
+	// Invalid declarations must be rejected before anything is written
+	invalid := []struct {
+		contracts []GenesisContract
+		err       error
+	}{
+		{[]GenesisContract{{Address: common.Address{0x11}, Type: protocol.BinaryContract, Code: []byte{1}}}, errGenesisContractType},
+		{[]GenesisContract{{Address: common.Address{0x11}, Type: protocol.PersonalContract}}, errGenesisContractCode},
+		{[]GenesisContract{system, system}, errGenesisContractDuplicated},
+		{[]GenesisContract{system, {Address: common.Address{0x11}, Type: protocol.SystemContract, Code: []byte{1}}}, errGenesisSystemContracts},
+	}
+	for i, tt := range invalid {
+		genesis := &Genesis{Config: params.DposChainConfig, Contracts: tt.contracts}
+		db, _ := ethdb.NewMemDatabase()
+		if _, err := genesis.Commit(db); err == nil || !strings.HasPrefix(err.Error(), tt.err.Error()) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/core/vm"
+)
+
+var ErrInternalTxNotIndexed = errors.New("internal transactions of the block not indexed") //区块执行时没有开启内部交易索引
+
+//开启内部交易索引，之后执行的区块会追踪合约发起的转账，开启之前的区块不会重新执行
+func (bc *BlockChain) StartInternalTxIndex() {
+	atomic.StoreInt32(&bc.internalTxIndex, 1)
+}
+
+//判断是否开启了内部交易索引
+func (bc *BlockChain) InternalTxIndexEnabled() bool {
+	return atomic.LoadInt32(&bc.internalTxIndex) == 1
+}
+
+//创建执行区块时使用的内部交易追踪器，没有开启内部交易索引或者虚拟机已经配置了其它追踪器时返回nil
+func (bc *BlockChain) NewInternalTxTracer(statedb *state.StateDB) *InternalTxTracer {
+
+	if !bc.InternalTxIndexEnabled() || bc.vmConfig.Debug {
+		return nil
+	}
+	return &InternalTxTracer{statedb: statedb}
+}
+
+//追踪交易执行中合约发起的转账，转账按状态数据库中正在执行的交易序号记录
+type InternalTxTracer struct {
+	statedb   *state.StateDB
+	transfers []*InternalTransfer
+	calls     []*internalCall //等待返回结果的调用
+}
+
+//等待返回结果的调用，mark为发起调用时已经记录的转账数量，调用失败时之后记录的转账都被回滚
+type internalCall struct {
+	depth    int
+	mark     int
+	create   bool
+	transfer *InternalTransfer
+}
+
+//开启追踪时使用的虚拟机配置，追踪器为nil时返回原配置
+func (t *InternalTxTracer) VMConfig(cfg vm.Config) vm.Config {
+
+	if t != nil {
+		cfg.Debug, cfg.Tracer = true, t
+	}
+	return cfg
+}
+
+//交易开始执行，清除上一笔交易中没有返回的调用
+func (t *InternalTxTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.calls = t.calls[:0]
+	return nil
+}
+
+//根据每一步的指令记录转账，只处理发起调用和自毁的指令
+func (t *InternalTxTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+
+	//回到发起调用的深度时栈顶是调用结果，更深的调用没有返回结果说明执行中止
+	for len(t.calls) > 0 && t.calls[len(t.calls)-1].depth >= depth {
+		call := t.calls[len(t.calls)-1]
+		t.calls = t.calls[:len(t.calls)-1]
+
+		result := common.Big0
+		if call.depth == depth && len(stack.Data()) > 0 {
+			result = stack.Back(0)
+		}
+		if result.Sign() == 0 {
+			t.transfers = t.transfers[:call.mark]
+		} else if call.create && call.transfer != nil {
+			call.transfer.To = common.BigToAddress(result)
+		}
+	}
+	if err != nil {
+		return nil
+	}
+
+	switch op {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL, vm.CREATE, vm.CREATE2:
+		call := &internalCall{depth: depth, mark: len(t.transfers), create: op == vm.CREATE || op == vm.CREATE2}
+
+		//CALLCODE的转账接收方是合约自己，DELEGATECALL和STATICCALL不能转账
+		var value *big.Int
+		switch op {
+		case vm.CALL:
+			value = stack.Back(2)
+		case vm.CREATE, vm.CREATE2:
+			value = stack.Back(0)
+		}
+		if value != nil && value.Sign() > 0 {
+			call.transfer = t.newTransfer(op, contract.Address(), value, depth)
+			if op == vm.CALL {
+				call.transfer.To = common.BigToAddress(stack.Back(1))
+			}
+			t.transfers = append(t.transfers, call.transfer)
+		}
+		t.calls = append(t.calls, call)
+
+	case vm.SELFDESTRUCT:
+		if balance := env.StateDB.GetBalance(contract.Address()); balance.Sign() > 0 {
+			transfer := t.newTransfer(op, contract.Address(), balance, depth)
+			transfer.To = common.BigToAddress(stack.Back(0))
+			t.transfers = append(t.transfers, transfer)
+		}
+	}
+	return nil
+}
+
+func (t *InternalTxTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+func (t *InternalTxTracer) newTransfer(op vm.OpCode, from common.Address, value *big.Int, depth int) *InternalTransfer {
+	return &InternalTransfer{
+		TxIndex: uint64(t.statedb.TxIndex()),
+		Type:    op.String(),
+		From:    from,
+		Value:   new(big.Int).Set(value),
+		Depth:   uint64(depth),
+	}
+}
+
+//丢弃指定序号的交易记录的转账，打包区块时交易执行失败被回滚后调用
+func (t *InternalTxTracer) Discard(txIndex int) {
+
+	if t == nil {
+		return
+	}
+	for len(t.transfers) > 0 && t.transfers[len(t.transfers)-1].TxIndex >= uint64(txIndex) {
+		t.transfers = t.transfers[:len(t.transfers)-1]
+	}
+	t.calls = t.calls[:0]
+}
+
+//得到执行成功的交易中合约发起的转账，执行失败的交易的状态被回滚，其中的转账不返回
+func (t *InternalTxTracer) Transfers(receipts types.Receipts) []*InternalTransfer {
+
+	transfers := make([]*InternalTransfer, 0, len(t.transfers))
+	for _, transfer := range t.transfers {
+		if transfer.TxIndex < uint64(len(receipts)) && receipts[transfer.TxIndex].Status != types.ReceiptStatusFailed {
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers
+}
@@ -0,0 +1,317 @@
+package core
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/core/vm"
+	"github.com/Bokerchain/Boker/chain/metrics"
+)
+
+//并行执行的交易中结果被直接合并以及因冲突而串行重新执行的交易数量
+var (
+	parallelMergedMeter   = metrics.NewMeter("chain/parallel/merged")
+	parallelConflictMeter = metrics.NewMeter("chain/parallel/conflicts")
+)
+
+//记录交易访问的账户，并标记创建合约和自毁等无法从访问集合得到完整写入集合的操作
+type parallelTracer struct {
+	*vm.AccessListTracer
+	barrier bool
+}
+
+func newParallelTracer() *parallelTracer {
+	return &parallelTracer{AccessListTracer: vm.NewAccessListTracer()}
+}
+
+func (t *parallelTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	switch op {
+	case vm.CREATE, vm.CREATE2, vm.SELFDESTRUCT:
+		t.barrier = true
+	}
+	return t.AccessListTracer.CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+}
+
+//返回交易访问的账户集合
+func (t *parallelTracer) accounts(msg types.Message) map[common.Address]struct{} {
+	set := map[common.Address]struct{}{msg.From(): {}}
+	if msg.To() != nil {
+		set[*msg.To()] = struct{}{}
+	}
+	for _, tuple := range t.AccessList() {
+		set[tuple.Address] = struct{}{}
+	}
+	return set
+}
+
+//交易在区块执行前状态的拷贝上预先执行的结果
+type speculativeResult struct {
+	msg      types.Message
+	statedb  *state.StateDB
+	accounts map[common.Address]struct{}
+	slots    vm.AccessList
+	barrier  bool
+	extra    []byte
+	gas      *big.Int
+	failed   bool
+	err      error
+}
+
+//设置并行执行交易的线程数量，小于2时串行执行区块中的交易
+func (p *StateProcessor) SetParallel(workers int) { p.parallel = workers }
+
+//判断交易能否并行执行，只有调用普通合约或者普通转账的交易可以并行执行，创建合约以及播客链的基础交易都是串行执行
+func (p *StateProcessor) parallelizable(tx *types.Transaction) bool {
+
+	if tx.Type() != protocol.Binary || tx.To() == nil {
+		return false
+	}
+	if p.boker != nil {
+		if contractType, err := p.boker.GetContract(*tx.To()); err == nil && contractType > protocol.BinaryContract {
+			return false
+		}
+	}
+	return true
+}
+
+//并行执行区块中的交易：连续的可并行交易在执行前状态的拷贝上同时预先执行，和前面交易访问的账户不冲突的交易直接合并结果，冲突的交易串行重新执行
+func (p *StateProcessor) processParallel(block *types.Block, statedb *state.StateDB, cfg vm.Config, gp *GasPool, usedGas *big.Int) (types.Receipts, []*types.Log, error) {
+
+	var (
+		receipts types.Receipts
+		allLogs  []*types.Log
+		header   = block.Header()
+		txs      = block.Transactions()
+	)
+	for i := 0; i < len(txs); {
+
+		//串行执行不能并行的交易
+		if !p.parallelizable(txs[i]) {
+			statedb.Prepare(txs[i].Hash(), block.Hash(), i)
+			receipt, _, err := ApplyTransaction(p.config, block.DposCtx(), p.bc, nil, gp, statedb, header, txs[i], usedGas, cfg, p.boker)
+			if err != nil {
+				return nil, nil, err
+			}
+			receipts = append(receipts, receipt)
+			allLogs = append(allLogs, receipt.Logs...)
+			i++
+			continue
+		}
+
+		//找到连续的可并行交易
+		end := i + 1
+		for end < len(txs) && p.parallelizable(txs[end]) {
+			end++
+		}
+		runReceipts, err := p.processRun(block, statedb, cfg, gp, usedGas, i, end)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, receipt := range runReceipts {
+			receipts = append(receipts, receipt)
+			allLogs = append(allLogs, receipt.Logs...)
+		}
+		i = end
+	}
+	return receipts, allLogs, nil
+}
+
+//执行区块中从start到end(不包括)的一组连续可并行交易
+func (p *StateProcessor) processRun(block *types.Block, statedb *state.StateDB, cfg vm.Config, gp *GasPool, usedGas *big.Int, start, end int) (types.Receipts, error) {
+
+	var (
+		header  = block.Header()
+		txs     = block.Transactions()[start:end]
+		results = make([]*speculativeResult, len(txs))
+	)
+	//预先执行的交易都从相同的状态开始，出块者余额的增加量就是交易的手续费
+	coinbase, _ := p.engine.Author(header)
+	prefee := new(big.Int).Set(statedb.GetBalance(coinbase))
+
+	//在执行前状态的拷贝上同时预先执行所有交易
+	copies := make([]*state.StateDB, len(txs))
+	for i := range txs {
+		copies[i] = statedb.Copy()
+	}
+	var (
+		wg   sync.WaitGroup
+		next = make(chan int, len(txs))
+	)
+	for i := range txs {
+		next <- i
+	}
+	close(next)
+	for w := 0; w < p.parallel && w < len(txs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = p.speculate(block, copies[i], cfg, txs[i], start+i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	//按区块中的顺序合并预先执行的结果
+	var (
+		receipts types.Receipts
+		written  = make(map[common.Address]struct{}) //前面的交易访问过的账户
+		barrier  bool                                //前面串行执行的交易创建或者销毁了账户，后面的交易只能串行执行
+	)
+	for i, tx := range txs {
+
+		result := results[i]
+		if !barrier && p.mergeable(result, statedb, written, coinbase) {
+
+			receipt, err := p.merge(block, statedb, cfg, gp, usedGas, tx, start+i, result, coinbase, prefee)
+			if err != nil {
+				return nil, err
+			}
+			parallelMergedMeter.Mark(1)
+			receipts = append(receipts, receipt)
+			for addr := range result.accounts {
+				written[addr] = struct{}{}
+			}
+			continue
+		}
+
+		//和前面的交易冲突，在当前状态上串行重新执行并记录访问的账户
+		parallelConflictMeter.Mark(1)
+
+		tracer := newParallelTracer()
+		serialCfg := cfg
+		serialCfg.Debug, serialCfg.Tracer = true, tracer
+
+		statedb.Prepare(tx.Hash(), block.Hash(), start+i)
+		receipt, _, err := ApplyTransaction(p.config, block.DposCtx(), p.bc, nil, gp, statedb, header, tx, usedGas, serialCfg, p.boker)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+
+		msg, _ := tx.AsMessage(types.MakeSigner(p.config, header.Number))
+		for addr := range tracer.accounts(msg) {
+			written[addr] = struct{}{}
+		}
+		barrier = barrier || tracer.barrier
+	}
+	return receipts, nil
+}
+
+//在执行前状态的拷贝上预先执行交易，并记录交易访问的账户
+func (p *StateProcessor) speculate(block *types.Block, statedb *state.StateDB, cfg vm.Config, tx *types.Transaction, index int) *speculativeResult {
+
+	header := block.Header()
+	msg, err := tx.AsMessage(types.MakeSigner(p.config, header.Number))
+	if err != nil {
+		return &speculativeResult{err: err}
+	}
+	tracer := newParallelTracer()
+	cfg.Debug, cfg.Tracer = true, tracer
+
+	//预先执行时不能访问播客链接口，调用播客链合约的交易已经被排除在并行执行之外
+	statedb.Prepare(tx.Hash(), block.Hash(), index)
+	vmenv := vm.NewEVM(NewEVMContext(msg, header, p.bc, nil), statedb, p.config, cfg)
+	_, extra, gas, failed, err := BinaryMessage(vmenv, msg, new(GasPool).AddGas(header.GasLimit), nil)
+	if err != nil {
+		return &speculativeResult{err: err}
+	}
+	statedb.Finalise(true)
+
+	return &speculativeResult{
+		msg:      msg,
+		statedb:  statedb,
+		accounts: tracer.accounts(msg),
+		slots:    tracer.AccessList(),
+		barrier:  tracer.barrier,
+		extra:    extra,
+		gas:      gas,
+		failed:   failed,
+	}
+}
+
+//判断预先执行的结果能否直接合并：执行成功、没有创建或销毁账户、没有访问出块者账户以及前面交易访问过的账户
+func (p *StateProcessor) mergeable(result *speculativeResult, statedb *state.StateDB, written map[common.Address]struct{}, coinbase common.Address) bool {
+
+	if result.err != nil || result.barrier {
+		return false
+	}
+	if _, ok := result.accounts[coinbase]; ok {
+		return false
+	}
+	for addr := range result.accounts {
+		if _, ok := written[addr]; ok {
+			return false
+		}
+		//空账户在执行中被删除
+		if !result.statedb.Exist(addr) && statedb.Exist(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+//将预先执行的结果写入当前状态并生成交易回执
+func (p *StateProcessor) merge(block *types.Block, statedb *state.StateDB, cfg vm.Config, gp *GasPool, usedGas *big.Int, tx *types.Transaction, index int, result *speculativeResult, coinbase common.Address, prefee *big.Int) (*types.Receipt, error) {
+
+	header := block.Header()
+
+	//区块的Gas限制按交易顺序检查
+	if err := gp.SubGas(result.msg.Gas()); err != nil {
+		return nil, err
+	}
+	gp.AddGas(new(big.Int).Sub(result.msg.Gas(), result.gas))
+
+	//写入交易访问的账户的余额、nonce、代码以及存储
+	spec := result.statedb
+	for addr := range result.accounts {
+		if !spec.Exist(addr) {
+			continue
+		}
+		if balance := spec.GetBalance(addr); balance.Cmp(statedb.GetBalance(addr)) != 0 {
+			statedb.SetBalance(addr, balance)
+		}
+		if nonce := spec.GetNonce(addr); nonce != statedb.GetNonce(addr) {
+			statedb.SetNonce(addr, nonce)
+		}
+		if spec.GetCodeHash(addr) != statedb.GetCodeHash(addr) {
+			statedb.SetCode(addr, spec.GetCode(addr))
+		}
+	}
+	for _, tuple := range result.slots {
+		for _, key := range tuple.StorageKeys {
+			if value := spec.GetState(tuple.Address, key); value != statedb.GetState(tuple.Address, key) {
+				statedb.SetState(tuple.Address, key, value)
+			}
+		}
+	}
+	statedb.AddBalance(coinbase, new(big.Int).Sub(spec.GetBalance(coinbase), prefee))
+
+	//按交易在区块中的位置重新生成日志
+	statedb.Prepare(tx.Hash(), block.Hash(), index)
+	for _, l := range spec.GetLogs(tx.Hash()) {
+		cpy := *l
+		statedb.AddLog(&cpy)
+	}
+	if cfg.EnablePreimageRecording {
+		for hash, preimage := range spec.Preimages() {
+			statedb.AddPreimage(hash, preimage)
+		}
+	}
+	statedb.Finalise(true)
+	tx.SetExtra(result.extra)
+
+	usedGas.Add(usedGas, result.gas)
+	receipt := types.NewReceipt(nil, result.failed, usedGas)
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = new(big.Int).Set(result.gas)
+	receipt.Logs = statedb.GetLogs(tx.Hash())
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	setReceiptMeta(receipt, tx, header)
+
+	return receipt, nil
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/consensus/ethash"
+	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/core/vm"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/params"
+)
+
+//测试并行执行的结果和串行执行完全一致，包括冲突的交易、合约存储以及日志
+func TestParallelProcess(t *testing.T) {
+
+	var (
+		db, _    = ethdb.NewMemDatabase()
+		coinbase = common.HexToAddress("0xc0ffee")
+		contract = common.HexToAddress("0xc0de")
+		keys     = make([]*ecdsa.PrivateKey, 6)
+		alloc    = GenesisAlloc{
+			//CALLER PUSH1 0 SSTORE PUSH1 0 PUSH1 0 LOG0 STOP
+			contract: {Code: common.Hex2Bytes("3360005560006000a000"), Balance: big.NewInt(0)},
+		}
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = GenesisAccount{Balance: big.NewInt(1000000000000000000)}
+	}
+	genesis := (&Genesis{Config: params.TestChainConfig, Alloc: alloc, GasLimit: 10000000}).MustCommit(db)
+	chain, err := NewBlockChain(db, params.TestChainConfig, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		Time:       big.NewInt(1),
+		Difficulty: big.NewInt(1),
+		Coinbase:   coinbase,
+	}
+	signer := types.MakeSigner(params.TestChainConfig, header.Number)
+	send := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(protocol.Binary, nonce, to, big.NewInt(1000), big.NewInt(100000), big.NewInt(1), nil), signer, key)
+		return tx
+	}
+	txs := types.Transactions{
+		send(keys[0], 0, common.HexToAddress("0x01")),
+		send(keys[1], 0, contract),
+		send(keys[2], 0, common.HexToAddress("0x01")), //和第一个交易冲突
+		send(keys[3], 0, contract),                    //和第二个交易冲突
+		send(keys[4], 0, common.HexToAddress("0x02")),
+		send(keys[5], 0, coinbase),                    //访问出块者账户
+		send(keys[0], 1, common.HexToAddress("0x03")), //同一个发送者的第二个交易
+	}
+	block := types.NewBlock(header, txs, nil, nil)
+
+	//串行执行
+	serial, _ := state.New(genesis.Root(), state.NewDatabase(db))
+	var (
+		serialGas      = new(big.Int)
+		serialReceipts types.Receipts
+		gp             = new(GasPool).AddGas(header.GasLimit)
+	)
+	for i, tx := range txs {
+		serial.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, _, err := ApplyTransaction(params.TestChainConfig, nil, chain, nil, gp, serial, header, tx, serialGas, vm.Config{}, nil)
+		if err != nil {
+			t.Fatalf("tx %d: serial execution failed: %v", i, err)
+		}
+		serialReceipts = append(serialReceipts, receipt)
+	}
+
+	//并行执行
+	processor := NewStateProcessor(params.TestChainConfig, chain, ethash.NewFaker())
+	processor.SetParallel(4)
+
+	parallel, _ := state.New(genesis.Root(), state.NewDatabase(db))
+	parallelGas := new(big.Int)
+	receipts, logs, err := processor.processParallel(block, parallel, vm.Config{}, new(GasPool).AddGas(header.GasLimit), parallelGas)
+	if err != nil {
+		t.Fatalf("parallel execution failed: %v", err)
+	}
+
+	if have, want := parallel.IntermediateRoot(true), serial.IntermediateRoot(true); have != want {
+		t.Errorf("state root mismatch: have %x, want %x", have, want)
+	}
+	if parallelGas.Cmp(serialGas) != 0 {
+		t.Errorf("used gas mismatch: have %v, want %v", parallelGas, serialGas)
+	}
+	if types.DeriveSha(receipts) != types.DeriveSha(serialReceipts) {
+		t.Errorf("receipts mismatch")
+	}
+	if len(logs) != 2 {
+		t.Fatalf("log count mismatch: have %d, want 2", len(logs))
+	}
+	for i, l := range logs {
+		if l.Index != uint(i) || l.TxHash != txs[2*i+1].Hash() || l.TxIndex != uint(2*i+1) {
+			t.Errorf("log %d: position mismatch: index %d, tx %d", i, l.Index, l.TxIndex)
+		}
+	}
+}
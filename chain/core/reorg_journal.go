@@ -0,0 +1,81 @@
+package core
+
+import (
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/event"
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+const reorgJournalLimit = 1024 //重组日志最多保留的记录数量，超出后删除最早的记录
+
+//重组中涉及的区块以及出块的验证者
+type ReorgBlock struct {
+	Number    uint64         `json:"number"`
+	Hash      common.Hash    `json:"hash"`
+	Validator common.Address `json:"validator"`
+}
+
+//一次链重组的记录，包括共同祖先、被丢弃的区块以及新加入规范链的区块
+type ReorgEntry struct {
+	Seq      uint64       `json:"seq"`
+	Time     uint64       `json:"time"`
+	Ancestor ReorgBlock   `json:"ancestor"`
+	Dropped  []ReorgBlock `json:"dropped"` //从规范链中丢弃的区块(从高到低)
+	Added    []ReorgBlock `json:"added"`   //加入规范链的区块(从高到低)
+}
+
+func newReorgBlock(block *types.Block) ReorgBlock {
+	return ReorgBlock{Number: block.NumberU64(), Hash: block.Hash(), Validator: block.Header().Validator}
+}
+
+//将一次链重组写入重组日志并通知订阅者，调用者需要持有bc.mu
+func (bc *BlockChain) journalReorg(ancestor *types.Block, oldChain, newChain types.Blocks) {
+
+	entry := &ReorgEntry{
+		Seq:      GetReorgJournalSize(bc.chainDb),
+		Time:     uint64(time.Now().Unix()),
+		Ancestor: newReorgBlock(ancestor),
+	}
+	for _, block := range oldChain {
+		entry.Dropped = append(entry.Dropped, newReorgBlock(block))
+	}
+	for _, block := range newChain {
+		entry.Added = append(entry.Added, newReorgBlock(block))
+	}
+	batch := bc.chainDb.NewBatch()
+	if err := WriteReorgEntry(batch, entry); err != nil {
+		log.Error("Failed to journal chain reorg", "err", err)
+		return
+	}
+	WriteReorgJournalSize(batch, entry.Seq+1)
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to journal chain reorg", "err", err)
+		return
+	}
+	if entry.Seq >= reorgJournalLimit {
+		DeleteReorgEntry(bc.chainDb, entry.Seq-reorgJournalLimit)
+	}
+	go bc.reorgFeed.Send(ChainReorgEvent{Reorg: entry})
+}
+
+//返回最近的重组记录(从新到旧)，最多返回limit条
+func (bc *BlockChain) ReorgJournal(limit int) []*ReorgEntry {
+
+	var entries []*ReorgEntry
+	for seq := GetReorgJournalSize(bc.chainDb); seq > 0 && len(entries) < limit; seq-- {
+		entry := GetReorgEntry(bc.chainDb, seq-1)
+		if entry == nil {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+//订阅链重组事件
+func (bc *BlockChain) SubscribeChainReorgEvent(ch chan<- ChainReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
@@ -0,0 +1,412 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	"github.com/Bokerchain/Boker/chain/trie"
+)
+
+const (
+	SnapshotVersion = 1 //快照格式版本
+
+	snapshotManifestFile = "manifest.json" //快照清单文件名
+	snapshotBlockChunk   = 1024            //每个区块分片包含的区块数量
+	snapshotStateChunk   = 4 * 1024 * 1024 //每个状态分片包含的节点数据大小上限
+	snapshotHeaderCheck  = 100             //导入时区块头的抽样验证频率(与快速同步相同)
+	snapshotBlocksKind   = "blocks"        //区块分片
+	snapshotStateKind    = "state"         //状态分片(状态树、合约代码、Dpos树和播客链树的节点)
+)
+
+var (
+	errSnapshotVersion  = errors.New("unsupported snapshot version")               //快照版本不支持
+	errSnapshotGenesis  = errors.New("snapshot belongs to a different network")    //快照的创世区块不一致
+	errSnapshotChecksum = errors.New("snapshot chunk checksum mismatch")           //分片校验失败
+	errSnapshotBehind   = errors.New("local chain already past the snapshot head") //本地链已经超过快照高度
+)
+
+//快照中的一个分片文件
+type SnapshotChunk struct {
+	Kind string      `json:"kind"` //分片类型(blocks或state)
+	File string      `json:"file"` //分片文件名
+	Size int64       `json:"size"` //分片文件大小
+	Hash common.Hash `json:"hash"` //分片文件内容的Keccak256校验值
+}
+
+//快照清单，记录快照所在区块以及全部分片
+type SnapshotManifest struct {
+	Version uint64          `json:"version"`
+	Genesis common.Hash     `json:"genesis"`
+	Number  uint64          `json:"number"`
+	Hash    common.Hash     `json:"hash"`
+	Root    common.Hash     `json:"stateRoot"`
+	Chunks  []SnapshotChunk `json:"chunks"`
+}
+
+//区块分片中的一项，区块以及其收据
+type snapshotBlock struct {
+	Block    *types.Block
+	Receipts types.Receipts
+}
+
+//导出指定高度的区块、收据以及该区块的状态树、Dpos树和播客链树到目录中，数据按分片压缩保存并在清单中记录每个分片的校验值
+func (bc *BlockChain) ExportSnapshot(dir string, number uint64) (*SnapshotManifest, error) {
+
+	head := bc.GetBlockByNumber(number)
+	if head == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	//快照直接从磁盘读取状态，先将缓存中的节点写入磁盘
+	if err := bc.CommitTrieCache(); err != nil {
+		return nil, err
+	}
+	if _, err := state.New(head.Root(), state.NewDatabase(bc.chainDb)); err != nil {
+		return nil, fmt.Errorf("state of block #%d not available: %v", number, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	manifest := &SnapshotManifest{
+		Version: SnapshotVersion,
+		Genesis: bc.Genesis().Hash(),
+		Number:  number,
+		Hash:    head.Hash(),
+		Root:    head.Root(),
+	}
+	//沿父区块回溯收集区块哈希，避免导出过程中发生链重组导致区块不连续
+	hashes := make([]common.Hash, number+1)
+	hashes[number] = head.Hash()
+	for n := number; n > 0; n-- {
+		header := bc.GetHeader(hashes[n], n)
+		if header == nil {
+			return nil, fmt.Errorf("header #%d [%x…] not found", n, hashes[n].Bytes()[:4])
+		}
+		hashes[n-1] = header.ParentHash
+	}
+	//导出区块及收据(创世区块由节点自己生成，不需要导出)
+	var items []interface{}
+	for n := uint64(1); n <= number; n++ {
+		block := bc.GetBlock(hashes[n], n)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d [%x…] not found", n, hashes[n].Bytes()[:4])
+		}
+		items = append(items, &snapshotBlock{Block: block, Receipts: GetBlockReceipts(bc.chainDb, hashes[n], n)})
+		if len(items) == snapshotBlockChunk || n == number {
+			if err := manifest.writeChunk(dir, snapshotBlocksKind, items); err != nil {
+				return nil, err
+			}
+			items = items[:0]
+		}
+	}
+	//导出状态节点
+	writer := &snapshotStateWriter{dir: dir, manifest: manifest, db: bc.chainDb}
+	if err := writer.exportState(head.Header()); err != nil {
+		return nil, err
+	}
+	if err := writer.flush(); err != nil {
+		return nil, err
+	}
+	blob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, snapshotManifestFile), blob, 0644); err != nil {
+		return nil, err
+	}
+	log.Info("Exported chain snapshot", "number", number, "hash", head.Hash(), "chunks", len(manifest.Chunks))
+	return manifest, nil
+}
+
+//压缩编码一个分片并写入目录，同时在清单中记录
+func (m *SnapshotManifest) writeChunk(dir string, kind string, items interface{}) error {
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := rlp.Encode(gz, items); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	chunk := SnapshotChunk{
+		Kind: kind,
+		File: fmt.Sprintf("%s-%05d.rlp.gz", kind, len(m.Chunks)),
+		Size: int64(buf.Len()),
+		Hash: crypto.Keccak256Hash(buf.Bytes()),
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, chunk.File), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	m.Chunks = append(m.Chunks, chunk)
+	return nil
+}
+
+//状态分片的写入器，累积节点数据直到达到分片大小
+type snapshotStateWriter struct {
+	dir      string
+	manifest *SnapshotManifest
+	db       ethdb.Database
+	nodes    [][]byte
+	size     int
+}
+
+//收集区块状态树(包括合约存储树和代码)、Dpos树以及播客链树的全部节点
+func (w *snapshotStateWriter) exportState(header *types.Header) error {
+
+	statedb, err := state.New(header.Root, state.NewDatabase(w.db))
+	if err != nil {
+		return err
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		if it.Hash == (common.Hash{}) {
+			continue
+		}
+		if err := w.add(it.Hash); err != nil {
+			return err
+		}
+	}
+	if it.Error != nil {
+		return it.Error
+	}
+	for _, root := range snapshotTrieRoots(header) {
+		t, err := trie.New(root, w.db)
+		if err != nil {
+			return err
+		}
+		nodes := t.NodeIterator(nil)
+		for nodes.Next(true) {
+			if nodes.Hash() == (common.Hash{}) {
+				continue
+			}
+			if err := w.add(nodes.Hash()); err != nil {
+				return err
+			}
+		}
+		if nodes.Error() != nil {
+			return nodes.Error()
+		}
+	}
+	return nil
+}
+
+func (w *snapshotStateWriter) add(hash common.Hash) error {
+
+	blob, err := w.db.Get(hash.Bytes())
+	if err != nil {
+		return fmt.Errorf("state node %x: %v", hash, err)
+	}
+	w.nodes = append(w.nodes, blob)
+	if w.size += len(blob); w.size >= snapshotStateChunk {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *snapshotStateWriter) flush() error {
+
+	if len(w.nodes) == 0 {
+		return nil
+	}
+	if err := w.manifest.writeChunk(w.dir, snapshotStateKind, w.nodes); err != nil {
+		return err
+	}
+	w.nodes, w.size = nil, 0
+	return nil
+}
+
+//区块头中引用的Dpos树和播客链树的根
+func snapshotTrieRoots(header *types.Header) []common.Hash {
+
+	var roots []common.Hash
+	if header.DposProto != nil {
+		roots = append(roots, header.DposProto.EpochHash, header.DposProto.ValidatorHash, header.DposProto.BlockCntHash)
+	}
+	if header.BokerProto != nil {
+		roots = append(roots, header.BokerProto.SingleHash, header.BokerProto.ContractsHash, header.BokerProto.ContracAbiHash)
+	}
+	return roots
+}
+
+//读取快照清单
+func ReadSnapshotManifest(dir string) (*SnapshotManifest, error) {
+
+	blob, err := ioutil.ReadFile(filepath.Join(dir, snapshotManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(SnapshotManifest)
+	if err := json.Unmarshal(blob, manifest); err != nil {
+		return nil, fmt.Errorf("invalid snapshot manifest: %v", err)
+	}
+	if manifest.Version != SnapshotVersion {
+		return nil, errSnapshotVersion
+	}
+	return manifest, nil
+}
+
+//读取并校验一个分片，返回解压后的数据流
+func readSnapshotChunk(dir string, chunk SnapshotChunk) (*rlp.Stream, error) {
+
+	blob, err := ioutil.ReadFile(filepath.Join(dir, filepath.Base(chunk.File)))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(blob)) != chunk.Size || crypto.Keccak256Hash(blob) != chunk.Hash {
+		return nil, fmt.Errorf("%s: %v", chunk.File, errSnapshotChecksum)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(blob))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", chunk.File, err)
+	}
+	return rlp.NewStream(gz, 0), nil
+}
+
+//从快照目录导入区块和状态，导入完成后将快照区块设置为当前区块，节点随后从该区块继续同步
+func (bc *BlockChain) ImportSnapshot(dir string) (*SnapshotManifest, error) {
+
+	manifest, err := ReadSnapshotManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Genesis != bc.Genesis().Hash() {
+		return nil, errSnapshotGenesis
+	}
+	if bc.CurrentBlock().NumberU64() >= manifest.Number {
+		return nil, errSnapshotBehind
+	}
+	//导入前先校验全部分片，避免写入一半的数据
+	for _, chunk := range manifest.Chunks {
+		if _, err := readSnapshotChunk(dir, chunk); err != nil {
+			return nil, err
+		}
+	}
+	var head *types.Block
+	for _, chunk := range manifest.Chunks {
+		stream, err := readSnapshotChunk(dir, chunk)
+		if err != nil {
+			return nil, err
+		}
+		switch chunk.Kind {
+		case snapshotBlocksKind:
+			var items []*snapshotBlock
+			if err := stream.Decode(&items); err != nil {
+				return nil, fmt.Errorf("%s: %v", chunk.File, err)
+			}
+			if err := bc.importSnapshotBlocks(items); err != nil {
+				return nil, fmt.Errorf("%s: %v", chunk.File, err)
+			}
+			if len(items) > 0 {
+				head = items[len(items)-1].Block
+			}
+		case snapshotStateKind:
+			var nodes [][]byte
+			if err := stream.Decode(&nodes); err != nil {
+				return nil, fmt.Errorf("%s: %v", chunk.File, err)
+			}
+			if err := bc.importSnapshotNodes(nodes); err != nil {
+				return nil, fmt.Errorf("%s: %v", chunk.File, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown chunk kind %q", chunk.File, chunk.Kind)
+		}
+		log.Info("Imported snapshot chunk", "file", chunk.File, "kind", chunk.Kind)
+	}
+	if head == nil {
+		return nil, errors.New("snapshot contains no blocks")
+	}
+	if head.Hash() != manifest.Hash || head.Root() != manifest.Root {
+		return nil, fmt.Errorf("snapshot head mismatch: have %x, want %x", head.Hash(), manifest.Hash)
+	}
+	//确认快照区块的状态完整
+	if err := verifySnapshotState(bc.chainDb, head.Header()); err != nil {
+		return nil, fmt.Errorf("incomplete snapshot state: %v", err)
+	}
+	if err := bc.FastSyncCommitHead(head.Hash()); err != nil {
+		return nil, err
+	}
+	if err := WriteHeadBlockHash(bc.chainDb, head.Hash()); err != nil {
+		return nil, err
+	}
+	log.Info("Imported chain snapshot", "number", head.Number(), "hash", head.Hash())
+	return manifest, nil
+}
+
+//与快速同步相同，先插入并验证区块头，再写入区块体和收据
+func (bc *BlockChain) importSnapshotBlocks(items []*snapshotBlock) error {
+
+	var (
+		headers  = make([]*types.Header, len(items))
+		blocks   = make(types.Blocks, len(items))
+		receipts = make([]types.Receipts, len(items))
+	)
+	for i, item := range items {
+		headers[i], blocks[i], receipts[i] = item.Block.Header(), item.Block, item.Receipts
+	}
+	if n, err := bc.InsertHeaderChain(headers, snapshotHeaderCheck); err != nil {
+		return fmt.Errorf("header #%d: %v", headers[n].Number, err)
+	}
+	if n, err := bc.InsertReceiptChain(blocks, receipts); err != nil {
+		return fmt.Errorf("block #%d: %v", blocks[n].Number(), err)
+	}
+	return nil
+}
+
+//写入状态节点，节点以内容哈希为键
+func (bc *BlockChain) importSnapshotNodes(nodes [][]byte) error {
+
+	batch := bc.chainDb.NewBatch()
+	for _, blob := range nodes {
+		if err := batch.Put(crypto.Keccak256(blob), blob); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch = bc.chainDb.NewBatch()
+		}
+	}
+	return batch.Write()
+}
+
+//遍历快照区块的状态树、Dpos树和播客链树，确认没有缺失的节点
+func verifySnapshotState(db ethdb.Database, header *types.Header) error {
+
+	statedb, err := state.New(header.Root, state.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		return it.Error
+	}
+	for _, root := range snapshotTrieRoots(header) {
+		t, err := trie.New(root, db)
+		if err != nil {
+			return err
+		}
+		nodes := t.NodeIterator(nil)
+		for nodes.Next(true) {
+		}
+		if nodes.Error() != nil {
+			return nodes.Error()
+		}
+	}
+	return nil
+}
@@ -61,6 +61,7 @@ type Trie interface {
 	Hash() common.Hash
 	NodeIterator(startKey []byte) trie.NodeIterator
 	GetKey([]byte) []byte // TODO(fjl): remove this when SecureTrie is removed
+	Prove(key []byte, fromLevel uint, proofDb trie.DatabaseWriter) error
 }
 
 // NewDatabase creates a backing store for state. The returned database is safe for
@@ -70,8 +71,15 @@ func NewDatabase(db ethdb.Database) Database {
 	return &cachingDB{db: db, codeSizeCache: csc}
 }
 
+// NewDatabaseWithCache creates a backing store for state that reads and writes
+// trie nodes and contract code through the given shared node cache.
+func NewDatabaseWithCache(cache *trie.NodeCache) Database {
+	csc, _ := lru.New(codeSizeCacheSize)
+	return &cachingDB{db: cache, codeSizeCache: csc}
+}
+
 type cachingDB struct {
-	db            ethdb.Database
+	db            trie.Database
 	mu            sync.Mutex
 	pastTries     []*trie.SecureTrie
 	codeSizeCache *lru.Cache
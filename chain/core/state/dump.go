@@ -17,8 +17,10 @@
 package state
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"math/big"
 
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/rlp"
@@ -39,34 +41,145 @@ type Dump struct {
 	Accounts map[string]DumpAccount `json:"accounts"`
 }
 
-func (self *StateDB) RawDump() Dump {
-	dump := Dump{
-		Root:     fmt.Sprintf("%x", self.trie.Hash()),
-		Accounts: make(map[string]DumpAccount),
+// DumpFilter selects the accounts and the account details returned by a
+// DumpIterator. The zero value selects all accounts with all details.
+type DumpFilter struct {
+	OnlyContracts  bool     // only accounts with code
+	OnlyEOAs       bool     // only accounts without code
+	MinBalance     *big.Int // only accounts holding at least this balance
+	ExcludeCode    bool     // don't load account code
+	ExcludeStorage bool     // don't load account storage
+}
+
+// matches reports whether the account passes the filter.
+func (f *DumpFilter) matches(data *Account) bool {
+	hasCode := !bytes.Equal(data.CodeHash, emptyCodeHash)
+	if f.OnlyContracts && !hasCode {
+		return false
 	}
+	if f.OnlyEOAs && hasCode {
+		return false
+	}
+	if f.MinBalance != nil && data.Balance.Cmp(f.MinBalance) < 0 {
+		return false
+	}
+	return true
+}
 
-	it := trie.NewIterator(self.trie.NodeIterator(nil))
-	for it.Next() {
-		addr := self.trie.GetKey(it.Key)
+// DumpIterator walks the accounts of the state trie in hashed key order,
+// loading only the accounts selected by its filter.
+type DumpIterator struct {
+	state  *StateDB
+	it     *trie.Iterator
+	filter DumpFilter
+	err    error
+
+	Key     []byte         // hashed address of the current account
+	Address common.Address // address of the current account
+	data    Account        // trie data of the current account
+}
+
+// NewDumpIterator creates an iterator over the accounts whose hashed address
+// is at or after start.
+func (self *StateDB) NewDumpIterator(start []byte, filter DumpFilter) *DumpIterator {
+	return &DumpIterator{
+		state:  self,
+		it:     trie.NewIterator(self.trie.NodeIterator(start)),
+		filter: filter,
+	}
+}
+
+// Next moves the iterator to the next account passing the filter. It returns
+// false when the iteration is finished or failed, see Err.
+func (it *DumpIterator) Next() bool {
+	for it.err == nil && it.it.Next() {
 		var data Account
-		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
-			panic(err)
+		if err := rlp.DecodeBytes(it.it.Value, &data); err != nil {
+			it.err = err
+			return false
 		}
-
-		obj := newObject(nil, common.BytesToAddress(addr), data, nil)
-		account := DumpAccount{
-			Balance:  data.Balance.String(),
-			Nonce:    data.Nonce,
-			Root:     common.Bytes2Hex(data.Root[:]),
-			CodeHash: common.Bytes2Hex(data.CodeHash),
-			Code:     common.Bytes2Hex(obj.Code(self.db)),
-			Storage:  make(map[string]string),
+		if !it.filter.matches(&data) {
+			continue
 		}
+		it.Key = it.it.Key
+		it.Address = common.BytesToAddress(it.state.trie.GetKey(it.it.Key))
+		it.data = data
+		return true
+	}
+	if it.err == nil {
+		it.err = it.it.Err
+	}
+	return false
+}
+
+// Account loads the dump of the current account.
+func (it *DumpIterator) Account() DumpAccount {
+	return it.state.dumpAccount(it.Address, it.data, &it.filter)
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *DumpIterator) Err() error {
+	return it.err
+}
+
+// dumpAccount assembles the dump of an account from its trie data.
+func (self *StateDB) dumpAccount(addr common.Address, data Account, filter *DumpFilter) DumpAccount {
+	obj := newObject(nil, addr, data, nil)
+	account := DumpAccount{
+		Balance:  data.Balance.String(),
+		Nonce:    data.Nonce,
+		Root:     common.Bytes2Hex(data.Root[:]),
+		CodeHash: common.Bytes2Hex(data.CodeHash),
+		Storage:  make(map[string]string),
+	}
+	if !filter.ExcludeCode {
+		account.Code = common.Bytes2Hex(obj.Code(self.db))
+	}
+	if !filter.ExcludeStorage {
 		storageIt := trie.NewIterator(obj.getTrie(self.db).NodeIterator(nil))
 		for storageIt.Next() {
 			account.Storage[common.Bytes2Hex(self.trie.GetKey(storageIt.Key))] = common.Bytes2Hex(storageIt.Value)
 		}
-		dump.Accounts[common.Bytes2Hex(addr)] = account
+	}
+	return account
+}
+
+// IteratorDump is one page of a state dump. Next is the hashed address to
+// continue the dump from, nil if the dump is complete.
+type IteratorDump struct {
+	Root     string                 `json:"root"`
+	Accounts map[string]DumpAccount `json:"accounts"`
+	Next     []byte                 `json:"next,omitempty"`
+}
+
+// IteratorDump dumps at most maxAccounts accounts passing the filter, starting
+// at the given hashed address.
+func (self *StateDB) IteratorDump(start []byte, maxAccounts int, filter DumpFilter) (IteratorDump, error) {
+	dump := IteratorDump{
+		Root:     fmt.Sprintf("%x", self.trie.Hash()),
+		Accounts: make(map[string]DumpAccount),
+	}
+	it := self.NewDumpIterator(start, filter)
+	for len(dump.Accounts) < maxAccounts && it.Next() {
+		dump.Accounts[common.Bytes2Hex(it.Address[:])] = it.Account()
+	}
+	if it.Next() {
+		dump.Next = it.Key
+	}
+	return dump, it.Err()
+}
+
+func (self *StateDB) RawDump() Dump {
+	dump := Dump{
+		Root:     fmt.Sprintf("%x", self.trie.Hash()),
+		Accounts: make(map[string]DumpAccount),
+	}
+	it := self.NewDumpIterator(nil, DumpFilter{})
+	for it.Next() {
+		dump.Accounts[common.Bytes2Hex(it.Address[:])] = it.Account()
+	}
+	if err := it.Err(); err != nil {
+		panic(err)
 	}
 	return dump
 }
@@ -86,6 +86,56 @@ func (s *StateSuite) TestDump(c *checker.C) {
 	}
 }
 
+func (s *StateSuite) TestIteratorDump(c *checker.C) {
+	obj1 := s.state.GetOrNewStateObject(toAddr([]byte{0x01}))
+	obj1.AddBalance(big.NewInt(22))
+	obj2 := s.state.GetOrNewStateObject(toAddr([]byte{0x01, 0x02}))
+	obj2.SetCode(crypto.Keccak256Hash([]byte{3, 3, 3, 3, 3, 3, 3}), []byte{3, 3, 3, 3, 3, 3, 3})
+	obj3 := s.state.GetOrNewStateObject(toAddr([]byte{0x02}))
+	obj3.SetBalance(big.NewInt(44))
+	s.state.CommitTo(s.db, false)
+
+	// page through all accounts one at a time
+	var (
+		start []byte
+		seen  = make(map[string]bool)
+	)
+	for pages := 0; ; pages++ {
+		dump, err := s.state.IteratorDump(start, 1, DumpFilter{})
+		c.Assert(err, checker.IsNil)
+		c.Assert(len(dump.Accounts), checker.Equals, 1)
+		for addr := range dump.Accounts {
+			seen[addr] = true
+		}
+		if start = dump.Next; start == nil {
+			c.Assert(pages, checker.Equals, 2)
+			break
+		}
+	}
+	c.Assert(len(seen), checker.Equals, 3)
+
+	// check the filters
+	filters := []struct {
+		filter DumpFilter
+		want   []string
+	}{
+		{DumpFilter{OnlyContracts: true}, []string{"0000000000000000000000000000000000000102"}},
+		{DumpFilter{OnlyEOAs: true}, []string{"0000000000000000000000000000000000000001", "0000000000000000000000000000000000000002"}},
+		{DumpFilter{MinBalance: big.NewInt(30)}, []string{"0000000000000000000000000000000000000002"}},
+	}
+	for _, test := range filters {
+		dump, err := s.state.IteratorDump(nil, 10, test.filter)
+		c.Assert(err, checker.IsNil)
+		c.Assert(len(dump.Accounts), checker.Equals, len(test.want))
+		for _, addr := range test.want {
+			_, ok := dump.Accounts[addr]
+			c.Assert(ok, checker.Equals, true)
+		}
+	}
+	dump, _ := s.state.IteratorDump(nil, 10, DumpFilter{OnlyContracts: true, ExcludeCode: true})
+	c.Assert(dump.Accounts["0000000000000000000000000000000000000102"].Code, checker.Equals, "")
+}
+
 func (s *StateSuite) SetUpTest(c *checker.C) {
 	s.db, _ = ethdb.NewMemDatabase()
 	s.state, _ = New(common.Hash{}, NewDatabase(s.db))
@@ -18,6 +18,7 @@
 package state
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"sort"
@@ -236,6 +237,34 @@ func (self *StateDB) GetState(a common.Address, b common.Hash) common.Hash {
 	return common.Hash{}
 }
 
+// proofList collects the encoded nodes of a merkle proof in path order.
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+// GetProof returns the merkle proof of the account in the state trie, which
+// also proves the absence of accounts not in the state.
+func (self *StateDB) GetProof(a common.Address) ([][]byte, error) {
+	var proof proofList
+	err := self.trie.Prove(a.Bytes(), 0, &proof)
+	return [][]byte(proof), err
+}
+
+// GetStorageProof returns the merkle proof of the storage slot in the storage
+// trie of the account.
+func (self *StateDB) GetStorageProof(a common.Address, key common.Hash) ([][]byte, error) {
+	var proof proofList
+	tr := self.StorageTrie(a)
+	if tr == nil {
+		return proof, errors.New("storage trie for requested address does not exist")
+	}
+	err := tr.Prove(key.Bytes(), 0, &proof)
+	return [][]byte(proof), err
+}
+
 // StorageTrie returns the storage trie of an account.
 // The return value is a copy and is nil for non-existent accounts.
 func (self *StateDB) StorageTrie(a common.Address) Trie {
@@ -455,7 +484,7 @@ func (self *StateDB) Copy() *StateDB {
 	// Copy all the basic fields, initialize the memory ones
 	state := &StateDB{
 		db:                self.db,
-		trie:              self.trie,
+		trie:              self.db.CopyTrie(self.trie),
 		stateObjects:      make(map[common.Address]*stateObject, len(self.stateObjectsDirty)),
 		stateObjectsDirty: make(map[common.Address]struct{}, len(self.stateObjectsDirty)),
 		refund:            new(big.Int).Set(self.refund),
@@ -514,6 +543,15 @@ func (self *StateDB) GetRefund() *big.Int {
 	return self.refund
 }
 
+//得到提交之前被修改过的账户地址
+func (s *StateDB) DirtyAccounts() []common.Address {
+	addrs := make([]common.Address, 0, len(s.stateObjectsDirty))
+	for addr := range s.stateObjectsDirty {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
 // Finalise finalises the state by removing the self destructed objects
 // and clears the journal as well as the refunds.
 func (s *StateDB) Finalise(deleteEmptyObjects bool) {
@@ -545,6 +583,11 @@ func (self *StateDB) Prepare(thash, bhash common.Hash, ti int) {
 	self.txIndex = ti
 }
 
+//返回正在执行的交易在区块中的序号
+func (self *StateDB) TxIndex() int {
+	return self.txIndex
+}
+
 // DeleteSuicides flags the suicided objects for deletion so that it
 // won't be referenced again when called / queried up on.
 //
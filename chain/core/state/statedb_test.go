@@ -32,7 +32,10 @@ import (
 
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	"github.com/Bokerchain/Boker/chain/trie"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -62,6 +65,56 @@ func TestUpdateLeaks(t *testing.T) {
 	}
 }
 
+// Tests that account and storage proofs verify against the state and storage roots.
+func TestGetProof(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	addr := common.BytesToAddress([]byte{0x01})
+	key, value := common.BytesToHash([]byte{0x02}), common.BytesToHash([]byte{0x03})
+	for i := byte(0); i < 16; i++ {
+		state.AddBalance(common.BytesToAddress([]byte{i}), big.NewInt(int64(i)+1))
+	}
+	state.SetState(addr, key, value)
+	root := state.IntermediateRoot(false)
+
+	verify := func(root common.Hash, key []byte, proof [][]byte) []byte {
+		proofDb, _ := ethdb.NewMemDatabase()
+		for _, node := range proof {
+			proofDb.Put(crypto.Keccak256(node), node)
+		}
+		val, err, _ := trie.VerifyProof(root, crypto.Keccak256(key), proofDb)
+		if err != nil {
+			t.Fatalf("proof of %x invalid: %v", key, err)
+		}
+		return val
+	}
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verify(root, addr.Bytes(), proof) == nil {
+		t.Errorf("account proof does not contain the account")
+	}
+	proof, err = state.GetStorageProof(addr, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
+	if val := verify(state.StorageTrie(addr).Hash(), key.Bytes(), proof); !bytes.Equal(val, enc) {
+		t.Errorf("storage proof value mismatch: have %x, want %x", val, enc)
+	}
+
+	// Proofs of missing accounts prove their absence
+	missing := common.BytesToAddress([]byte{0xff, 0xff})
+	if proof, err = state.GetProof(missing); err != nil {
+		t.Fatal(err)
+	}
+	if verify(root, missing.Bytes(), proof) != nil {
+		t.Errorf("proof of missing account contains a value")
+	}
+}
+
 // Tests that no intermediate state of an object is stored into the database,
 // only the one right before the commit.
 func TestIntermediateLeaks(t *testing.T) {
@@ -20,6 +20,7 @@ import (
 	_ "bytes"
 	"errors"
 	"math/big"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/boker/api"
 	"github.com/Bokerchain/Boker/chain/boker/protocol"
@@ -31,15 +32,22 @@ import (
 	"github.com/Bokerchain/Boker/chain/core/vm"
 	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
 	"github.com/Bokerchain/Boker/chain/params"
+	"github.com/Bokerchain/Boker/chain/rlp"
 )
 
+//区块中交易执行(EVM及基础合约)耗时的度量标准
+var blockExecutionTimer = metrics.NewTimer("chain/execution")
+
 //状态处理器，负责一个从一个节点到另一个节点
 type StateProcessor struct {
 	config *params.ChainConfig //链配置选项
 	bc     *BlockChain         //规范块链
 	engine consensus.Engine    //共识引擎
 	boker  bokerapi.Api        //播客链的接口
+
+	parallel int //并行执行交易的线程数量
 }
 
 //初始化一个新的状态处理器。
@@ -59,6 +67,7 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		header       = block.Header()
 		allLogs      []*types.Log
 		gp           = new(GasPool).AddGas(block.GasLimit())
+		start        = time.Now()
 	)
 
 	//根据任何硬叉规范改变块和状态
@@ -66,6 +75,19 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		misc.ApplyDAOHardFork(statedb)
 	}
 
+	//开启并行执行时，并行执行区块中的交易(调试模式下以及拜占庭分叉之前的区块需要中间状态根，只能串行执行)
+	if p.parallel > 1 && !cfg.Debug && p.config.IsByzantium(header.Number) {
+
+		var err error
+		if receipts, allLogs, err = p.processParallel(block, statedb, cfg, gp, totalUsedGas); err != nil {
+			log.Error("Process", "Number", block.Number(), "error", err)
+			return nil, nil, nil, err
+		}
+		p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts, block.DposCtx(), p.boker)
+		blockExecutionTimer.UpdateSince(start)
+		return receipts, allLogs, totalUsedGas, nil
+	}
+
 	//得到区块中所有的交易，并将这些交易使用Dpos引擎进行执行。
 	for i, tx := range block.Transactions() {
 
@@ -87,6 +109,7 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 
 	//执行完块中所有的交易，应用任何共识引擎特定的附加功能（例如块奖励）
 	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts, block.DposCtx(), p.boker)
+	blockExecutionTimer.UpdateSince(start)
 
 	//返回执行成功的回执数组/日志/以及总的使用Gas的数量
 	return receipts, allLogs, totalUsedGas, nil
@@ -184,6 +207,14 @@ func contractSetTransaction(config *params.ChainConfig,
 	}
 	tx.SetExtra(extra)
 
+	//替换基础合约的签名或者内容无效时交易仍然打包(消耗Nonce)，回执标记为失败并且不修改注册表
+	if msg.TxType() == protocol.UpdateContract {
+		if err := scheduleContractUpdate(dposContext, header, msg, boker); err != nil {
+			log.Warn("contractSetTransaction scheduleContractUpdate", "hash", tx.Hash(), "err", err)
+			failed = true
+		}
+	}
+
 	var root []byte
 	if config.IsByzantium(header.Number) {
 
@@ -205,6 +236,38 @@ func contractSetTransaction(config *params.ChainConfig,
 	return receipt, gas, err
 }
 
+//校验替换基础合约交易的验证者签名，通过后记录在指定区块生效的替换。签名需要来自当前周期超过三分之二的验证者，
+//签名内容包含打包时的注册表Hash，所以注册表修改之后同样的签名不能再使用
+func scheduleContractUpdate(dposContext *types.DposContext, header *types.Header, msg types.Message, boker bokerapi.Api) error {
+
+	if boker == nil || dposContext == nil {
+		return protocol.ErrInvalidSystem
+	}
+	var payload bokerapi.UpdateContractPayload
+	if err := rlp.DecodeBytes(msg.Data(), &payload); err != nil {
+		return err
+	}
+	update := protocol.ContractUpdate{Old: payload.Old, New: *msg.To(), ContractType: payload.ContractType, Number: payload.Number}
+	if update.ContractType != protocol.SystemContract && update.ContractType != protocol.PersonalContract {
+		return protocol.ErrNotFoundType
+	}
+	if (update.Old == common.Address{}) || (update.New == common.Address{}) || update.Old == update.New {
+		return protocol.ErrInvalidAddress
+	}
+	if update.Number <= header.Number.Uint64() {
+		return protocol.ErrUpdateExpired
+	}
+	validators, err := dposContext.GetEpochTrie()
+	if err != nil {
+		return err
+	}
+	hash := bokerapi.UpdateContractHash(boker.RegistryHash(), update.Old, update.New, update.ContractType, update.Number)
+	if err := bokerapi.VerifyValidatorSignatures(hash, payload.Signatures, validators); err != nil {
+		return err
+	}
+	return boker.ScheduleContractUpdate(update)
+}
+
 //用户投票合约
 func baseTransaction(config *params.ChainConfig,
 	dposContext *types.DposContext,
@@ -251,14 +314,27 @@ func baseTransaction(config *params.ChainConfig,
 		}
 	}
 
+	//分配通证交易记录合约转出的金额
 	context := NewEVMContext(msg, header, bc, author)
-	vmenv := vm.NewEVM(context, statedb, config, cfg)
+	var (
+		db       vm.StateDB = statedb
+		recorder *transferRecorder
+	)
+	if tx.Type() == protocol.AssignToken {
+		recorder = newTransferRecorder(statedb, msg.From())
+		context.Transfer = recorder.transfer
+		db = recorder
+	}
+	vmenv := vm.NewEVM(context, db, config, cfg)
 	_, extra, gas, failed, err := baseMessage(vmenv, msg, gp, boker)
 	if err != nil {
 		log.Error("baseTransaction failed", "err", err)
 		return nil, nil, err
 	}
 	tx.SetExtra(extra)
+	if !failed {
+		addBaseEventLog(config, statedb, header, tx, msg.From())
+	}
 
 	var root []byte
 	if config.IsByzantium(header.Number) {
@@ -273,6 +349,9 @@ func baseTransaction(config *params.ChainConfig,
 	receipt.GasUsed = new(big.Int).Set(gas)
 	receipt.Logs = statedb.GetLogs(tx.Hash())
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	if recorder != nil {
+		receipt.Transfers = recorder.transfers
+	}
 
 	//log.Info("****baseTransaction End****", "gas", gas, "err", err)
 	return receipt, gas, err
@@ -380,6 +459,60 @@ func ApplyTransaction(config *params.ChainConfig,
 	cfg vm.Config,
 	boker bokerapi.Api) (*types.Receipt, *big.Int, error) {
 
+	receipt, gas, err := applyTransaction(config, dposContext, bc, author, gp, statedb, header, tx, usedGas, cfg, boker)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	setReceiptMeta(receipt, tx, header)
+	return receipt, gas, err
+}
+
+//在回执中记录播客链交易元数据
+func setReceiptMeta(receipt *types.Receipt, tx *types.Transaction, header *types.Header) {
+	receipt.TxType = tx.Type()
+	receipt.Epoch = uint64(header.Time.Int64() / protocol.EpochInterval)
+	if tx.Type() == protocol.AssignToken {
+		receipt.AssignedAmount = tx.Value()
+	}
+}
+
+//基础合约交易执行成功后生成对应的事件日志，合约本身没有触发事件时也可以通过eth_getLogs和布隆过滤器查询投票、注册候选人和分配通证
+func addBaseEventLog(config *params.ChainConfig, statedb *state.StateDB, header *types.Header, tx *types.Transaction, from common.Address) {
+
+	//日志地址是基础合约地址，没有接收地址的交易不生成事件日志
+	if !config.IsBaseEventLog(header.Number) || tx.To() == nil {
+		return
+	}
+	topic, ok := protocol.BaseEventTopic(tx.Type())
+	if !ok {
+		return
+	}
+	data := common.CopyBytes(tx.Data())
+	if tx.Type() == protocol.AssignToken {
+		data = common.LeftPadBytes(tx.Value().Bytes(), 32)
+	}
+	statedb.AddLog(&types.Log{
+		Address:     *tx.To(),
+		Topics:      []common.Hash{topic, common.BytesToHash(from.Bytes())},
+		Data:        data,
+		BlockNumber: header.Number.Uint64(),
+	})
+}
+
+//根据交易类型分发执行交易
+func applyTransaction(config *params.ChainConfig,
+	dposContext *types.DposContext,
+	bc *BlockChain,
+	author *common.Address,
+	gp *GasPool,
+	statedb *state.StateDB,
+	header *types.Header,
+	tx *types.Transaction,
+	usedGas *big.Int,
+	cfg vm.Config,
+	boker bokerapi.Api) (*types.Receipt, *big.Int, error) {
+
 	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number))
 	if err != nil {
 		return nil, nil, err
@@ -398,7 +531,7 @@ func ApplyTransaction(config *params.ChainConfig,
 		//根据交易类型来区分
 		switch msg.TxType() {
 
-		case protocol.SetPersonalContract, protocol.CancelPersonalContract, protocol.SetSystemContract, protocol.CancelSystemContract:
+		case protocol.SetPersonalContract, protocol.CancelPersonalContract, protocol.SetSystemContract, protocol.CancelSystemContract, protocol.UpdateContract:
 			//设置合约(已经测试)
 			return contractSetTransaction(config, dposContext, bc, author, gp, statedb, header, tx, usedGas, cfg, msg, boker)
 		case protocol.VoteUser, protocol.VoteEpoch, protocol.AssignToken, protocol.RegisterCandidate, protocol.UserEvent: //基础交易(已经测试)
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/api"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/params"
+	"github.com/Bokerchain/Boker/chain/rlp"
+)
+
+//执行基础合约交易后生成事件日志，并得到对应的回执
+func baseEventReceipt(config *params.ChainConfig, tx *types.Transaction, from common.Address) *types.Receipt {
+
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.Prepare(tx.Hash(), common.Hash{}, 0)
+
+	header := &types.Header{Number: big.NewInt(10)}
+	addBaseEventLog(config, statedb, header, tx, from)
+
+	receipt := types.NewReceipt(nil, false, new(big.Int))
+	receipt.Logs = statedb.GetLogs(tx.Hash())
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	return receipt
+}
+
+//测试基础合约交易生成的事件日志的主题和数据，回执的布隆过滤器可以查询到日志
+func TestBaseEventLog(t *testing.T) {
+
+	config := *params.TestChainConfig
+	config.BaseEventLogBlock = big.NewInt(5)
+
+	var (
+		contract = common.Address{1}
+		from     = common.Address{2}
+		payload  = []byte{0x01, 0x02, 0x03}
+		amount   = big.NewInt(1000)
+	)
+	tests := []struct {
+		tx    *types.Transaction
+		topic common.Hash
+		data  []byte
+	}{
+		{types.NewBaseTransaction(protocol.VoteUser, 0, contract, new(big.Int), payload), protocol.VoteCastTopic, payload},
+		{types.NewBaseTransaction(protocol.RegisterCandidate, 0, contract, new(big.Int), payload), protocol.CandidateRegisteredTopic, payload},
+		{types.NewAssginTransaction(protocol.AssignToken, 0, contract, amount, nil, 1), protocol.TokenAssignedTopic, common.LeftPadBytes(amount.Bytes(), 32)},
+	}
+	for i, tt := range tests {
+		receipt := baseEventReceipt(&config, tt.tx, from)
+		if len(receipt.Logs) != 1 {
+			t.Fatalf("test %d: log count mismatch: have %d, want 1", i, len(receipt.Logs))
+		}
+		log := receipt.Logs[0]
+		if log.Address != contract {
+			t.Errorf("test %d: log address mismatch: have %x, want %x", i, log.Address, contract)
+		}
+		if len(log.Topics) != 2 || log.Topics[0] != tt.topic || log.Topics[1] != common.BytesToHash(from.Bytes()) {
+			t.Errorf("test %d: log topics mismatch: have %x, want [%x %x]", i, log.Topics, tt.topic, common.BytesToHash(from.Bytes()))
+		}
+		if !bytes.Equal(log.Data, tt.data) {
+			t.Errorf("test %d: log data mismatch: have %x, want %x", i, log.Data, tt.data)
+		}
+		if log.BlockNumber != 10 {
+			t.Errorf("test %d: log block number mismatch: have %d, want 10", i, log.BlockNumber)
+		}
+		if receipt.Bloom != types.BytesToBloom(types.LogsBloom(receipt.Logs).Bytes()) {
+			t.Errorf("test %d: receipt bloom mismatch", i)
+		}
+		for _, topic := range []common.Hash{tt.topic, common.BytesToHash(from.Bytes())} {
+			if !types.BloomLookup(receipt.Bloom, topic) {
+				t.Errorf("test %d: topic %x missing from receipt bloom", i, topic)
+			}
+		}
+		if !types.BloomLookup(receipt.Bloom, contract) {
+			t.Errorf("test %d: address missing from receipt bloom", i)
+		}
+	}
+}
+
+//测试分叉之前、不生成日志的交易类型以及没有接收地址的交易不生成事件日志
+func TestBaseEventLogSkipped(t *testing.T) {
+
+	config := *params.TestChainConfig
+	config.BaseEventLogBlock = big.NewInt(5)
+
+	from := common.Address{2}
+	vote := types.NewBaseTransaction(protocol.VoteUser, 0, common.Address{1}, new(big.Int), nil)
+	binary := types.NewTransaction(protocol.Binary, 0, common.Address{1}, new(big.Int), new(big.Int), new(big.Int), nil)
+
+	//构造一个没有接收地址的投票交易
+	var fields []rlp.RawValue
+	enc, _ := rlp.EncodeToBytes(types.NewContractCreation(0, new(big.Int), new(big.Int), new(big.Int), nil))
+	if err := rlp.DecodeBytes(enc, &fields); err != nil {
+		t.Fatalf("failed to decode transaction fields: %v", err)
+	}
+	fields[5], _ = rlp.EncodeToBytes(protocol.VoteUser)
+	enc, _ = rlp.EncodeToBytes(fields)
+	create := new(types.Transaction)
+	if err := rlp.DecodeBytes(enc, create); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if create.To() != nil || create.Type() != protocol.VoteUser {
+		t.Fatalf("transaction mismatch: to %v, type %v", create.To(), create.Type())
+	}
+
+	preFork := config
+	preFork.BaseEventLogBlock = big.NewInt(11)
+
+	tests := []struct {
+		config *params.ChainConfig
+		tx     *types.Transaction
+	}{
+		{&preFork, vote},
+		{&config, binary},
+		{&config, create},
+	}
+	for i, tt := range tests {
+		receipt := baseEventReceipt(tt.config, tt.tx, from)
+		if len(receipt.Logs) != 0 {
+			t.Errorf("test %d: unexpected logs: %v", i, receipt.Logs)
+		}
+		if receipt.Bloom != (types.Bloom{}) {
+			t.Errorf("test %d: receipt bloom not empty", i)
+		}
+	}
+}
+
+//只实现替换基础合约需要的方法，记录收到的替换
+type updateTestBoker struct {
+	bokerapi.Api
+	registry  common.Hash
+	scheduled []protocol.ContractUpdate
+}
+
+func (b *updateTestBoker) RegistryHash() common.Hash { return b.registry }
+
+func (b *updateTestBoker) ScheduleContractUpdate(update protocol.ContractUpdate) error {
+	b.scheduled = append(b.scheduled, update)
+	return nil
+}
+
+//测试替换基础合约交易只有在超过三分之二的验证者签名并且生效区块在打包区块之后时才记录替换
+func TestScheduleContractUpdate(t *testing.T) {
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	validators := make([]common.Address, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		validators[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	db, _ := ethdb.NewMemDatabase()
+	dposContext, _ := types.NewDposContext(db)
+	if err := dposContext.SetEpochTrie(validators); err != nil {
+		t.Fatalf("failed to set validators: %v", err)
+	}
+
+	var (
+		registry = common.Hash{1}
+		old, to  = common.Address{2}, common.Address{3}
+		header   = &types.Header{Number: big.NewInt(10)}
+	)
+	sign := func(registry common.Hash, number uint64, keys ...*ecdsa.PrivateKey) [][]byte {
+		hash := bokerapi.UpdateContractHash(registry, old, to, protocol.SystemContract, number)
+		sigs := make([][]byte, len(keys))
+		for i, key := range keys {
+			sigs[i], _ = crypto.Sign(hash.Bytes(), key)
+		}
+		return sigs
+	}
+	message := func(payload *bokerapi.UpdateContractPayload) types.Message {
+		data, _ := rlp.EncodeToBytes(payload)
+		return types.NewMessage(validators[0], &to, 0, new(big.Int), new(big.Int), new(big.Int), data, nil, false, protocol.UpdateContract)
+	}
+
+	tests := []struct {
+		name    string
+		payload *bokerapi.UpdateContractPayload
+		ok      bool
+	}{
+		{"quorum", &bokerapi.UpdateContractPayload{Old: old, ContractType: protocol.SystemContract, Number: 20, Signatures: sign(registry, 20, keys...)}, true},
+		{"below quorum", &bokerapi.UpdateContractPayload{Old: old, ContractType: protocol.SystemContract, Number: 20, Signatures: sign(registry, 20, keys[:2]...)}, false},
+		{"stale registry", &bokerapi.UpdateContractPayload{Old: old, ContractType: protocol.SystemContract, Number: 20, Signatures: sign(common.Hash{9}, 20, keys...)}, false},
+		{"other number", &bokerapi.UpdateContractPayload{Old: old, ContractType: protocol.SystemContract, Number: 21, Signatures: sign(registry, 20, keys...)}, false},
+		{"expired", &bokerapi.UpdateContractPayload{Old: old, ContractType: protocol.SystemContract, Number: 10, Signatures: sign(registry, 10, keys...)}, false},
+		{"invalid type", &bokerapi.UpdateContractPayload{Old: old, ContractType: protocol.BinaryContract, Number: 20, Signatures: sign(registry, 20, keys...)}, false},
+		{"same address", &bokerapi.UpdateContractPayload{Old: to, ContractType: protocol.SystemContract, Number: 20, Signatures: sign(registry, 20, keys...)}, false},
+	}
+	for _, tt := range tests {
+		boker := &updateTestBoker{registry: registry}
+		err := scheduleContractUpdate(dposContext, header, message(tt.payload), boker)
+		if tt.ok {
+			want := protocol.ContractUpdate{Old: old, New: to, ContractType: protocol.SystemContract, Number: 20}
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			} else if len(boker.scheduled) != 1 || boker.scheduled[0] != want {
+				t.Errorf("%s: scheduled mismatch: have %v, want [%v]", tt.name, boker.scheduled, want)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+		if len(boker.scheduled) != 0 {
+			t.Errorf("%s: update scheduled: %v", tt.name, boker.scheduled)
+		}
+	}
+
+	//数据无法解码时不记录
+	msg := types.NewMessage(validators[0], &to, 0, new(big.Int), new(big.Int), new(big.Int), []byte{0x01}, nil, false, protocol.UpdateContract)
+	if err := scheduleContractUpdate(dposContext, header, msg, &updateTestBoker{registry: registry}); err == nil {
+		t.Errorf("undecodable payload: expected error")
+	}
+}
@@ -115,6 +115,19 @@ func IntrinsicGas(data []byte, contractCreation, homestead bool) *big.Int {
 	return igas
 }
 
+//返回基础合约交易固定消耗的Gas，基础合约交易不经过EVM计费。普通交易需要执行后才能确定消耗的Gas，未知的交易类型返回false
+func FixedGas(txType protocol.TxType) (*big.Int, bool) {
+
+	switch txType {
+	case protocol.SetPersonalContract, protocol.CancelPersonalContract, protocol.SetSystemContract, protocol.CancelSystemContract,
+		protocol.VoteUser, protocol.VoteEpoch, protocol.AssignToken, protocol.RegisterCandidate, protocol.UserEvent,
+		protocol.SetValidator, protocol.UpdateContract:
+		return new(big.Int), true
+	default:
+		return nil, false
+	}
+}
+
 // NewStateTransition initialises and returns a new state transition object.
 //创建一个交易的状态对象
 func NewStateTransition(evm *vm.EVM, msg Message, gp *GasPool) *StateTransition {
@@ -0,0 +1,343 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+const (
+	tokenIndexChunk     = 1024             //后台补建索引时每次持有链锁处理的区块数量
+	tokenIndexLiveLimit = 16               //写入新区块时最多顺带索引的区块数量
+	tokenIndexRecheck   = 10 * time.Second //后台补建索引完成后重新检查的间隔
+)
+
+var (
+	ErrTokenIndexDisabled = errors.New("token transfer index not enabled")   //通证转账索引没有开启
+	errTokenIndexMissing  = errors.New("token transfer index block missing") //索引需要的区块数据不存在
+
+	TokenTransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")) //ERC-20转账事件
+)
+
+//开启ERC-20转账索引，并在后台从上次索引的位置补建到当前区块
+func (bc *BlockChain) StartTokenIndex() {
+
+	if !atomic.CompareAndSwapInt32(&bc.tokenIndex, 0, 1) {
+		return
+	}
+	bc.wg.Add(1)
+	go bc.tokenIndexLoop()
+}
+
+//判断是否开启了ERC-20转账索引
+func (bc *BlockChain) TokenIndexEnabled() bool {
+	return atomic.LoadInt32(&bc.tokenIndex) == 1
+}
+
+//后台补建ERC-20转账索引，补建完成后定期检查是否有遗漏的区块
+func (bc *BlockChain) tokenIndexLoop() {
+	defer bc.wg.Done()
+
+	ticker := time.NewTicker(tokenIndexRecheck)
+	defer ticker.Stop()
+
+	for {
+		bc.mu.Lock()
+		head, done, err := bc.updateTokenIndex(tokenIndexChunk)
+		bc.mu.Unlock()
+
+		if err != nil {
+			log.Error("Failed to update token transfer index", "err", err)
+			done = true
+		}
+		if !done {
+			log.Info("Backfilling token transfer index", "indexed", head, "head", bc.CurrentBlock().NumberU64())
+			select {
+			case <-bc.quit:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+//将ERC-20转账索引更新到当前的规范链：先回退已经不在规范链上的区块，再最多向前索引limit个区块，
+//返回索引到的区块号以及是否已经追上链头，调用者需要持有bc.mu锁
+func (bc *BlockChain) updateTokenIndex(limit int) (uint64, bool, error) {
+
+	head, err := bc.tokenIndexHead()
+	if err != nil {
+		return 0, false, err
+	}
+
+	//回退分叉后不在规范链上的区块
+	for GetCanonicalHash(bc.chainDb, head.Number.Uint64()) != head.Hash() {
+		if head, err = bc.unindexTokenTransfers(head); err != nil {
+			return 0, false, err
+		}
+	}
+
+	//向前索引规范链上的区块
+	number, current := head.Number.Uint64(), bc.currentBlock.NumberU64()
+	for ; number < current && limit > 0; limit-- {
+		block := bc.GetBlockByNumber(number + 1)
+		if block == nil {
+			return number, false, errTokenIndexMissing
+		}
+		if err := bc.indexTokenTransfers(block); err != nil {
+			return number, false, err
+		}
+		number++
+	}
+	return number, number >= current, nil
+}
+
+//将ERC-20转账索引回退到指定的区块号，区块数据被删除前调用，调用者需要持有bc.mu锁
+func (bc *BlockChain) rewindTokenIndex(number uint64) error {
+
+	if GetTokenIndexHead(bc.chainDb) == (common.Hash{}) {
+		return nil
+	}
+	head, err := bc.tokenIndexHead()
+	if err != nil {
+		return err
+	}
+	for head.Number.Uint64() > number {
+		if head, err = bc.unindexTokenTransfers(head); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//获取ERC-20转账索引最后索引的区块头，从未建立过索引时从创世块开始
+func (bc *BlockChain) tokenIndexHead() (*types.Header, error) {
+
+	hash := GetTokenIndexHead(bc.chainDb)
+	if hash == (common.Hash{}) {
+		WriteTokenIndexHead(bc.chainDb, bc.genesisBlock.Hash())
+		return bc.genesisBlock.Header(), nil
+	}
+	head := bc.GetHeaderByHash(hash)
+	if head == nil {
+		return nil, errTokenIndexMissing
+	}
+	return head, nil
+}
+
+//从区块的回执中解析ERC-20转账事件
+func (bc *BlockChain) blockTokenTransfers(block *types.Block) ([]*TokenTransferEntry, error) {
+
+	if len(block.Transactions()) == 0 {
+		return nil, nil
+	}
+	receipts := GetBlockReceipts(bc.chainDb, block.Hash(), block.NumberU64())
+	if len(receipts) != len(block.Transactions()) {
+		return nil, errTokenIndexMissing
+	}
+	return tokenTransfers(block.NumberU64(), block.Transactions(), receipts), nil
+}
+
+//将区块中的转账加入转出方和接收方的索引，同时记录账户涉及的通证
+func (bc *BlockChain) indexTokenTransfers(block *types.Block) error {
+
+	transfers, err := bc.blockTokenTransfers(block)
+	if err != nil {
+		return err
+	}
+	var (
+		batch  = bc.chainDb.NewBatch()
+		counts = make(map[common.Address]uint64)
+		tokens = make(map[common.Address]map[common.Address]bool)
+	)
+	for _, transfer := range transfers {
+		for _, addr := range transfer.addresses() {
+			count, ok := counts[addr]
+			if !ok {
+				count = GetTokenTransferCount(bc.chainDb, addr)
+			}
+			if err := WriteTokenTransferEntry(batch, addr, count, transfer); err != nil {
+				return err
+			}
+			counts[addr] = count + 1
+
+			if tokens[addr] == nil {
+				tokens[addr] = make(map[common.Address]bool)
+			}
+			tokens[addr][transfer.Token] = true
+		}
+	}
+	for addr, count := range counts {
+		if err := WriteTokenTransferCount(batch, addr, count); err != nil {
+			return err
+		}
+	}
+	for addr, held := range tokens {
+		if err := bc.addHeldTokens(batch, addr, held); err != nil {
+			return err
+		}
+	}
+	WriteTokenIndexHead(batch, block.Hash())
+	return batch.Write()
+}
+
+//将新出现的通证加入账户的通证列表，列表只增不减，分叉回退时不删除
+func (bc *BlockChain) addHeldTokens(batch ethdb.Putter, addr common.Address, held map[common.Address]bool) error {
+
+	count := GetHeldTokenCount(bc.chainDb, addr)
+	for _, token := range GetHeldTokens(bc.chainDb, addr) {
+		delete(held, token)
+	}
+	if len(held) == 0 {
+		return nil
+	}
+	added := make([]common.Address, 0, len(held))
+	for token := range held {
+		added = append(added, token)
+	}
+	sort.Slice(added, func(i, j int) bool { return bytes.Compare(added[i][:], added[j][:]) < 0 })
+	for _, token := range added {
+		if err := WriteHeldToken(batch, addr, count, token); err != nil {
+			return err
+		}
+		count++
+	}
+	return WriteHeldTokenCount(batch, addr, count)
+}
+
+//将区块中的转账从索引中删除，返回父区块头作为新的索引位置
+func (bc *BlockChain) unindexTokenTransfers(header *types.Header) (*types.Header, error) {
+
+	parent := bc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	block := bc.GetBlock(header.Hash(), header.Number.Uint64())
+	if parent == nil || block == nil {
+		return nil, errTokenIndexMissing
+	}
+	transfers, err := bc.blockTokenTransfers(block)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		batch  = bc.chainDb.NewBatch()
+		counts = make(map[common.Address]uint64)
+	)
+	for _, transfer := range transfers {
+		for _, addr := range transfer.addresses() {
+			count, ok := counts[addr]
+			if !ok {
+				count = GetTokenTransferCount(bc.chainDb, addr)
+			}
+			if count > 0 {
+				counts[addr] = count - 1
+			}
+		}
+	}
+
+	//先更新计数再删除条目，中途退出时多余的条目会在下次索引时被覆盖
+	for addr, count := range counts {
+		if err := WriteTokenTransferCount(batch, addr, count); err != nil {
+			return nil, err
+		}
+	}
+	WriteTokenIndexHead(batch, parent.Hash())
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+	for addr, count := range counts {
+		for seq := count; ; seq++ {
+			entry := GetTokenTransferEntry(bc.chainDb, addr, seq)
+			if entry == nil {
+				break
+			}
+			DeleteTokenTransferEntry(bc.chainDb, addr, seq)
+		}
+	}
+	return parent, nil
+}
+
+//解析回执中的ERC-20转账事件，事件有转出方和接收方两个索引参数，数据为32字节的金额(ERC-721的编号是索引参数，不会被匹配)
+func tokenTransfers(number uint64, txs types.Transactions, receipts types.Receipts) []*TokenTransferEntry {
+
+	var (
+		transfers []*TokenTransferEntry
+		logIndex  uint64
+	)
+	for i, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			if len(l.Topics) == 3 && l.Topics[0] == TokenTransferTopic && len(l.Data) == 32 {
+				transfers = append(transfers, &TokenTransferEntry{
+					BlockNumber: number,
+					TxHash:      txs[i].Hash(),
+					LogIndex:    logIndex,
+					Token:       l.Address,
+					From:        common.BytesToAddress(l.Topics[1].Bytes()),
+					To:          common.BytesToAddress(l.Topics[2].Bytes()),
+					Value:       new(big.Int).SetBytes(l.Data),
+				})
+			}
+			logIndex++
+		}
+	}
+	return transfers
+}
+
+//转账涉及的账户，铸造和销毁时的零地址不建立索引
+func (t *TokenTransferEntry) addresses() []common.Address {
+
+	var addrs []common.Address
+	if t.From != (common.Address{}) {
+		addrs = append(addrs, t.From)
+	}
+	if t.To != (common.Address{}) && t.To != t.From {
+		addrs = append(addrs, t.To)
+	}
+	return addrs
+}
+
+//按区块范围分页查询账户的ERC-20转账，start为上一页返回的位置(为空时从区块范围的起点开始)，
+//返回最多limit个条目，以及还有更多条目时下一页的起始位置
+func GetTokenTransfers(db DatabaseReader, addr common.Address, from, to uint64, start *uint64, limit int) ([]*TokenTransferEntry, *uint64) {
+
+	count := GetTokenTransferCount(db, addr)
+
+	var seq uint64
+	if start != nil {
+		seq = *start
+	} else {
+		seq = uint64(sort.Search(int(count), func(i int) bool {
+			entry := GetTokenTransferEntry(db, addr, uint64(i))
+			return entry == nil || entry.BlockNumber >= from
+		}))
+	}
+	var entries []*TokenTransferEntry
+	for ; seq < count; seq++ {
+		entry := GetTokenTransferEntry(db, addr, seq)
+		if entry == nil || entry.BlockNumber > to {
+			return entries, nil
+		}
+		if entry.BlockNumber < from {
+			continue
+		}
+		if len(entries) >= limit {
+			next := seq
+			return entries, &next
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
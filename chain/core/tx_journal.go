@@ -20,7 +20,9 @@ import (
 	"errors"
 	"io"
 	"os"
+	"time"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/log"
@@ -40,23 +42,37 @@ type devNull struct{}
 func (*devNull) Write(p []byte) (n int, err error) { return len(p), nil }
 func (*devNull) Close() error                      { return nil }
 
+// journalEntry is the journal record of a gas-free system transaction (token
+// assignment or epoch vote), which is only valid within the token noder slot
+// it was submitted in and thus carries the time it was first journaled.
+// Other transactions are journaled bare. The two are told apart by the first
+// element of the record: an entry starts with the transaction list, while a
+// bare transaction starts with its nonce.
+type journalEntry struct {
+	Tx   *types.Transaction
+	Time uint64 // Unix time the transaction was first journaled at
+}
+
 // txJournal is a rotating log of transactions with the aim of storing locally
 // created transactions to allow non-executed ones to survive node restarts.
 type txJournal struct {
-	path   string         // Filesystem path to store the transactions at
-	writer io.WriteCloser // Output stream to write new transactions into
+	path   string                 // Filesystem path to store the transactions at
+	writer io.WriteCloser         // Output stream to write new transactions into
+	times  map[common.Hash]uint64 // Journal times of the system transactions
 }
 
 // newTxJournal creates a new transaction journal to
 func newTxJournal(path string) *txJournal {
 	return &txJournal{
-		path: path,
+		path:  path,
+		times: make(map[common.Hash]uint64),
 	}
 }
 
 // load parses a transaction journal dump from disk, loading its contents into
-// the specified pool.
-func (journal *txJournal) load(add func(*types.Transaction) error) error {
+// the specified pool. System transactions are passed along with the time they
+// were first journaled, other transactions with the zero time.
+func (journal *txJournal) load(add func(*types.Transaction, time.Time) error) error {
 	// Skip the parsing if the journal file doens't exist at all
 	if _, err := os.Stat(journal.path); os.IsNotExist(err) {
 		return nil
@@ -79,8 +95,8 @@ func (journal *txJournal) load(add func(*types.Transaction) error) error {
 	var failure error
 	for {
 		// Parse the next transaction and terminate on error
-		tx := new(types.Transaction)
-		if err = stream.Decode(tx); err != nil {
+		tx, journaled, err := decodeJournalRecord(stream)
+		if err != nil {
 			if err != io.EOF {
 				failure = err
 			}
@@ -88,8 +104,12 @@ func (journal *txJournal) load(add func(*types.Transaction) error) error {
 		}
 		// Import the transaction and bump the appropriate progress counters
 		total++
-		if err = add(tx); err != nil {
+		if !journaled.IsZero() {
+			journal.times[tx.Hash()] = uint64(journaled.Unix())
+		}
+		if err = add(tx, journaled); err != nil {
 			log.Debug("Failed to add journaled transaction", "err", err)
+			delete(journal.times, tx.Hash())
 			dropped++
 			continue
 		}
@@ -99,12 +119,54 @@ func (journal *txJournal) load(add func(*types.Transaction) error) error {
 	return failure
 }
 
+// decodeJournalRecord parses the next journal record, which is either a bare
+// transaction or a timestamped system transaction entry.
+func decodeJournalRecord(stream *rlp.Stream) (*types.Transaction, time.Time, error) {
+	raw, err := stream.Raw()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	kind, _, _, err := rlp.Split(content)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if kind != rlp.List {
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(raw, tx); err != nil {
+			return nil, time.Time{}, err
+		}
+		return tx, time.Time{}, nil
+	}
+	var entry journalEntry
+	if err := rlp.DecodeBytes(raw, &entry); err != nil {
+		return nil, time.Time{}, err
+	}
+	return entry.Tx, time.Unix(int64(entry.Time), 0), nil
+}
+
+// encode writes the journal record of a transaction, timestamping system
+// transactions with the time they were first journaled.
+func (journal *txJournal) encode(w io.Writer, tx *types.Transaction) error {
+	if !protocol.IsGasFreeSystemTx(tx.Type()) {
+		return rlp.Encode(w, tx)
+	}
+	hash := tx.Hash()
+	if _, ok := journal.times[hash]; !ok {
+		journal.times[hash] = uint64(time.Now().Unix())
+	}
+	return rlp.Encode(w, &journalEntry{Tx: tx, Time: journal.times[hash]})
+}
+
 // insert adds the specified transaction to the local disk journal.
 func (journal *txJournal) insert(tx *types.Transaction) error {
 	if journal.writer == nil {
 		return errNoActiveJournal
 	}
-	if err := rlp.Encode(journal.writer, tx); err != nil {
+	if err := journal.encode(journal.writer, tx); err != nil {
 		return err
 	}
 	return nil
@@ -128,10 +190,14 @@ func (journal *txJournal) rotate(all map[common.Address]types.Transactions) erro
 	if err != nil {
 		return err
 	}
-	journaled := 0
+	journaled, times := 0, journal.times
+	journal.times = make(map[common.Hash]uint64)
 	for _, txs := range all {
 		for _, tx := range txs {
-			if err = rlp.Encode(replacement, tx); err != nil {
+			if t, ok := times[tx.Hash()]; ok {
+				journal.times[tx.Hash()] = t
+			}
+			if err = journal.encode(replacement, tx); err != nil {
 				replacement.Close()
 				return err
 			}
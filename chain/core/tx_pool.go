@@ -27,10 +27,6 @@ const (
 	rmTxChanSize = 10
 )
 
-const (
-	MaxOverSize = 32 * 1024
-)
-
 var (
 	ErrInvalidSender = errors.New("invalid sender")          //如果交易包含无效签名
 	ErrNonceTooLow   = errors.New("nonce too low")           //Nonce太低
@@ -49,8 +45,12 @@ var (
 	// ErrOversizedData is returned if the input data of a transaction is greater
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
-	ErrOversizedData = errors.New("oversized data")           //超大数据
-	ErrInvalidType   = errors.New("unknown transaction type") //未知交易类型
+	ErrOversizedData  = errors.New("oversized data")           //超大数据
+	ErrInvalidType    = errors.New("unknown transaction type") //未知交易类型
+	ErrInvalidChainId = errors.New("invalid chain id")         //交易签名中的链ID与本节点配置的链ID不一致
+
+	ErrSystemTxExpired = errors.New("system transaction slot expired") //系统基础合约交易发起时所在的分币时间片已经结束
+	ErrNotTokenNoder   = errors.New("sender is not the token noder")   //系统基础合约交易的发送者不是当时的分币节点
 )
 
 var (
@@ -74,6 +74,10 @@ var (
 	// General tx metrics
 	invalidTxCounter     = metrics.NewCounter("txpool/invalid")
 	underpricedTxCounter = metrics.NewCounter("txpool/underpriced")
+
+	//交易池大小的度量标准
+	pendingGauge = metrics.NewGauge("txpool/pending")
+	queuedGauge  = metrics.NewGauge("txpool/queued")
 )
 
 // TxStatus is the current status of a transaction as seen py the pool.
@@ -90,6 +94,7 @@ const (
 // some pre checks in tx pool and event subscribers.
 type blockChain interface {
 	CurrentBlock() *types.Block
+	Genesis() *types.Block
 	GetBlock(hash common.Hash, number uint64) *types.Block
 	StateAt(root common.Hash) (*state.StateDB, error)
 	SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription
@@ -163,6 +168,7 @@ type TxPool struct {
 	currentState  *state.StateDB                     //区块链头部当前状态
 	pendingState  *state.ManagedState                //Pending state tracking virtual nonces
 	currentMaxGas *big.Int                           //当前的交易Gas上限
+	maxTxSize     uint64                             //当前的交易大小上限
 	locals        *accountSet                        //Set of local transaction to exepmt from evicion rules
 	journal       *txJournal                         //日志本地交易备份到磁盘
 	pending       map[common.Address]*txList         //所有当前可处理的交易
@@ -185,7 +191,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:      config,
 		chainconfig: chainconfig,
 		chain:       chain,
-		signer:      types.HomesteadSigner{},
+		signer:      types.MakeSigner(chainconfig, chain.CurrentBlock().Number()),
 		pending:     make(map[common.Address]*txList),
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
@@ -194,7 +200,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 	}
 
-	pool.locals = newAccountSet(types.HomesteadSigner{})
+	pool.locals = newAccountSet(pool.signer)
 	pool.priced = newTxPricedList(&pool.all)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
@@ -203,7 +209,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	if !config.NoLocals && config.Journal != "" {
 		pool.journal = newTxJournal(config.Journal)
 
-		if err := pool.journal.load(pool.AddLocal); err != nil {
+		if err := pool.journal.load(pool.addJournaled); err != nil {
 			log.Warn("Failed to load transaction journal", "err", err)
 		}
 		if err := pool.journal.rotate(pool.local()); err != nil {
@@ -269,6 +275,9 @@ func (pool *TxPool) loop() {
 			stales := pool.priced.stales
 			pool.mu.RUnlock()
 
+			pendingGauge.Update(int64(pending))
+			queuedGauge.Update(int64(queued))
+
 			if pending != prevPending || queued != prevQueued || stales != prevStales {
 				log.Debug("Transaction pool status report", "executable", pending, "queued", queued, "stales", stales)
 				prevPending, prevQueued, prevStales = pending, queued, stales
@@ -395,6 +404,11 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	pool.maxTxSize = pool.chainconfig.MaxTxSize(new(big.Int).Add(newHead.Number, common.Big1))
+
+	//下一个区块到达EIP155签名分叉之后使用EIP155签名验证交易
+	pool.signer = types.MakeSigner(pool.chainconfig, new(big.Int).Add(newHead.Number, common.Big1))
+	pool.locals.signer = pool.signer
 	pool.addTxsLocked(reinject, false)
 
 	//验证pending transaction池里面的交易， 会移除所有已经存在区块链里面的交易，或者是因为其他交易导致不可用的交易(比如有一个更高的gasPrice)
@@ -530,6 +544,14 @@ func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return pending, nil
 }
 
+//返回交易池当前视为本地的账户
+func (pool *TxPool) Locals() []common.Address {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	return pool.locals.flatten()
+}
+
 //检索按来源分组的所有当前已知的本地交易帐户并按nonce排序。 返回的交易集是一个副本，可以是通过调用代码自由修改。
 func (pool *TxPool) local() map[common.Address]types.Transactions {
 
@@ -555,18 +577,13 @@ func (pool *TxPool) normalValidateTx(tx *types.Transaction, local bool) error {
 		"value", tx.Value(),
 		"pool.currentMaxGas", pool.currentMaxGas)
 
-	//Dos攻击判断
-	if tx.Size() > MaxOverSize {
-		return ErrOversizedData
-	}
-
 	//如果当前的最大Gas数量小于交易所标记的Gas数量，则放回GasLimit错误(这里需要添加针对基础合约类型的判断，因为基础合约采用的Gas为最大值)
 	if pool.currentMaxGas.Cmp(tx.Gas()) < 0 {
 		return ErrGasLimit
 	}
 
 	//判断交易是否已经经过正确的签名
-	from, err := types.Sender(types.HomesteadSigner{}, tx)
+	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
 		return ErrInvalidSender
 	}
@@ -606,8 +623,7 @@ func (pool *TxPool) normalValidateTx(tx *types.Transaction, local bool) error {
 func (pool *TxPool) baseValidateTx(tx *types.Transaction, local bool) error {
 
 	//判断交易是否已经经过正确的签名
-	//from, err := types.Sender(pool.signer, tx)
-	from, err := types.Sender(types.HomesteadSigner{}, tx)
+	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
 		return ErrInvalidSender
 	}
@@ -616,6 +632,14 @@ func (pool *TxPool) baseValidateTx(tx *types.Transaction, local bool) error {
 	if pool.currentState.GetNonce(from) > tx.Nonce() {
 		return ErrNonceTooLow
 	}
+
+	//免Gas的用户交易需要限制单个账户在交易池中的数量，防止垃圾交易
+	if limit := pool.chainconfig.GasFree; limit != nil && limit.AccountPending > 0 && protocol.IsGasFreeUserTx(tx.Type()) {
+		if countGasFree(pool.pending[from], tx.Nonce())+countGasFree(pool.queue[from], tx.Nonce()) >= limit.AccountPending {
+			log.Warn("TxPool Discarding rate limited gas-free transaction", "from", from, "type", tx.Type(), "limit", limit.AccountPending)
+			return ErrGasFreeAccountLimit
+		}
+	}
 	return nil
 }
 
@@ -627,11 +651,23 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrNegativeValue
 	}
 
+	//Dos攻击判断，所有类型的交易都不能超过链配置中的交易大小限制
+	if size, limit := uint64(tx.Size()), pool.maxTxSize; size > limit {
+		log.Debug("Rejecting oversized transaction", "hash", tx.Hash(), "size", size, "limit", limit)
+		return ErrOversizedData
+	}
+
+	//带重放保护的交易必须是为本链签名的，防止测试网和主网之间的交易重放
+	if tx.Protected() && tx.ChainId().Cmp(pool.chainconfig.ChainId) != 0 {
+		log.Debug("Rejecting transaction for another chain", "hash", tx.Hash(), "chainId", tx.ChainId(), "want", pool.chainconfig.ChainId)
+		return ErrInvalidChainId
+	}
+
 	if types.IsBinary(tx.Type()) {
 
 		//普通交易类型
 		return pool.normalValidateTx(tx, local)
-	} else if (tx.Type() >= protocol.SetValidator) && (tx.Type() <= protocol.AssignToken) {
+	} else if (tx.Type() >= protocol.SetValidator) && (tx.Type() <= protocol.UpdateContract) {
 
 		//基础合约交易类型
 		return pool.baseValidateTx(tx, local)
@@ -689,7 +725,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	//log.Info("(pool *TxPool) add GlobalQueue")
 
 	//根据交易签名获取本次交易的from用户
-	from, _ := types.Sender(types.HomesteadSigner{}, tx)
+	from, _ := types.Sender(pool.signer, tx)
 
 	//判断本次交易的Nonce值是否已经存在于此用户的交易列表中
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
@@ -749,7 +785,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	//log.Info("(pool *TxPool) enqueueTx", "hash", hash)
 
 	//尝试将交易插入到将来的队列中
-	from, _ := types.Sender(types.HomesteadSigner{}, tx)
+	from, _ := types.Sender(pool.signer, tx)
 	if pool.queue[from] == nil {
 		pool.queue[from] = newTxList(false)
 	}
@@ -832,6 +868,60 @@ func (pool *TxPool) AddLocal(tx *types.Transaction) error {
 	return pool.addTx(tx, !pool.config.NoLocals)
 }
 
+//从日志中恢复本地交易，系统基础合约交易需要重新校验分币节点和时间片
+func (pool *TxPool) addJournaled(tx *types.Transaction, journaled time.Time) error {
+
+	if protocol.IsGasFreeSystemTx(tx.Type()) {
+		if err := pool.validateJournaledTx(tx, journaled, time.Now()); err != nil {
+			log.Warn("Dropping stale journaled system transaction", "hash", tx.Hash(), "type", tx.Type(), "err", err)
+			return err
+		}
+	}
+	return pool.AddLocal(tx)
+}
+
+//校验从日志中恢复的系统基础合约交易：按照当前的DPOS上下文交易的发送者仍然是分币节点，并且交易加入日志时所在的分币时间片还没有结束
+func (pool *TxPool) validateJournaledTx(tx *types.Transaction, journaled time.Time, now time.Time) error {
+
+	//旧格式的日志中没有记录时间，使用交易的发起时间
+	if journaled.IsZero() {
+		journaled = time.Unix(tx.Time().Int64(), 0)
+	}
+	genesis, current := pool.chain.Genesis(), pool.chain.CurrentBlock()
+	if genesis == nil || current == nil || current.DposContext == nil {
+		return protocol.ErrInvalidTokenNoder
+	}
+	firstTimer := genesis.Time().Int64()
+
+	//分币时间片
+	slot := func(t int64) int64 { return (t - firstTimer) / protocol.TokenNoderInterval }
+	if slot(journaled.Unix()) != slot(now.Unix()) {
+		return ErrSystemTxExpired
+	}
+
+	//分配通证交易在执行时要求交易时间正好是分币时间片的开始，其它交易按照发起时间查找分币节点
+	var (
+		tokenNoder common.Address
+		err        error
+	)
+	if tx.Type() == protocol.AssignToken {
+		tokenNoder, err = current.DposContext.GetTokenNoder(tx.Time().Int64(), firstTimer)
+	} else {
+		tokenNoder, err = current.DposContext.GetNowTokenNoder(firstTimer, tx.Time().Int64())
+	}
+	if err != nil {
+		return err
+	}
+	from, err := types.Sender(pool.signer, tx)
+	if err != nil {
+		return ErrInvalidSender
+	}
+	if from != tokenNoder {
+		return ErrNotTokenNoder
+	}
+	return nil
+}
+
 //网络中接收的单条交易
 func (pool *TxPool) AddRemote(tx *types.Transaction) error {
 
@@ -869,7 +959,7 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 
 	//如果我们添加了新的交易，请运行促销检查并返回
 	if !replace {
-		from, _ := types.Sender(types.HomesteadSigner{}, tx)
+		from, _ := types.Sender(pool.signer, tx)
 		pool.promoteExecutables([]common.Address{from})
 	}
 	return nil
@@ -896,8 +986,7 @@ func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) []error {
 
 			//replace 是替换的意思， 如果不是替换，那么就说明状态有更新，有可以下一步处理的可能。
 			if !replace {
-				//from, _ := types.Sender(pool.signer, tx) // already validated
-				from, _ := types.Sender(types.HomesteadSigner{}, tx)
+				from, _ := types.Sender(pool.signer, tx) // already validated
 				dirty[from] = struct{}{}
 			}
 		}
@@ -925,8 +1014,7 @@ func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 	status := make([]TxStatus, len(hashes))
 	for i, hash := range hashes {
 		if tx := pool.all[hash]; tx != nil {
-			//from, _ := types.Sender(pool.signer, tx) // already validated
-			from, _ := types.Sender(types.HomesteadSigner{}, tx)
+			from, _ := types.Sender(pool.signer, tx) // already validated
 			if pool.pending[from].txs.items[tx.Nonce()] != nil {
 				status[i] = TxStatusPending
 			} else {
@@ -955,7 +1043,7 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 	if !ok {
 		return
 	}
-	addr, _ := types.Sender(types.HomesteadSigner{}, tx)
+	addr, _ := types.Sender(pool.signer, tx)
 
 	// Remove it from the list of known transactions
 	delete(pool.all, hash)
@@ -1293,3 +1381,12 @@ func (as *accountSet) containsTx(tx *types.Transaction) bool {
 func (as *accountSet) add(addr common.Address) {
 	as.accounts[addr] = struct{}{}
 }
+
+// flatten returns the list of addresses within this set.
+func (as *accountSet) flatten() []common.Address {
+	accounts := make([]common.Address, 0, len(as.accounts))
+	for account := range as.accounts {
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
@@ -26,6 +26,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/state"
 	"github.com/Bokerchain/Boker/chain/core/types"
@@ -56,6 +57,10 @@ func (bc *testBlockChain) CurrentBlock() *types.Block {
 	}, nil, nil, nil)
 }
 
+func (bc *testBlockChain) Genesis() *types.Block {
+	return bc.CurrentBlock()
+}
+
 func (bc *testBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
 	return bc.CurrentBlock()
 }
@@ -73,7 +78,7 @@ func transaction(nonce uint64, gaslimit *big.Int, key *ecdsa.PrivateKey) *types.
 }
 
 func pricedTransaction(nonce uint64, gaslimit, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
-	tx, _ := types.SignTx(types.NewTransaction(types.Binary, nonce, common.Address{}, big.NewInt(100), gaslimit, gasprice, nil), types.HomesteadSigner{}, key)
+	tx, _ := types.SignTx(types.NewTransaction(protocol.Binary, nonce, common.Address{}, big.NewInt(100), gaslimit, gasprice, nil), types.HomesteadSigner{}, key)
 	return tx
 }
 
@@ -321,7 +326,7 @@ func TestTransactionNegativeValue(t *testing.T) {
 	pool, key := setupTxPool()
 	defer pool.Stop()
 
-	tx, _ := types.SignTx(types.NewTransaction(types.Binary, 0, common.Address{}, big.NewInt(-1), big.NewInt(100), big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	tx, _ := types.SignTx(types.NewTransaction(protocol.Binary, 0, common.Address{}, big.NewInt(-1), big.NewInt(100), big.NewInt(1), nil), types.HomesteadSigner{}, key)
 	from, _ := deriveSender(tx)
 	pool.currentState.AddBalance(from, big.NewInt(1))
 	if err := pool.AddRemote(tx); err != ErrNegativeValue {
@@ -329,6 +334,117 @@ func TestTransactionNegativeValue(t *testing.T) {
 	}
 }
 
+func TestTransactionWrongChainId(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	other := new(big.Int).Add(params.TestChainConfig.ChainId, big.NewInt(1))
+	tx, _ := types.SignTx(types.NewTransaction(protocol.Binary, 0, common.Address{}, big.NewInt(1), big.NewInt(100000), big.NewInt(1), nil), types.NewEIP155Signer(other), key)
+	if err := pool.AddRemote(tx); err != ErrInvalidChainId {
+		t.Error("expected", ErrInvalidChainId, "got", err)
+	}
+}
+
+// Tests that an account can't keep more gas-free base transactions in the pool
+// than allowed, while replacing one of its pending ones is still accepted.
+func TestTransactionGasFreeAccountLimit(t *testing.T) {
+	t.Parallel()
+
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, big.NewInt(1000000), new(event.Feed)}
+
+	config := *params.TestChainConfig
+	config.GasFree = &params.GasFreeConfig{AccountPending: 2}
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	vote := func(nonce uint64, payload []byte, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewBaseTransaction(protocol.VoteUser, nonce, common.Address{}, new(big.Int), payload), types.HomesteadSigner{}, key)
+		return tx
+	}
+	// Fill up the account's allowance, one executable and one queued transaction
+	if err := pool.AddRemote(vote(0, nil, key)); err != nil {
+		t.Fatalf("failed to add first gas-free transaction: %v", err)
+	}
+	if err := pool.AddRemote(vote(2, nil, key)); err != nil {
+		t.Fatalf("failed to add second gas-free transaction: %v", err)
+	}
+	if err := pool.AddRemote(vote(1, nil, key)); err != ErrGasFreeAccountLimit {
+		t.Fatalf("over limit transaction error mismatch: have %v, want %v", err, ErrGasFreeAccountLimit)
+	}
+	// Replacements don't count against the limit, neither do other accounts
+	pool.mu.Lock()
+	for i, nonce := range []uint64{0, 2} {
+		if err := pool.validateTx(vote(nonce, []byte{0x01}, key), false); err != nil {
+			t.Errorf("replacement %d: unexpected error: %v", i, err)
+		}
+	}
+	pool.mu.Unlock()
+
+	if err := pool.AddRemote(vote(0, nil, other)); err != nil {
+		t.Errorf("failed to add gas-free transaction from other account: %v", err)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the pool only accepts replay protected transactions once the next
+// block is past the EIP155 signer fork, while unprotected ones are accepted on
+// both sides of it.
+func TestTransactionReplayProtectionFork(t *testing.T) {
+	t.Parallel()
+
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, big.NewInt(1000000), new(event.Feed)}
+
+	config := *params.TestChainConfig
+	config.EIP155SignerBlock = big.NewInt(2)
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	sign := func(nonce uint64, signer types.Signer) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(protocol.Binary, nonce, common.Address{}, big.NewInt(1), big.NewInt(100000), big.NewInt(1), nil), signer, key)
+		return tx
+	}
+	eip155 := types.NewEIP155Signer(config.ChainId)
+
+	// The next block is 1, before the fork
+	if err := pool.AddRemote(sign(0, eip155)); err != ErrInvalidSender {
+		t.Errorf("protected transaction before fork: have %v, want %v", err, ErrInvalidSender)
+	}
+	if err := pool.AddRemote(sign(0, types.HomesteadSigner{})); err != nil {
+		t.Errorf("unprotected transaction before fork: %v", err)
+	}
+	// Move the head to block 1, the next block is past the fork
+	pool.lockedReset(nil, &types.Header{Number: big.NewInt(1), GasLimit: big.NewInt(1000000)})
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	if err := pool.AddRemote(sign(1, eip155)); err != nil {
+		t.Errorf("protected transaction after fork: %v", err)
+	}
+	if err := pool.AddRemote(sign(2, types.HomesteadSigner{})); err != nil {
+		t.Errorf("unprotected transaction after fork: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending+queued != 3 {
+		t.Errorf("transaction count mismatch: have %d, want 3", pending+queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 func TestTransactionChainFork(t *testing.T) {
 	t.Parallel()
 
@@ -377,9 +493,9 @@ func TestTransactionDoubleNonce(t *testing.T) {
 	resetState()
 
 	signer := types.HomesteadSigner{}
-	tx1, _ := types.SignTx(types.NewTransaction(types.Binary, 0, common.Address{}, big.NewInt(100), big.NewInt(100000), big.NewInt(1), nil), signer, key)
-	tx2, _ := types.SignTx(types.NewTransaction(types.Binary, 0, common.Address{}, big.NewInt(100), big.NewInt(1000000), big.NewInt(2), nil), signer, key)
-	tx3, _ := types.SignTx(types.NewTransaction(types.Binary, 0, common.Address{}, big.NewInt(100), big.NewInt(1000000), big.NewInt(1), nil), signer, key)
+	tx1, _ := types.SignTx(types.NewTransaction(protocol.Binary, 0, common.Address{}, big.NewInt(100), big.NewInt(100000), big.NewInt(1), nil), signer, key)
+	tx2, _ := types.SignTx(types.NewTransaction(protocol.Binary, 0, common.Address{}, big.NewInt(100), big.NewInt(1000000), big.NewInt(2), nil), signer, key)
+	tx3, _ := types.SignTx(types.NewTransaction(protocol.Binary, 0, common.Address{}, big.NewInt(100), big.NewInt(1000000), big.NewInt(1), nil), signer, key)
 
 	// Add the first two transaction, ensure higher priced stays only
 	if replace, err := pool.add(tx1, false); err != nil || replace {
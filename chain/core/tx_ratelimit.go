@@ -0,0 +1,71 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/params"
+)
+
+var (
+	ErrGasFreeAccountLimit = errors.New("gas-free transaction account limit exceeded") //账户的免Gas交易数量超过限制
+	ErrGasFreeBlockLimit   = errors.New("gas-free transaction block limit exceeded")   //区块的免Gas交易数量超过限制
+)
+
+//出块时统计区块中已经打包的免Gas交易数量，用于对免Gas交易进行限流
+type GasFreeCounter struct {
+	config   *params.GasFreeConfig
+	total    uint64
+	accounts map[common.Address]uint64
+}
+
+//根据链配置创建免Gas交易计数器，没有配置限流时所有交易都允许打包
+func NewGasFreeCounter(config *params.ChainConfig) *GasFreeCounter {
+
+	return &GasFreeCounter{
+		config:   config.GasFree,
+		accounts: make(map[common.Address]uint64),
+	}
+}
+
+//判断交易是否还能被打包进当前区块
+func (c *GasFreeCounter) Allow(from common.Address, tx *types.Transaction) error {
+
+	if c.config == nil || !protocol.IsGasFreeUserTx(tx.Type()) {
+		return nil
+	}
+	if c.config.PerBlock > 0 && c.total >= c.config.PerBlock {
+		return ErrGasFreeBlockLimit
+	}
+	if c.config.AccountPerBlock > 0 && c.accounts[from] >= c.config.AccountPerBlock {
+		return ErrGasFreeAccountLimit
+	}
+	return nil
+}
+
+//记录一笔已经打包的交易
+func (c *GasFreeCounter) Add(from common.Address, tx *types.Transaction) {
+
+	if !protocol.IsGasFreeUserTx(tx.Type()) {
+		return
+	}
+	c.total++
+	c.accounts[from]++
+}
+
+//统计账户在交易列表中的免Gas交易数量(不包括与nonce相同的交易，即将被替换的交易)
+func countGasFree(list *txList, nonce uint64) uint64 {
+
+	if list == nil {
+		return 0
+	}
+	var count uint64
+	for _, tx := range list.Flatten() {
+		if tx.Nonce() != nonce && protocol.IsGasFreeUserTx(tx.Type()) {
+			count++
+		}
+	}
+	return count
+}
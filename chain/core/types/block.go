@@ -87,6 +87,135 @@ type Header struct {
 	Extra       []byte                      `json:"extraData"        gencodec:"required"`  //区块相关的附加信息
 	MixDigest   common.Hash                 `json:"mixHash"          gencodec:"required"`  //该哈希值与Nonce值一起能够证明在该区块上已经进行了足够的计算（用于验证该区块挖矿成功与否的Hash值）
 	Nonce       BlockNonce                  `json:"nonce"            gencodec:"required"`  //该哈希值与MixDigest值一起能够证明在该区块上已经进行了足够的计算（用于验证该区块挖矿成功与否的Hash值）
+	Revision    uint8                       `json:"revision,omitempty"    rlp:"-"`         //区块头的编码版本(HeaderRevisionLegacy或HeaderRevisionCompact)
+	Slot        uint64                      `json:"slot,omitempty"        rlp:"-"`         //出块时间槽(出块时间/出块间隔)，只有紧凑格式的区块头包含
+	Checkpoints []*Checkpoint               `json:"checkpoints,omitempty" rlp:"tail"`      //验证者对周期边界区块的联合签名(可选，最多一个)，为空时区块头编码与之前相同
+}
+
+//检查点，一个周期的验证者对该周期最后一个区块的联合签名，为轻节点和跨链桥提供更强的最终确认证明
+type Checkpoint struct {
+	Number     uint64          `json:"number"`     //周期边界区块号
+	Hash       common.Hash     `json:"hash"`       //周期边界区块Hash
+	Signatures []hexutil.Bytes `json:"signatures"` //验证者的签名
+}
+
+//区块头的编码版本，旧格式包含从以太坊继承的全部字段，紧凑格式去掉DPOS下恒定的叔块Hash、难度、MixDigest以及Nonce，
+//并加入出块时间槽。解码时根据第一个元素区分两种格式：旧格式以32字节的父区块Hash开头，紧凑格式以版本号开头
+const (
+	HeaderRevisionLegacy  uint8 = 0
+	HeaderRevisionCompact uint8 = 1
+)
+
+//旧格式区块头的RLP编码，字段与以太坊区块头保持一致
+type legacyHeader struct {
+	ParentHash  common.Hash
+	UncleHash   common.Hash
+	Validator   common.Address
+	Coinbase    common.Address
+	Root        common.Hash
+	TxHash      common.Hash
+	ReceiptHash common.Hash
+	DposProto   *DposContextProto
+	BokerProto  *protocol.BokerBackendProto
+	Bloom       Bloom
+	Difficulty  *big.Int
+	Number      *big.Int
+	GasLimit    *big.Int
+	GasUsed     *big.Int
+	Time        *big.Int
+	Extra       []byte
+	MixDigest   common.Hash
+	Nonce       BlockNonce
+	Checkpoints []*Checkpoint `rlp:"tail"`
+}
+
+//紧凑格式区块头的RLP编码
+type compactHeader struct {
+	Revision    uint8
+	ParentHash  common.Hash
+	Validator   common.Address
+	Coinbase    common.Address
+	Root        common.Hash
+	TxHash      common.Hash
+	ReceiptHash common.Hash
+	DposProto   *DposContextProto
+	BokerProto  *protocol.BokerBackendProto
+	Bloom       Bloom
+	Number      *big.Int
+	GasLimit    *big.Int
+	GasUsed     *big.Int
+	Time        *big.Int
+	Slot        uint64
+	Extra       []byte
+	Checkpoints []*Checkpoint `rlp:"tail"`
+}
+
+// EncodeRLP implements rlp.Encoder, encoding the header in the format of its revision.
+func (h *Header) EncodeRLP(w io.Writer) error {
+	switch h.Revision {
+	case HeaderRevisionLegacy:
+		return rlp.Encode(w, &legacyHeader{
+			ParentHash: h.ParentHash, UncleHash: h.UncleHash, Validator: h.Validator, Coinbase: h.Coinbase,
+			Root: h.Root, TxHash: h.TxHash, ReceiptHash: h.ReceiptHash, DposProto: h.DposProto, BokerProto: h.BokerProto,
+			Bloom: h.Bloom, Difficulty: h.Difficulty, Number: h.Number, GasLimit: h.GasLimit, GasUsed: h.GasUsed,
+			Time: h.Time, Extra: h.Extra, MixDigest: h.MixDigest, Nonce: h.Nonce, Checkpoints: h.Checkpoints,
+		})
+	case HeaderRevisionCompact:
+		return rlp.Encode(w, &compactHeader{
+			Revision: h.Revision, ParentHash: h.ParentHash, Validator: h.Validator, Coinbase: h.Coinbase,
+			Root: h.Root, TxHash: h.TxHash, ReceiptHash: h.ReceiptHash, DposProto: h.DposProto, BokerProto: h.BokerProto,
+			Bloom: h.Bloom, Number: h.Number, GasLimit: h.GasLimit, GasUsed: h.GasUsed, Time: h.Time,
+			Slot: h.Slot, Extra: h.Extra, Checkpoints: h.Checkpoints,
+		})
+	default:
+		return fmt.Errorf("unsupported header revision %d", h.Revision)
+	}
+}
+
+// DecodeRLP implements rlp.Decoder, accepting both the legacy and the compact
+// header format. Fields omitted by the compact format are set to the constant
+// values they have under DPoS.
+func (h *Header) DecodeRLP(s *rlp.Stream) error {
+	raw, err := s.Raw()
+	if err != nil {
+		return err
+	}
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return err
+	}
+	kind, first, _, err := rlp.Split(content)
+	if err != nil {
+		return err
+	}
+	//旧格式以32字节的父区块Hash开头
+	if kind == rlp.String && len(first) == common.HashLength {
+		var dec legacyHeader
+		if err := rlp.DecodeBytes(raw, &dec); err != nil {
+			return err
+		}
+		*h = Header{
+			ParentHash: dec.ParentHash, UncleHash: dec.UncleHash, Validator: dec.Validator, Coinbase: dec.Coinbase,
+			Root: dec.Root, TxHash: dec.TxHash, ReceiptHash: dec.ReceiptHash, DposProto: dec.DposProto, BokerProto: dec.BokerProto,
+			Bloom: dec.Bloom, Difficulty: dec.Difficulty, Number: dec.Number, GasLimit: dec.GasLimit, GasUsed: dec.GasUsed,
+			Time: dec.Time, Extra: dec.Extra, MixDigest: dec.MixDigest, Nonce: dec.Nonce, Checkpoints: dec.Checkpoints,
+		}
+		return nil
+	}
+	var dec compactHeader
+	if err := rlp.DecodeBytes(raw, &dec); err != nil {
+		return err
+	}
+	if dec.Revision != HeaderRevisionCompact {
+		return fmt.Errorf("unsupported header revision %d", dec.Revision)
+	}
+	*h = Header{
+		ParentHash: dec.ParentHash, UncleHash: EmptyUncleHash, Validator: dec.Validator, Coinbase: dec.Coinbase,
+		Root: dec.Root, TxHash: dec.TxHash, ReceiptHash: dec.ReceiptHash, DposProto: dec.DposProto, BokerProto: dec.BokerProto,
+		Bloom: dec.Bloom, Difficulty: big.NewInt(1), Number: dec.Number, GasLimit: dec.GasLimit, GasUsed: dec.GasUsed,
+		Time: dec.Time, Extra: dec.Extra, Checkpoints: dec.Checkpoints, Revision: dec.Revision, Slot: dec.Slot,
+	}
+	return nil
 }
 
 // field type overrides for gencodec
@@ -97,6 +226,8 @@ type headerMarshaling struct {
 	GasUsed    *hexutil.Big
 	Time       *hexutil.Big
 	Extra      hexutil.Bytes
+	Revision   hexutil.Uint64
+	Slot       hexutil.Uint64
 	Hash       common.Hash `json:"hash"` // adds call to Hash() in MarshalJSON
 }
 
@@ -275,6 +406,16 @@ func CopyHeader(h *Header) *Header {
 	if h.BokerProto != nil {
 		cpy.BokerProto = h.BokerProto
 	}
+
+	if len(h.Checkpoints) > 0 {
+		cpy.Checkpoints = make([]*Checkpoint, len(h.Checkpoints))
+		for i, cp := range h.Checkpoints {
+			cpy.Checkpoints[i] = &Checkpoint{Number: cp.Number, Hash: cp.Hash, Signatures: make([]hexutil.Bytes, len(cp.Signatures))}
+			for j, sig := range cp.Signatures {
+				cpy.Checkpoints[i].Signatures[j] = common.CopyBytes(sig)
+			}
+		}
+	}
 	return &cpy
 }
 
@@ -428,6 +569,10 @@ Uncles:
 }
 
 func (h *Header) String() string {
+	dpos := h.DposProto
+	if dpos == nil {
+		dpos = &DposContextProto{}
+	}
 	return fmt.Sprintf(`Header(%x):
 [
 	ParentHash:	    %x
@@ -437,7 +582,10 @@ func (h *Header) String() string {
 	Root:		    %x
 	TxSha		    %x
 	ReceiptSha:	    %x
-    DposContext:    %x
+	DposContext:
+		EpochHash:	    %x
+		ValidatorHash:	    %x
+		BlockCntHash:	    %x
 	Bloom:		    %x
 	Difficulty:	    %v
 	Number:		    %v
@@ -447,7 +595,7 @@ func (h *Header) String() string {
 	Extra:		    %s
 	MixDigest:      %x
 	Nonce:		    %x
-]`, h.Hash(), h.ParentHash, h.UncleHash, h.Validator, h.Coinbase, h.Root, h.TxHash, h.ReceiptHash, h.DposProto, h.Bloom, h.Difficulty, h.Number, h.GasLimit, h.GasUsed, h.Time, h.Extra, h.MixDigest, h.Nonce)
+]`, h.Hash(), h.ParentHash, h.UncleHash, h.Validator, h.Coinbase, h.Root, h.TxHash, h.ReceiptHash, dpos.EpochHash, dpos.ValidatorHash, dpos.BlockCntHash, h.Bloom, h.Difficulty, h.Number, h.GasLimit, h.GasUsed, h.Time, h.Extra, h.MixDigest, h.Nonce)
 }
 
 type Blocks []*Block
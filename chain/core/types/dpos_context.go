@@ -172,6 +172,15 @@ func (p *DposContextProto) Root() (h common.Hash) {
 	return h
 }
 
+//创建Dpos上下文(周期、验证人、出块数)树的同步调度器，用于快速同步时下载轴心区块的Dpos状态，三棵树的叶子均为普通数据，不引用其它子树
+func NewDposContextSync(ctxProto *DposContextProto, database trie.DatabaseReader) *trie.TrieSync {
+
+	syncer := trie.NewTrieSync(ctxProto.EpochHash, database, nil)
+	syncer.AddSubTrie(ctxProto.ValidatorHash, 0, common.Hash{}, nil)
+	syncer.AddSubTrie(ctxProto.BlockCntHash, 0, common.Hash{}, nil)
+	return syncer
+}
+
 func (d *DposContext) CommitTo(dbw trie.DatabaseWriter) (*DposContextProto, error) {
 
 	epochRoot, err := d.epochTrie.CommitTo(dbw)
@@ -380,6 +389,24 @@ func (dc *DposContext) GetCurrentProducer(firstTimer int64) (common.Address, err
 	return producers[offset], nil
 }
 
+func (dc *DposContext) GetNowProducer(firstTimer int64, now int64) (common.Address, error) {
+
+	producers, err := dc.GetEpochTrie()
+	if err != nil {
+		return common.Address{}, errors.New("failed to GetValidators")
+	}
+	producerSize := len(producers)
+	if producerSize == 0 {
+		return common.Address{}, protocol.ErrEpochTrieNil
+	}
+
+	offset := (now - firstTimer) % protocol.EpochInterval
+	offset /= protocol.ProducerInterval
+
+	offset %= int64(producerSize)
+	return producers[offset], nil
+}
+
 func (dc *DposContext) GetCurrentTokenNoder(firstTimer int64) (common.Address, error) {
 
 	producers, err := dc.GetEpochTrie()
@@ -35,6 +35,9 @@ func (h Header) MarshalJSON() ([]byte, error) {
 		Extra       hexutil.Bytes               `json:"extraData"        gencodec:"required"`
 		MixDigest   common.Hash                 `json:"mixHash"          gencodec:"required"`
 		Nonce       BlockNonce                  `json:"nonce"            gencodec:"required"`
+		Revision    hexutil.Uint64              `json:"revision,omitempty"    rlp:"-"`
+		Slot        hexutil.Uint64              `json:"slot,omitempty"        rlp:"-"`
+		Checkpoints []*Checkpoint               `json:"checkpoints,omitempty" rlp:"tail"`
 		Hash        common.Hash                 `json:"hash"`
 	}
 	var enc Header
@@ -56,6 +59,9 @@ func (h Header) MarshalJSON() ([]byte, error) {
 	enc.Extra = h.Extra
 	enc.MixDigest = h.MixDigest
 	enc.Nonce = h.Nonce
+	enc.Revision = hexutil.Uint64(h.Revision)
+	enc.Slot = hexutil.Uint64(h.Slot)
+	enc.Checkpoints = h.Checkpoints
 	enc.Hash = h.Hash()
 	return json.Marshal(&enc)
 }
@@ -81,6 +87,9 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		Extra       *hexutil.Bytes              `json:"extraData"        gencodec:"required"`
 		MixDigest   *common.Hash                `json:"mixHash"          gencodec:"required"`
 		Nonce       *BlockNonce                 `json:"nonce"            gencodec:"required"`
+		Revision    *hexutil.Uint64             `json:"revision,omitempty"    rlp:"-"`
+		Slot        *hexutil.Uint64             `json:"slot,omitempty"        rlp:"-"`
+		Checkpoints []*Checkpoint               `json:"checkpoints,omitempty" rlp:"tail"`
 	}
 	var dec Header
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -161,5 +170,14 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'nonce' for Header")
 	}
 	h.Nonce = *dec.Nonce
+	if dec.Revision != nil {
+		h.Revision = uint8(*dec.Revision)
+	}
+	if dec.Slot != nil {
+		h.Slot = uint64(*dec.Slot)
+	}
+	if dec.Checkpoints != nil {
+		h.Checkpoints = dec.Checkpoints
+	}
 	return nil
 }
@@ -7,20 +7,24 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
 )
 
 func (r Receipt) MarshalJSON() ([]byte, error) {
 	type Receipt struct {
-		PostState         hexutil.Bytes  `json:"root"`
-		Status            hexutil.Uint   `json:"status"`
-		CumulativeGasUsed *hexutil.Big   `json:"cumulativeGasUsed" gencodec:"required"`
-		Bloom             Bloom          `json:"logsBloom"         gencodec:"required"`
-		Logs              []*Log         `json:"logs"              gencodec:"required"`
-		TxHash            common.Hash    `json:"transactionHash" gencodec:"required"`
-		ContractAddress   common.Address `json:"contractAddress"`
-		GasUsed           *hexutil.Big   `json:"gasUsed" gencodec:"required"`
+		PostState         hexutil.Bytes   `json:"root"`
+		Status            hexutil.Uint    `json:"status"`
+		CumulativeGasUsed *hexutil.Big    `json:"cumulativeGasUsed" gencodec:"required"`
+		Bloom             Bloom           `json:"logsBloom"         gencodec:"required"`
+		Logs              []*Log          `json:"logs"              gencodec:"required"`
+		TxHash            common.Hash     `json:"transactionHash" gencodec:"required"`
+		ContractAddress   common.Address  `json:"contractAddress"`
+		GasUsed           *hexutil.Big    `json:"gasUsed" gencodec:"required"`
+		TxType            protocol.TxType `json:"txType"`
+		Epoch             hexutil.Uint64  `json:"epoch"`
+		AssignedAmount    *hexutil.Big    `json:"assignedAmount,omitempty"`
 	}
 	var enc Receipt
 	enc.PostState = r.PostState
@@ -31,19 +35,25 @@ func (r Receipt) MarshalJSON() ([]byte, error) {
 	enc.TxHash = r.TxHash
 	enc.ContractAddress = r.ContractAddress
 	enc.GasUsed = (*hexutil.Big)(r.GasUsed)
+	enc.TxType = r.TxType
+	enc.Epoch = hexutil.Uint64(r.Epoch)
+	enc.AssignedAmount = (*hexutil.Big)(r.AssignedAmount)
 	return json.Marshal(&enc)
 }
 
 func (r *Receipt) UnmarshalJSON(input []byte) error {
 	type Receipt struct {
-		PostState         hexutil.Bytes   `json:"root"`
-		Status            *hexutil.Uint   `json:"status"`
-		CumulativeGasUsed *hexutil.Big    `json:"cumulativeGasUsed" gencodec:"required"`
-		Bloom             *Bloom          `json:"logsBloom"         gencodec:"required"`
-		Logs              []*Log          `json:"logs"              gencodec:"required"`
-		TxHash            *common.Hash    `json:"transactionHash" gencodec:"required"`
-		ContractAddress   *common.Address `json:"contractAddress"`
-		GasUsed           *hexutil.Big    `json:"gasUsed" gencodec:"required"`
+		PostState         hexutil.Bytes    `json:"root"`
+		Status            *hexutil.Uint    `json:"status"`
+		CumulativeGasUsed *hexutil.Big     `json:"cumulativeGasUsed" gencodec:"required"`
+		Bloom             *Bloom           `json:"logsBloom"         gencodec:"required"`
+		Logs              []*Log           `json:"logs"              gencodec:"required"`
+		TxHash            *common.Hash     `json:"transactionHash" gencodec:"required"`
+		ContractAddress   *common.Address  `json:"contractAddress"`
+		GasUsed           *hexutil.Big     `json:"gasUsed" gencodec:"required"`
+		TxType            *protocol.TxType `json:"txType"`
+		Epoch             *hexutil.Uint64  `json:"epoch"`
+		AssignedAmount    *hexutil.Big     `json:"assignedAmount,omitempty"`
 	}
 	var dec Receipt
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -78,5 +88,14 @@ func (r *Receipt) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'gasUsed' for Receipt")
 	}
 	r.GasUsed = (*big.Int)(dec.GasUsed)
+	if dec.TxType != nil {
+		r.TxType = *dec.TxType
+	}
+	if dec.Epoch != nil {
+		r.Epoch = uint64(*dec.Epoch)
+	}
+	if dec.AssignedAmount != nil {
+		r.AssignedAmount = (*big.Int)(dec.AssignedAmount)
+	}
 	return nil
 }
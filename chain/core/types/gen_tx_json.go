@@ -23,6 +23,7 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 		GasLimit     *hexutil.Big    `json:"gas"      gencodec:"required"`
 		Recipient    *common.Address `json:"to"       rlp:"nil"`
 		Amount       *hexutil.Big    `json:"value"    gencodec:"required"`
+		Time         *hexutil.Big    `json:"timestamp"        gencodec:"required"`
 		Payload      hexutil.Bytes   `json:"input"    gencodec:"required"`
 		Extra        hexutil.Bytes   `json:"extra"    gencodec:"required"`
 		V            *hexutil.Big    `json:"v" gencodec:"required"`
@@ -37,6 +38,7 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 	enc.GasLimit = (*hexutil.Big)(t.GasLimit)
 	enc.Recipient = t.Recipient
 	enc.Amount = (*hexutil.Big)(t.Amount)
+	enc.Time = (*hexutil.Big)(t.Time)
 	enc.Payload = t.Payload
 	enc.Extra = t.Extra
 	enc.V = (*hexutil.Big)(t.V)
@@ -55,6 +57,7 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		GasLimit     *hexutil.Big     `json:"gas"      gencodec:"required"`
 		Recipient    *common.Address  `json:"to"       rlp:"nil"`
 		Amount       *hexutil.Big     `json:"value"    gencodec:"required"`
+		Time         *hexutil.Big     `json:"timestamp"        gencodec:"required"`
 		Payload      *hexutil.Bytes   `json:"input"    gencodec:"required"`
 		Extra        *hexutil.Bytes   `json:"extra"    gencodec:"required"`
 		V            *hexutil.Big     `json:"v" gencodec:"required"`
@@ -89,6 +92,10 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'value' for txdata")
 	}
 	t.Amount = (*big.Int)(dec.Amount)
+	t.Time = new(big.Int)
+	if dec.Time != nil {
+		t.Time = (*big.Int)(dec.Time)
+	}
 	if dec.Payload == nil {
 		return errors.New("missing required field 'input' for txdata")
 	}
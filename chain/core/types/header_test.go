@@ -0,0 +1,187 @@
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/rlp"
+)
+
+//区块头编码版本修改之前的区块头编码和Hash，旧格式的编码和Hash必须保持不变
+var (
+	legacyHeaderRLP  = common.FromHex("f902daa00000000000000000000000000000000000000000000000000000000000000001a01dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347940000000000000000000000000000000000000002940000000000000000000000000000000000000003a00000000000000000000000000000000000000000000000000000000000000004a056e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421a056e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421f863a00000000000000000000000000000000000000000000000000000000000000005a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000000f863a00000000000000000000000000000000000000000000000000000000000000006a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000000b901000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000001648347e7c48252088459682f0085626f6b6572a00000000000000000000000000000000000000000000000000000000000000000880000000000000000")
+	legacyHeaderHash = common.HexToHash("22ec7cdf5304c42d6161d3e3fffef31ef398374881df2e114b677aed361dbc5c")
+)
+
+//与legacyHeaderRLP对应的区块头
+func testLegacyHeader() *Header {
+	return &Header{
+		ParentHash:  common.HexToHash("0x01"),
+		UncleHash:   EmptyUncleHash,
+		Validator:   common.HexToAddress("0x02"),
+		Coinbase:    common.HexToAddress("0x03"),
+		Root:        common.HexToHash("0x04"),
+		TxHash:      EmptyRootHash,
+		ReceiptHash: EmptyRootHash,
+		DposProto:   &DposContextProto{EpochHash: common.HexToHash("0x05")},
+		BokerProto:  &protocol.BokerBackendProto{SingleHash: common.HexToHash("0x06")},
+		Difficulty:  big.NewInt(1),
+		Number:      big.NewInt(100),
+		GasLimit:    big.NewInt(4712388),
+		GasUsed:     big.NewInt(21000),
+		Time:        big.NewInt(1500000000),
+		Extra:       []byte("boker"),
+	}
+}
+
+//解码区块头，没有检查点时尾部字段解码为空的切片，统一为nil以便比较
+func decodeTestHeader(t *testing.T, enc []byte) *Header {
+
+	var dec Header
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	if len(dec.Checkpoints) == 0 {
+		dec.Checkpoints = nil
+	}
+	return &dec
+}
+
+//与testLegacyHeader内容相同的紧凑格式区块头
+func testCompactHeader() *Header {
+
+	header := testLegacyHeader()
+	header.Revision = HeaderRevisionCompact
+	header.Slot = header.Time.Uint64() / uint64(protocol.ProducerInterval)
+	return header
+}
+
+func testCheckpoints() []*Checkpoint {
+	return []*Checkpoint{{
+		Number:     99,
+		Hash:       common.HexToHash("0x07"),
+		Signatures: []hexutil.Bytes{bytes.Repeat([]byte{1}, protocol.ExtraSeal), bytes.Repeat([]byte{2}, protocol.ExtraSeal)},
+	}}
+}
+
+func TestLegacyHeaderHash(t *testing.T) {
+
+	header := testLegacyHeader()
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatalf("failed to encode header: %v", err)
+	}
+	if !bytes.Equal(enc, legacyHeaderRLP) {
+		t.Errorf("legacy encoding changed:\nhave %x\nwant %x", enc, legacyHeaderRLP)
+	}
+	if hash := header.Hash(); hash != legacyHeaderHash {
+		t.Errorf("legacy hash changed: have %x, want %x", hash, legacyHeaderHash)
+	}
+
+	var dec Header
+	if err := rlp.DecodeBytes(legacyHeaderRLP, &dec); err != nil {
+		t.Fatalf("failed to decode legacy header: %v", err)
+	}
+	if dec.Revision != HeaderRevisionLegacy || dec.Hash() != legacyHeaderHash {
+		t.Errorf("decoded legacy header mismatch: revision %d, hash %x", dec.Revision, dec.Hash())
+	}
+}
+
+func TestHeaderRoundTrip(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		header *Header
+	}{
+		{"legacy", testLegacyHeader()},
+		{"compact", testCompactHeader()},
+	}
+	for _, tt := range tests {
+		for _, checkpoints := range [][]*Checkpoint{nil, testCheckpoints()} {
+			header := CopyHeader(tt.header)
+			header.Checkpoints = checkpoints
+
+			enc, err := rlp.EncodeToBytes(header)
+			if err != nil {
+				t.Fatalf("%s: failed to encode header: %v", tt.name, err)
+			}
+			dec := decodeTestHeader(t, enc)
+			if !reflect.DeepEqual(dec, header) {
+				t.Errorf("%s with %d checkpoints: decoded header mismatch:\nhave %+v\nwant %+v", tt.name, len(checkpoints), dec, header)
+			}
+			if dec.Hash() != header.Hash() {
+				t.Errorf("%s with %d checkpoints: hash mismatch after decoding", tt.name, len(checkpoints))
+			}
+		}
+	}
+
+	//检查点会改变区块头的Hash，两种格式的Hash也不同
+	legacy, compact := testLegacyHeader(), testCompactHeader()
+	if legacy.Hash() == compact.Hash() {
+		t.Errorf("legacy and compact headers have the same hash")
+	}
+	legacy.Checkpoints = testCheckpoints()
+	if legacy.Hash() == legacyHeaderHash {
+		t.Errorf("checkpoints not covered by the header hash")
+	}
+}
+
+func TestCompactHeaderDefaults(t *testing.T) {
+
+	//紧凑格式不编码DPOS下恒定的字段，解码时使用恒定值
+	header := testCompactHeader()
+	header.UncleHash = common.HexToHash("0x08")
+	header.Difficulty = big.NewInt(131072)
+	header.MixDigest = common.HexToHash("0x09")
+	header.Nonce = EncodeNonce(10)
+
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatalf("failed to encode header: %v", err)
+	}
+	if len(enc) >= len(legacyHeaderRLP) {
+		t.Errorf("compact encoding not smaller than legacy: %d >= %d", len(enc), len(legacyHeaderRLP))
+	}
+	dec := decodeTestHeader(t, enc)
+	if !reflect.DeepEqual(dec, testCompactHeader()) {
+		t.Errorf("decoded header mismatch:\nhave %+v\nwant %+v", dec, testCompactHeader())
+	}
+	if dec.UncleHash != EmptyUncleHash || dec.Difficulty.Cmp(big.NewInt(1)) != 0 || dec.MixDigest != (common.Hash{}) || dec.Nonce != (BlockNonce{}) {
+		t.Errorf("compact defaults mismatch: uncles %x, difficulty %v, mix %x, nonce %x", dec.UncleHash, dec.Difficulty, dec.MixDigest, dec.Nonce)
+	}
+	if dec.Slot != header.Slot {
+		t.Errorf("slot mismatch: have %d, want %d", dec.Slot, header.Slot)
+	}
+}
+
+func TestHeaderUnknownRevision(t *testing.T) {
+
+	header := testCompactHeader()
+	header.Revision = HeaderRevisionCompact + 1
+	if _, err := rlp.EncodeToBytes(header); err == nil {
+		t.Errorf("header with unknown revision encoded")
+	}
+
+	//紧凑格式的编码中版本号不是HeaderRevisionCompact时拒绝解码
+	for _, revision := range []uint8{HeaderRevisionLegacy, HeaderRevisionCompact + 1} {
+		h := testCompactHeader()
+		enc, err := rlp.EncodeToBytes(&compactHeader{
+			Revision: revision, ParentHash: h.ParentHash, Validator: h.Validator, Coinbase: h.Coinbase,
+			Root: h.Root, TxHash: h.TxHash, ReceiptHash: h.ReceiptHash, DposProto: h.DposProto, BokerProto: h.BokerProto,
+			Bloom: h.Bloom, Number: h.Number, GasLimit: h.GasLimit, GasUsed: h.GasUsed, Time: h.Time,
+			Slot: h.Slot, Extra: h.Extra,
+		})
+		if err != nil {
+			t.Fatalf("failed to encode compact header: %v", err)
+		}
+		var dec Header
+		if err := rlp.DecodeBytes(enc, &dec); err == nil {
+			t.Errorf("header with revision %d decoded", revision)
+		}
+	}
+}
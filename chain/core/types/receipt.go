@@ -22,6 +22,7 @@ import (
 	"io"
 	"math/big"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/rlp"
@@ -42,7 +43,7 @@ const (
 	ReceiptStatusSuccessful = uint(1)
 )
 
-//Receipt代表交易的结果
+// Receipt代表交易的结果
 type Receipt struct {
 
 	//共识字段
@@ -56,6 +57,19 @@ type Receipt struct {
 	TxHash          common.Hash    `json:"transactionHash" gencodec:"required"`
 	ContractAddress common.Address `json:"contractAddress"`
 	GasUsed         *big.Int       `json:"gasUsed" gencodec:"required"`
+
+	//播客链交易元数据（不参与共识编码）
+	TxType         protocol.TxType  `json:"txType"`                   //交易类型
+	Epoch          uint64           `json:"epoch"`                    //交易执行时所在的周期
+	AssignedAmount *big.Int         `json:"assignedAmount,omitempty"` //分配通证的数量(仅AssignToken交易)
+	Transfers      []*TokenTransfer `json:"-"`                        //分配通证时合约转出的金额(仅在执行时记录，不保存在回执中)
+}
+
+//分配通证交易执行过程中的一笔转账
+type TokenTransfer struct {
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Amount *big.Int       `json:"amount"`
 }
 
 type receiptMarshaling struct {
@@ -63,6 +77,8 @@ type receiptMarshaling struct {
 	Status            hexutil.Uint
 	CumulativeGasUsed *hexutil.Big
 	GasUsed           *hexutil.Big
+	Epoch             hexutil.Uint64
+	AssignedAmount    *hexutil.Big
 }
 
 // receiptRLP is the consensus encoding of a receipt.
@@ -81,6 +97,20 @@ type receiptStorageRLP struct {
 	ContractAddress   common.Address
 	Logs              []*LogForStorage
 	GasUsed           *big.Int
+	TxType            protocol.TxType
+	Epoch             uint64
+	AssignedAmount    *big.Int
+}
+
+//没有播客链交易元数据的旧版本存储格式
+type legacyReceiptStorageRLP struct {
+	PostStateOrStatus []byte
+	CumulativeGasUsed *big.Int
+	Bloom             Bloom
+	TxHash            common.Hash
+	ContractAddress   common.Address
+	Logs              []*LogForStorage
+	GasUsed           *big.Int
 }
 
 // NewReceipt creates a barebone transaction receipt, copying the init fields.
@@ -161,6 +191,9 @@ func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
 		ContractAddress:   r.ContractAddress,
 		Logs:              make([]*LogForStorage, len(r.Logs)),
 		GasUsed:           r.GasUsed,
+		TxType:            r.TxType,
+		Epoch:             r.Epoch,
+		AssignedAmount:    r.AssignedAmount,
 	}
 	for i, log := range r.Logs {
 		enc.Logs[i] = (*LogForStorage)(log)
@@ -169,12 +202,29 @@ func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
 }
 
 // DecodeRLP implements rlp.Decoder, and loads both consensus and implementation
-// fields of a receipt from an RLP stream.
+// fields of a receipt from an RLP stream. Receipts stored before the Boker
+// transaction metadata was added are decoded using the legacy layout.
 func (r *ReceiptForStorage) DecodeRLP(s *rlp.Stream) error {
-	var dec receiptStorageRLP
-	if err := s.Decode(&dec); err != nil {
+	blob, err := s.Raw()
+	if err != nil {
 		return err
 	}
+	var dec receiptStorageRLP
+	if err := rlp.DecodeBytes(blob, &dec); err != nil {
+		var legacy legacyReceiptStorageRLP
+		if err := rlp.DecodeBytes(blob, &legacy); err != nil {
+			return err
+		}
+		dec = receiptStorageRLP{
+			PostStateOrStatus: legacy.PostStateOrStatus,
+			CumulativeGasUsed: legacy.CumulativeGasUsed,
+			Bloom:             legacy.Bloom,
+			TxHash:            legacy.TxHash,
+			ContractAddress:   legacy.ContractAddress,
+			Logs:              legacy.Logs,
+			GasUsed:           legacy.GasUsed,
+		}
+	}
 	if err := (*Receipt)(r).setStatus(dec.PostStateOrStatus); err != nil {
 		return err
 	}
@@ -186,6 +236,12 @@ func (r *ReceiptForStorage) DecodeRLP(s *rlp.Stream) error {
 	}
 	// Assign the implementation fields
 	r.TxHash, r.ContractAddress, r.GasUsed = dec.TxHash, dec.ContractAddress, dec.GasUsed
+
+	// Assign the Boker transaction metadata
+	r.TxType, r.Epoch = dec.TxType, dec.Epoch
+	if dec.TxType == protocol.AssignToken {
+		r.AssignedAmount = dec.AssignedAmount
+	}
 	return nil
 }
 
@@ -0,0 +1,123 @@
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/rlp"
+)
+
+//增加播客链交易元数据之前保存的7个字段的回执，包含一条日志
+var legacyStoredReceipt = common.FromHex("f901c30182a410b9010000000000020000000000002000000000000000000000000000000000400000000000000000000000000000000000000000000000000000000000000000000400000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000400000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000008000000000000a01111111111111111111111111111111111111111111111111111111111111111942222222222222222222222222222222222222222f881f87f943333333333333333333333333333333333333333e1a0444444444444444444444444444444444444444444444444444444444444444482010207a0111111111111111111111111111111111111111111111111111111111111111101a0555555555555555555555555555555555555555555555555555555555555555502825208")
+
+//测试旧版本存储格式的回执可以被解码，播客链交易元数据使用默认值
+func TestLegacyReceiptStorageDecode(t *testing.T) {
+
+	var dec ReceiptForStorage
+	if err := rlp.DecodeBytes(legacyStoredReceipt, &dec); err != nil {
+		t.Fatalf("failed to decode legacy receipt: %v", err)
+	}
+	receipt := (*Receipt)(&dec)
+
+	txHash := common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
+	wantLog := &Log{
+		Address:     common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		Topics:      []common.Hash{common.HexToHash("0x4444444444444444444444444444444444444444444444444444444444444444")},
+		Data:        []byte{0x01, 0x02},
+		BlockNumber: 7,
+		TxHash:      txHash,
+		TxIndex:     1,
+		BlockHash:   common.HexToHash("0x5555555555555555555555555555555555555555555555555555555555555555"),
+		Index:       2,
+	}
+	if receipt.Status != ReceiptStatusSuccessful {
+		t.Errorf("status mismatch: have %d, want %d", receipt.Status, ReceiptStatusSuccessful)
+	}
+	if receipt.CumulativeGasUsed.Cmp(big.NewInt(42000)) != 0 || receipt.GasUsed.Cmp(big.NewInt(21000)) != 0 {
+		t.Errorf("gas mismatch: cumulative %v, used %v", receipt.CumulativeGasUsed, receipt.GasUsed)
+	}
+	if receipt.TxHash != txHash {
+		t.Errorf("tx hash mismatch: have %x, want %x", receipt.TxHash, txHash)
+	}
+	if want := common.HexToAddress("0x2222222222222222222222222222222222222222"); receipt.ContractAddress != want {
+		t.Errorf("contract address mismatch: have %x, want %x", receipt.ContractAddress, want)
+	}
+	if len(receipt.Logs) != 1 || !reflect.DeepEqual(receipt.Logs[0], wantLog) {
+		t.Errorf("logs mismatch: have %v, want [%v]", receipt.Logs, wantLog)
+	}
+	if receipt.Bloom != CreateBloom(Receipts{receipt}) {
+		t.Errorf("bloom mismatch")
+	}
+	if receipt.TxType != protocol.Binary || receipt.Epoch != 0 || receipt.AssignedAmount != nil {
+		t.Errorf("metadata mismatch: type %v, epoch %d, assigned %v", receipt.TxType, receipt.Epoch, receipt.AssignedAmount)
+	}
+
+	//重新编码后使用新的存储格式
+	enc, err := rlp.EncodeToBytes(&dec)
+	if err != nil {
+		t.Fatalf("failed to encode receipt: %v", err)
+	}
+	if bytes.Equal(enc, legacyStoredReceipt) {
+		t.Errorf("re-encoded receipt still uses the legacy layout")
+	}
+	var again ReceiptForStorage
+	if err := rlp.DecodeBytes(enc, &again); err != nil {
+		t.Fatalf("failed to decode re-encoded receipt: %v", err)
+	}
+	if !reflect.DeepEqual(again, dec) {
+		t.Errorf("re-encoded receipt mismatch: have %v, want %v", (*Receipt)(&again), receipt)
+	}
+}
+
+//测试播客链交易元数据在存储格式中往返编码，分配通证的数量只在AssignToken交易中保存
+func TestReceiptStorageRoundTrip(t *testing.T) {
+
+	log := &Log{
+		Address: common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		Topics:  []common.Hash{protocol.TokenAssignedTopic},
+		Data:    common.LeftPadBytes(big.NewInt(1000).Bytes(), 32),
+	}
+	tests := []struct {
+		txType   protocol.TxType
+		epoch    uint64
+		assigned *big.Int
+		want     *big.Int
+	}{
+		{protocol.AssignToken, 12, big.NewInt(1000), big.NewInt(1000)},
+		{protocol.VoteUser, 3, big.NewInt(1000), nil},
+		{protocol.Binary, 0, nil, nil},
+	}
+	for i, tt := range tests {
+		receipt := NewReceipt(nil, false, big.NewInt(42000))
+		receipt.TxHash = common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
+		receipt.GasUsed = big.NewInt(21000)
+		receipt.Logs = []*Log{log}
+		receipt.Bloom = CreateBloom(Receipts{receipt})
+		receipt.TxType, receipt.Epoch, receipt.AssignedAmount = tt.txType, tt.epoch, tt.assigned
+
+		enc, err := rlp.EncodeToBytes((*ReceiptForStorage)(receipt))
+		if err != nil {
+			t.Fatalf("test %d: failed to encode receipt: %v", i, err)
+		}
+		var dec ReceiptForStorage
+		if err := rlp.DecodeBytes(enc, &dec); err != nil {
+			t.Fatalf("test %d: failed to decode receipt: %v", i, err)
+		}
+		if dec.TxType != tt.txType || dec.Epoch != tt.epoch {
+			t.Errorf("test %d: metadata mismatch: have type %v epoch %d, want type %v epoch %d", i, dec.TxType, dec.Epoch, tt.txType, tt.epoch)
+		}
+		if (dec.AssignedAmount == nil) != (tt.want == nil) || (tt.want != nil && dec.AssignedAmount.Cmp(tt.want) != 0) {
+			t.Errorf("test %d: assigned amount mismatch: have %v, want %v", i, dec.AssignedAmount, tt.want)
+		}
+		if dec.Status != ReceiptStatusSuccessful || dec.TxHash != receipt.TxHash || dec.GasUsed.Cmp(receipt.GasUsed) != 0 || dec.Bloom != receipt.Bloom {
+			t.Errorf("test %d: receipt mismatch: have %v, want %v", i, (*Receipt)(&dec), receipt)
+		}
+		if len(dec.Logs) != 1 || !reflect.DeepEqual(dec.Logs[0], log) {
+			t.Errorf("test %d: logs mismatch: have %v, want [%v]", i, dec.Logs, log)
+		}
+	}
+}
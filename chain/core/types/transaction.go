@@ -83,6 +83,7 @@ type txdataMarshaling struct {
 	Price        *hexutil.Big
 	GasLimit     *hexutil.Big
 	Amount       *hexutil.Big
+	Time         *hexutil.Big
 	Payload      hexutil.Bytes
 	Extra        hexutil.Bytes
 	Type         protocol.TxType
@@ -288,7 +289,7 @@ func IsBinary(txType protocol.TxType) bool {
 //当当前交易不是普通类型是进行校验(这里进行了修改，交易非普通类型时也应该继续处理)
 func (tx *Transaction) Validate() error {
 
-	if tx.Type() < protocol.Binary || tx.Type() > protocol.AssignToken {
+	if tx.Type() < protocol.Binary || tx.Type() > protocol.UpdateContract {
 		return errors.New("unknown transaction type")
 	}
 	return nil
@@ -324,6 +325,48 @@ func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
 	return err
 }
 
+//Boker交易RLP编码的字段数量，比以太坊交易多出交易类型、交易时间和扩展数据
+const txRLPFields = 12
+
+//以太坊交易RLP编码的字段数量
+const ethTxRLPFields = 9
+
+var ErrEthereumTxEncoding = errors.New("standard Ethereum transaction encoding is not supported, Boker transactions also encode the type, timestamp and extra fields")
+
+//解码通过eth_sendRawTransaction等接口提交的RLP编码交易，对常见的编码错误给出明确的错误信息
+func DecodeRawTransaction(encoded []byte) (*Transaction, error) {
+
+	if len(encoded) == 0 {
+		return nil, errors.New("empty transaction data")
+	}
+	kind, content, _, err := rlp.Split(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction RLP: %v", err)
+	}
+	if kind != rlp.List {
+		return nil, errors.New("invalid transaction RLP: not a list")
+	}
+	fields, err := rlp.CountValues(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction RLP: %v", err)
+	}
+	switch fields {
+	case txRLPFields:
+	case ethTxRLPFields:
+		return nil, ErrEthereumTxEncoding
+	default:
+		return nil, fmt.Errorf("invalid transaction RLP: %d fields, want %d", fields, txRLPFields)
+	}
+	tx := new(Transaction)
+	if err := rlp.DecodeBytes(encoded, tx); err != nil {
+		return nil, fmt.Errorf("invalid transaction RLP: %v", err)
+	}
+	if err := tx.Validate(); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
 func (tx *Transaction) MarshalJSON() ([]byte, error) {
 	hash := tx.Hash()
 	data := tx.data
@@ -365,6 +408,7 @@ func (tx *Transaction) V() *big.Int           { return tx.data.V }
 func (tx *Transaction) S() *big.Int           { return tx.data.S }
 func (tx *Transaction) R() *big.Int           { return tx.data.R }
 func (tx *Transaction) SetTime()              { tx.data.Time.SetInt64(time.Now().Unix()) }
+func (tx *Transaction) SetTimestamp(t int64)  { tx.data.Time.SetInt64(t) }
 
 // To returns the recipient address of the transaction.
 // It returns nil if the transaction is a contract creation.
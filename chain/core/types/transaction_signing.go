@@ -39,17 +39,12 @@ type sigCache struct {
 }
 
 //MakeSigner根据给定的链配置和块编号返回签名者。
+//EIP155签名者同样可以恢复不带链ID的交易，因此旧的交易仍然有效；
+//链配置中设置了LegacySigning时继续使用Homestead签名。
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
-	/*var signer Signer
-	switch {
-	case config.IsEIP155(blockNumber):
-		signer = NewEIP155Signer(config.ChainId)
-	case config.IsHomestead(blockNumber):
-		signer = HomesteadSigner{}
-	default:
-		signer = FrontierSigner{}
+	if config.IsReplayProtected(blockNumber) {
+		return NewEIP155Signer(config.ChainId)
 	}
-	return signer*/
 	return HomesteadSigner{}
 }
 
@@ -20,8 +20,10 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/params"
 )
 
 func TestEIP155Signing(t *testing.T) {
@@ -29,7 +31,7 @@ func TestEIP155Signing(t *testing.T) {
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 
 	signer := NewEIP155Signer(big.NewInt(18))
-	tx, err := SignTx(NewTransaction(Binary, 0, addr, new(big.Int), new(big.Int), new(big.Int), nil), signer, key)
+	tx, err := SignTx(NewTransaction(protocol.Binary, 0, addr, new(big.Int), new(big.Int), new(big.Int), nil), signer, key)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -43,12 +45,54 @@ func TestEIP155Signing(t *testing.T) {
 	}
 }
 
+// Tests that the signer only switches to EIP155 at the configured fork block, and
+// that unprotected transactions stay valid on both sides of the fork.
+func TestMakeSignerFork(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := &params.ChainConfig{ChainId: big.NewInt(18), EIP155Block: big.NewInt(0), EIP155SignerBlock: big.NewInt(10)}
+	legacy := *config
+	legacy.LegacySigning = true
+
+	protected, _ := SignTx(NewTransaction(protocol.Binary, 0, addr, new(big.Int), new(big.Int), new(big.Int), nil), NewEIP155Signer(config.ChainId), key)
+	unprotected, _ := SignTx(NewTransaction(protocol.Binary, 0, addr, new(big.Int), new(big.Int), new(big.Int), nil), HomesteadSigner{}, key)
+
+	tests := []struct {
+		config    *params.ChainConfig
+		number    int64
+		protected bool
+	}{
+		{config, 0, false},
+		{config, 9, false},
+		{config, 10, true},
+		{config, 11, true},
+		{&legacy, 10, false},
+	}
+	for i, tt := range tests {
+		signer := MakeSigner(tt.config, big.NewInt(tt.number))
+		if _, ok := signer.(EIP155Signer); ok != tt.protected {
+			t.Errorf("test %d: signer mismatch: have %T, want EIP155 %v", i, signer, tt.protected)
+		}
+		if from, err := Sender(signer, unprotected); err != nil || from != addr {
+			t.Errorf("test %d: unprotected sender mismatch: have %x (%v), want %x", i, from, err, addr)
+		}
+		from, err := Sender(signer, protected)
+		if tt.protected && (err != nil || from != addr) {
+			t.Errorf("test %d: protected sender mismatch: have %x (%v), want %x", i, from, err, addr)
+		}
+		if !tt.protected && err == nil && from == addr {
+			t.Errorf("test %d: protected transaction accepted before the fork", i)
+		}
+	}
+}
+
 func TestEIP155ChainId(t *testing.T) {
 	key, _ := crypto.GenerateKey()
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 
 	signer := NewEIP155Signer(big.NewInt(18))
-	tx, err := SignTx(NewTransaction(Binary, 0, addr, new(big.Int), new(big.Int), new(big.Int), nil), signer, key)
+	tx, err := SignTx(NewTransaction(protocol.Binary, 0, addr, new(big.Int), new(big.Int), new(big.Int), nil), signer, key)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -60,7 +104,7 @@ func TestEIP155ChainId(t *testing.T) {
 		t.Error("expected chainId to be", signer.chainId, "got", tx.ChainId())
 	}
 
-	tx = NewTransaction(Binary, 0, addr, new(big.Int), new(big.Int), new(big.Int), nil)
+	tx = NewTransaction(protocol.Binary, 0, addr, new(big.Int), new(big.Int), new(big.Int), nil)
 	tx, err = SignTx(tx, HomesteadSigner{}, key)
 	if err != nil {
 		t.Fatal(err)
@@ -78,7 +122,7 @@ func TestEIP155ChainId(t *testing.T) {
 func TestChainId(t *testing.T) {
 	key, _ := defaultTestKey()
 
-	tx := NewTransaction(Binary, 0, common.Address{}, new(big.Int), new(big.Int), new(big.Int), nil)
+	tx := NewTransaction(protocol.Binary, 0, common.Address{}, new(big.Int), new(big.Int), new(big.Int), nil)
 
 	var err error
 	tx, err = SignTx(tx, NewEIP155Signer(big.NewInt(1)), key)
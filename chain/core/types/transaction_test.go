@@ -23,6 +23,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/rlp"
@@ -32,7 +33,7 @@ import (
 // at github.com/ethereum/tests.
 var (
 	emptyTx = NewTransaction(
-		Binary,
+		protocol.Binary,
 		0,
 		common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87"),
 		big.NewInt(0), big.NewInt(0), big.NewInt(0),
@@ -40,7 +41,7 @@ var (
 	)
 
 	rightvrsTx, _ = NewTransaction(
-		Binary,
+		protocol.Binary,
 		3,
 		common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b"),
 		big.NewInt(10),
@@ -53,12 +54,18 @@ var (
 	)
 )
 
+// Boker transactions carry their creation time, pin it so the hashes are stable.
+func init() {
+	emptyTx.data.Time.SetInt64(0)
+	rightvrsTx.data.Time.SetInt64(0)
+}
+
 func TestTransactionSigHash(t *testing.T) {
 	var homestead HomesteadSigner
-	if homestead.Hash(emptyTx) != common.HexToHash("c775b99e7ad12f50d819fcd602390467e28141316969f4b57f0626f74fe3b386") {
+	if homestead.Hash(emptyTx) != common.HexToHash("a3251f9fd402add36ff1141db4bc6cf5d012f15cf9ffe035ca3c4799a82dc7a7") {
 		t.Errorf("empty transaction hash mismatch, got %x", emptyTx.Hash())
 	}
-	if homestead.Hash(rightvrsTx) != common.HexToHash("fe7a79529ed5f7c3375d06b26b186a8644e0e16c373d7a12be41c62d6042b77a") {
+	if homestead.Hash(rightvrsTx) != common.HexToHash("70697a209678a323a9e18af2899bb043f2a41ff613d94ecba3ec00c603fdc906") {
 		t.Errorf("RightVRS transaction hash mismatch, got %x", rightvrsTx.Hash())
 	}
 }
@@ -104,7 +111,7 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 	for start, key := range keys {
 		addr := crypto.PubkeyToAddress(key.PublicKey)
 		for i := 0; i < 25; i++ {
-			tx, _ := SignTx(NewTransaction(Binary, uint64(start+i), common.Address{}, big.NewInt(100), big.NewInt(100), big.NewInt(int64(start+i)), nil), signer, key)
+			tx, _ := SignTx(NewTransaction(protocol.Binary, uint64(start+i), common.Address{}, big.NewInt(100), big.NewInt(100), big.NewInt(int64(start+i)), nil), signer, key)
 			groups[addr] = append(groups[addr], tx)
 		}
 	}
@@ -169,7 +176,7 @@ func TestTransactionJSON(t *testing.T) {
 		var tx *Transaction
 		switch i % 2 {
 		case 0:
-			tx = NewTransaction(Binary, i, common.Address{1}, common.Big0, common.Big1, common.Big2, []byte("abcdef"))
+			tx = NewTransaction(protocol.Binary, i, common.Address{1}, common.Big0, common.Big1, common.Big2, []byte("abcdef"))
 		case 1:
 			tx = NewContractCreation(i, common.Big0, common.Big1, common.Big2, []byte("abcdef"))
 		}
@@ -201,18 +208,16 @@ func TestTransactionJSON(t *testing.T) {
 
 func TestTransactionValidate(t *testing.T) {
 	validTransactions := []*Transaction{
-		newTransaction(Binary, 0, nil, common.Big0, common.Big1, common.Big2, []byte("abcdef")),
-		newTransaction(LoginCandidate, 0, nil, common.Big0, common.Big1, common.Big2, nil),
-		newTransaction(LogoutCandidate, 0, &common.Address{1}, common.Big0, common.Big1, common.Big2, nil),
-		newTransaction(UnDelegate, 0, &common.Address{1}, common.Big0, common.Big1, common.Big2, nil),
+		newTransaction(protocol.Binary, 0, nil, common.Big0, common.Big1, common.Big2, []byte("abcdef")),
+		newTransaction(protocol.RegisterCandidate, 0, &common.Address{1}, common.Big0, common.Big1, common.Big2, nil),
+		newTransaction(protocol.VoteUser, 0, &common.Address{1}, common.Big0, common.Big1, common.Big2, []byte("abcddf")),
+		newTransaction(protocol.AssignToken, 0, &common.Address{1}, common.Big1, common.Big1, common.Big2, nil),
+		newTransaction(protocol.UpdateContract, 0, &common.Address{1}, common.Big0, common.Big1, common.Big2, nil),
 	}
 	invalidTransactions := []*Transaction{
-		// value = 0 is invalid when the type isn't binary
-		newTransaction(LoginCandidate, 0, nil, common.Big1, common.Big1, common.Big2, nil),
-		// to = nil is invalid when the type isn't binary
-		newTransaction(Delegate, 0, nil, common.Big0, common.Big1, common.Big2, nil),
-		// payload != nil is invalid when the type isn't binary
-		newTransaction(UnDelegate, 0, &common.Address{1}, common.Big0, common.Big1, common.Big2, []byte("abcddf")),
+		// unknown transaction types
+		newTransaction(protocol.UpdateContract+1, 0, &common.Address{1}, common.Big0, common.Big1, common.Big2, nil),
+		newTransaction(protocol.TxType(255), 0, nil, common.Big0, common.Big1, common.Big2, nil),
 	}
 	for _, tx := range validTransactions {
 		if err := tx.Validate(); err != nil {
@@ -0,0 +1,89 @@
+package vm
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+)
+
+//访问列表中的一个账户以及该账户被访问的存储位置
+type AccessTuple struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+//执行过程中访问的全部账户和存储位置(按首次访问的顺序)
+type AccessList []AccessTuple
+
+//记录执行过程中访问的账户和存储位置的Tracer
+type AccessListTracer struct {
+	list  AccessList
+	index map[common.Address]int                      //账户在访问列表中的位置
+	slots map[common.Address]map[common.Hash]struct{} //账户已经记录的存储位置
+}
+
+//创建一个访问列表Tracer
+func NewAccessListTracer() *AccessListTracer {
+	return &AccessListTracer{
+		index: make(map[common.Address]int),
+		slots: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+func (a *AccessListTracer) addAddress(addr common.Address) {
+	if _, ok := a.index[addr]; !ok {
+		a.index[addr] = len(a.list)
+		a.list = append(a.list, AccessTuple{Address: addr, StorageKeys: []common.Hash{}})
+	}
+}
+
+func (a *AccessListTracer) addSlot(addr common.Address, slot common.Hash) {
+	a.addAddress(addr)
+	if a.slots[addr] == nil {
+		a.slots[addr] = make(map[common.Hash]struct{})
+	}
+	if _, ok := a.slots[addr][slot]; !ok {
+		a.slots[addr][slot] = struct{}{}
+		tuple := &a.list[a.index[addr]]
+		tuple.StorageKeys = append(tuple.StorageKeys, slot)
+	}
+}
+
+//记录交易的发送者和接收者
+func (a *AccessListTracer) CaptureStart(env *EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	a.addAddress(from)
+	a.addAddress(to)
+	return nil
+}
+
+//记录当前合约以及操作访问的账户和存储位置
+func (a *AccessListTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+
+	a.addAddress(contract.Address())
+
+	switch op {
+	case SLOAD, SSTORE:
+		if stack.len() >= 1 {
+			a.addSlot(contract.Address(), common.BigToHash(stack.Back(0)))
+		}
+	case BALANCE, EXTCODESIZE, EXTCODECOPY, EXTCODEHASH, SELFDESTRUCT:
+		if stack.len() >= 1 {
+			a.addAddress(common.BigToAddress(stack.Back(0)))
+		}
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		if stack.len() >= 2 {
+			a.addAddress(common.BigToAddress(stack.Back(1)))
+		}
+	}
+	return nil
+}
+
+func (a *AccessListTracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	return nil
+}
+
+//返回记录的访问列表
+func (a *AccessListTracer) AccessList() AccessList {
+	return a.list
+}
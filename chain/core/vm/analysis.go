@@ -20,12 +20,50 @@ import (
 	"math/big"
 
 	"github.com/Bokerchain/Boker/chain/common"
+	lru "github.com/hashicorp/golang-lru"
 )
 
-// destinations stores one map per contract (keyed by hash of code).
-// The maps contain an entry for each location of a JUMPDEST
+// analysisCacheSize is the number of contract code analyses kept across
+// executions, so frequently called contracts are only analysed once.
+const analysisCacheSize = 1024
+
+var analysisCache, _ = lru.New(analysisCacheSize)
+
+// codeAnalysis is the result of analysing a contract's code. It is never
+// modified after creation and can be shared between interpreters.
+type codeAnalysis struct {
+	jumpdests bitvec // data locations, see codeBitmap
+	fusions   bitvec // start locations of fusable instruction pairs
+}
+
+// analyse returns the code analysis for the given code, consulting the
+// shared analysis cache first.
+func analyse(codehash common.Hash, code []byte) *codeAnalysis {
+	if codehash == (common.Hash{}) {
+		return &codeAnalysis{jumpdests: codeBitmap(code), fusions: fusionBitmap(code)}
+	}
+	if cached, ok := analysisCache.Get(codehash); ok {
+		return cached.(*codeAnalysis)
+	}
+	analysis := &codeAnalysis{jumpdests: codeBitmap(code), fusions: fusionBitmap(code)}
+	analysisCache.Add(codehash, analysis)
+	return analysis
+}
+
+// destinations stores one analysis per contract (keyed by hash of code).
+// The analyses contain an entry for each location of a JUMPDEST
 // instruction.
-type destinations map[common.Hash]bitvec
+type destinations map[common.Hash]*codeAnalysis
+
+// analysis returns the code analysis for the given code.
+func (d destinations) analysis(codehash common.Hash, code []byte) *codeAnalysis {
+	a, analysed := d[codehash]
+	if !analysed {
+		a = analyse(codehash, code)
+		d[codehash] = a
+	}
+	return a
+}
 
 // has checks whether code has a JUMPDEST at dest.
 func (d destinations) has(codehash common.Hash, code []byte, dest *big.Int) bool {
@@ -36,11 +74,7 @@ func (d destinations) has(codehash common.Hash, code []byte, dest *big.Int) bool
 		return false
 	}
 
-	m, analysed := d[codehash]
-	if !analysed {
-		m = codeBitmap(code)
-		d[codehash] = m
-	}
+	m := d.analysis(codehash, code).jumpdests
 	return OpCode(code[udest]) == JUMPDEST && m.codeSegment(udest)
 }
 
@@ -57,6 +91,11 @@ func (bits *bitvec) set8(pos uint64) {
 	(*bits)[pos/8+1] |= ^(0xFF >> (pos % 8))
 }
 
+// isSet checks if the bit at the position is set.
+func (bits *bitvec) isSet(pos uint64) bool {
+	return !bits.codeSegment(pos)
+}
+
 // codeSegment checks if the position is in a code segment.
 func (bits *bitvec) codeSegment(pos uint64) bool {
 	return ((*bits)[pos/8] & (0x80 >> (pos % 8))) == 0
@@ -88,3 +127,23 @@ func codeBitmap(code []byte) bitvec {
 	}
 	return bits
 }
+
+// fusionBitmap collects the locations of PUSHn instructions directly followed
+// by a JUMP or JUMPI, which the interpreter can execute as one instruction.
+func fusionBitmap(code []byte) bitvec {
+	bits := make(bitvec, len(code)/8+1)
+	for pc := uint64(0); pc < uint64(len(code)); {
+		op := OpCode(code[pc])
+
+		if op >= PUSH1 && op <= PUSH32 {
+			next := pc + uint64(op-PUSH1) + 2
+			if next < uint64(len(code)) && (OpCode(code[next]) == JUMP || OpCode(code[next]) == JUMPI) {
+				bits.set(pc)
+			}
+			pc = next
+		} else {
+			pc++
+		}
+	}
+	return bits
+}
@@ -16,7 +16,12 @@
 
 package vm
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/common"
+)
 
 func TestJumpDestAnalysis(t *testing.T) {
 	tests := []struct {
@@ -51,3 +56,38 @@ func TestJumpDestAnalysis(t *testing.T) {
 	}
 
 }
+
+func TestFusionAnalysis(t *testing.T) {
+	code := []byte{
+		byte(PUSH1), 0x04, byte(JUMP), // fusable
+		byte(JUMPDEST),
+		byte(PUSH2), byte(JUMP), byte(JUMPI), byte(JUMPI), // fusable, jumps are push data
+		byte(PUSH1), 0x01, byte(ADD), byte(JUMP), // not fusable
+		byte(PUSH1), 0x00, // push at the end of the code
+	}
+	fusions := fusionBitmap(code)
+	for pc := uint64(0); pc < uint64(len(code)); pc++ {
+		if want := pc == 0 || pc == 4; fusions.isSet(pc) != want {
+			t.Errorf("pc %d: fusable %v, want %v", pc, fusions.isSet(pc), want)
+		}
+	}
+}
+
+func TestAnalysisCache(t *testing.T) {
+	code := []byte{byte(PUSH1), 0x03, byte(JUMP), byte(JUMPDEST)}
+	hash := common.BytesToHash(code)
+
+	if analyse(hash, code) != analyse(hash, code) {
+		t.Errorf("analysis of the same code hash not cached")
+	}
+	if analyse(common.Hash{}, code) == analyse(common.Hash{}, code) {
+		t.Errorf("analysis of code without hash cached")
+	}
+	// Fresh destinations should pick up the cached analysis
+	if !make(destinations).has(hash, code, big.NewInt(3)) {
+		t.Errorf("jumpdest not found")
+	}
+	if make(destinations).analysis(hash, code) != analyse(hash, code) {
+		t.Errorf("destinations not using the cached analysis")
+	}
+}
@@ -44,8 +44,7 @@ func run(evm *EVM, snapshot int, contract *Contract, input []byte) ([]byte, erro
 	//判断合约地址是否为nil
 	if contract.CodeAddr != nil {
 
-		precompiles := PrecompiledContractsHomestead
-		if p := precompiles[*contract.CodeAddr]; p != nil {
+		if p := evm.precompiles[*contract.CodeAddr]; p != nil {
 
 			return RunPrecompiledContract(p, input, contract)
 		}
@@ -100,6 +99,8 @@ type EVM struct {
 	chainConfig *params.ChainConfig
 	// chain rules contains the chain rules for the current epoch
 	chainRules params.Rules
+	//当前区块启用的预编译合约(包括链配置中注册的预编译合约)
+	precompiles map[common.Address]PrecompiledContract
 	// virtual machine configuration options used to initialise the
 	// evm.
 	vmConfig Config
@@ -120,6 +121,7 @@ func NewEVM(ctx Context, statedb StateDB, chainConfig *params.ChainConfig, vmCon
 		vmConfig:    vmConfig,
 		chainConfig: chainConfig,
 		chainRules:  chainConfig.Rules(ctx.BlockNumber),
+		precompiles: activePrecompiles(chainConfig, ctx.BlockNumber),
 	}
 
 	evm.interpreter = NewInterpreter(evm, vmConfig)
@@ -161,12 +163,14 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	//判断地址交易地址是否存在
 	if !evm.StateDB.Exist(addr) {
 
-		precompiles := PrecompiledContractsHomestead
-		if precompiles[addr] == nil && value.Sign() == 0 {
+		if evm.precompiles[addr] == nil && value.Sign() == 0 {
 			return nil, gas, nil
 		}
 		evm.StateDB.CreateAccount(addr)
 	}
+	if evm.vmConfig.Debug && evm.depth == 0 {
+		evm.vmConfig.Tracer.CaptureStart(evm, caller.Address(), addr, false, input, gas, value)
+	}
 	evm.Transfer(evm.StateDB, caller.Address(), to.Address(), value)
 
 	//初始化新合约并设置要使用的代码 以太坊智能合同是此执行上下文的作用域环境只要。
@@ -311,6 +315,21 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 //使用代码作为部署代码创建新合同
 func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
 
+	//生成合约地址，使用sender的address+nonce的rlp值，然后Keccak256加密
+	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(caller.Address()))
+	return evm.create(caller, code, gas, value, contractAddr)
+}
+
+//使用代码作为部署代码创建新合同，合约地址由调用者地址、salt以及部署代码的哈希值决定(CREATE2)
+func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *big.Int, salt *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+
+	contractAddr = crypto.CreateAddress2(caller.Address(), common.BigToHash(salt), crypto.Keccak256(code))
+	return evm.create(caller, code, gas, endowment, contractAddr)
+}
+
+//在给定的地址上创建合约并执行部署代码
+func (evm *EVM) create(caller ContractRef, code []byte, gas uint64, value *big.Int, address common.Address) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+
 	//深度检查执行， 如果我们试图在上面超过限制的执行，则失败
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, common.Address{}, gas, ErrDepth
@@ -324,8 +343,7 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
 	log.Info("(evm *EVM) Create", "addr", caller.Address(), "nonce", nonce+1)
 
-	//生成合约地址，使用sender的address+nonce的rlp值，然后Keccak256加密
-	contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	contractAddr = address
 	contractHash := evm.StateDB.GetCodeHash(contractAddr)
 
 	//保证没有合约正在执行(双花), 并且合约存在
@@ -342,6 +360,9 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 		evm.StateDB.SetNonce(contractAddr, 1)
 	}*/
 
+	if evm.vmConfig.Debug && evm.depth == 0 {
+		evm.vmConfig.Tracer.CaptureStart(evm, caller.Address(), contractAddr, true, code, gas, value)
+	}
 	//执行转账,最终调用的statedb的SubBalance（sender减去value）和AddBalance(recipient加上value),中间人是contractAddr
 	evm.Transfer(evm.StateDB, caller.Address(), contractAddr, value)
 
@@ -0,0 +1,47 @@
+package vm
+
+import (
+	"fmt"
+)
+
+var (
+	fusedJumpStack  = makeStackFunc(0, 1) //PUSHn + JUMP的栈检查
+	fusedJumpiStack = makeStackFunc(1, 2) //PUSHn + JUMPI的栈检查
+)
+
+//执行融合的PUSHn + JUMP/JUMPI指令对，跳转目标直接从代码中读取而不经过栈，消耗的gas和执行结果与分别执行两条指令相同
+func (in *Interpreter) runFused(pc *uint64, contract *Contract, stack *Stack) error {
+
+	size := uint64(contract.GetOp(*pc)-PUSH1) + 1
+	jump := contract.GetOp(*pc + size + 1)
+
+	validateStack, cost := fusedJumpStack, GasFastestStep+GasMidStep
+	if jump == JUMPI {
+		validateStack, cost = fusedJumpiStack, GasFastestStep+GasSlowStep
+	}
+	if err := validateStack(stack); err != nil {
+		return err
+	}
+	if !in.cfg.DisableGasMetering && !contract.UseGas(cost) {
+		return ErrOutOfGas
+	}
+
+	pos := in.intPool.get().SetBytes(contract.Code[*pc+1 : *pc+1+size])
+	defer in.intPool.put(pos)
+
+	if jump == JUMPI {
+		cond := stack.pop()
+		defer in.intPool.put(cond)
+
+		if cond.Sign() == 0 {
+			*pc += size + 2
+			return nil
+		}
+	}
+	if !contract.jumpdests.has(contract.CodeHash, contract.Code, pos) {
+		nop := contract.GetOp(pos.Uint64())
+		return fmt.Errorf("invalid jump destination (%v) %v", nop, pos)
+	}
+	*pc = pos.Uint64()
+	return nil
+}
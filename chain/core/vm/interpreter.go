@@ -25,6 +25,9 @@ type Config struct {
 	DisableGasMetering bool
 	// Enable recording of SHA3/keccak preimages
 	EnablePreimageRecording bool
+	// EnableFusion executes a PUSHn directly followed by a JUMP or JUMPI as
+	// a single instruction. It has no effect while debugging.
+	EnableFusion bool
 	// JumpTable contains the EVM instruction table. This
 	// may be left uninitialised and will be set to the default
 	// table.
@@ -49,8 +52,10 @@ func NewInterpreter(evm *EVM, cfg Config) *Interpreter {
 	// We use the STOP instruction whether to see
 	// the jump table was initialised. If it was not
 	// we'll set the default jump table.
-	/*if !cfg.JumpTable[STOP].valid {
+	if !cfg.JumpTable[STOP].valid {
 		switch {
+		case evm.ChainConfig().IsConstantinople(evm.BlockNumber):
+			cfg.JumpTable = constantinopleInstructionSet
 		case evm.ChainConfig().IsByzantium(evm.BlockNumber):
 			cfg.JumpTable = byzantiumInstructionSet
 		case evm.ChainConfig().IsHomestead(evm.BlockNumber):
@@ -58,9 +63,6 @@ func NewInterpreter(evm *EVM, cfg Config) *Interpreter {
 		default:
 			cfg.JumpTable = frontierInstructionSet
 		}
-	}*/
-	if !cfg.JumpTable[STOP].valid {
-		cfg.JumpTable = homesteadInstructionSet
 	}
 
 	return &Interpreter{
@@ -122,9 +124,14 @@ func (in *Interpreter) Run(snapshot int, contract *Contract, input []byte) (ret
 		pcCopy    uint64       // needed for the deferred Tracer
 		gasCopy   uint64       // for Tracer to log gas remaining before execution
 		logged    bool         // deferred Tracer should ignore already logged steps
+		fusions   bitvec       // locations of fusable instruction pairs
 	)
 	contract.Input = input
 
+	if in.cfg.EnableFusion && !in.cfg.Debug {
+		fusions = contract.jumpdests.analysis(codehash, contract.Code).fusions
+	}
+
 	defer func() {
 		if err != nil && !logged && in.cfg.Debug {
 			in.cfg.Tracer.CaptureState(in.evm, pcCopy, op, gasCopy, cost, mem, stackCopy, contract, in.evm.depth, err)
@@ -148,6 +155,14 @@ func (in *Interpreter) Run(snapshot int, contract *Contract, input []byte) (ret
 		//pc是程序计数器，控制当前执行到的code位置, 正常情况下每次都会定位在操作码上
 		op = contract.GetOp(pc)
 
+		//PUSHn之后紧跟JUMP或JUMPI时作为一条指令执行
+		if fusions != nil && pc < uint64(len(contract.Code)) && fusions.isSet(pc) {
+			if err := in.runFused(&pc, contract, stack); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		if in.cfg.Debug {
 			logged = false
 			pcCopy = pc
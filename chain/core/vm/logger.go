@@ -46,6 +46,8 @@ type LogConfig struct {
 	DisableStack   bool // disable stack capture
 	DisableStorage bool // disable storage capture
 	Limit          int  // maximum length of output, but zero means unlimited
+
+	EnableStorageDiff bool // capture storage writes and the account diff of the transaction
 }
 
 //go:generate gencodec -type StructLog -field-override structLogMarshaling -out gen_structlog.go
@@ -79,11 +81,13 @@ func (s *StructLog) OpName() string {
 }
 
 // Tracer is used to collect execution traces from an EVM transaction
-// execution. CaptureState is called for each step of the VM with the
-// current VM state.
+// execution. CaptureStart is called once before the top level call or
+// create transfers any value, CaptureState is called for each step of
+// the VM with the current VM state.
 // Note that reference types are actual VM data structures; make copies
 // if you need to retain them beyond the current call.
 type Tracer interface {
+	CaptureStart(env *EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error
 	CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error
 	CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error
 }
@@ -98,12 +102,18 @@ type StructLogger struct {
 
 	logs          []StructLog
 	changedValues map[common.Address]Storage
+
+	statedb StateDB                     // state the traced transaction executes on
+	writes  []StorageWrite              // storage writes in execution order
+	touched []common.Address            // accounts touched by the transaction
+	seen    map[common.Address]struct{} // set of touched accounts
 }
 
 // NewStructLogger returns a new logger
 func NewStructLogger(cfg *LogConfig) *StructLogger {
 	logger := &StructLogger{
 		changedValues: make(map[common.Address]Storage),
+		seen:          make(map[common.Address]struct{}),
 	}
 	if cfg != nil {
 		logger.cfg = *cfg
@@ -111,10 +121,26 @@ func NewStructLogger(cfg *LogConfig) *StructLogger {
 	return logger
 }
 
+// CaptureStart records the sender, the recipient and the coinbase of the
+// transaction as touched accounts when storage diffs are enabled.
+func (l *StructLogger) CaptureStart(env *EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	if l.cfg.EnableStorageDiff {
+		l.statedb = env.StateDB
+		l.touch(from)
+		l.touch(to)
+		l.touch(env.Coinbase)
+	}
+	return nil
+}
+
 // CaptureState logs a new structured log message and pushes it out to the environment
 //
 // CaptureState also tracks SSTORE ops to track dirty values.
 func (l *StructLogger) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	// storage writes are captured regardless of the log limit
+	if l.cfg.EnableStorageDiff {
+		l.captureWrites(env, pc, op, stack, contract, depth)
+	}
 	// check if already accumulated the specified number of logs
 	if l.cfg.Limit != 0 && l.cfg.Limit <= len(l.logs) {
 		return ErrTraceLimitReached
@@ -161,6 +187,7 @@ func (l *StructLogger) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost ui
 	return nil
 }
 
+// CaptureEnd is called after the call finishes.
 func (l *StructLogger) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
 	fmt.Printf("0x%x", output)
 	if err != nil {
@@ -0,0 +1,136 @@
+package vm
+
+import (
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+)
+
+//调用中的一次存储写入
+type StorageWrite struct {
+	Address  common.Address `json:"address"`
+	Slot     common.Hash    `json:"slot"`
+	Original common.Hash    `json:"original"` //写入前的值
+	Value    common.Hash    `json:"value"`    //写入的值
+	Depth    int            `json:"depth"`
+	Pc       uint64         `json:"pc"`
+}
+
+//交易执行前后账户状态的变化，只包含发生了变化的字段
+type AccountDiff struct {
+	Address  common.Address           `json:"address"`
+	Balance  *BalanceDiff             `json:"balance,omitempty"`
+	Nonce    *NonceDiff               `json:"nonce,omitempty"`
+	CodeHash *HashDiff                `json:"codeHash,omitempty"`
+	Storage  map[common.Hash]HashDiff `json:"storage,omitempty"`
+}
+
+//余额的变化
+type BalanceDiff struct {
+	From *hexutil.Big `json:"from"`
+	To   *hexutil.Big `json:"to"`
+}
+
+//nonce的变化
+type NonceDiff struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+//代码哈希或者存储值的变化
+type HashDiff struct {
+	From common.Hash `json:"from"`
+	To   common.Hash `json:"to"`
+}
+
+//记录账户为交易涉及的账户
+func (l *StructLogger) touch(addr common.Address) {
+	if _, ok := l.seen[addr]; !ok {
+		l.seen[addr] = struct{}{}
+		l.touched = append(l.touched, addr)
+	}
+}
+
+//记录SSTORE的写入以及调用和自毁涉及的账户，在操作执行前调用，因此可以从状态中读取写入前的值
+func (l *StructLogger) captureWrites(env *EVM, pc uint64, op OpCode, stack *Stack, contract *Contract, depth int) {
+
+	if l.statedb == nil {
+		l.statedb = env.StateDB
+	}
+	l.touch(contract.Address())
+
+	switch op {
+	case SSTORE:
+		if stack.len() >= 2 {
+			slot := common.BigToHash(stack.Back(0))
+			l.writes = append(l.writes, StorageWrite{
+				Address:  contract.Address(),
+				Slot:     slot,
+				Original: env.StateDB.GetState(contract.Address(), slot),
+				Value:    common.BigToHash(stack.Back(1)),
+				Depth:    depth,
+				Pc:       pc,
+			})
+		}
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		if stack.len() >= 2 {
+			l.touch(common.BigToAddress(stack.Back(1)))
+		}
+	case SELFDESTRUCT:
+		if stack.len() >= 1 {
+			l.touch(common.BigToAddress(stack.Back(0)))
+		}
+	}
+}
+
+//返回交易中的全部存储写入(按执行顺序)
+func (l *StructLogger) StorageWrites() []StorageWrite {
+	return l.writes
+}
+
+//比较交易执行前的状态pre和执行后的状态，返回交易涉及的账户中发生变化的账户，需要在交易执行完成后调用
+func (l *StructLogger) AccountDiffs(pre StateDB) []AccountDiff {
+
+	if l.statedb == nil {
+		return nil
+	}
+	post := l.statedb
+
+	slots := make(map[common.Address][]common.Hash)
+	for _, write := range l.writes {
+		slots[write.Address] = append(slots[write.Address], write.Slot)
+	}
+	var diffs []AccountDiff
+	for _, addr := range l.touched {
+		diff := AccountDiff{Address: addr}
+		changed := false
+
+		if from, to := pre.GetBalance(addr), post.GetBalance(addr); from.Cmp(to) != 0 {
+			diff.Balance = &BalanceDiff{From: (*hexutil.Big)(from), To: (*hexutil.Big)(to)}
+			changed = true
+		}
+		if from, to := pre.GetNonce(addr), post.GetNonce(addr); from != to {
+			diff.Nonce = &NonceDiff{From: hexutil.Uint64(from), To: hexutil.Uint64(to)}
+			changed = true
+		}
+		if from, to := pre.GetCodeHash(addr), post.GetCodeHash(addr); from != to {
+			diff.CodeHash = &HashDiff{From: from, To: to}
+			changed = true
+		}
+		for _, slot := range slots[addr] {
+			if _, done := diff.Storage[slot]; done {
+				continue
+			}
+			if from, to := pre.GetState(addr, slot), post.GetState(addr, slot); from != to {
+				if diff.Storage == nil {
+					diff.Storage = make(map[common.Hash]HashDiff)
+				}
+				diff.Storage[slot] = HashDiff{From: from, To: to}
+				changed = true
+			}
+		}
+		if changed {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,159 @@
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/crypto/blake2b"
+	"github.com/Bokerchain/Boker/chain/params"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	blake2FInputLength       = 213                                           //blake2b压缩函数的输入长度
+	ed25519VerifyInputLength = ed25519.PublicKeySize + ed25519.SignatureSize //ed25519验证的最小输入长度(公钥和签名)
+)
+
+var (
+	errBlake2FInvalidInputLength = errors.New("invalid input length")
+	errBlake2FInvalidFinalFlag   = errors.New("invalid final flag")
+	errEd25519InvalidInputLength = errors.New("invalid input length")
+)
+
+//可以在链配置中注册的预编译合约的Go实现，按名称索引
+var precompileRegistry = map[string]PrecompiledContract{
+	"blake2f": &blake2F{},
+	"ed25519": &ed25519Verify{},
+}
+
+//注册一个可以在链配置中使用的预编译合约实现，名称重复时panic
+func RegisterPrecompiledContract(name string, p PrecompiledContract) {
+	if _, exist := precompileRegistry[name]; exist {
+		panic(fmt.Sprintf("precompiled contract %q already registered", name))
+	}
+	precompileRegistry[name] = p
+}
+
+//检查链配置中注册的预编译合约是否有效，实现必须已经注册并且地址不能与内置的预编译合约或其他配置重复
+func ValidatePrecompiles(config *params.ChainConfig) error {
+
+	seen := make(map[common.Address]bool)
+	for _, p := range config.Precompiles {
+		if _, ok := precompileRegistry[p.Name]; !ok {
+			return fmt.Errorf("precompile %x: unknown implementation %q", p.Address, p.Name)
+		}
+		if PrecompiledContractsByzantium[p.Address] != nil {
+			return fmt.Errorf("precompile %x: address reserved by a built-in precompile", p.Address)
+		}
+		if seen[p.Address] {
+			return fmt.Errorf("precompile %x: duplicate address", p.Address)
+		}
+		seen[p.Address] = true
+	}
+	return nil
+}
+
+//返回在给定区块中启用的全部预编译合约
+func activePrecompiles(config *params.ChainConfig, num *big.Int) map[common.Address]PrecompiledContract {
+
+	precompiles := PrecompiledContractsHomestead
+	if config.IsByzantium(num) {
+		precompiles = PrecompiledContractsByzantium
+	}
+	if len(config.Precompiles) == 0 {
+		return precompiles
+	}
+	active := make(map[common.Address]PrecompiledContract, len(precompiles)+len(config.Precompiles))
+	for addr, p := range precompiles {
+		active[addr] = p
+	}
+	for i := range config.Precompiles {
+		cfg := &config.Precompiles[i]
+		if impl := precompileRegistry[cfg.Name]; impl != nil && cfg.IsActive(num) && active[cfg.Address] == nil {
+			active[cfg.Address] = &configuredPrecompile{PrecompiledContract: impl, config: cfg}
+		}
+	}
+	return active
+}
+
+//链配置中注册的预编译合约，配置了Gas时使用配置的Gas计算
+type configuredPrecompile struct {
+	PrecompiledContract
+	config *params.Precompile
+}
+
+func (c *configuredPrecompile) RequiredGas(input []byte) uint64 {
+	if c.config.BaseGas == 0 && c.config.WordGas == 0 {
+		return c.PrecompiledContract.RequiredGas(input)
+	}
+	return uint64(len(input)+31)/32*c.config.WordGas + c.config.BaseGas
+}
+
+//blake2b压缩函数F(EIP-152)的预编译合约
+type blake2F struct{}
+
+func (c *blake2F) RequiredGas(input []byte) uint64 {
+	if len(input) != blake2FInputLength {
+		return 0
+	}
+	return uint64(binary.BigEndian.Uint32(input[0:4])) * params.Blake2bFRoundGas
+}
+
+func (c *blake2F) Run(input []byte) ([]byte, error) {
+	//输入为：轮数(4字节大端) | 状态h(64字节) | 消息m(128字节) | 计数器t(16字节) | 结束标志f(1字节)
+	if len(input) != blake2FInputLength {
+		return nil, errBlake2FInvalidInputLength
+	}
+	if input[212] != 0 && input[212] != 1 {
+		return nil, errBlake2FInvalidFinalFlag
+	}
+	var (
+		rounds = binary.BigEndian.Uint32(input[0:4])
+		final  = input[212] == 1
+		h      [8]uint64
+		m      [16]uint64
+		t      [2]uint64
+	)
+	for i := 0; i < 8; i++ {
+		h[i] = binary.LittleEndian.Uint64(input[4+i*8:])
+	}
+	for i := 0; i < 16; i++ {
+		m[i] = binary.LittleEndian.Uint64(input[68+i*8:])
+	}
+	t[0] = binary.LittleEndian.Uint64(input[196:204])
+	t[1] = binary.LittleEndian.Uint64(input[204:212])
+
+	blake2b.F(&h, m, t, final, rounds)
+
+	output := make([]byte, 64)
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint64(output[i*8:], h[i])
+	}
+	return output, nil
+}
+
+//ed25519签名验证的预编译合约
+type ed25519Verify struct{}
+
+func (c *ed25519Verify) RequiredGas(input []byte) uint64 {
+	return uint64(len(input)+31)/32*params.Ed25519VerifyPerWordGas + params.Ed25519VerifyBaseGas
+}
+
+func (c *ed25519Verify) Run(input []byte) ([]byte, error) {
+	//输入为：公钥(32字节) | 签名(64字节) | 消息，签名有效时返回1，否则返回0
+	if len(input) < ed25519VerifyInputLength {
+		return nil, errEd25519InvalidInputLength
+	}
+	var (
+		pubkey    = input[:ed25519.PublicKeySize]
+		signature = input[ed25519.PublicKeySize:ed25519VerifyInputLength]
+		message   = input[ed25519VerifyInputLength:]
+	)
+	if ed25519.Verify(pubkey, message, signature) {
+		return common.LeftPadBytes([]byte{1}, 32), nil
+	}
+	return make([]byte, 32), nil
+}
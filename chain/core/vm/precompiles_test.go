@@ -0,0 +1,102 @@
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/params"
+	"golang.org/x/crypto/ed25519"
+)
+
+//blake2b压缩函数的测试数据(EIP-152)
+var blake2FTests = []precompiledTest{
+	{
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		gas:      12,
+		name:     "vector 5",
+	},
+}
+
+func TestPrecompiledBlake2F(t *testing.T) {
+	p := precompileRegistry["blake2f"]
+	for _, test := range blake2FTests {
+		in := common.Hex2Bytes(test.input)
+		if gas := p.RequiredGas(in); gas != test.gas {
+			t.Errorf("%s: gas mismatch: have %d, want %d", test.name, gas, test.gas)
+		}
+		if res, err := p.Run(in); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if common.Bytes2Hex(res) != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, common.Bytes2Hex(res))
+		}
+		if _, err := p.Run(in[1:]); err != errBlake2FInvalidInputLength {
+			t.Errorf("%s: short input: have %v, want %v", test.name, err, errBlake2FInvalidInputLength)
+		}
+		in[len(in)-1] = 2
+		if _, err := p.Run(in); err != errBlake2FInvalidFinalFlag {
+			t.Errorf("%s: final flag: have %v, want %v", test.name, err, errBlake2FInvalidFinalFlag)
+		}
+	}
+}
+
+func TestPrecompiledEd25519Verify(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(nil)
+	msg := []byte("boker base contract")
+
+	input := append(append(append([]byte{}, pub...), ed25519.Sign(key, msg)...), msg...)
+	p := precompileRegistry["ed25519"]
+
+	if res, err := p.Run(input); err != nil || new(big.Int).SetBytes(res).Uint64() != 1 {
+		t.Errorf("valid signature rejected: %x, %v", res, err)
+	}
+	input[len(input)-1] ^= 0xff
+	if res, err := p.Run(input); err != nil || len(res) != 32 || new(big.Int).SetBytes(res).Sign() != 0 {
+		t.Errorf("invalid signature accepted: %x, %v", res, err)
+	}
+	if _, err := p.Run(input[:ed25519VerifyInputLength-1]); err != errEd25519InvalidInputLength {
+		t.Errorf("short input: have %v, want %v", err, errEd25519InvalidInputLength)
+	}
+}
+
+//测试链配置中注册的预编译合约按区块启用并使用配置的Gas
+func TestConfiguredPrecompiles(t *testing.T) {
+	addr := common.BytesToAddress([]byte{0x10})
+	config := &params.ChainConfig{
+		ByzantiumBlock: big.NewInt(0),
+		Precompiles: []params.Precompile{
+			{Address: addr, Name: "ed25519", Block: big.NewInt(5), BaseGas: 100, WordGas: 10},
+		},
+	}
+	if err := ValidatePrecompiles(config); err != nil {
+		t.Fatalf("valid config rejected: %v", err)
+	}
+	if p := activePrecompiles(config, big.NewInt(4))[addr]; p != nil {
+		t.Errorf("precompile active before its block")
+	}
+	p := activePrecompiles(config, big.NewInt(5))[addr]
+	if p == nil {
+		t.Fatalf("precompile not active at its block")
+	}
+	if gas := p.RequiredGas(make([]byte, 100)); gas != 140 {
+		t.Errorf("gas mismatch: have %d, want %d", gas, 140)
+	}
+	if activePrecompiles(config, big.NewInt(5))[common.BytesToAddress([]byte{8})] == nil {
+		t.Errorf("built-in precompile missing")
+	}
+
+	invalid := []params.Precompile{
+		{Address: addr, Name: "unknown"},
+		{Address: common.BytesToAddress([]byte{1}), Name: "blake2f"},
+	}
+	for _, p := range invalid {
+		if err := ValidatePrecompiles(&params.ChainConfig{Precompiles: []params.Precompile{p}}); err == nil {
+			t.Errorf("invalid precompile %x (%s) accepted", p.Address, p.Name)
+		}
+	}
+	duplicate := []params.Precompile{{Address: addr, Name: "blake2f"}, {Address: addr, Name: "ed25519"}}
+	if err := ValidatePrecompiles(&params.ChainConfig{Precompiles: duplicate}); err == nil {
+		t.Errorf("duplicate precompile address accepted")
+	}
+}
@@ -149,3 +149,133 @@ func BenchmarkCall(b *testing.B) {
 		}
 	}
 }
+
+// Tests that fused instructions produce the same results and gas usage as the
+// instructions executed one by one.
+func TestFusedCall(t *testing.T) {
+	programs := [][]byte{
+		{
+			byte(vm.PUSH1), 0x04, byte(vm.JUMP), 0xfe,
+			byte(vm.JUMPDEST),
+			byte(vm.PUSH1), 5,
+			byte(vm.JUMPDEST),
+			byte(vm.PUSH1), 1, byte(vm.SWAP1), byte(vm.SUB),
+			byte(vm.DUP1), byte(vm.PUSH1), 0x07, byte(vm.JUMPI),
+			byte(vm.PUSH1), 42, byte(vm.PUSH1), 0, byte(vm.MSTORE),
+			byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+		},
+		{byte(vm.PUSH1), 0x03, byte(vm.JUMP), byte(vm.STOP)},
+		{byte(vm.PUSH1), 0x03, byte(vm.JUMPI)},
+	}
+	for i, code := range programs {
+		var (
+			rets [2][]byte
+			gas  [2]uint64
+			errs [2]error
+		)
+		for j, fusion := range []bool{false, true} {
+			db, _ := ethdb.NewMemDatabase()
+			statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+			address := common.HexToAddress("0x0a")
+			statedb.SetCode(address, code)
+
+			cfg := &Config{State: statedb, GasLimit: 100000, EVMConfig: vm.Config{EnableFusion: fusion}}
+			rets[j], gas[j], errs[j] = Call(address, nil, cfg)
+		}
+		if string(rets[0]) != string(rets[1]) || gas[0] != gas[1] || (errs[0] == nil) != (errs[1] == nil) {
+			t.Errorf("program %d: fused result mismatch: have (%x, %d, %v), want (%x, %d, %v)", i, rets[1], gas[1], errs[1], rets[0], gas[0], errs[0])
+		}
+	}
+}
+
+// Tests that the struct logger records storage writes and the resulting account
+// diff when storage diffs are enabled.
+func TestStorageDiffCapture(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	address := common.HexToAddress("0x0a")
+	statedb.SetCode(address, []byte{
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE),
+		byte(vm.PUSH1), 2, byte(vm.PUSH1), 0, byte(vm.SSTORE),
+		byte(vm.PUSH1), 7, byte(vm.PUSH1), 1, byte(vm.SSTORE),
+	})
+	prestate := statedb.Copy()
+
+	logger := vm.NewStructLogger(&vm.LogConfig{EnableStorageDiff: true})
+	cfg := &Config{State: statedb, EVMConfig: vm.Config{Debug: true, Tracer: logger}}
+	if _, _, err := Call(address, nil, cfg); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	writes := logger.StorageWrites()
+	if len(writes) != 3 {
+		t.Fatalf("write count mismatch: have %d, want 3", len(writes))
+	}
+	originals := []int64{0, 1, 0}
+	for i, write := range writes {
+		if write.Address != address || write.Original != common.BigToHash(big.NewInt(originals[i])) {
+			t.Errorf("write %d: have %x original %x, want %x original %d", i, write.Address, write.Original, address, originals[i])
+		}
+	}
+	var diff *vm.AccountDiff
+	for _, d := range logger.AccountDiffs(prestate) {
+		if d.Address == address {
+			diff = &d
+		}
+	}
+	if diff == nil {
+		t.Fatalf("no diff for contract %x", address)
+	}
+	want := map[common.Hash]vm.HashDiff{
+		common.BigToHash(big.NewInt(0)): {To: common.BigToHash(big.NewInt(2))},
+		common.BigToHash(big.NewInt(1)): {To: common.BigToHash(big.NewInt(7))},
+	}
+	if len(diff.Storage) != len(want) {
+		t.Fatalf("storage diff size mismatch: have %d, want %d", len(diff.Storage), len(want))
+	}
+	for slot, w := range want {
+		if diff.Storage[slot] != w {
+			t.Errorf("slot %x: have %v, want %v", slot, diff.Storage[slot], w)
+		}
+	}
+}
+
+// Tests that the access list tracer records every touched account and storage
+// slot exactly once.
+func TestAccessListTracer(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	address := common.HexToAddress("0x0a")
+	other := common.HexToAddress("0x0b")
+	statedb.SetCode(address, []byte{
+		byte(vm.PUSH1), 1, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 2, byte(vm.PUSH1), 1, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0x0b, byte(vm.BALANCE), byte(vm.POP),
+		byte(vm.PUSH1), 3, byte(vm.SLOAD), byte(vm.POP),
+	})
+	tracer := vm.NewAccessListTracer()
+	cfg := &Config{State: statedb, EVMConfig: vm.Config{Debug: true, Tracer: tracer}}
+	if _, _, err := Call(address, nil, cfg); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	list := tracer.AccessList()
+
+	var target, touched *vm.AccessTuple
+	for i := range list {
+		switch list[i].Address {
+		case address:
+			target = &list[i]
+		case other:
+			touched = &list[i]
+		}
+	}
+	if target == nil || touched == nil {
+		t.Fatalf("missing accounts in access list: %v", list)
+	}
+	want := []common.Hash{common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(3))}
+	if len(target.StorageKeys) != len(want) || target.StorageKeys[0] != want[0] || target.StorageKeys[1] != want[1] {
+		t.Errorf("storage keys mismatch: have %x, want %x", target.StorageKeys, want)
+	}
+	if len(touched.StorageKeys) != 0 {
+		t.Errorf("unexpected storage keys for %x: %x", other, touched.StorageKeys)
+	}
+}
@@ -0,0 +1,31 @@
+package blake2b
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+//使用压缩函数计算单个数据块的blake2b-512哈希(RFC 7693附录A)
+func TestF(t *testing.T) {
+
+	h := iv
+	h[0] ^= 0x01010040
+
+	var m [16]uint64
+	block := make([]byte, 128)
+	copy(block, "abc")
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(block[i*8:])
+	}
+	F(&h, m, [2]uint64{3, 0}, true, 12)
+
+	sum := make([]byte, 64)
+	for i := range h {
+		binary.LittleEndian.PutUint64(sum[i*8:], h[i])
+	}
+	want := "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"
+	if have := hex.EncodeToString(sum); have != want {
+		t.Errorf("hash mismatch: have %s, want %s", have, want)
+	}
+}
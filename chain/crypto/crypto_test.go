@@ -107,6 +107,22 @@ func TestNewContractAddress(t *testing.T) {
 	checkAddr(t, common.HexToAddress("c9ddedf451bc62ce88bf9292afb13df35b670699"), caddr2)
 }
 
+// Tests CREATE2 address derivation against the examples of EIP-1014.
+func TestCreateAddress2(t *testing.T) {
+	tests := []struct {
+		origin, salt, code, want string
+	}{
+		{"0000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "00", "4D1A2e2bB4F88F0250f26Ffff098B0b30B26BF38"},
+		{"deadbeef00000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "00", "B928f69Bb1D91Cd65274e3c79d8986362984fDA3"},
+		{"deadbeef00000000000000000000000000000000", "000000000000000000000000feed000000000000000000000000000000000000", "00", "D04116cDd17beBE565EB2422F2497E06cC1C9833"},
+		{"0000000000000000000000000000000000000000", "0000000000000000000000000000000000000000000000000000000000000000", "", "E33C0C7F7df4809055C3ebA6c09CFe4BaF1BD9e0"},
+	}
+	for _, test := range tests {
+		addr := CreateAddress2(common.HexToAddress(test.origin), common.HexToHash(test.salt), Keccak256(common.Hex2Bytes(test.code)))
+		checkAddr(t, common.HexToAddress(test.want), addr)
+	}
+}
+
 func TestLoadECDSAFile(t *testing.T) {
 	keyBytes := common.FromHex(testPrivHex)
 	fileName0 := "test_key0"
@@ -15,12 +15,14 @@ import (
 
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/consensus/dpos"
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/core/state"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/core/vm"
 	"github.com/Bokerchain/Boker/chain/internal/ethapi"
 	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/miner"
 	"github.com/Bokerchain/Boker/chain/params"
 	"github.com/Bokerchain/Boker/chain/rlp"
 	"github.com/Bokerchain/Boker/chain/rpc"
@@ -59,6 +61,9 @@ func (api *PublicEthereumAPI) Validator() (common.Address, error) {
 	}
 
 	firstTimer := api.e.BlockChain().GetBlockByNumber(0).Time().Int64()
+	if engine, ok := api.e.Engine().(*dpos.Dpos); ok {
+		return engine.ProducerAt(api.e.BlockChain().CurrentBlock().Header(), time.Now().Unix(), firstTimer)
+	}
 	return api.e.BlockChain().CurrentBlock().DposCtx().GetCurrentProducer(firstTimer)
 }
 
@@ -72,6 +77,34 @@ func (api *PublicEthereumAPI) Hashrate() hexutil.Uint64 {
 	return hexutil.Uint64(api.e.Miner().HashRate())
 }
 
+//订阅链重组事件，每次重组时推送共同祖先、被丢弃和新加入的区块以及对应的验证者
+func (api *PublicEthereumAPI) ChainReorg(ctx context.Context) (*rpc.Subscription, error) {
+
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan core.ChainReorgEvent)
+		reorgsSub := api.e.BlockChain().SubscribeChainReorgEvent(reorgs)
+		defer reorgsSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-reorgs:
+				notifier.Notify(rpcSub.ID, ev.Reorg)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 //提供用来控制矿工的API，它仅提供对数据进行操作的方法，这些方法在可公开访问且不会带来安全风险。
 type PublicMinerAPI struct {
 	e *Ethereum
@@ -144,6 +177,34 @@ func (api *PrivateMinerAPI) SetExtra(extra string) (bool, error) {
 	return true, nil
 }
 
+//设置提前开始组装区块的时间，例如"500ms"，不能超过出块间隔
+func (api *PrivateMinerAPI) SetSlotMargin(margin string) (bool, error) {
+	d, err := time.ParseDuration(margin)
+	if err != nil {
+		return false, err
+	}
+	if err := api.e.Miner().SetSlotMargin(d); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+//设置组装区块时各个交易通道(基础合约交易、本地交易、远程交易)最多可以使用的区块Gas比例
+func (api *PrivateMinerAPI) SetLaneQuotas(quotas miner.LaneQuotas) (bool, error) {
+	if err := api.e.Miner().SetLaneQuotas(quotas); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+//设置出块时区块Gas限制的调整目标，用于在不硬分叉的情况下调整网络容量
+func (api *PrivateMinerAPI) SetGasLimitTarget(target hexutil.Big) (bool, error) {
+	if err := api.e.Miner().SetGasLimitTarget((*big.Int)(&target)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 //设置矿工的最低可接受Gas价格
 func (api *PrivateMinerAPI) SetGasPrice(gasPrice hexutil.Big) bool {
 	api.e.lock.Lock()
@@ -181,7 +242,8 @@ func (api *PrivateMinerAPI) GetHashrate() uint64 {
 
 //以太坊全节点相关API的集合，通过私有管理端点公开。
 type PrivateAdminAPI struct {
-	eth *Ethereum
+	eth     *Ethereum
+	imports chainImports //后台运行的区块导入任务
 }
 
 func NewPrivateAdminAPI(eth *Ethereum) *PrivateAdminAPI {
@@ -210,63 +272,46 @@ func (api *PrivateAdminAPI) ExportChain(file string) (bool, error) {
 	return true, nil
 }
 
-func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
-	for _, b := range bs {
-		if !chain.HasBlock(b.Hash(), b.NumberU64()) {
-			return false
-		}
-	}
+//导出指定高度的链快照(区块、收据、状态树和Dpos树)到目录中，用于新节点快速启动
+func (api *PrivateAdminAPI) ExportSnapshot(dir string, number rpc.BlockNumber) (*core.SnapshotManifest, error) {
 
-	return true
+	head := api.eth.BlockChain().CurrentBlock().NumberU64()
+	switch {
+	case number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber:
+		number = rpc.BlockNumber(head)
+	case number < 0 || uint64(number) > head:
+		return nil, fmt.Errorf("block #%d not available, head is #%d", number, head)
+	}
+	return api.eth.BlockChain().ExportSnapshot(dir, uint64(number))
 }
 
-//从本地文件导入区块链
-func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
-	// Make sure the can access the file to import
-	in, err := os.Open(file)
-	if err != nil {
-		return false, err
-	}
-	defer in.Close()
+//从目录导入链快照并将快照区块设置为当前区块，之后节点从该区块继续同步
+func (api *PrivateAdminAPI) ImportSnapshot(dir string) (*core.SnapshotManifest, error) {
 
-	var reader io.Reader = in
-	if strings.HasSuffix(file, ".gz") {
-		if reader, err = gzip.NewReader(reader); err != nil {
-			return false, err
-		}
+	if api.eth.Downloader().Synchronising() {
+		return nil, errors.New("chain synchronisation in progress")
 	}
+	return api.eth.BlockChain().ImportSnapshot(dir)
+}
 
-	// Run actual the import in pre-configured batches
-	stream := rlp.NewStream(reader, 0)
+//得到本地时钟相对NTP网络时间的偏差以及出块时间的补偿信息
+func (api *PrivateAdminAPI) ClockStatus() (*dpos.ClockStatus, error) {
 
-	blocks, index := make([]*types.Block, 0, 2500), 0
-	for batch := 0; ; batch++ {
-		// Load a batch of blocks from the input file
-		for len(blocks) < cap(blocks) {
-			block := new(types.Block)
-			if err := stream.Decode(block); err == io.EOF {
-				break
-			} else if err != nil {
-				return false, fmt.Errorf("block %d: failed to parse: %v", index, err)
-			}
-			blocks = append(blocks, block)
-			index++
-		}
-		if len(blocks) == 0 {
-			break
-		}
+	engine, ok := api.eth.Engine().(*dpos.Dpos)
+	if !ok {
+		return nil, ErrDpos
+	}
+	return engine.ClockStatus(), nil
+}
 
-		if hasAllBlocks(api.eth.BlockChain(), blocks) {
-			blocks = blocks[:0]
-			continue
-		}
-		// Import the batch and reset the buffer
-		if _, err := api.eth.BlockChain().InsertChain(blocks); err != nil {
-			return false, fmt.Errorf("batch %d: failed to insert: %v", batch, err)
-		}
-		blocks = blocks[:0]
+//立即重新测量本地时钟的偏差
+func (api *PrivateAdminAPI) CheckClock() (*dpos.ClockStatus, error) {
+
+	engine, ok := api.eth.Engine().(*dpos.Dpos)
+	if !ok {
+		return nil, ErrDpos
 	}
-	return true, nil
+	return engine.CheckClock()
 }
 
 //公开的以太坊全节点API，通过公共调试端点
@@ -278,14 +323,70 @@ func NewPublicDebugAPI(eth *Ethereum) *PublicDebugAPI {
 	return &PublicDebugAPI{eth: eth}
 }
 
+//返回重组日志中最近的链重组记录(从新到旧)，默认返回最近16条
+func (api *PublicDebugAPI) ReorgJournal(count *int) []*core.ReorgEntry {
+
+	limit := 16
+	if count != nil && *count > 0 {
+		limit = *count
+	}
+	return api.eth.BlockChain().ReorgJournal(limit)
+}
+
 // DumpBlock retrieves the entire state of the database at a given block.
 func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error) {
+	stateDb, err := api.stateAt(blockNr)
+	if err != nil {
+		return state.Dump{}, err
+	}
+	return stateDb.RawDump(), nil
+}
+
+const maxDumpAccounts = 256 //增量导出状态时单次返回的最大账户数量
+
+//增量导出状态时的账户过滤条件
+type DumpFilterArgs struct {
+	OnlyContracts  bool         `json:"onlyContracts"`  //只导出合约账户
+	OnlyEOAs       bool         `json:"onlyEOAs"`       //只导出外部账户
+	MinBalance     *hexutil.Big `json:"minBalance"`     //只导出余额不低于该值的账户
+	ExcludeCode    bool         `json:"excludeCode"`    //不导出合约代码
+	ExcludeStorage bool         `json:"excludeStorage"` //不导出合约存储
+}
+
+//从startKey(账户地址的哈希)开始导出最多maxAccounts个满足过滤条件的账户，返回结果中的next用于继续导出
+func (api *PublicDebugAPI) DumpBlockIncremental(blockNr rpc.BlockNumber, startKey hexutil.Bytes, maxAccounts int, filter *DumpFilterArgs) (state.IteratorDump, error) {
+
+	if maxAccounts <= 0 || maxAccounts > maxDumpAccounts {
+		maxAccounts = maxDumpAccounts
+	}
+	if len(startKey) > common.HashLength {
+		return state.IteratorDump{}, errInvalidKeyStart
+	}
+	var dumpFilter state.DumpFilter
+	if filter != nil {
+		dumpFilter = state.DumpFilter{
+			OnlyContracts:  filter.OnlyContracts,
+			OnlyEOAs:       filter.OnlyEOAs,
+			MinBalance:     (*big.Int)(filter.MinBalance),
+			ExcludeCode:    filter.ExcludeCode,
+			ExcludeStorage: filter.ExcludeStorage,
+		}
+	}
+	stateDb, err := api.stateAt(blockNr)
+	if err != nil {
+		return state.IteratorDump{}, err
+	}
+	return stateDb.IteratorDump(startKey, maxAccounts, dumpFilter)
+}
+
+//返回给定区块的状态
+func (api *PublicDebugAPI) stateAt(blockNr rpc.BlockNumber) (*state.StateDB, error) {
 	if blockNr == rpc.PendingBlockNumber {
 		// If we're dumping the pending state, we need to request
 		// both the pending block as well as the pending state from
 		// the miner and operate on those
 		_, stateDb := api.eth.miner.Pending()
-		return stateDb.RawDump(), nil
+		return stateDb, nil
 	}
 	var block *types.Block
 	if blockNr == rpc.LatestBlockNumber {
@@ -294,19 +395,16 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
 	}
 	if block == nil {
-		return state.Dump{}, fmt.Errorf("block #%d not found", blockNr)
-	}
-	stateDb, err := api.eth.BlockChain().StateAt(block.Root())
-	if err != nil {
-		return state.Dump{}, err
+		return nil, fmt.Errorf("block #%d not found", blockNr)
 	}
-	return stateDb.RawDump(), nil
+	return api.eth.BlockChain().StateAt(block.Root())
 }
 
 //公开的以太坊全节点API，私有调试端点。
 type PrivateDebugAPI struct {
 	config *params.ChainConfig
 	eth    *Ethereum
+	dumps  storageDumps //后台运行的合约存储导出任务
 }
 
 func NewPrivateDebugAPI(config *params.ChainConfig, eth *Ethereum) *PrivateDebugAPI {
@@ -461,8 +559,14 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 			}
 		}
 
-		var err error
-		if tracer, err = ethapi.NewJavascriptTracer(*config.Tracer); err != nil {
+		//优先使用启动时按名称注册的自定义追踪器，否则作为Javascript代码执行
+		named, ok, err := ethapi.NewNamedTracer(*config.Tracer)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			tracer = named
+		} else if tracer, err = ethapi.NewJavascriptTracer(*config.Tracer); err != nil {
 			return nil, err
 		}
 
@@ -470,7 +574,9 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*ethapi.JavascriptTracer).Stop(&timeoutError{})
+			if stopper, ok := tracer.(interface{ Stop(error) }); ok {
+				stopper.Stop(&timeoutError{})
+			}
 		}()
 		defer cancel()
 	} else if config == nil {
@@ -489,6 +595,12 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 		return nil, err
 	}
 
+	//记录存储变化时保存交易执行前的状态用于比较
+	var prestate *state.StateDB
+	if config != nil && config.Tracer == nil && config.LogConfig != nil && config.EnableStorageDiff {
+		prestate = statedb.Copy()
+	}
+
 	// Run the transaction with tracing enabled.
 	log.Info("****TraceTransaction****")
 	vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
@@ -498,19 +610,30 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 	}
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
-		return &ethapi.ExecutionResult{
+		result := &ethapi.ExecutionResult{
 			Gas:         gas,
 			Failed:      failed,
 			ReturnValue: fmt.Sprintf("%x", ret),
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
-		}, nil
-	case *ethapi.JavascriptTracer:
+		}
+		if prestate != nil {
+			result.StorageWrites = tracer.StorageWrites()
+			result.AccountDiffs = tracer.AccountDiffs(prestate)
+		}
+		return result, nil
+	case ethapi.ResultTracer:
 		return tracer.GetResult()
 	default:
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
 	}
 }
 
+// ListTracers returns the names of the custom tracers registered at startup,
+// which can be passed as the tracer option of debug_traceTransaction.
+func (api *PrivateDebugAPI) ListTracers() []string {
+	return ethapi.TracerNames()
+}
+
 // computeTxEnv returns the execution environment of a certain transaction.
 func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (core.Message, vm.Context, *state.StateDB, error) {
 
@@ -564,6 +687,22 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]core.BadBlockAr
 	return api.eth.BlockChain().BadBlocks()
 }
 
+// GetBadBlockRlp returns the RLP encoding of a recorded bad block.
+func (api *PrivateDebugAPI) GetBadBlockRlp(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
+	bad := api.eth.BlockChain().GetBadBlock(hash)
+	if bad == nil {
+		return nil, fmt.Errorf("bad block %x not found", hash)
+	}
+	return rlp.EncodeToBytes(bad.Block)
+}
+
+const maxStorageRangeResult = 1024 //单次查询合约存储返回的最大条数
+
+var (
+	errInvalidMaxResult = errors.New("invalid maxResult")             //返回条数不能为负数
+	errInvalidKeyStart  = errors.New("keyStart longer than 32 bytes") //起始位置超过哈希长度
+)
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
@@ -578,7 +717,18 @@ type storageEntry struct {
 }
 
 // StorageRangeAt returns the storage at the given block height and transaction index.
+// At most maxStorageRangeResult entries are returned per call, the NextKey of the
+// result can be used to continue.
 func (api *PrivateDebugAPI) StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex int, contractAddress common.Address, keyStart hexutil.Bytes, maxResult int) (StorageRangeResult, error) {
+	if maxResult < 0 {
+		return StorageRangeResult{}, errInvalidMaxResult
+	}
+	if maxResult > maxStorageRangeResult {
+		maxResult = maxStorageRangeResult
+	}
+	if len(keyStart) > common.HashLength {
+		return StorageRangeResult{}, errInvalidKeyStart
+	}
 	_, _, statedb, err := api.computeTxEnv(blockHash, txIndex)
 	if err != nil {
 		return StorageRangeResult{}, err
@@ -669,11 +819,14 @@ func (api *PrivateDebugAPI) getModifiedAccounts(startBlock, endBlock *types.Bloc
 		return nil, fmt.Errorf("start block height (%d) must be less than end block height (%d)", startBlock.Number().Uint64(), endBlock.Number().Uint64())
 	}
 
-	oldTrie, err := trie.NewSecure(startBlock.Root(), api.eth.chainDb, 0)
+	// Open the tries through the shared node cache, recent states may not have
+	// been flushed to disk yet
+	triedb := api.eth.blockchain.TrieDB()
+	oldTrie, err := trie.NewSecure(startBlock.Root(), triedb, 0)
 	if err != nil {
 		return nil, err
 	}
-	newTrie, err := trie.NewSecure(endBlock.Root(), api.eth.chainDb, 0)
+	newTrie, err := trie.NewSecure(endBlock.Root(), triedb, 0)
 	if err != nil {
 		return nil, err
 	}
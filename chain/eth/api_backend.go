@@ -33,6 +33,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/eth/gasprice"
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/event"
+	"github.com/Bokerchain/Boker/chain/internal/ethapi"
 	_ "github.com/Bokerchain/Boker/chain/log"
 	"github.com/Bokerchain/Boker/chain/params"
 	"github.com/Bokerchain/Boker/chain/rpc"
@@ -86,6 +87,11 @@ func (b *EthApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 func (b *EthApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
 	// Pending state is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
+		//存在分叉时pending状态为分叉的状态
+		if fork := b.eth.Fork(); fork != nil {
+			state, header := fork.stateAndHeader()
+			return state, header, nil
+		}
 		block, state := b.eth.miner.Pending()
 		return state, block.Header(), nil
 	}
@@ -110,6 +116,14 @@ func (b *EthApiBackend) GetTd(blockHash common.Hash) *big.Int {
 	return b.eth.blockchain.GetTdByHash(blockHash)
 }
 
+//获取区块的Dpos上下文
+func (b *EthApiBackend) DposContext(ctx context.Context, header *types.Header) (*types.DposContext, error) {
+	if current := b.eth.blockchain.CurrentBlock(); current.Hash() == header.Hash() && current.DposCtx() != nil {
+		return current.DposCtx(), nil
+	}
+	return types.NewDposContextFromProto(b.eth.chainDb, header.DposProto)
+}
+
 func (b *EthApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
 	state.SetBalance(msg.From(), math.MaxBig256)
 	vmError := func() error { return nil }
@@ -138,6 +152,13 @@ func (b *EthApiBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 func (b *EthApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
 
 	//log.Info("(b *EthApiBackend) SendTx", "Nonce", signedTx.Nonce())
+	if b.eth.Draining() {
+		return errDraining
+	}
+	//存在分叉时拒绝提交交易，避免交易既没有进入交易池也没有广播，分叉上的交易只能通过debug_forkSendRawTransaction执行
+	if b.eth.Fork() != nil {
+		return errForkActive
+	}
 	return b.eth.txPool.AddLocal(signedTx)
 }
 
@@ -158,6 +179,9 @@ func (b *EthApiBackend) GetPoolTransaction(hash common.Hash) *types.Transaction
 }
 
 func (b *EthApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	if fork := b.eth.Fork(); fork != nil {
+		return fork.nonce(addr), nil
+	}
 	return b.eth.txPool.State().GetNonce(addr), nil
 }
 
@@ -185,6 +209,10 @@ func (b *EthApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *EthApiBackend) GasPriceHistory(ctx context.Context, start rpc.BlockNumber, count uint64, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.PriceHistory(ctx, start, count, percentiles)
+}
+
 func (b *EthApiBackend) ChainDb() ethdb.Database {
 	return b.eth.ChainDb()
 }
@@ -218,6 +246,10 @@ func (b *EthApiBackend) SetCoinbase(coinbase common.Address) {
 }
 
 func (b *EthApiBackend) Boker() bokerapi.Api {
+	//存在分叉时基础合约注册表的修改只作用于分叉
+	if fork := b.eth.Fork(); fork != nil {
+		return fork.boker
+	}
 	return b.eth.boker
 }
 
@@ -235,3 +267,15 @@ func (b *EthApiBackend) DecodeParams(code []byte) ([]byte, error) {
 
 	return b.eth.DecodeParams(code)
 }
+
+func (b *EthApiBackend) CallCache() *ethapi.CallCache {
+	return b.eth.callCache
+}
+
+func (b *EthApiBackend) RPCGasCap() uint64 {
+	return b.eth.config.RPCGasCap
+}
+
+func (b *EthApiBackend) RPCTxFeeCap() float64 {
+	return b.eth.config.RPCTxFeeCap
+}
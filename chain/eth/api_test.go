@@ -17,13 +17,25 @@
 package eth
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/consensus/ethash"
+	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/core/vm"
 	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/params"
+	"github.com/Bokerchain/Boker/chain/trie"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -86,3 +98,86 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+func TestStorageDump(t *testing.T) {
+	var (
+		db, _    = ethdb.NewMemDatabase()
+		state, _ = state.New(common.Hash{}, state.NewDatabase(db))
+		addr     = common.Address{0x01}
+	)
+	for i := 1; i <= maxStorageRangeResult+10; i++ {
+		state.SetState(addr, common.BigToHash(big.NewInt(int64(i))), common.Hash{0x01})
+	}
+	file, err := ioutil.TempFile("", "storagedump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	job := &storageDumpJob{cancel: make(chan struct{})}
+	if err := job.run(state.StorageTrie(addr), file); err != nil {
+		t.Fatalf("dump failed: %v", err)
+	}
+	file.Close()
+
+	if status := job.progress(); status.Entries != maxStorageRangeResult+10 {
+		t.Errorf("entry count mismatch: have %d, want %d", status.Entries, maxStorageRangeResult+10)
+	}
+	blob, _ := ioutil.ReadFile(file.Name())
+	lines := strings.Split(strings.TrimSpace(string(blob)), "\n")
+	if len(lines) != maxStorageRangeResult+10 {
+		t.Fatalf("line count mismatch: have %d, want %d", len(lines), maxStorageRangeResult+10)
+	}
+	var entry storageDumpEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("invalid entry: %v", err)
+	}
+	if entry.Key == nil || entry.Value != (common.Hash{0x01}) {
+		t.Errorf("unexpected entry: %s", lines[0])
+	}
+}
+
+// Tests that modified accounts can be listed for blocks whose state is still
+// held in the shared trie node cache and was not flushed to disk yet.
+func TestModifiedAccountsUnflushed(t *testing.T) {
+	var (
+		db, _     = ethdb.NewMemDatabase()
+		gendb, _  = ethdb.NewMemDatabase()
+		gspec     = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000)}}}
+		genesis   = gspec.MustCommit(db)
+		recipient = common.Address{0x01}
+	)
+	gspec.MustCommit(gendb)
+
+	// Generate the blocks in a separate database so that the imported state
+	// only lives in the node cache of the chain
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, gendb, 1, nil, func(i int, gen *core.BlockGen) {
+		gen.OffsetTime(0)
+		tx := types.NewTransaction(protocol.Binary, 0, recipient, big.NewInt(1), big.NewInt(100000), big.NewInt(0), nil)
+		tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testBankKey)
+		gen.AddTx(tx, nil)
+	})
+	blockchain, _ := core.NewBlockChain(db, gspec.Config, ethash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := trie.NewSecure(blocks[0].Root(), db, 0); err == nil {
+		t.Fatalf("state of block %d already flushed to disk", blocks[0].NumberU64())
+	}
+
+	api := NewPrivateDebugAPI(gspec.Config, &Ethereum{blockchain: blockchain, chainDb: db})
+	accounts, err := api.GetModifiedAccountsByHash(blocks[0].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to get modified accounts: %v", err)
+	}
+	modified := make(map[common.Address]bool)
+	for _, account := range accounts {
+		modified[account] = true
+	}
+	for _, want := range []common.Address{testBank, recipient} {
+		if !modified[want] {
+			t.Errorf("account %x missing from modified accounts %x", want, accounts)
+		}
+	}
+}
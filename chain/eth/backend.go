@@ -7,8 +7,11 @@ import (
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/accounts/external"
+	"github.com/Bokerchain/Boker/chain/accounts/keystore"
 	"github.com/Bokerchain/Boker/chain/boker/api"
 	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
@@ -34,10 +37,19 @@ import (
 	"github.com/Bokerchain/Boker/chain/rpc"
 )
 
+var (
+	errDraining     = errors.New("node is draining, transactions are not accepted")
+	errDrainTimeout = errors.New("block production did not finish before the drain timeout")
+)
+
+//停止时等待正在进行的出块完成的最长时间
+const stopMintingTimeout = time.Duration(protocol.ProducerInterval) * time.Second
+
 type LesServer interface {
 	Start(srvr *p2p.Server)
 	Stop()
 	Protocols() []p2p.Protocol
+	APIs() []rpc.API
 	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
 }
 
@@ -67,6 +79,9 @@ type Ethereum struct {
 	netRPCService   *ethapi.PublicNetAPI           //网络Api接口
 	lock            sync.RWMutex                   // Protects the variadic fields (e.g. gas price and coinbase)
 	boker           bokerapi.Api                   //播客链新增加的接口
+	callCache       *ethapi.CallCache              //eth_call结果缓存
+	draining        int32                          //节点正在准备停止，atomic访问
+	fork            *chainFork                     //debug_forkAt创建的内存分叉，由lock保护
 }
 
 func (s *Ethereum) AddLesServer(ls LesServer) {
@@ -89,6 +104,13 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	}
 	stopDbUpgrade := upgradeDeduplicateData(chainDb)
 
+	//冻结库保存在链数据库目录中，内存数据库不使用冻结库
+	if dir := ctx.ResolvePath("chaindata"); dir != "" {
+		if chainDb, err = core.NewFreezerDatabase(chainDb, core.FreezerDir(dir)); err != nil {
+			return nil, err
+		}
+	}
+
 	//得到配置信息
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
@@ -119,7 +141,12 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		core.WriteBlockChainVersion(chainDb, core.BlockChainVersion)
 	}
 
-	vmConfig := vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
+	if config.TracersDir != "" {
+		if err := ethapi.LoadTracers(config.TracersDir); err != nil {
+			return nil, err
+		}
+	}
+	vmConfig := vm.Config{EnablePreimageRecording: config.EnablePreimageRecording, EnableFusion: config.EnableVMFusion}
 	eth.blockchain, err = core.NewBlockChain(chainDb, eth.chainConfig, eth.engine, vmConfig)
 	if err != nil {
 		return nil, err
@@ -131,7 +158,25 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		eth.blockchain.SetHead(compat.RewindTo)
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
+	eth.blockchain.SetParallelWorkers(config.ParallelTxWorkers)
+	eth.blockchain.SetFreezerThreshold(config.FreezerThreshold)
+	if config.TrieCache > 0 {
+		eth.blockchain.SetTrieCache(config.TrieCache*1024*1024*3/4, config.TrieCache*1024*1024/4)
+	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	eth.callCache = ethapi.NewCallCache(config.CallCache, eth.blockchain)
+	if config.AddressIndex {
+		eth.blockchain.StartAddressIndex()
+	}
+	if config.BalanceIndex {
+		eth.blockchain.StartBalanceIndex()
+	}
+	if config.TokenIndex {
+		eth.blockchain.StartTokenIndex()
+	}
+	if config.InternalTxIndex {
+		eth.blockchain.StartInternalTxIndex()
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
@@ -152,9 +197,17 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		return nil, err
 	}
 
+	eth.protocolManager.downloader.SetCheckpoint(config.TrustedCheckpoint(genesisHash))
+
 	//新建矿工
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine)
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
+	if err := eth.miner.SetSlotMargin(config.MinerSlotMargin); err != nil {
+		return nil, err
+	}
+	if dpos, ok := eth.engine.(*dpos.Dpos); ok {
+		dpos.SetClockMonitor(config.DposNTPServers, config.DposClockDrift, config.DposClockStrict)
+	}
 
 	//新建后台
 	eth.ApiBackend = &EthApiBackend{eth, nil}
@@ -209,6 +262,9 @@ func (s *Ethereum) APIs() []rpc.API {
 	//添加共识引擎支持的Api接口到apis中
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	//过滤器接口同时提供给管理接口，用于查看和关闭客户端的过滤器和订阅
+	filterAPI := filters.NewPublicFilterAPI(s.ApiBackend, false, s.boker, s.config.FilterLimits, s.config.FilterQuotas)
+
 	//添加所有本地Api结构到apis中
 	apis = append(apis, []rpc.API{
 		{
@@ -234,12 +290,16 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, false, s.boker),
+			Service:   filterAPI,
 			Public:    true,
 		}, {
 			Namespace: "admin",
 			Version:   "1.0",
 			Service:   NewPrivateAdminAPI(s),
+		}, {
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   filters.NewPrivateFilterAPI(filterAPI),
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -257,6 +317,10 @@ func (s *Ethereum) APIs() []rpc.API {
 		},
 	}...)
 
+	//如果同时作为轻节点服务器，添加轻节点服务器的管理接口
+	if s.lesServer != nil {
+		apis = append(apis, s.lesServer.APIs()...)
+	}
 	return apis
 }
 
@@ -350,7 +414,18 @@ func (s *Ethereum) StartMining(local bool) error {
 			log.Error("Coinbase account unavailable locally", "err", err)
 			return fmt.Errorf("signer missing: %v", err)
 		}
-		dpos.Authorize(coinbase, wallet.SignHash)
+		//密钥库和外部签名器中的账号使用区块签名专用的函数，从而支持只允许签名区块的解锁方式以及签名器的出块策略
+		signFn := wallet.SignHash
+		if signer, ok := wallet.(*external.ExternalSigner); ok {
+			signFn = signer.SignSealHash
+		}
+		for _, backend := range s.accountManager.Backends(keystore.KeyStoreType) {
+			if ks, ok := backend.(*keystore.KeyStore); ok && ks.HasAddress(coinbase) {
+				signFn = ks.SignSealHash
+				break
+			}
+		}
+		dpos.Authorize(coinbase, signFn)
 	}
 
 	if local {
@@ -442,25 +517,69 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+
+	//启动时钟偏差检查和出块看门狗
+	if dpos, ok := s.engine.(*dpos.Dpos); ok {
+		dpos.StartClockMonitor()
+		if s.config.DposWatchdog {
+			dpos.StartWatchdog(s.blockchain)
+		}
+	}
 	return nil
 }
 
+//停止接收RPC和网络交易，等待正在进行的出块完成并将trie节点缓存写入磁盘，之后节点可以安全停止
+func (s *Ethereum) Drain(timeout time.Duration) error {
+
+	atomic.StoreInt32(&s.draining, 1)
+	atomic.StoreUint32(&s.protocolManager.draining, 1)
+	log.Info("Draining ethereum service", "timeout", common.PrettyDuration(timeout))
+
+	var err error
+	if !s.miner.Drain(timeout) {
+		log.Warn("Block production still in progress after drain timeout")
+		err = errDrainTimeout
+	}
+	if cerr := s.blockchain.CommitTrieCache(); cerr != nil {
+		log.Error("Failed to flush trie cache", "err", cerr)
+		if err == nil {
+			err = cerr
+		}
+	}
+	log.Info("Ethereum service drained")
+	return err
+}
+
+//节点是否正在准备停止
+func (s *Ethereum) Draining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
 func (s *Ethereum) Stop() error {
 
 	if s.stopDbUpgrade != nil {
 		s.stopDbUpgrade()
 	}
 
-	s.bloomIndexer.Close()
-	s.blockchain.Stop()
-	s.protocolManager.Stop()
+	//先停止出块并等待正在写入的区块，避免区块在链停止后写入
+	if !s.miner.Drain(stopMintingTimeout) {
+		log.Warn("Block production still in progress, stopping anyway")
+	}
 
+	//停止网络协议，不再从其他节点导入区块
+	s.protocolManager.Stop()
 	if s.lesServer != nil {
 		s.lesServer.Stop()
 	}
 
+	if dpos, ok := s.engine.(*dpos.Dpos); ok {
+		dpos.StopWatchdog()
+		dpos.StopClockMonitor()
+	}
+	s.bloomIndexer.Close()
+	s.callCache.Stop()
+	s.blockchain.Stop()
 	s.txPool.Stop()
-	s.miner.Stop()
 	s.eventMux.Stop()
 	s.chainDb.Close()
 	close(s.shutdownChan)
@@ -18,9 +18,11 @@ package eth
 
 import (
 	"context"
+	"errors"
 	"math/big"
 
 	"github.com/Bokerchain/Boker/chain"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/core/types"
@@ -37,9 +39,10 @@ import (
 // object. These should be rewritten to internal Go method calls when the Go API
 // is refactored to support a clean library use.
 type ContractBackend struct {
-	eapi  *ethapi.PublicEthereumAPI        // Wrapper around the Ethereum object to access metadata
-	bcapi *ethapi.PublicBlockChainAPI      // Wrapper around the blockchain to access chain data
-	txapi *ethapi.PublicTransactionPoolAPI // Wrapper around the transaction pool to access transaction data
+	eapi    *ethapi.PublicEthereumAPI        // Wrapper around the Ethereum object to access metadata
+	bcapi   *ethapi.PublicBlockChainAPI      // Wrapper around the blockchain to access chain data
+	txapi   *ethapi.PublicTransactionPoolAPI // Wrapper around the transaction pool to access transaction data
+	backend ethapi.Backend                   // Backend used for the Boker base contract lookups
 }
 
 // NewContractBackend creates a new native contract backend using an existing
@@ -47,9 +50,10 @@ type ContractBackend struct {
 //创建一个合约后台用于现有的以太坊对象
 func NewContractBackend(apiBackend ethapi.Backend) *ContractBackend {
 	return &ContractBackend{
-		eapi:  ethapi.NewPublicEthereumAPI(apiBackend),
-		bcapi: ethapi.NewPublicBlockChainAPI(apiBackend),
-		txapi: ethapi.NewPublicTransactionPoolAPI(apiBackend, new(ethapi.AddrLocker)),
+		eapi:    ethapi.NewPublicEthereumAPI(apiBackend),
+		bcapi:   ethapi.NewPublicBlockChainAPI(apiBackend),
+		txapi:   ethapi.NewPublicTransactionPoolAPI(apiBackend, new(ethapi.AddrLocker)),
+		backend: apiBackend,
 	}
 }
 
@@ -79,6 +83,28 @@ func (b *ContractBackend) PendingCallContract(ctx context.Context, msg ethereum.
 	return out, err
 }
 
+// CallContractBatch implements bind.BatchContractCaller executing several
+// contract calls against the same state of the given block.
+func (b *ContractBackend) CallContractBatch(ctx context.Context, msgs []ethereum.CallMsg, blockNum *big.Int) ([][]byte, []error, error) {
+	args := make([]ethapi.CallArgs, len(msgs))
+	for i, msg := range msgs {
+		args[i] = toCallArgs(msg)
+	}
+	results, err := b.bcapi.CallBatch(ctx, args, toBlockNumber(blockNum))
+	if err != nil {
+		return nil, nil, err
+	}
+	outputs, errs := make([][]byte, len(results)), make([]error, len(results))
+	for i, result := range results {
+		if result.Error != "" {
+			errs[i] = errors.New(result.Error)
+			continue
+		}
+		outputs[i] = result.Output
+	}
+	return outputs, errs, nil
+}
+
 func toCallArgs(msg ethereum.CallMsg) ethapi.CallArgs {
 	args := ethapi.CallArgs{
 		To:   msg.To,
@@ -138,3 +164,62 @@ func (b *ContractBackend) SendTransaction(ctx context.Context, tx *types.Transac
 	_, err := b.txapi.SendRawTransaction(ctx, raw)
 	return err
 }
+
+// ChainID implements bind.ChainIDReader, returning the chain ID transactions are
+// replay protected with, or nil if the chain is configured for legacy signing.
+func (b *ContractBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	config := b.backend.ChainConfig()
+	if !config.IsReplayProtected(b.backend.CurrentBlock().Number()) {
+		return nil, nil
+	}
+	return new(big.Int).Set(config.ChainId), nil
+}
+
+// GetContractType implements bind.BokerBackend, returning the base contract type
+// registered for the given address.
+func (b *ContractBackend) GetContractType(address common.Address) (protocol.ContractType, error) {
+	boker := b.backend.Boker()
+	if boker == nil {
+		return protocol.BinaryContract, nil
+	}
+	return boker.GetContract(address)
+}
+
+// CurrentTokenNoder implements bind.BokerBackend, returning the account scheduled
+// to assign tokens at the given time according to the current dpos context.
+func (b *ContractBackend) CurrentTokenNoder(now int64) (common.Address, error) {
+	dposContext, firstTimer, err := b.dposContext()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return dposContext.GetNowTokenNoder(firstTimer, now)
+}
+
+// CurrentProducer implements bind.BokerBackend, returning the account scheduled
+// to produce blocks at the given time according to the current dpos context.
+func (b *ContractBackend) CurrentProducer(now int64) (common.Address, error) {
+	dposContext, firstTimer, err := b.dposContext()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return dposContext.GetNowProducer(firstTimer, now)
+}
+
+// dposContext returns the dpos context of the current head block together with
+// the timestamp of the genesis block the producer schedule is offset from.
+func (b *ContractBackend) dposContext() (*types.DposContext, int64, error) {
+	ctx := context.Background()
+	current, err := b.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil || current == nil {
+		return nil, 0, errors.New("failed to lookup dpos context")
+	}
+	dposContext, err := b.backend.DposContext(ctx, current)
+	if err != nil || dposContext == nil {
+		return nil, 0, errors.New("failed to lookup dpos context")
+	}
+	genesis, err := b.backend.HeaderByNumber(ctx, 0)
+	if err != nil || genesis == nil {
+		return nil, 0, errors.New("failed to lookup genesis block")
+	}
+	return dposContext, genesis.Time.Int64(), nil
+}
@@ -0,0 +1,296 @@
+package eth
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/consensus/dpos"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/rlp"
+)
+
+const importBatchSize = 2500 //每批导入的区块数量
+
+//导入任务状态
+const (
+	importRunning   = "running"
+	importDone      = "done"
+	importFailed    = "failed"
+	importCancelled = "cancelled"
+)
+
+var (
+	errImportRunning  = errors.New("another chain import is running") //已经有导入任务在运行
+	errUnknownImport  = errors.New("unknown import job")              //导入任务不存在
+	errImportCanceled = errors.New("import cancelled")                //导入被取消
+)
+
+//导入任务的进度
+type ImportStatus struct {
+	ID       uint64     `json:"id"`
+	File     string     `json:"file"`
+	State    string     `json:"state"`           //running、done、failed或cancelled
+	Error    string     `json:"error,omitempty"` //任务失败的原因
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Size     int64      `json:"size"`     //文件大小
+	Read     int64      `json:"read"`     //已读取的文件字节数
+	Blocks   uint64     `json:"blocks"`   //已读取的区块数量
+	Imported uint64     `json:"imported"` //已导入的区块数量
+	Skipped  uint64     `json:"skipped"`  //本地已经存在而跳过的区块数量
+	Head     uint64     `json:"head"`     //最后处理的区块号
+}
+
+//后台运行的区块导入任务
+type importJob struct {
+	lock   sync.Mutex
+	status ImportStatus
+	read   *countingReader
+	cancel chan struct{}
+	once   sync.Once
+}
+
+//统计读取字节数的Reader，用于计算导入进度
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(&c.n, int64(n))
+	return n, err
+}
+
+//导入任务的集合，同一时间只允许运行一个导入任务
+type chainImports struct {
+	lock   sync.Mutex
+	nextID uint64
+	jobs   map[uint64]*importJob
+}
+
+//从本地文件导入区块链，导入在后台运行，返回任务编号用于查询进度和取消
+func (api *PrivateAdminAPI) ImportChain(file string) (uint64, error) {
+
+	api.imports.lock.Lock()
+	defer api.imports.lock.Unlock()
+
+	for _, job := range api.imports.jobs {
+		if job.state() == importRunning {
+			return 0, errImportRunning
+		}
+	}
+	in, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	info, err := in.Stat()
+	if err != nil {
+		in.Close()
+		return 0, err
+	}
+	counter := &countingReader{r: in}
+
+	var reader io.Reader = counter
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			in.Close()
+			return 0, err
+		}
+	}
+	api.imports.nextID++
+	job := &importJob{
+		status: ImportStatus{
+			ID:      api.imports.nextID,
+			File:    file,
+			State:   importRunning,
+			Started: time.Now(),
+			Size:    info.Size(),
+		},
+		read:   counter,
+		cancel: make(chan struct{}),
+	}
+	if api.imports.jobs == nil {
+		api.imports.jobs = make(map[uint64]*importJob)
+	}
+	api.imports.jobs[job.status.ID] = job
+
+	go func() {
+		defer in.Close()
+		err := api.runImport(job, rlp.NewStream(reader, 0))
+		job.finish(err)
+	}()
+	return job.status.ID, nil
+}
+
+//查询导入任务的进度
+func (api *PrivateAdminAPI) ImportStatus(id uint64) (*ImportStatus, error) {
+
+	api.imports.lock.Lock()
+	job := api.imports.jobs[id]
+	api.imports.lock.Unlock()
+
+	if job == nil {
+		return nil, errUnknownImport
+	}
+	return job.progress(), nil
+}
+
+//取消正在运行的导入任务，已经导入的区块会保留，当前批次导入完成后任务停止
+func (api *PrivateAdminAPI) CancelImport(id uint64) (bool, error) {
+
+	api.imports.lock.Lock()
+	job := api.imports.jobs[id]
+	api.imports.lock.Unlock()
+
+	if job == nil {
+		return false, errUnknownImport
+	}
+	if job.state() != importRunning {
+		return false, nil
+	}
+	job.once.Do(func() { close(job.cancel) })
+	return true, nil
+}
+
+//分批读取、验证并导入区块，每批导入前检查是否被取消
+func (api *PrivateAdminAPI) runImport(job *importJob, stream *rlp.Stream) error {
+
+	chain := api.eth.BlockChain()
+	blocks := make([]*types.Block, 0, importBatchSize)
+
+	for batch := 0; ; batch++ {
+		select {
+		case <-job.cancel:
+			return errImportCanceled
+		default:
+		}
+		//读取一批区块
+		for len(blocks) < cap(blocks) {
+			block := new(types.Block)
+			if err := stream.Decode(block); err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("block %d: failed to parse: %v", job.progress().Blocks, err)
+			}
+			blocks = append(blocks, block)
+			job.update(func(s *ImportStatus) { s.Blocks++ })
+		}
+		if len(blocks) == 0 {
+			return nil
+		}
+		//跳过本地已经存在的区块
+		known := 0
+		for known < len(blocks) && chain.HasBlock(blocks[known].Hash(), blocks[known].NumberU64()) {
+			known++
+		}
+		if known > 0 {
+			job.update(func(s *ImportStatus) {
+				s.Skipped += uint64(known)
+				s.Head = blocks[known-1].NumberU64()
+			})
+		}
+		if pending := blocks[known:]; len(pending) > 0 {
+			if err := api.verifyImportBatch(job, pending); err != nil {
+				return fmt.Errorf("batch %d: %v", batch, err)
+			}
+			if _, err := chain.InsertChain(pending); err != nil {
+				return fmt.Errorf("batch %d: failed to insert: %v", batch, err)
+			}
+			job.update(func(s *ImportStatus) {
+				s.Imported += uint64(len(pending))
+				s.Head = pending[len(pending)-1].NumberU64()
+			})
+		}
+		blocks = blocks[:0]
+	}
+}
+
+//在持有链锁导入前验证一批区块：区块连续、区块头合法以及区块由区块头中的验证者签名，验证过程可以被取消
+func (api *PrivateAdminAPI) verifyImportBatch(job *importJob, blocks []*types.Block) error {
+
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		if i > 0 && (block.NumberU64() != blocks[i-1].NumberU64()+1 || block.ParentHash() != blocks[i-1].Hash()) {
+			return fmt.Errorf("non contiguous block #%d [%x…]", block.NumberU64(), block.Hash().Bytes()[:4])
+		}
+		headers[i] = block.Header()
+	}
+	engine, isDpos := api.eth.Engine().(*dpos.Dpos)
+	if isDpos {
+		for _, header := range headers {
+			select {
+			case <-job.cancel:
+				return errImportCanceled
+			default:
+			}
+			if err := engine.VerifySigner(header); err != nil {
+				return fmt.Errorf("block #%d: invalid seal: %v", header.Number, err)
+			}
+		}
+	}
+	abort, results := api.eth.Engine().VerifyHeaders(api.eth.BlockChain(), headers, make([]bool, len(headers)))
+	defer close(abort)
+
+	for _, header := range headers {
+		select {
+		case <-job.cancel:
+			return errImportCanceled
+		case err := <-results:
+			if err != nil {
+				return fmt.Errorf("block #%d: invalid header: %v", header.Number, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (job *importJob) update(fn func(*ImportStatus)) {
+	job.lock.Lock()
+	fn(&job.status)
+	job.lock.Unlock()
+}
+
+func (job *importJob) state() string {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	return job.status.State
+}
+
+//返回任务进度的拷贝
+func (job *importJob) progress() *ImportStatus {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	status := job.status
+	status.Read = atomic.LoadInt64(&job.read.n)
+	return &status
+}
+
+//记录任务的结束状态
+func (job *importJob) finish(err error) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	now := time.Now()
+	job.status.Finished = &now
+	switch {
+	case err == errImportCanceled:
+		job.status.State = importCancelled
+	case err != nil:
+		job.status.State = importFailed
+		job.status.Error = err.Error()
+	default:
+		job.status.State = importDone
+	}
+	log.Info("Chain import finished", "id", job.status.ID, "file", job.status.File, "state", job.status.State,
+		"imported", job.status.Imported, "skipped", job.status.Skipped, "err", err)
+}
@@ -0,0 +1,282 @@
+package eth
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	compactRecentLimit = 64              //缓存的最近转发区块数量，用于响应对端请求缺失的交易
+	compactPendingMax  = 32              //同时等待缺失交易的精简区块数量上限
+	compactTimeout     = 5 * time.Second //等待缺失交易的最长时间，超时后改为请求完整区块
+)
+
+var (
+	compactRebuiltMeter  = metrics.NewMeter("eth/compact/rebuilt")  //完全从本地交易池还原的区块
+	compactFetchedMeter  = metrics.NewMeter("eth/compact/fetched")  //请求缺失交易后还原的区块
+	compactMissingMeter  = metrics.NewMeter("eth/compact/missing")  //本地交易池缺失的交易数量
+	compactFallbackMeter = metrics.NewMeter("eth/compact/fallback") //无法还原改为请求完整区块
+)
+
+//精简区块消息，只包含区块头和交易Hash，接收方从本地交易池还原区块
+type compactBlockData struct {
+	Header   *types.Header
+	TxHashes []common.Hash
+	TD       *big.Int
+}
+
+//请求精简区块中本地交易池缺失的交易
+type getBlockTxsData struct {
+	Hash    common.Hash
+	Indexes []uint64 //缺失交易在区块中的位置
+}
+
+//返回请求的交易，顺序与请求的位置一致
+type blockTxsData struct {
+	Hash common.Hash
+	Txs  []*types.Transaction
+}
+
+//等待缺失交易的精简区块
+type pendingCompact struct {
+	peer     *peer
+	header   *types.Header
+	td       *big.Int
+	hashes   []common.Hash
+	txs      []*types.Transaction //已还原的交易，缺失的位置为nil
+	missing  []uint64
+	created  time.Time
+	deadline time.Time //等待缺失交易超时的时间
+}
+
+//精简区块转发的状态，等待缺失交易超时后由定时器改为请求完整区块
+type compactRelay struct {
+	recent   *lru.Cache //最近转发的区块，这些区块可能还没有导入本地链
+	pending  map[common.Hash]*pendingCompact
+	timeout  time.Duration
+	timer    *time.Timer                         //最早的等待超时时触发，没有等待的区块时为nil
+	fallback func(p *peer, header *types.Header) //超时后向发送方请求完整区块
+	closed   bool
+	lock     sync.Mutex
+}
+
+func newCompactRelay(fallback func(p *peer, header *types.Header)) *compactRelay {
+	recent, _ := lru.New(compactRecentLimit)
+	return &compactRelay{
+		recent:   recent,
+		pending:  make(map[common.Hash]*pendingCompact),
+		timeout:  compactTimeout,
+		fallback: fallback,
+	}
+}
+
+//记录转发的区块，对端缺少交易时从这里返回
+func (c *compactRelay) remember(block *types.Block) {
+	c.recent.Add(block.Hash(), block)
+}
+
+//得到最近转发的区块
+func (c *compactRelay) block(hash common.Hash) *types.Block {
+	if block, ok := c.recent.Get(hash); ok {
+		return block.(*types.Block)
+	}
+	return nil
+}
+
+//保存等待缺失交易的精简区块，已经在等待或者超出数量时返回false
+func (c *compactRelay) wait(hash common.Hash, pending *pendingCompact) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, exist := c.pending[hash]; exist || len(c.pending) >= compactPendingMax || c.closed {
+		return false
+	}
+	pending.deadline = time.Now().Add(c.timeout)
+	c.pending[hash] = pending
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.timeout, c.expire)
+	}
+	return true
+}
+
+//定时器触发时丢弃等待超时的精简区块并改为请求完整区块，之后重新设置定时器
+func (c *compactRelay) expire() {
+	c.lock.Lock()
+
+	var (
+		now     = time.Now()
+		next    time.Time
+		expired []*pendingCompact
+	)
+	for hash, pending := range c.pending {
+		if now.Before(pending.deadline) {
+			if next.IsZero() || pending.deadline.Before(next) {
+				next = pending.deadline
+			}
+			continue
+		}
+		delete(c.pending, hash)
+		expired = append(expired, pending)
+	}
+	c.timer = nil
+	if !next.IsZero() && !c.closed {
+		c.timer = time.AfterFunc(next.Sub(now), c.expire)
+	}
+	c.lock.Unlock()
+
+	for _, pending := range expired {
+		log.Debug("Compact block transactions timed out", "peer", pending.peer.id, "number", pending.header.Number, "hash", pending.header.Hash())
+		c.fallback(pending.peer, pending.header)
+	}
+}
+
+//停止定时器，不再处理超时的精简区块
+func (c *compactRelay) stop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+//取出指定节点发送的等待中的精简区块
+func (c *compactRelay) take(hash common.Hash, p *peer) *pendingCompact {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	pending, ok := c.pending[hash]
+	if !ok || pending.peer != p {
+		return nil
+	}
+	delete(c.pending, hash)
+	return pending
+}
+
+//以精简区块的形式向支持eth/64的节点转发区块，其它节点仍然发送完整区块
+func (pm *ProtocolManager) sendPropagatedBlock(p *peer, block *types.Block, td *big.Int) {
+	if p.version >= eth64 {
+		pm.compact.remember(block)
+		p.SendCompactBlock(block, td)
+		return
+	}
+	p.SendNewBlock(block, td)
+}
+
+//处理收到的精简区块，本地交易池包含所有交易时直接还原区块，否则向发送方请求缺失的交易
+func (pm *ProtocolManager) handleCompactBlock(p *peer, request *compactBlockData, receivedAt time.Time) error {
+
+	header := request.Header
+	hash := header.Hash()
+	p.MarkBlock(hash)
+	if pm.blockchain.HasBlock(hash, header.Number.Uint64()) {
+		return nil
+	}
+
+	txs := make([]*types.Transaction, len(request.TxHashes))
+	var missing []uint64
+	for i, txHash := range request.TxHashes {
+		if tx := pm.txpool.Get(txHash); tx != nil {
+			txs[i] = tx
+		} else {
+			missing = append(missing, uint64(i))
+		}
+	}
+	if len(missing) == 0 {
+		compactRebuiltMeter.Mark(1)
+		pm.deliverCompact(p, header, txs, request.TD, receivedAt)
+		return nil
+	}
+	compactMissingMeter.Mark(int64(len(missing)))
+
+	pending := &pendingCompact{
+		peer:    p,
+		header:  header,
+		td:      request.TD,
+		hashes:  request.TxHashes,
+		txs:     txs,
+		missing: missing,
+		created: receivedAt,
+	}
+	if !pm.compact.wait(hash, pending) {
+		pm.fallbackCompact(p, header)
+		return nil
+	}
+	return p.RequestBlockTxs(hash, missing)
+}
+
+//返回对端请求的精简区块中的交易，区块未知时返回空列表
+func (pm *ProtocolManager) handleGetBlockTxs(p *peer, request *getBlockTxsData) error {
+
+	block := pm.compact.block(request.Hash)
+	if block == nil {
+		block = pm.blockchain.GetBlockByHash(request.Hash)
+	}
+	var txs []*types.Transaction
+	if block != nil {
+		all := block.Transactions()
+		for _, index := range request.Indexes {
+			if index >= uint64(len(all)) {
+				return errResp(ErrDecode, "transaction index %d out of range", index)
+			}
+			txs = append(txs, all[index])
+		}
+	}
+	return p.SendBlockTxs(request.Hash, txs)
+}
+
+//收到缺失的交易后还原精简区块，交易不符时改为请求完整区块
+func (pm *ProtocolManager) handleBlockTxs(p *peer, response *blockTxsData) error {
+
+	pending := pm.compact.take(response.Hash, p)
+	if pending == nil {
+		return nil
+	}
+	if len(response.Txs) != len(pending.missing) {
+		pm.fallbackCompact(p, pending.header)
+		return nil
+	}
+	for i, index := range pending.missing {
+		tx := response.Txs[i]
+		if tx == nil || tx.Hash() != pending.hashes[index] {
+			pm.fallbackCompact(p, pending.header)
+			return nil
+		}
+		p.MarkTransaction(tx.Hash())
+		pending.txs[index] = tx
+	}
+	compactFetchedMeter.Mark(1)
+	pm.deliverCompact(p, pending.header, pending.txs, pending.td, pending.created)
+	return nil
+}
+
+//将还原的区块交给区块获取器导入，交易列表与区块头中的交易根不一致时改为请求完整区块
+func (pm *ProtocolManager) deliverCompact(p *peer, header *types.Header, txs []*types.Transaction, td *big.Int, receivedAt time.Time) {
+
+	if types.DeriveSha(types.Transactions(txs)) != header.TxHash {
+		log.Debug("Compact block transactions mismatch", "peer", p.id, "number", header.Number, "hash", header.Hash())
+		pm.fallbackCompact(p, header)
+		return
+	}
+	block := types.NewBlockWithHeader(header).WithBody(txs, nil)
+	block.ReceivedAt = receivedAt
+	block.ReceivedFrom = p
+
+	pm.fetcher.Enqueue(p.id, block)
+	pm.updatePeerHead(p, block, td)
+}
+
+//无法还原精简区块时按照区块Hash通知的方式从对端获取完整区块
+func (pm *ProtocolManager) fallbackCompact(p *peer, header *types.Header) {
+	compactFallbackMeter.Mark(1)
+	pm.fetcher.Notify(p.id, header.Hash(), header.Number.Uint64(), time.Now(), p.RequestOneHeader, p.RequestBodies)
+}
@@ -0,0 +1,207 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/eth/downloader"
+	"github.com/Bokerchain/Boker/chain/p2p"
+)
+
+//在本地链头之上生成一个包含给定交易的区块，不导入本地链
+func newCompactTestBlock(pm *ProtocolManager, txs ...*types.Transaction) (*types.Block, *big.Int) {
+
+	parent := pm.blockchain.CurrentBlock()
+	blocks, _ := core.GenerateChain(pm.chainconfig, parent, pm.chaindb, 1, nil, func(i int, gen *core.BlockGen) {
+		gen.OffsetTime(0)
+		for _, tx := range txs {
+			gen.AddTx(tx, nil)
+		}
+	})
+	td := new(big.Int).Add(pm.blockchain.GetTd(parent.Hash(), parent.NumberU64()), blocks[0].Difficulty())
+	return blocks[0], td
+}
+
+//交易池中放入给定交易后连接测试节点，并读取节点连接时同步的交易，保证之后的消息顺序确定
+func newCompactTestPeer(t *testing.T, pm *ProtocolManager, pooled ...*types.Transaction) *testPeer {
+
+	pm.txpool.AddRemotes(pooled)
+	p, _ := newTestPeer("peer", eth64, pm, true)
+	if len(pooled) > 0 {
+		if err := p2p.ExpectMsg(p.app, TxMsg, pooled); err != nil {
+			p.close()
+			t.Fatalf("pooled transactions not synced: %v", err)
+		}
+	}
+	return p
+}
+
+//以精简区块的形式把区块发给本地节点
+func sendCompactBlock(t *testing.T, p *testPeer, block *types.Block, td *big.Int) {
+
+	hashes := make([]common.Hash, 0, len(block.Transactions()))
+	for _, tx := range block.Transactions() {
+		hashes = append(hashes, tx.Hash())
+	}
+	if err := p2p.Send(p.app, CompactBlockMsg, &compactBlockData{Header: block.Header(), TxHashes: hashes, TD: td}); err != nil {
+		t.Fatalf("failed to send compact block: %v", err)
+	}
+}
+
+//等待区块被区块获取器导入本地链
+func waitBlockImported(t *testing.T, pm *ProtocolManager, block *types.Block) {
+
+	for start := time.Now(); !pm.blockchain.HasBlock(block.Hash(), block.NumberU64()); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("block not imported")
+		}
+	}
+}
+
+//精简区块还原失败后，区块获取器按照Hash通知的方式向对端请求区块头
+func expectHeaderRequest(t *testing.T, p *testPeer, hash common.Hash) {
+
+	type result struct {
+		msg p2p.Msg
+		err error
+	}
+	read := make(chan result, 1)
+	go func() {
+		msg, err := p.app.ReadMsg()
+		read <- result{msg, err}
+	}()
+
+	select {
+	case res := <-read:
+		if res.err != nil {
+			t.Fatalf("failed to read message: %v", res.err)
+		}
+		defer res.msg.Discard()
+		if res.msg.Code != GetBlockHeadersMsg {
+			t.Fatalf("message code mismatch: have %d, want %d", res.msg.Code, GetBlockHeadersMsg)
+		}
+		var request getBlockHeadersData
+		if err := res.msg.Decode(&request); err != nil {
+			t.Fatalf("failed to decode header request: %v", err)
+		}
+		if request.Origin.Hash != hash || request.Amount != 1 {
+			t.Fatalf("header request mismatch: have %x/%d, want %x/1", request.Origin.Hash, request.Amount, hash)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("header not requested")
+	}
+}
+
+//等待缺失交易的精简区块数量
+func pendingCompacts(pm *ProtocolManager) int {
+	pm.compact.lock.Lock()
+	defer pm.compact.lock.Unlock()
+	return len(pm.compact.pending)
+}
+
+//本地交易池包含所有交易时直接还原区块，不向对端请求
+func TestCompactRebuildFromPool(t *testing.T) {
+
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	tx := newTestTransaction(testBankKey, 0, 0)
+	block, td := newCompactTestBlock(pm, tx)
+	p := newCompactTestPeer(t, pm, tx)
+	defer p.close()
+
+	sendCompactBlock(t, p, block, td)
+	waitBlockImported(t, pm, block)
+	if n := pendingCompacts(pm); n != 0 {
+		t.Errorf("compact blocks left waiting: %d", n)
+	}
+}
+
+//本地交易池缺少交易时向对端请求缺失的交易，交易到达后还原区块
+func TestCompactFetchMissingTxs(t *testing.T) {
+
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	known, missing := newTestTransaction(testBankKey, 0, 0), newTestTransaction(testBankKey, 1, 0)
+	block, td := newCompactTestBlock(pm, known, missing)
+	p := newCompactTestPeer(t, pm, known)
+	defer p.close()
+
+	sendCompactBlock(t, p, block, td)
+	if err := p2p.ExpectMsg(p.app, GetBlockTxsMsg, &getBlockTxsData{Hash: block.Hash(), Indexes: []uint64{1}}); err != nil {
+		t.Fatalf("missing transactions not requested: %v", err)
+	}
+	if err := p2p.Send(p.app, BlockTxsMsg, &blockTxsData{Hash: block.Hash(), Txs: []*types.Transaction{missing}}); err != nil {
+		t.Fatalf("failed to deliver transactions: %v", err)
+	}
+	waitBlockImported(t, pm, block)
+	if n := pendingCompacts(pm); n != 0 {
+		t.Errorf("compact blocks left waiting: %d", n)
+	}
+}
+
+//对端返回的交易与请求的不符时改为请求完整区块
+func TestCompactMismatchFallback(t *testing.T) {
+
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	p, _ := newTestPeer("peer", eth64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testBankKey, 0, 0)
+	block, td := newCompactTestBlock(pm, tx)
+
+	sendCompactBlock(t, p, block, td)
+	if err := p2p.ExpectMsg(p.app, GetBlockTxsMsg, &getBlockTxsData{Hash: block.Hash(), Indexes: []uint64{0}}); err != nil {
+		t.Fatalf("missing transactions not requested: %v", err)
+	}
+	other := newTestTransaction(testBankKey, 1, 0)
+	if err := p2p.Send(p.app, BlockTxsMsg, &blockTxsData{Hash: block.Hash(), Txs: []*types.Transaction{other}}); err != nil {
+		t.Fatalf("failed to deliver transactions: %v", err)
+	}
+	expectHeaderRequest(t, p, block.Hash())
+}
+
+//交易Hash列表与区块头中的交易根不一致时改为请求完整区块
+func TestCompactTxRootMismatchFallback(t *testing.T) {
+
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	p, _ := newTestPeer("peer", eth64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testBankKey, 0, 0)
+	block, td := newCompactTestBlock(pm, tx)
+
+	if err := p2p.Send(p.app, CompactBlockMsg, &compactBlockData{Header: block.Header(), TD: td}); err != nil {
+		t.Fatalf("failed to send compact block: %v", err)
+	}
+	expectHeaderRequest(t, p, block.Hash())
+}
+
+//对端不返回缺失的交易时，超时后由定时器改为请求完整区块
+func TestCompactTimeoutFallback(t *testing.T) {
+
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.compact.timeout = 50 * time.Millisecond
+	p, _ := newTestPeer("peer", eth64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testBankKey, 0, 0)
+	block, td := newCompactTestBlock(pm, tx)
+
+	sendCompactBlock(t, p, block, td)
+	if err := p2p.ExpectMsg(p.app, GetBlockTxsMsg, &getBlockTxsData{Hash: block.Hash(), Indexes: []uint64{0}}); err != nil {
+		t.Fatalf("missing transactions not requested: %v", err)
+	}
+	expectHeaderRequest(t, p, block.Hash())
+	if n := pendingCompacts(pm); n != 0 {
+		t.Errorf("compact blocks left waiting: %d", n)
+	}
+}
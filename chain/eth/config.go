@@ -20,27 +20,36 @@ import (
 	"math/big"
 	"os"
 	"os/user"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/eth/downloader"
+	"github.com/Bokerchain/Boker/chain/eth/filters"
 	"github.com/Bokerchain/Boker/chain/eth/gasprice"
+	"github.com/Bokerchain/Boker/chain/internal/ethapi"
 	"github.com/Bokerchain/Boker/chain/params"
 )
 
 // DefaultConfig contains default settings for use on the Ethereum main net.
 var DefaultConfig = Config{
-	SyncMode:      downloader.FullSync,
-	NetworkId:     1357,
-	LightPeers:    20,
-	DatabaseCache: 128,
-	GasPrice:      big.NewInt(18 * params.Shannon),
-	TxPool:        core.DefaultTxPoolConfig,
+	SyncMode:         downloader.FullSync,
+	NetworkId:        1357,
+	LightPeers:       20,
+	DatabaseCache:    128,
+	TrieCache:        256,
+	FreezerThreshold: 90000,
+	GasPrice:         big.NewInt(18 * params.Shannon),
+	TxPool:           core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     10,
 		Percentile: 50,
+		MaxHistory: 1024,
 	},
+	FilterLimits: filters.DefaultLimits,
+	FilterQuotas: filters.DefaultQuotas,
+	CallCache:    ethapi.DefaultCallCacheConfig,
 }
 
 func init() {
@@ -58,23 +67,59 @@ type Config struct {
 	Genesis                 *core.Genesis       `toml:",omitempty"` //genesis块，如果数据库为空则插入。如果为nil，则使用以太坊主网块。
 	NetworkId               uint64              //用于选择要连接的其它节点的网络ID
 	SyncMode                downloader.SyncMode //是否同步模式
+	AddressIndex            bool                `toml:",omitempty"` //是否维护地址到交易的索引
+	BalanceIndex            bool                `toml:",omitempty"` //是否维护账户余额变化的历史索引
+	TokenIndex              bool                `toml:",omitempty"` //是否维护ERC-20转账事件的索引
+	InternalTxIndex         bool                `toml:",omitempty"` //是否在执行区块时记录合约发起的转账
 	LightServ               int                 `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers              int                 `toml:",omitempty"` // Maximum number of LES client peers
+	LightCapacity           uint64              `toml:",omitempty"` // Total request capacity shared by LES clients (0 = unlimited)
 	SkipBcVersionCheck      bool                `toml:"-"`
 	DatabaseHandles         int                 `toml:"-"`
 	DatabaseCache           int
-	Coinbase                common.Address    `toml:",omitempty"` //矿工账号
-	MinerThreads            int               `toml:",omitempty"` //挖矿线程数量
-	ExtraData               []byte            `toml:",omitempty"` //扩展字段
-	GasPrice                *big.Int          //交易价格
-	TxPool                  core.TxPoolConfig //交易池配置
-	GPO                     gasprice.Config   //Gas配置
-	EnablePreimageRecording bool              //是否允许跟踪VM中的SHA3 preimages
-	DocRoot                 string            `toml:"-"`
-	PowFake                 bool              `toml:"-"`
-	PowTest                 bool              `toml:"-"`
-	PowShared               bool              `toml:"-"`
-	Dpos                    bool              `toml:"-"`
+	TrieCache               int                    //trie节点缓存的大小(MB)，3/4用于clean缓存，1/4用于dirty缓存
+	FreezerThreshold        uint64                 //深度超过该值的区块移到冻结库，为0时不冻结
+	Coinbase                common.Address         `toml:",omitempty"` //矿工账号
+	MinerThreads            int                    `toml:",omitempty"` //挖矿线程数量
+	ExtraData               []byte                 `toml:",omitempty"` //扩展字段
+	MinerSlotMargin         time.Duration          `toml:",omitempty"` //提前开始组装区块的时间
+	DposWatchdog            bool                   `toml:",omitempty"` //本节点错过出块时间片时记录错误日志并增加度量计数
+	DposClockDrift          time.Duration          `toml:",omitempty"` //允许的本地时钟偏差，为0时不进行NTP检查
+	DposClockStrict         bool                   `toml:",omitempty"` //时钟偏差超过阈值时拒绝封装区块
+	DposNTPServers          []string               `toml:",omitempty"` //检查时钟偏差使用的NTP服务器
+	GasPrice                *big.Int               //交易价格
+	TxPool                  core.TxPoolConfig      //交易池配置
+	GPO                     gasprice.Config        //Gas配置
+	FilterLimits            filters.Limits         //单次日志查询的结果数量和耗时限制
+	FilterQuotas            filters.Quotas         //每个连接的过滤器和订阅数量限制
+	CallCache               ethapi.CallCacheConfig //eth_call结果缓存的数量和时间
+	RPCGasCap               uint64                 `toml:",omitempty"` //eth_call和eth_estimateGas可以使用的Gas上限，为0时不限制
+	RPCTxFeeCap             float64                `toml:",omitempty"` //通过RPC发送和签名的交易手续费上限(单位ether)，为0时不限制
+	EnablePreimageRecording bool                   //是否允许跟踪VM中的SHA3 preimages
+	EnableVMFusion          bool                   //是否将常见的指令对合并为一条指令执行
+	ParallelTxWorkers       int                    `toml:",omitempty"` //并行执行区块中交易的线程数量，小于2时串行执行
+	TracersDir              string                 `toml:",omitempty"` //启动时加载自定义追踪器的目录(Javascript文件或Go插件)
+	DocRoot                 string                 `toml:"-"`
+	PowFake                 bool                   `toml:"-"`
+	PowTest                 bool                   `toml:"-"`
+	PowShared               bool                   `toml:"-"`
+	Dpos                    bool                   `toml:"-"`
+
+	//可信检查点
+	Checkpoint   *params.TrustedCheckpoint `toml:",omitempty"` //替换内置的可信检查点
+	NoCheckpoint bool                      `toml:",omitempty"` //不使用可信检查点，允许从低于检查点的链同步
+}
+
+//得到同步时使用的可信检查点，优先使用配置中指定的检查点，没有指定时使用内置的检查点
+func (c *Config) TrustedCheckpoint(genesis common.Hash) *params.TrustedCheckpoint {
+
+	if c.NoCheckpoint {
+		return nil
+	}
+	if c.Checkpoint != nil {
+		return c.Checkpoint
+	}
+	return params.TrustedCheckpoints[genesis]
 }
 
 type configMarshaling struct {
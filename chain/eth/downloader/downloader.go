@@ -93,6 +93,8 @@ var (
 	errCancelContentProcessing = errors.New("content processing canceled (requested)")
 	errNoSyncActive            = errors.New("no sync active")
 	errTooOld                  = errors.New("peer doesn't speak recent enough protocol version (need version >= 62)")
+	errCheckpointUnreached     = errors.New("remote chain is below the trusted checkpoint")
+	errCheckpointMismatch      = errors.New("remote chain does not contain the trusted checkpoint")
 )
 
 type Downloader struct {
@@ -113,10 +115,12 @@ type Downloader struct {
 	syncStatsChainOrigin uint64 // Origin block number where syncing started at
 	syncStatsChainHeight uint64 // Highest block number known when syncing started
 	syncStatsState       stateSyncStats
-	syncStatsLock        sync.RWMutex // Lock protecting the sync stats fields
+	syncStatsDpos        stateSyncStats // DPoS context trie (epoch, validator and block count) download stats
+	syncStatsLock        sync.RWMutex   // Lock protecting the sync stats fields
 
 	lightchain LightChain
 	blockchain BlockChain
+	checkpoint *params.TrustedCheckpoint // Checkpoint the remote chain must contain while the local one is below it
 
 	// Callbacks
 	dropPeer peerDropFn // Drops a peer for misbehaving
@@ -261,7 +265,24 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 		HighestBlock:  d.syncStatsChainHeight,
 		PulledStates:  d.syncStatsState.processed,
 		KnownStates:   d.syncStatsState.processed + d.syncStatsState.pending,
+
+		PulledDposStates:  d.syncStatsDpos.processed,
+		KnownDposStates:   d.syncStatsDpos.processed + d.syncStatsDpos.pending,
+		IrreversibleBlock: d.irreversibleBlock(),
+	}
+}
+
+// irreversibleBlock returns the number of the latest block the DPoS engine
+// verified as irreversible, or zero if none was recorded yet.
+func (d *Downloader) irreversibleBlock() uint64 {
+	hash, err := d.stateDB.Get(protocol.ConfirmedBlockHead)
+	if err != nil || len(hash) != common.HashLength {
+		return 0
+	}
+	if header := d.lightchain.GetHeaderByHash(common.BytesToHash(hash)); header != nil {
+		return header.Number.Uint64()
 	}
+	return 0
 }
 
 // Synchronising returns whether the downloader is currently retrieving blocks.
@@ -323,7 +344,7 @@ func (d *Downloader) Synchronise(id string, head common.Hash, td *big.Int, mode
 
 	case errTimeout, errBadPeer, errStallingPeer,
 		errEmptyHeaderSet, errPeersUnavailable, errTooOld,
-		errInvalidAncestor, errInvalidChain:
+		errInvalidAncestor, errInvalidChain, errCheckpointMismatch:
 		log.Warn("Synchronisation failed, dropping peer", "peer", id, "err", err)
 		d.dropPeer(id)
 
@@ -426,6 +447,9 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	}
 	height := latest.Number.Uint64()
 
+	if err := d.verifyCheckpoint(p, height); err != nil {
+		return err
+	}
 	origin, err := d.findAncestor(p, height)
 	if err != nil {
 		return err
@@ -594,6 +618,82 @@ func (d *Downloader) fetchHeight(p *peerConnection) (*types.Header, error) {
 	}
 }
 
+// SetCheckpoint sets the trusted checkpoint that remote chains have to contain
+// before a node whose chain is still below it syncs from them. A nil checkpoint
+// disables the check. It must be called before synchronisation starts.
+func (d *Downloader) SetCheckpoint(checkpoint *params.TrustedCheckpoint) {
+	d.checkpoint = checkpoint
+}
+
+// verifyCheckpoint makes sure the remote chain contains the trusted checkpoint
+// if the local chain has not reached it yet, protecting fresh nodes from being
+// fed a long-range fake chain.
+func (d *Downloader) verifyCheckpoint(p *peerConnection, height uint64) error {
+	if d.checkpoint == nil {
+		return nil
+	}
+	number := d.checkpoint.HeadNumber()
+
+	local := d.lightchain.CurrentHeader().Number.Uint64()
+	if d.mode == FullSync {
+		local = d.blockchain.CurrentBlock().NumberU64()
+	} else if d.mode == FastSync {
+		local = d.blockchain.CurrentFastBlock().NumberU64()
+	}
+	if local >= number {
+		return nil
+	}
+	if height < number {
+		p.log.Debug("Remote chain below trusted checkpoint", "height", height, "checkpoint", number)
+		return errCheckpointUnreached
+	}
+	header, err := d.fetchHeader(p, number)
+	if err != nil {
+		return err
+	}
+	if header.Hash() != d.checkpoint.SectionHead {
+		p.log.Warn("Remote chain does not contain trusted checkpoint", "number", number, "have", header.Hash(), "want", d.checkpoint.SectionHead)
+		return errCheckpointMismatch
+	}
+	p.log.Debug("Trusted checkpoint verified", "number", number, "hash", header.Hash())
+	return nil
+}
+
+// fetchHeader retrieves the canonical header with the given number from a peer.
+func (d *Downloader) fetchHeader(p *peerConnection, number uint64) (*types.Header, error) {
+	go p.peer.RequestHeadersByNumber(number, 1, 0, false)
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelBlockFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			headers := packet.(*headerPack).headers
+			if len(headers) != 1 || headers[0].Number.Uint64() != number {
+				p.log.Debug("Invalid header for single request", "headers", len(headers), "number", number)
+				return nil, errBadPeer
+			}
+			return headers[0], nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for header timed out", "number", number, "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
 // findAncestor tries to locate the common ancestor link of the local chain and
 // a remote peers blockchain. In the general case when our node was in sync and
 // on the correct chain, checking the top N links should already get us a match.
@@ -1463,10 +1563,12 @@ func (d *Downloader) commitPivotBlock(result *fetchResult) error {
 	if err := d.syncState(b.Root()).Wait(); err != nil {
 		return err
 	}
-	if err := d.syncDposContextState(b.Header().DposProto); err != nil {
-		return err
+	// The DPoS context tries are needed to validate and produce the blocks
+	// following the pivot, fetch them too.
+	if b.Header().DposProto == nil {
+		return errInvalidChain
 	}
-	if err := d.syncDposContextState(b.Header().DposProto); err != nil {
+	if err := d.syncDposState(b.Header().DposProto).Wait(); err != nil {
 		return err
 	}
 
@@ -1477,21 +1579,6 @@ func (d *Downloader) commitPivotBlock(result *fetchResult) error {
 	return d.blockchain.FastSyncCommitHead(b.Hash())
 }
 
-// Todo: sync dpos context in concurrent
-func (d *Downloader) syncDposContextState(context *types.DposContextProto) error {
-	roots := []common.Hash{
-		context.ValidatorHash,
-		context.EpochHash,
-		context.BlockCntHash,
-	}
-	for _, root := range roots {
-		if err := d.syncState(root).Wait(); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 // Todo: sync dpos context in concurrent
 func (d *Downloader) syncBokerContextState(context *protocol.BokerBackendProto) error {
 	roots := []common.Hash{
@@ -25,6 +25,7 @@ import (
 
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/crypto/sha3"
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/log"
@@ -59,7 +60,19 @@ type stateSyncStats struct {
 
 // syncState starts downloading state with the given root hash.
 func (d *Downloader) syncState(root common.Hash) *stateSync {
-	s := newStateSync(d, root)
+	return d.startStateSync(newStateSync(d, state.NewStateSync(root, d.stateDB)))
+}
+
+// syncDposState starts downloading the DPoS context tries (epoch, validator and
+// block count) referenced by a header.
+func (d *Downloader) syncDposState(context *types.DposContextProto) *stateSync {
+	s := newStateSync(d, types.NewDposContextSync(context, d.stateDB))
+	s.dpos = true
+	return d.startStateSync(s)
+}
+
+// startStateSync hands a state sync over to the state fetcher.
+func (d *Downloader) startStateSync(s *stateSync) *stateSync {
 	select {
 	case d.stateSyncStart <- s:
 	case <-d.quitCh:
@@ -206,12 +219,13 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 	}
 }
 
-// stateSync schedules requests for downloading a particular state trie defined
-// by a given state root.
+// stateSync schedules requests for downloading a particular set of tries, either
+// the state trie defined by a given state root or the DPoS context tries.
 type stateSync struct {
 	d *Downloader // Downloader instance to access and manage current peerset
 
 	sched  *trie.TrieSync             // State trie sync scheduler defining the tasks
+	dpos   bool                       // Whether the sync downloads the DPoS context tries
 	keccak hash.Hash                  // Keccak256 hasher to verify deliveries with
 	tasks  map[common.Hash]*stateTask // Set of tasks currently queued for retrieval
 
@@ -231,12 +245,12 @@ type stateTask struct {
 	attempts map[string]struct{}
 }
 
-// newStateSync creates a new state trie download scheduler. This method does not
-// yet start the sync. The user needs to call run to initiate.
-func newStateSync(d *Downloader, root common.Hash) *stateSync {
+// newStateSync creates a new trie download scheduler. This method does not yet
+// start the sync. The user needs to call run to initiate.
+func newStateSync(d *Downloader, sched *trie.TrieSync) *stateSync {
 	return &stateSync{
 		d:       d,
-		sched:   state.NewStateSync(root, d.stateDB),
+		sched:   sched,
 		keccak:  sha3.NewKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
 		deliver: make(chan *stateReq),
@@ -464,12 +478,16 @@ func (s *stateSync) updateStats(written, duplicate, unexpected int, duration tim
 	s.d.syncStatsLock.Lock()
 	defer s.d.syncStatsLock.Unlock()
 
-	s.d.syncStatsState.pending = uint64(s.sched.Pending())
-	s.d.syncStatsState.processed += uint64(written)
-	s.d.syncStatsState.duplicate += uint64(duplicate)
-	s.d.syncStatsState.unexpected += uint64(unexpected)
+	stats, msg := &s.d.syncStatsState, "Imported new state entries"
+	if s.dpos {
+		stats, msg = &s.d.syncStatsDpos, "Imported new DPoS state entries"
+	}
+	stats.pending = uint64(s.sched.Pending())
+	stats.processed += uint64(written)
+	stats.duplicate += uint64(duplicate)
+	stats.unexpected += uint64(unexpected)
 
 	if written > 0 || duplicate > 0 || unexpected > 0 {
-		log.Info("Imported new state entries", "count", written, "elapsed", common.PrettyDuration(duration), "processed", s.d.syncStatsState.processed, "pending", s.d.syncStatsState.pending, "retry", len(s.tasks), "duplicate", s.d.syncStatsState.duplicate, "unexpected", s.d.syncStatsState.unexpected)
+		log.Info(msg, "count", written, "elapsed", common.PrettyDuration(duration), "processed", stats.processed, "pending", stats.pending, "retry", len(s.tasks), "duplicate", stats.duplicate, "unexpected", stats.unexpected)
 	}
 }
@@ -26,11 +26,13 @@ import (
 	"time"
 
 	"github.com/Bokerchain/Boker/chain/boker/api"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/event"
+	"github.com/Bokerchain/Boker/chain/internal/ethapi"
 	"github.com/Bokerchain/Boker/chain/rpc"
 )
 
@@ -47,6 +49,7 @@ type filter struct {
 	crit     FilterCriteria
 	logs     []*types.Log
 	s        *Subscription // associated subscription in event system
+	owner    *rpc.Notifier // connection which installed the filter, nil for HTTP
 }
 
 // PublicFilterAPI offers support to create and manage filters. This will allow external clients to retrieve various
@@ -59,27 +62,43 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+	subs      map[rpc.ID]*activeSubscription
 	boker     bokerapi.Api
+	limits    Limits
+	quotas    Quotas
+
+	filterCount map[*rpc.Notifier]int // installed filters per connection
+	subCount    map[*rpc.Notifier]int // active subscriptions per connection
 }
 
-// NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool, boker bokerapi.Api) *PublicFilterAPI {
+// NewPublicFilterAPI returns a new PublicFilterAPI instance. The limits bound the
+// work done by a single eth_getLogs or eth_getLogsPage call, the quotas bound the
+// filters and subscriptions a single connection may hold.
+func NewPublicFilterAPI(backend Backend, lightMode bool, boker bokerapi.Api, limits Limits, quotas Quotas) *PublicFilterAPI {
+	if quotas.FilterTimeout <= 0 {
+		quotas.FilterTimeout = deadline
+	}
 	api := &PublicFilterAPI{
-		backend: backend,
-		mux:     backend.EventMux(),
-		chainDb: backend.ChainDb(),
-		events:  NewEventSystem(backend.EventMux(), backend, lightMode),
-		filters: make(map[rpc.ID]*filter),
+		backend:     backend,
+		mux:         backend.EventMux(),
+		chainDb:     backend.ChainDb(),
+		events:      NewEventSystem(backend.EventMux(), backend, lightMode),
+		filters:     make(map[rpc.ID]*filter),
+		subs:        make(map[rpc.ID]*activeSubscription),
+		limits:      limits,
+		quotas:      quotas,
+		filterCount: make(map[*rpc.Notifier]int),
+		subCount:    make(map[*rpc.Notifier]int),
 	}
 	go api.timeoutLoop()
 
 	return api
 }
 
-// timeoutLoop runs every 5 minutes and deletes filters that have not been recently used.
-// Tt is started when the api is created.
+// timeoutLoop runs every filter timeout and deletes filters that have not been
+// recently used. Tt is started when the api is created.
 func (api *PublicFilterAPI) timeoutLoop() {
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(api.quotas.FilterTimeout)
 	for {
 		<-ticker.C
 		api.filtersMu.Lock()
@@ -87,7 +106,8 @@ func (api *PublicFilterAPI) timeoutLoop() {
 			select {
 			case <-f.deadline.C:
 				f.s.Unsubscribe()
-				delete(api.filters, id)
+				api.removeFilter(id)
+				expiredFiltersCounter.Inc(1)
 			default:
 				continue
 			}
@@ -103,15 +123,19 @@ func (api *PublicFilterAPI) timeoutLoop() {
 // `eth_getFilterChanges` polling method that is also used for log filters.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newpendingtransactionfilter
-func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
+func (api *PublicFilterAPI) NewPendingTransactionFilter(ctx context.Context) (rpc.ID, error) {
 	var (
 		pendingTxs   = make(chan common.Hash)
 		pendingTxSub = api.events.SubscribePendingTxEvents(pendingTxs)
 	)
 
 	api.filtersMu.Lock()
-	api.filters[pendingTxSub.ID] = &filter{typ: PendingTransactionsSubscription, deadline: time.NewTimer(deadline), hashes: make([]common.Hash, 0), s: pendingTxSub}
+	err := api.installFilter(pendingTxSub.ID, &filter{typ: PendingTransactionsSubscription, deadline: time.NewTimer(api.quotas.FilterTimeout), hashes: make([]common.Hash, 0), s: pendingTxSub, owner: connection(ctx)})
 	api.filtersMu.Unlock()
+	if err != nil {
+		pendingTxSub.Unsubscribe()
+		return rpc.ID(""), err
+	}
 
 	go func() {
 		for {
@@ -124,40 +148,104 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 				api.filtersMu.Unlock()
 			case <-pendingTxSub.Err():
 				api.filtersMu.Lock()
-				delete(api.filters, pendingTxSub.ID)
+				api.removeFilter(pendingTxSub.ID)
 				api.filtersMu.Unlock()
 				return
 			}
 		}
 	}()
 
-	return pendingTxSub.ID
+	return pendingTxSub.ID, nil
+}
+
+// PendingTxCriteria selects the pending transactions a newPendingTransactions
+// subscription is notified of. Without criteria only the hashes of all pending
+// transactions are delivered.
+type PendingTxCriteria struct {
+	FullTx    bool              `json:"fullTx"`    // deliver full transaction objects instead of hashes
+	Types     []protocol.TxType `json:"types"`     // only deliver transactions of these types
+	Addresses []common.Address  `json:"addresses"` // only deliver transactions sent to these addresses
+}
+
+// matches returns whether the transaction satisfies the criteria.
+func (crit *PendingTxCriteria) matches(tx *types.Transaction) bool {
+	if len(crit.Types) > 0 {
+		included := false
+		for _, typ := range crit.Types {
+			if tx.Type() == typ {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if len(crit.Addresses) > 0 {
+		to := tx.To()
+		if to == nil {
+			return false
+		}
+		included := false
+		for _, addr := range crit.Addresses {
+			if *to == addr {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
 }
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// The optional criteria select full transaction objects and filter the transactions by type
+// or by target address.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, crit *PendingTxCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
+	if crit == nil {
+		crit = new(PendingTxCriteria)
+	}
+	active, err := api.trackSubscription(notifier, PendingTxBodiesSubscription)
+	if err != nil {
+		return nil, err
+	}
 
 	rpcSub := notifier.CreateSubscription()
+	api.startSubscription(rpcSub.ID, active)
 
 	go func() {
-		txHashes := make(chan common.Hash)
-		pendingTxSub := api.events.SubscribePendingTxEvents(txHashes)
+		defer api.dropSubscription(rpcSub.ID, active)
+
+		txs := make(chan *types.Transaction)
+		pendingTxSub := api.events.SubscribePendingTxs(txs)
 
 		for {
 			select {
-			case h := <-txHashes:
-				notifier.Notify(rpcSub.ID, h)
+			case tx := <-txs:
+				if !crit.matches(tx) {
+					continue
+				}
+				if crit.FullTx {
+					notifier.Notify(rpcSub.ID, ethapi.NewRPCPendingTransaction(tx))
+				} else {
+					notifier.Notify(rpcSub.ID, tx.Hash())
+				}
 			case <-rpcSub.Err():
 				pendingTxSub.Unsubscribe()
 				return
 			case <-notifier.Closed():
 				pendingTxSub.Unsubscribe()
 				return
+			case <-active.kill:
+				pendingTxSub.Unsubscribe()
+				return
 			}
 		}
 	}()
@@ -169,15 +257,19 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newblockfilter
-func (api *PublicFilterAPI) NewBlockFilter() rpc.ID {
+func (api *PublicFilterAPI) NewBlockFilter(ctx context.Context) (rpc.ID, error) {
 	var (
 		headers   = make(chan *types.Header)
 		headerSub = api.events.SubscribeNewHeads(headers)
 	)
 
 	api.filtersMu.Lock()
-	api.filters[headerSub.ID] = &filter{typ: BlocksSubscription, deadline: time.NewTimer(deadline), hashes: make([]common.Hash, 0), s: headerSub}
+	err := api.installFilter(headerSub.ID, &filter{typ: BlocksSubscription, deadline: time.NewTimer(api.quotas.FilterTimeout), hashes: make([]common.Hash, 0), s: headerSub, owner: connection(ctx)})
 	api.filtersMu.Unlock()
+	if err != nil {
+		headerSub.Unsubscribe()
+		return rpc.ID(""), err
+	}
 
 	go func() {
 		for {
@@ -190,14 +282,14 @@ func (api *PublicFilterAPI) NewBlockFilter() rpc.ID {
 				api.filtersMu.Unlock()
 			case <-headerSub.Err():
 				api.filtersMu.Lock()
-				delete(api.filters, headerSub.ID)
+				api.removeFilter(headerSub.ID)
 				api.filtersMu.Unlock()
 				return
 			}
 		}
 	}()
 
-	return headerSub.ID
+	return headerSub.ID, nil
 }
 
 // NewHeads send a notification each time a new (header) block is appended to the chain.
@@ -207,9 +299,17 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
 
+	active, err := api.trackSubscription(notifier, BlocksSubscription)
+	if err != nil {
+		return nil, err
+	}
+
 	rpcSub := notifier.CreateSubscription()
+	api.startSubscription(rpcSub.ID, active)
 
 	go func() {
+		defer api.dropSubscription(rpcSub.ID, active)
+
 		headers := make(chan *types.Header)
 		headersSub := api.events.SubscribeNewHeads(headers)
 
@@ -223,6 +323,9 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 			case <-notifier.Closed():
 				headersSub.Unsubscribe()
 				return
+			case <-active.kill:
+				headersSub.Unsubscribe()
+				return
 			}
 		}
 	}()
@@ -237,6 +340,10 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
 
+	active, err := api.trackSubscription(notifier, LogsSubscription)
+	if err != nil {
+		return nil, err
+	}
 	var (
 		rpcSub      = notifier.CreateSubscription()
 		matchedLogs = make(chan []*types.Log)
@@ -244,10 +351,13 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 
 	logsSub, err := api.events.SubscribeLogs(crit, matchedLogs)
 	if err != nil {
+		api.dropSubscription(rpcSub.ID, active)
 		return nil, err
 	}
+	api.startSubscription(rpcSub.ID, active)
 
 	go func() {
+		defer api.dropSubscription(rpcSub.ID, active)
 
 		for {
 			select {
@@ -261,6 +371,9 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 			case <-notifier.Closed(): // connection dropped
 				logsSub.Unsubscribe()
 				return
+			case <-active.kill: // killed by an administrator
+				logsSub.Unsubscribe()
+				return
 			}
 		}
 	}()
@@ -289,7 +402,7 @@ type FilterCriteria struct {
 // In case "fromBlock" > "toBlock" an error is returned.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newfilter
-func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
+func (api *PublicFilterAPI) NewFilter(ctx context.Context, crit FilterCriteria) (rpc.ID, error) {
 	logs := make(chan []*types.Log)
 	logsSub, err := api.events.SubscribeLogs(crit, logs)
 	if err != nil {
@@ -297,8 +410,12 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 	}
 
 	api.filtersMu.Lock()
-	api.filters[logsSub.ID] = &filter{typ: LogsSubscription, crit: crit, deadline: time.NewTimer(deadline), logs: make([]*types.Log, 0), s: logsSub}
+	err = api.installFilter(logsSub.ID, &filter{typ: LogsSubscription, crit: crit, deadline: time.NewTimer(api.quotas.FilterTimeout), logs: make([]*types.Log, 0), s: logsSub, owner: connection(ctx)})
 	api.filtersMu.Unlock()
+	if err != nil {
+		logsSub.Unsubscribe()
+		return rpc.ID(""), err
+	}
 
 	go func() {
 		for {
@@ -311,7 +428,7 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 				api.filtersMu.Unlock()
 			case <-logsSub.Err():
 				api.filtersMu.Lock()
-				delete(api.filters, logsSub.ID)
+				api.removeFilter(logsSub.ID)
 				api.filtersMu.Unlock()
 				return
 			}
@@ -334,23 +451,76 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 	}
 	// Create and run the filter to get all the logs
 	filter := New(api.backend, crit.FromBlock.Int64(), crit.ToBlock.Int64(), crit.Addresses, crit.Topics)
+	filter.SetLimits(api.limits)
 
 	logs, err := filter.Logs(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if filter.Truncated() {
+		return nil, fmt.Errorf("query exceeds limits, page through it with eth_getLogsPage or continue from block %d", filter.Next())
+	}
 	return returnLogs(logs), err
 }
 
+// LogsPage is a chunk of the logs matching a filter criteria.
+type LogsPage struct {
+	Logs    []*types.Log    `json:"logs"`
+	ToBlock hexutil.Uint64  `json:"toBlock"` // Last block of the queried range
+	Cursor  *hexutil.Uint64 `json:"cursor"`  // Block to continue the query from, nil if the range was fully scanned
+}
+
+// GetLogsPage returns the logs matching the given argument, stopping at the first
+// block boundary after the query limits are reached. The returned cursor can be
+// passed back together with the same criteria to retrieve the next page. The
+// end of the range is resolved on the first page and returned as toBlock, which
+// clients should pin in the criteria of the following pages.
+func (api *PublicFilterAPI) GetLogsPage(ctx context.Context, crit FilterCriteria, cursor *hexutil.Uint64) (*LogsPage, error) {
+	header, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	head := header.Number.Uint64()
+
+	// Resolve the block range, starting from the cursor if continuing
+	from, to := head, head
+	if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 {
+		from = crit.FromBlock.Uint64()
+	}
+	if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 && crit.ToBlock.Uint64() < head {
+		to = crit.ToBlock.Uint64()
+	}
+	if cursor != nil {
+		if uint64(*cursor) < from {
+			return nil, fmt.Errorf("cursor %d before start of range %d", uint64(*cursor), from)
+		}
+		from = uint64(*cursor)
+	}
+	page := &LogsPage{Logs: []*types.Log{}, ToBlock: hexutil.Uint64(to)}
+	if from > to {
+		return page, nil
+	}
+	filter := New(api.backend, int64(from), int64(to), crit.Addresses, crit.Topics)
+	filter.SetLimits(api.limits)
+
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page.Logs = returnLogs(logs)
+	if filter.Truncated() {
+		next := hexutil.Uint64(filter.Next())
+		page.Cursor = &next
+	}
+	return page, nil
+}
+
 // UninstallFilter removes the filter with the given filter id.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_uninstallfilter
 func (api *PublicFilterAPI) UninstallFilter(id rpc.ID) bool {
 	api.filtersMu.Lock()
-	f, found := api.filters[id]
-	if found {
-		delete(api.filters, id)
-	}
+	f, found := api.removeFilter(id)
 	api.filtersMu.Unlock()
 	if found {
 		f.s.Unsubscribe()
@@ -407,7 +577,7 @@ func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
 			// receive timer value and reset timer
 			<-f.deadline.C
 		}
-		f.deadline.Reset(deadline)
+		f.deadline.Reset(api.quotas.FilterTimeout)
 
 		switch f.typ {
 		case PendingTransactionsSubscription, BlocksSubscription:
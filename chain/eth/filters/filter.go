@@ -19,6 +19,7 @@ package filters
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core"
@@ -44,6 +45,24 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// indexedChunkSections is the number of bloom bit sections scanned by a single
+// matcher session, allowing the query limits to be checked between chunks.
+const indexedChunkSections = 16
+
+// Limits caps the amount of work a single log query may do. A query reaching
+// any of the limits stops at the next block boundary and can be resumed from
+// the block reported by Filter.Next.
+type Limits struct {
+	MaxResults int           // Number of logs after which to stop scanning (0 = unlimited)
+	Timeout    time.Duration // Approximate time after which to stop scanning (0 = unlimited)
+}
+
+// DefaultLimits contains the default log query limits.
+var DefaultLimits = Limits{
+	MaxResults: 10000,
+	Timeout:    10 * time.Second,
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -54,6 +73,10 @@ type Filter struct {
 	topics     [][]common.Hash
 
 	matcher *bloombits.Matcher
+
+	limits    Limits
+	deadline  time.Time
+	truncated bool
 }
 
 // New creates a new filter which uses a bloom filter on blocks to figure out whether
@@ -91,9 +114,46 @@ func New(backend Backend, begin, end int64, addresses []common.Address, topics [
 	}
 }
 
+// SetLimits sets the limits the subsequent Logs calls are bound by.
+func (f *Filter) SetLimits(limits Limits) {
+	f.limits = limits
+}
+
+// Truncated reports whether the last Logs call stopped because of the query
+// limits before scanning the whole block range.
+func (f *Filter) Truncated() bool {
+	return f.truncated
+}
+
+// Next returns the first block not yet scanned by the filter.
+func (f *Filter) Next() uint64 {
+	return uint64(f.begin)
+}
+
+// limited reports whether the query limits were reached after gathering the
+// given number of logs, flagging the filter as truncated if so.
+func (f *Filter) limited(count int) bool {
+	if f.limits.MaxResults > 0 && count >= f.limits.MaxResults {
+		f.truncated = true
+	}
+	if !f.deadline.IsZero() && time.Now().After(f.deadline) {
+		f.truncated = true
+	}
+	return f.truncated
+}
+
 // Logs searches the blockchain for matching log entries, returning all from the
 // first block that contains matches, updating the start of the filter accordingly.
+// If the filter has limits set, the search stops at the first block boundary
+// after reaching them, in which case Truncated reports true and Next the block
+// to continue from.
 func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
+	f.truncated = false
+	if f.limits.Timeout > 0 {
+		f.deadline = time.Now().Add(f.limits.Timeout)
+	} else {
+		f.deadline = time.Time{}
+	}
 	// Figure out the limits of the filter range
 	header, _ := f.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	if header == nil {
@@ -120,32 +180,52 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		} else {
 			logs, err = f.indexedLogs(ctx, indexed-1)
 		}
-		if err != nil {
+		if err != nil || f.truncated {
 			return logs, err
 		}
 	}
-	rest, err := f.unindexedLogs(ctx, end)
-	logs = append(logs, rest...)
-	return logs, err
+	return f.unindexedLogs(ctx, end, logs)
 }
 
 // indexedLogs returns the logs matching the filter criteria based on the bloom
-// bits indexed available locally or via the network.
+// bits indexed available locally or via the network. The range is scanned in
+// chunks of indexedChunkSections sections to keep the matcher sessions short.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
+	var (
+		logs    []*types.Log
+		err     error
+		size, _ = f.backend.BloomStatus()
+	)
+	for uint64(f.begin) <= end {
+		last := (uint64(f.begin)/size+indexedChunkSections)*size - 1
+		if last > end {
+			last = end
+		}
+		if logs, err = f.indexedChunk(ctx, last, logs); err != nil || f.truncated {
+			return logs, err
+		}
+		if uint64(f.begin) <= end && f.limited(len(logs)) {
+			return logs, nil
+		}
+	}
+	return logs, nil
+}
+
+// indexedChunk appends the logs matching the filter criteria up to the given
+// block to the already gathered ones, using a single matcher session.
+func (f *Filter) indexedChunk(ctx context.Context, end uint64, logs []*types.Log) ([]*types.Log, error) {
 	// Create a matcher session and request servicing from the backend
 	matches := make(chan uint64, 64)
 
 	session, err := f.matcher.Start(ctx, uint64(f.begin), end, matches)
 	if err != nil {
-		return nil, err
+		return logs, err
 	}
 	defer session.Close()
 
 	f.backend.ServiceFilter(ctx, session)
 
-	// Iterate over the matches until exhausted or context closed
-	var logs []*types.Log
-
+	// Iterate over the matches until exhausted, limited or context closed
 	for {
 		select {
 		case number, ok := <-matches:
@@ -170,18 +250,23 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 			}
 			logs = append(logs, found...)
 
+			if number < end && f.limited(len(logs)) {
+				return logs, nil
+			}
+
 		case <-ctx.Done():
 			return logs, ctx.Err()
 		}
 	}
 }
 
-// indexedLogs returns the logs matching the filter criteria based on raw block
-// iteration and bloom matching.
-func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
-	var logs []*types.Log
-
+// unindexedLogs appends the logs matching the filter criteria based on raw block
+// iteration and bloom matching to the already gathered ones.
+func (f *Filter) unindexedLogs(ctx context.Context, end uint64, logs []*types.Log) ([]*types.Log, error) {
 	for ; f.begin <= int64(end); f.begin++ {
+		if f.limited(len(logs)) {
+			return logs, nil
+		}
 		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
 		if header == nil || err != nil {
 			return logs, err
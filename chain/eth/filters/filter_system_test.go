@@ -134,7 +134,7 @@ func TestBlockSubscription(t *testing.T) {
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
 		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api         = NewPublicFilterAPI(backend, false)
+		api         = NewPublicFilterAPI(backend, false, nil, Limits{}, Quotas{})
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, db, 10, func(i int, gen *core.BlockGen) {})
 		chainEvents = []core.ChainEvent{}
@@ -191,7 +191,7 @@ func TestPendingTxFilter(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, nil, Limits{}, Quotas{})
 
 		transactions = []*types.Transaction{
 			types.NewTransaction(types.Binary, 0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), new(big.Int), new(big.Int), nil),
@@ -204,7 +204,7 @@ func TestPendingTxFilter(t *testing.T) {
 		hashes []common.Hash
 	)
 
-	fid0 := api.NewPendingTransactionFilter()
+	fid0, _ := api.NewPendingTransactionFilter(context.Background())
 
 	time.Sleep(1 * time.Second)
 	for _, tx := range transactions {
@@ -254,7 +254,7 @@ func TestLogFilterCreation(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, nil, Limits{}, Quotas{})
 
 		testCases = []struct {
 			crit    FilterCriteria
@@ -280,7 +280,7 @@ func TestLogFilterCreation(t *testing.T) {
 	)
 
 	for i, test := range testCases {
-		_, err := api.NewFilter(test.crit)
+		_, err := api.NewFilter(context.Background(), test.crit)
 		if test.success && err != nil {
 			t.Errorf("expected filter creation for case %d to success, got %v", i, err)
 		}
@@ -303,7 +303,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, nil, Limits{}, Quotas{})
 	)
 
 	// different situations where log filter creation should fail.
@@ -315,7 +315,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 	}
 
 	for i, test := range testCases {
-		if _, err := api.NewFilter(test); err == nil {
+		if _, err := api.NewFilter(context.Background(), test); err == nil {
 			t.Errorf("Expected NewFilter for case #%d to fail", i)
 		}
 	}
@@ -333,7 +333,7 @@ func TestLogFilter(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, nil, Limits{}, Quotas{})
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -391,7 +391,7 @@ func TestLogFilter(t *testing.T) {
 
 	// create all filters
 	for i := range testCases {
-		testCases[i].id, _ = api.NewFilter(testCases[i].crit)
+		testCases[i].id, _ = api.NewFilter(context.Background(), testCases[i].crit)
 	}
 
 	// raise events
@@ -452,7 +452,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, nil, Limits{}, Quotas{})
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
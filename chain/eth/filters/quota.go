@@ -0,0 +1,221 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/metrics"
+	"github.com/Bokerchain/Boker/chain/rpc"
+)
+
+var (
+	errFilterQuota       = errors.New("too many filters installed by this connection")
+	errSubscriptionQuota = errors.New("too many subscriptions active on this connection")
+)
+
+var (
+	activeFiltersGauge       = metrics.NewGauge("rpc/filters/active")
+	activeSubscriptionsGauge = metrics.NewGauge("rpc/subscriptions/active")
+	expiredFiltersCounter    = metrics.NewCounter("rpc/filters/expired")
+	rejectedFiltersCounter   = metrics.NewCounter("rpc/filters/rejected")
+	rejectedSubsCounter      = metrics.NewCounter("rpc/subscriptions/rejected")
+	killedFiltersCounter     = metrics.NewCounter("rpc/filters/killed")
+)
+
+// Quotas bounds the filters and subscriptions a single connection may hold.
+// Requests served over HTTP carry no persistent connection, so all filters
+// installed over HTTP share a single quota.
+type Quotas struct {
+	MaxFilters       int           // Polling filters installed per connection (0 = unlimited)
+	MaxSubscriptions int           // Active subscriptions per connection (0 = unlimited)
+	FilterTimeout    time.Duration // Time after which a filter that is not polled is removed
+}
+
+// DefaultQuotas contains the default filter and subscription quotas.
+var DefaultQuotas = Quotas{
+	MaxFilters:       0,
+	MaxSubscriptions: 128,
+	FilterTimeout:    deadline,
+}
+
+// activeSubscription is the bookkeeping of a live subscription, used to
+// enforce the quotas and to allow administrators to kill it.
+type activeSubscription struct {
+	typ     Type
+	owner   *rpc.Notifier
+	created time.Time
+	kill    chan struct{} // closed when the subscription is killed
+}
+
+// connection returns the notifier of the connection serving the request, or
+// nil if the request was served over HTTP.
+func connection(ctx context.Context) *rpc.Notifier {
+	notifier, _ := rpc.NotifierFromContext(ctx)
+	return notifier
+}
+
+// installFilter tracks a new polling filter of the given connection, failing
+// if the connection already reached its quota. The filters lock must be held.
+func (api *PublicFilterAPI) installFilter(id rpc.ID, f *filter) error {
+	if max := api.quotas.MaxFilters; max > 0 && api.filterCount[f.owner] >= max {
+		rejectedFiltersCounter.Inc(1)
+		return errFilterQuota
+	}
+	api.filters[id] = f
+	api.filterCount[f.owner]++
+	activeFiltersGauge.Update(int64(len(api.filters)))
+	return nil
+}
+
+// removeFilter stops tracking a polling filter, returning it if it was still
+// installed. The filters lock must be held.
+func (api *PublicFilterAPI) removeFilter(id rpc.ID) (*filter, bool) {
+	f, found := api.filters[id]
+	if !found {
+		return nil, false
+	}
+	delete(api.filters, id)
+	if api.filterCount[f.owner]--; api.filterCount[f.owner] <= 0 {
+		delete(api.filterCount, f.owner)
+	}
+	activeFiltersGauge.Update(int64(len(api.filters)))
+	return f, true
+}
+
+// trackSubscription registers a new subscription of the given connection,
+// failing if the connection already reached its quota.
+func (api *PublicFilterAPI) trackSubscription(owner *rpc.Notifier, typ Type) (*activeSubscription, error) {
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	if max := api.quotas.MaxSubscriptions; max > 0 && api.subCount[owner] >= max {
+		rejectedSubsCounter.Inc(1)
+		return nil, errSubscriptionQuota
+	}
+	api.subCount[owner]++
+	return &activeSubscription{typ: typ, owner: owner, created: time.Now(), kill: make(chan struct{})}, nil
+}
+
+// startSubscription makes a tracked subscription visible under its id once
+// the RPC subscription was created.
+func (api *PublicFilterAPI) startSubscription(id rpc.ID, sub *activeSubscription) {
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	api.subs[id] = sub
+	activeSubscriptionsGauge.Update(int64(len(api.subs)))
+}
+
+// dropSubscription stops tracking a subscription once its event loop returned.
+func (api *PublicFilterAPI) dropSubscription(id rpc.ID, sub *activeSubscription) {
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	delete(api.subs, id)
+	if api.subCount[sub.owner]--; api.subCount[sub.owner] <= 0 {
+		delete(api.subCount, sub.owner)
+	}
+	activeSubscriptionsGauge.Update(int64(len(api.subs)))
+}
+
+// FilterInfo describes an installed filter or an active subscription.
+type FilterInfo struct {
+	ID           rpc.ID    `json:"id"`
+	Type         string    `json:"type"`
+	Subscription bool      `json:"subscription"` // whether events are pushed instead of polled
+	Connection   string    `json:"connection"`   // identifies the owning connection, "http" for HTTP requests
+	Created      time.Time `json:"created"`
+}
+
+// typeName returns the name of a subscription type reported to administrators.
+func typeName(typ Type) string {
+	switch typ {
+	case LogsSubscription, PendingLogsSubscription, MinedAndPendingLogsSubscription:
+		return "logs"
+	case PendingTransactionsSubscription, PendingTxBodiesSubscription:
+		return "pendingTransactions"
+	case BlocksSubscription:
+		return "newHeads"
+	default:
+		return "unknown"
+	}
+}
+
+// connectionName returns the identifier of a connection reported to administrators.
+func connectionName(owner *rpc.Notifier) string {
+	if owner == nil {
+		return "http"
+	}
+	return fmt.Sprintf("%p", owner)
+}
+
+// PrivateFilterAPI offers administrators an overview of the filters and
+// subscriptions installed by the clients and allows killing them.
+type PrivateFilterAPI struct {
+	api *PublicFilterAPI
+}
+
+// NewPrivateFilterAPI creates the administrative API of the given filter API.
+func NewPrivateFilterAPI(api *PublicFilterAPI) *PrivateFilterAPI {
+	return &PrivateFilterAPI{api: api}
+}
+
+// Filters lists the installed filters and active subscriptions, oldest first.
+func (api *PrivateFilterAPI) Filters() []FilterInfo {
+	api.api.filtersMu.Lock()
+	defer api.api.filtersMu.Unlock()
+
+	infos := make([]FilterInfo, 0, len(api.api.filters)+len(api.api.subs))
+	for id, f := range api.api.filters {
+		infos = append(infos, FilterInfo{ID: id, Type: typeName(f.typ), Connection: connectionName(f.owner), Created: f.s.f.created})
+	}
+	for id, sub := range api.api.subs {
+		infos = append(infos, FilterInfo{ID: id, Type: typeName(sub.typ), Subscription: true, Connection: connectionName(sub.owner), Created: sub.created})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Created.Before(infos[j].Created) })
+	return infos
+}
+
+// KillFilter removes the filter or stops the subscription with the given id.
+// The owning client is not notified, a killed filter is reported as not found
+// on its next poll and a killed subscription stops delivering events.
+func (api *PrivateFilterAPI) KillFilter(id rpc.ID) bool {
+	api.api.filtersMu.Lock()
+	f, isFilter := api.api.removeFilter(id)
+	sub, isSub := api.api.subs[id]
+	if isSub {
+		delete(api.api.subs, id)
+		activeSubscriptionsGauge.Update(int64(len(api.api.subs)))
+	}
+	api.api.filtersMu.Unlock()
+
+	switch {
+	case isFilter:
+		f.s.Unsubscribe()
+	case isSub:
+		close(sub.kill)
+	default:
+		return false
+	}
+	killedFiltersCounter.Inc(1)
+	return true
+}
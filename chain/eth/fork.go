@@ -0,0 +1,420 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/boker/api"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/core/vm"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/rpc"
+)
+
+var (
+	errNoFork         = errors.New("no fork is active")                                  //当前没有分叉
+	errForkSubmit     = errors.New("boker transactions can't be submitted in fork mode") //分叉模式下不能向网络提交播客链交易
+	errBokerNotLoaded = errors.New("boker interface is not available")                   //播客链接口还没有设置
+	errForkActive     = errors.New("transactions are not accepted during a debug fork")  //分叉期间拒绝公共接口提交的交易
+)
+
+//分叉的信息
+type ForkStatus struct {
+	Parent       uint64      `json:"parent"` //分叉所基于的区块
+	ParentHash   common.Hash `json:"parentHash"`
+	Number       uint64      `json:"number"`       //分叉上执行交易使用的区块号
+	Root         common.Hash `json:"root"`         //分叉当前的状态根
+	Transactions int         `json:"transactions"` //分叉上已经执行的交易数量
+	GasUsed      *big.Int    `json:"gasUsed"`
+}
+
+//基于历史区块状态创建的内存分叉，分叉上执行的交易不会进入交易池也不会广播，节点重启后分叉丢失
+type chainFork struct {
+	eth         *Ethereum
+	parent      *types.Block
+	header      *types.Header
+	state       *state.StateDB
+	dposContext *types.DposContext
+	boker       *forkBoker
+	receipts    types.Receipts
+	txs         types.Transactions
+	lock        sync.RWMutex
+}
+
+//以指定区块的状态创建分叉，分叉上的交易按照下一个区块执行
+func newChainFork(eth *Ethereum, parent *types.Block) (*chainFork, error) {
+
+	if eth.boker == nil {
+		return nil, errBokerNotLoaded
+	}
+	statedb, err := eth.blockchain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	dposContext, err := types.NewDposContextFromProto(eth.chainDb, parent.Header().DposProto)
+	if err != nil {
+		return nil, err
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   new(big.Int).Set(parent.GasLimit()),
+		GasUsed:    new(big.Int),
+		Difficulty: new(big.Int).Set(parent.Difficulty()),
+		Time:       big.NewInt(time.Now().Unix()),
+		DposProto:  parent.Header().DposProto,
+		BokerProto: parent.Header().BokerProto,
+	}
+	//出块时间不能早于父区块
+	if header.Time.Cmp(parent.Time()) <= 0 {
+		header.Time = new(big.Int).Add(parent.Time(), common.Big1)
+	}
+	if coinbase, err := eth.Coinbase(); err == nil {
+		header.Coinbase = coinbase
+	}
+	return &chainFork{
+		eth:         eth,
+		parent:      parent,
+		header:      header,
+		state:       statedb,
+		dposContext: dposContext,
+		boker:       newForkBoker(eth.boker),
+	}, nil
+}
+
+//在分叉上执行交易，执行失败时回滚状态，不影响之后的交易
+func (f *chainFork) apply(tx *types.Transaction) (*types.Receipt, error) {
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	snap := f.state.Snapshot()
+	dposSnap := f.dposContext.Snapshot()
+
+	//每笔交易都有完整的区块Gas，分叉上可以执行任意多的交易
+	gp := new(core.GasPool).AddGas(f.header.GasLimit)
+	f.state.Prepare(tx.Hash(), common.Hash{}, len(f.txs))
+
+	receipt, _, err := core.ApplyTransaction(f.eth.chainConfig, f.dposContext, f.eth.blockchain, &f.header.Coinbase, gp, f.state, f.header, tx, f.header.GasUsed, vm.Config{}, f.boker)
+	if err != nil {
+		f.state.RevertToSnapshot(snap)
+		f.dposContext.RevertToSnapShot(dposSnap)
+		return nil, err
+	}
+	f.txs = append(f.txs, tx)
+	f.receipts = append(f.receipts, receipt)
+
+	log.Info("Executed transaction on fork", "hash", tx.Hash(), "parent", f.parent.NumberU64(), "gas", receipt.GasUsed, "status", receipt.Status)
+	return receipt, nil
+}
+
+//得到分叉当前状态的副本和执行交易使用的区块头
+func (f *chainFork) stateAndHeader() (*state.StateDB, *types.Header) {
+
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.state.Copy(), types.CopyHeader(f.header)
+}
+
+//得到账户在分叉上的Nonce
+func (f *chainFork) nonce(addr common.Address) uint64 {
+
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.state.GetNonce(addr)
+}
+
+func (f *chainFork) status() *ForkStatus {
+
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return &ForkStatus{
+		Parent:       f.parent.NumberU64(),
+		ParentHash:   f.parent.Hash(),
+		Number:       f.header.Number.Uint64(),
+		Root:         f.state.IntermediateRoot(f.eth.chainConfig.IsEIP158(f.header.Number)),
+		Transactions: len(f.txs),
+		GasUsed:      new(big.Int).Set(f.header.GasUsed),
+	}
+}
+
+//分叉上使用的播客链接口，基础合约注册表的修改只保存在内存中，查询和其它方法使用节点的接口
+type forkBoker struct {
+	bokerapi.Api
+	contracts map[common.Address]protocol.ContractType
+	updates   []protocol.ContractUpdate //分叉上记录的基础合约替换
+	modified  bool
+	lock      sync.RWMutex
+}
+
+//以节点当前的基础合约注册表创建分叉使用的接口
+func newForkBoker(boker bokerapi.Api) *forkBoker {
+	return &forkBoker{Api: boker, contracts: boker.GetContracts()}
+}
+
+func (b *forkBoker) GetContract(address common.Address) (protocol.ContractType, error) {
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if contractType, exist := b.contracts[address]; exist {
+		return contractType, nil
+	}
+	return protocol.BinaryContract, nil
+}
+
+func (b *forkBoker) GetContracts() map[common.Address]protocol.ContractType {
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	contracts := make(map[common.Address]protocol.ContractType, len(b.contracts))
+	for k, v := range b.contracts {
+		contracts[k] = v
+	}
+	return contracts
+}
+
+func (b *forkBoker) GetContractAddr(contractType protocol.ContractType) (common.Address, error) {
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	for k, v := range b.contracts {
+		if v == contractType {
+			return k, nil
+		}
+	}
+	return common.Address{}, protocol.ErrNotFoundContract
+}
+
+//只修改分叉的注册表，不启动合约服务
+func (b *forkBoker) SetContract(address common.Address, contractType protocol.ContractType, isCancel bool, abiJson string) error {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if _, exist := b.contracts[address]; exist {
+		return protocol.ErrContractExist
+	}
+	b.contracts[address] = contractType
+	b.modified = true
+	return nil
+}
+
+func (b *forkBoker) CancelContract(address common.Address) error {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.contracts, address)
+	b.modified = true
+	return nil
+}
+
+//只在分叉上记录基础合约替换，分叉不会结束区块，替换只用于演练签名和交易是否有效
+func (b *forkBoker) ScheduleContractUpdate(update protocol.ContractUpdate) error {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if current, exist := b.contracts[update.Old]; !exist || current != update.ContractType {
+		return protocol.ErrNotFoundContract
+	}
+	if _, exist := b.contracts[update.New]; exist {
+		return protocol.ErrContractExist
+	}
+	for _, scheduled := range b.updates {
+		if scheduled == update {
+			return nil
+		}
+		if scheduled.Old == update.Old || scheduled.New == update.New {
+			return protocol.ErrUpdateScheduled
+		}
+	}
+	b.updates = append(b.updates, update)
+	return nil
+}
+
+func (b *forkBoker) ApplyContractUpdates(number uint64) {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var pending []protocol.ContractUpdate
+	for _, update := range b.updates {
+		if update.Number > number {
+			pending = append(pending, update)
+			continue
+		}
+		if current, exist := b.contracts[update.Old]; !exist || current != update.ContractType {
+			continue
+		}
+		if _, exist := b.contracts[update.New]; exist {
+			continue
+		}
+		delete(b.contracts, update.Old)
+		b.contracts[update.New] = update.ContractType
+		b.modified = true
+	}
+	b.updates = pending
+}
+
+//注册表没有修改时使用节点的注册表Hash，这样验证者对节点注册表的签名可以在分叉上演练，修改后使用分叉注册表内容的Hash
+func (b *forkBoker) RegistryHash() common.Hash {
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	if !b.modified {
+		return b.Api.RegistryHash()
+	}
+	addrs := make([]common.Address, 0, len(b.contracts))
+	for addr := range b.contracts {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	data := make([]byte, 0, len(addrs)*(common.AddressLength+1))
+	for _, addr := range addrs {
+		data = append(data, addr.Bytes()...)
+		data = append(data, byte(b.contracts[addr]))
+	}
+	return crypto.Keccak256Hash([]byte("boker-fork-registry"), data)
+}
+
+func (b *forkBoker) SubmitBokerTransaction(ctx context.Context, txType protocol.TxType, to common.Address, extra string) (*types.Transaction, error) {
+	return nil, errForkSubmit
+}
+
+//得到当前的分叉，没有分叉时返回nil
+func (s *Ethereum) Fork() *chainFork {
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.fork
+}
+
+//以指定区块的状态创建内存分叉，之后只有通过debug_forkSendRawTransaction发送的交易在分叉上执行，不进入交易池也不广播，
+//分叉期间eth_sendTransaction和eth_sendRawTransaction返回错误，pending状态的查询返回分叉的状态。再次调用会丢弃之前的分叉
+func (api *PrivateDebugAPI) ForkAt(blockNr rpc.BlockNumber) (*ForkStatus, error) {
+
+	var block *types.Block
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		block = api.eth.blockchain.CurrentBlock()
+	} else {
+		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	fork, err := newChainFork(api.eth, block)
+	if err != nil {
+		return nil, err
+	}
+
+	api.eth.lock.Lock()
+	api.eth.fork = fork
+	api.eth.lock.Unlock()
+
+	log.Warn("Forked chain state, transactions will not be broadcast", "number", block.NumberU64(), "hash", block.Hash())
+	return fork.status(), nil
+}
+
+//得到当前分叉的信息
+func (api *PrivateDebugAPI) ForkStatus() (*ForkStatus, error) {
+
+	fork := api.eth.Fork()
+	if fork == nil {
+		return nil, errNoFork
+	}
+	return fork.status(), nil
+}
+
+//得到分叉上已经执行的交易的回执
+func (api *PrivateDebugAPI) ForkReceipts() ([]map[string]interface{}, error) {
+
+	fork := api.eth.Fork()
+	if fork == nil {
+		return nil, errNoFork
+	}
+	fork.lock.RLock()
+	defer fork.lock.RUnlock()
+
+	signer := types.MakeSigner(api.config, fork.header.Number)
+	results := make([]map[string]interface{}, len(fork.receipts))
+	for i, receipt := range fork.receipts {
+		tx := fork.txs[i]
+		from, _ := types.Sender(signer, tx)
+
+		fields := map[string]interface{}{
+			"blockNumber":       (*hexutil.Big)(fork.header.Number),
+			"transactionHash":   tx.Hash(),
+			"transactionIndex":  hexutil.Uint64(i),
+			"type":              tx.Type(),
+			"from":              from,
+			"to":                tx.To(),
+			"gasUsed":           (*hexutil.Big)(receipt.GasUsed),
+			"cumulativeGasUsed": (*hexutil.Big)(receipt.CumulativeGasUsed),
+			"contractAddress":   nil,
+			"logs":              receipt.Logs,
+			"logsBloom":         receipt.Bloom,
+			"status":            hexutil.Uint(receipt.Status),
+		}
+		if receipt.Logs == nil {
+			fields["logs"] = [][]*types.Log{}
+		}
+		if receipt.ContractAddress != (common.Address{}) {
+			fields["contractAddress"] = receipt.ContractAddress
+		}
+		results[i] = fields
+	}
+	return results, nil
+}
+
+//在当前分叉上执行已经签名的交易，交易不进入交易池也不广播，执行结果通过debug_forkReceipts查询
+func (api *PrivateDebugAPI) ForkSendRawTransaction(encodedTx hexutil.Bytes) (common.Hash, error) {
+
+	fork := api.eth.Fork()
+	if fork == nil {
+		return common.Hash{}, errNoFork
+	}
+	tx, err := types.DecodeRawTransaction(encodedTx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if _, err := fork.apply(tx); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+//丢弃当前分叉，恢复正常的交易处理
+func (api *PrivateDebugAPI) Unfork() bool {
+
+	api.eth.lock.Lock()
+	defer api.eth.lock.Unlock()
+
+	if api.eth.fork == nil {
+		return false
+	}
+	api.eth.fork = nil
+	log.Info("Dropped chain fork")
+	return true
+}
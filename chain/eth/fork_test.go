@@ -0,0 +1,134 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/api"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/consensus/ethash"
+	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/core/vm"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/params"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	"github.com/Bokerchain/Boker/chain/rpc"
+)
+
+//没有注册基础合约的播客链接口
+type forkTestBoker struct {
+	bokerapi.Api
+}
+
+func (b *forkTestBoker) GetContracts() map[common.Address]protocol.ContractType {
+	return make(map[common.Address]protocol.ContractType)
+}
+
+func (b *forkTestBoker) GetContract(address common.Address) (protocol.ContractType, error) {
+	return protocol.BinaryContract, nil
+}
+
+//创建只包含创世区块的节点
+func newForkTestBackend(t *testing.T) (*EthApiBackend, *PrivateDebugAPI) {
+
+	var (
+		db, _ = ethdb.NewMemDatabase()
+		gspec = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000)}}}
+	)
+	gspec.MustCommit(db)
+	blockchain, err := core.NewBlockChain(db, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	//不记录本地交易，避免测试之间通过日志文件互相影响
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+	txpool := core.NewTxPool(config, gspec.Config, blockchain)
+
+	eth := &Ethereum{
+		chainConfig: gspec.Config,
+		chainDb:     db,
+		blockchain:  blockchain,
+		txPool:      txpool,
+		boker:       &forkTestBoker{},
+		coinbase:    common.Address{0x0c},
+	}
+	return &EthApiBackend{eth: eth}, NewPrivateDebugAPI(gspec.Config, eth)
+}
+
+//分叉期间公共接口提交的交易返回错误，既不进入交易池也不在分叉上执行
+func TestForkRejectsPublicSubmission(t *testing.T) {
+
+	backend, api := newForkTestBackend(t)
+	defer backend.eth.blockchain.Stop()
+	defer backend.eth.txPool.Stop()
+
+	if _, err := api.ForkAt(rpc.LatestBlockNumber); err != nil {
+		t.Fatalf("failed to fork: %v", err)
+	}
+	tx := newTestTransaction(testBankKey, 0, 0)
+	if err := backend.SendTx(context.Background(), tx); err != errForkActive {
+		t.Fatalf("submission during fork: have %v, want %v", err, errForkActive)
+	}
+	if pooled := backend.GetPoolTransaction(tx.Hash()); pooled != nil {
+		t.Errorf("transaction entered the pool during fork")
+	}
+	if status, _ := api.ForkStatus(); status.Transactions != 0 {
+		t.Errorf("public transaction executed on fork: %d transactions", status.Transactions)
+	}
+
+	//丢弃分叉之后恢复正常的交易处理
+	if !api.Unfork() {
+		t.Fatalf("fork not dropped")
+	}
+	if err := backend.SendTx(context.Background(), tx); err != nil {
+		t.Fatalf("submission after unfork: %v", err)
+	}
+	if pooled := backend.GetPoolTransaction(tx.Hash()); pooled == nil {
+		t.Errorf("transaction missing from pool after unfork")
+	}
+}
+
+//通过调试接口发送的交易只在分叉上执行，分叉的Nonce和回执随之更新
+func TestForkSendRawTransaction(t *testing.T) {
+
+	backend, api := newForkTestBackend(t)
+	defer backend.eth.blockchain.Stop()
+	defer backend.eth.txPool.Stop()
+
+	tx := newTestTransaction(testBankKey, 0, 0)
+	raw, _ := rlp.EncodeToBytes(tx)
+	if _, err := api.ForkSendRawTransaction(raw); err != errNoFork {
+		t.Fatalf("send without fork: have %v, want %v", err, errNoFork)
+	}
+	if _, err := api.ForkAt(rpc.LatestBlockNumber); err != nil {
+		t.Fatalf("failed to fork: %v", err)
+	}
+	hash, err := api.ForkSendRawTransaction(raw)
+	if err != nil {
+		t.Fatalf("failed to send on fork: %v", err)
+	}
+	if hash != tx.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", hash, tx.Hash())
+	}
+	if status, _ := api.ForkStatus(); status.Transactions != 1 {
+		t.Errorf("fork transactions mismatch: have %d, want 1", status.Transactions)
+	}
+	if receipts, _ := api.ForkReceipts(); len(receipts) != 1 || receipts[0]["transactionHash"] != tx.Hash() {
+		t.Errorf("fork receipts mismatch: %v", receipts)
+	}
+	if nonce, _ := backend.GetPoolNonce(context.Background(), testBank); nonce != 1 {
+		t.Errorf("fork nonce mismatch: have %d, want 1", nonce)
+	}
+	if pooled := backend.GetPoolTransaction(tx.Hash()); pooled != nil {
+		t.Errorf("fork transaction entered the pool")
+	}
+
+	//分叉上的交易不影响节点的交易池
+	api.Unfork()
+	if nonce, _ := backend.GetPoolNonce(context.Background(), testBank); nonce != 0 {
+		t.Errorf("pool nonce changed by fork: have %d, want 0", nonce)
+	}
+}
@@ -18,11 +18,14 @@ package gasprice
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/big"
 	"sort"
 	"sync"
 
 	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/internal/ethapi"
 	"github.com/Bokerchain/Boker/chain/params"
 	"github.com/Bokerchain/Boker/chain/rpc"
@@ -30,9 +33,19 @@ import (
 
 var maxPrice = big.NewInt(500 * params.Shannon)
 
+// defaultMaxHistory is the number of blocks a single price history query may
+// span if not configured otherwise.
+const defaultMaxHistory = 1024
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
 type Config struct {
 	Blocks     int
 	Percentile int
+	MaxHistory int      `toml:",omitempty"` // Maximum number of blocks served by a single PriceHistory query
 	Default    *big.Int `toml:",omitempty"`
 }
 
@@ -46,6 +59,7 @@ type Oracle struct {
 	fetchLock                        sync.Mutex
 	checkBlocks, maxEmpty, maxBlocks int
 	percentile                       int
+	maxHistory                       int
 }
 
 // NewOracle returns a new oracle.
@@ -61,6 +75,10 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 	if percent > 100 {
 		percent = 100
 	}
+	history := params.MaxHistory
+	if history < 1 {
+		history = defaultMaxHistory
+	}
 	return &Oracle{
 		backend:     backend,
 		lastPrice:   params.Default,
@@ -68,6 +86,7 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 		maxEmpty:    blocks / 2,
 		maxBlocks:   blocks * 5,
 		percentile:  percent,
+		maxHistory:  history,
 	}
 }
 
@@ -158,12 +177,82 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, blockNum uint64, ch chan
 		ch <- getBlockPricesResult{nil, err}
 		return
 	}
-	txs := block.Transactions()
-	prices := make([]*big.Int, len(txs))
-	for i, tx := range txs {
-		prices[i] = tx.GasPrice()
+	ch <- getBlockPricesResult{blockPrices(block), nil}
+}
+
+// blockPrices returns the gas prices of the gas paying transactions in a block.
+// Boker base transactions are gas free and carry a fixed placeholder price, so
+// they would only skew the statistics.
+func blockPrices(block *types.Block) []*big.Int {
+	var prices []*big.Int
+	for _, tx := range block.Transactions() {
+		if types.IsBinary(tx.Type()) {
+			prices = append(prices, tx.GasPrice())
+		}
+	}
+	return prices
+}
+
+// PriceHistory returns, for consecutive blocks starting at start, the gas
+// prices at the requested percentiles (0-100) of the gas paying transactions in
+// each block, together with the ratio of gas used to the block gas limit. The
+// range is truncated at the configured maximum and at the current head. Blocks
+// without transactions report zero prices.
+func (gpo *Oracle) PriceHistory(ctx context.Context, start rpc.BlockNumber, blocks uint64, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	if blocks == 0 {
+		return nil, nil, nil, nil
+	}
+	// Clamp the requested range before converting it, arbitrary client supplied
+	// counts would otherwise wrap around
+	if blocks > uint64(gpo.maxHistory) {
+		blocks = uint64(gpo.maxHistory)
+	}
+	count := int(blocks)
+	for i, p := range percentiles {
+		if p < 0 || p > 100 || (i > 0 && p < percentiles[i-1]) {
+			return nil, nil, nil, fmt.Errorf("%v: %f", errInvalidPercentile, p)
+		}
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return nil, nil, nil, err
+	}
+	first := uint64(start.Int64())
+	if start == rpc.LatestBlockNumber || start == rpc.PendingBlockNumber {
+		first = head.Number.Uint64()
+	}
+	if first > head.Number.Uint64() {
+		return nil, nil, nil, fmt.Errorf("%v: requested %d, head %d", errRequestBeyondHead, first, head.Number.Uint64())
+	}
+	if last := first + uint64(count) - 1; last > head.Number.Uint64() {
+		count = int(head.Number.Uint64() - first + 1)
+	}
+
+	var (
+		prices = make([][]*big.Int, count)
+		ratios = make([]float64, count)
+	)
+	for i := 0; i < count; i++ {
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(first+uint64(i)))
+		if block == nil {
+			return nil, nil, nil, err
+		}
+		if limit := block.GasLimit(); limit.Sign() > 0 {
+			ratios[i], _ = new(big.Float).Quo(new(big.Float).SetInt(block.GasUsed()), new(big.Float).SetInt(limit)).Float64()
+		}
+		txPrices := blockPrices(block)
+		sort.Sort(bigIntArray(txPrices))
+
+		prices[i] = make([]*big.Int, len(percentiles))
+		for j, p := range percentiles {
+			if len(txPrices) == 0 {
+				prices[i][j] = new(big.Int)
+				continue
+			}
+			prices[i][j] = new(big.Int).Set(txPrices[int(float64(len(txPrices)-1)*p/100)])
+		}
 	}
-	ch <- getBlockPricesResult{prices, nil}
+	return new(big.Int).SetUint64(first), prices, ratios, nil
 }
 
 type bigIntArray []*big.Int
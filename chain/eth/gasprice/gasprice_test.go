@@ -0,0 +1,174 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/internal/ethapi"
+	"github.com/Bokerchain/Boker/chain/rpc"
+)
+
+// testBackend serves the blocks of a fixed chain to the oracle.
+type testBackend struct {
+	ethapi.Backend
+	chain []*types.Block
+}
+
+func (b *testBackend) block(number rpc.BlockNumber) *types.Block {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.chain[len(b.chain)-1]
+	}
+	if number < 0 || int64(number) >= int64(len(b.chain)) {
+		return nil
+	}
+	return b.chain[number]
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if block := b.block(number); block != nil {
+		return block.Header(), nil
+	}
+	return nil, nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	return b.block(number), nil
+}
+
+// newTestBackend creates a chain of the given length, block i containing i gas
+// paying transactions priced 1..i and a free base contract transaction, using
+// i percent of the gas limit.
+func newTestBackend(blocks int) *testBackend {
+	backend := new(testBackend)
+	for i := 0; i < blocks; i++ {
+		var txs []*types.Transaction
+		for j := 1; j <= i; j++ {
+			txs = append(txs, types.NewTransaction(protocol.Binary, uint64(j), common.Address{}, new(big.Int), big.NewInt(21000), big.NewInt(int64(j)), nil))
+		}
+		if i > 0 {
+			txs = append(txs, types.NewTransaction(protocol.SetValidator, 0, common.Address{}, new(big.Int), new(big.Int), big.NewInt(1000), nil))
+		}
+		header := &types.Header{
+			Number:     big.NewInt(int64(i)),
+			Difficulty: new(big.Int),
+			GasLimit:   big.NewInt(100),
+			GasUsed:    big.NewInt(int64(i)),
+		}
+		backend.chain = append(backend.chain, types.NewBlock(header, txs, nil, nil))
+	}
+	return backend
+}
+
+// Tests that the price history reports the requested percentiles of the gas
+// paying transactions and the gas used ratio of every block.
+func TestPriceHistory(t *testing.T) {
+	oracle := NewOracle(newTestBackend(5), Config{Blocks: 1})
+
+	oldest, prices, ratios, err := oracle.PriceHistory(context.Background(), 1, 3, []float64{0, 50, 100})
+	if err != nil {
+		t.Fatalf("failed to retrieve price history: %v", err)
+	}
+	if oldest.Uint64() != 1 {
+		t.Errorf("oldest block mismatch: have %v, want %v", oldest, 1)
+	}
+	want := [][]int64{{1, 1, 1}, {1, 1, 2}, {1, 2, 3}}
+	if len(prices) != len(want) || len(ratios) != len(want) {
+		t.Fatalf("block count mismatch: have %d/%d, want %d", len(prices), len(ratios), len(want))
+	}
+	for i := range want {
+		for j := range want[i] {
+			if prices[i][j].Int64() != want[i][j] {
+				t.Errorf("block %d, percentile %d: price mismatch: have %v, want %v", i, j, prices[i][j], want[i][j])
+			}
+		}
+		if ratio := float64(i+1) / 100; ratios[i] != ratio {
+			t.Errorf("block %d: ratio mismatch: have %v, want %v", i, ratios[i], ratio)
+		}
+	}
+	// Blocks without gas paying transactions report zero prices
+	_, prices, _, err = oracle.PriceHistory(context.Background(), 0, 1, []float64{50})
+	if err != nil {
+		t.Fatalf("failed to retrieve empty block history: %v", err)
+	}
+	if len(prices) != 1 || prices[0][0].Sign() != 0 {
+		t.Errorf("empty block prices mismatch: have %v, want [[0]]", prices)
+	}
+}
+
+// Tests that the requested range is clamped to the configured maximum and to
+// the current head, including counts that do not fit into an int.
+func TestPriceHistoryRange(t *testing.T) {
+	oracle := NewOracle(newTestBackend(10), Config{Blocks: 1, MaxHistory: 4})
+
+	tests := []struct {
+		start  rpc.BlockNumber
+		count  uint64
+		oldest uint64
+		blocks int
+	}{
+		{0, 0, 0, 0},
+		{0, 1, 0, 1},
+		{0, 4, 0, 4},
+		{0, 5, 0, 4},
+		{8, 4, 8, 2},
+		{9, math.MaxUint64, 9, 1},
+		{0, math.MaxUint64, 0, 4},
+		{0, math.MaxInt64 + 1, 0, 4},
+		{rpc.LatestBlockNumber, 3, 9, 1},
+	}
+	for i, tt := range tests {
+		oldest, prices, ratios, err := oracle.PriceHistory(context.Background(), tt.start, tt.count, nil)
+		if err != nil {
+			t.Errorf("test %d: failed to retrieve price history: %v", i, err)
+			continue
+		}
+		if len(prices) != tt.blocks || len(ratios) != tt.blocks {
+			t.Errorf("test %d: block count mismatch: have %d/%d, want %d", i, len(prices), len(ratios), tt.blocks)
+		}
+		if tt.blocks > 0 && oldest.Uint64() != tt.oldest {
+			t.Errorf("test %d: oldest block mismatch: have %v, want %v", i, oldest, tt.oldest)
+		}
+	}
+}
+
+// Tests that invalid requests are rejected.
+func TestPriceHistoryInvalid(t *testing.T) {
+	oracle := NewOracle(newTestBackend(10), Config{Blocks: 1})
+
+	tests := []struct {
+		start       rpc.BlockNumber
+		percentiles []float64
+	}{
+		{10, nil},
+		{-3, nil},
+		{0, []float64{-1}},
+		{0, []float64{101}},
+		{0, []float64{50, 10}},
+	}
+	for i, tt := range tests {
+		if _, _, _, err := oracle.PriceHistory(context.Background(), tt.start, 1, tt.percentiles); err == nil {
+			t.Errorf("test %d: invalid request accepted", i)
+		}
+	}
+}
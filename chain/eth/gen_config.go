@@ -4,12 +4,16 @@ package eth
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/eth/downloader"
+	"github.com/Bokerchain/Boker/chain/eth/filters"
 	"github.com/Bokerchain/Boker/chain/eth/gasprice"
+	"github.com/Bokerchain/Boker/chain/internal/ethapi"
+	"github.com/Bokerchain/Boker/chain/params"
 )
 
 var _ = (*configMarshaling)(nil)
@@ -20,47 +24,91 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Genesis            *core.Genesis `toml:",omitempty"`
 		NetworkId          uint64
 		SyncMode           downloader.SyncMode
-		LightServ          int  `toml:",omitempty"`
-		LightPeers         int  `toml:",omitempty"`
-		SkipBcVersionCheck bool `toml:"-"`
-		DatabaseHandles    int  `toml:"-"`
+		AddressIndex       bool   `toml:",omitempty"`
+		BalanceIndex       bool   `toml:",omitempty"`
+		TokenIndex         bool   `toml:",omitempty"`
+		InternalTxIndex    bool   `toml:",omitempty"`
+		LightServ          int    `toml:",omitempty"`
+		LightPeers         int    `toml:",omitempty"`
+		LightCapacity      uint64 `toml:",omitempty"`
+		SkipBcVersionCheck bool   `toml:"-"`
+		DatabaseHandles    int    `toml:"-"`
 		DatabaseCache      int
+		TrieCache          int
+		FreezerThreshold   uint64
 		//Validator               common.Address `toml:",omitempty"`
 		Coinbase                common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
+		MinerSlotMargin         time.Duration  `toml:",omitempty"`
+		DposWatchdog            bool           `toml:",omitempty"`
+		DposClockDrift          time.Duration  `toml:",omitempty"`
+		DposClockStrict         bool           `toml:",omitempty"`
+		DposNTPServers          []string       `toml:",omitempty"`
 		GasPrice                *big.Int
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
+		FilterLimits            filters.Limits
+		FilterQuotas            filters.Quotas
+		CallCache               ethapi.CallCacheConfig
+		RPCGasCap               uint64  `toml:",omitempty"`
+		RPCTxFeeCap             float64 `toml:",omitempty"`
 		EnablePreimageRecording bool
-		DocRoot                 string `toml:"-"`
-		PowFake                 bool   `toml:"-"`
-		PowTest                 bool   `toml:"-"`
-		PowShared               bool   `toml:"-"`
-		Dpos                    bool   `toml:"-"`
+		EnableVMFusion          bool
+		ParallelTxWorkers       int                       `toml:",omitempty"`
+		TracersDir              string                    `toml:",omitempty"`
+		DocRoot                 string                    `toml:"-"`
+		PowFake                 bool                      `toml:"-"`
+		PowTest                 bool                      `toml:"-"`
+		PowShared               bool                      `toml:"-"`
+		Dpos                    bool                      `toml:"-"`
+		Checkpoint              *params.TrustedCheckpoint `toml:",omitempty"`
+		NoCheckpoint            bool                      `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
+	enc.AddressIndex = c.AddressIndex
+	enc.BalanceIndex = c.BalanceIndex
+	enc.TokenIndex = c.TokenIndex
+	enc.InternalTxIndex = c.InternalTxIndex
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.LightCapacity = c.LightCapacity
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.TrieCache = c.TrieCache
+	enc.FreezerThreshold = c.FreezerThreshold
 	//enc.Validator = c.Validator
 	enc.Coinbase = c.Coinbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
+	enc.MinerSlotMargin = c.MinerSlotMargin
+	enc.DposWatchdog = c.DposWatchdog
+	enc.DposClockDrift = c.DposClockDrift
+	enc.DposClockStrict = c.DposClockStrict
+	enc.DposNTPServers = c.DposNTPServers
 	enc.GasPrice = c.GasPrice
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
+	enc.FilterLimits = c.FilterLimits
+	enc.FilterQuotas = c.FilterQuotas
+	enc.CallCache = c.CallCache
+	enc.RPCGasCap = c.RPCGasCap
+	enc.RPCTxFeeCap = c.RPCTxFeeCap
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.EnableVMFusion = c.EnableVMFusion
+	enc.ParallelTxWorkers = c.ParallelTxWorkers
+	enc.TracersDir = c.TracersDir
 	enc.DocRoot = c.DocRoot
 	enc.PowFake = c.PowFake
 	enc.PowTest = c.PowTest
 	enc.PowShared = c.PowShared
 	enc.Dpos = c.Dpos
+	enc.Checkpoint = c.Checkpoint
+	enc.NoCheckpoint = c.NoCheckpoint
 	return &enc, nil
 }
 
@@ -70,24 +118,46 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		AddressIndex            *bool   `toml:",omitempty"`
+		BalanceIndex            *bool   `toml:",omitempty"`
+		TokenIndex              *bool   `toml:",omitempty"`
+		InternalTxIndex         *bool   `toml:",omitempty"`
+		LightServ               *int    `toml:",omitempty"`
+		LightPeers              *int    `toml:",omitempty"`
+		LightCapacity           *uint64 `toml:",omitempty"`
+		SkipBcVersionCheck      *bool   `toml:"-"`
+		DatabaseHandles         *int    `toml:"-"`
 		DatabaseCache           *int
+		TrieCache               *int
+		FreezerThreshold        *uint64
 		Validator               *common.Address `toml:",omitempty"`
 		Coinbase                *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
+		MinerSlotMargin         *time.Duration  `toml:",omitempty"`
+		DposWatchdog            *bool           `toml:",omitempty"`
+		DposClockDrift          *time.Duration  `toml:",omitempty"`
+		DposClockStrict         *bool           `toml:",omitempty"`
+		DposNTPServers          []string        `toml:",omitempty"`
 		GasPrice                *big.Int
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
+		FilterLimits            *filters.Limits
+		FilterQuotas            *filters.Quotas
+		CallCache               *ethapi.CallCacheConfig
+		RPCGasCap               *uint64  `toml:",omitempty"`
+		RPCTxFeeCap             *float64 `toml:",omitempty"`
 		EnablePreimageRecording *bool
-		DocRoot                 *string `toml:"-"`
-		PowFake                 *bool   `toml:"-"`
-		PowTest                 *bool   `toml:"-"`
-		PowShared               *bool   `toml:"-"`
-		Dpos                    *bool   `toml:"-"`
+		EnableVMFusion          *bool
+		ParallelTxWorkers       *int                      `toml:",omitempty"`
+		TracersDir              *string                   `toml:",omitempty"`
+		DocRoot                 *string                   `toml:"-"`
+		PowFake                 *bool                     `toml:"-"`
+		PowTest                 *bool                     `toml:"-"`
+		PowShared               *bool                     `toml:"-"`
+		Dpos                    *bool                     `toml:"-"`
+		Checkpoint              *params.TrustedCheckpoint `toml:",omitempty"`
+		NoCheckpoint            *bool                     `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -102,12 +172,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SyncMode != nil {
 		c.SyncMode = *dec.SyncMode
 	}
+	if dec.AddressIndex != nil {
+		c.AddressIndex = *dec.AddressIndex
+	}
+	if dec.BalanceIndex != nil {
+		c.BalanceIndex = *dec.BalanceIndex
+	}
+	if dec.TokenIndex != nil {
+		c.TokenIndex = *dec.TokenIndex
+	}
+	if dec.InternalTxIndex != nil {
+		c.InternalTxIndex = *dec.InternalTxIndex
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.LightCapacity != nil {
+		c.LightCapacity = *dec.LightCapacity
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -117,6 +202,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.TrieCache != nil {
+		c.TrieCache = *dec.TrieCache
+	}
+	if dec.FreezerThreshold != nil {
+		c.FreezerThreshold = *dec.FreezerThreshold
+	}
 	if dec.Coinbase != nil {
 		c.Coinbase = *dec.Coinbase
 	}
@@ -126,6 +217,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.ExtraData != nil {
 		c.ExtraData = *dec.ExtraData
 	}
+	if dec.MinerSlotMargin != nil {
+		c.MinerSlotMargin = *dec.MinerSlotMargin
+	}
+	if dec.DposWatchdog != nil {
+		c.DposWatchdog = *dec.DposWatchdog
+	}
+	if dec.DposClockDrift != nil {
+		c.DposClockDrift = *dec.DposClockDrift
+	}
+	if dec.DposClockStrict != nil {
+		c.DposClockStrict = *dec.DposClockStrict
+	}
+	if dec.DposNTPServers != nil {
+		c.DposNTPServers = dec.DposNTPServers
+	}
 	if dec.GasPrice != nil {
 		c.GasPrice = dec.GasPrice
 	}
@@ -135,9 +241,33 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
+	if dec.FilterLimits != nil {
+		c.FilterLimits = *dec.FilterLimits
+	}
+	if dec.FilterQuotas != nil {
+		c.FilterQuotas = *dec.FilterQuotas
+	}
+	if dec.CallCache != nil {
+		c.CallCache = *dec.CallCache
+	}
+	if dec.RPCGasCap != nil {
+		c.RPCGasCap = *dec.RPCGasCap
+	}
+	if dec.RPCTxFeeCap != nil {
+		c.RPCTxFeeCap = *dec.RPCTxFeeCap
+	}
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.EnableVMFusion != nil {
+		c.EnableVMFusion = *dec.EnableVMFusion
+	}
+	if dec.ParallelTxWorkers != nil {
+		c.ParallelTxWorkers = *dec.ParallelTxWorkers
+	}
+	if dec.TracersDir != nil {
+		c.TracersDir = *dec.TracersDir
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -153,5 +283,11 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Dpos != nil {
 		c.Dpos = *dec.Dpos
 	}
+	if dec.Checkpoint != nil {
+		c.Checkpoint = dec.Checkpoint
+	}
+	if dec.NoCheckpoint != nil {
+		c.NoCheckpoint = *dec.NoCheckpoint
+	}
 	return nil
 }
@@ -28,9 +28,11 @@ import (
 
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/consensus"
+	"github.com/Bokerchain/Boker/chain/consensus/dpos"
 	"github.com/Bokerchain/Boker/chain/consensus/misc"
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/eth/downloader"
 	"github.com/Bokerchain/Boker/chain/eth/fetcher"
 	"github.com/Bokerchain/Boker/chain/ethdb"
@@ -49,6 +51,10 @@ const (
 	// txChanSize is the size of channel listening to TxPreEvent.
 	// The number is referenced from the size of tx pool.
 	txChanSize = 4096
+
+	// staleAnnounceDist is the distance below the local head beyond which a
+	// block announcement is considered useless.
+	staleAnnounceDist = 64
 )
 
 var (
@@ -69,6 +75,7 @@ type ProtocolManager struct {
 
 	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
 	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
+	draining  uint32 //节点正在准备停止，不再接收和转发交易
 
 	txpool      txPool
 	blockchain  *core.BlockChain
@@ -79,6 +86,8 @@ type ProtocolManager struct {
 	downloader *downloader.Downloader //负责所有向相邻个体主动发起的同步流程
 	fetcher    *fetcher.Fetcher       //累积所有其他个体发送来的有关新数据的宣布消息，并在自身对照后，安排相应的获取请求
 	peers      *peerSet               //缓存相邻个体列表
+	compact    *compactRelay          //精简区块转发的状态
+	txRequests *txRequests            //正在向其它节点请求的交易
 
 	SubProtocols []p2p.Protocol
 
@@ -117,6 +126,8 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		txsyncCh:    make(chan *txsync),
 		quitSync:    make(chan struct{}),
 	}
+	manager.compact = newCompactRelay(manager.fallbackCompact)
+	manager.txRequests = newTxRequests(manager.refetchTxs)
 
 	//判断是否允许快速同步
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
@@ -161,7 +172,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 			},
 			PeerInfo: func(id discover.NodeID) interface{} {
 				if p := manager.peers.Peer(fmt.Sprintf("%x", id[:8])); p != nil {
-					return p.Info()
+					return manager.peerInfo(p)
 				}
 				return nil
 			},
@@ -171,7 +182,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		return nil, errIncompatibleConfig
 	}
 	// Construct the different synchronisation mechanisms
-	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.penalizePeer(p2p.RequestTimeout))
 
 	validator := func(header *types.Header) error {
 		return engine.VerifyHeader(blockchain, header, true)
@@ -179,16 +190,24 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 	heighter := func() uint64 {
 		return blockchain.CurrentBlock().NumberU64()
 	}
-	inserter := func(blocks types.Blocks) (int, error) {
+	inserter := func(peer string, blocks types.Blocks) (int, error) {
 		// If fast sync is running, deny importing weird blocks
 		if atomic.LoadUint32(&manager.fastSync) == 1 {
 			log.Warn("Discarded bad propagated block", "number", blocks[0].Number(), "hash", blocks[0].Hash())
 			return 0, nil
 		}
 		atomic.StoreUint32(&manager.acceptTxs, 1) // Mark initial sync done on any fetcher import
-		return manager.blockchain.InsertChain(blocks)
+		n, err := manager.blockchain.InsertChainFromPeer(blocks, peer)
+
+		//签名无效的区块降低发送节点的信誉，反复发送的节点会被断开并禁止连接
+		if err != nil && dpos.IsSealError(err) {
+			if p := manager.peers.Peer(peer); p != nil {
+				p.Penalize(p2p.InvalidSeal)
+			}
+		}
+		return n, err
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.penalizePeer(p2p.InvalidBlock))
 
 	return manager, nil
 }
@@ -212,6 +231,16 @@ func (pm *ProtocolManager) removePeer(id string) {
 	}
 }
 
+//返回一个降低节点信誉后再断开节点的回调，供下载器和区块获取器丢弃出错的节点
+func (pm *ProtocolManager) penalizePeer(m p2p.Misbehaviour) func(id string) {
+	return func(id string) {
+		if peer := pm.peers.Peer(id); peer != nil {
+			peer.Penalize(m)
+		}
+		pm.removePeer(id)
+	}
+}
+
 //启动P2P网络
 func (pm *ProtocolManager) Start(maxPeers int) {
 
@@ -252,6 +281,8 @@ func (pm *ProtocolManager) Stop() {
 
 	// Quit fetcher, txsyncLoop.
 	close(pm.quitSync)
+	pm.txRequests.stop()
+	pm.compact.stop()
 
 	// Disconnect existing sessions.
 	// This also closes the gate for any new registrations on the peer set.
@@ -563,7 +594,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				return errResp(ErrDecode, "msg %v: %v", msg, err)
 			}
 			// Retrieve the requested state entry, stopping if enough was found
-			if entry, err := pm.chaindb.Get(hash.Bytes()); err == nil {
+			if entry, err := pm.blockchain.TrieNode(hash); err == nil {
 				data = append(data, entry)
 				bytes += len(entry)
 			}
@@ -634,8 +665,16 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
 		// Mark the hashes as present at the remote node
+		head := pm.blockchain.CurrentBlock().NumberU64()
+		stale := false
 		for _, block := range announces {
 			p.MarkBlock(block.Hash)
+			if block.Number+staleAnnounceDist < head {
+				stale = true
+			}
+		}
+		if stale {
+			p.Penalize(p2p.UselessAnnouncement)
 		}
 		// Schedule all the unknown hashes for retrieval
 		unknown := make(newBlockHashesData, 0, len(announces))
@@ -660,29 +699,53 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		// Mark the peer as owning the block and schedule it for import
 		p.MarkBlock(request.Block.Hash())
 		pm.fetcher.Enqueue(p.id, request.Block)
+		pm.updatePeerHead(p, request.Block, request.TD)
 
-		// Assuming the block is importable by the peer, but possibly not yet done so,
-		// calculate the head hash and TD that the peer truly must have.
-		var (
-			trueHead = request.Block.ParentHash()
-			trueTD   = new(big.Int).Sub(request.TD, request.Block.Difficulty())
-		)
-		// Update the peers total difficulty if better than the previous
-		if _, td := p.Head(); trueTD.Cmp(td) > 0 {
-			p.SetHead(trueHead, trueTD)
-
-			// Schedule a sync if above ours. Note, this will not fire a sync for a gap of
-			// a singe block (as the true TD is below the propagated block), however this
-			// scenario should easily be covered by the fetcher.
-			currentBlock := pm.blockchain.CurrentBlock()
-			if trueTD.Cmp(pm.blockchain.GetTd(currentBlock.Hash(), currentBlock.NumberU64())) > 0 {
-				go pm.synchronise(p)
-			}
+	case p.version >= eth64 && msg.Code == CompactBlockMsg:
+		var request compactBlockData
+		if err := msg.Decode(&request); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if request.Header == nil || request.Header.Number == nil || request.TD == nil {
+			return errResp(ErrDecode, "%v: incomplete compact block", msg)
+		}
+		return pm.handleCompactBlock(p, &request, msg.ReceivedAt)
+
+	case p.version >= eth64 && msg.Code == GetBlockTxsMsg:
+		var request getBlockTxsData
+		if err := msg.Decode(&request); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
+		return pm.handleGetBlockTxs(p, &request)
 
-	case msg.Code == TxMsg: //交易信息返回, 在我们没用同步完成之前不会接收交易信息
+	case p.version >= eth64 && msg.Code == BlockTxsMsg:
+		var response blockTxsData
+		if err := msg.Decode(&response); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		return pm.handleBlockTxs(p, &response)
+
+	case p.version >= eth64 && msg.Code == NewPooledTxHashesMsg:
+		// Transaction announcements are only acted upon once synchronised
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 || atomic.LoadUint32(&pm.draining) == 1 {
+			break
+		}
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return pm.handleTxHashes(p, hashes)
+
+	case p.version >= eth64 && msg.Code == GetPooledTxsMsg:
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return pm.handleGetPooledTxs(p, hashes)
+
+	case msg.Code == TxMsg, p.version >= eth64 && msg.Code == PooledTxsMsg: //交易信息返回, 在我们没用同步完成之前不会接收交易信息
 		// Transactions arrived, make sure we have a valid and fresh chain to handle them
-		if atomic.LoadUint32(&pm.acceptTxs) == 0 {
+		if atomic.LoadUint32(&pm.acceptTxs) == 0 || atomic.LoadUint32(&pm.draining) == 1 {
 			break
 		}
 		// Transactions can be processed, parse all of them and deliver to the pool
@@ -697,6 +760,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.MarkTransaction(tx.Hash())
 		}
+		pm.txRequests.delivered(txs)
 		pm.txpool.AddRemotes(txs)
 
 	default:
@@ -724,7 +788,7 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 		// Send the block to a subset of our peers
 		transfer := peers[:int(math.Sqrt(float64(len(peers))))]
 		for _, peer := range transfer {
-			peer.SendNewBlock(block, td)
+			pm.sendPropagatedBlock(peer, block, td)
 		}
 		log.Trace("Propagated block", "hash", hash, "recipients", len(transfer), "duration", common.PrettyDuration(time.Since(block.ReceivedAt)))
 		return
@@ -739,16 +803,26 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	}
 }
 
-//将一个交易进行广播
-func (pm *ProtocolManager) BroadcastTx(hash common.Hash, tx *types.Transaction) {
-
-	//向一批不知道它的节点广播交易
-	peers := pm.peers.PeersWithoutTx(hash)
-	//FIXME include this again: peers = peers[:int(math.Sqrt(float64(len(peers))))]
-	for _, peer := range peers {
-		peer.SendTransactions(types.Transactions{tx})
+// updatePeerHead updates the head of a peer that propagated a block, assuming
+// the block is importable by the peer but possibly not yet done so.
+func (pm *ProtocolManager) updatePeerHead(p *peer, block *types.Block, blockTd *big.Int) {
+	// Calculate the head hash and TD that the peer truly must have.
+	var (
+		trueHead = block.ParentHash()
+		trueTD   = new(big.Int).Sub(blockTd, block.Difficulty())
+	)
+	// Update the peers total difficulty if better than the previous
+	if _, td := p.Head(); trueTD.Cmp(td) > 0 {
+		p.SetHead(trueHead, trueTD)
+
+		// Schedule a sync if above ours. Note, this will not fire a sync for a gap of
+		// a singe block (as the true TD is below the propagated block), however this
+		// scenario should easily be covered by the fetcher.
+		currentBlock := pm.blockchain.CurrentBlock()
+		if trueTD.Cmp(pm.blockchain.GetTd(currentBlock.Hash(), currentBlock.NumberU64())) > 0 {
+			go pm.synchronise(p)
+		}
 	}
-	log.Trace("Broadcast transaction", "hash", hash, "recipients", len(peers))
 }
 
 //广播新挖掘出的区块(等待本节点的新挖掘出区块事件)
@@ -769,7 +843,9 @@ func (self *ProtocolManager) txBroadcastLoop() {
 	for {
 		select {
 		case event := <-self.txCh:
-			self.BroadcastTx(event.Tx.Hash(), event.Tx)
+			if atomic.LoadUint32(&self.draining) == 0 {
+				self.BroadcastTx(event.Tx.Hash(), event.Tx)
+			}
 
 		// Err() channel will be closed when unsubscribing.
 		case <-self.txSub.Err():
@@ -778,6 +854,24 @@ func (self *ProtocolManager) txBroadcastLoop() {
 	}
 }
 
+//获取节点的协议信息，补充节点最新区块的高度，以及节点密钥对应的账号是否为当前的验证者
+func (pm *ProtocolManager) peerInfo(p *peer) *PeerInfo {
+
+	info := p.Info()
+	hash, _ := p.Head()
+	if header := pm.blockchain.GetHeaderByHash(hash); header != nil {
+		number := header.Number.Uint64()
+		info.Number = &number
+	}
+	if pubkey, err := p.ID().Pubkey(); err == nil {
+		validator := crypto.PubkeyToAddress(*pubkey)
+		if dposContext := pm.blockchain.CurrentBlock().DposCtx(); dposContext != nil && dposContext.IsValidator(validator) {
+			info.Validator = &validator
+		}
+	}
+	return info
+}
+
 // EthNodeInfo represents a short summary of the Ethereum sub-protocol metadata known
 // about the host peer.
 type EthNodeInfo struct {
@@ -56,6 +56,18 @@ var (
 	miscOutTrafficMeter       = metrics.NewMeter("eth/misc/out/traffic")
 )
 
+// Meters of the eth/64 compact block relay.
+var (
+	propCompactInPacketsMeter  = metrics.NewMeter("eth/prop/compact/in/packets")
+	propCompactInTrafficMeter  = metrics.NewMeter("eth/prop/compact/in/traffic")
+	propCompactOutPacketsMeter = metrics.NewMeter("eth/prop/compact/out/packets")
+	propCompactOutTrafficMeter = metrics.NewMeter("eth/prop/compact/out/traffic")
+	reqBlockTxInPacketsMeter   = metrics.NewMeter("eth/req/blocktxs/in/packets")
+	reqBlockTxInTrafficMeter   = metrics.NewMeter("eth/req/blocktxs/in/traffic")
+	reqBlockTxOutPacketsMeter  = metrics.NewMeter("eth/req/blocktxs/out/packets")
+	reqBlockTxOutTrafficMeter  = metrics.NewMeter("eth/req/blocktxs/out/traffic")
+)
+
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
 // accumulating the above defined metrics based on the data stream contents.
 type meteredMsgReadWriter struct {
@@ -96,11 +108,15 @@ func (rw *meteredMsgReadWriter) ReadMsg() (p2p.Msg, error) {
 		packets, traffic = reqStateInPacketsMeter, reqStateInTrafficMeter
 	case rw.version >= eth63 && msg.Code == ReceiptsMsg:
 		packets, traffic = reqReceiptInPacketsMeter, reqReceiptInTrafficMeter
+	case rw.version >= eth64 && msg.Code == BlockTxsMsg:
+		packets, traffic = reqBlockTxInPacketsMeter, reqBlockTxInTrafficMeter
 
 	case msg.Code == NewBlockHashesMsg:
 		packets, traffic = propHashInPacketsMeter, propHashInTrafficMeter
 	case msg.Code == NewBlockMsg:
 		packets, traffic = propBlockInPacketsMeter, propBlockInTrafficMeter
+	case rw.version >= eth64 && msg.Code == CompactBlockMsg:
+		packets, traffic = propCompactInPacketsMeter, propCompactInTrafficMeter
 	case msg.Code == TxMsg:
 		packets, traffic = propTxnInPacketsMeter, propTxnInTrafficMeter
 	}
@@ -123,11 +139,15 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 		packets, traffic = reqStateOutPacketsMeter, reqStateOutTrafficMeter
 	case rw.version >= eth63 && msg.Code == ReceiptsMsg:
 		packets, traffic = reqReceiptOutPacketsMeter, reqReceiptOutTrafficMeter
+	case rw.version >= eth64 && msg.Code == BlockTxsMsg:
+		packets, traffic = reqBlockTxOutPacketsMeter, reqBlockTxOutTrafficMeter
 
 	case msg.Code == NewBlockHashesMsg:
 		packets, traffic = propHashOutPacketsMeter, propHashOutTrafficMeter
 	case msg.Code == NewBlockMsg:
 		packets, traffic = propBlockOutPacketsMeter, propBlockOutTrafficMeter
+	case rw.version >= eth64 && msg.Code == CompactBlockMsg:
+		packets, traffic = propCompactOutPacketsMeter, propCompactOutTrafficMeter
 	case msg.Code == TxMsg:
 		packets, traffic = propTxnOutPacketsMeter, propTxnOutTrafficMeter
 	}
@@ -27,6 +27,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/p2p"
 	"github.com/Bokerchain/Boker/chain/rlp"
+	lru "github.com/hashicorp/golang-lru"
 	"gopkg.in/fatih/set.v0"
 )
 
@@ -45,9 +46,11 @@ const (
 // PeerInfo represents a short summary of the Ethereum sub-protocol metadata known
 // about a connected peer.
 type PeerInfo struct {
-	Version    int      `json:"version"`    // Ethereum protocol version negotiated
-	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's blockchain
-	Head       string   `json:"head"`       // SHA3 hash of the peer's best owned block
+	Version    int             `json:"version"`             // Ethereum protocol version negotiated
+	Difficulty *big.Int        `json:"difficulty"`          // Total difficulty of the peer's blockchain
+	Head       string          `json:"head"`                // SHA3 hash of the peer's best owned block
+	Number     *uint64         `json:"number,omitempty"`    // Number of the peer's best owned block, if known
+	Validator  *common.Address `json:"validator,omitempty"` // Dpos validator the peer's node key belongs to, if registered
 }
 
 type peer struct {
@@ -63,19 +66,20 @@ type peer struct {
 	td   *big.Int
 	lock sync.RWMutex
 
-	knownTxs    *set.Set // Set of transaction hashes known to be known by this peer
-	knownBlocks *set.Set // Set of block hashes known to be known by this peer
+	knownTxs    *lru.Cache // Transaction hashes known to be known by this peer, least recently seen evicted first
+	knownBlocks *set.Set   // Set of block hashes known to be known by this peer
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 	id := p.ID()
+	knownTxs, _ := lru.New(maxKnownTxs)
 
 	return &peer{
 		Peer:        p,
 		rw:          rw,
 		version:     version,
 		id:          fmt.Sprintf("%x", id[:8]),
-		knownTxs:    set.New(),
+		knownTxs:    knownTxs,
 		knownBlocks: set.New(),
 	}
 }
@@ -123,22 +127,41 @@ func (p *peer) MarkBlock(hash common.Hash) {
 // MarkTransaction marks a transaction as known for the peer, ensuring that it
 // will never be propagated to this particular peer.
 func (p *peer) MarkTransaction(hash common.Hash) {
-	// If we reached the memory allowance, drop a previously known transaction hash
-	for p.knownTxs.Size() >= maxKnownTxs {
-		p.knownTxs.Pop()
-	}
-	p.knownTxs.Add(hash)
+	p.knownTxs.Add(hash, nil)
 }
 
 // SendTransactions sends transactions to the peer and includes the hashes
 // in its transaction hash set for future reference.
 func (p *peer) SendTransactions(txs types.Transactions) error {
 	for _, tx := range txs {
-		p.knownTxs.Add(tx.Hash())
+		p.knownTxs.Add(tx.Hash(), nil)
 	}
 	return p2p.Send(p.rw, TxMsg, txs)
 }
 
+// SendTxHashes announces the availability of a number of transactions through
+// a hash notification, leaving the peer to request the ones it is missing.
+func (p *peer) SendTxHashes(hashes []common.Hash) error {
+	for _, hash := range hashes {
+		p.knownTxs.Add(hash, nil)
+	}
+	return p2p.Send(p.rw, NewPooledTxHashesMsg, hashes)
+}
+
+// RequestTxs fetches a batch of announced transactions from the peer's pool.
+func (p *peer) RequestTxs(hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of pooled transactions", "count", len(hashes))
+	return p2p.Send(p.rw, GetPooledTxsMsg, hashes)
+}
+
+// SendPooledTxs sends a batch of requested pooled transactions to the peer.
+func (p *peer) SendPooledTxs(txs []*types.Transaction) error {
+	for _, tx := range txs {
+		p.knownTxs.Add(tx.Hash(), nil)
+	}
+	return p2p.Send(p.rw, PooledTxsMsg, txs)
+}
+
 // SendNewBlockHashes announces the availability of a number of blocks through
 // a hash notification.
 func (p *peer) SendNewBlockHashes(hashes []common.Hash, numbers []uint64) error {
@@ -159,6 +182,32 @@ func (p *peer) SendNewBlock(block *types.Block, td *big.Int) error {
 	return p2p.Send(p.rw, NewBlockMsg, []interface{}{block, td})
 }
 
+// SendCompactBlock propagates a block to a remote peer as its header and the
+// hashes of its transactions, leaving the peer to rebuild the block from its
+// own transaction pool.
+func (p *peer) SendCompactBlock(block *types.Block, td *big.Int) error {
+	p.knownBlocks.Add(block.Hash())
+
+	txs := block.Transactions()
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	return p2p.Send(p.rw, CompactBlockMsg, &compactBlockData{Header: block.Header(), TxHashes: hashes, TD: td})
+}
+
+// RequestBlockTxs fetches the transactions of a compact block at the given
+// indexes, which were missing from the local transaction pool.
+func (p *peer) RequestBlockTxs(hash common.Hash, indexes []uint64) error {
+	p.Log().Debug("Fetching missing compact block transactions", "hash", hash, "count", len(indexes))
+	return p2p.Send(p.rw, GetBlockTxsMsg, &getBlockTxsData{Hash: hash, Indexes: indexes})
+}
+
+// SendBlockTxs sends the requested transactions of a compact block.
+func (p *peer) SendBlockTxs(hash common.Hash, txs []*types.Transaction) error {
+	return p2p.Send(p.rw, BlockTxsMsg, &blockTxsData{Hash: hash, Txs: txs})
+}
+
 // SendBlockHeaders sends a batch of block headers to the remote peer.
 func (p *peer) SendBlockHeaders(headers []*types.Header) error {
 	return p2p.Send(p.rw, BlockHeadersMsg, headers)
@@ -380,7 +429,7 @@ func (ps *peerSet) PeersWithoutTx(hash common.Hash) []*peer {
 
 	list := make([]*peer, 0, len(ps.peers))
 	for _, p := range ps.peers {
-		if !p.knownTxs.Has(hash) {
+		if !p.knownTxs.Contains(hash) {
 			list = append(list, p)
 		}
 	}
@@ -32,16 +32,17 @@ import (
 const (
 	eth62 = 62
 	eth63 = 63
+	eth64 = 64
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "eth"
 
 // Supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{eth63, eth62}
+var ProtocolVersions = []uint{eth64, eth63, eth62}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{23, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 //协议消息大小的最大上限
 
@@ -62,6 +63,16 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+	//eth/64新增的精简区块转发消息
+	CompactBlockMsg = 0x11 //区块头加交易Hash的区块广播
+	GetBlockTxsMsg  = 0x12 //请求精简区块中本地交易池缺失的交易
+	BlockTxsMsg     = 0x13 //返回请求的交易
+
+	//eth/64新增的交易Hash通知消息
+	NewPooledTxHashesMsg = 0x14 //通知对端本地交易池中新的交易Hash
+	GetPooledTxsMsg      = 0x15 //请求通知中本地缺失的交易
+	PooledTxsMsg         = 0x16 //返回请求的交易
 )
 
 type errCode int
@@ -103,6 +114,10 @@ type txPool interface {
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
 
+	// Get should return the pooled transaction with the given hash, or nil if
+	// the pool does not contain it.
+	Get(hash common.Hash) *types.Transaction
+
 	// SubscribeTxPreEvent should return an event subscription of
 	// TxPreEvent and send events to the given channel.
 	SubscribeTxPreEvent(chan<- core.TxPreEvent) event.Subscription
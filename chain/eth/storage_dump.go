@@ -0,0 +1,233 @@
+package eth
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/state"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/rpc"
+	"github.com/Bokerchain/Boker/chain/trie"
+)
+
+//导出任务状态
+const (
+	dumpRunning   = "running"
+	dumpDone      = "done"
+	dumpFailed    = "failed"
+	dumpCancelled = "cancelled"
+)
+
+var (
+	errUnknownDump   = errors.New("unknown storage dump job") //导出任务不存在
+	errDumpCancelled = errors.New("storage dump cancelled")   //导出被取消
+)
+
+//合约存储导出任务的进度
+type StorageDumpStatus struct {
+	ID       uint64         `json:"id"`
+	File     string         `json:"file"`
+	Contract common.Address `json:"contract"`
+	Block    uint64         `json:"block"`
+	Root     common.Hash    `json:"root"`            //合约的存储根
+	State    string         `json:"state"`           //running、done、failed或cancelled
+	Error    string         `json:"error,omitempty"` //任务失败的原因
+	Started  time.Time      `json:"started"`
+	Finished *time.Time     `json:"finished,omitempty"`
+	Entries  uint64         `json:"entries"`           //已导出的存储条数
+	LastKey  *common.Hash   `json:"lastKey,omitempty"` //最后导出的存储位置的哈希
+	Progress float64        `json:"progress"`          //按哈希空间估计的导出进度(0到1)
+}
+
+//导出文件中的一条存储，每行一条JSON记录
+type storageDumpEntry struct {
+	Hash  common.Hash  `json:"hash"`
+	Key   *common.Hash `json:"key,omitempty"` //存储位置的原像，节点没有记录时为空
+	Value common.Hash  `json:"value"`
+}
+
+//后台运行的合约存储导出任务
+type storageDumpJob struct {
+	lock   sync.Mutex
+	status StorageDumpStatus
+	cancel chan struct{}
+	once   sync.Once
+}
+
+//合约存储导出任务的集合
+type storageDumps struct {
+	lock   sync.Mutex
+	nextID uint64
+	jobs   map[uint64]*storageDumpJob
+}
+
+//将合约在指定区块(为空时使用最新区块)的全部存储导出到节点本地文件，导出在后台运行，返回任务编号用于查询进度和取消
+func (api *PrivateDebugAPI) DumpStorage(contract common.Address, blockNr *rpc.BlockNumber, file string) (uint64, error) {
+
+	var block *types.Block
+	if blockNr == nil || *blockNr == rpc.LatestBlockNumber || *blockNr == rpc.PendingBlockNumber {
+		block = api.eth.blockchain.CurrentBlock()
+	} else {
+		block = api.eth.blockchain.GetBlockByNumber(uint64(*blockNr))
+	}
+	if block == nil {
+		return 0, fmt.Errorf("block #%d not found", *blockNr)
+	}
+	statedb, err := api.eth.BlockChain().StateAt(block.Root())
+	if err != nil {
+		return 0, err
+	}
+	st := statedb.StorageTrie(contract)
+	if st == nil {
+		return 0, fmt.Errorf("account %x doesn't exist", contract)
+	}
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	api.dumps.lock.Lock()
+	defer api.dumps.lock.Unlock()
+
+	api.dumps.nextID++
+	job := &storageDumpJob{
+		status: StorageDumpStatus{
+			ID:       api.dumps.nextID,
+			File:     file,
+			Contract: contract,
+			Block:    block.NumberU64(),
+			Root:     st.Hash(),
+			State:    dumpRunning,
+			Started:  time.Now(),
+		},
+		cancel: make(chan struct{}),
+	}
+	if api.dumps.jobs == nil {
+		api.dumps.jobs = make(map[uint64]*storageDumpJob)
+	}
+	api.dumps.jobs[job.status.ID] = job
+
+	go func() {
+		err := job.run(st, out)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		job.finish(err)
+	}()
+	return job.status.ID, nil
+}
+
+//查询合约存储导出任务的进度
+func (api *PrivateDebugAPI) DumpStorageStatus(id uint64) (*StorageDumpStatus, error) {
+
+	api.dumps.lock.Lock()
+	job := api.dumps.jobs[id]
+	api.dumps.lock.Unlock()
+
+	if job == nil {
+		return nil, errUnknownDump
+	}
+	return job.progress(), nil
+}
+
+//取消正在运行的合约存储导出任务，已经写入文件的存储会保留
+func (api *PrivateDebugAPI) CancelDumpStorage(id uint64) (bool, error) {
+
+	api.dumps.lock.Lock()
+	job := api.dumps.jobs[id]
+	api.dumps.lock.Unlock()
+
+	if job == nil {
+		return false, errUnknownDump
+	}
+	if job.progress().State != dumpRunning {
+		return false, nil
+	}
+	job.once.Do(func() { close(job.cancel) })
+	return true, nil
+}
+
+//遍历存储树并逐条写入文件，每导出maxStorageRangeResult条更新一次进度并检查是否被取消
+func (job *storageDumpJob) run(st state.Trie, out *os.File) error {
+
+	writer := bufio.NewWriter(out)
+	encoder := json.NewEncoder(writer)
+
+	it := trie.NewIterator(st.NodeIterator(nil))
+	for done := false; !done; {
+		select {
+		case <-job.cancel:
+			writer.Flush()
+			return errDumpCancelled
+		default:
+		}
+		var (
+			count uint64
+			last  common.Hash
+		)
+		for count < maxStorageRangeResult {
+			if !it.Next() {
+				done = true
+				break
+			}
+			entry := storageDumpEntry{Hash: common.BytesToHash(it.Key), Value: common.BytesToHash(it.Value)}
+			if preimage := st.GetKey(it.Key); preimage != nil {
+				key := common.BytesToHash(preimage)
+				entry.Key = &key
+			}
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+			last = entry.Hash
+			count++
+		}
+		if it.Err != nil {
+			return it.Err
+		}
+		if count > 0 {
+			job.lock.Lock()
+			job.status.Entries += count
+			job.status.LastKey = &last
+			job.status.Progress = float64(last[0]) / 256
+			job.lock.Unlock()
+		}
+	}
+	return writer.Flush()
+}
+
+//返回任务进度的拷贝
+func (job *storageDumpJob) progress() *StorageDumpStatus {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	status := job.status
+	return &status
+}
+
+//记录任务的结束状态
+func (job *storageDumpJob) finish(err error) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	now := time.Now()
+	job.status.Finished = &now
+	switch {
+	case err == errDumpCancelled:
+		job.status.State = dumpCancelled
+	case err != nil:
+		job.status.State = dumpFailed
+		job.status.Error = err.Error()
+	default:
+		job.status.State = dumpDone
+		job.status.Progress = 1
+	}
+	log.Info("Storage dump finished", "id", job.status.ID, "contract", job.status.Contract, "file", job.status.File,
+		"state", job.status.State, "entries", job.status.Entries, "err", err)
+}
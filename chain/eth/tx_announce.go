@@ -0,0 +1,235 @@
+package eth
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
+)
+
+const (
+	maxTxAnnounces   = 4096            //单条通知消息中最多处理的交易Hash数量
+	maxTxRequests    = 256             //单次向节点请求的交易数量上限
+	maxTxRequested   = 16384           //同时在请求中的交易数量上限，超出后新通知的交易不再请求
+	maxTxAnnouncers  = 4               //每笔交易最多记录的备用通知节点数量
+	txRequestTimeout = 5 * time.Second //请求的交易在这段时间内没有到达时向其它通知过的节点重新请求
+)
+
+var (
+	txAnnounceInMeter     = metrics.NewMeter("eth/announce/txs/in")      //收到的交易Hash通知数量
+	txAnnounceOutMeter    = metrics.NewMeter("eth/announce/txs/out")     //发出的交易Hash通知数量
+	txRequestOutMeter     = metrics.NewMeter("eth/announce/txs/req")     //向对端请求的交易数量
+	txKnownDropMeter      = metrics.NewMeter("eth/announce/txs/dup")     //本地已有、已经在请求中或者超出请求上限的交易Hash数量
+	txRequestTimeoutMeter = metrics.NewMeter("eth/announce/txs/timeout") //请求超时并且没有其它节点可以重新请求的交易数量
+)
+
+//正在请求的交易，记录请求的节点以及其它通知过这笔交易的节点
+type txRequest struct {
+	peer       *peer     //当前请求的节点
+	alternates []*peer   //其它通知过这笔交易的节点，请求超时后依次向它们重新请求
+	deadline   time.Time //请求超时的时间
+}
+
+//记录已经向其它节点请求的交易，避免同一笔交易从多个节点重复拉取，请求超时后由定时器向其它通知过的节点重新请求
+type txRequests struct {
+	requested map[common.Hash]*txRequest
+	timeout   time.Duration
+	timer     *time.Timer                         //最早的请求超时时触发，没有请求时为nil
+	fetch     func(p *peer, hashes []common.Hash) //向备用节点重新请求交易
+	closed    bool
+	lock      sync.Mutex
+}
+
+func newTxRequests(fetch func(p *peer, hashes []common.Hash)) *txRequests {
+	return &txRequests{
+		requested: make(map[common.Hash]*txRequest),
+		timeout:   txRequestTimeout,
+		fetch:     fetch,
+	}
+}
+
+//过滤出需要向节点请求的交易Hash并记录请求，已经在请求中的交易把节点记为备用节点
+func (r *txRequests) schedule(p *peer, hashes []common.Hash) []common.Hash {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	deadline := time.Now().Add(r.timeout)
+	var fetch []common.Hash
+	for _, hash := range hashes {
+		if req, ok := r.requested[hash]; ok {
+			if req.peer != p && len(req.alternates) < maxTxAnnouncers && !containsPeer(req.alternates, p) {
+				req.alternates = append(req.alternates, p)
+			}
+			continue
+		}
+		if len(r.requested) >= maxTxRequested {
+			continue
+		}
+		r.requested[hash] = &txRequest{peer: p, deadline: deadline}
+		fetch = append(fetch, hash)
+	}
+	if len(r.requested) > 0 && r.timer == nil && !r.closed {
+		r.timer = time.AfterFunc(r.timeout, r.expire)
+	}
+	return fetch
+}
+
+//定时器触发时处理超时的请求：有备用节点的改为向备用节点请求，否则丢弃请求记录，之后重新设置定时器
+func (r *txRequests) expire() {
+	r.lock.Lock()
+
+	var (
+		now     = time.Now()
+		next    time.Time
+		refetch = make(map[*peer][]common.Hash)
+	)
+	for hash, req := range r.requested {
+		if now.Before(req.deadline) {
+			if next.IsZero() || req.deadline.Before(next) {
+				next = req.deadline
+			}
+			continue
+		}
+		if len(req.alternates) == 0 {
+			txRequestTimeoutMeter.Mark(1)
+			delete(r.requested, hash)
+			continue
+		}
+		req.peer, req.alternates = req.alternates[0], req.alternates[1:]
+		req.deadline = now.Add(r.timeout)
+		refetch[req.peer] = append(refetch[req.peer], hash)
+		if next.IsZero() || req.deadline.Before(next) {
+			next = req.deadline
+		}
+	}
+	r.timer = nil
+	if !next.IsZero() && !r.closed {
+		r.timer = time.AfterFunc(next.Sub(now), r.expire)
+	}
+	r.lock.Unlock()
+
+	for p, hashes := range refetch {
+		r.fetch(p, hashes)
+	}
+}
+
+//交易到达后删除请求记录
+func (r *txRequests) delivered(txs []*types.Transaction) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, tx := range txs {
+		delete(r.requested, tx.Hash())
+	}
+}
+
+//停止定时器，不再重新请求
+func (r *txRequests) stop() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.closed = true
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+}
+
+func containsPeer(peers []*peer, p *peer) bool {
+	for _, peer := range peers {
+		if peer == p {
+			return true
+		}
+	}
+	return false
+}
+
+//广播交易，随机选取节点数量平方根个节点发送完整交易，其余支持eth/64的节点只发送交易Hash，由对端按需拉取
+func (pm *ProtocolManager) BroadcastTx(hash common.Hash, tx *types.Transaction) {
+
+	//向一批不知道它的节点广播交易
+	peers := pm.peers.PeersWithoutTx(hash)
+	direct := int(math.Sqrt(float64(len(peers))))
+	for i, peer := range peers {
+		if i < direct || peer.version < eth64 {
+			peer.SendTransactions(types.Transactions{tx})
+			continue
+		}
+		peer.SendTxHashes([]common.Hash{hash})
+		txAnnounceOutMeter.Mark(1)
+	}
+	log.Trace("Broadcast transaction", "hash", hash, "recipients", len(peers), "direct", direct)
+}
+
+//处理对端的交易Hash通知，向其请求本地交易池中没有并且没有正在从其它节点请求的交易
+func (pm *ProtocolManager) handleTxHashes(p *peer, hashes []common.Hash) error {
+
+	if len(hashes) > maxTxAnnounces {
+		hashes = hashes[:maxTxAnnounces]
+	}
+	txAnnounceInMeter.Mark(int64(len(hashes)))
+
+	unknown := make([]common.Hash, 0, len(hashes))
+	for _, hash := range hashes {
+		p.MarkTransaction(hash)
+		if pm.txpool.Get(hash) == nil {
+			unknown = append(unknown, hash)
+		}
+	}
+	fetch := pm.txRequests.schedule(p, unknown)
+	txKnownDropMeter.Mark(int64(len(hashes) - len(fetch)))
+
+	return pm.requestTxs(p, fetch)
+}
+
+//分批向节点请求交易
+func (pm *ProtocolManager) requestTxs(p *peer, hashes []common.Hash) error {
+
+	for len(hashes) > 0 {
+		batch := hashes
+		if len(batch) > maxTxRequests {
+			batch = batch[:maxTxRequests]
+		}
+		hashes = hashes[len(batch):]
+
+		txRequestOutMeter.Mark(int64(len(batch)))
+		if err := p.RequestTxs(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//请求超时后向备用节点重新请求交易，节点已经断开时等待下一次超时换用其它节点
+func (pm *ProtocolManager) refetchTxs(p *peer, hashes []common.Hash) {
+
+	if pm.peers.Peer(p.id) == nil {
+		return
+	}
+	if err := pm.requestTxs(p, hashes); err != nil {
+		p.Log().Debug("Failed to re-request transactions", "count", len(hashes), "err", err)
+	}
+}
+
+//返回对端请求的交易池中的交易，本地已经没有的交易直接跳过
+func (pm *ProtocolManager) handleGetPooledTxs(p *peer, hashes []common.Hash) error {
+
+	var (
+		txs   []*types.Transaction
+		bytes int
+	)
+	for _, hash := range hashes {
+		if len(txs) >= maxTxRequests || bytes >= softResponseLimit {
+			break
+		}
+		if tx := pm.txpool.Get(hash); tx != nil {
+			txs = append(txs, tx)
+			bytes += int(tx.Size())
+		}
+	}
+	return p.SendPooledTxs(txs)
+}
@@ -0,0 +1,152 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/eth/downloader"
+	"github.com/Bokerchain/Boker/chain/p2p"
+)
+
+//等待交易被加入交易池
+func waitTxAdded(t *testing.T, txAdded <-chan []*types.Transaction, tx *types.Transaction) {
+
+	select {
+	case added := <-txAdded:
+		if len(added) != 1 || added[0].Hash() != tx.Hash() {
+			t.Fatalf("added transactions mismatch: have %v, want %x", added, tx.Hash())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("transaction not added within 2 seconds")
+	}
+}
+
+//正在请求的交易数量
+func pendingTxRequests(pm *ProtocolManager) int {
+	pm.txRequests.lock.Lock()
+	defer pm.txRequests.lock.Unlock()
+	return len(pm.txRequests.requested)
+}
+
+//收到交易Hash通知后向通知的节点请求交易，交易到达后加入交易池并删除请求记录
+func TestTxAnnounceFetch(t *testing.T) {
+
+	txAdded := make(chan []*types.Transaction)
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+	pm.acceptTxs = 1
+	p, _ := newTestPeer("peer", eth64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(p.app, NewPooledTxHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, GetPooledTxsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("transaction not requested: %v", err)
+	}
+	if err := p2p.Send(p.app, PooledTxsMsg, []*types.Transaction{tx}); err != nil {
+		t.Fatalf("failed to deliver: %v", err)
+	}
+	waitTxAdded(t, txAdded, tx)
+
+	if n := pendingTxRequests(pm); n != 0 {
+		t.Errorf("requests left after delivery: %d", n)
+	}
+}
+
+//请求的节点不返回交易时，超时后向其它通知过这笔交易的节点重新请求
+func TestTxAnnounceWithholdingPeer(t *testing.T) {
+
+	txAdded := make(chan []*types.Transaction)
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, txAdded)
+	pm.acceptTxs = 1
+	pm.txRequests.timeout = 100 * time.Millisecond
+	withholder, _ := newTestPeer("withholder", eth64, pm, true)
+	honest, _ := newTestPeer("honest", eth64, pm, true)
+	defer pm.Stop()
+	defer withholder.close()
+	defer honest.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(withholder.app, NewPooledTxHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+	if err := p2p.ExpectMsg(withholder.app, GetPooledTxsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("transaction not requested: %v", err)
+	}
+
+	//同一笔交易正在请求中，第二个节点的通知只记为备用节点，超时后才向它请求
+	announced := time.Now()
+	if err := p2p.Send(honest.app, NewPooledTxHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+	if err := p2p.ExpectMsg(honest.app, GetPooledTxsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("transaction not re-requested: %v", err)
+	}
+	if elapsed := time.Since(announced); elapsed < pm.txRequests.timeout/2 {
+		t.Errorf("transaction re-requested before timeout: %v", elapsed)
+	}
+	if err := p2p.Send(honest.app, PooledTxsMsg, []*types.Transaction{tx}); err != nil {
+		t.Fatalf("failed to deliver: %v", err)
+	}
+	waitTxAdded(t, txAdded, tx)
+}
+
+//没有备用节点的请求超时后被删除，可以重新请求
+func TestTxAnnounceExpire(t *testing.T) {
+
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.acceptTxs = 1
+	pm.txRequests.timeout = 50 * time.Millisecond
+	p, _ := newTestPeer("peer", eth64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	tx := newTestTransaction(testAccount, 0, 0)
+	if err := p2p.Send(p.app, NewPooledTxHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, GetPooledTxsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("transaction not requested: %v", err)
+	}
+	for start := time.Now(); pendingTxRequests(pm) != 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("request not expired")
+		}
+	}
+	if err := p2p.Send(p.app, NewPooledTxHashesMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("failed to announce: %v", err)
+	}
+	if err := p2p.ExpectMsg(p.app, GetPooledTxsMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatalf("expired transaction not requested again: %v", err)
+	}
+}
+
+//正在请求的交易数量和每笔交易的备用节点数量都有上限
+func TestTxRequestsLimits(t *testing.T) {
+
+	r := newTxRequests(func(*peer, []common.Hash) {})
+	defer r.stop()
+
+	first := new(peer)
+	hashes := make([]common.Hash, maxTxRequested+1)
+	for i := range hashes {
+		hashes[i] = common.BigToHash(big.NewInt(int64(i)))
+	}
+	if fetch := r.schedule(first, hashes); len(fetch) != maxTxRequested {
+		t.Errorf("scheduled requests: have %d, want %d", len(fetch), maxTxRequested)
+	}
+	for i := 0; i < maxTxAnnouncers+2; i++ {
+		if fetch := r.schedule(new(peer), hashes[:1]); len(fetch) != 0 {
+			t.Errorf("requested transaction scheduled again")
+		}
+	}
+	r.schedule(first, hashes[:1])
+	if alternates := r.requested[hashes[0]].alternates; len(alternates) != maxTxAnnouncers || containsPeer(alternates, first) {
+		t.Errorf("alternates mismatch: have %d, want %d", len(alternates), maxTxAnnouncers)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"math/big"
 
 	"github.com/Bokerchain/Boker/chain"
-	_ "github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/core/types"
@@ -249,6 +249,10 @@ type rpcProgress struct {
 	HighestBlock  hexutil.Uint64
 	PulledStates  hexutil.Uint64
 	KnownStates   hexutil.Uint64
+
+	PulledDposStates  hexutil.Uint64
+	KnownDposStates   hexutil.Uint64
+	IrreversibleBlock hexutil.Uint64
 }
 
 //检索同步算法当前的进度，如果当前没有同步操作，则返回nil
@@ -273,6 +277,10 @@ func (ec *Client) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, err
 		HighestBlock:  uint64(progress.HighestBlock),
 		PulledStates:  uint64(progress.PulledStates),
 		KnownStates:   uint64(progress.KnownStates),
+
+		PulledDposStates:  uint64(progress.PulledDposStates),
+		KnownDposStates:   uint64(progress.KnownDposStates),
+		IrreversibleBlock: uint64(progress.IrreversibleBlock),
 	}, nil
 }
 
@@ -296,6 +304,15 @@ func (ec *Client) NetworkID(ctx context.Context) (*big.Int, error) {
 	return version, nil
 }
 
+//返回交易重放保护使用的链ID
+func (ec *Client) ChainID(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := ec.c.CallContext(ctx, &result, "eth_chainId"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
 //返回指定账户的余额（单位wei），如果是nil则从最新的块中获取
 func (ec *Client) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
 	var result hexutil.Big
@@ -414,6 +431,34 @@ func (ec *Client) PendingCallContract(ctx context.Context, msg ethereum.CallMsg)
 	return hex, nil
 }
 
+//在一次请求中基于同一个区块状态执行多个合约调用，返回每个调用的结果或者错误
+func (ec *Client) CallContractBatch(ctx context.Context, msgs []ethereum.CallMsg, blockNumber *big.Int) ([][]byte, []error, error) {
+
+	args := make([]interface{}, len(msgs))
+	for i, msg := range msgs {
+		args[i] = toCallArg(msg)
+	}
+	var results []struct {
+		Output hexutil.Bytes `json:"output"`
+		Error  string        `json:"error"`
+	}
+	if err := ec.c.CallContext(ctx, &results, "eth_callBatch", args, toBlockNumArg(blockNumber)); err != nil {
+		return nil, nil, err
+	}
+	if len(results) != len(msgs) {
+		return nil, nil, fmt.Errorf("batch call returned %d results for %d calls", len(results), len(msgs))
+	}
+	outputs, errs := make([][]byte, len(results)), make([]error, len(results))
+	for i, result := range results {
+		if result.Error != "" {
+			errs[i] = errors.New(result.Error)
+			continue
+		}
+		outputs[i] = result.Output
+	}
+	return outputs, errs, nil
+}
+
 //返回当前执行交易建议的Gas价格
 func (ec *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 	var hex hexutil.Big
@@ -564,5 +609,8 @@ func toCallArg(msg ethereum.CallMsg) interface{} {
 	if len(msg.Extra) > 0 {
 		arg["extra"] = hexutil.Bytes(msg.Extra)
 	}
+	if msg.TxType != protocol.Binary {
+		arg["txType"] = msg.TxType
+	}
 	return arg
 }
@@ -0,0 +1,288 @@
+// +build badger
+
+package ethdb
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/options"
+)
+
+const (
+	badgerGCInterval     = 5 * time.Minute //回收value log的间隔
+	badgerGCDiscardRatio = 0.5             //value log文件中无效数据超过该比例时回收
+	badgerMemtableSize   = 64              //每个memtable的大小(MB)
+)
+
+//Badger不允许空键并保留了"!badger!"开头的键，所有的键都加上该前缀后保存
+var badgerKeyPrefix = []byte{'e'}
+
+func badgerKey(key []byte) []byte {
+	return append(append(make([]byte, 0, len(badgerKeyPrefix)+len(key)), badgerKeyPrefix...), key...)
+}
+
+func init() {
+	RegisterEngine(&Engine{
+		Name: EngineBadger,
+		Open: func(file string, cache int, handles int) (Database, error) {
+			return NewBadgerDatabase(file, cache)
+		},
+		Detect: func(dir string) bool {
+			return fileExist(filepath.Join(dir, "MANIFEST")) && !fileExist(filepath.Join(dir, "CURRENT"))
+		},
+	})
+}
+
+//使用Badger作为存储引擎的数据库，键和值分开存储，写入放大比LevelDB小
+type BadgerDatabase struct {
+	fn string
+	db *badger.DB
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+
+	log log.Logger
+}
+
+//打开或者创建Badger数据库，cache决定memtable的数量
+func NewBadgerDatabase(file string, cache int) (*BadgerDatabase, error) {
+	logger := log.New("database", file)
+
+	memtables := cache / 4 / badgerMemtableSize
+	if memtables < 2 {
+		memtables = 2
+	}
+	logger.Info("Allocated memtables", "cache", cache, "memtables", memtables)
+
+	opts := badger.DefaultOptions(file).
+		WithLogger(badgerLogger{logger}).
+		WithTruncate(true).
+		WithNumMemtables(memtables).
+		WithMaxTableSize(badgerMemtableSize << 20).
+		WithTableLoadingMode(options.MemoryMap).
+		WithValueLogLoadingMode(options.FileIO)
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	bdb := &BadgerDatabase{
+		fn:   file,
+		db:   db,
+		quit: make(chan struct{}),
+		log:  logger,
+	}
+	bdb.wg.Add(1)
+	go bdb.gc()
+
+	return bdb, nil
+}
+
+func (db *BadgerDatabase) Path() string {
+	return db.fn
+}
+
+func (db *BadgerDatabase) Put(key []byte, value []byte) error {
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(badgerKey(key), common.CopyBytes(value))
+	})
+}
+
+func (db *BadgerDatabase) Has(key []byte) (bool, error) {
+	err := db.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(badgerKey(key))
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (db *BadgerDatabase) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := db.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(badgerKey(key))
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (db *BadgerDatabase) Delete(key []byte) error {
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(badgerKey(key))
+	})
+}
+
+func (db *BadgerDatabase) Close() {
+	close(db.quit)
+	db.wg.Wait()
+
+	if err := db.db.Close(); err != nil {
+		db.log.Error("Failed to close database", "err", err)
+	} else {
+		db.log.Info("Database closed")
+	}
+}
+
+func (db *BadgerDatabase) NewBatch() Batch {
+	return &badgerBatch{db: db.db}
+}
+
+//返回Badger实例
+func (db *BadgerDatabase) Badger() *badger.DB {
+	return db.db
+}
+
+//定期回收value log中的无效数据
+func (db *BadgerDatabase) gc() {
+	defer db.wg.Done()
+
+	ticker := time.NewTicker(badgerGCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for db.db.RunValueLogGC(badgerGCDiscardRatio) == nil {
+			}
+		case <-db.quit:
+			return
+		}
+	}
+}
+
+func (db *BadgerDatabase) Iterate(prefix []byte) Iterator {
+	txn := db.db.NewTransaction(false)
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = badgerKey(prefix)
+	it := txn.NewIterator(opts)
+	it.Rewind()
+	return &badgerIterator{txn: txn, it: it}
+}
+
+//遍历一个只读事务中指定前缀的键值对
+type badgerIterator struct {
+	txn     *badger.Txn
+	it      *badger.Iterator
+	started bool
+	value   []byte
+	err     error
+}
+
+func (it *badgerIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.started {
+		it.it.Next()
+	}
+	it.started = true
+	if !it.it.Valid() {
+		return false
+	}
+	it.value, it.err = it.it.Item().ValueCopy(it.value[:0])
+	return it.err == nil
+}
+
+func (it *badgerIterator) Key() []byte   { return it.it.Item().KeyCopy(nil)[len(badgerKeyPrefix):] }
+func (it *badgerIterator) Value() []byte { return it.value }
+func (it *badgerIterator) Error() error  { return it.err }
+
+func (it *badgerIterator) Release() {
+	it.it.Close()
+	it.txn.Discard()
+}
+
+//压缩全部LSM层级并回收value log中的无效数据
+func (db *BadgerDatabase) Compact() error {
+	if err := db.db.Flatten(runtime.NumCPU()); err != nil {
+		return err
+	}
+	for db.db.RunValueLogGC(badgerGCDiscardRatio) == nil {
+	}
+	return nil
+}
+
+//返回LSM树和value log的大小以及每一层的表数量
+func (db *BadgerDatabase) Stat() (string, error) {
+	lsm, vlog := db.db.Size()
+	var levels []int
+	for _, table := range db.db.Tables(false) {
+		for len(levels) <= table.Level {
+			levels = append(levels, 0)
+		}
+		levels[table.Level]++
+	}
+	stats := fmt.Sprintf("LSM size: %v\nValue log size: %v\n", common.StorageSize(lsm), common.StorageSize(vlog))
+	for level, tables := range levels {
+		stats += fmt.Sprintf("Level %d: %d tables\n", level, tables)
+	}
+	return stats, nil
+}
+
+//在内存中缓存写入，Write时通过Badger的WriteBatch一次写入
+type badgerBatch struct {
+	db   *badger.DB
+	keys [][]byte
+	vals [][]byte
+	size int
+}
+
+func (b *badgerBatch) Put(key, value []byte) error {
+	b.keys = append(b.keys, badgerKey(key))
+	b.vals = append(b.vals, common.CopyBytes(value))
+	b.size += len(value)
+	return nil
+}
+
+func (b *badgerBatch) Write() error {
+	wb := b.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	for i, key := range b.keys {
+		if err := wb.Set(key, b.vals[i]); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
+func (b *badgerBatch) ValueSize() int {
+	return b.size
+}
+
+//将Badger的日志输出到节点日志
+type badgerLogger struct {
+	log log.Logger
+}
+
+func (l badgerLogger) Errorf(format string, args ...interface{}) {
+	l.log.Error(fmt.Sprintf(format, args...))
+}
+
+func (l badgerLogger) Warningf(format string, args ...interface{}) {
+	l.log.Warn(fmt.Sprintf(format, args...))
+}
+
+func (l badgerLogger) Infof(format string, args ...interface{}) {
+	l.log.Debug(fmt.Sprintf(format, args...))
+}
+
+func (l badgerLogger) Debugf(format string, args ...interface{}) {
+	l.log.Trace(fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,63 @@
+// +build badger
+
+package ethdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/ethdb"
+)
+
+func newTestBadger() (*ethdb.BadgerDatabase, func()) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "ethdb_test_")
+	if err != nil {
+		panic("failed to create test file: " + err.Error())
+	}
+	db, err := ethdb.NewBadgerDatabase(dirname, 0)
+	if err != nil {
+		panic("failed to create test database: " + err.Error())
+	}
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dirname)
+	}
+}
+
+func TestBadger_PutGet(t *testing.T) {
+	db, remove := newTestBadger()
+	defer remove()
+	testPutGet(db, t)
+}
+
+func TestBadger_ParallelPutGet(t *testing.T) {
+	db, remove := newTestBadger()
+	defer remove()
+	testParallelPutGet(db, t)
+}
+
+//测试LevelDB迁移到Badger之后可以按引擎检测并打开
+func TestBadger_Migrate(t *testing.T) {
+	src, remove := newTestLDB()
+	defer remove()
+	for _, v := range test_values {
+		src.Put([]byte("key"+v), []byte(v))
+	}
+	dst, removeDst := newTestBadger()
+	defer removeDst()
+
+	if err := ethdb.Migrate(src, dst, nil); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if engine := ethdb.DetectEngine(dst.Path()); engine != ethdb.EngineBadger {
+		t.Fatalf("engine mismatch: have %q, want %q", engine, ethdb.EngineBadger)
+	}
+	for _, v := range test_values {
+		data, err := dst.Get([]byte("key" + v))
+		if err != nil || string(data) != v {
+			t.Fatalf("get %q mismatch: have %q, err %v", v, data, err)
+		}
+	}
+}
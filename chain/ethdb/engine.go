@@ -0,0 +1,119 @@
+package ethdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//数据库引擎名称
+const (
+	EngineLevelDB = "leveldb"
+	EngineBadger  = "badger"
+
+	DefaultEngine = EngineLevelDB
+)
+
+//按引擎打开或者创建数据库的函数，cache为缓存大小(MB)，handles为允许打开的文件数量
+type Opener func(file string, cache int, handles int) (Database, error)
+
+//引擎的描述，Detect判断目录中是否是该引擎的数据库
+type Engine struct {
+	Name   string
+	Open   Opener
+	Detect func(dir string) bool
+}
+
+var (
+	enginesLock sync.RWMutex
+	engines     = make(map[string]*Engine)
+)
+
+func init() {
+	RegisterEngine(&Engine{
+		Name: EngineLevelDB,
+		Open: func(file string, cache int, handles int) (Database, error) {
+			return NewLDBDatabase(file, cache, handles)
+		},
+		Detect: func(dir string) bool { return fileExist(filepath.Join(dir, "CURRENT")) },
+	})
+}
+
+//注册数据库引擎，引擎名称重复时panic
+func RegisterEngine(engine *Engine) {
+	enginesLock.Lock()
+	defer enginesLock.Unlock()
+
+	if _, ok := engines[engine.Name]; ok {
+		panic(fmt.Sprintf("database engine %q already registered", engine.Name))
+	}
+	engines[engine.Name] = engine
+}
+
+//返回已经编译进来的数据库引擎名称
+func Engines() []string {
+	enginesLock.RLock()
+	defer enginesLock.RUnlock()
+
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupEngine(name string) (*Engine, error) {
+	if name == "" {
+		name = DefaultEngine
+	}
+	enginesLock.RLock()
+	engine, ok := engines[name]
+	enginesLock.RUnlock()
+
+	if !ok {
+		if name == EngineBadger {
+			return nil, fmt.Errorf("database engine %q not compiled in (rebuild with -tags badger)", name)
+		}
+		return nil, fmt.Errorf("unknown database engine %q (available: %s)", name, strings.Join(Engines(), ", "))
+	}
+	return engine, nil
+}
+
+//返回目录中已有数据库使用的引擎，目录不存在或者为空时返回空字符串
+func DetectEngine(dir string) string {
+	enginesLock.RLock()
+	defer enginesLock.RUnlock()
+
+	for _, name := range []string{EngineLevelDB, EngineBadger} {
+		if engine, ok := engines[name]; ok && engine.Detect(dir) {
+			return name
+		}
+	}
+	//引擎没有编译进来时，按照数据文件判断
+	if fileExist(filepath.Join(dir, "MANIFEST")) {
+		return EngineBadger
+	}
+	return ""
+}
+
+//使用指定的引擎打开数据库，目录中已经存在其他引擎的数据库时返回错误
+func Open(name string, file string, cache int, handles int) (Database, error) {
+
+	engine, err := lookupEngine(name)
+	if err != nil {
+		return nil, err
+	}
+	if existing := DetectEngine(file); existing != "" && existing != engine.Name {
+		return nil, fmt.Errorf("database %s uses engine %q, not %q (convert it with 'geth db migrate')", file, existing, engine.Name)
+	}
+	return engine.Open(file, cache, handles)
+}
+
+func fileExist(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
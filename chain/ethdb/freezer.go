@@ -0,0 +1,235 @@
+package ethdb
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+//读取冻结数据的接口
+type AncientReader interface {
+	Ancients() uint64                                    //已经冻结的数据项数量
+	Ancient(table string, number uint64) ([]byte, error) //读取某张表中的一项数据
+	AncientSize(table string) (uint64, error)            //某张表占用的磁盘空间
+}
+
+//写入冻结数据的接口
+type AncientWriter interface {
+	AppendAncient(number uint64, items map[string][]byte) error //在每张表的末尾追加一项数据
+	TruncateAncients(items uint64) error                        //只保留前items项数据
+	SyncAncient() error                                         //将冻结数据写入磁盘
+}
+
+//支持冻结数据的数据库
+type AncientStore interface {
+	Database
+	AncientReader
+	AncientWriter
+}
+
+//从冻结数据中查找键对应的值，键不属于冻结数据时返回false
+type AncientResolver func(ancients AncientReader, key []byte) ([]byte, bool)
+
+//由多张只允许追加的平面文件表组成的冻结库，每张表中第n项数据属于同一个序号(区块号)
+type Freezer struct {
+	frozen uint64 //已经冻结的数据项数量(atomic)
+
+	lock   sync.Mutex //追加和截断的互斥锁
+	tables map[string]*freezerTable
+}
+
+//打开或者创建冻结库，tables为表名以及是否压缩
+func NewFreezer(dir string, tables map[string]bool) (*Freezer, error) {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	freezer := &Freezer{tables: make(map[string]*freezerTable)}
+	for name, compress := range tables {
+		table, err := openFreezerTable(dir, name, compress)
+		if err != nil {
+			freezer.Close()
+			return nil, err
+		}
+		freezer.tables[name] = table
+	}
+	//异常退出时各张表的数据项数量可能不一致，全部截断为最少的数量
+	frozen := uint64(0)
+	first := true
+	for _, table := range freezer.tables {
+		if items := table.count(); first || items < frozen {
+			frozen, first = items, false
+		}
+	}
+	for _, table := range freezer.tables {
+		if err := table.truncate(frozen); err != nil {
+			freezer.Close()
+			return nil, err
+		}
+	}
+	freezer.frozen = frozen
+
+	log.Info("Opened ancient database", "dir", dir, "frozen", frozen)
+	return freezer, nil
+}
+
+func (f *Freezer) Ancients() uint64 {
+	return atomic.LoadUint64(&f.frozen)
+}
+
+func (f *Freezer) Ancient(table string, number uint64) ([]byte, error) {
+	t, ok := f.tables[table]
+	if !ok {
+		return nil, fmt.Errorf("unknown ancient table %q", table)
+	}
+	if number >= f.Ancients() {
+		return nil, errOutOfBounds
+	}
+	return t.retrieve(number)
+}
+
+func (f *Freezer) AncientSize(table string) (uint64, error) {
+	t, ok := f.tables[table]
+	if !ok {
+		return 0, fmt.Errorf("unknown ancient table %q", table)
+	}
+	return t.diskSize(), nil
+}
+
+//追加序号为number的数据，每张表都必须有对应的数据项，任何一张表写入失败时全部回滚
+func (f *Freezer) AppendAncient(number uint64, items map[string][]byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	frozen := f.Ancients()
+	if number != frozen {
+		return fmt.Errorf("%v (have %d, want %d)", errOutOfOrder, number, frozen)
+	}
+	for name := range f.tables {
+		if _, ok := items[name]; !ok {
+			return fmt.Errorf("missing ancient item for table %q", name)
+		}
+	}
+	for name, table := range f.tables {
+		if err := table.append(number, items[name]); err != nil {
+			f.rollback(frozen)
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, frozen+1)
+	return nil
+}
+
+func (f *Freezer) TruncateAncients(items uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if items >= f.Ancients() {
+		return nil
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+func (f *Freezer) SyncAncient() error {
+	for _, table := range f.tables {
+		if err := table.sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Freezer) Close() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+func (f *Freezer) rollback(items uint64) {
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			log.Error("Failed to roll back ancient table", "table", table.name, "err", err)
+		}
+	}
+}
+
+//在键值数据库之上叠加冻结库的数据库，键值数据库中找不到的键通过resolver从冻结库中读取
+type FreezerDatabase struct {
+	Database
+	freezer  *Freezer
+	resolver AncientResolver
+}
+
+func NewFreezerDatabase(db Database, freezer *Freezer, resolver AncientResolver) *FreezerDatabase {
+	return &FreezerDatabase{
+		Database: db,
+		freezer:  freezer,
+		resolver: resolver,
+	}
+}
+
+func (db *FreezerDatabase) Get(key []byte) ([]byte, error) {
+	value, err := db.Database.Get(key)
+	if err == nil {
+		return value, nil
+	}
+	if value, ok := db.resolver(db.freezer, key); ok {
+		return value, nil
+	}
+	return nil, err
+}
+
+func (db *FreezerDatabase) Has(key []byte) (bool, error) {
+	if ok, err := db.Database.Has(key); ok || err != nil {
+		return ok, err
+	}
+	_, ok := db.resolver(db.freezer, key)
+	return ok, nil
+}
+
+func (db *FreezerDatabase) Close() {
+	db.Database.Close()
+	if err := db.freezer.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+}
+
+func (db *FreezerDatabase) Ancients() uint64 {
+	return db.freezer.Ancients()
+}
+
+func (db *FreezerDatabase) Ancient(table string, number uint64) ([]byte, error) {
+	return db.freezer.Ancient(table, number)
+}
+
+func (db *FreezerDatabase) AncientSize(table string) (uint64, error) {
+	return db.freezer.AncientSize(table)
+}
+
+func (db *FreezerDatabase) AppendAncient(number uint64, items map[string][]byte) error {
+	return db.freezer.AppendAncient(number, items)
+}
+
+func (db *FreezerDatabase) TruncateAncients(items uint64) error {
+	return db.freezer.TruncateAncients(items)
+}
+
+func (db *FreezerDatabase) SyncAncient() error {
+	return db.freezer.SyncAncient()
+}
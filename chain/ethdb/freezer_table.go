@@ -0,0 +1,211 @@
+package ethdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+var (
+	errOutOfBounds = errors.New("out of bounds")       //读取的数据项还没有冻结
+	errOutOfOrder  = errors.New("append out of order") //追加的数据项序号不连续
+)
+
+//冻结库中的一张表，只允许追加。
+//数据文件(.dat/.cdat)顺序保存每一项的内容，索引文件(.idx/.cidx)保存每一项在数据文件中的结束位置(8字节大端)
+type freezerTable struct {
+	name     string
+	compress bool //是否使用snappy压缩
+
+	lock  sync.RWMutex
+	index *os.File
+	data  *os.File
+	items uint64 //数据项数量
+	size  uint64 //数据文件的有效长度
+}
+
+//打开或者创建一张表，并截掉上次异常退出时写了一半的数据
+func openFreezerTable(dir string, name string, compress bool) (*freezerTable, error) {
+
+	idxName, datName := name+".idx", name+".dat"
+	if compress {
+		idxName, datName = name+".cidx", name+".cdat"
+	}
+	index, err := os.OpenFile(filepath.Join(dir, idxName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.OpenFile(filepath.Join(dir, datName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		index.Close()
+		return nil, err
+	}
+	t := &freezerTable{
+		name:     name,
+		compress: compress,
+		index:    index,
+		data:     data,
+	}
+	if err := t.repair(); err != nil {
+		t.close()
+		return nil, err
+	}
+	return t, nil
+}
+
+//使索引文件和数据文件保持一致：丢弃不完整的索引项以及索引项指向数据文件之外的数据项
+func (t *freezerTable) repair() error {
+
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	datStat, err := t.data.Stat()
+	if err != nil {
+		return err
+	}
+	items := uint64(stat.Size()) / 8
+	for items > 0 {
+		end, err := t.offset(items)
+		if err != nil {
+			return err
+		}
+		if end <= uint64(datStat.Size()) {
+			t.size = end
+			break
+		}
+		items--
+	}
+	t.items = items
+	if items == 0 {
+		t.size = 0
+	}
+	if err := t.index.Truncate(int64(items * 8)); err != nil {
+		return err
+	}
+	return t.data.Truncate(int64(t.size))
+}
+
+//返回前n项数据在数据文件中的结束位置
+func (t *freezerTable) offset(n uint64) (uint64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	var buf [8]byte
+	if _, err := t.index.ReadAt(buf[:], int64((n-1)*8)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+//在表的末尾追加一项数据，item必须等于当前的数据项数量
+func (t *freezerTable) append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if item != t.items {
+		return fmt.Errorf("%s: %v (have %d, want %d)", t.name, errOutOfOrder, item, t.items)
+	}
+	if t.compress {
+		blob = snappy.Encode(nil, blob)
+	}
+	//先写数据再写索引，异常退出时没有索引的数据在下次打开时截掉
+	if _, err := t.data.WriteAt(blob, int64(t.size)); err != nil {
+		return err
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], t.size+uint64(len(blob)))
+	if _, err := t.index.WriteAt(buf[:], int64(t.items*8)); err != nil {
+		return err
+	}
+	t.items++
+	t.size += uint64(len(blob))
+	return nil
+}
+
+//读取一项数据
+func (t *freezerTable) retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if item >= t.items {
+		return nil, errOutOfBounds
+	}
+	start, err := t.offset(item)
+	if err != nil {
+		return nil, err
+	}
+	end, err := t.offset(item + 1)
+	if err != nil {
+		return nil, err
+	}
+	blob := make([]byte, end-start)
+	if _, err := t.data.ReadAt(blob, int64(start)); err != nil {
+		return nil, err
+	}
+	if t.compress {
+		return snappy.Decode(nil, blob)
+	}
+	return blob, nil
+}
+
+//将表截断为只保留前items项
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if items >= t.items {
+		return nil
+	}
+	size, err := t.offset(items)
+	if err != nil {
+		return err
+	}
+	if err := t.index.Truncate(int64(items * 8)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(size)); err != nil {
+		return err
+	}
+	t.items, t.size = items, size
+	return nil
+}
+
+func (t *freezerTable) count() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.items
+}
+
+//返回数据文件和索引文件的总大小
+func (t *freezerTable) diskSize() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.size + t.items*8
+}
+
+func (t *freezerTable) sync() error {
+	if err := t.data.Sync(); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+func (t *freezerTable) close() error {
+	var errs []error
+	if err := t.index.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := t.data.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %v", t.name, errs)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package ethdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testFreezerTables = map[string]bool{"raw": false, "compressed": true}
+
+func testAncientItem(table string, number uint64) []byte {
+	return bytes.Repeat([]byte(table), int(number)+1)
+}
+
+func appendTestAncients(t *testing.T, f *Freezer, from, to uint64) {
+	for number := from; number < to; number++ {
+		items := make(map[string][]byte)
+		for table := range testFreezerTables {
+			items[table] = testAncientItem(table, number)
+		}
+		if err := f.AppendAncient(number, items); err != nil {
+			t.Fatalf("failed to append item %d: %v", number, err)
+		}
+	}
+}
+
+func checkTestAncients(t *testing.T, f *Freezer, items uint64) {
+	if frozen := f.Ancients(); frozen != items {
+		t.Fatalf("frozen items mismatch: have %d, want %d", frozen, items)
+	}
+	for number := uint64(0); number < items; number++ {
+		for table := range testFreezerTables {
+			blob, err := f.Ancient(table, number)
+			if err != nil {
+				t.Fatalf("failed to read %s item %d: %v", table, number, err)
+			}
+			if !bytes.Equal(blob, testAncientItem(table, number)) {
+				t.Fatalf("%s item %d mismatch", table, number)
+			}
+		}
+	}
+	if _, err := f.Ancient("raw", items); err != errOutOfBounds {
+		t.Fatalf("read beyond frozen items: %v", err)
+	}
+}
+
+//测试冻结库的追加、重新打开以及截断
+func TestFreezerAppendTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := NewFreezer(dir, testFreezerTables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendTestAncients(t, f, 0, 50)
+	if err := f.AppendAncient(60, map[string][]byte{"raw": nil, "compressed": nil}); err == nil {
+		t.Errorf("out of order append accepted")
+	}
+	if err := f.AppendAncient(50, map[string][]byte{"raw": nil}); err == nil {
+		t.Errorf("incomplete append accepted")
+	}
+	checkTestAncients(t, f, 50)
+	f.Close()
+
+	if f, err = NewFreezer(dir, testFreezerTables); err != nil {
+		t.Fatal(err)
+	}
+	checkTestAncients(t, f, 50)
+
+	if err := f.TruncateAncients(20); err != nil {
+		t.Fatal(err)
+	}
+	checkTestAncients(t, f, 20)
+	appendTestAncients(t, f, 20, 30)
+	checkTestAncients(t, f, 30)
+	f.Close()
+}
+
+//测试异常退出后截掉不完整的数据项，并使各张表的数量一致
+func TestFreezerRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := NewFreezer(dir, testFreezerTables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendTestAncients(t, f, 0, 10)
+	f.Close()
+
+	//未压缩表的最后一项数据只写了一半，压缩表的索引多出半项
+	raw := filepath.Join(dir, "raw.dat")
+	stat, _ := os.Stat(raw)
+	if err := os.Truncate(raw, stat.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+	idx, _ := os.OpenFile(filepath.Join(dir, "compressed.cidx"), os.O_APPEND|os.O_WRONLY, 0644)
+	idx.Write([]byte{0, 0, 0})
+	idx.Close()
+
+	if f, err = NewFreezer(dir, testFreezerTables); err != nil {
+		t.Fatal(err)
+	}
+	checkTestAncients(t, f, 9)
+	appendTestAncients(t, f, 9, 12)
+	checkTestAncients(t, f, 12)
+	f.Close()
+}
+
+//测试键值数据库中找不到的键通过resolver从冻结库读取
+func TestFreezerDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := NewFreezer(dir, testFreezerTables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendTestAncients(t, f, 0, 5)
+
+	mem, _ := NewMemDatabase()
+	mem.Put([]byte("live"), []byte("value"))
+	db := NewFreezerDatabase(mem, f, func(ancients AncientReader, key []byte) ([]byte, bool) {
+		if len(key) != 1 {
+			return nil, false
+		}
+		blob, err := ancients.Ancient("raw", uint64(key[0]))
+		return blob, err == nil
+	})
+	defer db.Close()
+
+	if value, err := db.Get([]byte("live")); err != nil || string(value) != "value" {
+		t.Errorf("live key mismatch: %q, %v", value, err)
+	}
+	if value, err := db.Get([]byte{3}); err != nil || !bytes.Equal(value, testAncientItem("raw", 3)) {
+		t.Errorf("ancient key mismatch: %q, %v", value, err)
+	}
+	if ok, _ := db.Has([]byte{4}); !ok {
+		t.Errorf("ancient key not found")
+	}
+	if _, err := db.Get([]byte{5}); err == nil {
+		t.Errorf("unfrozen key found")
+	}
+	if ok, _ := db.Has([]byte("missing")); ok {
+		t.Errorf("missing key found")
+	}
+}
@@ -0,0 +1,65 @@
+package ethdb
+
+import (
+	"errors"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var (
+	errNotCompactable = errors.New("database does not support compaction")
+	errNoStats        = errors.New("database does not provide statistics")
+)
+
+//支持手动压缩的数据库
+type Compacter interface {
+	Compact() error
+}
+
+//可以输出存储引擎内部统计信息的数据库
+type Stater interface {
+	Stat() (string, error)
+}
+
+//压缩整个数据库
+func (db *LDBDatabase) Compact() error {
+	return db.db.CompactRange(util.Range{})
+}
+
+//返回LevelDB每一层的文件数量、大小以及读写量
+func (db *LDBDatabase) Stat() (string, error) {
+	return db.db.GetProperty("leveldb.stats")
+}
+
+//遍历键值数据库，冻结库中的数据不参与遍历
+func (db *FreezerDatabase) Iterate(prefix []byte) Iterator {
+	if iteratee, ok := db.Database.(Iteratee); ok {
+		return iteratee.Iterate(prefix)
+	}
+	return &errIterator{err: errNotIterable}
+}
+
+func (db *FreezerDatabase) Compact() error {
+	if compacter, ok := db.Database.(Compacter); ok {
+		return compacter.Compact()
+	}
+	return errNotCompactable
+}
+
+func (db *FreezerDatabase) Stat() (string, error) {
+	if stater, ok := db.Database.(Stater); ok {
+		return stater.Stat()
+	}
+	return "", errNoStats
+}
+
+//只返回错误的迭代器
+type errIterator struct {
+	err error
+}
+
+func (it *errIterator) Next() bool    { return false }
+func (it *errIterator) Key() []byte   { return nil }
+func (it *errIterator) Value() []byte { return nil }
+func (it *errIterator) Release()      {}
+func (it *errIterator) Error() error  { return it.err }
@@ -0,0 +1,115 @@
+package ethdb
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var errNotIterable = errors.New("source database does not support iteration")
+
+//按键的顺序遍历数据库中全部键值对的迭代器
+type Iterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Release()
+	Error() error
+}
+
+//支持按前缀遍历键值对的数据库，前缀为空时遍历全部键值对
+type Iteratee interface {
+	Iterate(prefix []byte) Iterator
+}
+
+func (db *LDBDatabase) Iterate(prefix []byte) Iterator {
+	return db.db.NewIterator(util.BytesPrefix(prefix), nil)
+}
+
+func (db *MemDatabase) Iterate(prefix []byte) Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	it := &memIterator{index: -1}
+	for key, value := range db.db {
+		if bytes.HasPrefix([]byte(key), prefix) {
+			it.keys = append(it.keys, key)
+			it.values = append(it.values, value)
+		}
+	}
+	sort.Sort(it)
+	return it
+}
+
+//内存数据库的迭代器，遍历创建时的快照
+type memIterator struct {
+	keys   []string
+	values [][]byte
+	index  int
+}
+
+func (it *memIterator) Next() bool {
+	if it.index+1 >= len(it.keys) {
+		return false
+	}
+	it.index++
+	return true
+}
+
+func (it *memIterator) Key() []byte   { return []byte(it.keys[it.index]) }
+func (it *memIterator) Value() []byte { return it.values[it.index] }
+func (it *memIterator) Release()      { it.keys, it.values = nil, nil }
+func (it *memIterator) Error() error  { return nil }
+
+func (it *memIterator) Len() int           { return len(it.keys) }
+func (it *memIterator) Less(i, j int) bool { return it.keys[i] < it.keys[j] }
+func (it *memIterator) Swap(i, j int) {
+	it.keys[i], it.keys[j] = it.keys[j], it.keys[i]
+	it.values[i], it.values[j] = it.values[j], it.values[i]
+}
+
+//将源数据库的全部键值对批量复制到目标数据库，每写入一批调用一次progress
+func Migrate(src Database, dst Database, progress func(keys uint64, size uint64)) error {
+
+	iteratee, ok := src.(Iteratee)
+	if !ok {
+		return errNotIterable
+	}
+	it := iteratee.Iterate(nil)
+	defer it.Release()
+
+	var (
+		batch = dst.NewBatch()
+		keys  uint64
+		size  uint64
+	)
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return err
+		}
+		keys++
+		size += uint64(len(it.Key()) + len(it.Value()))
+
+		if batch.ValueSize() >= IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch = dst.NewBatch()
+			if progress != nil {
+				progress(keys, size)
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if progress != nil {
+		progress(keys, size)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package ethdb_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/ethdb"
+)
+
+//测试迁移后目标数据库包含源数据库的全部键值对
+func TestMigrate(t *testing.T) {
+	src, _ := ethdb.NewMemDatabase()
+	for i := 0; i < 5000; i++ {
+		src.Put([]byte(fmt.Sprintf("key-%05d", i)), bytes.Repeat([]byte{byte(i)}, 100))
+	}
+	dst, remove := newTestLDB()
+	defer remove()
+
+	var batches int
+	if err := ethdb.Migrate(src, dst, func(keys, size uint64) { batches++ }); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if batches < 2 {
+		t.Errorf("progress reported %d times, want multiple batches", batches)
+	}
+	for _, key := range src.Keys() {
+		want, _ := src.Get(key)
+		have, err := dst.Get(key)
+		if err != nil {
+			t.Fatalf("key %q missing after migration: %v", key, err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Fatalf("value mismatch for %q", key)
+		}
+	}
+	//迁移回内存数据库，检查迭代顺序与数量
+	back, _ := ethdb.NewMemDatabase()
+	if err := ethdb.Migrate(dst, back, nil); err != nil {
+		t.Fatalf("reverse migration failed: %v", err)
+	}
+	if len(back.Keys()) != len(src.Keys()) {
+		t.Errorf("key count mismatch: have %d, want %d", len(back.Keys()), len(src.Keys()))
+	}
+}
+
+//测试打开其他引擎创建的数据库以及未知引擎时返回错误
+func TestOpenEngine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ethdb_engine_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if engine := ethdb.DetectEngine(dir); engine != "" {
+		t.Fatalf("empty directory detected as %q", engine)
+	}
+	db, err := ethdb.Open("", dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open default engine: %v", err)
+	}
+	db.Close()
+
+	if engine := ethdb.DetectEngine(dir); engine != ethdb.EngineLevelDB {
+		t.Fatalf("engine mismatch: have %q, want %q", engine, ethdb.EngineLevelDB)
+	}
+	if _, err := ethdb.Open("nosuchdb", dir, 0, 0); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("unknown engine accepted: %v", err)
+	}
+	if _, err := ethdb.Open(ethdb.EngineBadger, dir, 0, 0); err == nil {
+		t.Errorf("leveldb database opened as badger")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core/types"
 )
@@ -105,6 +106,10 @@ type SyncProgress struct {
 	HighestBlock  uint64 // Highest alleged block number in the chain
 	PulledStates  uint64 // Number of state trie entries already downloaded
 	KnownStates   uint64 // Total number of state trie entries known about
+
+	PulledDposStates  uint64 // Number of DPoS context trie entries already downloaded
+	KnownDposStates   uint64 // Total number of DPoS context trie entries known about
+	IrreversibleBlock uint64 // Highest block made irreversible by the validators
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
@@ -122,6 +127,7 @@ type CallMsg struct {
 	Value    *big.Int        // amount of wei sent along with the call
 	Data     []byte          // input data, usually an ABI-encoded contract method invocation
 	Extra    []byte
+	TxType   protocol.TxType //交易类型，默认为普通交易
 }
 
 // A ContractCaller provides contract calls, essentially transactions that are executed by
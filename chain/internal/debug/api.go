@@ -21,7 +21,9 @@
 package debug
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"os/user"
@@ -33,9 +35,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/log"
 )
 
+// maxProfileDuration caps the duration of CPU profiles returned over RPC.
+const maxProfileDuration = 5 * time.Minute
+
 // Handler is the global debugging handler.
 var Handler = new(HandlerT)
 
@@ -62,6 +68,18 @@ func (*HandlerT) Vmodule(pattern string) error {
 	return glogger.Vmodule(pattern)
 }
 
+// SetVerbosityPattern raises the log verbosity of individual modules, e.g.
+// "dpos=5,txpool=4". Module names are translated to the source files logging
+// for them, other names are used as vmodule patterns. Like Vmodule, it replaces
+// the previous pattern and cannot lower a module below the global verbosity.
+func (*HandlerT) SetVerbosityPattern(pattern string) error {
+	vmodule, err := expandModules(pattern)
+	if err != nil {
+		return err
+	}
+	return glogger.Vmodule(vmodule)
+}
+
 // BacktraceAt sets the log backtrace location. See package log for details on
 // the pattern syntax.
 func (*HandlerT) BacktraceAt(location string) error {
@@ -162,6 +180,55 @@ func (*HandlerT) WriteBlockProfile(file string) error {
 	return writeProfile("block", file)
 }
 
+// SetMutexProfileFraction sets the rate of mutex contention profiling, on
+// average 1/rate events are reported. It returns the previous rate, a rate of
+// 0 disables mutex profiling.
+func (*HandlerT) SetMutexProfileFraction(rate int) int {
+	return runtime.SetMutexProfileFraction(rate)
+}
+
+// WriteMutexProfile writes a goroutine mutex contention profile to the given
+// file.
+func (*HandlerT) WriteMutexProfile(file string) error {
+	return writeProfile("mutex", file)
+}
+
+// Profile returns a profile in the binary pprof format, so that it can be
+// captured from a remote node without access to its file system. The name is
+// "cpu", which profiles the CPU for nsec seconds, or one of the runtime
+// profiles ("heap", "goroutine", "block", "mutex", "threadcreate") which are
+// snapshots taken immediately.
+func (h *HandlerT) Profile(name string, nsec uint) (hexutil.Bytes, error) {
+	buf := new(bytes.Buffer)
+	if name == "cpu" {
+		duration := time.Duration(nsec) * time.Second
+		if duration <= 0 || duration > maxProfileDuration {
+			return nil, fmt.Errorf("CPU profile duration must be between 1s and %v", maxProfileDuration)
+		}
+		h.mu.Lock()
+		if h.cpuW != nil {
+			h.mu.Unlock()
+			return nil, errors.New("CPU profiling already in progress")
+		}
+		err := pprof.StartCPUProfile(buf)
+		h.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		time.Sleep(duration)
+		pprof.StopCPUProfile()
+		return buf.Bytes(), nil
+	}
+	p := pprof.Lookup(name)
+	if p == nil {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	if err := p.WriteTo(buf, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // WriteMemProfile writes an allocation profile to the given file.
 // Note that the profiling rate cannot be set through the API,
 // it must be set on the command line.
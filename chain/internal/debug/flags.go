@@ -46,6 +46,24 @@ var (
 		Usage: "Request a stack trace at a specific logging statement (e.g. \"block.go:271\")",
 		Value: "",
 	}
+	logJSONFlag = cli.BoolFlag{
+		Name:  "log.json",
+		Usage: "Format logs as JSON lines instead of human readable text",
+	}
+	logFileFlag = cli.StringFlag{
+		Name:  "log.file",
+		Usage: "Write logs to the given file instead of the console",
+	}
+	logMaxSizeFlag = cli.IntFlag{
+		Name:  "log.maxsize",
+		Usage: "Maximum size in megabytes of the log file before it is rotated (0 = no rotation)",
+		Value: 100,
+	}
+	logMaxBackupsFlag = cli.IntFlag{
+		Name:  "log.maxbackups",
+		Usage: "Maximum number of rotated log files to keep",
+		Value: 10,
+	}
 	debugFlag = cli.BoolFlag{
 		Name:  "debug",
 		Usage: "Prepends log messages with call-site location (file and line number)",
@@ -86,6 +104,7 @@ var (
 // Flags holds all command-line flags required for debugging.
 var Flags = []cli.Flag{
 	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
+	logJSONFlag, logFileFlag, logMaxSizeFlag, logMaxBackupsFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
 	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
 }
@@ -105,6 +124,9 @@ func init() {
 // It should be called as early as possible in the program.
 func Setup(ctx *cli.Context) error {
 	// logging
+	if err := setupOutput(ctx); err != nil {
+		return err
+	}
 	log.PrintOrigins(ctx.GlobalBool(debugFlag.Name))
 	glogger.Verbosity(log.Lvl(ctx.GlobalInt(verbosityFlag.Name)))
 	glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name))
@@ -138,6 +160,32 @@ func Setup(ctx *cli.Context) error {
 	return nil
 }
 
+// setupOutput replaces the console output of the logger if JSON formatting or
+// a log file was requested. The verbosity settings are kept.
+func setupOutput(ctx *cli.Context) error {
+	useJSON, file := ctx.GlobalBool(logJSONFlag.Name), ctx.GlobalString(logFileFlag.Name)
+	if !useJSON && file == "" {
+		return nil
+	}
+	format := log.TerminalFormat(false)
+	if useJSON {
+		format = log.JsonFormat()
+	}
+	var handler log.Handler
+	if file != "" {
+		maxSize := int64(ctx.GlobalInt(logMaxSizeFlag.Name)) * 1024 * 1024
+		h, err := log.RotatingFileHandler(expandHome(file), maxSize, ctx.GlobalInt(logMaxBackupsFlag.Name), format)
+		if err != nil {
+			return err
+		}
+		handler = h
+	} else {
+		handler = log.StreamHandler(os.Stderr, format)
+	}
+	glogger = log.NewGlogHandler(handler)
+	return nil
+}
+
 // Exit stops all running profiles, flushing their output to the
 // respective file.
 func Exit() {
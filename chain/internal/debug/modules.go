@@ -0,0 +1,72 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"errors"
+	"strings"
+)
+
+// errModuleSyntax is returned when a module verbosity pattern is invalid.
+var errModuleSyntax = errors.New("expect comma-separated list of module=N")
+
+// logModules maps the module names accepted by SetVerbosityPattern to the
+// vmodule patterns of the source files logging for them. Names which are not
+// listed are passed through as vmodule patterns, so any package name or file
+// pattern understood by Vmodule works as well.
+var logModules = map[string]string{
+	"dpos":       "consensus/dpos/*",
+	"txpool":     "core/tx_pool.go",
+	"chain":      "core/blockchain.go",
+	"state":      "core/state/*",
+	"vm":         "core/vm/*",
+	"miner":      "miner/*",
+	"downloader": "eth/downloader/*",
+	"fetcher":    "eth/fetcher/*",
+	"eth":        "eth/*",
+	"les":        "les/*",
+	"p2p":        "p2p/*",
+	"rpc":        "rpc/*",
+	"boker":      "boker/*",
+	"gossip":     "boker/gossip/*",
+	"shh":        "whisper/*",
+	"ethdb":      "ethdb/*",
+}
+
+// expandModules converts a module verbosity pattern like "dpos=5,txpool=4"
+// into the equivalent vmodule pattern.
+func expandModules(pattern string) (string, error) {
+	var rules []string
+	for _, rule := range strings.Split(pattern, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		parts := strings.Split(rule, "=")
+		if len(parts) != 2 {
+			return "", errModuleSyntax
+		}
+		module, level := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if module == "" || level == "" {
+			return "", errModuleSyntax
+		}
+		if files, ok := logModules[module]; ok {
+			module = files
+		}
+		rules = append(rules, module+"="+level)
+	}
+	return strings.Join(rules, ","), nil
+}
@@ -1,11 +1,14 @@
 package ethapi
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,6 +20,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/common/math"
 	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/core/state"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/core/vm"
 	"github.com/Bokerchain/Boker/chain/crypto"
@@ -50,6 +54,36 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx)
 }
 
+//Gas价格历史的返回结果
+type GasPriceHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`        //返回的第一个区块号
+	GasPrice     [][]*hexutil.Big `json:"gasPrice,omitempty"` //每个区块中按百分位统计的交易Gas价格
+	GasUsedRatio []float64        `json:"gasUsedRatio"`       //每个区块使用的Gas占Gas上限的比例
+}
+
+//返回从startBlock开始的count个区块中，按给定百分位统计的交易Gas价格(免Gas的基础合约交易不参与统计)
+func (s *PublicEthereumAPI) GasPriceHistory(ctx context.Context, startBlock rpc.BlockNumber, count hexutil.Uint64, percentiles []float64) (*GasPriceHistoryResult, error) {
+
+	oldest, prices, ratios, err := s.b.GasPriceHistory(ctx, startBlock, uint64(count), percentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &GasPriceHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: ratios,
+	}
+	if len(percentiles) > 0 {
+		result.GasPrice = make([][]*hexutil.Big, len(prices))
+		for i, blockPrices := range prices {
+			result.GasPrice[i] = make([]*hexutil.Big, len(blockPrices))
+			for j, price := range blockPrices {
+				result.GasPrice[i][j] = (*hexutil.Big)(price)
+			}
+		}
+	}
+	return result, nil
+}
+
 //返回此节点支持的当前以太坊协议版本
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -76,6 +110,11 @@ func (s *PublicEthereumAPI) Syncing() (interface{}, error) {
 		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
 		"pulledStates":  hexutil.Uint64(progress.PulledStates),
 		"knownStates":   hexutil.Uint64(progress.KnownStates),
+
+		//Dpos状态同步进度，周期树等Dpos状态下载完成并且不可逆区块追上网络后才可以安全出块
+		"pulledDposStates":  hexutil.Uint64(progress.PulledDposStates),
+		"knownDposStates":   hexutil.Uint64(progress.KnownDposStates),
+		"irreversibleBlock": hexutil.Uint64(progress.IrreversibleBlock),
 	}, nil
 }
 
@@ -102,7 +141,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -110,7 +149,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
@@ -292,6 +331,30 @@ func (s *PrivateAccountAPI) ImportRawKey(privkey string, password string) (commo
 	return acc.Address, err
 }
 
+// ExportRawKey decrypts the key of the given account with the password and
+// returns the hex encoded ECDSA private key, so that it can be moved to another
+// node with ImportRawKey.
+func (s *PrivateAccountAPI) ExportRawKey(addr common.Address, password string) (string, error) {
+	key, err := fetchKeystore(s.am).ExportECDSA(accounts.Account{Address: addr}, password)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(crypto.FromECDSA(key)), nil
+}
+
+// ImportKeystoreJSON stores the given encrypted JSON key (as produced by another
+// node's keystore) into the key directory. The key is decrypted with password and
+// re-encrypted with newPassword using the local scrypt parameters; if newPassword
+// is omitted the original password is kept.
+func (s *PrivateAccountAPI) ImportKeystoreJSON(keyJSON string, password string, newPassword *string) (common.Address, error) {
+	passphrase := password
+	if newPassword != nil {
+		passphrase = *newPassword
+	}
+	acc, err := fetchKeystore(s.am).Import([]byte(keyJSON), password, passphrase)
+	return acc.Address, err
+}
+
 // UnlockAccount will unlock the account associated with the given address with
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
@@ -321,6 +384,28 @@ func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string,
 	return err == nil, err
 }
 
+//只为签名区块解锁出块账号并将其设置为Coinbase，duration秒之后自动重新锁定(0表示一直解锁)，
+//解锁期间该账号不能用于签名交易或任意数据，同时不会保存账号的密码
+func (s *PrivateAccountAPI) UnlockAccountForSealing(addr common.Address, password string, duration *uint64) (bool, error) {
+
+	const max = uint64(time.Duration(math.MaxInt64) / time.Second)
+	var d time.Duration
+	if duration == nil {
+		d = 300 * time.Second
+	} else if *duration > max {
+		return false, errors.New("unlock duration too large")
+	} else {
+		d = time.Duration(*duration) * time.Second
+	}
+	if err := fetchKeystore(s.am).TimedUnlockSealOnly(accounts.Account{Address: addr}, password, d); err != nil {
+		return false, err
+	}
+	s.b.SetCoinbase(addr)
+	log.Info("Unlocked account for block sealing only", "address", addr, "duration", d)
+
+	return true, nil
+}
+
 // LockAccount will lock the account associated with the given address when it's unlocked.
 func (s *PrivateAccountAPI) LockAccount(addr common.Address) bool {
 	return fetchKeystore(s.am).Lock(addr) == nil
@@ -360,7 +445,7 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 
 	//获取区块链的配置，检查是否是EIP155的区块号(https://github.com/ethereum/eips/issues/155)
 	var chainID *big.Int
-	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
+	if config := s.b.ChainConfig(); config.IsReplayProtected(s.b.CurrentBlock().Number()) {
 		chainID = config.ChainId
 	}
 
@@ -461,6 +546,11 @@ func (s *PublicBlockChainAPI) BlockNumber() *big.Int {
 	return header.Number
 }
 
+//返回交易重放保护使用的链ID
+func (s *PublicBlockChainAPI) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(s.b.ChainConfig().ChainId)
+}
+
 //GetBalance返回给定地址的wei数量给定块号。 rpc.LatestBlockNumber和rpc.PendingBlockNumber元块号也是允许的。
 func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*big.Int, error) {
 
@@ -570,6 +660,78 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the result of eth_getProof, the merkle proof of an account
+// and of the requested storage slots of the account.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the merkle proof of a storage slot.
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+//返回账号以及指定存储项的Merkle证明(EIP-1186)，用于根据区块头中的状态根验证账号状态
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	storageTrie := state.StorageTrie(address)
+	storageHash := types.EmptyRootHash
+	if storageTrie != nil {
+		storageHash = storageTrie.Hash()
+	}
+
+	//不存在存储树的账号不需要存储证明
+	storageProof := make([]StorageResult, len(storageKeys))
+	for i, key := range storageKeys {
+		if storageTrie == nil {
+			storageProof[i] = StorageResult{key, &hexutil.Big{}, []string{}}
+			continue
+		}
+		proof, err := state.GetStorageProof(address, common.HexToHash(key))
+		if err != nil {
+			return nil, err
+		}
+		value := state.GetState(address, common.HexToHash(key)).Big()
+		storageProof[i] = StorageResult{key, (*hexutil.Big)(value), toHexSlice(proof)}
+	}
+
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		CodeHash:     state.GetCodeHash(address),
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+//将字节数组转换为十六进制字符串数组
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutil.Encode(b[i])
+	}
+	return r
+}
+
 //****播客链新增处理****
 
 //得到最后一次的出块节点
@@ -901,6 +1063,12 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if state == nil || err != nil {
 		return nil, common.Big0, false, err
 	}
+	return s.applyCall(ctx, state, header, args, vmCfg)
+}
+
+//在给定的状态上执行调用，调用会修改状态，需要保持原状态时传入状态的副本
+func (s *PublicBlockChainAPI) applyCall(ctx context.Context, statedb *state.StateDB, header *types.Header, args CallArgs, vmCfg vm.Config) ([]byte, *big.Int, bool, error) {
+
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
@@ -915,6 +1083,11 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if gas.Sign() == 0 {
 		gas = big.NewInt(50000000)
 	}
+	//限制调用可以使用的Gas，防止公开的RPC节点执行代价过高的调用
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && gas.Cmp(new(big.Int).SetUint64(gasCap)) > 0 {
+		log.Warn("Caller gas above allowance, capping", "requested", gas, "cap", gasCap)
+		gas = new(big.Int).SetUint64(gasCap)
+	}
 	if gasPrice.Sign() == 0 {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
@@ -935,7 +1108,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	defer func() { cancel() }()
 
 	// Get a new instance of the EVM.
-	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
+	evm, vmError, err := s.b.GetEVM(ctx, msg, statedb, header, vmCfg)
 	if err != nil {
 		return nil, common.Big0, false, err
 	}
@@ -965,15 +1138,103 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
 func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
 
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{DisableGasMetering: true})
+	//已确定区块上的调用结果可以缓存，pending区块的状态随时变化不缓存
+	if cache := s.b.CallCache(); cache != nil && blockNr != rpc.PendingBlockNumber {
+		if header, _ := s.b.HeaderByNumber(ctx, blockNr); header != nil {
+			key := callCacheKey(header.Hash(), &args)
+			if result, ok := cache.get(key); ok {
+				return result, nil
+			}
+			//使用解析出的区块号，避免执行期间latest变化导致结果与缓存键不一致
+			result, _, _, err := s.doCall(ctx, args, rpc.BlockNumber(header.Number.Int64()), s.callConfig())
+			if err == nil {
+				cache.put(key, result)
+			}
+			return (hexutil.Bytes)(result), err
+		}
+	}
+	result, _, _, err := s.doCall(ctx, args, blockNr, s.callConfig())
 
 	//log.Info("****Call****", "result", result)
 	return (hexutil.Bytes)(result), err
 }
 
+//eth_call使用的虚拟机配置，设置了Gas上限时按Gas计费执行，否则不计费只限制执行时间
+func (s *PublicBlockChainAPI) callConfig() vm.Config {
+	return vm.Config{DisableGasMetering: s.b.RPCGasCap() == 0}
+}
+
+// maxBatchCalls is the maximum number of calls executed by a single
+// eth_callBatch request.
+const maxBatchCalls = 100
+
+// CallResult is the outcome of a single call of an eth_callBatch request.
+type CallResult struct {
+	Output hexutil.Bytes `json:"output"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// CallBatch executes the given calls on the state of the given block number,
+// all calls see the same state and none sees the effects of another. A failing
+// call is reported in its result and does not abort the others.
+func (s *PublicBlockChainAPI) CallBatch(ctx context.Context, args []CallArgs, blockNr rpc.BlockNumber) ([]CallResult, error) {
+	if len(args) > maxBatchCalls {
+		return nil, fmt.Errorf("too many calls in batch: %d > %d", len(args), maxBatchCalls)
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	results := make([]CallResult, len(args))
+	for i, call := range args {
+		output, _, _, err := s.applyCall(ctx, state.Copy(), header, call, s.callConfig())
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Output = output
+	}
+	return results, nil
+}
+
+// AccessListResult is the result of an eth_createAccessList call.
+type AccessListResult struct {
+	AccessList vm.AccessList `json:"accessList"`
+	GasUsed    *hexutil.Big  `json:"gasUsed"`
+	Failed     bool          `json:"failed"`
+}
+
+// CreateAccessList executes the given transaction on the state for the given
+// block number and returns the accounts and storage slots it accessed together
+// with the gas it used.
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (*AccessListResult, error) {
+	//基础合约交易不经过EVM，没有访问列表
+	if args.TxType != protocol.Binary {
+		gas, ok := core.FixedGas(args.TxType)
+		if !ok {
+			return nil, protocol.ErrInvalidType
+		}
+		return &AccessListResult{AccessList: vm.AccessList{}, GasUsed: (*hexutil.Big)(gas)}, nil
+	}
+	tracer := vm.NewAccessListTracer()
+	_, gas, failed, err := s.doCall(ctx, args, blockNr, vm.Config{Debug: true, Tracer: tracer})
+	if err != nil {
+		return nil, err
+	}
+	return &AccessListResult{AccessList: tracer.AccessList(), GasUsed: (*hexutil.Big)(gas), Failed: failed}, nil
+}
+
 // EstimateGas returns an estimate of the amount of gas needed to execute the
 // given transaction against the current pending block.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (*hexutil.Big, error) {
+	//基础合约交易不经过EVM计费，直接返回交易类型固定消耗的Gas
+	if args.TxType != protocol.Binary {
+		gas, ok := core.FixedGas(args.TxType)
+		if !ok {
+			return nil, protocol.ErrInvalidType
+		}
+		return (*hexutil.Big)(gas), nil
+	}
 	// Determine the lowest and highest possible gas limits to binary search in between
 	var (
 		lo  uint64 = params.TxGas - 1
@@ -990,6 +1251,10 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (*
 		}
 		hi = block.GasLimit().Uint64()
 	}
+	//二分查找的上界不超过配置的Gas上限
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && hi > gasCap {
+		hi = gasCap
+	}
 	cap = hi
 
 	// Create a helper to check if a gas allowance results in an executable transaction
@@ -1027,6 +1292,9 @@ type ExecutionResult struct {
 	Failed      bool           `json:"failed"`
 	ReturnValue string         `json:"returnValue"`
 	StructLogs  []StructLogRes `json:"structLogs"`
+
+	StorageWrites []vm.StorageWrite `json:"storageWrites,omitempty"` //交易中的存储写入，启用EnableStorageDiff时返回
+	AccountDiffs  []vm.AccountDiff  `json:"accountDiffs,omitempty"`  //交易前后发生变化的账户，启用EnableStorageDiff时返回
 }
 
 // StructLogRes stores a structured log emitted by the EVM while replaying a
@@ -1109,6 +1377,9 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 		"dposProto":        head.DposProto.Root().String(),
 		"bokerProto":       head.BokerProto.Root().String(),
 	}
+	if len(head.Checkpoints) > 0 {
+		fields["checkpoints"] = head.Checkpoints
+	}
 
 	if inclTx {
 		formatTx := func(tx *types.Transaction) (interface{}, error) {
@@ -1193,9 +1464,18 @@ type RPCTransaction struct {
 	S                *hexutil.Big    `json:"s"`
 }
 
+// txSigner returns the signer able to recover the sender of tx, honouring the
+// chain ID of replay protected transactions.
+func txSigner(tx *types.Transaction) types.Signer {
+	if tx.Protected() {
+		return types.NewEIP155Signer(tx.ChainId())
+	}
+	return types.HomesteadSigner{}
+}
+
 func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) *RPCTransaction {
 
-	from, _ := types.Sender(types.HomesteadSigner{}, tx)
+	from, _ := types.Sender(txSigner(tx), tx)
 	v, r, s := tx.RawSignatureValues()
 
 	result := &RPCTransaction{
@@ -1221,10 +1501,10 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 	return result
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 
-	log.Info("api.go newRPCPendingTransaction", "tx", tx.Hash())
+	log.Info("api.go NewRPCPendingTransaction", "tx", tx.Hash())
 	return newRPCTransaction(tx, common.Hash{}, 0, 0)
 }
 
@@ -1346,12 +1626,65 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	}
 
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx)
+		return NewRPCPendingTransaction(tx)
 	}
 
 	return nil
 }
 
+// maxAddressTransactions is the maximum number of transactions returned by a
+// single eth_getTransactionsByAddress call.
+const maxAddressTransactions = 100
+
+// AddressTransactions is a page of the transactions sent from or to an address.
+type AddressTransactions struct {
+	Transactions  []*RPCTransaction `json:"transactions"`
+	IndexedBlock  hexutil.Uint64    `json:"indexedBlock"`  // Last block covered by the address index
+	NextPageToken *hexutil.Uint64   `json:"nextPageToken"` // Token of the next page, nil if there are no more
+}
+
+// GetTransactionsByAddress returns the transactions sent from or to the given
+// address within a block range, oldest first. Results are paged, the returned
+// page token can be passed back with the same range to retrieve the next page.
+// The node must be running with the address transaction index enabled.
+func (s *PublicTransactionPoolAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, pageToken *hexutil.Uint64) (*AddressTransactions, error) {
+	db := s.b.ChainDb()
+
+	indexHead := core.GetAddressTxIndexHead(db)
+	if indexHead == (common.Hash{}) {
+		return nil, core.ErrAddressIndexDisabled
+	}
+	indexed := core.GetBlockNumber(db, indexHead)
+
+	// Resolve the block range, capping it to the indexed blocks
+	header, err := s.b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	from, to := uint64(fromBlock.Int64()), uint64(toBlock.Int64())
+	if fromBlock < 0 {
+		from = header.Number.Uint64()
+	}
+	if toBlock < 0 {
+		to = header.Number.Uint64()
+	}
+	if to > indexed {
+		to = indexed
+	}
+	result := &AddressTransactions{Transactions: []*RPCTransaction{}, IndexedBlock: hexutil.Uint64(indexed)}
+	if from > to {
+		return result, nil
+	}
+	entries, next := core.GetAddressTxs(db, address, from, to, (*uint64)(pageToken), maxAddressTransactions)
+	for _, entry := range entries {
+		if tx, blockHash, blockNumber, index := core.GetTransaction(db, entry.TxHash); tx != nil {
+			result.Transactions = append(result.Transactions, newRPCTransaction(tx, blockHash, blockNumber, index))
+		}
+	}
+	result.NextPageToken = (*hexutil.Uint64)(next)
+	return result, nil
+}
+
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
 func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 
@@ -1375,7 +1708,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 		return nil, nil
 	}
 	receipt, _, _, _ := core.GetReceipt(s.b.ChainDb(), hash) // Old receipts don't have the lookup data available
-	from, _ := types.Sender(types.HomesteadSigner{}, tx)
+	from, _ := types.Sender(txSigner(tx), tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
@@ -1391,8 +1724,13 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
+		"epoch":             hexutil.Uint64(receipt.Epoch),
 	}
 
+	// Assign the assigned token amount for AssignToken transactions.
+	if tx.Type() == protocol.AssignToken && receipt.AssignedAmount != nil {
+		fields["assignedAmount"] = (*hexutil.Big)(receipt.AssignedAmount)
+	}
 	// Assign receipt status or post state.
 	if len(receipt.PostState) > 0 {
 		fields["root"] = hexutil.Bytes(receipt.PostState)
@@ -1425,7 +1763,7 @@ func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transacti
 
 	// Request the wallet to sign the transaction
 	var chainID *big.Int
-	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
+	if config := s.b.ChainConfig(); config.IsReplayProtected(s.b.CurrentBlock().Number()) {
 		chainID = config.ChainId
 	}
 	return wallet.SignTx(account, tx, chainID)
@@ -1485,7 +1823,7 @@ func (args *SendTxArgs) ToTransaction() (*types.Transaction, error) {
 	//判断交易地址是否为空
 	if args.To == nil {
 
-		if (args.Type >= protocol.SetValidator) && (args.Type <= protocol.AssignToken) {
+		if (args.Type >= protocol.SetValidator) && (args.Type <= protocol.UpdateContract) {
 
 			//return types.NewBaseContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), args.Data), nil
 			return nil, errors.New("base contract transaction type not found contract address")
@@ -1515,6 +1853,11 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 		return common.Hash{}, err
 	}
 
+	//拒绝手续费超过上限的交易，防止误操作或者恶意请求耗尽账户余额
+	if err := checkTxFee(tx.GasPrice(), tx.Gas(), b.RPCTxFeeCap()); err != nil {
+		return common.Hash{}, err
+	}
+
 	//发送交易
 	if err := b.SendTx(ctx, tx); err != nil {
 		log.Error("SubmitTransaction SendTx", "error", err, "txType", tx.Type())
@@ -1523,7 +1866,7 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 
 	//如果to为空得到签名者，并进行签名
 	if tx.To() == nil {
-		from, err := types.Sender(types.HomesteadSigner{}, tx)
+		from, err := types.Sender(txSigner(tx), tx)
 		if err != nil {
 			log.Error("SubmitTransaction Sender", "error", err)
 			return common.Hash{}, err
@@ -1536,6 +1879,18 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 	return tx.Hash(), nil
 }
 
+//检查交易的手续费(gasPrice * gas)是否超过以ether为单位的上限，上限为0时不检查
+func checkTxFee(gasPrice, gas *big.Int, cap float64) error {
+	if cap == 0 {
+		return nil
+	}
+	fee := new(big.Float).Quo(new(big.Float).SetInt(new(big.Int).Mul(gasPrice, gas)), new(big.Float).SetFloat64(params.Ether))
+	if feeFloat, _ := fee.Float64(); feeFloat > cap {
+		return fmt.Errorf("tx fee (%.2f ether) exceeds the configured cap (%.2f ether)", feeFloat, cap)
+	}
+	return nil
+}
+
 //用户通过JSON RPC发起eth_sendTransaction请求，最终会调用PublicTransactionPoolAPI
 //SendTransaction为给定的参数创建一个交易，对其进行签名并将其提交给交易池。
 func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args SendTxArgs) (common.Hash, error) {
@@ -1567,7 +1922,7 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	}
 
 	var chainID *big.Int
-	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
+	if config := s.b.ChainConfig(); config.IsReplayProtected(s.b.CurrentBlock().Number()) {
 		chainID = config.ChainId
 	}
 	signed, err := wallet.SignTx(account, tx, chainID)
@@ -1580,8 +1935,8 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
 
 	log.Info("(s *PublicTransactionPoolAPI) SendRawTransaction", "len", len(encodedTx), "encodedTx", encodedTx)
-	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+	tx, err := types.DecodeRawTransaction(encodedTx)
+	if err != nil {
 
 		log.Error("(s *PublicTransactionPoolAPI) SendRawTransaction", "error", err, "encodedTx", encodedTx)
 		return common.Hash{}, err
@@ -1619,9 +1974,9 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encod
 	}
 
 	//获取交易发起用户
-	sender, err := types.Sender(types.HomesteadSigner{}, tx)
+	sender, err := types.Sender(txSigner(tx), tx)
 	if err != nil {
-		panic(fmt.Errorf("invalid transaction: %v", err))
+		return common.Hash{}, fmt.Errorf("invalid transaction signature: %v", err)
 	}
 	log.Info("(s *PublicTransactionPoolAPI) SendRawTransaction types.Sender", "from", sender.String())
 
@@ -1666,6 +2021,35 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
+// SignTypedData_v4 calculates an ECDSA signature over EIP-712 structured data:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message)).
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+//
+// The account associated with addr must be unlocked.
+//
+// https://eips.ethereum.org/EIPS/eip-712
+func (s *PublicTransactionPoolAPI) SignTypedData_v4(addr common.Address, typedData accounts.TypedData) (hexutil.Bytes, error) {
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := typedData.SigHash()
+	if err != nil {
+		return nil, err
+	}
+	// Sign the typed data hash with the wallet
+	signature, err := wallet.SignHash(account, hash)
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -1693,6 +2077,9 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return nil, err
 	}
+	if err := checkTxFee(trans.GasPrice(), trans.Gas(), s.b.RPCTxFeeCap()); err != nil {
+		return nil, err
+	}
 
 	//tx, err := s.sign(args.From, args.toTransaction())
 	tx, err := s.sign(args.From, trans)
@@ -1717,17 +2104,108 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	transactions := make([]*RPCTransaction, 0, len(pending))
 	for _, tx := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
-		/*if tx.Protected() {
+		if tx.Protected() {
 			signer = types.NewEIP155Signer(tx.ChainId())
-		}*/
+		}
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err == nil {
-			transactions = append(transactions, newRPCPendingTransaction(tx))
+			transactions = append(transactions, NewRPCPendingTransaction(tx))
 		}
 	}
 	return transactions, nil
 }
 
+// maxPendingTransactions is the maximum number of transactions returned by a
+// single eth_filterPendingTransactions call.
+const maxPendingTransactions = 100
+
+// PendingTxQuery selects the pending transactions returned by
+// eth_filterPendingTransactions. Empty fields match any transaction.
+type PendingTxQuery struct {
+	From  *common.Address   `json:"from"`  // only return transactions sent by this account
+	To    *common.Address   `json:"to"`    // only return transactions sent to this account
+	Types []protocol.TxType `json:"types"` // only return transactions of these types
+}
+
+// matches returns whether the transaction sent by from satisfies the query.
+func (q *PendingTxQuery) matches(tx *types.Transaction, from common.Address) bool {
+	if q.From != nil && *q.From != from {
+		return false
+	}
+	if q.To != nil && (tx.To() == nil || *tx.To() != *q.To) {
+		return false
+	}
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, typ := range q.Types {
+		if tx.Type() == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingTransactionsPage is a page of the pending transactions matching a query.
+type PendingTransactionsPage struct {
+	Transactions  []*RPCTransaction `json:"transactions"`
+	Total         hexutil.Uint64    `json:"total"`         // Number of pending transactions matching the query
+	NextPageToken *hexutil.Uint64   `json:"nextPageToken"` // Token of the next page, nil if there are no more
+}
+
+// FilterPendingTransactions returns the transactions in the pool that match
+// the given query, regardless of whether the node manages their sender. They
+// are ordered by sender and nonce and paged, the returned page token can be
+// passed back with the same query to retrieve the next page. As the pool
+// changes between calls, pages may skip or repeat transactions.
+func (s *PublicTransactionPoolAPI) FilterPendingTransactions(query PendingTxQuery, pageToken *hexutil.Uint64) (*PendingTransactionsPage, error) {
+	pending, err := s.b.GetPoolTransactions()
+	if err != nil {
+		return nil, err
+	}
+	type pendingTx struct {
+		tx   *types.Transaction
+		from common.Address
+	}
+	matched := make([]pendingTx, 0, len(pending))
+	for _, tx := range pending {
+		var signer types.Signer = types.HomesteadSigner{}
+		if tx.Protected() {
+			signer = types.NewEIP155Signer(tx.ChainId())
+		}
+		from, _ := types.Sender(signer, tx)
+		if query.matches(tx, from) {
+			matched = append(matched, pendingTx{tx, from})
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].from != matched[j].from {
+			return bytes.Compare(matched[i].from[:], matched[j].from[:]) < 0
+		}
+		return matched[i].tx.Nonce() < matched[j].tx.Nonce()
+	})
+
+	page := &PendingTransactionsPage{Transactions: []*RPCTransaction{}, Total: hexutil.Uint64(len(matched))}
+	start := 0
+	if pageToken != nil {
+		if uint64(*pageToken) >= uint64(len(matched)) {
+			return page, nil
+		}
+		start = int(*pageToken)
+	}
+	end := start + maxPendingTransactions
+	if end < len(matched) {
+		next := hexutil.Uint64(end)
+		page.NextPageToken = &next
+	} else {
+		end = len(matched)
+	}
+	for _, p := range matched[start:end] {
+		page.Transactions = append(page.Transactions, NewRPCPendingTransaction(p.tx))
+	}
+	return page, nil
+}
+
 // Resend accepts an existing transaction and a new gas price and limit. It will remove
 // the given transaction from the pool and reinsert it with the new gas price and limit.
 func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxArgs, gasPrice, gasLimit *hexutil.Big) (common.Hash, error) {
@@ -1750,9 +2228,9 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 
 	for _, p := range pending {
 		var signer types.Signer = types.HomesteadSigner{}
-		/*if p.Protected() {
+		if p.Protected() {
 			signer = types.NewEIP155Signer(p.ChainId())
-		}*/
+		}
 		wantSigHash := signer.Hash(matchTx)
 
 		if pFrom, err := types.Sender(signer, p); err == nil && pFrom == sendArgs.From && signer.Hash(p) == wantSigHash {
@@ -1768,6 +2246,9 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 			if err != nil {
 				return common.Hash{}, err
 			}
+			if err := checkTxFee(trans.GasPrice(), trans.Gas(), s.b.RPCTxFeeCap()); err != nil {
+				return common.Hash{}, err
+			}
 
 			//signedTx, err := s.sign(sendArgs.From, sendArgs.toTransaction())
 			signedTx, err := s.sign(sendArgs.From, trans)
@@ -1798,11 +2279,26 @@ func NewPublicDebugAPI(b Backend) *PublicDebugAPI {
 	return &PublicDebugAPI{b: b}
 }
 
-// GetBlockRlp retrieves the RLP encoded for of a single block.
-func (api *PublicDebugAPI) GetBlockRlp(ctx context.Context, number uint64) (string, error) {
-	block, _ := api.b.BlockByNumber(ctx, rpc.BlockNumber(number))
+// blockByNumberOrHash retrieves a block either by number or by hash.
+func (api *PublicDebugAPI) blockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	var block *types.Block
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block, _ = api.b.GetBlock(ctx, hash)
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		block, _ = api.b.BlockByNumber(ctx, number)
+	}
 	if block == nil {
-		return "", fmt.Errorf("block #%d not found", number)
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	return block, nil
+}
+
+// GetBlockRlp retrieves the RLP encoded for of a single block, identified by
+// number or hash.
+func (api *PublicDebugAPI) GetBlockRlp(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (string, error) {
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return "", err
 	}
 	encoded, err := rlp.EncodeToBytes(block)
 	if err != nil {
@@ -1811,15 +2307,78 @@ func (api *PublicDebugAPI) GetBlockRlp(ctx context.Context, number uint64) (stri
 	return fmt.Sprintf("%x", encoded), nil
 }
 
-// PrintBlock retrieves a block and returns its pretty printed form.
-func (api *PublicDebugAPI) PrintBlock(ctx context.Context, number uint64) (string, error) {
-	block, _ := api.b.BlockByNumber(ctx, rpc.BlockNumber(number))
-	if block == nil {
-		return "", fmt.Errorf("block #%d not found", number)
+// PrintBlock retrieves a block, identified by number or hash, and returns its
+// pretty printed form, including the dpos context roots of the header.
+func (api *PublicDebugAPI) PrintBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (string, error) {
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return "", err
 	}
 	return block.String(), nil
 }
 
+// RPCInternalTransaction is a value transfer made by a contract while executing
+// a transaction.
+type RPCInternalTransaction struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint64 `json:"transactionIndex"`
+	Type        string         `json:"type"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *hexutil.Big   `json:"value"`
+	Depth       hexutil.Uint64 `json:"depth"`
+}
+
+// GetInternalTransactions returns the value transfers made by contracts while
+// executing a transaction, given its hash, or all transactions of a block, given
+// its number or hash. Transfers of reverted calls and failed transactions are not
+// included. Only blocks processed while the internal transaction index was
+// enabled are available.
+func (api *PublicDebugAPI) GetInternalTransactions(ctx context.Context, blockNrOrTxHash rpc.BlockNumberOrHash) ([]*RPCInternalTransaction, error) {
+	var (
+		db      = api.b.ChainDb()
+		block   *types.Block
+		txIndex = -1
+	)
+	// A hash is looked up as a transaction first, then as a block
+	if hash, ok := blockNrOrTxHash.Hash(); ok {
+		if tx, blockHash, _, index := core.GetTransaction(db, hash); tx != nil {
+			block, _ = api.b.GetBlock(ctx, blockHash)
+			txIndex = int(index)
+		}
+	}
+	if block == nil {
+		var err error
+		if block, err = api.blockByNumberOrHash(ctx, blockNrOrTxHash); err != nil {
+			return nil, err
+		}
+		txIndex = -1
+	}
+	transfers, ok := core.GetInternalTransfers(db, block.Hash(), block.NumberU64())
+	if !ok {
+		return nil, core.ErrInternalTxNotIndexed
+	}
+	txs := block.Transactions()
+	result := []*RPCInternalTransaction{}
+	for _, transfer := range transfers {
+		if transfer.TxIndex >= uint64(len(txs)) || (txIndex >= 0 && transfer.TxIndex != uint64(txIndex)) {
+			continue
+		}
+		result = append(result, &RPCInternalTransaction{
+			BlockNumber: hexutil.Uint64(block.NumberU64()),
+			TxHash:      txs[transfer.TxIndex].Hash(),
+			TxIndex:     hexutil.Uint64(transfer.TxIndex),
+			Type:        transfer.Type,
+			From:        transfer.From,
+			To:          transfer.To,
+			Value:       (*hexutil.Big)(transfer.Value),
+			Depth:       hexutil.Uint64(transfer.Depth),
+		})
+	}
+	return result, nil
+}
+
 // PrivateDebugAPI is the collection of Ethereum APIs exposed over the private
 // debugging endpoint.
 type PrivateDebugAPI struct {
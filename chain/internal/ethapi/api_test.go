@@ -1,25 +1,55 @@
 package ethapi
 
 import (
+	"context"
+	"math"
 	"math/big"
 	"testing"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
-	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/rpc"
 )
 
 func TestToTransaction(t *testing.T) {
 	nonce := uint64(0)
 	args := &SendTxArgs{
-		Type:     types.ApplyCandidate,
+		Type:     protocol.Binary,
 		Nonce:    (*hexutil.Uint64)(&nonce),
 		Gas:      (*hexutil.Big)(big.NewInt(0)),
 		GasPrice: (*hexutil.Big)(big.NewInt(0)),
 		Value:    (*hexutil.Big)(big.NewInt(0)),
 		To:       nil,
 	}
-	tx := args.toTransaction()
+	tx, err := args.ToTransaction()
+	if err != nil {
+		t.Fatalf("failed to convert transaction: %v", err)
+	}
 	if tx.To() != nil {
 		t.Errorf("transaction receiptent nil is expected, but got %x", tx.To())
 	}
 }
+
+//记录请求的区块数量的Gas价格历史后端
+type historyBackend struct {
+	Backend
+	count uint64
+}
+
+func (b *historyBackend) GasPriceHistory(ctx context.Context, start rpc.BlockNumber, count uint64, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	b.count = count
+	return new(big.Int), nil, nil, nil
+}
+
+//超出int范围的区块数量原样交给后端截断，不能转换成负数
+func TestGasPriceHistoryCount(t *testing.T) {
+	for _, count := range []uint64{0, 1, math.MaxInt64, math.MaxInt64 + 1, math.MaxUint64} {
+		backend := new(historyBackend)
+		if _, err := NewPublicEthereumAPI(backend).GasPriceHistory(context.Background(), rpc.LatestBlockNumber, hexutil.Uint64(count), nil); err != nil {
+			t.Fatalf("count %d: failed to retrieve price history: %v", count, err)
+		}
+		if backend.count != count {
+			t.Errorf("count mismatch: have %d, want %d", backend.count, count)
+		}
+	}
+}
@@ -42,6 +42,7 @@ type Backend interface {
 	Downloader() *downloader.Downloader
 	ProtocolVersion() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
+	GasPriceHistory(ctx context.Context, start rpc.BlockNumber, count uint64, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error)
 	ChainDb() ethdb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
@@ -54,6 +55,7 @@ type Backend interface {
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 	GetTd(blockHash common.Hash) *big.Int
+	DposContext(ctx context.Context, header *types.Header) (*types.DposContext, error)
 	GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error)
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
@@ -76,6 +78,9 @@ type Backend interface {
 	SetPassword(password string)
 	Boker() bokerapi.Api
 	DecodeParams(code []byte) ([]byte, error)
+	CallCache() *CallCache //eth_call结果缓存，为nil时不缓存
+	RPCGasCap() uint64     //eth_call和eth_estimateGas可以使用的Gas上限，为0时不限制
+	RPCTxFeeCap() float64  //通过RPC发送和签名的交易手续费上限(单位ether)，为0时不限制
 }
 
 func GetAPIs(apiBackend Backend, boker bokerapi.Api) []rpc.API {
@@ -120,6 +125,16 @@ func GetAPIs(apiBackend Backend, boker bokerapi.Api) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
 			Public:    false,
+		}, {
+			Namespace: "boker",
+			Version:   "1.0",
+			Service:   NewPublicBokerAPI(apiBackend),
+			Public:    true,
+		}, {
+			Namespace: "boker",
+			Version:   "1.0",
+			Service:   NewPrivateBokerAPI(apiBackend, nonceLock),
+			Public:    false,
 		},
 	}
 }
@@ -0,0 +1,472 @@
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/boker/api"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/core/vm"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	"github.com/Bokerchain/Boker/chain/rpc"
+)
+
+const (
+	maxAssignHistoryRange   = 100000 //一次查询分配通证记录的最大区块数量
+	maxBalanceHistoryPoints = 10000  //一次查询余额历史的最大采样点数量
+	maxTokenTransfers       = 100    //一次查询返回的ERC-20转账最大数量
+	maxTokenBalances        = 1000   //一次查询余额的通证最大数量
+	tokenBalanceGas         = 100000 //查询一个通证余额时调用balanceOf的Gas上限
+)
+
+var tokenBalanceOfSelector = crypto.Keccak256([]byte("balanceOf(address)"))[:4] //ERC-20 balanceOf方法的选择器
+
+var errBokerUnavailable = errors.New("boker backend not available")
+var errDposContextUnavailable = errors.New("dpos context of current block not available")
+
+//基础合约注册信息
+type RPCBaseContract struct {
+	Address common.Address        `json:"address"`
+	Type    protocol.ContractType `json:"type"`
+	Name    string                `json:"name"`
+}
+
+//基础合约类型的名称
+func contractTypeName(contractType protocol.ContractType) string {
+	switch contractType {
+	case protocol.SystemContract:
+		return "system"
+	case protocol.PersonalContract:
+		return "personal"
+	default:
+		return "binary"
+	}
+}
+
+//boker命名空间下任何人都可以调用的基础合约查询接口
+type PublicBokerAPI struct {
+	b Backend
+}
+
+func NewPublicBokerAPI(b Backend) *PublicBokerAPI {
+	return &PublicBokerAPI{b}
+}
+
+//得到所有已注册的基础合约，按地址排序
+func (s *PublicBokerAPI) GetContracts() ([]RPCBaseContract, error) {
+
+	boker := s.b.Boker()
+	if boker == nil {
+		return nil, errBokerUnavailable
+	}
+	contracts := make([]RPCBaseContract, 0)
+	for address, contractType := range boker.GetContracts() {
+		contracts = append(contracts, RPCBaseContract{Address: address, Type: contractType, Name: contractTypeName(contractType)})
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return bytes.Compare(contracts[i].Address.Bytes(), contracts[j].Address.Bytes()) < 0
+	})
+	return contracts, nil
+}
+
+//得到合约地址的基础合约类型，没有注册的合约为普通合约
+func (s *PublicBokerAPI) GetContractType(address common.Address) (RPCBaseContract, error) {
+
+	boker := s.b.Boker()
+	if boker == nil {
+		return RPCBaseContract{}, errBokerUnavailable
+	}
+	contractType, err := boker.GetContract(address)
+	if err != nil {
+		return RPCBaseContract{}, err
+	}
+	return RPCBaseContract{Address: address, Type: contractType, Name: contractTypeName(contractType)}, nil
+}
+
+//根据最新区块的Dpos状态得到当前负责分配通证的节点
+func (s *PublicBokerAPI) GetCurrentTokenNoder(ctx context.Context) (common.Address, error) {
+
+	current, err := s.b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil || current == nil {
+		return common.Address{}, errors.New("failed to lookup latest block")
+	}
+	genesis, err := s.b.HeaderByNumber(ctx, 0)
+	if err != nil || genesis == nil {
+		return common.Address{}, errors.New("failed to lookup genesis block")
+	}
+	dposContext, err := s.b.DposContext(ctx, current)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return dposContext.GetCurrentTokenNoder(genesis.Time.Int64())
+}
+
+//分配通证交易中合约转出的一笔金额
+type RPCTokenTransfer struct {
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Amount *hexutil.Big   `json:"amount"`
+}
+
+//一笔分配通证交易的审计记录
+type RPCTokenAssignment struct {
+	BlockNumber hexutil.Uint64      `json:"blockNumber"`
+	BlockHash   common.Hash         `json:"blockHash"`
+	TxHash      common.Hash         `json:"transactionHash"`
+	TxIndex     hexutil.Uint64      `json:"transactionIndex"`
+	Noder       common.Address      `json:"noder"`
+	Amount      *hexutil.Big        `json:"amount"`
+	Failed      bool                `json:"failed"`
+	Recipients  []*RPCTokenTransfer `json:"recipients"`
+}
+
+//返回规范链上[fromBlock, toBlock]范围内所有分配通证交易、分配节点以及每个接收者得到的金额。
+//只包含本节点执行过的区块，快速同步导入的区块没有记录
+func (s *PublicBokerAPI) GetAssignHistory(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([]*RPCTokenAssignment, error) {
+
+	from, err := s.resolveNumber(ctx, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.resolveNumber(ctx, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("fromBlock %d is after toBlock %d", from, to)
+	}
+	if to-from >= maxAssignHistoryRange {
+		return nil, fmt.Errorf("block range too large, at most %d blocks per query", maxAssignHistoryRange)
+	}
+	db := s.b.ChainDb()
+	history := make([]*RPCTokenAssignment, 0)
+	for number := from; number <= to; number++ {
+		hash := core.GetCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			break
+		}
+		for _, assignment := range core.GetTokenAssignments(db, hash, number) {
+			entry := &RPCTokenAssignment{
+				BlockNumber: hexutil.Uint64(number),
+				BlockHash:   hash,
+				TxHash:      assignment.TxHash,
+				TxIndex:     hexutil.Uint64(assignment.TxIndex),
+				Noder:       assignment.Noder,
+				Amount:      (*hexutil.Big)(assignment.Amount),
+				Failed:      assignment.Failed,
+				Recipients:  make([]*RPCTokenTransfer, 0, len(assignment.Transfers)),
+			}
+			for _, transfer := range assignment.Transfers {
+				entry.Recipients = append(entry.Recipients, &RPCTokenTransfer{From: transfer.From, To: transfer.To, Amount: (*hexutil.Big)(transfer.Amount)})
+			}
+			history = append(history, entry)
+		}
+	}
+	return history, nil
+}
+
+//账户在一个区块执行之后的余额
+type RPCBalancePoint struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	Balance     *hexutil.Big   `json:"balance"`
+}
+
+//账户的余额历史以及索引覆盖的区块范围
+type BalanceHistory struct {
+	IndexedFrom  hexutil.Uint64     `json:"indexedFrom"`  //可以查询的最早区块
+	IndexedBlock hexutil.Uint64     `json:"indexedBlock"` //最后索引的区块
+	Balances     []*RPCBalancePoint `json:"balances"`
+}
+
+//按step个区块的间隔返回账户在[fromBlock, toBlock]范围内的余额，范围限制在余额历史索引覆盖的区块之内，
+//节点需要开启余额历史索引，只有开启之后执行的区块被索引
+func (s *PublicBokerAPI) GetBalanceHistory(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, step hexutil.Uint64) (*BalanceHistory, error) {
+
+	db := s.b.ChainDb()
+	indexHead := core.GetBalanceIndexHead(db)
+	if indexHead == (common.Hash{}) {
+		return nil, core.ErrBalanceIndexDisabled
+	}
+	if step == 0 {
+		return nil, errors.New("step must be positive")
+	}
+	indexed := core.GetBlockNumber(db, indexHead)
+	indexedFrom := indexed
+	if tail := core.GetBalanceIndexTail(db); tail > 0 && tail-1 < indexed {
+		indexedFrom = tail - 1
+	}
+
+	//区块范围限制在索引覆盖的区块之内
+	from, err := s.resolveNumber(ctx, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.resolveNumber(ctx, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from < indexedFrom {
+		from = indexedFrom
+	}
+	if to > indexed {
+		to = indexed
+	}
+	history := &BalanceHistory{IndexedFrom: hexutil.Uint64(indexedFrom), IndexedBlock: hexutil.Uint64(indexed), Balances: []*RPCBalancePoint{}}
+	if from > to {
+		return history, nil
+	}
+	if (to-from)/uint64(step) >= maxBalanceHistoryPoints {
+		return nil, fmt.Errorf("too many points, at most %d per query", maxBalanceHistoryPoints)
+	}
+
+	//索引的最后一个区块之后没有变化的余额与该区块的余额相同
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(indexed))
+	if state == nil || err != nil {
+		return nil, err
+	}
+	latest := state.GetBalance(address)
+
+	for number := from; number <= to; number += uint64(step) {
+		balance, ok := core.GetBalanceAt(db, address, number)
+		if !ok {
+			balance = latest
+		}
+		history.Balances = append(history.Balances, &RPCBalancePoint{BlockNumber: hexutil.Uint64(number), Balance: (*hexutil.Big)(balance)})
+		if number+uint64(step) < number {
+			break
+		}
+	}
+	return history, nil
+}
+
+//一笔ERC-20转账事件
+type RPCTokenTransferEvent struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	LogIndex    hexutil.Uint64 `json:"logIndex"`
+	Token       common.Address `json:"token"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *hexutil.Big   `json:"value"`
+}
+
+//账户的一页ERC-20转账，NextPageToken不为空时可以用于查询下一页
+type TokenTransfers struct {
+	Transfers     []*RPCTokenTransferEvent `json:"transfers"`
+	IndexedBlock  hexutil.Uint64           `json:"indexedBlock"`
+	NextPageToken *hexutil.Uint64          `json:"nextPageToken"`
+}
+
+//分页返回账户在[fromBlock, toBlock]范围内转出或者收到的ERC-20转账，节点需要开启通证转账索引，
+//范围限制在已经索引的区块之内
+func (s *PublicBokerAPI) GetTokenTransfers(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, pageToken *hexutil.Uint64) (*TokenTransfers, error) {
+
+	db := s.b.ChainDb()
+	indexHead := core.GetTokenIndexHead(db)
+	if indexHead == (common.Hash{}) {
+		return nil, core.ErrTokenIndexDisabled
+	}
+	indexed := core.GetBlockNumber(db, indexHead)
+
+	//区块范围限制在已经索引的区块之内
+	from, err := s.resolveNumber(ctx, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.resolveNumber(ctx, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if to > indexed {
+		to = indexed
+	}
+	result := &TokenTransfers{Transfers: []*RPCTokenTransferEvent{}, IndexedBlock: hexutil.Uint64(indexed)}
+	if from > to {
+		return result, nil
+	}
+	entries, next := core.GetTokenTransfers(db, address, from, to, (*uint64)(pageToken), maxTokenTransfers)
+	for _, entry := range entries {
+		result.Transfers = append(result.Transfers, &RPCTokenTransferEvent{
+			BlockNumber: hexutil.Uint64(entry.BlockNumber),
+			TxHash:      entry.TxHash,
+			LogIndex:    hexutil.Uint64(entry.LogIndex),
+			Token:       entry.Token,
+			From:        entry.From,
+			To:          entry.To,
+			Value:       (*hexutil.Big)(entry.Value),
+		})
+	}
+	result.NextPageToken = (*hexutil.Uint64)(next)
+	return result, nil
+}
+
+//账户持有的一种通证
+type RPCTokenBalance struct {
+	Token   common.Address `json:"token"`
+	Balance *hexutil.Big   `json:"balance"`
+}
+
+//返回账户在最新区块持有的ERC-20通证余额，通证列表来自通证转账索引，余额通过调用合约的balanceOf得到，
+//调用失败或者余额为零的通证不返回
+func (s *PublicBokerAPI) GetTokenBalances(ctx context.Context, address common.Address) ([]*RPCTokenBalance, error) {
+
+	db := s.b.ChainDb()
+	if core.GetTokenIndexHead(db) == (common.Hash{}) {
+		return nil, core.ErrTokenIndexDisabled
+	}
+	tokens := core.GetHeldTokens(db, address)
+	if len(tokens) > maxTokenBalances {
+		tokens = tokens[:maxTokenBalances]
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	var (
+		chainAPI = NewPublicBlockChainAPI(s.b)
+		data     = append(common.CopyBytes(tokenBalanceOfSelector), common.LeftPadBytes(address.Bytes(), 32)...)
+		balances = []*RPCTokenBalance{}
+	)
+	for _, token := range tokens {
+		token := token
+		args := CallArgs{From: address, To: &token, Gas: hexutil.Big(*big.NewInt(tokenBalanceGas)), Data: data}
+		res, _, failed, err := chainAPI.applyCall(ctx, state, header, args, vm.Config{})
+		if err != nil || failed || len(res) < 32 {
+			continue
+		}
+		balance := new(big.Int).SetBytes(res[:32])
+		if balance.Sign() == 0 {
+			continue
+		}
+		balances = append(balances, &RPCTokenBalance{Token: token, Balance: (*hexutil.Big)(balance)})
+	}
+	return balances, nil
+}
+
+//将区块号(包括latest和pending)转换为具体的区块号
+func (s *PublicBokerAPI) resolveNumber(ctx context.Context, number rpc.BlockNumber) (uint64, error) {
+	if number >= 0 {
+		return uint64(number), nil
+	}
+	header, err := s.b.HeaderByNumber(ctx, number)
+	if err != nil || header == nil {
+		return 0, errors.New("failed to lookup block")
+	}
+	return header.Number.Uint64(), nil
+}
+
+//boker命名空间下只允许节点管理者调用的基础合约管理接口
+type PrivateBokerAPI struct {
+	b         Backend
+	nonceLock *AddrLocker
+}
+
+func NewPrivateBokerAPI(b Backend, nonceLock *AddrLocker) *PrivateBokerAPI {
+	return &PrivateBokerAPI{b, nonceLock}
+}
+
+//以当前Coinbase提交设置基础合约的交易，Coinbase必须是验证者，abiJson为可选的合约abi
+func (s *PrivateBokerAPI) SetSystemContract(ctx context.Context, address common.Address, contractType protocol.ContractType, abiJson *string) (common.Hash, error) {
+
+	if s.b.Boker() == nil {
+		return common.Hash{}, errBokerUnavailable
+	}
+	abi := ""
+	if abiJson != nil {
+		abi = *abiJson
+	}
+	return NewPublicBlockChainAPI(s.b).SetBaseContracts(ctx, address, contractType, abi)
+}
+
+//得到验证者为替换基础合约地址需要签名的Hash，包含当前基础合约注册表的Hash和替换生效的区块号
+func (s *PublicBokerAPI) GetUpdateBaseContractHash(old common.Address, new common.Address, contractType protocol.ContractType, number hexutil.Uint64) (common.Hash, error) {
+
+	boker := s.b.Boker()
+	if boker == nil {
+		return common.Hash{}, errBokerUnavailable
+	}
+	return bokerapi.UpdateContractHash(boker.RegistryHash(), old, new, contractType, uint64(number)), nil
+}
+
+//以当前Coinbase对替换基础合约地址签名，Coinbase必须是当前周期的验证者并且已经解锁
+func (s *PrivateBokerAPI) SignBaseContractUpdate(ctx context.Context, old common.Address, new common.Address, contractType protocol.ContractType, number hexutil.Uint64) (hexutil.Bytes, error) {
+
+	hash, err := NewPublicBokerAPI(s.b).GetUpdateBaseContractHash(old, new, contractType, number)
+	if err != nil {
+		return nil, err
+	}
+	coinbase, err := s.b.Coinbase()
+	if err != nil {
+		return nil, err
+	}
+	dposContext := s.b.CurrentBlock().DposContext
+	if dposContext == nil {
+		return nil, errDposContextUnavailable
+	}
+	if !dposContext.IsValidator(coinbase) {
+		return nil, errors.New("current coinbase is not a validator")
+	}
+	account := accounts.Account{Address: coinbase}
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.SignHash(account, hash.Bytes())
+}
+
+//以当前Coinbase提交替换基础合约地址的交易，需要超过三分之二的当前周期验证者签名。
+//交易打包时所有节点重新校验签名，替换在number区块结束时生效，返回交易Hash
+func (s *PrivateBokerAPI) UpdateBaseContract(ctx context.Context, old common.Address, new common.Address, contractType protocol.ContractType, number hexutil.Uint64, signatures []hexutil.Bytes) (common.Hash, error) {
+
+	boker := s.b.Boker()
+	if boker == nil {
+		return common.Hash{}, errBokerUnavailable
+	}
+	if contractType != protocol.SystemContract && contractType != protocol.PersonalContract {
+		return common.Hash{}, fmt.Errorf("invalid base contract type %d", contractType)
+	}
+	if (old == common.Address{}) || (new == common.Address{}) || old == new {
+		return common.Hash{}, errors.New("invalid base contract address")
+	}
+	current := s.b.CurrentBlock()
+	if uint64(number) <= current.NumberU64() {
+		return common.Hash{}, fmt.Errorf("activation block %d is not after current block %d", number, current.NumberU64())
+	}
+
+	//提交之前先按照当前状态校验签名，避免打包一笔必然失败的交易
+	dposContext := current.DposContext
+	if dposContext == nil {
+		return common.Hash{}, errDposContextUnavailable
+	}
+	validators, err := dposContext.GetEpochTrie()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	sigs := make([][]byte, len(signatures))
+	for i, sig := range signatures {
+		sigs[i] = sig
+	}
+	hash := bokerapi.UpdateContractHash(boker.RegistryHash(), old, new, contractType, uint64(number))
+	if err := bokerapi.VerifyValidatorSignatures(hash, sigs, validators); err != nil {
+		return common.Hash{}, err
+	}
+	payload, err := rlp.EncodeToBytes(&bokerapi.UpdateContractPayload{Old: old, ContractType: contractType, Number: uint64(number), Signatures: sigs})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	coinbase, err := s.b.Coinbase()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	args := SendTxArgs{From: coinbase, Type: protocol.UpdateContract, To: &new, Data: payload}
+	return NewPublicTransactionPoolAPI(s.b, s.nonceLock).SendTransaction(ctx, args)
+}
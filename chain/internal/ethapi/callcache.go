@@ -0,0 +1,125 @@
+package ethapi
+
+import (
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/crypto/sha3"
+	"github.com/Bokerchain/Boker/chain/event"
+	"github.com/Bokerchain/Boker/chain/metrics"
+	"github.com/Bokerchain/Boker/chain/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+var (
+	callCacheHitCounter   = metrics.NewCounter("rpc/callcache/hits")   //命中缓存的eth_call数量
+	callCacheMissCounter  = metrics.NewCounter("rpc/callcache/misses") //没有命中缓存的eth_call数量
+	callCachePurgeCounter = metrics.NewCounter("rpc/callcache/purges") //新区块到达时清空缓存的次数
+)
+
+//eth_call结果缓存的配置
+type CallCacheConfig struct {
+	Size int           //缓存的调用结果数量，为0时不缓存
+	TTL  time.Duration //调用结果最长的缓存时间，为0时只在新区块到达时失效
+}
+
+var DefaultCallCacheConfig = CallCacheConfig{
+	Size: 0,
+	TTL:  time.Minute,
+}
+
+//可以订阅新区块的链
+type chainHeadSubscriber interface {
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+//缓存的调用结果
+type callCacheEntry struct {
+	result  []byte
+	expires time.Time
+}
+
+//eth_call结果缓存，以区块Hash和调用参数为键，只缓存已确定区块上的调用，新区块到达时全部失效
+type CallCache struct {
+	cache *lru.Cache
+	ttl   time.Duration
+	sub   event.Subscription
+}
+
+//创建调用结果缓存，配置的数量为0时返回nil（不缓存）
+func NewCallCache(config CallCacheConfig, chain chainHeadSubscriber) *CallCache {
+
+	if config.Size <= 0 {
+		return nil
+	}
+	cache, _ := lru.New(config.Size)
+	c := &CallCache{cache: cache, ttl: config.TTL}
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	c.sub = chain.SubscribeChainHeadEvent(heads)
+	go c.loop(heads)
+	return c
+}
+
+//新区块到达时清空缓存，latest对应的区块已经改变，旧区块上的调用很少再被请求
+func (c *CallCache) loop(heads chan core.ChainHeadEvent) {
+
+	for {
+		select {
+		case <-heads:
+			if c.cache.Len() > 0 {
+				c.cache.Purge()
+				callCachePurgeCounter.Inc(1)
+			}
+		case <-c.sub.Err():
+			return
+		}
+	}
+}
+
+//停止监听新区块
+func (c *CallCache) Stop() {
+	if c != nil {
+		c.sub.Unsubscribe()
+	}
+}
+
+//调用结果的缓存键
+func callCacheKey(block common.Hash, args *CallArgs) (key common.Hash) {
+
+	hasher := sha3.NewKeccak256()
+	rlp.Encode(hasher, []interface{}{
+		block,
+		args.From,
+		args.To,
+		args.Gas.ToInt(),
+		args.GasPrice.ToInt(),
+		args.Value.ToInt(),
+		args.Data,
+		args.Extra,
+		args.TxType,
+	})
+	hasher.Sum(key[:0])
+	return key
+}
+
+//查找缓存的调用结果
+func (c *CallCache) get(key common.Hash) ([]byte, bool) {
+
+	if value, ok := c.cache.Get(key); ok {
+		entry := value.(*callCacheEntry)
+		if c.ttl <= 0 || time.Now().Before(entry.expires) {
+			callCacheHitCounter.Inc(1)
+			return common.CopyBytes(entry.result), true
+		}
+		c.cache.Remove(key)
+	}
+	callCacheMissCounter.Inc(1)
+	return nil, false
+}
+
+//缓存调用结果
+func (c *CallCache) put(key common.Hash, result []byte) {
+	c.cache.Add(key, &callCacheEntry{result: common.CopyBytes(result), expires: time.Now().Add(c.ttl)})
+}
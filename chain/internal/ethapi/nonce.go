@@ -0,0 +1,146 @@
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/params"
+	"github.com/Bokerchain/Boker/chain/rpc"
+)
+
+//一次eth_fillNonceGap调用最多填补的Nonce数量
+const maxNonceGapFill = 64
+
+var errNoNonceGap = errors.New("account has no nonce gap")
+
+//交易池中缺失的一段连续Nonce(包含First和Last)
+type NonceGap struct {
+	First hexutil.Uint64 `json:"first"`
+	Last  hexutil.Uint64 `json:"last"`
+}
+
+//账户在交易池中的Nonce情况
+type NonceGaps struct {
+	Confirmed hexutil.Uint64   `json:"confirmed"` //最新区块状态中的账户Nonce
+	Next      hexutil.Uint64   `json:"next"`      //下一个可以执行的Nonce(已经可执行的交易之后)
+	Pending   []hexutil.Uint64 `json:"pending"`   //可执行交易的Nonce
+	Queued    []hexutil.Uint64 `json:"queued"`    //因为前面的Nonce缺失而无法执行的交易的Nonce
+	Gaps      []NonceGap       `json:"gaps"`      //阻塞排队交易的缺失Nonce
+}
+
+//缺失的Nonce数量
+func (n *NonceGaps) missing() uint64 {
+	var count uint64
+	for _, gap := range n.Gaps {
+		count += uint64(gap.Last-gap.First) + 1
+	}
+	return count
+}
+
+//统计账户在交易池中的Nonce以及阻塞排队交易的缺失Nonce
+func nonceGaps(ctx context.Context, b Backend, address common.Address) (*NonceGaps, error) {
+
+	state, _, err := b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	next, err := b.GetPoolNonce(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	result := &NonceGaps{
+		Confirmed: hexutil.Uint64(state.GetNonce(address)),
+		Next:      hexutil.Uint64(next),
+		Pending:   []hexutil.Uint64{},
+		Queued:    []hexutil.Uint64{},
+		Gaps:      []NonceGap{},
+	}
+
+	pending, queue := b.TxPoolContent()
+	for _, tx := range pending[address] {
+		result.Pending = append(result.Pending, hexutil.Uint64(tx.Nonce()))
+	}
+	for _, tx := range queue[address] {
+		result.Queued = append(result.Queued, hexutil.Uint64(tx.Nonce()))
+	}
+	sort.Slice(result.Pending, func(i, j int) bool { return result.Pending[i] < result.Pending[j] })
+	sort.Slice(result.Queued, func(i, j int) bool { return result.Queued[i] < result.Queued[j] })
+
+	//从下一个可执行的Nonce开始，排队交易之间缺失的Nonce都是空洞
+	expected := result.Next
+	for _, nonce := range result.Queued {
+		if nonce < expected {
+			continue
+		}
+		if nonce > expected {
+			result.Gaps = append(result.Gaps, NonceGap{First: expected, Last: nonce - 1})
+		}
+		expected = nonce + 1
+	}
+	return result, nil
+}
+
+//返回账户的下一个可执行Nonce、可执行和排队交易的Nonce，以及阻塞排队交易的缺失Nonce
+func (s *PublicTxPoolAPI) NonceGaps(ctx context.Context, address common.Address) (*NonceGaps, error) {
+	return nonceGaps(ctx, s.b, address)
+}
+
+//为本节点管理的账户填补交易池中缺失的Nonce，每个缺失的Nonce发送一笔给自己的零金额转账，
+//使因为Nonce乱序而排队的交易可以执行。gasPrice为空时使用建议的Gas价格，返回填补交易的Hash
+func (s *PublicTransactionPoolAPI) FillNonceGap(ctx context.Context, address common.Address, gasPrice *hexutil.Big) ([]common.Hash, error) {
+
+	account := accounts.Account{Address: address}
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	s.nonceLock.LockAddr(address)
+	defer s.nonceLock.UnlockAddr(address)
+
+	gaps, err := nonceGaps(ctx, s.b, address)
+	if err != nil {
+		return nil, err
+	}
+	if len(gaps.Gaps) == 0 {
+		return nil, errNoNonceGap
+	}
+	if missing := gaps.missing(); missing > maxNonceGapFill {
+		return nil, fmt.Errorf("%d missing nonces, at most %d can be filled at once", missing, maxNonceGapFill)
+	}
+
+	price := (*big.Int)(gasPrice)
+	if price == nil {
+		if price, err = s.b.SuggestPrice(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var chainID *big.Int
+	if config := s.b.ChainConfig(); config.IsReplayProtected(s.b.CurrentBlock().Number()) {
+		chainID = config.ChainId
+	}
+
+	hashes := make([]common.Hash, 0, gaps.missing())
+	for _, gap := range gaps.Gaps {
+		for nonce := uint64(gap.First); nonce <= uint64(gap.Last); nonce++ {
+			tx := types.NewTransaction(protocol.Binary, nonce, address, new(big.Int), new(big.Int).SetUint64(params.TxGas), price, nil)
+			signed, err := wallet.SignTx(account, tx, chainID)
+			if err != nil {
+				return hashes, err
+			}
+			hash, err := SubmitTransaction(ctx, s.b, signed)
+			if err != nil {
+				return hashes, err
+			}
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, nil
+}
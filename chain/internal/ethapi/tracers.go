@@ -0,0 +1,148 @@
+package ethapi
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Bokerchain/Boker/chain/core/vm"
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+//Go插件中创建追踪器的导出函数名，函数类型为func() vm.Tracer
+const TracerPluginSymbol = "NewTracer"
+
+var ErrTracerExists = errors.New("tracer already registered")
+
+//可以返回追踪结果的追踪器，自定义追踪器需要实现这个接口
+type ResultTracer interface {
+	vm.Tracer
+	GetResult() (interface{}, error)
+}
+
+//每次追踪时创建一个新的追踪器
+type TracerConstructor func() (ResultTracer, error)
+
+var (
+	tracersLock sync.RWMutex
+	tracers     = make(map[string]TracerConstructor) //按名称注册的自定义追踪器
+)
+
+//按名称注册自定义追踪器，名称已经存在时返回错误
+func RegisterTracer(name string, constructor TracerConstructor) error {
+
+	tracersLock.Lock()
+	defer tracersLock.Unlock()
+
+	if _, exist := tracers[name]; exist {
+		return fmt.Errorf("%v: %s", ErrTracerExists, name)
+	}
+	tracers[name] = constructor
+	return nil
+}
+
+//根据名称创建已注册的追踪器，没有注册时返回false
+func NewNamedTracer(name string) (ResultTracer, bool, error) {
+
+	tracersLock.RLock()
+	constructor, ok := tracers[name]
+	tracersLock.RUnlock()
+
+	if !ok {
+		return nil, false, nil
+	}
+	tracer, err := constructor()
+	return tracer, true, err
+}
+
+//得到所有已注册的追踪器名称
+func TracerNames() []string {
+
+	tracersLock.RLock()
+	defer tracersLock.RUnlock()
+
+	names := make([]string, 0, len(tracers))
+	for name := range tracers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//加载目录中的自定义追踪器，.js文件为Javascript追踪器，.so文件为导出NewTracer函数的Go插件，追踪器名称为去掉扩展名的文件名
+func LoadTracers(dir string) error {
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		ext := filepath.Ext(file.Name())
+		name := strings.TrimSuffix(file.Name(), ext)
+
+		var constructor TracerConstructor
+		switch ext {
+		case ".js":
+			constructor, err = loadJavascriptTracer(path)
+		case ".so":
+			constructor, err = loadPluginTracer(path)
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load tracer %s: %v", path, err)
+		}
+		if err := RegisterTracer(name, constructor); err != nil {
+			return err
+		}
+		log.Info("Loaded custom tracer", "name", name, "path", path)
+	}
+	return nil
+}
+
+//读取Javascript追踪器，加载时先编译一次检查代码是否有效
+func loadJavascriptTracer(path string) (TracerConstructor, error) {
+
+	code, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := NewJavascriptTracer(string(code)); err != nil {
+		return nil, err
+	}
+	return func() (ResultTracer, error) {
+		return NewJavascriptTracer(string(code))
+	}, nil
+}
+
+//打开Go插件并查找创建追踪器的函数，加载时先创建一次检查追踪器是否可以返回结果
+func loadPluginTracer(path string) (TracerConstructor, error) {
+
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	symbol, err := p.Lookup(TracerPluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	newTracer, ok := symbol.(func() vm.Tracer)
+	if !ok {
+		return nil, fmt.Errorf("%s has type %T, want func() vm.Tracer", TracerPluginSymbol, symbol)
+	}
+	if _, ok := newTracer().(ResultTracer); !ok {
+		return nil, errors.New("tracer does not implement GetResult() (interface{}, error)")
+	}
+	return func() (ResultTracer, error) {
+		return newTracer().(ResultTracer), nil
+	}, nil
+}
@@ -334,6 +334,31 @@ func (self *JSRE) Evaluate(code string, w io.Writer) error {
 	return fail
 }
 
+// EvaluateJSON executes code and writes the result encoded by JSON.stringify
+// as a single line to w, undefined results being written as null.
+func (self *JSRE) EvaluateJSON(code string, w io.Writer) error {
+	var fail error
+	self.Do(func(vm *otto.Otto) {
+		val, err := vm.Run(code)
+		if err != nil {
+			fail = err
+			return
+		}
+		json, _ := vm.Get("JSON")
+		out, err := json.Object().Call("stringify", val)
+		if err != nil {
+			fail = err
+			return
+		}
+		if out.IsUndefined() {
+			fmt.Fprintln(w, "null")
+		} else {
+			fmt.Fprintln(w, out.String())
+		}
+	})
+	return fail
+}
+
 // Compile compiles and then runs a piece of JS code.
 func (self *JSRE) Compile(filename string, src interface{}) (err error) {
 	self.Do(func(vm *otto.Otto) { _, err = compileAndRun(vm, filename, src) })
@@ -17,6 +17,7 @@
 package jsre
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path"
@@ -135,3 +136,33 @@ func TestLoadScript(t *testing.T) {
 	}
 	jsre.Stop(false)
 }
+
+func TestEvaluateJSON(t *testing.T) {
+	jsre := New("", os.Stdout)
+	defer jsre.Stop(false)
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{`({a: 1, b: ["x", true]})`, "{\"a\":1,\"b\":[\"x\",true]}\n"},
+		{`"text"`, "\"text\"\n"},
+		{`var x = 1`, "null\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := jsre.EvaluateJSON(tt.code, &buf); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.code, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.code, buf.String(), tt.want)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jsre.EvaluateJSON(`throw new Error("fail")`, &buf); err == nil {
+		t.Error("expected error for throwing code")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output for throwing code, got %q", buf.String())
+	}
+}
@@ -28,9 +28,46 @@ var Modules = map[string]string{
 	"personal":   Personal_JS,
 	"rpc":        RPC_JS,
 	"shh":        Shh_JS,
+	"gossip":     Gossip_JS,
 	"swarmfs":    SWARMFS_JS,
 	"txpool":     TxPool_JS,
 	"dpos":       Dpos_JS,
+	"les":        Les_JS,
+	"boker":      Boker_JS,
+}
+
+// Help contains the usage of the methods and properties of the Boker specific
+// modules, printed by <module>.help() in the console.
+var Help = map[string]map[string]string{
+	"dpos": {
+		"getValidators":           "getValidators(block): validators of the epoch at the given block number or tag",
+		"validators":              "validators: validators of the current epoch",
+		"getConfirmedBlockNumber": "getConfirmedBlockNumber(): number of the latest irreversible block",
+		"getIrreversibility":      "getIrreversibility(): latest irreversible block and the signers still needed to advance it",
+		"irreversibility":         "irreversibility: same as getIrreversibility()",
+		"selfCheck":               "selfCheck(account): producer health of the account (null for the local signer), including missed slots and clock skew",
+		"getProof":                "getProof(trie, key, block): merkle proof of the key in the epoch, validator or blockCnt context trie",
+		"signCheckpoint":          "signCheckpoint(hash): sign the last block of an epoch with the local validator for a checkpoint",
+		"addCheckpointSignature":  "addCheckpointSignature(hash, signature): add the checkpoint signature of another validator, returns the signer",
+		"getCheckpointSignatures": "getCheckpointSignatures(hash): checkpoint signatures collected locally for the block",
+	},
+	"gossip": {
+		"announce":   "announce(text, start, end): announce a maintenance window (unix times) of the local validator to the other validators",
+		"validators": "validators: liveness and maintenance announcements of the current validators received over whisper",
+	},
+	"boker": {
+		"getContractType":           "getContractType(address): base contract type of the address, normal contracts are not registered",
+		"getAssignHistory":          "getAssignHistory(from, to): token assignments in the canonical block range [from, to]",
+		"getBalanceHistory":         "getBalanceHistory(address, from, to, step): balance of the address every step blocks in [from, to], needs --balanceindex",
+		"getTokenTransfers":         "getTokenTransfers(address, from, to, pageToken): ERC-20 transfers of the address in [from, to], needs --tokenindex",
+		"getTokenBalances":          "getTokenBalances(address): non-zero balances of the ERC-20 tokens the address has received, needs --tokenindex",
+		"setSystemContract":         "setSystemContract(address, type, abi): register a base contract with the coinbase validator, abi is optional",
+		"getUpdateBaseContractHash": "getUpdateBaseContractHash(old, new, type, number): hash the validators sign to move a base contract to a new address at block number",
+		"signBaseContractUpdate":    "signBaseContractUpdate(old, new, type, number): sign the base contract move with the coinbase validator",
+		"updateBaseContract":        "updateBaseContract(old, new, type, number, signatures): submit a transaction moving a base contract to a new address at block number, signed by over 2/3 of the validators",
+		"contracts":                 "contracts: all registered base contracts ordered by address",
+		"currentTokenNoder":         "currentTokenNoder: node currently responsible for assigning tokens",
+	},
 }
 
 const Chequebook_JS = `
@@ -69,8 +106,8 @@ web3._extend({
 	property: 'dpos',
 	methods: [
 		new web3._extend.Method({
-			name: 'getCurrentProducer',
-			call: 'dpos_getCurrentProducer',
+			name: 'getValidators',
+			call: 'dpos_getValidators',
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
 		}),
@@ -80,6 +117,148 @@ web3._extend({
 			params: 0,
 			outputFormatter: web3._extend.utils.toBigNumber
 		}),
+		new web3._extend.Method({
+			name: 'getIrreversibility',
+			call: 'dpos_getIrreversibility',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'selfCheck',
+			call: 'dpos_selfCheck',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'dpos_getProof',
+			params: 3,
+			inputFormatter: [null, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'signCheckpoint',
+			call: 'dpos_signCheckpoint',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'addCheckpointSignature',
+			call: 'dpos_addCheckpointSignature',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getCheckpointSignatures',
+			call: 'dpos_getCheckpointSignatures',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'validators',
+			getter: 'dpos_getValidators'
+		}),
+		new web3._extend.Property({
+			name: 'irreversibility',
+			getter: 'dpos_getIrreversibility'
+		}),
+	]
+});
+`
+
+const Les_JS = `
+web3._extend({
+	property: 'les',
+	methods: [
+		new web3._extend.Method({
+			name: 'setClientCapacity',
+			call: 'les_setClientCapacity',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionStatus',
+			call: 'les_getTransactionStatus',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'clientCapacities',
+			getter: 'les_clientCapacities'
+		}),
+		new web3._extend.Property({
+			name: 'totalCapacity',
+			getter: 'les_totalCapacity'
+		}),
+	]
+});
+`
+
+const Boker_JS = `
+web3._extend({
+	property: 'boker',
+	methods: [
+		new web3._extend.Method({
+			name: 'getContractType',
+			call: 'boker_getContractType',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getAssignHistory',
+			call: 'boker_getAssignHistory',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBalanceHistory',
+			call: 'boker_getBalanceHistory',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'getTokenTransfers',
+			call: 'boker_getTokenTransfers',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'getTokenBalances',
+			call: 'boker_getTokenBalances',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'setSystemContract',
+			call: 'boker_setSystemContract',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getUpdateBaseContractHash',
+			call: 'boker_getUpdateBaseContractHash',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, null, web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'signBaseContractUpdate',
+			call: 'boker_signBaseContractUpdate',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, null, web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'updateBaseContract',
+			call: 'boker_updateBaseContract',
+			params: 5,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, null, web3._extend.utils.toHex, null]
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'contracts',
+			getter: 'boker_getContracts'
+		}),
+		new web3._extend.Property({
+			name: 'currentTokenNoder',
+			getter: 'boker_getCurrentTokenNoder'
+		}),
 	]
 });
 `
@@ -144,6 +323,26 @@ web3._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'addTrustedPeer',
+			call: 'admin_addTrustedPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeTrustedPeer',
+			call: 'admin_removeTrustedPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'banPeer',
+			call: 'admin_banPeer',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'drain',
+			call: 'admin_drain',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -155,6 +354,31 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'importStatus',
+			call: 'admin_importStatus',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'cancelImport',
+			call: 'admin_cancelImport',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'exportSnapshot',
+			call: 'admin_exportSnapshot',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'importSnapshot',
+			call: 'admin_importSnapshot',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'checkClock',
+			call: 'admin_checkClock'
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -180,6 +404,11 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'killFilter',
+			call: 'admin_killFilter',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -190,10 +419,22 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'peerScores',
+			getter: 'admin_peerScores'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'clockStatus',
+			getter: 'admin_clockStatus'
+		}),
+		new web3._extend.Property({
+			name: 'filters',
+			getter: 'admin_filters'
+		}),
 	]
 });
 `
@@ -202,6 +443,12 @@ const Debug_JS = `
 web3._extend({
 	property: 'debug',
 	methods: [
+		new web3._extend.Method({
+			name: 'reorgJournal',
+			call: 'debug_reorgJournal',
+			params: 1,
+			inputFormatter: [null]
+		}),
 		new web3._extend.Method({
 			name: 'printBlock',
 			call: 'debug_printBlock',
@@ -212,6 +459,11 @@ web3._extend({
 			call: 'debug_getBlockRlp',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactions',
+			call: 'debug_getInternalTransactions',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'setHead',
 			call: 'debug_setHead',
@@ -247,6 +499,12 @@ web3._extend({
 			call: 'debug_dumpBlock',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'dumpBlockIncremental',
+			call: 'debug_dumpBlockIncremental',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'chaindbProperty',
 			call: 'debug_chaindbProperty',
@@ -272,6 +530,11 @@ web3._extend({
 			call: 'debug_vmodule',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setVerbosityPattern',
+			call: 'debug_setVerbosityPattern',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'backtraceAt',
 			call: 'debug_backtraceAt',
@@ -353,12 +616,32 @@ web3._extend({
 			call: 'debug_writeMemProfile',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setMutexProfileFraction',
+			call: 'debug_setMutexProfileFraction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'writeMutexProfile',
+			call: 'debug_writeMutexProfile',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'profile',
+			call: 'debug_profile',
+			params: 2
+		}),
 		new web3._extend.Method({
 			name: 'traceTransaction',
 			call: 'debug_traceTransaction',
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'listTracers',
+			call: 'debug_listTracers',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -370,11 +653,58 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'getBadBlockRlp',
+			call: 'debug_getBadBlockRlp',
+			params: 1,
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
 			params: 5,
 		}),
+		new web3._extend.Method({
+			name: 'dumpStorage',
+			call: 'debug_dumpStorage',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'dumpStorageStatus',
+			call: 'debug_dumpStorageStatus',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'cancelDumpStorage',
+			call: 'debug_cancelDumpStorage',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'forkAt',
+			call: 'debug_forkAt',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'forkStatus',
+			call: 'debug_forkStatus',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'forkReceipts',
+			call: 'debug_forkReceipts',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'forkSendRawTransaction',
+			call: 'debug_forkSendRawTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'unfork',
+			call: 'debug_unfork',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'getModifiedAccountsByNumber',
 			call: 'debug_getModifiedAccountsByNumber',
@@ -402,6 +732,24 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'createAccessList',
+			call: 'eth_createAccessList',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'gasPriceHistory',
+			call: 'eth_gasPriceHistory',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex, null]
+		}),
+		new web3._extend.Method({
+			name: 'signTypedData_v4',
+			call: 'eth_signTypedData_v4',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'resend',
 			call: 'eth_resend',
@@ -425,6 +773,12 @@ web3._extend({
 			call: 'eth_getRawTransactionByHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'eth_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -433,8 +787,44 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
-		
-		
+		new web3._extend.Method({
+			name: 'getTransactionsByAddress',
+			call: 'eth_getTransactionsByAddress',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'callBatch',
+			call: 'eth_callBatch',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'chainId',
+			call: 'eth_chainId',
+			params: 0,
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'fillNonceGap',
+			call: 'eth_fillNonceGap',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'filterPendingTransactions',
+			call: 'eth_filterPendingTransactions',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'getLogsPage',
+			call: 'eth_getLogsPage',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.toHex]
+		}),
+
+
 		new web3._extend.Method({
 			name: 'getLastProducer',
 			call: 'eth_getLastProducer',
@@ -523,12 +913,28 @@ web3._extend({
 			call: 'miner_setExtra',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setSlotMargin',
+			call: 'miner_setSlotMargin',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setLaneQuotas',
+			call: 'miner_setLaneQuotas',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'setGasPrice',
 			call: 'miner_setGasPrice',
 			params: 1,
 			inputFormatter: [web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'setGasLimitTarget',
+			call: 'miner_setGasLimitTarget',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
 		new web3._extend.Method({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
@@ -560,6 +966,17 @@ web3._extend({
 			call: 'personal_importRawKey',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'exportRawKey',
+			call: 'personal_exportRawKey',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'importKeystoreJSON',
+			call: 'personal_importKeystoreJSON',
+			params: 3
+		}),
 		new web3._extend.Method({
 			name: 'sign',
 			call: 'personal_sign',
@@ -581,6 +998,12 @@ web3._extend({
 			call: 'personal_deriveAccount',
 			params: 3
 		}),
+		new web3._extend.Method({
+			name: 'unlockAccountForSealing',
+			call: 'personal_unlockAccountForSealing',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -624,6 +1047,25 @@ web3._extend({
 });
 `
 
+const Gossip_JS = `
+web3._extend({
+	property: 'gossip',
+	methods: [
+		new web3._extend.Method({
+			name: 'announce',
+			call: 'gossip_announce',
+			params: 3
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'validators',
+			getter: 'gossip_validators'
+		}),
+	]
+});
+`
+
 const SWARMFS_JS = `
 web3._extend({
 	property: 'swarmfs',
@@ -651,7 +1093,14 @@ web3._extend({
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods: [
+		new web3._extend.Method({
+			name: 'nonceGaps',
+			call: 'txpool_nonceGaps',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
@@ -0,0 +1,50 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package web3ext
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestHelp checks that the help of every module only documents methods and
+// properties defined by the module and documents all of them.
+func TestHelp(t *testing.T) {
+	for api, help := range Help {
+		module, ok := Modules[api]
+		if !ok {
+			t.Errorf("help for unknown module %s", api)
+			continue
+		}
+		for name := range help {
+			if !strings.Contains(module, fmt.Sprintf("name: '%s'", name)) {
+				t.Errorf("help for unknown %s.%s", api, name)
+			}
+		}
+		for _, line := range strings.Split(module, "\n") {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "name: '") {
+				continue
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(line, "name: '"), "',")
+			if _, ok := help[name]; !ok {
+				t.Errorf("no help for %s.%s", api, name)
+			}
+		}
+	}
+}
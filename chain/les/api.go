@@ -0,0 +1,155 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/hexutil"
+	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/light"
+	"github.com/Bokerchain/Boker/chain/p2p/discover"
+)
+
+// PublicLightAPI provides an API for light clients to query the network about
+// the inclusion of their transactions.
+type PublicLightAPI struct {
+	les *LightEthereum
+}
+
+// NewPublicLightAPI creates a new LES client API.
+func NewPublicLightAPI(les *LightEthereum) *PublicLightAPI {
+	return &PublicLightAPI{les: les}
+}
+
+// GetTransactionStatus retrieves the status of a transaction from a LES/3 server.
+// The status is "pending" or "queued" if the transaction is in the server's pool,
+// "mined" if it is included in the canonical chain, "dropped" if it is unknown to
+// the server while still waiting in the local pool and "unknown" otherwise. Mined
+// transactions are returned with their receipt and the merkle proofs of the
+// transaction and the receipt, both verified against the including header.
+func (api *PublicLightAPI) GetTransactionStatus(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	r, err := light.GetTransactionProof(ctx, api.les.odr, hash)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{"hash": hash}
+	switch r.Status {
+	case core.TxStatusPending:
+		fields["status"] = "pending"
+	case core.TxStatusQueued:
+		fields["status"] = "queued"
+	case core.TxStatusIncluded:
+		fields["status"] = "mined"
+	default:
+		if api.les.txPool.GetTransaction(hash) != nil {
+			fields["status"] = "dropped"
+		} else {
+			fields["status"] = "unknown"
+		}
+	}
+	if r.Status != core.TxStatusIncluded {
+		return fields, nil
+	}
+	receipt := r.Receipt
+	for _, log := range receipt.Logs {
+		log.BlockNumber = r.Lookup.BlockIndex
+		log.BlockHash = r.Lookup.BlockHash
+		log.TxHash = hash
+		log.TxIndex = uint(r.Lookup.Index)
+	}
+	fields["blockHash"] = r.Lookup.BlockHash
+	fields["blockNumber"] = hexutil.Uint64(r.Lookup.BlockIndex)
+	fields["transactionIndex"] = hexutil.Uint64(r.Lookup.Index)
+	fields["transactionsRoot"] = r.Header.TxHash
+	fields["receiptsRoot"] = r.Header.ReceiptHash
+	fields["cumulativeGasUsed"] = (*hexutil.Big)(receipt.CumulativeGasUsed)
+	fields["logs"] = receipt.Logs
+	fields["logsBloom"] = receipt.Bloom
+	if len(receipt.PostState) > 0 {
+		fields["root"] = hexutil.Bytes(receipt.PostState)
+	} else {
+		fields["receiptStatus"] = hexutil.Uint(receipt.Status)
+	}
+	fields["transactionProof"] = proofNodes(r.TxProof)
+	fields["receiptProof"] = proofNodes(r.ReceiptProof)
+	return fields, nil
+}
+
+// proofNodes converts the nodes of a merkle proof into their hex representation.
+func proofNodes(list light.NodeList) []hexutil.Bytes {
+	nodes := make([]hexutil.Bytes, len(list))
+	for i, node := range list {
+		nodes[i] = hexutil.Bytes(node)
+	}
+	return nodes
+}
+
+// PrivateLightServerAPI provides administrative access to the load management of
+// a light server.
+type PrivateLightServerAPI struct {
+	server *LesServer
+}
+
+// NewPrivateLightServerAPI creates a new LES server admin API.
+func NewPrivateLightServerAPI(server *LesServer) *PrivateLightServerAPI {
+	return &PrivateLightServerAPI{server: server}
+}
+
+// SetClientCapacity assigns a request capacity (in cost units per millisecond) to
+// the client with the given enode ID or URL, marking it as a priority client. The
+// new capacity applies immediately if the client is connected. A zero capacity
+// reverts the client to the default capacity.
+func (api *PrivateLightServerAPI) SetClientCapacity(node string, capacity uint64) (bool, error) {
+	id, err := parseNodeID(node)
+	if err != nil {
+		return false, err
+	}
+	api.server.capacity.setCapacity(id, capacity)
+	return true, nil
+}
+
+// ClientCapacities returns the capacity of the connected and the priority clients.
+func (api *PrivateLightServerAPI) ClientCapacities() map[discover.NodeID]ClientCapacity {
+	return api.server.capacity.info()
+}
+
+// TotalCapacity returns the total and currently used client capacity of the server.
+func (api *PrivateLightServerAPI) TotalCapacity() map[string]uint64 {
+	c := api.server.capacity
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return map[string]uint64{
+		"total":   c.total,
+		"used":    c.used,
+		"default": c.defaultCap,
+	}
+}
+
+// parseNodeID accepts either a hex encoded node ID or a full enode URL.
+func parseNodeID(node string) (discover.NodeID, error) {
+	if id, err := discover.HexID(node); err == nil {
+		return id, nil
+	}
+	n, err := discover.ParseNode(node)
+	if err != nil {
+		return discover.NodeID{}, err
+	}
+	return n.ID, nil
+}
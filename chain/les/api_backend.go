@@ -34,6 +34,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/eth/gasprice"
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/event"
+	"github.com/Bokerchain/Boker/chain/internal/ethapi"
 	"github.com/Bokerchain/Boker/chain/light"
 	"github.com/Bokerchain/Boker/chain/log"
 	"github.com/Bokerchain/Boker/chain/params"
@@ -94,6 +95,11 @@ func (b *LesApiBackend) GetTd(blockHash common.Hash) *big.Int {
 	return b.eth.blockchain.GetTdByHash(blockHash)
 }
 
+//获取区块的Dpos上下文，本地不存在时通过ODR从全节点获取验证者列表
+func (b *LesApiBackend) DposContext(ctx context.Context, header *types.Header) (*types.DposContext, error) {
+	return light.GetDposContext(ctx, b.eth.odr, header)
+}
+
 func (b *LesApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
 	state.SetBalance(msg.From(), math.MaxBig256)
 	context := core.NewEVMContext(msg, header, b.eth.blockchain, nil)
@@ -162,6 +168,10 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *LesApiBackend) GasPriceHistory(ctx context.Context, start rpc.BlockNumber, count uint64, percentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.PriceHistory(ctx, start, count, percentiles)
+}
+
 func (b *LesApiBackend) ChainDb() ethdb.Database {
 	return b.eth.chainDb
 }
@@ -216,3 +226,16 @@ func (b *LesApiBackend) DecodeParams(code []byte) ([]byte, error) {
 
 	return b.eth.DecodeParams(code)
 }
+
+//轻节点的调用需要从网络获取状态，结果不缓存
+func (b *LesApiBackend) CallCache() *ethapi.CallCache {
+	return nil
+}
+
+func (b *LesApiBackend) RPCGasCap() uint64 {
+	return b.eth.config.RPCGasCap
+}
+
+func (b *LesApiBackend) RPCTxFeeCap() float64 {
+	return b.eth.config.RPCTxFeeCap
+}
@@ -0,0 +1,141 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"sync"
+
+	"github.com/Bokerchain/Boker/chain/les/flowcontrol"
+	"github.com/Bokerchain/Boker/chain/p2p/discover"
+)
+
+// clientCapacities distributes the request capacity of a light server among its
+// clients. Every client gets a token bucket refilling at its capacity (in request
+// cost units per millisecond); clients with an explicitly assigned capacity are
+// prioritised: they are admitted even if the total capacity is exhausted by free
+// clients and get a proportionally larger share of the recharge capacity.
+type clientCapacities struct {
+	total      uint64                     // Total capacity shared by all clients (0 = unlimited)
+	defaultCap uint64                     // Capacity of clients without an assignment
+	used       uint64                     // Capacity of the connected clients
+	assigned   map[discover.NodeID]uint64 // Explicitly assigned (priority) capacities
+	clients    map[discover.NodeID]*peer  // Connected clients
+	lock       sync.Mutex
+}
+
+// ClientCapacity is the capacity information of a single client.
+type ClientCapacity struct {
+	Capacity  uint64 `json:"capacity"`
+	Priority  bool   `json:"priority"`
+	Connected bool   `json:"connected"`
+}
+
+func newClientCapacities(total, defaultCap uint64) *clientCapacities {
+	return &clientCapacities{
+		total:      total,
+		defaultCap: defaultCap,
+		assigned:   make(map[discover.NodeID]uint64),
+		clients:    make(map[discover.NodeID]*peer),
+	}
+}
+
+// capacityOf returns the capacity of a client and whether it is a priority client.
+func (c *clientCapacities) capacityOf(id discover.NodeID) (uint64, bool) {
+	if capacity, ok := c.assigned[id]; ok {
+		return capacity, true
+	}
+	return c.defaultCap, false
+}
+
+// register admits a newly connected client if there is enough free capacity,
+// creating its token bucket.
+func (c *clientCapacities) register(p *peer) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	id := p.ID()
+	capacity, priority := c.capacityOf(id)
+	if !priority && c.total != 0 && c.used+capacity > c.total {
+		return errResp(ErrCapacityExhausted, "used %d + %d > %d", c.used, capacity, c.total)
+	}
+	c.used += capacity
+	c.clients[id] = p
+
+	p.fcBucket = flowcontrol.NewTokenBucket(capacity)
+	p.fcClient.SetWeight(c.weight(capacity))
+	return nil
+}
+
+// unregister releases the capacity of a disconnected client.
+func (c *clientCapacities) unregister(p *peer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	id := p.ID()
+	if c.clients[id] != p {
+		return
+	}
+	delete(c.clients, id)
+	c.used -= p.fcBucket.Capacity()
+}
+
+// setCapacity assigns a capacity to a client, applying it immediately if the
+// client is connected. A zero capacity removes the assignment.
+func (c *clientCapacities) setCapacity(id discover.NodeID, capacity uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if capacity == 0 {
+		delete(c.assigned, id)
+	} else {
+		c.assigned[id] = capacity
+	}
+	if p, ok := c.clients[id]; ok {
+		newCap, _ := c.capacityOf(id)
+		c.used = c.used - p.fcBucket.Capacity() + newCap
+		p.fcBucket.SetCapacity(newCap)
+		p.fcClient.SetWeight(c.weight(newCap))
+	}
+}
+
+// weight returns the recharge weight of a client relative to the default capacity.
+func (c *clientCapacities) weight(capacity uint64) uint64 {
+	if c.defaultCap == 0 {
+		return 1
+	}
+	if weight := capacity / c.defaultCap; weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// info returns the capacity of all the connected and priority clients.
+func (c *clientCapacities) info() map[discover.NodeID]ClientCapacity {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	result := make(map[discover.NodeID]ClientCapacity)
+	for id := range c.assigned {
+		capacity, _ := c.capacityOf(id)
+		result[id] = ClientCapacity{Capacity: capacity, Priority: true}
+	}
+	for id, p := range c.clients {
+		_, priority := c.capacityOf(id)
+		result[id] = ClientCapacity{Capacity: p.fcBucket.Capacity(), Priority: priority, Connected: true}
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package flowcontrol
+
+import (
+	"math"
+	"sync"
+
+	"github.com/Bokerchain/Boker/chain/common/mclock"
+)
+
+// TokenBucket is a server side limit on the request cost a single client may
+// consume. It refills at capacity cost units per millisecond and holds at most
+// bucketBurst milliseconds worth of tokens. Unlike the buffer parameters announced
+// during the handshake it can be changed while the client is connected.
+type TokenBucket struct {
+	capacity uint64 // refill rate in cost units per millisecond
+	limit    uint64 // maximum number of tokens
+	tokens   uint64
+	lastTime mclock.AbsTime
+	lock     sync.Mutex
+}
+
+// bucketBurst is the number of milliseconds worth of capacity a bucket can hold.
+const bucketBurst = 6000
+
+// NewTokenBucket creates a full bucket refilling at capacity units per millisecond.
+func NewTokenBucket(capacity uint64) *TokenBucket {
+	return &TokenBucket{
+		capacity: capacity,
+		limit:    bucketLimit(capacity),
+		tokens:   bucketLimit(capacity),
+		lastTime: mclock.Now(),
+	}
+}
+
+// bucketLimit returns the maximum number of tokens of a bucket with the given
+// capacity, saturating instead of overflowing for huge capacities.
+func bucketLimit(capacity uint64) uint64 {
+	if capacity > math.MaxUint64/bucketBurst {
+		return math.MaxUint64
+	}
+	return capacity * bucketBurst
+}
+
+// refill adds the tokens accumulated since the last refill. Tokens are credited
+// for whole milliseconds, the remainder is carried over to the next refill. If
+// the elapsed time is enough to fill up the bucket it is filled without doing
+// the multiplication, so long idle periods or huge capacities cannot overflow.
+func (b *TokenBucket) refill(time mclock.AbsTime) {
+	if time <= b.lastTime || b.tokens >= b.limit || b.capacity == 0 {
+		b.lastTime = time
+		return
+	}
+	ms := uint64(time-b.lastTime) / uint64(fcTimeConst)
+	if ms > (b.limit-b.tokens)/b.capacity {
+		b.tokens = b.limit
+		b.lastTime = time
+		return
+	}
+	b.tokens += b.capacity * ms
+	b.lastTime += mclock.AbsTime(ms) * mclock.AbsTime(fcTimeConst)
+}
+
+// Take consumes cost tokens from the bucket if there are enough of them.
+func (b *TokenBucket) Take(cost uint64) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.refill(mclock.Now())
+	if cost > b.tokens {
+		return false
+	}
+	b.tokens -= cost
+	return true
+}
+
+// Capacity returns the refill rate of the bucket.
+func (b *TokenBucket) Capacity() uint64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.capacity
+}
+
+// SetCapacity changes the refill rate of the bucket, keeping the accumulated
+// tokens up to the new limit.
+func (b *TokenBucket) SetCapacity(capacity uint64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.refill(mclock.Now())
+	b.capacity = capacity
+	b.limit = bucketLimit(capacity)
+	if b.tokens > b.limit {
+		b.tokens = b.limit
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package flowcontrol
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common/mclock"
+)
+
+// newTestBucket creates a bucket with the given number of tokens, last refilled
+// at time zero.
+func newTestBucket(capacity, tokens uint64) *TokenBucket {
+	return &TokenBucket{
+		capacity: capacity,
+		limit:    bucketLimit(capacity),
+		tokens:   tokens,
+	}
+}
+
+// Tests that a new bucket is full and refuses requests exceeding its tokens.
+func TestTokenBucketTake(t *testing.T) {
+	b := NewTokenBucket(10)
+	if !b.Take(10 * bucketBurst) {
+		t.Fatalf("failed to take the full bucket")
+	}
+	if b.Take(10 * bucketBurst) {
+		t.Fatalf("took more tokens than the bucket holds")
+	}
+	if b.Take(math.MaxUint64) {
+		t.Fatalf("took more tokens than the bucket can ever hold")
+	}
+}
+
+// Tests that the bucket refills at its capacity per millisecond, carrying the
+// fractions of milliseconds over to the next refill.
+func TestTokenBucketRefill(t *testing.T) {
+	b := newTestBucket(10, 0)
+
+	tests := []struct {
+		time   time.Duration
+		tokens uint64
+	}{
+		{0, 0},
+		{500 * time.Microsecond, 0},
+		{1500 * time.Microsecond, 10},
+		{2 * time.Millisecond, 20},
+		{2 * time.Millisecond, 20},
+		{100 * time.Millisecond, 1000},
+		{time.Minute, 10 * bucketBurst},
+	}
+	for i, tt := range tests {
+		b.refill(mclock.AbsTime(tt.time))
+		if b.tokens != tt.tokens {
+			t.Errorf("test %d: tokens mismatch: have %d, want %d", i, b.tokens, tt.tokens)
+		}
+	}
+}
+
+// Tests that refilling after long idle periods or with huge capacities fills up
+// the bucket instead of overflowing.
+func TestTokenBucketRefillOverflow(t *testing.T) {
+	tests := []struct {
+		capacity uint64
+		tokens   uint64
+		time     mclock.AbsTime
+	}{
+		{1000000000, 0, mclock.AbsTime(time.Hour)},
+		{1, 0, mclock.AbsTime(math.MaxInt64)},
+		{math.MaxUint64 / bucketBurst, 0, mclock.AbsTime(math.MaxInt64)},
+		{math.MaxUint64, 0, mclock.AbsTime(time.Millisecond)},
+		{math.MaxUint64, 1, mclock.AbsTime(time.Hour)},
+	}
+	for i, tt := range tests {
+		b := newTestBucket(tt.capacity, tt.tokens)
+		b.refill(tt.time)
+		if b.tokens != b.limit {
+			t.Errorf("test %d: tokens mismatch: have %d, want %d", i, b.tokens, b.limit)
+		}
+	}
+}
+
+// Tests that the limit of huge capacities saturates instead of overflowing.
+func TestTokenBucketLimit(t *testing.T) {
+	tests := []struct {
+		capacity uint64
+		limit    uint64
+	}{
+		{0, 0},
+		{10, 10 * bucketBurst},
+		{math.MaxUint64 / bucketBurst, math.MaxUint64 / bucketBurst * bucketBurst},
+		{math.MaxUint64/bucketBurst + 1, math.MaxUint64},
+		{math.MaxUint64, math.MaxUint64},
+	}
+	for i, tt := range tests {
+		if limit := bucketLimit(tt.capacity); limit != tt.limit {
+			t.Errorf("test %d: limit mismatch: have %d, want %d", i, limit, tt.limit)
+		}
+	}
+}
+
+// Tests that changing the capacity keeps the accumulated tokens up to the new
+// limit and refills at the new rate afterwards.
+func TestTokenBucketSetCapacity(t *testing.T) {
+	b := NewTokenBucket(10)
+
+	b.SetCapacity(1)
+	if b.Capacity() != 1 {
+		t.Fatalf("capacity mismatch: have %d, want %d", b.Capacity(), 1)
+	}
+	if b.tokens != bucketBurst {
+		t.Fatalf("tokens mismatch after lowering: have %d, want %d", b.tokens, bucketBurst)
+	}
+	b.SetCapacity(100)
+	if b.limit != 100*bucketBurst {
+		t.Fatalf("limit mismatch after raising: have %d, want %d", b.limit, 100*bucketBurst)
+	}
+	if b.tokens > 2*bucketBurst {
+		t.Fatalf("tokens mismatch after raising: have %d, want at most %d", b.tokens, 2*bucketBurst)
+	}
+}
@@ -54,6 +54,12 @@ func (peer *ClientNode) Remove(cm *ClientManager) {
 	cm.removeNode(peer.cmNode)
 }
 
+// SetWeight sets the priority of the client when the server distributes its
+// recharge capacity among clients, the default being 1.
+func (peer *ClientNode) SetWeight(weight uint64) {
+	peer.cm.setWeight(peer.cmNode, weight)
+}
+
 func (peer *ClientNode) recalcBV(time mclock.AbsTime) {
 	dt := uint64(time - peer.lastTime)
 	if time < peer.lastTime {
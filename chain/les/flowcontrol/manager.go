@@ -124,6 +124,27 @@ func (self *ClientManager) removeNode(node *cmNode) {
 	self.update(time)
 }
 
+// setWeight changes the share of the recharge capacity a node receives relative
+// to the other recharging nodes.
+func (self *ClientManager) setWeight(node *cmNode, weight uint64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if weight == 0 {
+		weight = 1
+	}
+	time := mclock.Now()
+	self.update(time)
+	node.rcWeight = weight
+	self.updateNodes(time)
+	for node := range self.nodes {
+		if node.recharging {
+			node.set(node.serving, self.simReqCnt, self.sumWeight)
+		}
+	}
+	self.update(time)
+}
+
 // recalc sumWeight
 func (self *ClientManager) updateNodes(time mclock.AbsTime) (rce bool) {
 	var sumWeight, rcSum uint64
@@ -60,6 +60,7 @@ const (
 	MaxHelperTrieProofsFetch = 64  // Amount of merkle proofs to be fetched per retrieval request
 	MaxTxSend                = 64  // Amount of transactions to be send per request
 	MaxTxStatus              = 256 // Amount of transactions to queried per request
+	MaxTxProofs              = 64  // Amount of transaction status records with proofs to be queried per request
 
 	disableClientRemovePeer = false
 )
@@ -101,6 +102,7 @@ type ProtocolManager struct {
 	chainConfig *params.ChainConfig
 	blockchain  BlockChain
 	chainDb     ethdb.Database
+	stateDb     trie.Database //读取状态树节点和合约代码，服务端使用区块链共享的trie节点缓存
 	odr         *LesOdr
 	server      *LesServer
 	serverPool  *serverPool
@@ -136,6 +138,7 @@ func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, protoco
 		blockchain:  blockchain,
 		chainConfig: chainConfig,
 		chainDb:     chainDb,
+		stateDb:     chainDb,
 		odr:         odr,
 		networkId:   networkId,
 		txpool:      txpool,
@@ -209,6 +212,9 @@ func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, protoco
 		manager.peers.notify((*downloaderPeerNotify)(manager))
 		manager.fetcher = newLightFetcher(manager)
 	}
+	if bc, ok := blockchain.(*core.BlockChain); ok {
+		manager.stateDb = bc.TrieDB()
+	}
 
 	return manager, nil
 }
@@ -279,6 +285,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	defer func() {
 		if pm.server != nil && pm.server.fcManager != nil && p.fcClient != nil {
 			p.fcClient.Remove(pm.server.fcManager)
+			pm.server.capacity.unregister(p)
 		}
 		pm.removePeer(p.id)
 	}()
@@ -319,7 +326,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 }
 
-var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg}
+var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg, GetTxProofsMsg}
 
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
@@ -346,6 +353,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			p.Log().Error("Request came too early", "recharge", common.PrettyDuration(recharge))
 			return true
 		}
+		if p.fcBucket != nil && !p.fcBucket.Take(cost) {
+			p.Log().Debug("Request exceeds client capacity", "cost", cost, "capacity", p.fcBucket.Capacity())
+			return true
+		}
 		return false
 	}
 
@@ -569,11 +580,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		for _, req := range req.Reqs {
 			// Retrieve the requested state entry, stopping if enough was found
 			if header := core.GetHeader(pm.chainDb, req.BHash, core.GetBlockNumber(pm.chainDb, req.BHash)); header != nil {
-				if trie, _ := trie.New(header.Root, pm.chainDb); trie != nil {
+				if trie, _ := trie.New(header.Root, pm.stateDb); trie != nil {
 					sdata := trie.Get(req.AccKey)
 					var acc state.Account
 					if err := rlp.DecodeBytes(sdata, &acc); err == nil {
-						entry, _ := pm.chainDb.Get(acc.CodeHash)
+						entry, _ := pm.stateDb.Get(acc.CodeHash)
 						if bytes+len(entry) >= softResponseLimit {
 							break
 						}
@@ -696,13 +707,23 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			// Retrieve the requested state entry, stopping if enough was found
 			if header := core.GetHeader(pm.chainDb, req.BHash, core.GetBlockNumber(pm.chainDb, req.BHash)); header != nil {
-				if tr, _ := trie.New(header.Root, pm.chainDb); tr != nil {
+				if req.dposEpoch() {
+					//轻节点请求Dpos周期树中的验证者列表
+					if header.DposProto != nil {
+						if tr, _ := trie.New(header.DposProto.EpochHash, pm.chainDb); tr != nil {
+							var proof light.NodeList
+							tr.Prove(req.Key, 0, &proof)
+							proofs = append(proofs, proof)
+							bytes += proof.DataSize()
+						}
+					}
+				} else if tr, _ := trie.New(header.Root, pm.stateDb); tr != nil {
 					if len(req.AccKey) > 0 {
 						sdata := tr.Get(req.AccKey)
 						tr = nil
 						var acc state.Account
 						if err := rlp.DecodeBytes(sdata, &acc); err == nil {
-							tr, _ = trie.New(acc.Root, pm.chainDb)
+							tr, _ = trie.New(acc.Root, pm.stateDb)
 						}
 					}
 					if tr != nil {
@@ -732,6 +753,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		var (
 			lastBHash  common.Hash
 			lastAccKey []byte
+			header     *types.Header
 			tr, str    *trie.Trie
 		)
 		reqCnt := len(req.Reqs)
@@ -746,8 +768,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				break
 			}
 			if tr == nil || req.BHash != lastBHash {
-				if header := core.GetHeader(pm.chainDb, req.BHash, core.GetBlockNumber(pm.chainDb, req.BHash)); header != nil {
-					tr, _ = trie.New(header.Root, pm.chainDb)
+				if header = core.GetHeader(pm.chainDb, req.BHash, core.GetBlockNumber(pm.chainDb, req.BHash)); header != nil {
+					tr, _ = trie.New(header.Root, pm.stateDb)
 				} else {
 					tr = nil
 				}
@@ -755,13 +777,20 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				str = nil
 			}
 			if tr != nil {
-				if len(req.AccKey) > 0 {
+				if req.dposEpoch() {
+					//轻节点请求Dpos周期树中的验证者列表
+					if header.DposProto != nil {
+						if etr, _ := trie.New(header.DposProto.EpochHash, pm.chainDb); etr != nil {
+							etr.Prove(req.Key, req.FromLevel, nodes)
+						}
+					}
+				} else if len(req.AccKey) > 0 {
 					if str == nil || !bytes.Equal(req.AccKey, lastAccKey) {
 						sdata := tr.Get(req.AccKey)
 						str = nil
 						var acc state.Account
 						if err := rlp.DecodeBytes(sdata, &acc); err == nil {
-							str, _ = trie.New(acc.Root, pm.chainDb)
+							str, _ = trie.New(acc.Root, pm.stateDb)
 						}
 						lastAccKey = common.CopyBytes(req.AccKey)
 					}
@@ -1063,6 +1092,47 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
 
+	case GetTxProofsMsg:
+		if pm.txpool == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+		p.Log().Trace("Received transaction proofs request")
+		var req struct {
+			ReqID  uint64
+			Hashes []common.Hash
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		reqCnt := len(req.Hashes)
+		if reject(uint64(reqCnt), MaxTxProofs) {
+			return errResp(ErrRequestRejected, "")
+		}
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+
+		return p.SendTxProofs(req.ReqID, bv, pm.txProofs(req.Hashes))
+
+	case TxProofsMsg:
+		if pm.odr == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+
+		p.Log().Trace("Received transaction proofs response")
+		var resp struct {
+			ReqID, BV uint64
+			Proofs    []txProof
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.GotReply(resp.ReqID, resp.BV)
+		deliverMsg = &Msg{
+			MsgType: MsgTxProofs,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Proofs,
+		}
+
 	default:
 		p.Log().Trace("Received unknown message", "code", msg.Code)
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
@@ -1123,6 +1193,45 @@ func (pm *ProtocolManager) txStatus(hashes []common.Hash) []txStatus {
 	return stats
 }
 
+// txProofs retrieves the status of the given transactions and, for the mined
+// ones, proves the transaction and its receipt against the including block.
+func (pm *ProtocolManager) txProofs(hashes []common.Hash) []txProof {
+	proofs := make([]txProof, len(hashes))
+	for i, stat := range pm.txStatus(hashes) {
+		proofs[i].Status = stat.Status
+		if stat.Status != core.TxStatusIncluded {
+			continue
+		}
+		lookup := stat.Lookup
+		block := core.GetBlock(pm.chainDb, lookup.BlockHash, lookup.BlockIndex)
+		receipts := core.GetBlockReceipts(pm.chainDb, lookup.BlockHash, lookup.BlockIndex)
+		if block == nil || lookup.Index >= uint64(len(receipts)) {
+			//区块数据不完整时无法提供证明，按照未知交易返回
+			proofs[i].Status = core.TxStatusUnknown
+			continue
+		}
+		key, _ := rlp.EncodeToBytes(uint(lookup.Index))
+		deriveTrie(block.Transactions()).Prove(key, 0, &proofs[i].TxProof)
+		deriveTrie(receipts).Prove(key, 0, &proofs[i].ReceiptProof)
+
+		proofs[i].Lookup = lookup
+	}
+	return proofs
+}
+
+// deriveTrie builds the in-memory trie of a transaction or receipt list whose
+// root is the corresponding header field, see types.DeriveSha.
+func deriveTrie(list types.DerivableList) *trie.Trie {
+	keybuf := new(bytes.Buffer)
+	t := new(trie.Trie)
+	for i := 0; i < list.Len(); i++ {
+		keybuf.Reset()
+		rlp.Encode(keybuf, uint(i))
+		t.Update(keybuf.Bytes(), list.GetRlp(i))
+	}
+	return t
+}
+
 // NodeInfo retrieves some protocol metadata about the running host node.
 func (self *ProtocolManager) NodeInfo() *eth.EthNodeInfo {
 	return &eth.EthNodeInfo{
@@ -19,6 +19,7 @@
 package les
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -68,6 +69,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*ChtRequest)(r)
 	case *light.BloomRequest:
 		return (*BloomRequest)(r)
+	case *light.TxProofRequest:
+		return (*TxProofRequest)(r)
 	default:
 		return nil
 	}
@@ -178,12 +181,64 @@ func (r *ReceiptsRequest) Validate(db ethdb.Database, msg *Msg) error {
 	return nil
 }
 
+// TxProofRequest is the ODR request type for transaction status records with
+// receipt inclusion proofs, see LesOdrRequest interface
+type TxProofRequest light.TxProofRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *TxProofRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetTxProofsMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *TxProofRequest) CanSend(peer *peer) bool {
+	return peer.version >= lpv3
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *TxProofRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting transaction proof", "hash", r.Hash)
+	return peer.RequestTxProofs(reqID, r.GetCost(peer), []common.Hash{r.Hash})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *TxProofRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating transaction proof", "hash", r.Hash)
+
+	// Ensure we have a correct message with a single status record
+	if msg.MsgType != MsgTxProofs {
+		return errInvalidMessageType
+	}
+	proofs := msg.Obj.([]txProof)
+	if len(proofs) != 1 {
+		return errInvalidEntryCount
+	}
+	proof := proofs[0]
+	if proof.Status == core.TxStatusIncluded && proof.Lookup == nil {
+		return errInvalidEntryCount
+	}
+	// The proofs are verified against the canonical header of the including
+	// block, which might need to be retrieved separately (light.GetTransactionProof)
+	r.Status, r.Lookup = proof.Status, proof.Lookup
+	r.TxProof, r.ReceiptProof = proof.TxProof, proof.ReceiptProof
+	return nil
+}
+
 type ProofReq struct {
 	BHash       common.Hash
 	AccKey, Key []byte
 	FromLevel   uint
 }
 
+// dposEpoch tells if the proof is requested from the dpos epoch trie of the
+// block instead of its state trie.
+func (req *ProofReq) dposEpoch() bool {
+	return bytes.Equal(req.AccKey, light.DposEpochTrieKey)
+}
+
 // ODR request type for state/storage trie entries, see LesOdrRequest interface
 type TrieRequest light.TrieRequest
 
@@ -193,7 +248,7 @@ func (r *TrieRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetProofsV1Msg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetProofsV2Msg, 1)
 	default:
 		panic(nil)
@@ -353,7 +408,7 @@ func (r *ChtRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetHeaderProofsMsg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetHelperTrieProofsMsg, 1)
 	default:
 		panic(nil)
@@ -72,8 +72,9 @@ type peer struct {
 	hasBlock       func(common.Hash, uint64) bool
 	responseErrors int
 
-	fcClient       *flowcontrol.ClientNode // nil if the peer is server only
-	fcServer       *flowcontrol.ServerNode // nil if the peer is client only
+	fcClient       *flowcontrol.ClientNode  // nil if the peer is server only
+	fcBucket       *flowcontrol.TokenBucket // server side request capacity, nil if the peer is server only
+	fcServer       *flowcontrol.ServerNode  // nil if the peer is client only
 	fcServerParams *flowcontrol.ServerParams
 	fcCosts        requestCostTable
 }
@@ -103,11 +104,18 @@ func (p *peer) queueSend(f func()) {
 
 // Info gathers and returns a collection of metadata known about a peer.
 func (p *peer) Info() *eth.PeerInfo {
-	return &eth.PeerInfo{
+	info := &eth.PeerInfo{
 		Version:    p.version,
 		Difficulty: p.Td(),
 		Head:       fmt.Sprintf("%x", p.Head()),
 	}
+	p.lock.RLock()
+	if p.headInfo != nil {
+		number := p.headInfo.Number
+		info.Number = &number
+	}
+	p.lock.RUnlock()
+	return info
 }
 
 // Head retrieves a copy of the current head (most recent) hash of the peer.
@@ -236,6 +244,12 @@ func (p *peer) SendTxStatus(reqID, bv uint64, stats []txStatus) error {
 	return sendResponse(p.rw, TxStatusMsg, reqID, bv, stats)
 }
 
+// SendTxProofs sends a batch of transaction status records with receipt inclusion
+// proofs, corresponding to the ones requested.
+func (p *peer) SendTxProofs(reqID, bv uint64, proofs []txProof) error {
+	return sendResponse(p.rw, TxProofsMsg, reqID, bv, proofs)
+}
+
 // RequestHeadersByHash fetches a batch of blocks' headers corresponding to the
 // specified header query, based on the hash of an origin block.
 func (p *peer) RequestHeadersByHash(reqID, cost uint64, origin common.Hash, amount int, skip int, reverse bool) error {
@@ -276,7 +290,7 @@ func (p *peer) RequestProofs(reqID, cost uint64, reqs []ProofReq) error {
 	switch p.version {
 	case lpv1:
 		return sendRequest(p.rw, GetProofsV1Msg, reqID, cost, reqs)
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, GetProofsV2Msg, reqID, cost, reqs)
 	default:
 		panic(nil)
@@ -299,7 +313,7 @@ func (p *peer) RequestHelperTrieProofs(reqID, cost uint64, reqs []HelperTrieReq)
 			reqsV1[i] = ChtReq{ChtNum: (req.TrieIdx+1)*(light.ChtFrequency/light.ChtV1Frequency) - 1, BlockNum: blockNum, FromLevel: req.FromLevel}
 		}
 		return sendRequest(p.rw, GetHeaderProofsMsg, reqID, cost, reqsV1)
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, GetHelperTrieProofsMsg, reqID, cost, reqs)
 	default:
 		panic(nil)
@@ -312,13 +326,20 @@ func (p *peer) RequestTxStatus(reqID, cost uint64, txHashes []common.Hash) error
 	return sendRequest(p.rw, GetTxStatusMsg, reqID, cost, txHashes)
 }
 
+// RequestTxProofs fetches a batch of transaction status records together with
+// the receipt inclusion proofs of the mined ones from a remote node.
+func (p *peer) RequestTxProofs(reqID, cost uint64, txHashes []common.Hash) error {
+	p.Log().Debug("Requesting transaction proofs", "count", len(txHashes))
+	return sendRequest(p.rw, GetTxProofsMsg, reqID, cost, txHashes)
+}
+
 // SendTxStatus sends a batch of transactions to be added to the remote transaction pool.
 func (p *peer) SendTxs(reqID, cost uint64, txs types.Transactions) error {
 	p.Log().Debug("Fetching batch of transactions", "count", len(txs))
 	switch p.version {
 	case lpv1:
 		return p2p.Send(p.rw, SendTxMsg, txs) // old message format does not include reqID
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, SendTxV2Msg, reqID, cost, txs)
 	default:
 		panic(nil)
@@ -466,6 +487,11 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 			p.announceType = announceTypeSimple
 		}
 		p.fcClient = flowcontrol.NewClientNode(server.fcManager, server.defParams)
+		if err := server.capacity.register(p); err != nil {
+			p.fcClient.Remove(server.fcManager)
+			p.fcClient = nil
+			return err
+		}
 	} else {
 		if recv.get("serveChainSince", nil) != nil {
 			return errResp(ErrUselessPeer, "peer cannot serve chain")
@@ -30,6 +30,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/crypto/secp256k1"
+	"github.com/Bokerchain/Boker/chain/light"
 	"github.com/Bokerchain/Boker/chain/rlp"
 )
 
@@ -37,16 +38,17 @@ import (
 const (
 	lpv1 = 1
 	lpv2 = 2
+	lpv3 = 3
 )
 
 // Supported versions of the les protocol (first is primary)
 var (
-	ClientProtocolVersions = []uint{lpv2, lpv1}
-	ServerProtocolVersions = []uint{lpv2, lpv1}
+	ClientProtocolVersions = []uint{lpv3, lpv2, lpv1}
+	ServerProtocolVersions = []uint{lpv3, lpv2, lpv1}
 )
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = map[uint]uint64{lpv1: 15, lpv2: 22}
+var ProtocolLengths = map[uint]uint64{lpv1: 15, lpv2: 22, lpv3: 24}
 
 const (
 	NetworkId          = 1
@@ -79,6 +81,9 @@ const (
 	SendTxV2Msg            = 0x13
 	GetTxStatusMsg         = 0x14
 	TxStatusMsg            = 0x15
+	// Protocol messages belonging to LPV3
+	GetTxProofsMsg = 0x16
+	TxProofsMsg    = 0x17
 )
 
 type errCode int
@@ -99,6 +104,7 @@ const (
 	ErrInvalidResponse
 	ErrTooManyTimeouts
 	ErrMissingKey
+	ErrCapacityExhausted
 )
 
 func (e errCode) String() string {
@@ -121,6 +127,7 @@ var errorToString = map[int]string{
 	ErrInvalidResponse:         "Invalid response",
 	ErrTooManyTimeouts:         "Too many request timeouts",
 	ErrMissingKey:              "Key missing from list",
+	ErrCapacityExhausted:       "Server capacity exhausted",
 }
 
 type announceBlock struct {
@@ -226,3 +233,13 @@ type txStatus struct {
 	Lookup *core.TxLookupEntry
 	Error  error
 }
+
+// txProof is the LES/3 transaction status record. For mined transactions it
+// carries the merkle proofs of the transaction and its receipt against the
+// roots of the including block.
+type txProof struct {
+	Status       core.TxStatus
+	Lookup       *core.TxLookupEntry `rlp:"nil"`
+	TxProof      light.NodeList
+	ReceiptProof light.NodeList
+}
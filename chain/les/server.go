@@ -35,6 +35,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/p2p"
 	"github.com/Bokerchain/Boker/chain/p2p/discv5"
 	"github.com/Bokerchain/Boker/chain/rlp"
+	"github.com/Bokerchain/Boker/chain/rpc"
 )
 
 type LesServer struct {
@@ -45,6 +46,7 @@ type LesServer struct {
 	lesTopics       []discv5.Topic
 	privateKey      *ecdsa.PrivateKey
 	quitSync        chan struct{}
+	capacity        *clientCapacities // per-client request capacity and priorities
 
 	chtIndexer, bloomTrieIndexer *core.ChainIndexer
 }
@@ -98,6 +100,7 @@ func NewLesServer(eth *eth.Ethereum, config *eth.Config) (*LesServer, error) {
 		MinRecharge: 50000,
 	}
 	srv.fcManager = flowcontrol.NewClientManager(uint64(config.LightServ), 10, 1000000000)
+	srv.capacity = newClientCapacities(config.LightCapacity, srv.defParams.MinRecharge)
 	srv.fcCostStats = newCostStats(eth.ChainDb())
 	return srv, nil
 }
@@ -106,6 +109,18 @@ func (s *LesServer) Protocols() []p2p.Protocol {
 	return s.protocolManager.SubProtocols
 }
 
+// APIs returns the administrative RPC APIs of the LES server.
+func (s *LesServer) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPrivateLightServerAPI(s),
+			Public:    false,
+		},
+	}
+}
+
 // Start starts the LES server
 func (s *LesServer) Start(srvr *p2p.Server) {
 	s.protocolManager.Start()
@@ -64,6 +64,21 @@ func StateTrieID(header *types.Header) *TrieID {
 	}
 }
 
+// DposEpochTrieKey is the marker account key requesting proofs from the dpos
+// epoch trie of a block instead of its state trie.
+var DposEpochTrieKey = []byte("dpos-epoch")
+
+// DposEpochTrieID returns a TrieID for the dpos epoch trie belonging to a
+// certain block header.
+func DposEpochTrieID(header *types.Header) *TrieID {
+	return &TrieID{
+		BlockHash:   header.Hash(),
+		BlockNumber: header.Number.Uint64(),
+		AccKey:      DposEpochTrieKey,
+		Root:        header.DposProto.EpochHash,
+	}
+}
+
 // StorageTrieID returns a TrieID for a contract storage trie at a given account
 // of a given state trie. It also requires the root hash of the trie for
 // checking Merkle proofs.
@@ -128,6 +143,24 @@ func (req *ReceiptsRequest) StoreResult(db ethdb.Database) {
 	core.WriteBlockReceipts(db, req.Hash, req.Number, req.Receipts)
 }
 
+// TxProofRequest is the ODR request type for retrieving the status of a
+// transaction, along with the merkle proofs of the transaction and its receipt
+// if it is mined. The header and the receipt are filled in after the proofs are
+// verified against the canonical chain by GetTransactionProof.
+type TxProofRequest struct {
+	OdrRequest
+	Hash         common.Hash
+	Status       core.TxStatus
+	Lookup       *core.TxLookupEntry
+	TxProof      NodeList
+	ReceiptProof NodeList
+	Header       *types.Header
+	Receipt      *types.Receipt
+}
+
+// StoreResult is a no-op, transaction status is always retrieved from the network
+func (req *TxProofRequest) StoreResult(db ethdb.Database) {}
+
 // ChtRequest is the ODR request type for state/storage trie entries
 type ChtRequest struct {
 	OdrRequest
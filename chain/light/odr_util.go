@@ -20,11 +20,13 @@ import (
 	"bytes"
 	"context"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/core/types"
 	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/rlp"
+	"github.com/Bokerchain/Boker/chain/trie"
 )
 
 var sha3_nil = crypto.Keccak256Hash(nil)
@@ -126,6 +128,35 @@ func GetBlock(ctx context.Context, odr OdrBackend, hash common.Hash, number uint
 	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles), nil
 }
 
+// GetDposContext retrieves the dpos context of a block holding its epoch trie,
+// fetching the current validator list from the network if it's not available
+// locally. Only the epoch trie is resolved, which is sufficient to look up the
+// scheduled producers and token noders.
+func GetDposContext(ctx context.Context, odr OdrBackend, header *types.Header) (*types.DposContext, error) {
+	if header.DposProto == nil {
+		return nil, ErrNoDposContext
+	}
+	db := odr.Database()
+	for {
+		epochTrie, err := types.NewEpochTrie(header.DposProto.EpochHash, db)
+		if err == nil {
+			if _, err = epochTrie.TryGet(protocol.ValidatorsKey); err == nil {
+				dposContext := &types.DposContext{}
+				dposContext.SetEpoch(epochTrie)
+				return dposContext, nil
+			}
+		}
+		if _, ok := err.(*trie.MissingNodeError); !ok {
+			return nil, err
+		}
+		key := append(common.CopyBytes(protocol.EpochPrefix), protocol.ValidatorsKey...)
+		r := &TrieRequest{Id: DposEpochTrieID(header), Key: key}
+		if err := odr.Retrieve(ctx, r); err != nil {
+			return nil, err
+		}
+	}
+}
+
 // GetBlockReceipts retrieves the receipts generated by the transactions included
 // in a block given by its hash.
 func GetBlockReceipts(ctx context.Context, odr OdrBackend, hash common.Hash, number uint64) (types.Receipts, error) {
@@ -140,6 +171,49 @@ func GetBlockReceipts(ctx context.Context, odr OdrBackend, hash common.Hash, num
 	return r.Receipts, nil
 }
 
+// GetTransactionProof retrieves the status of a transaction from the network. If
+// the transaction is mined, the transaction and its receipt are verified by their
+// merkle proofs against the canonical header of the including block.
+func GetTransactionProof(ctx context.Context, odr OdrBackend, hash common.Hash) (*TxProofRequest, error) {
+	r := &TxProofRequest{Hash: hash}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	if r.Status != core.TxStatusIncluded {
+		return r, nil
+	}
+	header, err := GetHeaderByNumber(ctx, odr, r.Lookup.BlockIndex)
+	if err != nil {
+		return nil, err
+	}
+	if header.Hash() != r.Lookup.BlockHash {
+		return nil, ErrInvalidTxProof
+	}
+	key, _ := rlp.EncodeToBytes(uint(r.Lookup.Index))
+
+	// The transaction at the looked up index has to be the requested one
+	value, err, _ := trie.VerifyProof(header.TxHash, key, r.TxProof.NodeSet())
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if value == nil || rlp.DecodeBytes(value, tx) != nil || tx.Hash() != hash {
+		return nil, ErrInvalidTxProof
+	}
+	// The receipt at the same index belongs to the transaction
+	if value, err, _ = trie.VerifyProof(header.ReceiptHash, key, r.ReceiptProof.NodeSet()); err != nil {
+		return nil, err
+	}
+	receipt := new(types.Receipt)
+	if value == nil || rlp.DecodeBytes(value, receipt) != nil {
+		return nil, ErrInvalidTxProof
+	}
+	receipt.TxHash = hash
+
+	r.Header, r.Receipt = header, receipt
+	return r, nil
+}
+
 // GetBloomBits retrieves a batch of compressed bloomBits vectors belonging to the given bit index and section indexes
 func GetBloomBits(ctx context.Context, odr OdrBackend, bitIdx uint, sectionIdxList []uint64) ([][]byte, error) {
 	db := odr.Database()
@@ -131,6 +131,13 @@ func (t *odrTrie) NodeIterator(startkey []byte) trie.NodeIterator {
 	return newNodeIterator(t, startkey)
 }
 
+func (t *odrTrie) Prove(key []byte, fromLevel uint, proofDb trie.DatabaseWriter) error {
+	key = crypto.Keccak256(key)
+	return t.do(key, func() error {
+		return t.trie.Prove(key, fromLevel, proofDb)
+	})
+}
+
 func (t *odrTrie) GetKey(sha []byte) []byte {
 	return nil
 }
@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/core/state"
@@ -88,8 +89,7 @@ type TxRelayBackend interface {
 func NewTxPool(config *params.ChainConfig, chain *LightChain, relay TxRelayBackend) *TxPool {
 	pool := &TxPool{
 		config: config,
-		//signer:      types.NewEIP155Signer(config.ChainId),
-		signer:      types.HomesteadSigner{},
+		signer:      types.MakeSigner(config, chain.CurrentHeader().Number),
 		nonce:       make(map[common.Address]uint64),
 		pending:     make(map[common.Hash]*types.Transaction),
 		mined:       make(map[common.Hash][]*types.Transaction),
@@ -309,8 +309,7 @@ func (pool *TxPool) setNewHead(head *types.Header) {
 	m, r := txc.getLists()
 	pool.relay.NewHead(pool.head, m, r)
 	pool.homestead = pool.config.IsHomestead(head.Number)
-	//pool.signer = types.MakeSigner(pool.config, head.Number)
-	pool.signer = types.HomesteadSigner{}
+	pool.signer = types.MakeSigner(pool.config, head.Number)
 }
 
 // Stop stops the light transaction pool
@@ -348,8 +347,7 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 
 	// Validate the transaction sender and it's sig. Throw
 	// if the from fields is invalid.
-	//if from, err = types.Sender(pool.signer, tx); err != nil {
-	if from, err = types.Sender(types.HomesteadSigner{}, tx); err != nil {
+	if from, err = types.Sender(pool.signer, tx); err != nil {
 		return core.ErrInvalidSender
 	}
 	log.Info("validateTx tx", "Type", tx.Type(), "time", tx.Time(), "from", from.String())
@@ -360,13 +358,6 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 		return core.ErrNonceTooLow
 	}
 
-	// Check the transaction doesn't exceed the current
-	// block limit gas.
-	header := pool.chain.GetHeaderByHash(pool.head)
-	if header.GasLimit.Cmp(tx.Gas()) < 0 {
-		return core.ErrGasLimit
-	}
-
 	// Transactions can't be negative. This may never happen
 	// using RLP decoded transactions but may occur if you create
 	// a transaction using the RPC for example.
@@ -374,6 +365,21 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 		return core.ErrNegativeValue
 	}
 
+	//基础合约交易免Gas，轻节点只允许转发用户可以发起的基础交易(注册候选人、投票、取消投票等)
+	if !types.IsBinary(tx.Type()) {
+		if !protocol.IsGasFreeUserTx(tx.Type()) {
+			return core.ErrInvalidType
+		}
+		return currentState.Error()
+	}
+
+	// Check the transaction doesn't exceed the current
+	// block limit gas.
+	header := pool.chain.GetHeaderByHash(pool.head)
+	if header.GasLimit.Cmp(tx.Gas()) < 0 {
+		return core.ErrGasLimit
+	}
+
 	// Transactor should have enough funds to cover the costs
 	// cost == V + GP * GL
 	if b := currentState.GetBalance(from); b.Cmp(tx.Cost()) < 0 {
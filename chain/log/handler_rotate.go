@@ -0,0 +1,90 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFileHandler returns a handler which writes log records to the given
+// file using the given format. Once the file grows beyond maxSize bytes it is
+// renamed to path.1, older backups are shifted to path.2 ... path.maxBackups
+// and a new file is started. Backups beyond maxBackups are removed. A maxSize
+// of zero disables rotation.
+func RotatingFileHandler(path string, maxSize int64, maxBackups int, fmtr Format) (Handler, error) {
+	w := &rotatingWriter{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return closingHandler{w, StreamHandler(w, fmtr)}, nil
+}
+
+// rotatingWriter is an io.WriteCloser appending to a file which is rotated
+// once it reaches its maximum size.
+type rotatingWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+	mu   sync.Mutex
+}
+
+// open opens the log file for appending, continuing from its current size.
+func (w *rotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+// rotate closes the current file, shifts the backups and opens a new file.
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	if w.maxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", w.path, w.maxBackups))
+		for i := w.maxBackups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		}
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(w.path); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Write implements io.Writer, rotating the file before a write which would
+// exceed the maximum size. A single record is never split across files.
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close implements io.Closer.
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
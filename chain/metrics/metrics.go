@@ -32,6 +32,10 @@ import (
 const MetricsEnabledFlag = "metrics"
 const DashboardEnabledFlag = "dashboard"
 
+// MetricsPrometheusFlag is the CLI flag name to enable the Prometheus exporter,
+// which implies metrics collection.
+const MetricsPrometheusFlag = "metrics.prometheus"
+
 // Enabled is the flag specifying if metrics are enable or not.
 var Enabled = false
 
@@ -40,7 +44,7 @@ var Enabled = false
 // and peek into the command line args for the metrics flag.
 func init() {
 	for _, arg := range os.Args {
-		if flag := strings.TrimLeft(arg, "-"); flag == MetricsEnabledFlag || flag == DashboardEnabledFlag {
+		if flag := strings.TrimLeft(arg, "-"); flag == MetricsEnabledFlag || flag == DashboardEnabledFlag || flag == MetricsPrometheusFlag {
 			log.Info("Enabling metrics collection")
 			Enabled = true
 		}
@@ -57,6 +61,15 @@ func NewCounter(name string) metrics.Counter {
 	return metrics.GetOrRegisterCounter(name, metrics.DefaultRegistry)
 }
 
+// NewGauge create a new metrics Gauge, either a real one of a NOP stub depending
+// on the metrics flag.
+func NewGauge(name string) metrics.Gauge {
+	if !Enabled {
+		return new(metrics.NilGauge)
+	}
+	return metrics.GetOrRegisterGauge(name, metrics.DefaultRegistry)
+}
+
 // NewMeter create a new metrics Meter, either a real one of a NOP stub depending
 // on the metrics flag.
 func NewMeter(name string) metrics.Meter {
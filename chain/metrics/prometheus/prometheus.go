@@ -0,0 +1,105 @@
+// Package prometheus exposes the metrics registry in the Prometheus text
+// exposition format.
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/rcrowley/go-metrics"
+)
+
+// quantiles reported for timers and histograms.
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+// Handler returns an http.Handler serving the metrics in the registry.
+func Handler(reg metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(Export(reg))
+	})
+}
+
+// Start runs an HTTP server exposing the default registry on addr at /metrics.
+func Start(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(metrics.DefaultRegistry))
+
+	log.Info("Starting Prometheus metrics exporter", "addr", fmt.Sprintf("http://%s/metrics", addr))
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Error("Failed to start Prometheus metrics exporter", "err", err)
+		}
+	}()
+}
+
+// Export renders all the metrics in the registry, sorted by name.
+func Export(reg metrics.Registry) []byte {
+	names := make([]string, 0)
+	all := make(map[string]interface{})
+	reg.Each(func(name string, metric interface{}) {
+		names = append(names, name)
+		all[name] = metric
+	})
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		writeMetric(&buf, sanitize(name), all[name])
+	}
+	return buf.Bytes()
+}
+
+// writeMetric renders a single metric, mapping counters and gauges directly and
+// meters, timers and histograms to counters and summaries.
+func writeMetric(buf *bytes.Buffer, name string, metric interface{}) {
+	switch m := metric.(type) {
+	case metrics.Counter:
+		fmt.Fprintf(buf, "# TYPE %s counter\n%s %d\n", name, name, m.Count())
+
+	case metrics.Gauge:
+		fmt.Fprintf(buf, "# TYPE %s gauge\n%s %d\n", name, name, m.Value())
+
+	case metrics.GaugeFloat64:
+		fmt.Fprintf(buf, "# TYPE %s gauge\n%s %g\n", name, name, m.Value())
+
+	case metrics.Meter:
+		snap := m.Snapshot()
+		fmt.Fprintf(buf, "# TYPE %s_total counter\n%s_total %d\n", name, name, snap.Count())
+		fmt.Fprintf(buf, "# TYPE %s_rate1m gauge\n%s_rate1m %g\n", name, name, snap.Rate1())
+
+	case metrics.Timer:
+		snap := m.Snapshot()
+		writeSummary(buf, name, snap.Count(), snap.Sum(), snap.Percentiles(quantiles))
+
+	case metrics.Histogram:
+		snap := m.Snapshot()
+		writeSummary(buf, name, snap.Count(), snap.Sum(), snap.Percentiles(quantiles))
+	}
+}
+
+// writeSummary renders a summary with the configured quantiles.
+func writeSummary(buf *bytes.Buffer, name string, count, sum int64, values []float64) {
+	fmt.Fprintf(buf, "# TYPE %s summary\n", name)
+	for i, q := range quantiles {
+		fmt.Fprintf(buf, "%s{quantile=\"%g\"} %g\n", name, q, values[i])
+	}
+	fmt.Fprintf(buf, "%s_sum %d\n%s_count %d\n", name, sum, name, count)
+}
+
+// sanitize converts a registry name such as "chain/head/block" into a valid
+// Prometheus metric name.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,36 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// Tests that the registry is rendered in the Prometheus text format.
+func TestExport(t *testing.T) {
+	reg := metrics.NewRegistry()
+	metrics.GetOrRegisterCounter("txpool/invalid", reg).Inc(3)
+	metrics.GetOrRegisterGauge("chain/head/block", reg).Update(42)
+	metrics.GetOrRegisterMeter("p2p/InboundTraffic", reg).Mark(7)
+	metrics.GetOrRegisterTimer("chain/execution", reg).Update(time.Millisecond)
+
+	output := string(Export(reg))
+	for _, want := range []string{
+		"# TYPE txpool_invalid counter\ntxpool_invalid 3\n",
+		"# TYPE chain_head_block gauge\nchain_head_block 42\n",
+		"p2p_InboundTraffic_total 7\n",
+		"# TYPE chain_execution summary\n",
+		"chain_execution{quantile=\"0.5\"} 1e+06\n",
+		"chain_execution_count 1\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	// Metrics must be sorted by name
+	if strings.Index(output, "chain_execution") > strings.Index(output, "txpool_invalid") {
+		t.Errorf("metrics not sorted:\n%s", output)
+	}
+}
@@ -0,0 +1,112 @@
+package miner
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/event"
+)
+
+//交易通道，组装区块时按照通道的顺序打包交易
+const (
+	systemLane = iota //基础合约交易(AssignToken、VoteEpoch)
+	localLane         //本地提交的交易
+	remoteLane        //其他节点广播的交易，按照Gas价格排序
+	laneCount
+)
+
+//各个交易通道最多可以使用的区块Gas比例(百分比)
+type LaneQuotas struct {
+	System uint64 `json:"system"`
+	Local  uint64 `json:"local"`
+	Remote uint64 `json:"remote"`
+}
+
+//默认不限制各个通道使用的Gas，只保证通道的打包顺序
+var DefaultLaneQuotas = LaneQuotas{System: 100, Local: 100, Remote: 100}
+
+func (q LaneQuotas) validate() error {
+	for lane := 0; lane < laneCount; lane++ {
+		if quota := q.percent(lane); quota > 100 {
+			return fmt.Errorf("lane quota %d%% exceeds 100%%", quota)
+		}
+	}
+	return nil
+}
+
+func (q LaneQuotas) percent(lane int) uint64 {
+	switch lane {
+	case systemLane:
+		return q.System
+	case localLane:
+		return q.Local
+	default:
+		return q.Remote
+	}
+}
+
+//计算通道在给定区块Gas上限下可以使用的Gas
+func (q LaneQuotas) gas(lane int, gasLimit *big.Int) *big.Int {
+	gas := new(big.Int).Mul(gasLimit, new(big.Int).SetUint64(q.percent(lane)))
+	return gas.Div(gas, big.NewInt(100))
+}
+
+//是否是需要优先打包的基础合约交易
+func isSystemTx(tx *types.Transaction) bool {
+	return types.IsAssignToken(tx.Type()) || types.IsVoteEpoch(tx.Type())
+}
+
+//将待打包交易划分到各个通道，账户开头连续的基础合约交易进入系统通道，剩余交易根据账户是否是本地账户进入本地或远程通道
+func splitLanes(pending map[common.Address]types.Transactions, locals []common.Address) [laneCount]map[common.Address]types.Transactions {
+
+	var lanes [laneCount]map[common.Address]types.Transactions
+	for lane := range lanes {
+		lanes[lane] = make(map[common.Address]types.Transactions)
+	}
+	isLocal := make(map[common.Address]bool, len(locals))
+	for _, addr := range locals {
+		isLocal[addr] = true
+	}
+	for addr, txs := range pending {
+		n := 0
+		for n < len(txs) && isSystemTx(txs[n]) {
+			n++
+		}
+		if n > 0 {
+			lanes[systemLane][addr] = txs[:n]
+		}
+		if n == len(txs) {
+			continue
+		}
+		if isLocal[addr] {
+			lanes[localLane][addr] = txs[n:]
+		} else {
+			lanes[remoteLane][addr] = txs[n:]
+		}
+	}
+	return lanes
+}
+
+//按照通道顺序打包交易，每个通道使用的Gas不超过通道的配额以及区块剩余的Gas
+func (env *Work) commitLanes(mux *event.TypeMux, pending map[common.Address]types.Transactions, locals []common.Address, quotas LaneQuotas, bc *core.BlockChain, coinbase common.Address) {
+
+	gasFree := core.NewGasFreeCounter(env.config)
+
+	var coalescedLogs []*types.Log
+	for lane, txs := range splitLanes(pending, locals) {
+		if len(txs) == 0 {
+			continue
+		}
+		limit := quotas.gas(lane, env.header.GasLimit)
+		if remaining := new(big.Int).Sub(env.header.GasLimit, env.header.GasUsed); limit.Cmp(remaining) > 0 {
+			limit = remaining
+		}
+		gp := new(core.GasPool).AddGas(limit)
+		logs := env.applyTransactions(types.NewTransactionsByPriceAndNonce(env.signer, txs), bc, coinbase, gp, gasFree)
+		coalescedLogs = append(coalescedLogs, logs...)
+	}
+	env.postPending(mux, coalescedLogs)
+}
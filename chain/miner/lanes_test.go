@@ -0,0 +1,57 @@
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/core/types"
+)
+
+func laneTx(txType protocol.TxType, nonce uint64) *types.Transaction {
+	return types.NewTransaction(txType, nonce, common.Address{}, big.NewInt(0), big.NewInt(21000), big.NewInt(1), nil)
+}
+
+//测试交易按照基础合约交易、本地交易、远程交易划分通道，并且不打乱账户内的Nonce顺序
+func TestSplitLanes(t *testing.T) {
+
+	validator, local, remote := common.Address{1}, common.Address{2}, common.Address{3}
+	pending := map[common.Address]types.Transactions{
+		validator: {laneTx(protocol.VoteEpoch, 0), laneTx(protocol.AssignToken, 1), laneTx(protocol.Binary, 2), laneTx(protocol.AssignToken, 3)},
+		local:     {laneTx(protocol.Binary, 0)},
+		remote:    {laneTx(protocol.AssignToken, 0)},
+	}
+	lanes := splitLanes(pending, []common.Address{local})
+
+	if txs := lanes[systemLane][validator]; len(txs) != 2 || txs[1].Nonce() != 1 {
+		t.Errorf("validator system lane mismatch: %v", txs)
+	}
+	if txs := lanes[remoteLane][validator]; len(txs) != 2 || txs[0].Nonce() != 2 {
+		t.Errorf("validator remote lane mismatch: %v", txs)
+	}
+	if txs := lanes[localLane][local]; len(txs) != 1 {
+		t.Errorf("local lane mismatch: %v", txs)
+	}
+	if txs := lanes[systemLane][remote]; len(txs) != 1 {
+		t.Errorf("remote system lane mismatch: %v", txs)
+	}
+	if len(lanes[remoteLane][remote]) != 0 || len(lanes[localLane]) != 1 {
+		t.Errorf("unexpected lane contents: %v", lanes)
+	}
+}
+
+//测试通道配额的校验以及Gas计算
+func TestLaneQuotas(t *testing.T) {
+
+	quotas := LaneQuotas{System: 100, Local: 50, Remote: 25}
+	if err := quotas.validate(); err != nil {
+		t.Fatalf("valid quotas rejected: %v", err)
+	}
+	if gas := quotas.gas(remoteLane, big.NewInt(8000000)); gas.Cmp(big.NewInt(2000000)) != 0 {
+		t.Errorf("remote lane gas mismatch: have %v, want 2000000", gas)
+	}
+	if err := (LaneQuotas{Local: 101}).validate(); err == nil {
+		t.Errorf("quota above 100%% accepted")
+	}
+}
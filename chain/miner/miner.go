@@ -2,11 +2,15 @@ package miner
 
 import (
 	"fmt"
+	"math/big"
 	"sync/atomic"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/accounts"
 	"github.com/Bokerchain/Boker/chain/boker/api"
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
 	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/common/math"
 	"github.com/Bokerchain/Boker/chain/consensus"
 	"github.com/Bokerchain/Boker/chain/core"
 	"github.com/Bokerchain/Boker/chain/core/state"
@@ -117,6 +121,13 @@ func (self *Miner) Stop() {
 	atomic.StoreInt32(&self.shouldStart, 0)
 }
 
+//停止挖矿并等待正在进行的出块完成（包括将区块写入数据库），超时返回false
+func (self *Miner) Drain(timeout time.Duration) bool {
+
+	self.Stop()
+	return self.worker.waitMinting(timeout)
+}
+
 func (self *Miner) Mining() bool {
 	return atomic.LoadInt32(&self.mining) > 0
 }
@@ -147,6 +158,33 @@ func (self *Miner) PendingBlock() *types.Block {
 	return self.worker.pendingBlock()
 }
 
+//设置提前开始组装区块的时间，性能较差的验证者可以提前组装区块以保证在出块时间内完成封装
+func (self *Miner) SetSlotMargin(margin time.Duration) error {
+	if margin < 0 || margin >= time.Duration(protocol.ProducerInterval)*time.Second {
+		return fmt.Errorf("slot margin %v out of range [0, %ds)", margin, protocol.ProducerInterval)
+	}
+	self.worker.setSlotMargin(margin)
+	return nil
+}
+
+//设置各个交易通道最多可以使用的区块Gas比例，基础合约交易总是最先打包
+func (self *Miner) SetLaneQuotas(quotas LaneQuotas) error {
+	if err := quotas.validate(); err != nil {
+		return err
+	}
+	self.worker.setLaneQuotas(quotas)
+	return nil
+}
+
+//设置出块时区块Gas限制的调整目标，区块Gas限制每个区块最多向目标移动父区块的1/GasLimitBoundDivisor
+func (self *Miner) SetGasLimitTarget(target *big.Int) error {
+	if target.Cmp(params.MinGasLimit) < 0 || target.Cmp(math.MaxBig63) > 0 {
+		return fmt.Errorf("gas limit target %v out of range [%v, %v]", target, params.MinGasLimit, math.MaxBig63)
+	}
+	self.worker.setGasLimitTarget(target)
+	return nil
+}
+
 func (self *Miner) SetCoinbase(addr common.Address) {
 	self.coinbase = addr
 	self.worker.setCoinbase(addr)
@@ -21,6 +21,7 @@ import (
 	"github.com/Bokerchain/Boker/chain/ethdb"
 	"github.com/Bokerchain/Boker/chain/event"
 	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
 	"github.com/Bokerchain/Boker/chain/params"
 	"github.com/Bokerchain/Boker/chain/trie"
 	"gopkg.in/fatih/set.v0"
@@ -40,6 +41,13 @@ const (
 	chainSideChanSize = 10
 )
 
+var (
+	slotAssembleTimer = metrics.NewTimer("miner/slot/assemble") //组装区块耗费的时间
+	slotWaitTimer     = metrics.NewTimer("miner/slot/wait")     //组装完成后等待出块时间并签名耗费的时间
+	slotDelayTimer    = metrics.NewTimer("miner/slot/delay")    //区块封装完成的时间与出块时间的差距
+	slotMissedCounter = metrics.NewCounter("miner/slot/missed") //没有在出块时间内完成封装而丢弃的区块数量
+)
+
 // Work is the workers current environment and holds
 // all of the current state information
 type Work struct {
@@ -51,11 +59,13 @@ type Work struct {
 	family      *set.Set     // family set (used for checking uncle invalidity)
 	uncles      *set.Set     // uncle set
 	tcount      int          // tx count in cycle
+	size        uint64       // encoded size of the included transactions
 	Block       *types.Block // the new block
 	header      *types.Header
 	txs         []*types.Transaction
 	receipts    []*types.Receipt
 	createdAt   time.Time
+	internalTxs *core.InternalTxTracer
 }
 
 type Result struct {
@@ -91,6 +101,10 @@ type worker struct {
 	quitCh         chan struct{}
 	stopper        chan struct{}
 	isStart        bool
+	slotMargin     int64          //提前开始组装区块的时间(纳秒)，atomic访问
+	laneQuotas     LaneQuotas     //各个交易通道的Gas配额
+	gasTarget      *big.Int       //出块时调整区块Gas限制的目标，nil时使用全局的目标Gas限制
+	minting        sync.WaitGroup //出块循环以及已封装但尚未写入数据库的区块，停止时等待它们完成
 }
 
 func newWorker(config *params.ChainConfig, engine consensus.Engine, coinbase common.Address, eth Backend, mux *event.TypeMux) *worker {
@@ -113,6 +127,7 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, coinbase com
 		quitCh:         make(chan struct{}, 1),
 		stopper:        make(chan struct{}, 1),
 		isStart:        false,
+		laneQuotas:     DefaultLaneQuotas,
 	}
 
 	//订阅交易池的TxPreEvent事件
@@ -147,6 +162,25 @@ func (self *worker) setExtra(extra []byte) {
 	self.extra = extra
 }
 
+//设置提前组装区块的时间
+func (self *worker) setSlotMargin(margin time.Duration) {
+	atomic.StoreInt64(&self.slotMargin, int64(margin))
+}
+
+//设置出块时区块Gas限制的调整目标
+func (self *worker) setGasLimitTarget(target *big.Int) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.gasTarget = new(big.Int).Set(target)
+}
+
+//设置各个交易通道的Gas配额
+func (self *worker) setLaneQuotas(quotas LaneQuotas) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.laneQuotas = quotas
+}
+
 func (self *worker) pending() (*types.Block, *state.StateDB) {
 	self.currentMu.Lock()
 	defer self.currentMu.Unlock()
@@ -183,6 +217,7 @@ func (self *worker) start() {
 	defer self.mu.Unlock()
 
 	atomic.StoreInt32(&self.mining, 1)
+	self.minting.Add(1)
 	go self.mintLoop()
 }
 
@@ -214,20 +249,7 @@ func (self *worker) mintBlock(now int64) {
 		}
 
 		if self.chain.Boker().IsValidator(self.coinbase) {
-
-			work, err := self.createNewWork()
-			if err != nil {
-				log.Error("Failed to create the new work", "err", err)
-				return
-			}
-
-			//对区块进行封包处理
-			result, err := self.engine.Seal(self.chain, work.Block, self.quitCh)
-			if err != nil {
-				log.Error("Failed to seal the block", "err", err)
-				return
-			}
-			self.recv <- &Result{work, result}
+			self.produceBlock(now)
 		} else {
 			log.Error("current coinbase is`t special account", "coinbase", self.coinbase)
 		}
@@ -260,29 +282,64 @@ func (self *worker) mintBlock(now int64) {
 		}
 
 		//可以进行挖矿出块,创建一次挖矿矿工
-		work, err := self.createNewWork()
-		if err != nil {
-			log.Error("Failed to create the new work", "err", err)
-			return
-		}
-		//对区块进行封包处理
-		result, err := self.engine.Seal(self.chain, work.Block, self.quitCh)
-		if err != nil {
-			log.Error("Failed to seal the block", "err", err)
-			return
-		}
-		self.recv <- &Result{work, result}
+		self.produceBlock(now)
 	}
 }
 
+//在slot对应的出块时间组装并封装区块，没有在出块时间内完成封装的区块将被丢弃
+func (self *worker) produceBlock(slot int64) {
+
+	start := time.Now()
+	work, err := self.createSlotWork(slot)
+	if err != nil {
+		log.Error("Failed to create the new work", "err", err)
+		return
+	}
+	slotAssembleTimer.UpdateSince(start)
+
+	//对区块进行封包处理
+	sealStart := time.Now()
+	result, err := self.engine.Seal(self.chain, work.Block, self.quitCh)
+	if err != nil {
+		log.Error("Failed to seal the block", "err", err)
+		return
+	}
+	if result == nil {
+		return
+	}
+	slotWaitTimer.UpdateSince(sealStart)
+
+	delay := time.Since(time.Unix(slot, 0))
+	if delay < 0 {
+		delay = 0
+	}
+	slotDelayTimer.Update(delay)
+
+	if delay >= time.Duration(protocol.ProducerInterval)*time.Second {
+		slotMissedCounter.Inc(1)
+		log.Warn("Block sealed after its slot, dropping", "number", result.Number(), "slot", slot,
+			"delay", common.PrettyDuration(delay), "assemble", common.PrettyDuration(sealStart.Sub(start)))
+		return
+	}
+	self.minting.Add(1)
+	self.recv <- &Result{work, result}
+}
+
 //矿工挖矿循环
 func (self *worker) mintLoop() {
 
+	defer self.minting.Done()
+
 	ticker := time.NewTicker(time.Second).C
 	for {
 		select {
 		case now := <-ticker:
-			self.mintBlock(now.Unix())
+			//按照出块时间提前slotMargin开始组装区块
+			margin := time.Duration(atomic.LoadInt64(&self.slotMargin))
+			if engine, ok := self.engine.(*dpos.Dpos); ok {
+				margin += engine.ClockOffset() //按网络时间补偿本地时钟的偏差
+			}
+			self.mintBlock(now.Add(margin).Unix())
 		case <-self.stopper:
 			close(self.quitCh)
 			self.quitCh = make(chan struct{}, 1)
@@ -308,6 +365,22 @@ func (self *worker) stop() {
 	close(self.stopper)
 }
 
+//等待出块循环退出并且已封装的区块写入数据库，超时返回false
+func (self *worker) waitMinting(timeout time.Duration) bool {
+
+	done := make(chan struct{})
+	go func() {
+		self.minting.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
 func (self *worker) update() {
 
 	defer self.txSub.Unsubscribe()
@@ -346,55 +419,62 @@ func (self *worker) wait() {
 
 	for {
 		for result := range self.recv {
-			atomic.AddInt32(&self.atWork, -1)
+			self.writeResult(result)
+			self.minting.Done()
+		}
+	}
+}
 
-			if result == nil || result.Block == nil {
-				continue
-			}
-			block := result.Block
-			work := result.Work
-
-			// Update the block hash in all logs since it is now available and not when the
-			// receipt/log of individual transactions were created.
-			for _, r := range work.receipts {
-				for _, l := range r.Logs {
-					l.BlockHash = block.Hash()
-				}
-			}
-			for _, log := range work.state.Logs() {
-				log.BlockHash = block.Hash()
-			}
+//将封装完成的区块写入数据库并广播
+func (self *worker) writeResult(result *Result) {
 
-			//将区块和状态信息写入数据库
-			stat, err := self.chain.WriteBlockAndState(block, work.receipts, work.state)
-			if err != nil {
-				log.Error("Failed writing block to chain", "err", err)
-				continue
-			}
-			// check if canon block and write transactions
-			if stat == core.CanonStatTy {
-				// implicit by posting ChainHeadEvent
-			}
+	atomic.AddInt32(&self.atWork, -1)
 
-			//广播块并宣布链插入事件(发送这个事件是为了把新挖出的区块广播给其他结点，事件处理代码位于eth/handler.go 中的 minedBroadcastLoop)
-			self.mux.Post(core.NewMinedBlockEvent{Block: block})
-
-			//发送ChainEvent事件
-			var (
-				events []interface{}
-				logs   = work.state.Logs()
-			)
-			events = append(events, core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
-			if stat == core.CanonStatTy {
-				events = append(events, core.ChainHeadEvent{Block: block})
-			}
-			self.chain.PostChainEvents(events, logs)
+	if result == nil || result.Block == nil {
+		return
+	}
+	block := result.Block
+	work := result.Work
 
-			//将块插入待处理组中以等待确认
-			self.unconfirmed.Insert(block.NumberU64(), block.Hash())
-			//log.Info("Successfully sealed new block", "number", block.Number(), "hash", block.Hash())
+	// Update the block hash in all logs since it is now available and not when the
+	// receipt/log of individual transactions were created.
+	for _, r := range work.receipts {
+		for _, l := range r.Logs {
+			l.BlockHash = block.Hash()
 		}
 	}
+	for _, log := range work.state.Logs() {
+		log.BlockHash = block.Hash()
+	}
+
+	//将区块和状态信息写入数据库
+	stat, err := self.chain.WriteBlockAndState(block, work.receipts, work.state, work.internalTxs)
+	if err != nil {
+		log.Error("Failed writing block to chain", "err", err)
+		return
+	}
+	// check if canon block and write transactions
+	if stat == core.CanonStatTy {
+		// implicit by posting ChainHeadEvent
+	}
+
+	//广播块并宣布链插入事件(发送这个事件是为了把新挖出的区块广播给其他结点，事件处理代码位于eth/handler.go 中的 minedBroadcastLoop)
+	self.mux.Post(core.NewMinedBlockEvent{Block: block})
+
+	//发送ChainEvent事件
+	var (
+		events []interface{}
+		logs   = work.state.Logs()
+	)
+	events = append(events, core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
+	if stat == core.CanonStatTy {
+		events = append(events, core.ChainHeadEvent{Block: block})
+	}
+	self.chain.PostChainEvents(events, logs)
+
+	//将块插入待处理组中以等待确认
+	self.unconfirmed.Insert(block.NumberU64(), block.Hash())
+	//log.Info("Successfully sealed new block", "number", block.Number(), "hash", block.Hash())
 }
 
 func newBokerFromProto(db ethdb.Database, bokerProto *protocol.BokerBackendProto) (*trie.Trie, *trie.Trie, *trie.Trie, error) {
@@ -445,7 +525,7 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 	//创建一个work实例
 	work := &Work{
 		config:      self.config,
-		signer:      types.HomesteadSigner{},
+		signer:      types.MakeSigner(self.config, header.Number),
 		state:       state,
 		dposContext: dposContext,
 		ancestors:   set.New(),
@@ -454,6 +534,8 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 		header:      header,
 		createdAt:   time.Now(),
 	}
+	//开启内部交易索引时，打包交易的同时追踪合约发起的转账
+	work.internalTxs = self.chain.NewInternalTxTracer(state)
 
 	// when 08 is processed ancestors contain 07 (quick block)
 	for _, ancestor := range self.chain.GetBlocksFromHash(parent.Hash(), 7) {
@@ -466,11 +548,17 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 
 	// Keep track of transactions which return errors so they can be removed
 	work.tcount = 0
+	work.size = 0
 	self.current = work
 	return nil
 }
 
 func (self *worker) createNewWork() (*Work, error) {
+	return self.createSlotWork(time.Now().Unix())
+}
+
+//使用给定的出块时间创建挖矿工作
+func (self *worker) createSlotWork(slot int64) (*Work, error) {
 
 	//log.Info("(self *worker) createNewWork")
 
@@ -483,26 +571,31 @@ func (self *worker) createNewWork() (*Work, error) {
 	defer self.currentMu.Unlock()
 
 	//起始时间以及父块
-	tstart := time.Now()
 	parent := self.chain.CurrentBlock()
 
-	tstamp := tstart.Unix()
+	tstamp := slot
 	if parent.Time().Cmp(new(big.Int).SetInt64(tstamp)) >= 0 {
 		tstamp = parent.Time().Int64() + 1
 	}
 
-	if now := time.Now().Unix(); tstamp > now+1 {
+	//提前组装的区块允许超前slotMargin
+	margin := time.Duration(atomic.LoadInt64(&self.slotMargin))
+	if now := time.Now().Add(margin).Unix(); tstamp > now+1 {
 		wait := time.Duration(tstamp-now) * time.Second
 		log.Info("Mining too far in the future", "wait", common.PrettyDuration(wait))
 		time.Sleep(wait)
 	}
 
-	//获取块头信息
+	//获取块头信息，区块Gas限制向出块者设置的目标调整
+	target := self.gasTarget
+	if target == nil {
+		target = params.TargetGasLimit
+	}
 	num := parent.Number()
 	header := &types.Header{
 		ParentHash: parent.Hash(),
 		Number:     num.Add(num, common.Big1),
-		GasLimit:   core.CalcGasLimit(parent),
+		GasLimit:   core.CalcGasLimitTarget(parent, target),
 		GasUsed:    new(big.Int),
 		Extra:      self.extra,
 		Time:       big.NewInt(tstamp),
@@ -553,11 +646,8 @@ func (self *worker) createNewWork() (*Work, error) {
 	}
 	log.Info("(d *Dpos) Prepare", "Pending len", len(pending))
 
-	//将待处理交易列表,封装进一个TransactionsByPriceAndNonce类型的结构中。这个结构中包含一个heads字段，把交易按照gas price进行排序
-	txs := types.NewTransactionsByPriceAndNonce(self.current.signer, pending)
-
-	//调用commitTransactions把交易提交到EVM去执行
-	work.commitTransactions(self.mux, txs, self.chain, self.coinbase)
+	//按照基础合约交易、本地交易、远程交易的通道顺序把交易提交到EVM去执行，每个通道内按照gas price排序
+	work.commitLanes(self.mux, pending, self.eth.TxPool().Locals(), self.laneQuotas, self.chain, self.coinbase)
 
 	//遍历所有叔块
 	var (
@@ -635,6 +725,15 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 	//给GasPrice一个初值GasLimit
 	gp := new(core.GasPool).AddGas(env.header.GasLimit)
 
+	//统计本区块中的免Gas交易数量，用于限流
+	gasFree := core.NewGasFreeCounter(env.config)
+
+	env.postPending(mux, env.applyTransactions(txs, bc, coinbase, gp, gasFree))
+}
+
+//在给定的Gas池内依次执行交易，返回执行产生的日志
+func (env *Work) applyTransactions(txs *types.TransactionsByPriceAndNonce, bc *core.BlockChain, coinbase common.Address, gp *core.GasPool, gasFree *core.GasFreeCounter) []*types.Log {
+
 	var coalescedLogs []*types.Log
 	for {
 		//获取待处理交易池中一个交易,如果为空则退出
@@ -644,8 +743,22 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 		}
 
 		from, _ := types.Sender(env.signer, tx)
-		if tx.Protected() && !env.config.IsEIP155(env.header.Number) {
-			log.Trace("Ignoring reply protected transaction", "hash", tx.Hash(), "eip155", env.config.EIP155Block)
+		if tx.Protected() && !env.config.IsReplayProtected(env.header.Number) {
+			log.Trace("Ignoring reply protected transaction", "hash", tx.Hash(), "eip155", env.config.EIP155SignerBlock)
+			txs.Pop()
+			continue
+		}
+
+		//免Gas交易超过限制时跳过此账户的后续交易(后续交易依赖此交易的Nonce)
+		if err := gasFree.Allow(from, tx); err != nil {
+			log.Trace("Skipping rate limited gas-free transaction", "sender", from, "hash", tx.Hash(), "err", err)
+			txs.Pop()
+			continue
+		}
+
+		//区块大小达到限制时跳过此账户的后续交易，其他账户更小的交易仍然可以打包
+		if size, limit := uint64(tx.Size()), env.config.MaxBlockSize(env.header.Number); env.size+size > limit {
+			log.Trace("Block size limit reached, skipping account", "sender", from, "hash", tx.Hash(), "size", size, "used", env.size, "limit", limit)
 			txs.Pop()
 			continue
 		}
@@ -670,7 +783,9 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 
 		case nil:
 			coalescedLogs = append(coalescedLogs, logs...)
+			gasFree.Add(from, tx)
 			env.tcount++
+			env.size += uint64(tx.Size())
 			txs.Shift()
 
 		default:
@@ -680,6 +795,11 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 			txs.Shift()
 		}
 	}
+	return coalescedLogs
+}
+
+//通知订阅者挂起的日志以及挂起的状态
+func (env *Work) postPending(mux *event.TypeMux, coalescedLogs []*types.Log) {
 
 	if len(coalescedLogs) > 0 || env.tcount > 0 {
 		// make a copy, the state caches the logs and these logs get "upgraded" from pending to mined
@@ -719,7 +839,7 @@ func (env *Work) commitTransaction(tx *types.Transaction, bc *core.BlockChain, c
 		env.header,
 		tx,
 		env.header.GasUsed,
-		vm.Config{},
+		env.internalTxs.VMConfig(vm.Config{}),
 		bc.Boker())
 
 	if err != nil {
@@ -727,6 +847,7 @@ func (env *Work) commitTransaction(tx *types.Transaction, bc *core.BlockChain, c
 		//交易执行失败，则回滚到之前的快照状态并返回错误，该账户的所有后续交易都将被跳过
 		env.state.RevertToSnapshot(snap)
 		env.dposContext.RevertToSnapShot(dposSnap)
+		env.internalTxs.Discard(env.tcount)
 		return err, nil
 	}
 
@@ -0,0 +1,89 @@
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/boker/protocol"
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/consensus/ethash"
+	"github.com/Bokerchain/Boker/chain/core"
+	"github.com/Bokerchain/Boker/chain/core/types"
+	"github.com/Bokerchain/Boker/chain/core/vm"
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/params"
+)
+
+//测试打包区块时免Gas交易的数量不超过区块和单个账户的限制，超过限制的账户的后续交易被跳过
+func TestGasFreeBlockLimit(t *testing.T) {
+
+	config := *params.TestChainConfig
+	config.GasFree = &params.GasFreeConfig{AccountPerBlock: 1, PerBlock: 2}
+
+	db, _ := ethdb.NewMemDatabase()
+	genesis := (&core.Genesis{Config: &config}).MustCommit(db)
+	chain, err := core.NewBlockChain(db, &config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	statedb, err := chain.StateAt(genesis.Root())
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	dposContext, err := types.NewDposContextFromProto(db, genesis.Header().DposProto)
+	if err != nil {
+		t.Fatalf("failed to open genesis dpos context: %v", err)
+	}
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   genesis.GasLimit(),
+		GasUsed:    new(big.Int),
+		Difficulty: big.NewInt(1),
+		Time:       new(big.Int).Add(genesis.Time(), big.NewInt(1)),
+	}
+	signer := types.MakeSigner(&config, header.Number)
+	env := &Work{
+		config:      &config,
+		signer:      signer,
+		state:       statedb,
+		dposContext: dposContext,
+		header:      header,
+	}
+
+	//三个账户各有两笔投票交易
+	pending := make(map[common.Address]types.Transactions)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		pending[crypto.PubkeyToAddress(key.PublicKey)] = types.Transactions{voteTx(t, signer, key, 0), voteTx(t, signer, key, 1)}
+	}
+	gp := new(core.GasPool).AddGas(header.GasLimit)
+	env.applyTransactions(types.NewTransactionsByPriceAndNonce(signer, pending), chain, common.Address{}, gp, core.NewGasFreeCounter(&config))
+
+	if len(env.txs) != 2 || env.tcount != 2 {
+		t.Fatalf("packed transaction count mismatch: have %d (tcount %d), want 2", len(env.txs), env.tcount)
+	}
+	senders := make(map[common.Address]bool)
+	for _, tx := range env.txs {
+		from, _ := types.Sender(signer, tx)
+		if senders[from] {
+			t.Errorf("account %x packed more than one gas-free transaction", from)
+		}
+		senders[from] = true
+		if tx.Nonce() != 0 {
+			t.Errorf("account %x packed transaction with nonce %d, want 0", from, tx.Nonce())
+		}
+	}
+}
+
+func voteTx(t *testing.T, signer types.Signer, key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+	tx, err := types.SignTx(types.NewBaseTransaction(protocol.VoteUser, nonce, common.Address{0xff}, new(big.Int), nil), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
@@ -228,3 +228,12 @@ func (a *Addresses) Set(index int, address *Address) error {
 func (a *Addresses) Append(address *Address) {
 	a.addresses = append(a.addresses, address.address)
 }
+
+// DecodeFromHex decodes a hex string, with or without the 0x prefix, into the
+// binary data it represents, e.g. the bytecode of a contract to deploy.
+func DecodeFromHex(data string) ([]byte, error) {
+	if len(data) >= 2 && (data[:2] == "0x" || data[:2] == "0X") {
+		data = data[2:]
+	}
+	return hex.DecodeString(data)
+}
@@ -78,11 +78,14 @@ type SyncProgress struct {
 	progress ethereum.SyncProgress
 }
 
-func (p *SyncProgress) GetStartingBlock() int64 { return int64(p.progress.StartingBlock) }
-func (p *SyncProgress) GetCurrentBlock() int64  { return int64(p.progress.CurrentBlock) }
-func (p *SyncProgress) GetHighestBlock() int64  { return int64(p.progress.HighestBlock) }
-func (p *SyncProgress) GetPulledStates() int64  { return int64(p.progress.PulledStates) }
-func (p *SyncProgress) GetKnownStates() int64   { return int64(p.progress.KnownStates) }
+func (p *SyncProgress) GetStartingBlock() int64     { return int64(p.progress.StartingBlock) }
+func (p *SyncProgress) GetCurrentBlock() int64      { return int64(p.progress.CurrentBlock) }
+func (p *SyncProgress) GetHighestBlock() int64      { return int64(p.progress.HighestBlock) }
+func (p *SyncProgress) GetPulledStates() int64      { return int64(p.progress.PulledStates) }
+func (p *SyncProgress) GetKnownStates() int64       { return int64(p.progress.KnownStates) }
+func (p *SyncProgress) GetPulledDposStates() int64  { return int64(p.progress.PulledDposStates) }
+func (p *SyncProgress) GetKnownDposStates() int64   { return int64(p.progress.KnownDposStates) }
+func (p *SyncProgress) GetIrreversibleBlock() int64 { return int64(p.progress.IrreversibleBlock) }
 
 // Topics is a set of topic lists to filter events with.
 type Topics struct{ topics [][]common.Hash }
@@ -217,6 +217,33 @@ func NewTransaction(nonce int64, to *Address, amount, gasLimit, gasPrice *BigInt
 	return &Transaction{types.NewTransaction(protocol.Binary, uint64(nonce), to.address, amount.bigint, gasLimit.bigint, gasPrice.bigint, common.CopyBytes(data))}
 }
 
+// Transaction types supported by the Boker chain, Binary is the plain transfer
+// or contract call, the others are the gas free base transactions.
+const (
+	TxTypeBinary                 = int(protocol.Binary)
+	TxTypeSetValidator           = int(protocol.SetValidator)
+	TxTypeSetPersonalContract    = int(protocol.SetPersonalContract)
+	TxTypeCancelPersonalContract = int(protocol.CancelPersonalContract)
+	TxTypeSetSystemContract      = int(protocol.SetSystemContract)
+	TxTypeCancelSystemContract   = int(protocol.CancelSystemContract)
+	TxTypeRegisterCandidate      = int(protocol.RegisterCandidate)
+	TxTypeVoteUser               = int(protocol.VoteUser)
+	TxTypeVoteCancel             = int(protocol.VoteCancel)
+	TxTypeVoteEpoch              = int(protocol.VoteEpoch)
+	TxTypeUserEvent              = int(protocol.UserEvent)
+	TxTypeAssignToken            = int(protocol.AssignToken)
+	TxTypeUpdateContract         = int(protocol.UpdateContract)
+)
+
+// NewBaseTransaction creates a new gas free base transaction of the given type,
+// e.g. a vote or a candidate registration.
+func NewBaseTransaction(txType int, nonce int64, to *Address, amount *BigInt, data []byte) (*Transaction, error) {
+	if txType <= TxTypeBinary || txType > TxTypeUpdateContract {
+		return nil, fmt.Errorf("invalid base transaction type: %d", txType)
+	}
+	return &Transaction{types.NewBaseTransaction(protocol.TxType(txType), uint64(nonce), to.address, amount.bigint, common.CopyBytes(data))}, nil
+}
+
 // NewTransactionFromRLP parses a transaction from an RLP data dump.
 func NewTransactionFromRLP(data []byte) (*Transaction, error) {
 	tx := &Transaction{
@@ -261,6 +288,15 @@ func (tx *Transaction) GetGas() int64        { return tx.tx.Gas().Int64() }
 func (tx *Transaction) GetGasPrice() *BigInt { return &BigInt{tx.tx.GasPrice()} }
 func (tx *Transaction) GetValue() *BigInt    { return &BigInt{tx.tx.Value()} }
 func (tx *Transaction) GetNonce() int64      { return int64(tx.tx.Nonce()) }
+func (tx *Transaction) GetType() int         { return int(tx.tx.Type()) }
+
+// GetTimestamp returns the creation time of the transaction in seconds.
+func (tx *Transaction) GetTimestamp() int64 {
+	if t := tx.tx.Time(); t != nil {
+		return t.Int64()
+	}
+	return 0
+}
 
 func (tx *Transaction) GetHash() *Hash   { return &Hash{tx.tx.Hash()} }
 func (tx *Transaction) GetCost() *BigInt { return &BigInt{tx.tx.Cost()} }
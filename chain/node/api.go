@@ -19,11 +19,13 @@ package node
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/log"
 	"github.com/Bokerchain/Boker/chain/p2p"
 	"github.com/Bokerchain/Boker/chain/p2p/discover"
 	"github.com/Bokerchain/Boker/chain/rpc"
@@ -75,6 +77,81 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// AddTrustedPeer allows a remote node to always connect, even if slots are full
+func (api *PrivateAdminAPI) AddTrustedPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.AddTrustedPeer(node)
+	return true, nil
+}
+
+// RemoveTrustedPeer removes a remote node from the trusted peer set, but it
+// does not disconnect it automatically.
+func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.RemoveTrustedPeer(node)
+	return true, nil
+}
+
+// BanPeer refuses connections from the given IP address for a duration (e.g.
+// "30m") and drops the connected peers using it. A zero duration lifts the ban.
+func (api *PrivateAdminAPI) BanPeer(ip string, duration string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false, fmt.Errorf("invalid ip address: %s", ip)
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return false, fmt.Errorf("invalid duration: %v", err)
+	}
+	server.BanIP(addr, d)
+	return true, nil
+}
+
+// Drain winds the node down gracefully and then shuts it down: transactions
+// are no longer accepted over RPC and the network, the block being produced,
+// if any, is finished and written, and the trie caches are flushed. The node
+// is shut down even if draining did not finish within the timeout (e.g. "30s"),
+// in which case the error is returned.
+func (api *PrivateAdminAPI) Drain(timeout string) (bool, error) {
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return false, fmt.Errorf("invalid timeout: %v", err)
+	}
+	log.Info("Draining node before shutdown", "timeout", d)
+	err = api.node.Drain(d)
+	if err == ErrNodeStopped {
+		return false, err
+	}
+	// Stop the node asynchronously so that the response can still be sent
+	go func() {
+		if err := api.node.Stop(); err != nil {
+			log.Error("Failed to stop drained node", "err", err)
+		}
+	}()
+	return err == nil, err
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -243,6 +320,23 @@ func (api *PublicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// PeerScores is the reputation of the connected peers along with the banned
+// IP addresses.
+type PeerScores struct {
+	Peers []*p2p.PeerScore `json:"peers"`
+	Bans  []p2p.BannedIP   `json:"bans"`
+}
+
+// PeerScores retrieves the reputation scores of the connected peers, lowest
+// first, and the IP addresses currently banned.
+func (api *PublicAdminAPI) PeerScores() (*PeerScores, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return &PeerScores{Peers: server.PeerScores(), Bans: server.BannedIPs()}, nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *PublicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
@@ -24,8 +24,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/accounts"
+	"github.com/Bokerchain/Boker/chain/accounts/external"
 	"github.com/Bokerchain/Boker/chain/accounts/keystore"
 	"github.com/Bokerchain/Boker/chain/accounts/usbwallet"
 	"github.com/Bokerchain/Boker/chain/common"
@@ -66,6 +68,10 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the key-value store backing the node's databases. An empty
+	// value selects LevelDB; see ethdb.Engines for the engines compiled in.
+	DBEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -82,9 +88,19 @@ type Config struct {
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
 
+	// KeyStoreScryptN and KeyStoreScryptP override the scrypt parameters selected
+	// by UseLightweightKDF when non-zero, allowing operators to tune the cost of
+	// key encryption to the hardware validator keys are stored on.
+	KeyStoreScryptN int `toml:",omitempty"`
+	KeyStoreScryptP int `toml:",omitempty"`
+
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
+	// ExternalSigner is the IPC path or HTTP URL of an external signer holding
+	// the keys of the node, which are then never loaded into the node process.
+	ExternalSigner string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -110,6 +126,40 @@ type Config struct {
 	// exposed.
 	HTTPModules []string `toml:",omitempty"`
 
+	// HTTPVirtualHosts is the list of virtual hostnames which are allowed on incoming requests.
+	// This is by default {'localhost'}. Using this prevents attacks like
+	// DNS rebinding, which bypasses SOP by simply masquerading as being within the same
+	// origin. These attacks do not utilize CORS, since they are not cross-domain.
+	// By explicitly checking the Host-header, the server will not allow requests
+	// made against the server with a malicious host domain.
+	// Requests using ip address directly are not affected
+	HTTPVirtualHosts []string `toml:",omitempty"`
+
+	// HTTPAuthModules is the list of API modules which may only be called over
+	// HTTP with valid credentials, once an API key or JWT secret is configured.
+	HTTPAuthModules []string `toml:",omitempty"`
+
+	// HTTPAPIKey is the shared secret clients must send as bearer token or in the
+	// X-API-Key header to call the protected HTTP API modules.
+	HTTPAPIKey string `toml:",omitempty"`
+
+	// HTTPJWTSecret is the path of a file holding the hex encoded secret the JSON
+	// web tokens authorizing calls to the protected HTTP API modules are signed with.
+	HTTPJWTSecret string `toml:",omitempty"`
+
+	// GraphQLHost is the host interface on which to start the GraphQL server. If this
+	// field is empty, no GraphQL endpoint will be started.
+	GraphQLHost string `toml:",omitempty"`
+
+	// GraphQLPort is the TCP port number on which to start the GraphQL server. The
+	// default zero value is valid and will pick a port number randomly (useful
+	// for ephemeral nodes).
+	GraphQLPort int `toml:",omitempty"`
+
+	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
+	// clients of the GraphQL endpoint.
+	GraphQLCors []string `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -135,6 +185,23 @@ type Config struct {
 	// *WARNING* Only set this if the node is running in a trusted network, exposing
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
+
+	// RPCBatchLimit is the maximum number of requests accepted in a single batch
+	// over the HTTP and websocket RPC interfaces. Zero disables the limit.
+	RPCBatchLimit int `toml:",omitempty"`
+
+	// RPCResponseLimit is the maximum size in bytes of the result of a single
+	// call over the HTTP and websocket RPC interfaces. Zero disables the limit.
+	RPCResponseLimit int `toml:",omitempty"`
+
+	// RPCTimeout is the time a method called over the HTTP and websocket RPC
+	// interfaces may run before it is cancelled and an error is returned. It
+	// only applies to methods taking a context. Zero disables the timeout.
+	RPCTimeout time.Duration `toml:",omitempty"`
+
+	// RPCMethodTimeouts overrides RPCTimeout for individual methods, keyed by
+	// their full name (e.g. eth_call).
+	RPCMethodTimeouts map[string]time.Duration `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -197,6 +264,15 @@ func DefaultHTTPEndpoint() string {
 	return config.HTTPEndpoint()
 }
 
+// GraphQLEndpoint resolves a GraphQL endpoint based on the configured host
+// interface and port parameters.
+func (c *Config) GraphQLEndpoint() string {
+	if c.GraphQLHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.GraphQLHost, c.GraphQLPort)
+}
+
 // WSEndpoint resolves an websocket endpoint based on the configured host interface
 // and port parameters.
 func (c *Config) WSEndpoint() string {
@@ -368,6 +444,12 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 		scryptN = keystore.LightScryptN
 		scryptP = keystore.LightScryptP
 	}
+	if c.KeyStoreScryptN > 0 {
+		scryptN = c.KeyStoreScryptN
+	}
+	if c.KeyStoreScryptP > 0 {
+		scryptP = c.KeyStoreScryptP
+	}
 
 	var (
 		keydir string
@@ -407,6 +489,13 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	backends := []accounts.Backend{
 		keystore.NewKeyStore(keydir, scryptN, scryptP),
 	}
+	if conf.ExternalSigner != "" {
+		signer, err := external.NewExternalBackend(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", fmt.Errorf("error connecting to external signer: %v", err)
+		}
+		backends = append(backends, signer)
+	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
 		if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
@@ -21,25 +21,39 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/p2p"
 	"github.com/Bokerchain/Boker/chain/p2p/nat"
 )
 
 const (
-	DefaultHTTPHost = "localhost" // Default host interface for the HTTP RPC server
-	DefaultHTTPPort = 8545        // Default TCP port for the HTTP RPC server
-	DefaultWSHost   = "localhost" // Default host interface for the websocket RPC server
-	DefaultWSPort   = 8546        // Default TCP port for the websocket RPC server
+	DefaultHTTPHost    = "localhost" // Default host interface for the HTTP RPC server
+	DefaultHTTPPort    = 8545        // Default TCP port for the HTTP RPC server
+	DefaultWSHost      = "localhost" // Default host interface for the websocket RPC server
+	DefaultWSPort      = 8546        // Default TCP port for the websocket RPC server
+	DefaultGraphQLHost = "localhost" // Default host interface for the GraphQL server
+	DefaultGraphQLPort = 8547        // Default TCP port for the GraphQL server
+
+	DefaultRPCBatchLimit    = 1000             // Default maximum number of requests in an RPC batch
+	DefaultRPCResponseLimit = 25 * 1024 * 1024 // Default maximum size of an RPC call result
+	DefaultRPCTimeout       = time.Duration(0) // Default execution timeout of RPC methods, disabled unless configured
 )
 
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
-	DataDir:     DefaultDataDir(),
-	HTTPPort:    DefaultHTTPPort,
-	HTTPModules: []string{"net", "web3"},
-	WSPort:      DefaultWSPort,
-	WSModules:   []string{"net", "web3"},
+	DataDir:          DefaultDataDir(),
+	HTTPPort:         DefaultHTTPPort,
+	HTTPModules:      []string{"net", "web3"},
+	HTTPVirtualHosts: []string{"localhost"},
+	HTTPAuthModules:  []string{"admin", "debug", "miner"},
+	WSPort:           DefaultWSPort,
+	WSModules:        []string{"net", "web3"},
+	GraphQLPort:      DefaultGraphQLPort,
+
+	RPCBatchLimit:    DefaultRPCBatchLimit,
+	RPCResponseLimit: DefaultRPCResponseLimit,
+	RPCTimeout:       DefaultRPCTimeout,
 	P2P: p2p.Config{
 		ListenAddr:      ":30303",
 		DiscoveryV5Addr: ":30304",
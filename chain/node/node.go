@@ -17,14 +17,17 @@
 package node
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/accounts"
 	"github.com/Bokerchain/Boker/chain/ethdb"
@@ -50,6 +53,7 @@ type Node struct {
 
 	serviceFuncs []ServiceConstructor     // Service constructors (in dependency order)
 	services     map[reflect.Type]Service // Currently running services
+	serviceKinds []reflect.Type           // Kinds of the running services (in dependency order)
 
 	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
@@ -160,6 +164,7 @@ func (n *Node) Start() error {
 
 	//通过node的serviceFuncs所包含的构造函数，生成了一系列的Service
 	services := make(map[reflect.Type]Service)
+	kinds := []reflect.Type{}
 	for _, constructor := range n.serviceFuncs {
 
 		//创建服务的上下文
@@ -187,6 +192,7 @@ func (n *Node) Start() error {
 			return &DuplicateServiceError{Kind: kind}
 		}
 		services[kind] = service
+		kinds = append(kinds, kind)
 	}
 	//log.Info("create Service Context and Constrctor")
 
@@ -201,14 +207,14 @@ func (n *Node) Start() error {
 		return convertFileLockError(err)
 	}
 
-	//启动生成的Service
+	//按照注册顺序启动生成的Service，停止时按相反的顺序
 	started := []reflect.Type{}
-	for kind, service := range services {
+	for _, kind := range kinds {
 
 		// Start the next service, stopping all previous upon failure
-		if err := service.Start(running); err != nil {
-			for _, kind := range started {
-				services[kind].Stop()
+		if err := services[kind].Start(running); err != nil {
+			for i := len(started) - 1; i >= 0; i-- {
+				services[started[i]].Stop()
 			}
 			running.Stop()
 
@@ -232,6 +238,7 @@ func (n *Node) Start() error {
 
 	//完成启动的初始化工作
 	n.services = services
+	n.serviceKinds = kinds
 	n.server = running
 	n.stop = make(chan struct{})
 
@@ -395,6 +402,7 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	n.limitRPC(handler)
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -403,15 +411,16 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 			log.Debug(fmt.Sprintf("HTTP registered %T under '%s'", api.Service, api.Namespace))
 		}
 	}
+	auth, err := n.httpAuth()
+	if err != nil {
+		return err
+	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
+	var listener net.Listener
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return err
 	}
-	go rpc.NewHTTPServer(cors, handler).Serve(listener)
+	go rpc.NewHTTPServer(cors, n.config.HTTPVirtualHosts, auth, handler).Serve(listener)
 	log.Info(fmt.Sprintf("HTTP endpoint opened: http://%s", endpoint))
 
 	// All listeners booted successfully
@@ -422,6 +431,36 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	return nil
 }
 
+// httpAuth assembles the credentials required to call the protected HTTP API
+// modules, loading the JWT secret from disk if configured.
+func (n *Node) httpAuth() (*rpc.HTTPAuth, error) {
+	auth := &rpc.HTTPAuth{
+		APIKey:  n.config.HTTPAPIKey,
+		Modules: n.config.HTTPAuthModules,
+	}
+	if n.config.HTTPJWTSecret != "" {
+		blob, err := ioutil.ReadFile(n.config.HTTPJWTSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT secret: %v", err)
+		}
+		if auth.JWTSecret, err = hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x")); err != nil {
+			return nil, fmt.Errorf("invalid JWT secret: %v", err)
+		}
+	}
+	if (auth.APIKey != "" || len(auth.JWTSecret) > 0) && len(auth.Modules) > 0 {
+		log.Info("HTTP endpoint requires credentials", "modules", strings.Join(auth.Modules, ","))
+	}
+	return auth, nil
+}
+
+// limitRPC applies the configured batch, response size and execution time
+// limits to an RPC server exposed over the network.
+func (n *Node) limitRPC(handler *rpc.Server) {
+	handler.SetBatchLimit(n.config.RPCBatchLimit)
+	handler.SetResponseLimit(n.config.RPCResponseLimit)
+	handler.SetExecutionTimeout(n.config.RPCTimeout, n.config.RPCMethodTimeouts)
+}
+
 // stopHTTP terminates the HTTP RPC endpoint.
 func (n *Node) stopHTTP() {
 	if n.httpListener != nil {
@@ -449,6 +488,7 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	n.limitRPC(handler)
 	for _, api := range apis {
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -509,13 +549,15 @@ func (n *Node) Stop() error {
 	failure := &StopError{
 		Services: make(map[reflect.Type]error),
 	}
-	for kind, service := range n.services {
-		if err := service.Stop(); err != nil {
+	for i := len(n.serviceKinds) - 1; i >= 0; i-- {
+		kind := n.serviceKinds[i]
+		if err := n.services[kind].Stop(); err != nil {
 			failure.Services[kind] = err
 		}
 	}
 	n.server.Stop()
 	n.services = nil
+	n.serviceKinds = nil
 	n.server = nil
 
 	// Release instance directory lock.
@@ -544,6 +586,37 @@ func (n *Node) Stop() error {
 	return nil
 }
 
+// Drain asks the running services implementing Drainer, in reverse dependency
+// order, to stop accepting new work and finish the work in progress within the
+// given timeout. The node keeps running, it is up to the caller to stop it.
+func (n *Node) Drain(timeout time.Duration) error {
+	n.lock.RLock()
+	if n.server == nil {
+		n.lock.RUnlock()
+		return ErrNodeStopped
+	}
+	var drainers []Drainer
+	for i := len(n.serviceKinds) - 1; i >= 0; i-- {
+		if drainer, ok := n.services[n.serviceKinds[i]].(Drainer); ok {
+			drainers = append(drainers, drainer)
+		}
+	}
+	n.lock.RUnlock()
+
+	deadline := time.Now().Add(timeout)
+	var failure error
+	for _, drainer := range drainers {
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			remaining = 0
+		}
+		if err := drainer.Drain(remaining); err != nil && failure == nil {
+			failure = err
+		}
+	}
+	return failure
+}
+
 // Wait blocks the thread until the node is stopped. If the node is not running
 // at the time of invocation, the method immediately returns.
 func (n *Node) Wait() {
@@ -664,7 +737,7 @@ func (n *Node) OpenDatabase(name string, cache, handles int) (ethdb.Database, er
 	if n.config.DataDir == "" {
 		return ethdb.NewMemDatabase()
 	}
-	return ethdb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
+	return ethdb.Open(n.config.DBEngine, n.config.resolvePath(name), cache, handles)
 }
 
 // ResolvePath returns the absolute path of a resource in the instance directory.
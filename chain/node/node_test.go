@@ -407,6 +407,84 @@ func TestServiceTerminationGuarantee(t *testing.T) {
 	}
 }
 
+// Tests that services are started in registration order and stopped in the
+// reverse order.
+func TestServiceOrdering(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	var events []string
+	for _, service := range []struct {
+		id    string
+		maker InstrumentingWrapper
+	}{{"A", InstrumentedServiceMakerA}, {"B", InstrumentedServiceMakerB}, {"C", InstrumentedServiceMakerC}} {
+		id := service.id
+		constructor := func(*ServiceContext) (Service, error) {
+			return &InstrumentedService{
+				startHook: func(*p2p.Server) { events = append(events, "start "+id) },
+				stopHook:  func() { events = append(events, "stop "+id) },
+			}, nil
+		}
+		if err := stack.Register(service.maker(constructor)); err != nil {
+			t.Fatalf("service %s: registration failed: %v", id, err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		events = nil
+		if err := stack.Start(); err != nil {
+			t.Fatalf("iter %d: failed to start protocol stack: %v", i, err)
+		}
+		if err := stack.Stop(); err != nil {
+			t.Fatalf("iter %d: failed to stop protocol stack: %v", i, err)
+		}
+		want := []string{"start A", "start B", "start C", "stop C", "stop B", "stop A"}
+		if !reflect.DeepEqual(events, want) {
+			t.Fatalf("iter %d: lifecycle order mismatch: have %v, want %v", i, events, want)
+		}
+	}
+}
+
+// Tests that draining reaches the services implementing Drainer, reports their
+// failures and leaves the node running.
+func TestServiceDrain(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Drain(time.Second); err != ErrNodeStopped {
+		t.Fatalf("drain of stopped node mismatch: have %v, want %v", err, ErrNodeStopped)
+	}
+	var drained time.Duration
+	failure := errors.New("fail")
+	drainer := func(*ServiceContext) (Service, error) {
+		return &DrainedService{
+			drain:     failure,
+			drainHook: func(timeout time.Duration) { drained = timeout },
+		}, nil
+	}
+	if err := stack.Register(NewNoopService); err != nil {
+		t.Fatalf("noop registration failed: %v", err)
+	}
+	if err := stack.Register(drainer); err != nil {
+		t.Fatalf("drainer registration failed: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	if err := stack.Drain(time.Minute); err != failure {
+		t.Fatalf("drain failure mismatch: have %v, want %v", err, failure)
+	}
+	if drained <= 0 || drained > time.Minute {
+		t.Fatalf("drain timeout mismatch: have %v, want at most %v", drained, time.Minute)
+	}
+	if stack.Server() == nil {
+		t.Fatalf("node stopped by drain")
+	}
+}
+
 // TestServiceRetrieval tests that individual services can be retrieved.
 func TestServiceRetrieval(t *testing.T) {
 	// Create a simple stack and register two service types
@@ -18,6 +18,7 @@ package node
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/accounts"
 	"github.com/Bokerchain/Boker/chain/ethdb"
@@ -43,7 +44,7 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (et
 	if ctx.config.DataDir == "" {
 		return ethdb.NewMemDatabase()
 	}
-	db, err := ethdb.NewLDBDatabase(ctx.config.resolvePath(name), cache, handles)
+	db, err := ethdb.Open(ctx.config.DBEngine, ctx.config.resolvePath(name), cache, handles)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +68,14 @@ func (ctx *ServiceContext) Service(service interface{}) error {
 	return ErrServiceUnknown
 }
 
+// Drainer is implemented by services which need to wind down gracefully before
+// the node is stopped, e.g. to finish writing data in progress.
+type Drainer interface {
+	// Drain stops the service from accepting new work and waits at most the
+	// given timeout for the work in progress to finish.
+	Drain(timeout time.Duration) error
+}
+
 // ServiceConstructor is the function signature of the constructors needed to be
 // registered for service instantiation.
 type ServiceConstructor func(ctx *ServiceContext) (Service, error)
@@ -21,6 +21,7 @@ package node
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/p2p"
 	"github.com/Bokerchain/Boker/chain/rpc"
@@ -121,6 +122,21 @@ func InstrumentedServiceMakerC(base ServiceConstructor) ServiceConstructor {
 	return InstrumentingWrapperMaker(base, reflect.TypeOf(InstrumentedServiceC{}))
 }
 
+// DrainedService is an InstrumentedService which can also be drained.
+type DrainedService struct {
+	InstrumentedService
+
+	drain     error
+	drainHook func(time.Duration)
+}
+
+func (s *DrainedService) Drain(timeout time.Duration) error {
+	if s.drainHook != nil {
+		s.drainHook(timeout)
+	}
+	return s.drain
+}
+
 // OneMethodApi is a single-method API handler to be returned by test services.
 type OneMethodApi struct {
 	fun func()
@@ -0,0 +1,477 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discv5
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/rlp"
+)
+
+// This file implements a client for node lists published in DNS, following the
+// tree format of EIP-1459. A tree is rooted at a TXT record of its domain:
+//
+//	enrtree-root:v1 e=<enr-root> l=<link-root> seq=<sequence> sig=<signature>
+//
+// The hashes below the root name subdomains holding either branches
+// ("enrtree-branch:<h1>,<h2>,..."), node records or links to other trees
+// ("enrtree://<key>@<domain>"). Besides "enr:" records, leaves may hold plain
+// "enode://" URLs so trees can be published without ENR tooling.
+
+const (
+	dnsRootPrefix   = "enrtree-root:v1"
+	dnsLinkPrefix   = "enrtree://"
+	dnsBranchPrefix = "enrtree-branch:"
+	dnsENRPrefix    = "enr:"
+	dnsEnodePrefix  = "enode://"
+
+	dnsMaxEntries   = 10000            // Maximum number of entries resolved in a single sync
+	dnsMaxChildren  = 13               // Maximum number of children in a generated branch
+	dnsQueryTimeout = 10 * time.Second // Timeout of a single TXT lookup
+	dnsHashLength   = 16               // Length of the truncated entry hashes
+)
+
+var (
+	b32format = base32.StdEncoding.WithPadding(base32.NoPadding)
+	b64format = base64.RawURLEncoding
+)
+
+var (
+	errInvalidDNSURL   = errors.New("invalid enrtree URL")
+	errNoDNSRoot       = errors.New("no enrtree root found")
+	errDNSRootSig      = errors.New("invalid enrtree root signature")
+	errDNSHashMismatch = errors.New("enrtree entry hash mismatch")
+	errDNSTooLarge     = errors.New("enrtree has too many entries")
+	errDNSLinkInTree   = errors.New("link entry in node subtree")
+)
+
+// DNSResolver is the subset of net.Resolver used to look up tree entries.
+type DNSResolver interface {
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+// DNSClient retrieves node lists published in DNS.
+type DNSClient struct {
+	resolver DNSResolver
+}
+
+// NewDNSClient creates a client resolving through r, or through the system
+// resolver if r is nil.
+func NewDNSClient(r DNSResolver) *DNSClient {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	return &DNSClient{resolver: r}
+}
+
+// dnsLink is a reference to a tree, consisting of the domain the tree is
+// published at and the compressed public key signing its root.
+type dnsLink struct {
+	domain string
+	pubkey []byte
+}
+
+// ParseDNSURL validates an enrtree://<key>@<domain> URL.
+func ParseDNSURL(url string) error {
+	_, err := parseDNSLink(url)
+	return err
+}
+
+func parseDNSLink(url string) (*dnsLink, error) {
+	if !strings.HasPrefix(url, dnsLinkPrefix) {
+		return nil, errInvalidDNSURL
+	}
+	pos := strings.IndexByte(url, '@')
+	if pos < 0 {
+		return nil, errInvalidDNSURL
+	}
+	key, domain := url[len(dnsLinkPrefix):pos], url[pos+1:]
+	pubkey, err := b32format.DecodeString(strings.ToUpper(key))
+	if err != nil || len(pubkey) != 33 || domain == "" {
+		return nil, errInvalidDNSURL
+	}
+	return &dnsLink{domain: domain, pubkey: pubkey}, nil
+}
+
+// dnsRoot is the parsed root entry of a tree.
+type dnsRoot struct {
+	eroot string
+	lroot string
+	seq   uint
+	sig   []byte
+}
+
+func (r *dnsRoot) sigHash() []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("%s e=%s l=%s seq=%d", dnsRootPrefix, r.eroot, r.lroot, r.seq)))
+}
+
+func (r *dnsRoot) String() string {
+	return fmt.Sprintf("%s e=%s l=%s seq=%d sig=%s", dnsRootPrefix, r.eroot, r.lroot, r.seq, b64format.EncodeToString(r.sig))
+}
+
+func parseDNSRoot(txt string) (*dnsRoot, error) {
+	var (
+		r   dnsRoot
+		sig string
+	)
+	if _, err := fmt.Sscanf(txt, dnsRootPrefix+" e=%s l=%s seq=%d sig=%s", &r.eroot, &r.lroot, &r.seq, &sig); err != nil {
+		return nil, fmt.Errorf("invalid enrtree root: %v", err)
+	}
+	var err error
+	if r.sig, err = b64format.DecodeString(sig); err != nil || len(r.sig) != 65 {
+		return nil, errDNSRootSig
+	}
+	return &r, nil
+}
+
+// SyncTree resolves the tree at the given enrtree URL along with all trees it
+// links to and returns the nodes contained in them.
+func (c *DNSClient) SyncTree(ctx context.Context, url string) ([]*Node, error) {
+	link, err := parseDNSLink(url)
+	if err != nil {
+		return nil, err
+	}
+	s := &dnsSync{client: c, visited: make(map[string]bool), seen: make(map[NodeID]bool)}
+	if err := s.syncTree(ctx, link); err != nil {
+		return nil, err
+	}
+	return s.nodes, nil
+}
+
+// dnsSync holds the state of a single tree sync.
+type dnsSync struct {
+	client  *DNSClient
+	visited map[string]bool // linked domains already synced
+	entries int
+	seen    map[NodeID]bool
+	nodes   []*Node
+}
+
+func (s *dnsSync) syncTree(ctx context.Context, link *dnsLink) error {
+	if s.visited[link.domain] {
+		return nil
+	}
+	s.visited[link.domain] = true
+
+	root, err := s.resolveRoot(ctx, link)
+	if err != nil {
+		return fmt.Errorf("%s: %v", link.domain, err)
+	}
+	var links []*dnsLink
+	if err := s.resolveEntry(ctx, link.domain, root.eroot, false, &links); err != nil {
+		return fmt.Errorf("%s: %v", link.domain, err)
+	}
+	if err := s.resolveEntry(ctx, link.domain, root.lroot, true, &links); err != nil {
+		return fmt.Errorf("%s: %v", link.domain, err)
+	}
+	for _, l := range links {
+		if err := s.syncTree(ctx, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveRoot retrieves the root entry of a tree and checks its signature
+// against the key of the link.
+func (s *dnsSync) resolveRoot(ctx context.Context, link *dnsLink) (*dnsRoot, error) {
+	txts, err := s.lookup(ctx, link.domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		if !strings.HasPrefix(txt, dnsRootPrefix) {
+			continue
+		}
+		root, err := parseDNSRoot(txt)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := crypto.SigToPub(root.sigHash(), root.sig)
+		if err != nil || !bytes.Equal(compressPubkey(pub), link.pubkey) {
+			return nil, errDNSRootSig
+		}
+		return root, nil
+	}
+	return nil, errNoDNSRoot
+}
+
+// resolveEntry retrieves the entry with the given hash and everything below it.
+// Links are only accepted in the link subtree, nodes only in the node subtree.
+func (s *dnsSync) resolveEntry(ctx context.Context, domain, hash string, linkTree bool, links *[]*dnsLink) error {
+	if s.entries++; s.entries > dnsMaxEntries {
+		return errDNSTooLarge
+	}
+	txts, err := s.lookup(ctx, hash+"."+domain)
+	if err != nil {
+		return err
+	}
+	var entry string
+	for _, txt := range txts {
+		if dnsEntryHash(txt) == strings.ToUpper(hash) {
+			entry = txt
+			break
+		}
+	}
+	if entry == "" {
+		return errDNSHashMismatch
+	}
+	switch {
+	case strings.HasPrefix(entry, dnsBranchPrefix):
+		children := strings.TrimPrefix(entry, dnsBranchPrefix)
+		if children == "" {
+			return nil
+		}
+		for _, child := range strings.Split(children, ",") {
+			if err := s.resolveEntry(ctx, domain, child, linkTree, links); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case strings.HasPrefix(entry, dnsLinkPrefix):
+		if !linkTree {
+			return errDNSLinkInTree
+		}
+		link, err := parseDNSLink(entry)
+		if err != nil {
+			return err
+		}
+		*links = append(*links, link)
+		return nil
+
+	case strings.HasPrefix(entry, dnsENRPrefix), strings.HasPrefix(entry, dnsEnodePrefix):
+		if linkTree {
+			return fmt.Errorf("node entry in link subtree")
+		}
+		var node *Node
+		if strings.HasPrefix(entry, dnsENRPrefix) {
+			node, err = parseENR(strings.TrimPrefix(entry, dnsENRPrefix))
+		} else {
+			node, err = ParseNode(entry)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid node entry %s: %v", hash, err)
+		}
+		if !s.seen[node.ID] {
+			s.seen[node.ID] = true
+			s.nodes = append(s.nodes, node)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown enrtree entry %q", entry)
+	}
+}
+
+func (s *dnsSync) lookup(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+	return s.client.resolver.LookupTXT(ctx, name)
+}
+
+// dnsEntryHash returns the subdomain an entry is published at.
+func dnsEntryHash(entry string) string {
+	return b32format.EncodeToString(crypto.Keccak256([]byte(entry))[:dnsHashLength])
+}
+
+// parseENR extracts the endpoint and identity of a node from a "v4" node
+// record. The record signature is checked as well, although the tree hashes
+// already authenticate the record.
+func parseENR(input string) (*Node, error) {
+	blob, err := b64format.DecodeString(input)
+	if err != nil {
+		return nil, err
+	}
+	var elems []rlp.RawValue
+	if err := rlp.DecodeBytes(blob, &elems); err != nil {
+		return nil, err
+	}
+	if len(elems) < 2 || len(elems)%2 != 0 {
+		return nil, errors.New("malformed record")
+	}
+	var (
+		sig, pubkey []byte
+		ip          net.IP
+		tcp, udp    uint16
+	)
+	if err := rlp.DecodeBytes(elems[0], &sig); err != nil || len(sig) != 64 {
+		return nil, errors.New("invalid record signature")
+	}
+	for i := 2; i < len(elems); i += 2 {
+		var key string
+		if err := rlp.DecodeBytes(elems[i], &key); err != nil {
+			return nil, err
+		}
+		switch key {
+		case "secp256k1":
+			err = rlp.DecodeBytes(elems[i+1], &pubkey)
+		case "ip":
+			err = rlp.DecodeBytes(elems[i+1], &ip)
+		case "tcp":
+			err = rlp.DecodeBytes(elems[i+1], &tcp)
+		case "udp":
+			err = rlp.DecodeBytes(elems[i+1], &udp)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid record key %q: %v", key, err)
+		}
+	}
+	pub, err := decompressPubkey(pubkey)
+	if err != nil {
+		return nil, err
+	}
+	content, err := rlp.EncodeToBytes(elems[1:])
+	if err != nil {
+		return nil, err
+	}
+	if !verifyRecordSig(crypto.Keccak256(content), sig, pubkey) {
+		return nil, errors.New("invalid record signature")
+	}
+	if udp == 0 {
+		udp = tcp
+	}
+	node := NewNode(PubkeyID(pub), ip, udp, tcp)
+	if err := node.validateComplete(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// verifyRecordSig checks a 64 byte [R || S] signature by trying both recovery
+// ids against the compressed public key.
+func verifyRecordSig(hash, sig, pubkey []byte) bool {
+	for v := byte(0); v < 2; v++ {
+		pub, err := crypto.SigToPub(hash, append(append([]byte{}, sig...), v))
+		if err == nil && bytes.Equal(compressPubkey(pub), pubkey) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressPubkey encodes a public key to the 33 byte compressed format.
+func compressPubkey(pub *ecdsa.PublicKey) []byte {
+	out := make([]byte, 33)
+	out[0] = byte(0x02 | pub.Y.Bit(0))
+	x := pub.X.Bytes()
+	copy(out[33-len(x):], x)
+	return out
+}
+
+// decompressPubkey parses a public key in the 33 byte compressed format.
+func decompressPubkey(data []byte) (*ecdsa.PublicKey, error) {
+	if len(data) != 33 || (data[0] != 0x02 && data[0] != 0x03) {
+		return nil, errors.New("invalid compressed public key")
+	}
+	curve := crypto.S256()
+	p := curve.Params().P
+	x := new(big.Int).SetBytes(data[1:])
+	if x.Cmp(p) >= 0 {
+		return nil, errors.New("invalid compressed public key")
+	}
+	// y² = x³ + 7, and since p = 3 mod 4 the root is (y²)^((p+1)/4)
+	y := new(big.Int).Exp(x, big.NewInt(3), p)
+	y.Add(y, big.NewInt(7)).Mod(y, p)
+	exp := new(big.Int).Add(p, big.NewInt(1))
+	y.Exp(y, exp.Rsh(exp, 2), p)
+	if y.Bit(0) != uint(data[0]&1) {
+		y.Sub(p, y)
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("invalid compressed public key")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// MakeDNSTree builds and signs a tree holding the given nodes as enode URLs
+// and links to other trees. It returns the TXT records to publish, keyed by
+// their name relative to the tree domain, the root being keyed by "".
+func MakeDNSTree(nodes []*Node, links []string, seq uint, key *ecdsa.PrivateKey) (map[string]string, error) {
+	records := make(map[string]string)
+
+	leaves := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		leaves = append(leaves, n.String())
+	}
+	for _, l := range links {
+		if _, err := parseDNSLink(l); err != nil {
+			return nil, fmt.Errorf("invalid link %q: %v", l, err)
+		}
+	}
+	sort.Strings(leaves)
+	sort.Strings(links)
+
+	root := &dnsRoot{
+		eroot: buildDNSSubtree(records, leaves),
+		lroot: buildDNSSubtree(records, links),
+		seq:   seq,
+	}
+	sig, err := crypto.Sign(root.sigHash(), key)
+	if err != nil {
+		return nil, err
+	}
+	root.sig = sig
+	records[""] = root.String()
+	return records, nil
+}
+
+// DNSTreeURL returns the enrtree URL of a tree signed by key at domain.
+func DNSTreeURL(key *ecdsa.PublicKey, domain string) string {
+	return dnsLinkPrefix + b32format.EncodeToString(compressPubkey(key)) + "@" + domain
+}
+
+// buildDNSSubtree stores the entries in a tree of branches no wider than
+// dnsMaxChildren and returns the hash of its top.
+func buildDNSSubtree(records map[string]string, entries []string) string {
+	if len(entries) == 1 {
+		hash := dnsEntryHash(entries[0])
+		records[hash] = entries[0]
+		return hash
+	}
+	var children []string
+	if len(entries) <= dnsMaxChildren {
+		for _, e := range entries {
+			children = append(children, buildDNSSubtree(records, []string{e}))
+		}
+	} else {
+		per := (len(entries) + dnsMaxChildren - 1) / dnsMaxChildren
+		for i := 0; i < len(entries); i += per {
+			end := i + per
+			if end > len(entries) {
+				end = len(entries)
+			}
+			children = append(children, buildDNSSubtree(records, entries[i:end]))
+		}
+	}
+	branch := dnsBranchPrefix + strings.Join(children, ",")
+	hash := dnsEntryHash(branch)
+	records[hash] = branch
+	return hash
+}
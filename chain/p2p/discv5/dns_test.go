@@ -0,0 +1,147 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discv5
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/crypto"
+	"github.com/Bokerchain/Boker/chain/rlp"
+)
+
+// mapResolver serves TXT records from memory, ignoring case like DNS does.
+type mapResolver map[string]string
+
+func (mr mapResolver) add(domain string, records map[string]string) {
+	for name, txt := range records {
+		if name == "" {
+			mr[domain] = txt
+		} else {
+			mr[strings.ToLower(name)+"."+domain] = txt
+		}
+	}
+}
+
+func (mr mapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if txt, ok := mr[strings.ToLower(name)]; ok {
+		return []string{txt}, nil
+	}
+	return nil, fmt.Errorf("no such host %s", name)
+}
+
+func testDNSNodes(t *testing.T, n int) []*Node {
+	nodes := make([]*Node, n)
+	for i := range nodes {
+		key, _ := crypto.GenerateKey()
+		nodes[i] = NewNode(PubkeyID(&key.PublicKey), net.IP{10, 0, 0, byte(i + 1)}, 30303, 30303)
+	}
+	return nodes
+}
+
+// Tests that trees spanning multiple branch levels and links resolve fully.
+func TestDNSSyncTree(t *testing.T) {
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	nodes1, nodes2 := testDNSNodes(t, 40), testDNSNodes(t, 3)
+
+	resolver := make(mapResolver)
+	url2 := DNSTreeURL(&key2.PublicKey, "n2.example.org")
+	records, err := MakeDNSTree(nodes2, nil, 1, key2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver.add("n2.example.org", records)
+	if records, err = MakeDNSTree(nodes1, []string{url2}, 3, key1); err != nil {
+		t.Fatal(err)
+	}
+	resolver.add("n1.example.org", records)
+
+	nodes, err := NewDNSClient(resolver).SyncTree(context.Background(), DNSTreeURL(&key1.PublicKey, "n1.example.org"))
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	want := make(map[NodeID]bool)
+	for _, n := range append(nodes1, nodes2...) {
+		want[n.ID] = true
+	}
+	if len(nodes) != len(want) {
+		t.Fatalf("node count mismatch: have %d, want %d", len(nodes), len(want))
+	}
+	for _, n := range nodes {
+		if !want[n.ID] {
+			t.Errorf("unexpected node %v", n)
+		}
+	}
+}
+
+// Tests that trees signed by a different key or with altered entries are rejected.
+func TestDNSSyncTreeInvalid(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	records, err := MakeDNSTree(testDNSNodes(t, 2), nil, 1, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := make(mapResolver)
+	resolver.add("example.org", records)
+	client := NewDNSClient(resolver)
+
+	if _, err := client.SyncTree(context.Background(), DNSTreeURL(&other.PublicKey, "example.org")); err == nil {
+		t.Errorf("tree with foreign signature accepted")
+	}
+	for name, txt := range resolver {
+		if strings.HasPrefix(txt, dnsEnodePrefix) {
+			resolver[name] = testDNSNodes(t, 1)[0].String()
+			break
+		}
+	}
+	if _, err := client.SyncTree(context.Background(), DNSTreeURL(&key.PublicKey, "example.org")); err == nil {
+		t.Errorf("tree with altered entry accepted")
+	}
+	if err := ParseDNSURL("enode://foo@example.org"); err == nil {
+		t.Errorf("invalid url accepted")
+	}
+}
+
+// Tests that v4 node records are decoded and their signatures checked.
+func TestDNSParseENR(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	content := []interface{}{uint64(1), "id", "v4", "ip", net.IP{127, 0, 0, 1}, "secp256k1", compressPubkey(&key.PublicKey), "tcp", uint16(30303), "udp", uint16(30301)}
+
+	enc := func(sigKey *ecdsa.PrivateKey) string {
+		blob, _ := rlp.EncodeToBytes(content)
+		sig, _ := crypto.Sign(crypto.Keccak256(blob), sigKey)
+		record, _ := rlp.EncodeToBytes(append([]interface{}{sig[:64]}, content...))
+		return b64format.EncodeToString(record)
+	}
+	node, err := parseENR(enc(key))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if node.ID != PubkeyID(&key.PublicKey) || !node.IP.Equal(net.IP{127, 0, 0, 1}) || node.TCP != 30303 || node.UDP != 30301 {
+		t.Errorf("node mismatch: %v", node)
+	}
+	other, _ := crypto.GenerateKey()
+	if _, err := parseENR(enc(other)); err == nil {
+		t.Errorf("record with invalid signature accepted")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/p2p/discover"
+	"github.com/Bokerchain/Boker/chain/p2p/discv5"
+)
+
+const (
+	// dnsRefreshInterval is the time between two resolutions of the DNS
+	// published node lists.
+	dnsRefreshInterval = 30 * time.Minute
+
+	// dnsMaxBootnodes is the maximum number of DNS discovered nodes used as
+	// bootstrap nodes at a time. A different random subset is picked on each
+	// refresh, rotating through the published nodes.
+	dnsMaxBootnodes = 32
+)
+
+// startDNSDiscovery validates the configured tree URLs and launches the loop
+// feeding the discovered nodes into the discovery tables.
+func (srv *Server) startDNSDiscovery() error {
+	for _, url := range srv.DiscoveryDNS {
+		if err := discv5.ParseDNSURL(url); err != nil {
+			return fmt.Errorf("invalid discovery DNS URL %q: %v", url, err)
+		}
+	}
+	if srv.ntab == nil && srv.DiscV5 == nil {
+		log.Warn("DNS discovery configured with node discovery disabled")
+		return nil
+	}
+	srv.loopWG.Add(1)
+	go srv.dnsLoop(discv5.NewDNSClient(nil))
+	return nil
+}
+
+// dnsLoop periodically resolves the DNS node lists until the server stops.
+func (srv *Server) dnsLoop(client *discv5.DNSClient) {
+	defer srv.loopWG.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-srv.quit
+		cancel()
+	}()
+
+	refresh := time.NewTimer(0)
+	defer refresh.Stop()
+	for {
+		select {
+		case <-refresh.C:
+			srv.syncDNSNodes(ctx, client)
+			refresh.Reset(dnsRefreshInterval)
+		case <-srv.quit:
+			return
+		}
+	}
+}
+
+// syncDNSNodes resolves all configured trees and replaces the fallback nodes of
+// the discovery tables with the static bootnodes plus a random subset of the
+// resolved ones.
+func (srv *Server) syncDNSNodes(ctx context.Context, client *discv5.DNSClient) {
+	var nodes []*discv5.Node
+	for _, url := range srv.DiscoveryDNS {
+		found, err := client.SyncTree(ctx, url)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("Failed to resolve DNS node list", "url", url, "err", err)
+			}
+			continue
+		}
+		log.Debug("Resolved DNS node list", "url", url, "nodes", len(found))
+		nodes = append(nodes, found...)
+	}
+	if len(nodes) == 0 {
+		return
+	}
+	rand.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+	if len(nodes) > dnsMaxBootnodes {
+		nodes = nodes[:dnsMaxBootnodes]
+	}
+	if tab, ok := srv.ntab.(*discover.Table); ok {
+		fallback := append([]*discover.Node{}, srv.BootstrapNodes...)
+		for _, n := range nodes {
+			fallback = append(fallback, discover.NewNode(discover.NodeID(n.ID), n.IP, n.UDP, n.TCP))
+		}
+		if err := tab.SetFallbackNodes(fallback); err != nil {
+			log.Warn("Failed to set DNS bootstrap nodes", "err", err)
+		}
+	}
+	if srv.DiscV5 != nil {
+		fallback := append(append([]*discv5.Node{}, srv.BootstrapNodesV5...), nodes...)
+		if err := srv.DiscV5.SetFallbackNodes(fallback); err != nil {
+			log.Warn("Failed to set DNS bootstrap nodes", "err", err)
+		}
+	}
+	log.Info("Rotated DNS bootstrap nodes", "nodes", len(nodes))
+}
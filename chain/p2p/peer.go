@@ -109,6 +109,10 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// reputation tracks the score of the peer if set
+	reputation  *reputation
+	banDuration time.Duration
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -414,6 +418,9 @@ type PeerInfo struct {
 	Network struct {
 		LocalAddress  string `json:"localAddress"`  // Local endpoint of the TCP data connection
 		RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
+		Inbound       bool   `json:"inbound"`       // Whether the connection was initiated by the remote peer
+		Trusted       bool   `json:"trusted"`       // Whether the peer is in the trusted node set
+		Static        bool   `json:"static"`        // Whether the peer is a static node dialed by us
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
 }
@@ -434,6 +441,9 @@ func (p *Peer) Info() *PeerInfo {
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
+	info.Network.Inbound = p.rw.is(inboundConn)
+	info.Network.Trusted = p.rw.is(trustedConn)
+	info.Network.Static = p.rw.is(staticDialedConn)
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
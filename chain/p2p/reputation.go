@@ -0,0 +1,242 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/p2p/discover"
+)
+
+const (
+	// banScore is the score at or below which a peer is disconnected and its
+	// IP address banned.
+	banScore = -100
+
+	// scoreRecovery is the time after which a penalized peer regains one
+	// point of score.
+	scoreRecovery = 30 * time.Second
+
+	// DefaultBanDuration is the time a misbehaving peer is banned for if the
+	// server configuration does not specify one.
+	DefaultBanDuration = 30 * time.Minute
+)
+
+// Misbehaviour is a kind of protocol level misconduct a peer can be penalized
+// for by the sub-protocols.
+type Misbehaviour int
+
+const (
+	InvalidBlock        Misbehaviour = iota // Peer delivered a block failing validation
+	RequestTimeout                          // Peer failed to answer a request in time
+	UselessAnnouncement                     // Peer announced stale or otherwise useless data
+	InvalidSeal                             // Peer delivered a block not signed by the scheduled producer
+)
+
+// penalties maps each kind of misbehaviour to the score it costs.
+var penalties = map[Misbehaviour]int{
+	InvalidBlock:        50,
+	RequestTimeout:      10,
+	UselessAnnouncement: 2,
+	InvalidSeal:         25,
+}
+
+func (m Misbehaviour) String() string {
+	switch m {
+	case InvalidBlock:
+		return "invalid block"
+	case RequestTimeout:
+		return "request timeout"
+	case UselessAnnouncement:
+		return "useless announcement"
+	case InvalidSeal:
+		return "invalid seal"
+	default:
+		return "unknown"
+	}
+}
+
+// PeerScore is the reputation of a connected peer.
+type PeerScore struct {
+	ID            string `json:"id"`            // Unique node identifier
+	Name          string `json:"name"`          // Name of the node, including client type and version
+	RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
+	Score         int    `json:"score"`         // Current score, peers are banned at -100
+}
+
+// BannedIP is an IP address temporarily refused by the server.
+type BannedIP struct {
+	IP    string    `json:"ip"`
+	Until time.Time `json:"until"`
+}
+
+// score is the reputation of a single node along with the time it was last
+// updated, used to let penalties wear off.
+type score struct {
+	value   int
+	updated time.Time
+}
+
+// reputation tracks the scores of the nodes and the banned IP addresses.
+type reputation struct {
+	lock   sync.Mutex
+	scores map[discover.NodeID]*score
+	bans   map[string]time.Time
+}
+
+func newReputation() *reputation {
+	return &reputation{
+		scores: make(map[discover.NodeID]*score),
+		bans:   make(map[string]time.Time),
+	}
+}
+
+// current returns the score of a node after applying the recovery since it
+// was last updated. The lock must be held.
+func (r *reputation) current(id discover.NodeID, now time.Time) int {
+	s := r.scores[id]
+	if s == nil {
+		return 0
+	}
+	s.value += int(now.Sub(s.updated) / scoreRecovery)
+	s.updated = now
+	if s.value >= 0 {
+		delete(r.scores, id)
+		return 0
+	}
+	return s.value
+}
+
+// penalize lowers the score of a node, returning the new score.
+func (r *reputation) penalize(id discover.NodeID, m Misbehaviour) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	value := r.current(id, now) - penalties[m]
+	r.scores[id] = &score{value: value, updated: now}
+	return value
+}
+
+// score returns the current score of a node.
+func (r *reputation) score(id discover.NodeID) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.current(id, time.Now())
+}
+
+// ban refuses connections from an IP address for the given duration, a non
+// positive duration lifts the ban.
+func (r *reputation) ban(ip net.IP, duration time.Duration) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if duration <= 0 {
+		delete(r.bans, ip.String())
+		return
+	}
+	r.bans[ip.String()] = time.Now().Add(duration)
+}
+
+// banned tells if an IP address is currently banned.
+func (r *reputation) banned(ip net.IP) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	until, ok := r.bans[ip.String()]
+	if ok && time.Now().After(until) {
+		delete(r.bans, ip.String())
+		return false
+	}
+	return ok
+}
+
+// banList returns the currently banned IP addresses.
+func (r *reputation) banList() []BannedIP {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	bans := make([]BannedIP, 0, len(r.bans))
+	for ip, until := range r.bans {
+		if now.After(until) {
+			delete(r.bans, ip)
+			continue
+		}
+		bans = append(bans, BannedIP{IP: ip, Until: until})
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].IP < bans[j].IP })
+	return bans
+}
+
+// Penalize lowers the reputation of the peer for the given misbehaviour. Peers
+// reaching the ban score are disconnected and their IP address is banned.
+// Trusted peers are never banned.
+func (p *Peer) Penalize(m Misbehaviour) {
+	if p.reputation == nil {
+		return
+	}
+	value := p.reputation.penalize(p.ID(), m)
+	p.log.Debug("Penalized peer", "reason", m, "score", value)
+
+	if value <= banScore && !p.rw.is(trustedConn) {
+		if addr, ok := p.RemoteAddr().(*net.TCPAddr); ok {
+			p.reputation.ban(addr.IP, p.banDuration)
+		}
+		log.Info("Banning misbehaving peer", "id", p.ID(), "addr", p.RemoteAddr(), "reason", m, "duration", p.banDuration)
+		p.Disconnect(DiscUselessPeer)
+	}
+}
+
+// PeerScores returns the reputation of the connected peers.
+func (srv *Server) PeerScores() []*PeerScore {
+	var scores []*PeerScore
+	for _, p := range srv.Peers() {
+		scores = append(scores, &PeerScore{
+			ID:            p.ID().String(),
+			Name:          p.Name(),
+			RemoteAddress: p.RemoteAddr().String(),
+			Score:         srv.reputation.score(p.ID()),
+		})
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].Score < scores[j].Score })
+	return scores
+}
+
+// BannedIPs returns the IP addresses currently refused by the server.
+func (srv *Server) BannedIPs() []BannedIP {
+	return srv.reputation.banList()
+}
+
+// BanIP refuses connections from the given IP address for a duration and drops
+// the connected peers using it. A non positive duration lifts the ban.
+func (srv *Server) BanIP(ip net.IP, duration time.Duration) {
+	srv.reputation.ban(ip, duration)
+	if duration <= 0 {
+		return
+	}
+	for _, p := range srv.Peers() {
+		if addr, ok := p.RemoteAddr().(*net.TCPAddr); ok && addr.IP.Equal(ip) {
+			p.Disconnect(DiscUselessPeer)
+		}
+	}
+}
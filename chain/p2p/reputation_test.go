@@ -0,0 +1,71 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// Tests that penalties accumulate and wear off over time.
+func TestReputationScore(t *testing.T) {
+	r := newReputation()
+	id := randomID()
+
+	if score := r.penalize(id, RequestTimeout); score != -penalties[RequestTimeout] {
+		t.Fatalf("score mismatch: have %d, want %d", score, -penalties[RequestTimeout])
+	}
+	if score := r.penalize(id, InvalidBlock); score != -penalties[RequestTimeout]-penalties[InvalidBlock] {
+		t.Fatalf("score mismatch: have %d, want %d", score, -penalties[RequestTimeout]-penalties[InvalidBlock])
+	}
+	// Rewind the last update to simulate the passing of time
+	r.scores[id].updated = r.scores[id].updated.Add(-10 * scoreRecovery)
+	if score := r.score(id); score != -penalties[RequestTimeout]-penalties[InvalidBlock]+10 {
+		t.Fatalf("recovered score mismatch: have %d, want %d", score, -penalties[RequestTimeout]-penalties[InvalidBlock]+10)
+	}
+	r.scores[id].updated = r.scores[id].updated.Add(-time.Hour)
+	if score := r.score(id); score != 0 {
+		t.Fatalf("score not fully recovered: have %d", score)
+	}
+	if _, ok := r.scores[id]; ok {
+		t.Fatalf("recovered score not dropped")
+	}
+}
+
+// Tests that IP bans expire and can be lifted.
+func TestReputationBans(t *testing.T) {
+	r := newReputation()
+	ip := net.ParseIP("10.0.0.1")
+
+	r.ban(ip, time.Hour)
+	if !r.banned(ip) {
+		t.Fatalf("ip not banned")
+	}
+	if bans := r.banList(); len(bans) != 1 || bans[0].IP != ip.String() {
+		t.Fatalf("ban list mismatch: %v", bans)
+	}
+	r.ban(ip, 0)
+	if r.banned(ip) {
+		t.Fatalf("ban not lifted")
+	}
+	r.ban(ip, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if r.banned(ip) {
+		t.Fatalf("ban not expired")
+	}
+}
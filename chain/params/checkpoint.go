@@ -0,0 +1,103 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Bokerchain/Boker/chain/common"
+)
+
+// CHTFrequency is the block frequency for creating canonical hash tries. Each
+// trusted checkpoint commits to the section ending at a multiple of it.
+const CHTFrequency = 32768
+
+// TrustedCheckpoint represents a set of post-processed trie roots (CHT and
+// BloomTrie) associated with the appropriate section index and head hash. Light
+// clients start syncing from it, and both full and light nodes refuse to sync
+// from peers whose chain does not contain the section head.
+type TrustedCheckpoint struct {
+	Name         string      // Human readable name of the chain the checkpoint belongs to
+	SectionIndex uint64      // Index of the last section covered by the checkpoint
+	SectionHead  common.Hash // Hash of the last block in the section
+	CHTRoot      common.Hash // Root of the canonical hash trie up to the section
+	BloomRoot    common.Hash // Root of the bloom trie up to the section
+}
+
+// HeadNumber returns the number of the block the section head hash belongs to.
+func (c *TrustedCheckpoint) HeadNumber() uint64 {
+	return (c.SectionIndex+1)*CHTFrequency - 1
+}
+
+// String implements fmt.Stringer, producing the format accepted by
+// ParseCheckpoint.
+func (c *TrustedCheckpoint) String() string {
+	return fmt.Sprintf("%d:%x:%x:%x", c.SectionIndex, c.SectionHead, c.CHTRoot, c.BloomRoot)
+}
+
+// ParseCheckpoint parses a checkpoint given as
+// <section index>:<section head>:<cht root>[:<bloom trie root>].
+func ParseCheckpoint(s string) (*TrustedCheckpoint, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, fmt.Errorf("invalid checkpoint %q, want <section>:<head>:<cht root>[:<bloom root>]", s)
+	}
+	index, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint section %q: %v", parts[0], err)
+	}
+	cp := &TrustedCheckpoint{Name: "user supplied", SectionIndex: index}
+	for i, field := range []*common.Hash{&cp.SectionHead, &cp.CHTRoot, &cp.BloomRoot}[:len(parts)-1] {
+		hex := strings.TrimPrefix(parts[i+1], "0x")
+		if len(hex) != 2*common.HashLength {
+			return nil, fmt.Errorf("invalid checkpoint hash %q", parts[i+1])
+		}
+		*field = common.HexToHash(hex)
+	}
+	return cp, nil
+}
+
+var (
+	// MainnetTrustedCheckpoint contains the light client trusted checkpoint for
+	// the Ethereum main network.
+	MainnetTrustedCheckpoint = &TrustedCheckpoint{
+		Name:         "ETH mainnet",
+		SectionIndex: 129,
+		SectionHead:  common.HexToHash("64100587c8ec9a76870056d07cb0f58622552d16de6253a59cac4b580c899501"),
+		CHTRoot:      common.HexToHash("bb4fb4076cbe6923c8a8ce8f158452bbe19564959313466989fda095a60884ca"),
+		BloomRoot:    common.HexToHash("0db524b2c4a2a9520a42fd842b02d2e8fb58ff37c75cf57bd0eb82daeace6716"),
+	}
+
+	// TestnetTrustedCheckpoint contains the light client trusted checkpoint for
+	// the Ropsten test network.
+	TestnetTrustedCheckpoint = &TrustedCheckpoint{
+		Name:         "Ropsten testnet",
+		SectionIndex: 50,
+		SectionHead:  common.HexToHash("00bd65923a1aa67f85e6b4ae67835784dd54be165c37f056691723c55bf016bd"),
+		CHTRoot:      common.HexToHash("6f56dc61936752cc1f8c84b4addabdbe6a1c19693de3f21cb818362df2117f03"),
+		BloomRoot:    common.HexToHash("aca7d7c504d22737242effc3fdc604a762a0af9ced898036b5986c3a15220208"),
+	}
+)
+
+// TrustedCheckpoints associates each known checkpoint with the genesis hash of
+// the chain it belongs to. Boker networks get an entry here once a CHT section
+// of their chain has been generated and published.
+var TrustedCheckpoints = map[common.Hash]*TrustedCheckpoint{
+	MainnetGenesisHash: MainnetTrustedCheckpoint,
+}
@@ -0,0 +1,47 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"testing"
+)
+
+func TestParseCheckpoint(t *testing.T) {
+	// A checkpoint printed by String must parse back into the same values.
+	cp, err := ParseCheckpoint(MainnetTrustedCheckpoint.String())
+	if err != nil {
+		t.Fatalf("failed to parse mainnet checkpoint: %v", err)
+	}
+	if cp.SectionIndex != MainnetTrustedCheckpoint.SectionIndex || cp.SectionHead != MainnetTrustedCheckpoint.SectionHead ||
+		cp.CHTRoot != MainnetTrustedCheckpoint.CHTRoot || cp.BloomRoot != MainnetTrustedCheckpoint.BloomRoot {
+		t.Errorf("checkpoint mismatch: have %v, want %v", cp, MainnetTrustedCheckpoint)
+	}
+	if head := cp.HeadNumber(); head != 130*CHTFrequency-1 {
+		t.Errorf("head number mismatch: have %d, want %d", head, 130*CHTFrequency-1)
+	}
+	// The bloom trie root is optional and hashes may carry a 0x prefix.
+	hash := "0x" + MainnetTrustedCheckpoint.SectionHead.Hex()[2:]
+	if cp, err := ParseCheckpoint("1:" + hash + ":" + hash); err != nil || cp.CHTRoot != MainnetTrustedCheckpoint.SectionHead {
+		t.Errorf("failed to parse checkpoint without bloom root: %v", err)
+	}
+	// Malformed checkpoints must be rejected.
+	for _, s := range []string{"", "1", "1:" + hash, "x:" + hash + ":" + hash, "1:" + hash + ":1234", "1:" + hash + ":" + hash + ":" + hash + ":" + hash} {
+		if _, err := ParseCheckpoint(s); err == nil {
+			t.Errorf("checkpoint %q: expected error", s)
+		}
+	}
+}
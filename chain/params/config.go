@@ -12,17 +12,28 @@ var (
 )
 
 var (
+	//默认的免Gas交易限流配置
+	DefaultGasFreeConfig = &GasFreeConfig{
+		AccountPending:  16,
+		AccountPerBlock: 4,
+		PerBlock:        1024,
+	}
+
 	DposChainConfig = &ChainConfig{
-		ChainId:        big.NewInt(5),
-		HomesteadBlock: big.NewInt(0),
-		DAOForkBlock:   nil,
-		DAOForkSupport: false,
-		EIP150Block:    big.NewInt(0),
-		EIP150Hash:     common.Hash{},
-		EIP155Block:    big.NewInt(0),
-		EIP158Block:    big.NewInt(0),
-		ByzantiumBlock: big.NewInt(0),
-		Coinbase:       common.Address{},
+		ChainId:             big.NewInt(5),
+		HomesteadBlock:      big.NewInt(0),
+		DAOForkBlock:        nil,
+		DAOForkSupport:      false,
+		EIP150Block:         big.NewInt(0),
+		EIP150Hash:          common.Hash{},
+		EIP155Block:         big.NewInt(0),
+		EIP158Block:         big.NewInt(0),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: nil,
+		Coinbase:            common.Address{},
+		LegacySigning:       false,
+		EIP155SignerBlock:   nil,
+		GasFree:             DefaultGasFreeConfig,
 		//Dpos:           &DposConfig{},
 		//Contracts:      &BaseContractConfig{},
 	}
@@ -36,7 +47,17 @@ var (
 		big.NewInt(0),
 		big.NewInt(0),
 		big.NewInt(0),
-		common.Address{}}
+		nil,
+		common.Address{},
+		false,
+		big.NewInt(0),
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil}
 
 	AllEthashProtocolChanges = &ChainConfig{
 		big.NewInt(1337),
@@ -48,7 +69,17 @@ var (
 		big.NewInt(0),
 		big.NewInt(0),
 		big.NewInt(0),
-		common.Address{}}
+		nil,
+		common.Address{},
+		false,
+		big.NewInt(0),
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil}
 
 	AllCliqueProtocolChanges = &ChainConfig{
 		big.NewInt(1337),
@@ -60,22 +91,102 @@ var (
 		big.NewInt(0),
 		big.NewInt(0),
 		big.NewInt(0),
-		common.Address{}}
+		nil,
+		common.Address{},
+		false,
+		big.NewInt(0),
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil}
 )
 
 //ChainConfig是确定区块链设置的核心配置,ChainConfig基于每个块存储在数据库中。
 //意即由其创世块标识的任何网络都可以拥有自己的网络一组配置选项。
 type ChainConfig struct {
-	ChainId        *big.Int       `json:"chainId"`                  //Chain id identifies the current chain and is used for replay protection
-	HomesteadBlock *big.Int       `json:"homesteadBlock,omitempty"` //Homestead switch block (nil = no fork, 0 = already homestead)
-	DAOForkBlock   *big.Int       `json:"daoForkBlock,omitempty"`   //TheDAO hard-fork switch block (nil = no fork)
-	DAOForkSupport bool           `json:"daoForkSupport,omitempty"` //Whether the nodes supports or opposes the DAO hard-fork
-	EIP150Block    *big.Int       `json:"eip150Block,omitempty"`    //EIP150 HF block (nil = no fork)
-	EIP150Hash     common.Hash    `json:"eip150Hash,omitempty"`     //EIP150 HF hash (needed for header only clients as only gas pricing changed)
-	EIP155Block    *big.Int       `json:"eip155Block,omitempty"`    //EIP155 HF block
-	EIP158Block    *big.Int       `json:"eip158Block,omitempty"`    //EIP158 HF block
-	ByzantiumBlock *big.Int       `json:"byzantiumBlock,omitempty"` //Byzantium switch block (nil = no fork, 0 = already on byzantium)
-	Coinbase       common.Address `json:"coinbase,omitempty"`       //播客链新增当前挖矿的账号
+	ChainId             *big.Int       `json:"chainId"`                       //Chain id identifies the current chain and is used for replay protection
+	HomesteadBlock      *big.Int       `json:"homesteadBlock,omitempty"`      //Homestead switch block (nil = no fork, 0 = already homestead)
+	DAOForkBlock        *big.Int       `json:"daoForkBlock,omitempty"`        //TheDAO hard-fork switch block (nil = no fork)
+	DAOForkSupport      bool           `json:"daoForkSupport,omitempty"`      //Whether the nodes supports or opposes the DAO hard-fork
+	EIP150Block         *big.Int       `json:"eip150Block,omitempty"`         //EIP150 HF block (nil = no fork)
+	EIP150Hash          common.Hash    `json:"eip150Hash,omitempty"`          //EIP150 HF hash (needed for header only clients as only gas pricing changed)
+	EIP155Block         *big.Int       `json:"eip155Block,omitempty"`         //EIP155 HF block
+	EIP158Block         *big.Int       `json:"eip158Block,omitempty"`         //EIP158 HF block
+	ByzantiumBlock      *big.Int       `json:"byzantiumBlock,omitempty"`      //Byzantium switch block (nil = no fork, 0 = already on byzantium)
+	ConstantinopleBlock *big.Int       `json:"constantinopleBlock,omitempty"` //Constantinople switch block (nil = no fork, 0 = already activated)
+	Coinbase            common.Address `json:"coinbase,omitempty"`            //播客链新增当前挖矿的账号
+	LegacySigning       bool           `json:"legacySigning,omitempty"`       //使用旧的Homestead签名（不带链ID的重放保护）
+	EIP155SignerBlock   *big.Int       `json:"eip155SignerBlock,omitempty"`   //开始使用EIP155带链ID的交易签名的区块(nil表示不切换，之前的区块只接受Homestead签名)
+	GasFree             *GasFreeConfig `json:"gasFree,omitempty"`             //免Gas基础合约交易的限流配置(nil表示不限制)
+	Precompiles         []Precompile   `json:"precompiles,omitempty"`         //在链配置中额外注册的预编译合约
+	Dpos                *DposConfig    `json:"dpos,omitempty"`                //创世时的DPOS验证者配置
+	SizeLimits          *SizeConfig    `json:"sizeLimits,omitempty"`          //区块和交易的大小限制(nil表示使用默认限制)
+	HeaderRevisionBlock *big.Int       `json:"headerRevisionBlock,omitempty"` //开始使用精简区块头格式的区块(nil表示不切换)
+	BaseEventLogBlock   *big.Int       `json:"baseEventLogBlock,omitempty"`   //开始为基础合约交易生成事件日志的区块(nil表示不生成)
+	CheckpointBlock     *big.Int       `json:"checkpointBlock,omitempty"`     //开始在区块头中打包验证者联合签名检查点的区块(nil表示不打包)
+}
+
+//链配置中注册的预编译合约，Name为预编译合约的Go实现在虚拟机中注册的名称，BaseGas和WordGas都为0时使用实现自带的Gas计算
+type Precompile struct {
+	Address common.Address `json:"address"`
+	Name    string         `json:"name"`
+	Block   *big.Int       `json:"block,omitempty"`   //启用的区块(nil表示从创世区块开始启用)
+	BaseGas uint64         `json:"baseGas,omitempty"` //每次调用的基础Gas
+	WordGas uint64         `json:"wordGas,omitempty"` //输入数据每32字节的Gas
+}
+
+//判断预编译合约在给定区块是否已经启用
+func (p *Precompile) IsActive(num *big.Int) bool {
+	return p.Block == nil || isForked(p.Block, num)
+}
+
+//免Gas的用户基础合约交易（注册候选人、投票、用户事件等）的限流配置，0表示不限制
+type GasFreeConfig struct {
+	AccountPending  uint64 `json:"accountPending"`  //单个账户在交易池中允许存在的最大免Gas交易数量
+	AccountPerBlock uint64 `json:"accountPerBlock"` //单个账户在一个区块中允许打包的最大免Gas交易数量
+	PerBlock        uint64 `json:"perBlock"`        //一个区块中允许打包的最大免Gas交易数量
+}
+
+//区块和交易RLP编码后的大小限制，0表示使用默认限制
+type SizeConfig struct {
+	Number *big.Int `json:"number,omitempty"` //开始使用配置的区块大小限制的区块(nil表示从创世块开始)
+	Block  uint64   `json:"block"`            //一个区块中所有交易的最大字节数
+	Tx     uint64   `json:"tx"`               //交易池接收的单个交易的最大字节数
+}
+
+//配置的区块大小限制开始生效的区块，没有配置区块大小限制时返回nil
+func (s *SizeConfig) activation() *big.Int {
+	switch {
+	case s == nil || s.Block == 0:
+		return nil
+	case s.Number == nil:
+		return big.NewInt(0)
+	default:
+		return s.Number
+	}
+}
+
+//指定区块中所有交易的最大字节数，出块和验证区块时使用
+func (c *ChainConfig) MaxBlockSize(num *big.Int) uint64 {
+	if isForked(c.SizeLimits.activation(), num) {
+		return c.SizeLimits.Block
+	}
+	return BlockSize.Uint64()
+}
+
+//交易池接收的单个交易的最大字节数，不会超过指定区块的大小限制
+func (c *ChainConfig) MaxTxSize(num *big.Int) uint64 {
+	size := TxSize
+	if c.SizeLimits != nil && c.SizeLimits.Tx > 0 {
+		size = c.SizeLimits.Tx
+	}
+	if block := c.MaxBlockSize(num); size > block {
+		size = block
+	}
+	return size
 }
 
 // CliqueConfig is the consensus engine configs for proof-of-authority based sealing.
@@ -100,7 +211,7 @@ func (d *DposConfig) String() string {
 
 //实现fmt.Stringer接口
 func (c *ChainConfig) String() string {
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v}",
 		c.ChainId,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -109,6 +220,7 @@ func (c *ChainConfig) String() string {
 		c.EIP155Block,
 		c.EIP158Block,
 		c.ByzantiumBlock,
+		c.ConstantinopleBlock,
 		//c.Dpos,
 	)
 }
@@ -131,6 +243,11 @@ func (c *ChainConfig) IsEIP155(num *big.Int) bool {
 	return isForked(c.EIP155Block, num)
 }
 
+//判断是否使用EIP155带链ID的交易签名（重放保护）
+func (c *ChainConfig) IsReplayProtected(num *big.Int) bool {
+	return c.IsEIP155(num) && isForked(c.EIP155SignerBlock, num) && !c.LegacySigning
+}
+
 func (c *ChainConfig) IsEIP158(num *big.Int) bool {
 	return isForked(c.EIP158Block, num)
 }
@@ -139,6 +256,25 @@ func (c *ChainConfig) IsByzantium(num *big.Int) bool {
 	return isForked(c.ByzantiumBlock, num)
 }
 
+func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
+	return isForked(c.ConstantinopleBlock, num)
+}
+
+//判断区块是否使用精简格式的区块头(去掉叔块和工作量证明字段，增加时间槽字段)
+func (c *ChainConfig) IsHeaderRevision(num *big.Int) bool {
+	return isForked(c.HeaderRevisionBlock, num)
+}
+
+//判断区块头是否可以打包周期边界区块的检查点
+func (c *ChainConfig) IsCheckpoint(num *big.Int) bool {
+	return isForked(c.CheckpointBlock, num)
+}
+
+//判断区块中执行成功的基础合约交易是否生成事件日志(投票、注册候选人和分配通证)
+func (c *ChainConfig) IsBaseEventLog(num *big.Int) bool {
+	return isForked(c.BaseEventLogBlock, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -199,6 +335,68 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ByzantiumBlock, newcfg.ByzantiumBlock, head) {
 		return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
 	}
+	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
+		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
+	}
+	if isForkIncompatible(c.EIP155SignerBlock, newcfg.EIP155SignerBlock, head) {
+		return newCompatError("EIP155 signer fork block", c.EIP155SignerBlock, newcfg.EIP155SignerBlock)
+	}
+	if isForked(c.EIP155SignerBlock, head) && c.LegacySigning != newcfg.LegacySigning {
+		return newCompatError("legacy signing flag", c.EIP155SignerBlock, newcfg.EIP155SignerBlock)
+	}
+	if isForkIncompatible(c.HeaderRevisionBlock, newcfg.HeaderRevisionBlock, head) {
+		return newCompatError("Header revision block", c.HeaderRevisionBlock, newcfg.HeaderRevisionBlock)
+	}
+	if isForkIncompatible(c.BaseEventLogBlock, newcfg.BaseEventLogBlock, head) {
+		return newCompatError("Base event log block", c.BaseEventLogBlock, newcfg.BaseEventLogBlock)
+	}
+	if isForkIncompatible(c.CheckpointBlock, newcfg.CheckpointBlock, head) {
+		return newCompatError("Checkpoint block", c.CheckpointBlock, newcfg.CheckpointBlock)
+	}
+	if err := checkPrecompilesCompatible(c.Precompiles, newcfg.Precompiles, head); err != nil {
+		return err
+	}
+	//区块大小限制生效之后不能修改，交易大小限制只在交易池中使用，可以随时修改
+	s, n := c.SizeLimits.activation(), newcfg.SizeLimits.activation()
+	if isForkIncompatible(s, n, head) || (isForked(s, head) && c.SizeLimits.Block != newcfg.SizeLimits.Block) {
+		return newCompatError("block size limit", s, n)
+	}
+	return nil
+}
+
+//已经启用的预编译合约不能被修改或删除
+func checkPrecompilesCompatible(stored, newcfg []Precompile, head *big.Int) *ConfigCompatError {
+
+	find := func(list []Precompile, addr common.Address) *Precompile {
+		for i := range list {
+			if list[i].Address == addr {
+				return &list[i]
+			}
+		}
+		return nil
+	}
+	//预编译合约的启用区块，nil表示没有配置该预编译合约
+	activation := func(p *Precompile) *big.Int {
+		switch {
+		case p == nil:
+			return nil
+		case p.Block == nil:
+			return big.NewInt(0)
+		default:
+			return p.Block
+		}
+	}
+	for _, list := range [][]Precompile{stored, newcfg} {
+		for _, p := range list {
+			s, n := find(stored, p.Address), find(newcfg, p.Address)
+			if s != nil && n != nil && s.Name == n.Name && s.BaseGas == n.BaseGas && s.WordGas == n.WordGas && configNumEqual(activation(s), activation(n)) {
+				continue
+			}
+			if isForked(activation(s), head) || isForked(activation(n), head) {
+				return newCompatError("precompile "+p.Address.Hex(), activation(s), activation(n))
+			}
+		}
+	}
 	return nil
 }
 
@@ -265,7 +463,7 @@ func (err *ConfigCompatError) Error() string {
 type Rules struct {
 	ChainId                                   *big.Int
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158 bool
-	IsByzantium                               bool
+	IsByzantium, IsConstantinople             bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -273,5 +471,5 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainId == nil {
 		chainId = new(big.Int)
 	}
-	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num)}
+	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num), IsConstantinople: c.IsConstantinople(num)}
 }
@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/Bokerchain/Boker/chain/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -70,6 +72,102 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     9,
 			},
 		},
+		{
+			stored:  &ChainConfig{},
+			new:     &ChainConfig{Precompiles: []Precompile{{Address: common.Address{0x10}, Name: "blake2f", Block: big.NewInt(20)}}},
+			head:    10,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Precompiles: []Precompile{{Address: common.Address{0x10}, Name: "blake2f", Block: big.NewInt(5)}}},
+			new:    &ChainConfig{Precompiles: []Precompile{{Address: common.Address{0x10}, Name: "blake2f", Block: big.NewInt(5), BaseGas: 100}}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "precompile 0x1000000000000000000000000000000000000000",
+				StoredConfig: big.NewInt(5),
+				NewConfig:    big.NewInt(5),
+				RewindTo:     4,
+			},
+		},
+		{
+			stored:  &ChainConfig{EIP155SignerBlock: big.NewInt(20)},
+			new:     &ChainConfig{EIP155SignerBlock: big.NewInt(30), LegacySigning: true},
+			head:    10,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{EIP155SignerBlock: big.NewInt(5)},
+			new:    &ChainConfig{EIP155SignerBlock: big.NewInt(20)},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "EIP155 signer fork block",
+				StoredConfig: big.NewInt(5),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     4,
+			},
+		},
+		{
+			stored: &ChainConfig{EIP155SignerBlock: big.NewInt(5)},
+			new:    &ChainConfig{EIP155SignerBlock: big.NewInt(5), LegacySigning: true},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "legacy signing flag",
+				StoredConfig: big.NewInt(5),
+				NewConfig:    big.NewInt(5),
+				RewindTo:     4,
+			},
+		},
+		{
+			stored:  &ChainConfig{CheckpointBlock: big.NewInt(20)},
+			new:     &ChainConfig{CheckpointBlock: big.NewInt(30)},
+			head:    10,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{CheckpointBlock: big.NewInt(5)},
+			new:    &ChainConfig{},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "Checkpoint block",
+				StoredConfig: big.NewInt(5),
+				NewConfig:    nil,
+				RewindTo:     4,
+			},
+		},
+		{
+			stored:  &ChainConfig{SizeLimits: &SizeConfig{Number: big.NewInt(20), Block: 1 << 10}},
+			new:     &ChainConfig{SizeLimits: &SizeConfig{Number: big.NewInt(30), Block: 1 << 12, Tx: 1 << 8}},
+			head:    10,
+			wantErr: nil,
+		},
+		{
+			stored:  &ChainConfig{SizeLimits: &SizeConfig{Block: 1 << 10}},
+			new:     &ChainConfig{SizeLimits: &SizeConfig{Block: 1 << 10, Tx: 1 << 8}},
+			head:    10,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{SizeLimits: &SizeConfig{Number: big.NewInt(5), Block: 1 << 10}},
+			new:    &ChainConfig{SizeLimits: &SizeConfig{Number: big.NewInt(5), Block: 1 << 12}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "block size limit",
+				StoredConfig: big.NewInt(5),
+				NewConfig:    big.NewInt(5),
+				RewindTo:     4,
+			},
+		},
+		{
+			stored: &ChainConfig{},
+			new:    &ChainConfig{SizeLimits: &SizeConfig{Number: big.NewInt(5), Block: 1 << 10}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "block size limit",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(5),
+				RewindTo:     4,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -79,3 +177,48 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestSizeLimits(t *testing.T) {
+	tests := []struct {
+		limits    *SizeConfig
+		number    int64
+		block, tx uint64
+	}{
+		{nil, 0, BlockSize.Uint64(), TxSize},
+		{&SizeConfig{}, 0, BlockSize.Uint64(), TxSize},
+		{&SizeConfig{Block: 1 << 20, Tx: 1 << 10}, 0, 1 << 20, 1 << 10},
+		{&SizeConfig{Block: 1 << 10}, 0, 1 << 10, 1 << 10},
+		{&SizeConfig{Block: 1 << 10, Tx: 1 << 20}, 0, 1 << 10, 1 << 10},
+		{&SizeConfig{Number: big.NewInt(10), Block: 1 << 10}, 9, BlockSize.Uint64(), TxSize},
+		{&SizeConfig{Number: big.NewInt(10), Block: 1 << 10}, 10, 1 << 10, 1 << 10},
+		{&SizeConfig{Number: big.NewInt(10), Tx: 1 << 10}, 9, BlockSize.Uint64(), 1 << 10},
+	}
+	for i, tt := range tests {
+		config := &ChainConfig{SizeLimits: tt.limits}
+		if size := config.MaxBlockSize(big.NewInt(tt.number)); size != tt.block {
+			t.Errorf("test %d: block size limit mismatch: have %d, want %d", i, size, tt.block)
+		}
+		if size := config.MaxTxSize(big.NewInt(tt.number)); size != tt.tx {
+			t.Errorf("test %d: transaction size limit mismatch: have %d, want %d", i, size, tt.tx)
+		}
+	}
+}
+
+func TestIsReplayProtected(t *testing.T) {
+	tests := []struct {
+		config *ChainConfig
+		number int64
+		want   bool
+	}{
+		{&ChainConfig{EIP155Block: big.NewInt(0)}, 100, false},
+		{&ChainConfig{EIP155Block: big.NewInt(0), EIP155SignerBlock: big.NewInt(10)}, 9, false},
+		{&ChainConfig{EIP155Block: big.NewInt(0), EIP155SignerBlock: big.NewInt(10)}, 10, true},
+		{&ChainConfig{EIP155Block: big.NewInt(20), EIP155SignerBlock: big.NewInt(10)}, 10, false},
+		{&ChainConfig{EIP155Block: big.NewInt(0), EIP155SignerBlock: big.NewInt(10), LegacySigning: true}, 10, false},
+	}
+	for i, tt := range tests {
+		if have := tt.config.IsReplayProtected(big.NewInt(tt.number)); have != tt.want {
+			t.Errorf("test %d: replay protection mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
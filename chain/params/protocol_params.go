@@ -37,6 +37,7 @@ const (
 	MemoryGas               uint64 = 3      // Times the address of the (highest referenced byte in memory + 1). NOTE: referencing happens on read, write and in instructions such as RETURN and CALL.
 	TxDataNonZeroGas        uint64 = 68     // Per byte of data attached to a transaction that is not equal to zero. NOTE: Not payable on data of calls between transactions.
 	MaxCodeSize                    = 24576  // 允许合约的最大字节码(24K)
+	TxSize                  uint64 = 32768  //交易池接收的单个交易的默认大小限制 32KB
 	EcrecoverGas            uint64 = 3000   // Elliptic curve sender recovery gas price
 	Sha256BaseGas           uint64 = 60     // Base price for a SHA256 operation
 	Sha256PerWordGas        uint64 = 12     // Per-word price for a SHA256 operation
@@ -49,6 +50,10 @@ const (
 	Bn256ScalarMulGas       uint64 = 40000  // Gas needed for an elliptic curve scalar multiplication
 	Bn256PairingBaseGas     uint64 = 100000 // Base price for an elliptic curve pairing check
 	Bn256PairingPerPointGas uint64 = 80000  // Per-point price for an elliptic curve pairing check
+	ExtcodeHashGas          uint64 = 400    // Cost of EXTCODEHASH (introduced in Constantinople)
+	Blake2bFRoundGas        uint64 = 1      // Per-round price for a blake2b compression
+	Ed25519VerifyBaseGas    uint64 = 2000   // Base price for an ed25519 signature verification
+	Ed25519VerifyPerWordGas uint64 = 12     // Per-word price for an ed25519 signature verification
 )
 
 var (
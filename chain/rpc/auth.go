@@ -0,0 +1,154 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// HTTPAuth configures the credentials required to call the protected API
+// modules over HTTP. Requests only calling unprotected modules are served
+// without credentials.
+type HTTPAuth struct {
+	APIKey    string   // Shared secret accepted as bearer token or X-API-Key header
+	JWTSecret []byte   // HMAC secret bearer JSON web tokens must be signed with
+	Modules   []string // API modules (namespaces) requiring credentials
+}
+
+// enabled tells if the authentication layer has anything to enforce.
+func (auth *HTTPAuth) enabled() bool {
+	return auth != nil && len(auth.Modules) > 0 && (auth.APIKey != "" || len(auth.JWTSecret) > 0)
+}
+
+// authHandler rejects requests calling protected API modules without valid
+// credentials.
+type authHandler struct {
+	auth    *HTTPAuth
+	modules map[string]struct{}
+	next    http.Handler
+}
+
+// newAuthHandler wraps an HTTP handler with the authentication checks of the
+// given configuration.
+func newAuthHandler(auth *HTTPAuth, next http.Handler) http.Handler {
+	if !auth.enabled() {
+		return next
+	}
+	modules := make(map[string]struct{})
+	for _, module := range auth.Modules {
+		modules[module] = struct{}{}
+	}
+	return &authHandler{auth: auth, modules: modules, next: next}
+}
+
+// ServeHTTP implements http.Handler, checking the credentials of requests
+// calling protected modules.
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	// Buffer the body to find the called methods, the server validates the size
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxHTTPRequestContentLength+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxHTTPRequestContentLength {
+		err := fmt.Errorf("content length too large (%d>%d)", len(body), maxHTTPRequestContentLength)
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if h.protected(body) {
+		if err := h.authorize(r); err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rpc"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// protected tells if any of the calls in the request body targets a protected
+// module. The body is decoded exactly like the server's codec does, so the
+// checked calls are the ones that get executed. Bodies which can't be parsed
+// are treated as protected.
+func (h *authHandler) protected(body []byte) bool {
+	var msg json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&msg); err != nil {
+		return true
+	}
+	var (
+		reqs []rpcRequest
+		err  Error
+	)
+	if isBatch(msg) {
+		reqs, _, err = parseBatchRequest(msg)
+	} else {
+		reqs, _, err = parseRequest(msg)
+	}
+	if err != nil {
+		return true
+	}
+	for _, req := range reqs {
+		module := req.service
+		if module == "" {
+			module = strings.SplitN(req.method, serviceMethodSeparator, 2)[0]
+		}
+		if _, ok := h.modules[module]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize checks the credentials supplied with the request.
+func (h *authHandler) authorize(r *http.Request) error {
+	token := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if token == "" {
+		return fmt.Errorf("missing credentials")
+	}
+	if h.auth.APIKey != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.auth.APIKey)) == 1 {
+		return nil
+	}
+	if len(h.auth.JWTSecret) > 0 {
+		parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+			}
+			return h.auth.JWTSecret, nil
+		})
+		if err == nil && parsed.Valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid credentials")
+}
@@ -16,7 +16,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // request is for an unknown service
 type methodNotFoundError struct {
@@ -64,3 +67,42 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// issued when a batch holds more requests than the server accepts.
+type batchLimitError struct{ limit int }
+
+func (e *batchLimitError) ErrorCode() int { return -32600 }
+
+func (e *batchLimitError) Error() string {
+	return fmt.Sprintf("batch too large, at most %d requests allowed", e.limit)
+}
+
+// issued when a method did not complete within its execution timeout.
+type timeoutError struct {
+	method  string
+	timeout time.Duration
+}
+
+func (e *timeoutError) ErrorCode() int { return -32002 }
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %v", e.method, e.timeout)
+}
+
+// issued when the client went away before a method completed.
+type canceledError struct{ method string }
+
+func (e *canceledError) ErrorCode() int { return -32004 }
+
+func (e *canceledError) Error() string {
+	return fmt.Sprintf("%s canceled by client", e.method)
+}
+
+// issued when the result of a method exceeds the response size limit.
+type responseTooLargeError struct{ limit int }
+
+func (e *responseTooLargeError) ErrorCode() int { return -32003 }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("response too large, at most %d bytes allowed", e.limit)
+}
@@ -17,10 +17,14 @@
 package rpc
 
 import (
+	"compress/gzip"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/dgrijalva/jwt-go"
 )
 
 func TestHTTPErrorResponseWithDelete(t *testing.T) {
@@ -52,3 +56,106 @@ func testHTTPErrorResponse(t *testing.T, method, contentType, body string, expec
 		t.Fatalf("response code should be %d not %d", expected, code)
 	}
 }
+
+// serveHTTPRequest runs a JSON-RPC request through a full HTTP server handler.
+func serveHTTPRequest(t *testing.T, server *http.Server, host, body string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("POST", "http://"+host, strings.NewReader(body))
+	request.Header.Set("content-type", contentType)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	recorder := httptest.NewRecorder()
+	server.Handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestHTTPVirtualHosts(t *testing.T) {
+	server := NewHTTPServer(nil, []string{"localhost"}, nil, NewServer())
+	body := `{"jsonrpc":"2.0","id":1,"method":"rpc_modules"}`
+
+	if code := serveHTTPRequest(t, server, "localhost:8545", body, nil).Code; code != http.StatusOK {
+		t.Errorf("allowed host: response code mismatch: have %d, want %d", code, http.StatusOK)
+	}
+	if code := serveHTTPRequest(t, server, "127.0.0.1:8545", body, nil).Code; code != http.StatusOK {
+		t.Errorf("ip host: response code mismatch: have %d, want %d", code, http.StatusOK)
+	}
+	if code := serveHTTPRequest(t, server, "evil.com", body, nil).Code; code != http.StatusForbidden {
+		t.Errorf("unknown host: response code mismatch: have %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestHTTPGzipResponse(t *testing.T) {
+	server := NewHTTPServer(nil, []string{"*"}, nil, NewServer())
+	body := `{"jsonrpc":"2.0","id":1,"method":"rpc_modules"}`
+
+	resp := serveHTTPRequest(t, server, "localhost", body, map[string]string{"Accept-Encoding": "gzip"})
+	if enc := resp.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("content encoding mismatch: have %q, want gzip", enc)
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(blob), `"rpc":"1.0"`) {
+		t.Fatalf("unexpected response: %s", blob)
+	}
+}
+
+func TestHTTPAuth(t *testing.T) {
+	secret := []byte("secret")
+	auth := &HTTPAuth{APIKey: "key", JWTSecret: secret, Modules: []string{"rpc"}}
+	server := NewHTTPServer(nil, []string{"*"}, auth, NewServer())
+
+	token, err := jwt.New(jwt.SigningMethodHS256).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := jwt.New(jwt.SigningMethodHS256).SignedString([]byte("forged"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	protected := `{"jsonrpc":"2.0","id":1,"method":"rpc_modules"}`
+	batch := `[{"jsonrpc":"2.0","id":1,"method":"web3_clientVersion"},{"jsonrpc":"2.0","id":2,"method":"rpc_modules"}]`
+	public := `{"jsonrpc":"2.0","id":1,"method":"web3_clientVersion"}`
+	publicBatch := `[{"jsonrpc":"2.0","id":1,"method":"web3_clientVersion"},{"jsonrpc":"2.0","id":2,"method":"web3_sha3","params":["0x00"]}]`
+
+	tests := []struct {
+		body    string
+		headers map[string]string
+		code    int
+	}{
+		{protected, nil, http.StatusUnauthorized},
+		{batch, nil, http.StatusUnauthorized},
+		{protected, map[string]string{"X-API-Key": "wrong"}, http.StatusUnauthorized},
+		{protected, map[string]string{"Authorization": "Bearer " + forged}, http.StatusUnauthorized},
+		{protected, map[string]string{"X-API-Key": "key"}, http.StatusOK},
+		{protected, map[string]string{"Authorization": "Bearer key"}, http.StatusOK},
+		{batch, map[string]string{"Authorization": "Bearer " + token}, http.StatusOK},
+		{public, nil, http.StatusOK},
+		{publicBatch, nil, http.StatusOK},
+
+		// The server only executes the first JSON value, trailing data must not hide it
+		{protected + ` x`, nil, http.StatusUnauthorized},
+		{protected + ` {"jsonrpc":"2.0","id":2,"method":"web3_clientVersion"}`, nil, http.StatusUnauthorized},
+		{protected + ` x`, map[string]string{"X-API-Key": "key"}, http.StatusOK},
+		{public + ` x`, nil, http.StatusOK},
+
+		// Batches mixing protected calls with malformed or public ones
+		{`[{"jsonrpc":"2.0","id":1,"method":"rpc_modules"},1]`, nil, http.StatusUnauthorized},
+		{`[{"jsonrpc":"2.0","id":1,"method":"web3_clientVersion"},{"jsonrpc":"2.0","id":2,"method":"rpc_modules"}] x`, nil, http.StatusUnauthorized},
+		{`[{"jsonrpc":"2.0","id":1,"method":"rpc_modules"},{"jsonrpc":"2.0","id":2,"method":"web3_clientVersion"}]`, map[string]string{"X-API-Key": "key"}, http.StatusOK},
+
+		// Unparsable bodies require credentials
+		{`{"jsonrpc":"2.0","id":1,"method":`, nil, http.StatusUnauthorized},
+		{`{"jsonrpc":"2.0","id":1,"METHOD":"rpc_modules"}`, nil, http.StatusUnauthorized},
+	}
+	for i, tt := range tests {
+		if code := serveHTTPRequest(t, server, "localhost", tt.body, tt.headers).Code; code != tt.code {
+			t.Errorf("test %d: response code mismatch: have %d, want %d", i, code, tt.code)
+		}
+	}
+}
@@ -18,12 +18,14 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/Bokerchain/Boker/chain/log"
 	"gopkg.in/fatih/set.v0"
@@ -58,6 +60,37 @@ func NewServer() *Server {
 	return server
 }
 
+// SetBatchLimit sets the maximum number of requests the server accepts in a
+// single batch. Larger batches are rejected as a whole. Zero disables the limit.
+func (s *Server) SetBatchLimit(limit int) {
+	s.batchLimit = limit
+}
+
+// SetResponseLimit sets the maximum size in bytes of the encoded result of a
+// single call. Calls returning more data fail with an error. Zero disables the
+// limit.
+func (s *Server) SetResponseLimit(limit int) {
+	s.responseLimit = limit
+}
+
+// SetExecutionTimeout sets the time a method may run before its context is
+// cancelled and the server answers with an error. Only methods taking a context
+// can be interrupted, others are not subject to the timeout. The overrides map
+// full method names (e.g. eth_call) to a timeout replacing the default one.
+// Zero disables the timeout.
+func (s *Server) SetExecutionTimeout(timeout time.Duration, overrides map[string]time.Duration) {
+	s.timeout = timeout
+	s.methodTimeouts = overrides
+}
+
+// methodTimeout returns the execution timeout of the given method.
+func (s *Server) methodTimeout(method string) time.Duration {
+	if timeout, ok := s.methodTimeouts[method]; ok {
+		return timeout
+	}
+	return s.timeout
+}
+
 // RPCService gives meta information about the server.
 // e.g. gives information about the loaded modules.
 type RPCService struct {
@@ -130,7 +163,7 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 // If singleShot is true it will process a single request, otherwise it will handle
 // requests until the codec returns an error when reading a request (in most cases
 // an EOF). It executes requests in parallel when singleShot is false.
-func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption) error {
+func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot bool, options CodecOption) error {
 	var pend sync.WaitGroup
 
 	defer func() {
@@ -145,7 +178,7 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		s.codecsMu.Unlock()
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// if the codec supports notification include a notifier that callbacks can use
@@ -165,13 +198,23 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 	// test if the server is ordered to stop
 	for atomic.LoadInt32(&s.run) == 1 {
 		reqs, batch, err := s.readRequest(codec)
+		if _, ok := err.(*batchLimitError); ok {
+			// The stream is intact, reject the batch and keep serving
+			codec.Write(codec.CreateErrorResponse(nil, err))
+			if singleShot {
+				return nil
+			}
+			continue
+		}
 		if err != nil {
 			// If a parsing error occurred, send an error
 			if err.Error() != "EOF" {
 				log.Debug(fmt.Sprintf("read error %v\n", err))
 				codec.Write(codec.CreateErrorResponse(nil, err))
 			}
-			// Error or end of stream, wait for requests and tear down
+			// Error or end of stream, the client is gone so cancel the pending
+			// requests, wait for them and tear down
+			cancel()
 			pend.Wait()
 			return nil
 		}
@@ -220,14 +263,15 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 // stopped. In either case the codec is closed.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	s.serveRequest(context.Background(), codec, false, options)
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
 // close the codec unless a non-recoverable error has occurred. Note, this method will return after
-// a single request has been processed!
-func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) {
-	s.serveRequest(codec, true, options)
+// a single request has been processed! Methods taking a context are cancelled
+// once ctx is done.
+func (s *Server) ServeSingleRequest(ctx context.Context, codec ServerCodec, options CodecOption) {
+	s.serveRequest(ctx, codec, true, options)
 }
 
 // Stop will stop reading new requests, wait for stopPendingRequestTimeout to allow pending requests to finish,
@@ -304,6 +348,13 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, rpcErr), nil
 	}
 
+	// Only methods taking a context can be interrupted once the timeout passes
+	timeout := s.methodTimeout(req.method)
+	if timeout > 0 && req.callb.hasCtx {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
 		arguments = append(arguments, reflect.ValueOf(ctx))
@@ -312,8 +363,19 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		arguments = append(arguments, req.args...)
 	}
 
-	// execute RPC method and return result
+	// execute RPC method and return result, a result produced after the context
+	// was cancelled may be incomplete and is replaced by the cancellation cause
 	reply := req.callb.method.Func.Call(arguments)
+	if req.callb.hasCtx {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			log.Warn("RPC method execution timed out", "method", req.method, "timeout", timeout)
+			return codec.CreateErrorResponse(&req.id, &timeoutError{req.method, timeout}), nil
+		case context.Canceled:
+			log.Debug("RPC method execution canceled", "method", req.method)
+			return codec.CreateErrorResponse(&req.id, &canceledError{req.method}), nil
+		}
+	}
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
@@ -325,6 +387,22 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 			return res, nil
 		}
 	}
+	if s.responseLimit > 0 {
+		// Encode the result upfront to enforce the size cap, the encoded form
+		// is passed on so it isn't marshalled twice
+		res := reply[0].Interface()
+		if isHexNum(reflect.TypeOf(res)) {
+			res = fmt.Sprintf(`%#x`, res)
+		}
+		result, err := json.Marshal(res)
+		if err != nil {
+			return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
+		}
+		if len(result) > s.responseLimit {
+			return codec.CreateErrorResponse(&req.id, &responseTooLargeError{s.responseLimit}), nil
+		}
+		return codec.CreateResponse(req.id, json.RawMessage(result)), nil
+	}
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
 }
 
@@ -384,6 +462,9 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 	if err != nil {
 		return nil, batch, err
 	}
+	if batch && s.batchLimit > 0 && len(reqs) > s.batchLimit {
+		return nil, batch, &batchLimitError{s.batchLimit}
+	}
 
 	requests := make([]*serverRequest, len(reqs))
 
@@ -432,7 +513,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		}
 
 		if callb, ok := svc.callbacks[r.method]; ok { // lookup RPC method
-			requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+			requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + serviceMethodSeparator + r.method, callb: callb}
 			if r.params != nil && len(callb.argTypes) > 0 {
 				if args, err := codec.ParseRequestArguments(callb.argTypes, r.params); err == nil {
 					requests[i].args = args
@@ -160,3 +160,245 @@ func TestServerMethodExecution(t *testing.T) {
 func TestServerMethodWithCtx(t *testing.T) {
 	testServerMethodExecution(t, "echoWithCtx")
 }
+
+// SlowService waits until the duration passes or the request context is done,
+// reporting how each call ended on its channel if set.
+type SlowService struct {
+	started chan struct{}
+	ended   chan error
+}
+
+func (s *SlowService) Wait(ctx context.Context, duration time.Duration) bool {
+	if s.started != nil {
+		s.started <- struct{}{}
+	}
+	var err error
+	select {
+	case <-time.After(duration):
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if s.ended != nil {
+		s.ended <- err
+	}
+	return err == nil
+}
+
+// Sleep can't be interrupted as it doesn't take a context.
+func (s *SlowService) Sleep(duration time.Duration) bool {
+	time.Sleep(duration)
+	return true
+}
+
+// serveLimitedPipe starts serving a server with a test service over an in memory
+// pipe, returning the json encoder and decoder of the client side.
+func serveLimitedPipe(t *testing.T, server *Server) (*json.Encoder, *json.Decoder, func()) {
+	return serveSlowPipe(t, server, new(SlowService))
+}
+
+// serveSlowPipe is serveLimitedPipe with the given slow service instance.
+func serveSlowPipe(t *testing.T, server *Server, slow *SlowService) (*json.Encoder, *json.Decoder, func()) {
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("slow", slow); err != nil {
+		t.Fatal(err)
+	}
+	clientConn, serverConn := net.Pipe()
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMethodInvocation)
+
+	return json.NewEncoder(clientConn), json.NewDecoder(clientConn), func() { clientConn.Close() }
+}
+
+func TestServerBatchLimit(t *testing.T) {
+	server := NewServer()
+	server.SetBatchLimit(2)
+
+	out, in, closer := serveLimitedPipe(t, server)
+	defer closer()
+
+	request := func(id int) map[string]interface{} {
+		return map[string]interface{}{"id": id, "method": "test_rets", "version": "2.0"}
+	}
+	// A batch over the limit must be rejected as a whole
+	if err := out.Encode([]interface{}{request(1), request(2), request(3)}); err != nil {
+		t.Fatal(err)
+	}
+	var rejected jsonErrResponse
+	if err := in.Decode(&rejected); err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Error.Code != -32600 {
+		t.Fatalf("error code mismatch: have %d, want %d", rejected.Error.Code, -32600)
+	}
+	// The connection must stay usable for batches within the limit
+	if err := out.Encode([]interface{}{request(4), request(5)}); err != nil {
+		t.Fatal(err)
+	}
+	var responses []jsonSuccessResponse
+	if err := in.Decode(&responses); err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 2 {
+		t.Fatalf("response count mismatch: have %d, want 2", len(responses))
+	}
+}
+
+func TestServerExecutionTimeout(t *testing.T) {
+	server := NewServer()
+	server.SetExecutionTimeout(0, map[string]time.Duration{"slow_wait": 50 * time.Millisecond, "slow_sleep": time.Millisecond})
+
+	slow := &SlowService{ended: make(chan error, 1)}
+	out, in, closer := serveSlowPipe(t, server, slow)
+	defer closer()
+
+	// A method exceeding its timeout must fail
+	if err := out.Encode(map[string]interface{}{"id": 1, "method": "slow_wait", "version": "2.0", "params": []interface{}{int64(time.Second)}}); err != nil {
+		t.Fatal(err)
+	}
+	var timedout jsonErrResponse
+	if err := in.Decode(&timedout); err != nil {
+		t.Fatal(err)
+	}
+	if timedout.Error.Code != -32002 {
+		t.Fatalf("error code mismatch: have %d, want %d", timedout.Error.Code, -32002)
+	}
+	// The method must have been interrupted through its context, not abandoned
+	select {
+	case err := <-slow.ended:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("method context error mismatch: have %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("method not interrupted")
+	}
+	// Methods within their timeout must succeed
+	if err := out.Encode(map[string]interface{}{"id": 2, "method": "slow_wait", "version": "2.0", "params": []interface{}{0}}); err != nil {
+		t.Fatal(err)
+	}
+	var response jsonSuccessResponse
+	if err := in.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Result != true {
+		t.Fatalf("result mismatch: have %v, want true", response.Result)
+	}
+	<-slow.ended
+
+	// Methods without a context can't be interrupted and run to completion
+	if err := out.Encode(map[string]interface{}{"id": 3, "method": "slow_sleep", "version": "2.0", "params": []interface{}{int64(50 * time.Millisecond)}}); err != nil {
+		t.Fatal(err)
+	}
+	response = jsonSuccessResponse{}
+	if err := in.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Result != true {
+		t.Fatalf("result mismatch: have %v, want true", response.Result)
+	}
+}
+
+func TestServerTimeoutDisabledByDefault(t *testing.T) {
+	server := NewServer()
+
+	out, in, closer := serveLimitedPipe(t, server)
+	defer closer()
+
+	if err := out.Encode(map[string]interface{}{"id": 1, "method": "slow_wait", "version": "2.0", "params": []interface{}{int64(100 * time.Millisecond)}}); err != nil {
+		t.Fatal(err)
+	}
+	var response jsonSuccessResponse
+	if err := in.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Result != true {
+		t.Fatalf("result mismatch: have %v, want true", response.Result)
+	}
+}
+
+func TestServerSingleRequestCanceled(t *testing.T) {
+	server := NewServer()
+	slow := &SlowService{started: make(chan struct{}, 1), ended: make(chan error, 1)}
+	if err := server.RegisterName("slow", slow); err != nil {
+		t.Fatal(err)
+	}
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go server.ServeSingleRequest(ctx, NewJSONCodec(serverConn), OptionMethodInvocation)
+
+	out, in := json.NewEncoder(clientConn), json.NewDecoder(clientConn)
+	if err := out.Encode(map[string]interface{}{"id": 1, "method": "slow_wait", "version": "2.0", "params": []interface{}{int64(time.Minute)}}); err != nil {
+		t.Fatal(err)
+	}
+	<-slow.started
+	cancel()
+
+	// A cancelled request must not be reported as a timeout
+	var canceled jsonErrResponse
+	if err := in.Decode(&canceled); err != nil {
+		t.Fatal(err)
+	}
+	if canceled.Error.Code != -32004 {
+		t.Fatalf("error code mismatch: have %d, want %d", canceled.Error.Code, -32004)
+	}
+	if err := <-slow.ended; err != context.Canceled {
+		t.Fatalf("method context error mismatch: have %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestServerDisconnectCancels(t *testing.T) {
+	server := NewServer()
+	slow := &SlowService{started: make(chan struct{}, 1), ended: make(chan error, 1)}
+
+	out, _, closer := serveSlowPipe(t, server, slow)
+	if err := out.Encode(map[string]interface{}{"id": 1, "method": "slow_wait", "version": "2.0", "params": []interface{}{int64(time.Minute)}}); err != nil {
+		t.Fatal(err)
+	}
+	<-slow.started
+	closer()
+
+	// Closing the connection must cancel the pending request
+	select {
+	case err := <-slow.ended:
+		if err != context.Canceled {
+			t.Fatalf("method context error mismatch: have %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending request not cancelled on disconnect")
+	}
+}
+
+func TestServerResponseLimit(t *testing.T) {
+	server := NewServer()
+	server.SetResponseLimit(64)
+
+	out, in, closer := serveLimitedPipe(t, server)
+	defer closer()
+
+	if err := out.Encode(map[string]interface{}{"id": 1, "method": "test_echo", "version": "2.0", "params": []interface{}{"short", 1, nil}}); err != nil {
+		t.Fatal(err)
+	}
+	response := jsonSuccessResponse{Result: &Result{}}
+	if err := in.Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if result, ok := response.Result.(*Result); !ok || result.String != "short" {
+		t.Fatalf("invalid response: %v", response.Result)
+	}
+	long := make([]byte, 128)
+	for i := range long {
+		long[i] = 'a'
+	}
+	if err := out.Encode(map[string]interface{}{"id": 2, "method": "test_echo", "version": "2.0", "params": []interface{}{string(long), 1, nil}}); err != nil {
+		t.Fatal(err)
+	}
+	var rejected jsonErrResponse
+	if err := in.Decode(&rejected); err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Error.Code != -32003 {
+		t.Fatalf("error code mismatch: have %d, want %d", rejected.Error.Code, -32003)
+	}
+}
@@ -17,12 +17,16 @@
 package rpc
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/hexutil"
 	"gopkg.in/fatih/set.v0"
 )
@@ -57,6 +61,7 @@ type service struct {
 type serverRequest struct {
 	id            interface{}
 	svcname       string
+	method        string // full method name (e.g. eth_call) used for the execution limits
 	callb         *callback
 	args          []reflect.Value
 	isUnsubscribe bool
@@ -74,6 +79,11 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	batchLimit     int                      // maximum number of requests in a batch, 0 = unlimited
+	responseLimit  int                      // maximum encoded size of a call result, 0 = unlimited
+	timeout        time.Duration            // default method execution timeout, 0 = unlimited
+	methodTimeouts map[string]time.Duration // per method execution timeout overrides
 }
 
 // rpcRequest represents a raw incoming RPC request
@@ -163,3 +173,91 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 func (bn BlockNumber) Int64() int64 {
 	return (int64)(bn)
 }
+
+// BlockNumberOrHash identifies a block either by its number (or one of the
+// special block tags) or by its hash.
+type BlockNumberOrHash struct {
+	BlockNumber *BlockNumber `json:"blockNumber,omitempty"`
+	BlockHash   *common.Hash `json:"blockHash,omitempty"`
+}
+
+// UnmarshalJSON parses the given JSON fragment into a BlockNumberOrHash. It
+// supports an object with either a blockNumber or a blockHash field, a 32 byte
+// hex encoded hash, any input accepted by BlockNumber and plain decimal numbers.
+func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
+	type erased BlockNumberOrHash
+	e := erased{}
+	if err := json.Unmarshal(data, &e); err == nil {
+		if e.BlockNumber != nil && e.BlockHash != nil {
+			return fmt.Errorf("cannot specify both BlockHash and BlockNumber, choose one or the other")
+		}
+		if e.BlockNumber == nil && e.BlockHash == nil {
+			return fmt.Errorf("either BlockHash or BlockNumber must be specified")
+		}
+		bnh.BlockNumber, bnh.BlockHash = e.BlockNumber, e.BlockHash
+		return nil
+	}
+	input := strings.TrimSpace(string(data))
+	if number, err := strconv.ParseUint(input, 10, 63); err == nil {
+		bn := BlockNumber(number)
+		bnh.BlockNumber, bnh.BlockHash = &bn, nil
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	if len(str) == 66 {
+		hash := common.Hash{}
+		if err := hash.UnmarshalText([]byte(str)); err != nil {
+			return err
+		}
+		bnh.BlockNumber, bnh.BlockHash = nil, &hash
+		return nil
+	}
+	bn := BlockNumber(0)
+	if err := bn.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	bnh.BlockNumber, bnh.BlockHash = &bn, nil
+	return nil
+}
+
+// Number returns the block number if the block is identified by number.
+func (bnh *BlockNumberOrHash) Number() (BlockNumber, bool) {
+	if bnh.BlockNumber != nil {
+		return *bnh.BlockNumber, true
+	}
+	return BlockNumber(0), false
+}
+
+// Hash returns the block hash if the block is identified by hash.
+func (bnh *BlockNumberOrHash) Hash() (common.Hash, bool) {
+	if bnh.BlockHash != nil {
+		return *bnh.BlockHash, true
+	}
+	return common.Hash{}, false
+}
+
+// String implements fmt.Stringer.
+func (bnh BlockNumberOrHash) String() string {
+	if bnh.BlockHash != nil {
+		return bnh.BlockHash.Hex()
+	}
+	if bnh.BlockNumber != nil {
+		return fmt.Sprintf("#%d", bnh.BlockNumber.Int64())
+	}
+	return "nil"
+}
+
+// BlockNumberOrHashWithNumber returns a BlockNumberOrHash identifying a block
+// by number.
+func BlockNumberOrHashWithNumber(blockNr BlockNumber) BlockNumberOrHash {
+	return BlockNumberOrHash{BlockNumber: &blockNr}
+}
+
+// BlockNumberOrHashWithHash returns a BlockNumberOrHash identifying a block by
+// hash.
+func BlockNumberOrHashWithHash(hash common.Hash) BlockNumberOrHash {
+	return BlockNumberOrHash{BlockHash: &hash}
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/Bokerchain/Boker/chain/common"
 	"github.com/Bokerchain/Boker/chain/common/math"
 )
 
@@ -64,3 +65,43 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockNumberOrHashJSONUnmarshal(t *testing.T) {
+	hash := common.HexToHash("0x1234")
+	tests := []struct {
+		input    string
+		mustFail bool
+		expected BlockNumberOrHash
+	}{
+		0:  {`"0x1"`, false, BlockNumberOrHashWithNumber(1)},
+		1:  {`"latest"`, false, BlockNumberOrHashWithNumber(LatestBlockNumber)},
+		2:  {`12`, false, BlockNumberOrHashWithNumber(12)},
+		3:  {`"` + hash.Hex() + `"`, false, BlockNumberOrHashWithHash(hash)},
+		4:  {`{"blockNumber":"0x2"}`, false, BlockNumberOrHashWithNumber(2)},
+		5:  {`{"blockHash":"` + hash.Hex() + `"}`, false, BlockNumberOrHashWithHash(hash)},
+		6:  {`{"blockNumber":"0x2","blockHash":"` + hash.Hex() + `"}`, true, BlockNumberOrHash{}},
+		7:  {`{}`, true, BlockNumberOrHash{}},
+		8:  {`-1`, true, BlockNumberOrHash{}},
+		9:  {`"0x1234"`, false, BlockNumberOrHashWithNumber(0x1234)},
+		10: {`"ff"`, true, BlockNumberOrHash{}},
+	}
+
+	for i, test := range tests {
+		var bnh BlockNumberOrHash
+		err := json.Unmarshal([]byte(test.input), &bnh)
+		if test.mustFail && err == nil {
+			t.Errorf("Test %d should fail", i)
+			continue
+		}
+		if !test.mustFail && err != nil {
+			t.Errorf("Test %d should pass but got err: %v", i, err)
+			continue
+		}
+		if test.mustFail {
+			continue
+		}
+		if bnh.String() != test.expected.String() {
+			t.Errorf("Test %d got unexpected value, want %v, got %v", i, test.expected, bnh)
+		}
+	}
+}
@@ -46,8 +46,9 @@ on top of the dpa
 it is the public interface of the dpa which is included in the ethereum stack
 */
 type Api struct {
-	dpa *storage.DPA
-	dns Resolver
+	dpa   *storage.DPA
+	dns   Resolver
+	feeds feedVersions
 }
 
 //the api constructor initialises
@@ -75,6 +76,12 @@ func (self *Api) Store(data io.Reader, size int64, wg *sync.WaitGroup) (key stor
 	return self.dpa.Store(data, size, wg, nil)
 }
 
+// StoreEncrypted stores the data encrypted, the returned key contains the
+// encryption key and can be retrieved like any other key
+func (self *Api) StoreEncrypted(data io.Reader, size int64, wg *sync.WaitGroup) (key storage.Key, err error) {
+	return self.dpa.StoreEncrypted(data, size, wg, nil)
+}
+
 type ErrResolve error
 
 // DNS Resolver
@@ -128,8 +135,8 @@ func (self *Api) Put(content, contentType string) (storage.Key, error) {
 
 // Get uses iterative manifest retrieval and prefix matching
 // to resolve basePath to content using dpa retrieve
-// it returns a section reader, mimeType, status and an error
-func (self *Api) Get(key storage.Key, path string) (reader storage.LazySectionReader, mimeType string, status int, err error) {
+// it returns a section reader, mimeType, status, the key of the content and an error
+func (self *Api) Get(key storage.Key, path string) (reader storage.LazySectionReader, mimeType string, status int, contentKey storage.Key, err error) {
 	trie, err := loadManifest(self.dpa, key, nil)
 	if err != nil {
 		status = http.StatusNotFound
@@ -143,6 +150,7 @@ func (self *Api) Get(key storage.Key, path string) (reader storage.LazySectionRe
 
 	if entry != nil {
 		key = common.Hex2Bytes(entry.Hash)
+		contentKey = key
 		status = entry.Status
 		if status == http.StatusMultipleChoices {
 			return
@@ -19,6 +19,7 @@ package client
 import (
 	"archive/tar"
 	"bytes"
+	"crypto/ecdsa"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -32,8 +33,12 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/swarm/api"
+	"github.com/Bokerchain/Boker/chain/swarm/storage"
 )
 
 var (
@@ -41,6 +46,14 @@ var (
 	DefaultClient  = NewClient(DefaultGateway)
 )
 
+const (
+	// encryptAddr is the address which makes the gateway store uploads encrypted
+	encryptAddr = "encrypt"
+
+	// uploadSizeHeader contains the number of bytes received by an upload session
+	uploadSizeHeader = "X-Swarm-Upload-Size"
+)
+
 func NewClient(gateway string) *Client {
 	return &Client{
 		Gateway: gateway,
@@ -54,10 +67,21 @@ type Client struct {
 
 // UploadRaw uploads raw data to swarm and returns the resulting hash
 func (c *Client) UploadRaw(r io.Reader, size int64) (string, error) {
+	return c.uploadRaw(r, size, "")
+}
+
+// UploadRawEncrypted uploads raw data to swarm encrypted with a random key
+// and returns the resulting reference, which contains the encryption key and
+// can be downloaded like any other hash
+func (c *Client) UploadRawEncrypted(r io.Reader, size int64) (string, error) {
+	return c.uploadRaw(r, size, encryptAddr)
+}
+
+func (c *Client) uploadRaw(r io.Reader, size int64, addr string) (string, error) {
 	if size <= 0 {
 		return "", errors.New("data size must be greater than zero")
 	}
-	req, err := http.NewRequest("POST", c.Gateway+"/bzzr:/", r)
+	req, err := http.NewRequest("POST", c.Gateway+"/bzzr:/"+addr, r)
 	if err != nil {
 		return "", err
 	}
@@ -132,6 +156,16 @@ func (c *Client) Upload(file *File, manifest string) (string, error) {
 	return c.TarUpload(manifest, &FileUploader{file})
 }
 
+// UploadEncrypted uploads a file to swarm encrypted and creates a new
+// encrypted manifest containing the file, returning the resulting manifest
+// reference
+func (c *Client) UploadEncrypted(file *File) (string, error) {
+	if file.Size <= 0 {
+		return "", errors.New("file size must be greater than zero")
+	}
+	return c.TarUpload(encryptAddr, &FileUploader{file})
+}
+
 // Download downloads a file with the given path from the swarm manifest with
 // the given hash (i.e. it gets bzz:/<hash>/<path>)
 func (c *Client) Download(hash, path string) (*File, error) {
@@ -169,6 +203,12 @@ func (c *Client) UploadDirectory(dir, defaultPath, manifest string) (string, err
 	return c.TarUpload(manifest, &DirectoryUploader{dir, defaultPath})
 }
 
+// UploadDirectoryEncrypted uploads a directory tree to swarm encrypted and
+// creates a new encrypted manifest, returning the resulting manifest reference
+func (c *Client) UploadDirectoryEncrypted(dir, defaultPath string) (string, error) {
+	return c.UploadDirectory(dir, defaultPath, encryptAddr)
+}
+
 // DownloadDirectory downloads the files contained in a swarm manifest under
 // the given path into a local directory (existing files will be overwritten)
 func (c *Client) DownloadDirectory(hash, path, destDir string) error {
@@ -251,6 +291,226 @@ func (c *Client) DownloadManifest(hash string) (*api.Manifest, error) {
 	return &manifest, nil
 }
 
+// UpdateManifest modifies the swarm manifest with the given hash by adding
+// the entries of m under the given path, replacing entries with the same
+// path and removing the paths of entries without a hash, and returns the
+// resulting manifest hash. The entries must refer to content which is
+// already stored in swarm (e.g. using UploadRaw)
+func (c *Client) UpdateManifest(hash, path string, m *api.Manifest) (string, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("PUT", c.Gateway+"/bzz:/"+hash+"/"+path, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", api.ManifestType)
+	return c.doManifestRequest(req)
+}
+
+// Delete removes the given path from the swarm manifest with the given hash
+// and returns the resulting manifest hash
+func (c *Client) Delete(hash, path string) (string, error) {
+	req, err := http.NewRequest("DELETE", c.Gateway+"/bzz:/"+hash+"/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	return c.doManifestRequest(req)
+}
+
+// doManifestRequest sends a request which modifies a manifest and returns the
+// resulting manifest hash
+func (c *Client) doManifestRequest(req *http.Request) (string, error) {
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// BeginUpload creates a resumable upload session on the gateway and returns
+// its id
+func (c *Client) BeginUpload() (string, error) {
+	req, err := http.NewRequest("POST", c.Gateway+"/bzzu:/", nil)
+	if err != nil {
+		return "", err
+	}
+	return c.doManifestRequest(req)
+}
+
+// UploadStatus returns the number of bytes received by an upload session,
+// which is the offset the upload has to be resumed at
+func (c *Client) UploadStatus(id string) (int64, error) {
+	req, err := http.NewRequest("GET", c.Gateway+"/bzzu:/"+id, nil)
+	if err != nil {
+		return 0, err
+	}
+	return c.doUploadRequest(req)
+}
+
+// AppendUpload appends data to an upload session at the given offset and
+// returns the number of bytes received by the session. If the offset does not
+// match the received data an error is returned together with the offset to
+// resume at
+func (c *Client) AppendUpload(id string, offset int64, r io.Reader, size int64) (int64, error) {
+	req, err := http.NewRequest("PUT", c.Gateway+"/bzzu:/"+id+"?offset="+strconv.FormatInt(offset, 10), r)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = size
+	return c.doUploadRequest(req)
+}
+
+// ResumeUpload sends the content of r to an upload session in parts of the
+// given size, starting after the data already received by the session
+func (c *Client) ResumeUpload(id string, r io.ReaderAt, size, partSize int64) error {
+	if partSize <= 0 {
+		return errors.New("part size must be greater than zero")
+	}
+	offset, err := c.UploadStatus(id)
+	if err != nil {
+		return err
+	}
+	for offset < size {
+		n := partSize
+		if offset+n > size {
+			n = size - offset
+		}
+		if offset, err = c.AppendUpload(id, offset, io.NewSectionReader(r, offset, n), n); err != nil {
+			return err
+		}
+	}
+	if offset != size {
+		return fmt.Errorf("upload session contains %d bytes, expected %d", offset, size)
+	}
+	return nil
+}
+
+// CommitUpload stores the data of an upload session under the given path of
+// the manifest (or of a new manifest if the manifest argument is empty) and
+// returns the resulting manifest hash, the session is removed afterwards
+func (c *Client) CommitUpload(id, manifest, path, contentType string) (string, error) {
+	uri := c.Gateway + "/bzzu:/" + id + "/" + path
+	if manifest != "" {
+		uri += "?manifest=" + manifest
+	}
+	req, err := http.NewRequest("POST", uri, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.doManifestRequest(req)
+}
+
+// AbortUpload removes an upload session and the data received by it
+func (c *Client) AbortUpload(id string) error {
+	req, err := http.NewRequest("DELETE", c.Gateway+"/bzzu:/"+id, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.doManifestRequest(req)
+	return err
+}
+
+// doUploadRequest sends a request to an upload session and returns the number
+// of bytes received by the session
+func (c *Client) doUploadRequest(req *http.Request) (int64, error) {
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	size, serr := strconv.ParseInt(res.Header.Get(uploadSizeHeader), 10, 64)
+	switch {
+	case res.StatusCode == http.StatusConflict && serr == nil:
+		return size, fmt.Errorf("upload offset mismatch, resume at %d", size)
+	case res.StatusCode != http.StatusOK:
+		return 0, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	case serr != nil:
+		return 0, fmt.Errorf("invalid upload size: %v", serr)
+	}
+	return size, nil
+}
+
+// FeedLookup returns the given version of a feed, or the latest version if
+// version is 0. api.ErrFeedNotFound is returned if the version does not exist
+func (c *Client) FeedLookup(feed *storage.Feed, version uint64) (*storage.FeedUpdate, error) {
+	uri := c.Gateway + "/bzz-feed:/" + feed.Hex() + "?meta=true"
+	if version > 0 {
+		uri += "&version=" + strconv.FormatUint(version, 10)
+	}
+	res, err := http.DefaultClient.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, api.ErrFeedNotFound
+	default:
+		return nil, fmt.Errorf("unexpected HTTP status: %s", res.Status)
+	}
+	update := new(storage.FeedUpdate)
+	if err := json.NewDecoder(res.Body).Decode(update); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// UpdateFeed publishes the content as the next version of the feed with the
+// given topic, signing the update with the key of the feed user
+func (c *Client) UpdateFeed(prv *ecdsa.PrivateKey, topic common.Hash, content []byte) (*storage.FeedUpdate, error) {
+	feed := storage.Feed{Topic: topic, User: crypto.PubkeyToAddress(prv.PublicKey)}
+	var version uint64
+	latest, err := c.FeedLookup(&feed, 0)
+	switch {
+	case err == nil:
+		version = latest.Version
+	case err != api.ErrFeedNotFound:
+		return nil, err
+	}
+	update := &storage.FeedUpdate{
+		Feed:    feed,
+		Version: version + 1,
+		Time:    uint64(time.Now().Unix()),
+		Content: content,
+	}
+	if err := update.Sign(prv); err != nil {
+		return nil, err
+	}
+	if _, err := c.PostFeedUpdate(update); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// PostFeedUpdate publishes a signed feed update and returns the key of the
+// chunk storing it
+func (c *Client) PostFeedUpdate(update *storage.FeedUpdate) (string, error) {
+	data, err := json.Marshal(update)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", c.Gateway+"/bzz-feed:/"+update.Feed.Hex(), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.doManifestRequest(req)
+}
+
 // List list files in a swarm manifest which have the given prefix, grouping
 // common prefixes using "/" as a delimiter.
 //
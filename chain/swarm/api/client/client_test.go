@@ -18,14 +18,18 @@ package client
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
 	"testing"
 
+	"github.com/Bokerchain/Boker/chain/crypto"
 	"github.com/Bokerchain/Boker/chain/swarm/api"
+	"github.com/Bokerchain/Boker/chain/swarm/storage"
 	"github.com/Bokerchain/Boker/chain/swarm/testutil"
 )
 
@@ -58,6 +62,104 @@ func TestClientUploadDownloadRaw(t *testing.T) {
 	}
 }
 
+// TestClientUploadDownloadEncrypted tests uploading and downloading encrypted
+// raw data and files
+func TestClientUploadDownloadEncrypted(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	download := func(hash, path string) []byte {
+		file, err := client.Download(hash, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	// upload some raw data and check the reference contains the key
+	data := []byte("foo123")
+	hash, err := client.UploadRawEncrypted(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hash) != 128 {
+		t.Fatalf("expected encrypted reference of 128 hex characters, got %q", hash)
+	}
+	res, err := client.DownloadRaw(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotData, err := ioutil.ReadAll(res)
+	res.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotData, data) {
+		t.Fatalf("expected downloaded data to be %q, got %q", data, gotData)
+	}
+
+	// the content is not readable without the encryption key
+	res, err = client.DownloadRaw(hash[:64])
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotData, _ = ioutil.ReadAll(res)
+	res.Close()
+	if bytes.Contains(gotData, data) {
+		t.Fatal("expected content to be stored encrypted")
+	}
+
+	// upload a file to a new encrypted manifest and add another one to it
+	file := &File{
+		ReadCloser: ioutil.NopCloser(bytes.NewReader([]byte("secret1"))),
+		ManifestEntry: api.ManifestEntry{
+			Path:        "file1.txt",
+			ContentType: "text/plain",
+			Size:        7,
+		},
+	}
+	hash, err = client.UploadEncrypted(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file = &File{
+		ReadCloser: ioutil.NopCloser(bytes.NewReader([]byte("secret2"))),
+		ManifestEntry: api.ManifestEntry{
+			Path:        "dir/file2.txt",
+			ContentType: "text/plain",
+			Size:        7,
+		},
+	}
+	hash, err = client.Upload(file, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hash) != 128 {
+		t.Fatalf("expected updated manifest to stay encrypted, got %q", hash)
+	}
+	if got := download(hash, "file1.txt"); string(got) != "secret1" {
+		t.Fatalf("expected file1.txt to be %q, got %q", "secret1", got)
+	}
+	if got := download(hash, "dir/file2.txt"); string(got) != "secret2" {
+		t.Fatalf("expected dir/file2.txt to be %q, got %q", "secret2", got)
+	}
+	manifest, err := client.DownloadManifest(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range manifest.Entries {
+		if len(entry.Hash) != 128 {
+			t.Fatalf("expected manifest entry %q to be encrypted, got hash %q", entry.Path, entry.Hash)
+		}
+	}
+}
+
 // TestClientUploadDownloadFiles test uploading and downloading files to swarm
 // manifests
 func TestClientUploadDownloadFiles(t *testing.T) {
@@ -272,6 +374,146 @@ func TestClientFileList(t *testing.T) {
 	}
 }
 
+// TestClientUpdateManifest tests adding, replacing and removing manifest
+// entries of an existing manifest
+func TestClientUpdateManifest(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	dir := newTestDirectory(t)
+	defer os.RemoveAll(dir)
+
+	client := NewClient(srv.URL)
+	hash, err := client.UploadDirectory(dir, "", "")
+	if err != nil {
+		t.Fatalf("error uploading directory: %s", err)
+	}
+	store := func(data string) string {
+		key, err := client.UploadRaw(bytes.NewReader([]byte(data)), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	check := func(hash, path, expected string) {
+		file, err := client.Download(hash, path)
+		if err != nil {
+			t.Fatalf("error downloading %s: %s", path, err)
+		}
+		defer file.Close()
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Fatalf("expected %s to contain %q, got %q", path, expected, data)
+		}
+	}
+
+	// replace one file, add another and remove a third one under dir1/
+	newHash, err := client.UpdateManifest(hash, "dir1", &api.Manifest{
+		Entries: []api.ManifestEntry{
+			{Hash: store("replaced"), Path: "file3.txt", ContentType: "text/plain"},
+			{Hash: store("added"), Path: "file9.txt", ContentType: "text/plain"},
+			{Path: "file4.txt"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error updating manifest: %s", err)
+	}
+	check(newHash, "dir1/file3.txt", "replaced")
+	check(newHash, "dir1/file9.txt", "added")
+	if _, err := client.Download(newHash, "dir1/file4.txt"); err == nil {
+		t.Fatal("expected dir1/file4.txt to be removed")
+	}
+
+	// the original manifest is left intact
+	check(hash, "dir1/file3.txt", "dir1/file3.txt")
+	check(hash, "dir1/file4.txt", "dir1/file4.txt")
+
+	// delete a file using a DELETE request
+	newHash, err = client.Delete(newHash, "file1.txt")
+	if err != nil {
+		t.Fatalf("error deleting file: %s", err)
+	}
+	if _, err := client.Download(newHash, "file1.txt"); err == nil {
+		t.Fatal("expected file1.txt to be removed")
+	}
+	check(newHash, "file2.txt", "file2.txt")
+
+	// invalid content hashes are rejected
+	if _, err := client.UpdateManifest(hash, "", &api.Manifest{Entries: []api.ManifestEntry{{Hash: "1234", Path: "x"}}}); err == nil {
+		t.Fatal("expected invalid content hash to be rejected")
+	}
+}
+
+// TestClientResumableUpload tests uploading a file in parts using an upload
+// session, resuming after an interrupted part
+func TestClientResumableUpload(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+
+	id, err := client.BeginUpload()
+	if err != nil {
+		t.Fatalf("error beginning upload: %s", err)
+	}
+	if _, err := client.AppendUpload(id, 0, bytes.NewReader(data[:3000]), 3000); err != nil {
+		t.Fatalf("error appending upload: %s", err)
+	}
+
+	// appending at a wrong offset reports the offset to resume at
+	if size, err := client.AppendUpload(id, 1000, bytes.NewReader(data[1000:2000]), 1000); err == nil || size != 3000 {
+		t.Fatalf("expected offset mismatch at 3000, got size %d, err %v", size, err)
+	}
+
+	// resume the upload with the remaining data
+	if err := client.ResumeUpload(id, bytes.NewReader(data), int64(len(data)), 4096); err != nil {
+		t.Fatalf("error resuming upload: %s", err)
+	}
+	if size, err := client.UploadStatus(id); err != nil || size != int64(len(data)) {
+		t.Fatalf("expected upload size %d, got %d, err %v", len(data), size, err)
+	}
+	hash, err := client.CommitUpload(id, "", "video.mp4", "video/mp4")
+	if err != nil {
+		t.Fatalf("error committing upload: %s", err)
+	}
+	file, err := client.Download(hash, "video.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if file.ContentType != "video/mp4" {
+		t.Fatalf("expected content type %q, got %q", "video/mp4", file.ContentType)
+	}
+	gotData, err := ioutil.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotData, data) {
+		t.Fatal("downloaded data does not match the uploaded data")
+	}
+
+	// the session is removed after the commit
+	if _, err := client.UploadStatus(id); err == nil {
+		t.Fatal("expected upload session to be removed")
+	}
+
+	// aborted sessions are removed
+	id, err = client.BeginUpload()
+	if err != nil {
+		t.Fatalf("error beginning upload: %s", err)
+	}
+	if err := client.AbortUpload(id); err != nil {
+		t.Fatalf("error aborting upload: %s", err)
+	}
+	if _, err := client.UploadStatus(id); err == nil {
+		t.Fatal("expected aborted upload session to be removed")
+	}
+}
+
 // TestClientMultipartUpload tests uploading files to swarm using a multipart
 // upload
 func TestClientMultipartUpload(t *testing.T) {
@@ -323,3 +565,82 @@ func TestClientMultipartUpload(t *testing.T) {
 		checkDownloadFile(file)
 	}
 }
+
+// TestClientFeed tests publishing feed updates and reading them both as raw
+// content and as a reference to a manifest
+func TestClientFeed(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := &storage.Feed{Topic: storage.NewFeedTopic("channel"), User: crypto.PubkeyToAddress(key.PublicKey)}
+
+	if _, err := client.FeedLookup(feed, 0); err != api.ErrFeedNotFound {
+		t.Fatalf("expected %v for an empty feed, got %v", api.ErrFeedNotFound, err)
+	}
+	for i := 1; i <= 5; i++ {
+		update, err := client.UpdateFeed(key, feed.Topic, []byte(fmt.Sprintf("version %d", i)))
+		if err != nil {
+			t.Fatalf("error updating feed: %s", err)
+		}
+		if update.Version != uint64(i) {
+			t.Fatalf("expected version %d, got %d", i, update.Version)
+		}
+	}
+
+	// check the latest and a previous version
+	latest, err := client.FeedLookup(feed, 0)
+	if err != nil {
+		t.Fatalf("error looking up feed: %s", err)
+	}
+	if latest.Version != 5 || string(latest.Content) != "version 5" {
+		t.Fatalf("unexpected latest update: version %d, content %q", latest.Version, latest.Content)
+	}
+	update, err := client.FeedLookup(feed, 2)
+	if err != nil {
+		t.Fatalf("error looking up feed version 2: %s", err)
+	}
+	if string(update.Content) != "version 2" {
+		t.Fatalf("expected version 2 content, got %q", update.Content)
+	}
+
+	// republishing an existing version or an unsigned update fails
+	if _, err := client.PostFeedUpdate(update); err == nil {
+		t.Fatal("expected republishing version 2 to fail")
+	}
+	forged := &storage.FeedUpdate{Feed: *feed, Version: 6, Content: []byte("forged"), Signature: latest.Signature}
+	if _, err := client.PostFeedUpdate(forged); err == nil {
+		t.Fatal("expected publishing a forged update to fail")
+	}
+
+	// point the feed to a manifest and read a file through the feed
+	hash, err := client.Upload(&File{
+		ReadCloser:    ioutil.NopCloser(bytes.NewReader([]byte("metadata"))),
+		ManifestEntry: api.ManifestEntry{Path: "meta.json", ContentType: "application/json", Size: 8},
+	}, "")
+	if err != nil {
+		t.Fatalf("error uploading file: %s", err)
+	}
+	if _, err := client.UpdateFeed(key, feed.Topic, []byte(hash)); err != nil {
+		t.Fatalf("error updating feed: %s", err)
+	}
+	res, err := http.Get(srv.URL + "/bzz-feed:/" + feed.Hex() + "/meta.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || string(data) != "metadata" {
+		t.Fatalf("unexpected response %s: %q", res.Status, data)
+	}
+	if version := res.Header.Get("X-Swarm-Feed-Version"); version != "6" {
+		t.Fatalf("expected feed version 6, got %q", version)
+	}
+}
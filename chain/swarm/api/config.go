@@ -53,6 +53,11 @@ type Config struct {
 	BzzKey     string
 	EnsRoot    common.Address
 	NetworkId  uint64
+
+	// Rate limits of the HTTP API per client (API key or IP address), metering
+	// is enabled if either is set
+	ByteRateLimit    uint64 `json:",omitempty"` // bytes served per second
+	RequestRateLimit uint64 `json:",omitempty"` // requests per second
 }
 
 // config is agnostic to where private key is coming from
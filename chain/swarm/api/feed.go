@@ -0,0 +1,137 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/swarm/storage"
+)
+
+var (
+	// ErrFeedNotFound is returned when a feed or the requested version of it
+	// has no update
+	ErrFeedNotFound = errors.New("feed update not found")
+
+	// ErrFeedVersion is returned when publishing an update whose version does
+	// not follow the latest version of the feed
+	ErrFeedVersion = errors.New("feed update version must follow the latest version")
+)
+
+// feedVersions caches the latest version known for each feed, so that
+// lookups only need to probe the versions published since
+type feedVersions struct {
+	latest map[storage.Feed]uint64
+	mu     sync.Mutex
+}
+
+func (self *feedVersions) get(feed storage.Feed) uint64 {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.latest[feed]
+}
+
+func (self *feedVersions) set(feed storage.Feed, version uint64) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.latest == nil {
+		self.latest = make(map[storage.Feed]uint64)
+	}
+	if version > self.latest[feed] {
+		self.latest[feed] = version
+	}
+}
+
+// feedUpdate retrieves the given version of the feed
+func (self *Api) feedUpdate(feed *storage.Feed, version uint64) (*storage.FeedUpdate, error) {
+	key := feed.UpdateKey(version)
+	chunk, err := self.dpa.Get(key)
+	if err != nil || chunk.SData == nil {
+		return nil, ErrFeedNotFound
+	}
+	return storage.ParseFeedUpdate(key, chunk.SData)
+}
+
+// FeedLookup returns the given version of the feed, or the latest version if
+// version is 0
+func (self *Api) FeedLookup(feed *storage.Feed, version uint64) (*storage.FeedUpdate, error) {
+	if version == 0 {
+		var err error
+		if version, err = self.feedLatest(feed); err != nil {
+			return nil, err
+		}
+	}
+	update, err := self.feedUpdate(feed, version)
+	if err != nil {
+		return nil, err
+	}
+	self.feeds.set(*feed, update.Version)
+	return update, nil
+}
+
+// feedLatest finds the latest version of the feed by probing versions at
+// exponentially growing distances from the latest known one until a missing
+// version is found, then bisecting the range in between
+func (self *Api) feedLatest(feed *storage.Feed) (uint64, error) {
+	exists := func(version uint64) bool {
+		_, err := self.feedUpdate(feed, version)
+		return err == nil
+	}
+	lo := self.feeds.get(*feed)
+	step := uint64(1)
+	for exists(lo + step) {
+		lo += step
+		step *= 2
+	}
+	hi := lo + step
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if exists(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	if lo == 0 {
+		return 0, ErrFeedNotFound
+	}
+	log.Trace(fmt.Sprintf("Swarm feed %v latest version %d", feed, lo))
+	return lo, nil
+}
+
+// FeedUpdate publishes a signed update, its version must be the one following
+// the latest version of the feed. It returns the key of the update chunk
+func (self *Api) FeedUpdate(update *storage.FeedUpdate) (storage.Key, error) {
+	chunk, err := update.Chunk()
+	if err != nil {
+		return nil, err
+	}
+	latest, err := self.feedLatest(&update.Feed)
+	if err != nil && err != ErrFeedNotFound {
+		return nil, err
+	}
+	if update.Version != latest+1 {
+		return nil, ErrFeedVersion
+	}
+	self.dpa.Put(chunk)
+	self.feeds.set(update.Feed, update.Version)
+	log.Debug(fmt.Sprintf("Swarm feed %v updated to version %d", &update.Feed, update.Version))
+	return chunk.Key, nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/swarm/api"
+	"github.com/Bokerchain/Boker/chain/swarm/storage"
+)
+
+/*
+Feeds are read and published through bzz-feed:/ URIs, the address being the
+feed id (the hex encoded topic followed by the user address):
+
+	GET  /bzz-feed:/<id>               returns the content of the latest update
+	GET  /bzz-feed:/<id>/<path>        treats the content of the latest update as
+	                                   a manifest key and returns the file at <path>
+	POST /bzz-feed:/<id>               publishes the signed update in the JSON body
+
+GET requests take an optional version query parameter selecting a version
+other than the latest one, and return the update itself as JSON if the meta
+query parameter is set.
+*/
+
+// FeedVersionHeader is the response header containing the version of the
+// feed update served
+const FeedVersionHeader = "X-Swarm-Feed-Version"
+
+// HandleFeed handles the requests to bzz-feed:/ URIs
+func (s *Server) HandleFeed(w http.ResponseWriter, r *Request) {
+	feed, err := storage.ParseFeed(r.uri.Addr)
+	if err != nil {
+		s.BadRequest(w, r, err.Error())
+		return
+	}
+	switch r.Method {
+	case "GET":
+		s.handleGetFeed(w, r, feed)
+	case "POST":
+		s.handlePostFeed(w, r, feed)
+	default:
+		ShowError(w, &r.Request, fmt.Sprintf("Method %s is not supported for %s.", r.Method, r.uri), http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleGetFeed(w http.ResponseWriter, r *Request, feed *storage.Feed) {
+	var version uint64
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if version, err = strconv.ParseUint(v, 10, 64); err != nil || version == 0 {
+			s.BadRequest(w, r, "invalid version")
+			return
+		}
+	}
+	update, err := s.api.FeedLookup(feed, version)
+	if err == api.ErrFeedNotFound {
+		s.NotFound(w, r, err)
+		return
+	} else if err != nil {
+		s.Error(w, r, err)
+		return
+	}
+	w.Header().Set(FeedVersionHeader, strconv.FormatUint(update.Version, 10))
+	if version == 0 {
+		// the latest version changes with every update
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	switch {
+	case r.URL.Query().Get("meta") != "":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(update)
+
+	case r.uri.Path == "" && !strings.HasSuffix(r.URL.Path, "/"):
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if typ := r.URL.Query().Get("content_type"); typ != "" {
+			w.Header().Set("Content-Type", typ)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(update.Content)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(update.Content)
+
+	default:
+		key := storage.Key(common.FromHex(string(bytes.TrimSpace(update.Content))))
+		if len(key) != common.HashLength && !storage.IsEncryptedKey(key) {
+			s.BadRequest(w, r, fmt.Sprintf("feed version %d does not refer to a manifest", update.Version))
+			return
+		}
+		s.serveManifestFile(w, r, key)
+	}
+}
+
+func (s *Server) handlePostFeed(w http.ResponseWriter, r *Request, feed *storage.Feed) {
+	update := new(storage.FeedUpdate)
+	if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+		s.BadRequest(w, r, fmt.Sprintf("invalid feed update: %s", err))
+		return
+	}
+	if update.Feed != *feed {
+		s.BadRequest(w, r, "feed update does not belong to the requested feed")
+		return
+	}
+	key, err := s.api.FeedUpdate(update)
+	switch {
+	case err == api.ErrFeedVersion:
+		ShowError(w, &r.Request, fmt.Sprintf("Error updating %s: %s", r.uri, err), http.StatusConflict)
+		return
+	case err != nil:
+		s.BadRequest(w, r, err.Error())
+		return
+	}
+	s.logDebug("feed %s updated to version %d", feed, update.Version)
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set(FeedVersionHeader, strconv.FormatUint(update.Version, 10))
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, key)
+}
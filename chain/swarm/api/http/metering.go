@@ -0,0 +1,239 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package http
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/log"
+)
+
+const (
+	// ApiKeyHeader is the request header identifying the client of a metered
+	// gateway, clients without an API key are identified by their IP address
+	ApiKeyHeader = "X-Swarm-Api-Key"
+
+	// StatsPath is the path of the usage statistics endpoint of a metered gateway
+	StatsPath = "/bzz-stats"
+
+	meterBurst       = 10 * time.Second // a client can use the limits of this duration at once
+	meterIdleTimeout = time.Hour        // usage of clients idle for this long may be dropped
+	meterMaxClients  = 10000            // number of clients after which idle ones are dropped
+)
+
+// MeterConfig configures the per client rate limits of the HTTP API
+type MeterConfig struct {
+	ByteRate    uint64 // bytes per second served to each client (0 = unlimited)
+	RequestRate uint64 // requests per second of each client (0 = unlimited)
+}
+
+// Usage contains the usage counters of a gateway client
+type Usage struct {
+	Requests uint64    `json:"requests"`
+	Bytes    uint64    `json:"bytes"`
+	Limited  uint64    `json:"limited"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// Stats is the response of the usage statistics endpoint, the usage of all
+// clients is only reported to requests from the local host
+type Stats struct {
+	Config  MeterConfig       `json:"config"`
+	Total   Usage             `json:"total"`
+	Client  string            `json:"client"`
+	Usage   Usage             `json:"usage"`
+	Clients map[string]*Usage `json:"clients,omitempty"`
+}
+
+// clientMeter tracks the usage and the remaining allowance of a client
+type clientMeter struct {
+	Usage
+	bytes    float64 // bytes the client may still fetch, may become negative
+	requests float64 // requests the client may still send
+	updated  time.Time
+}
+
+// Meter is an http.Handler which tracks the bytes served to every client of
+// the wrapped handler and enforces the configured rate limits, rejecting
+// requests of clients exceeding them with 429 Too Many Requests
+type Meter struct {
+	handler http.Handler
+	config  MeterConfig
+
+	total   Usage
+	clients map[string]*clientMeter
+	mu      sync.Mutex
+}
+
+// NewMeter wraps the handler with metering and rate limiting
+func NewMeter(handler http.Handler, config MeterConfig) *Meter {
+	return &Meter{
+		handler: handler,
+		config:  config,
+		clients: make(map[string]*clientMeter),
+	}
+}
+
+// clientID identifies the client of a request by its API key or IP address
+func clientID(r *http.Request) string {
+	if key := r.Header.Get(ApiKeyHeader); key != "" {
+		return "key:" + key
+	}
+	if key := r.URL.Query().Get("api_key"); key != "" {
+		return "key:" + key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func (m *Meter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := clientID(r)
+	if r.URL.Path == StatsPath {
+		m.serveStats(w, r, id)
+		return
+	}
+	if wait := m.admit(id); wait > 0 {
+		log.Debug("Swarm gateway client rate limited", "client", id, "wait", wait)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+	cw := &countingWriter{ResponseWriter: w}
+	m.handler.ServeHTTP(cw, r)
+	m.served(id, cw.written)
+}
+
+// admit checks whether the client is within its limits and consumes one
+// request, returning how long the client has to wait otherwise
+func (m *Meter) admit(id string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	c := m.client(id, now)
+	c.LastSeen = now
+
+	var wait time.Duration
+	if rate := float64(m.config.ByteRate); rate > 0 && c.bytes <= 0 {
+		wait = time.Duration((1 - c.bytes) / rate * float64(time.Second))
+	}
+	if rate := float64(m.config.RequestRate); rate > 0 && c.requests < 1 {
+		if w := time.Duration((1 - c.requests) / rate * float64(time.Second)); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		c.Limited++
+		m.total.Limited++
+		return wait
+	}
+	c.requests--
+	c.Requests++
+	m.total.Requests++
+	return 0
+}
+
+// served records the bytes served to the client
+func (m *Meter) served(id string, n uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.client(id, time.Now())
+	c.bytes -= float64(n)
+	c.Bytes += n
+	m.total.Bytes += n
+}
+
+// client returns the meter of the client with its allowance refilled up to
+// now, the caller must hold the lock
+func (m *Meter) client(id string, now time.Time) *clientMeter {
+	c, ok := m.clients[id]
+	if !ok {
+		if len(m.clients) >= meterMaxClients {
+			m.prune(now)
+		}
+		c = &clientMeter{
+			bytes:    m.burst(m.config.ByteRate),
+			requests: m.burst(m.config.RequestRate),
+			updated:  now,
+		}
+		m.clients[id] = c
+		return c
+	}
+	elapsed := now.Sub(c.updated).Seconds()
+	c.bytes = math.Min(c.bytes+elapsed*float64(m.config.ByteRate), m.burst(m.config.ByteRate))
+	c.requests = math.Min(c.requests+elapsed*float64(m.config.RequestRate), m.burst(m.config.RequestRate))
+	c.updated = now
+	return c
+}
+
+func (m *Meter) burst(rate uint64) float64 {
+	return float64(rate) * meterBurst.Seconds()
+}
+
+// prune drops the clients which have been idle for meterIdleTimeout, the
+// caller must hold the lock
+func (m *Meter) prune(now time.Time) {
+	for id, c := range m.clients {
+		if now.Sub(c.LastSeen) > meterIdleTimeout {
+			delete(m.clients, id)
+		}
+	}
+}
+
+// serveStats responds with the usage statistics as JSON
+func (m *Meter) serveStats(w http.ResponseWriter, r *http.Request, id string) {
+	m.mu.Lock()
+	stats := &Stats{Config: m.config, Total: m.total, Client: id}
+	if c, ok := m.clients[id]; ok {
+		stats.Usage = c.Usage
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+			stats.Clients = make(map[string]*Usage, len(m.clients))
+			for id, c := range m.clients {
+				usage := c.Usage
+				stats.Clients[id] = &usage
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// countingWriter counts the bytes of the response body
+type countingWriter struct {
+	http.ResponseWriter
+	written uint64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.written += uint64(n)
+	return n, err
+}
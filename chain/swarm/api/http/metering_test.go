@@ -0,0 +1,105 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMeter(t *testing.T) {
+	data := bytes.Repeat([]byte{1}, 1500)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+	// allow 200 bytes per second, i.e. a burst of 2000 bytes
+	srv := httptest.NewServer(NewMeter(handler, MeterConfig{ByteRate: 200}))
+	defer srv.Close()
+
+	get := func(path, key string) *http.Response {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		if key != "" {
+			req.Header.Set(ApiKeyHeader, key)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		return res
+	}
+	// the client can exceed its burst with a single request, then it is limited
+	for i := 0; i < 2; i++ {
+		if res := get("/", "a"); res.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %s", i, res.Status)
+		}
+	}
+	res := get("/", "a")
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %s", res.Status)
+	}
+	if res.Header.Get("Retry-After") == "" {
+		t.Fatal("missing Retry-After header")
+	}
+	// other clients are not affected
+	if res := get("/", "b"); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200 for another client, got %s", res.Status)
+	}
+
+	// check the usage counters
+	req, _ := http.NewRequest("GET", srv.URL+StatsPath, nil)
+	req.Header.Set(ApiKeyHeader, "a")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var stats Stats
+	if err := json.NewDecoder(res.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Client != "key:a" || stats.Usage.Requests != 2 || stats.Usage.Bytes != 3000 || stats.Usage.Limited != 1 {
+		t.Errorf("unexpected client usage: %s %+v", stats.Client, stats.Usage)
+	}
+	if stats.Total.Requests != 3 || stats.Total.Bytes != 4500 || stats.Total.Limited != 1 {
+		t.Errorf("unexpected total usage: %+v", stats.Total)
+	}
+	// the test server listens on the loopback interface, so all clients are reported
+	if len(stats.Clients) != 2 || stats.Clients["key:b"] == nil || stats.Clients["key:b"].Bytes != 1500 {
+		t.Errorf("unexpected clients usage: %v", stats.Clients)
+	}
+}
+
+func TestMeterRequestRate(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	meter := NewMeter(handler, MeterConfig{RequestRate: 1})
+
+	// a burst of ten requests is allowed
+	for i := 0; i < 10; i++ {
+		if wait := meter.admit("ip:127.0.0.1"); wait != 0 {
+			t.Fatalf("request %d: unexpectedly limited for %v", i, wait)
+		}
+	}
+	if wait := meter.admit("ip:127.0.0.1"); wait <= 0 {
+		t.Fatal("expected request to be limited")
+	}
+}
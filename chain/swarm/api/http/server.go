@@ -42,11 +42,17 @@ import (
 	"github.com/rs/cors"
 )
 
+// encryptAddr is the address used in POST requests to store the uploaded
+// content encrypted
+const encryptAddr = "encrypt"
+
 // ServerConfig is the basic configuration needed for the HTTP server and also
 // includes CORS settings.
 type ServerConfig struct {
 	Addr       string
 	CorsString string
+	UploadDir  string       // directory of the resumable upload sessions
+	Meter      *MeterConfig // per client rate limits, nil disables metering
 }
 
 // browser API for registering bzz url scheme handlers:
@@ -66,17 +72,29 @@ func StartHttpServer(api *api.Api, config *ServerConfig) {
 		MaxAge:         600,
 		AllowedHeaders: []string{"*"},
 	})
-	hdlr := c.Handler(NewServer(api))
+	server := NewServer(api)
+	if config.UploadDir != "" {
+		server.uploads = newUploadSessions(config.UploadDir)
+	}
+	var hdlr http.Handler = server
+	if config.Meter != nil {
+		hdlr = NewMeter(hdlr, *config.Meter)
+	}
+	hdlr = c.Handler(hdlr)
 
 	go http.ListenAndServe(config.Addr, hdlr)
 }
 
 func NewServer(api *api.Api) *Server {
-	return &Server{api}
+	return &Server{
+		api:     api,
+		uploads: newUploadSessions(defaultUploadDir()),
+	}
 }
 
 type Server struct {
-	api *api.Api
+	api     *api.Api
+	uploads *uploadSessions
 }
 
 // Request wraps http.Request and also includes the parsed bzz URI
@@ -87,19 +105,30 @@ type Request struct {
 }
 
 // HandlePostRaw handles a POST request to a raw bzzr:/ URI, stores the request
-// body in swarm and returns the resulting storage key as a text/plain response
+// body in swarm and returns the resulting storage key as a text/plain response.
+// If the URI is bzzr:/encrypt the body is stored encrypted and the returned
+// key contains the encryption key
 func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 	if r.uri.Path != "" {
 		s.BadRequest(w, r, "raw POST request cannot contain a path")
 		return
 	}
+	encrypt := r.uri.Addr == encryptAddr
+	if r.uri.Addr != "" && !encrypt {
+		s.BadRequest(w, r, "raw POST request can only contain the encrypt address")
+		return
+	}
 
 	if r.Header.Get("Content-Length") == "" {
 		s.BadRequest(w, r, "missing Content-Length header in request")
 		return
 	}
 
-	key, err := s.api.Store(r.Body, r.ContentLength, nil)
+	store := s.api.Store
+	if encrypt {
+		store = s.api.StoreEncrypted
+	}
+	key, err := store(r.Body, r.ContentLength, nil)
 	if err != nil {
 		s.Error(w, r, err)
 		return
@@ -115,7 +144,9 @@ func (s *Server) HandlePostRaw(w http.ResponseWriter, r *Request) {
 // bzz:/<hash>/<path> which contains either a single file or multiple files
 // (either a tar archive or multipart form), adds those files either to an
 // existing manifest or to a new manifest under <path> and returns the
-// resulting manifest hash as a text/plain response
+// resulting manifest hash as a text/plain response.
+// Posting to bzz:/encrypt/<path> creates a new encrypted manifest, the files
+// added to an encrypted manifest are encrypted as well
 func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 	contentType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil {
@@ -124,7 +155,13 @@ func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 	}
 
 	var key storage.Key
-	if r.uri.Addr != "" {
+	if r.uri.Addr == encryptAddr {
+		key, err = s.api.NewEncryptedManifest()
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+	} else if r.uri.Addr != "" {
 		key, err = s.api.Resolve(r.uri)
 		if err != nil {
 			s.Error(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
@@ -139,20 +176,74 @@ func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 	}
 
 	newKey, err := s.updateManifest(key, func(mw *api.ManifestWriter) error {
-		switch contentType {
+		return s.handleUpload(r, contentType, params, mw)
+	})
+	if err != nil {
+		s.Error(w, r, fmt.Errorf("error creating manifest: %s", err))
+		return
+	}
 
-		case "application/x-tar":
-			return s.handleTarUpload(r, mw)
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, newKey)
+}
 
-		case "multipart/form-data":
-			return s.handleMultipartUpload(r, params["boundary"], mw)
+// HandlePutFiles handles a PUT request to bzz:/<manifest>/<path> which
+// modifies an existing manifest and returns the resulting manifest hash as a
+// text/plain response.
+//
+// If the request has the manifest content type, the body is a JSON manifest
+// whose entries reference content already stored in swarm and are added to
+// <manifest> under <path>, replacing entries with the same path (entries
+// without a hash remove the path instead). Otherwise the body is uploaded in
+// the same way as a POST request, replacing the files at the same paths.
+func (s *Server) HandlePutFiles(w http.ResponseWriter, r *Request) {
+	if r.uri.Addr == "" {
+		s.BadRequest(w, r, "PUT request must refer to an existing manifest")
+		return
+	}
+	contentType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		s.BadRequest(w, r, err.Error())
+		return
+	}
 
-		default:
-			return s.handleDirectUpload(r, mw)
+	var update api.Manifest
+	if contentType == api.ManifestType {
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			s.BadRequest(w, r, fmt.Sprintf("invalid manifest: %s", err))
+			return
 		}
+	}
+
+	key, err := s.api.Resolve(r.uri)
+	if err != nil {
+		s.Error(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
+		return
+	}
+
+	newKey, err := s.updateManifest(key, func(mw *api.ManifestWriter) error {
+		if contentType != api.ManifestType {
+			return s.handleUpload(r, contentType, params, mw)
+		}
+		for _, entry := range update.Entries {
+			entry.Path = path.Join(r.uri.Path, entry.Path)
+			if entry.Hash == "" {
+				s.logDebug("removing %s from manifest %s", entry.Path, key.Log())
+				if err := mw.RemoveEntry(entry.Path); err != nil {
+					return err
+				}
+				continue
+			}
+			s.logDebug("setting %s to %s in manifest %s", entry.Path, entry.Hash, key.Log())
+			if err := mw.SetEntry(&entry); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	if err != nil {
-		s.Error(w, r, fmt.Errorf("error creating manifest: %s", err))
+		s.Error(w, r, fmt.Errorf("error updating manifest: %s", err))
 		return
 	}
 
@@ -161,6 +252,22 @@ func (s *Server) HandlePostFiles(w http.ResponseWriter, r *Request) {
 	fmt.Fprint(w, newKey)
 }
 
+// handleUpload adds the files contained in the request body to the manifest,
+// the body being either a tar archive, a multipart form or a single file
+func (s *Server) handleUpload(req *Request, contentType string, params map[string]string, mw *api.ManifestWriter) error {
+	switch contentType {
+
+	case "application/x-tar":
+		return s.handleTarUpload(req, mw)
+
+	case "multipart/form-data":
+		return s.handleMultipartUpload(req, params["boundary"], mw)
+
+	default:
+		return s.handleDirectUpload(req, mw)
+	}
+}
+
 func (s *Server) handleTarUpload(req *Request, mw *api.ManifestWriter) error {
 	tr := tar.NewReader(req.Body)
 	for {
@@ -353,7 +460,7 @@ func (s *Server) HandleGetRaw(w http.ResponseWriter, r *Request) {
 	}
 	w.Header().Set("Content-Type", contentType)
 
-	http.ServeContent(w, &r.Request, "", time.Now(), reader)
+	s.serveContent(w, r, key, reader)
 }
 
 // HandleGetFiles handles a GET request to bzz:/<manifest> with an Accept
@@ -536,8 +643,13 @@ func (s *Server) HandleGetFile(w http.ResponseWriter, r *Request) {
 		s.Error(w, r, fmt.Errorf("error resolving %s: %s", r.uri.Addr, err))
 		return
 	}
+	s.serveManifestFile(w, r, key)
+}
 
-	reader, contentType, status, err := s.api.Get(key, r.uri.Path)
+// serveManifestFile responds with the content of the file at the request
+// path of the manifest with the given key
+func (s *Server) serveManifestFile(w http.ResponseWriter, r *Request, key storage.Key) {
+	reader, contentType, status, contentKey, err := s.api.Get(key, r.uri.Path)
 	if err != nil {
 		switch status {
 		case http.StatusNotFound:
@@ -572,7 +684,18 @@ func (s *Server) HandleGetFile(w http.ResponseWriter, r *Request) {
 
 	w.Header().Set("Content-Type", contentType)
 
-	http.ServeContent(w, &r.Request, "", time.Now(), reader)
+	s.serveContent(w, r, contentKey, reader)
+}
+
+// serveContent responds with the content of the reader, supporting Range
+// requests (with a 206 Partial Content response) so browsers can stream and
+// seek media files. Content stored in swarm never changes, so the storage key
+// is used as a strong ETag which makes conditional and If-Range requests work
+func (s *Server) serveContent(w http.ResponseWriter, r *Request, key storage.Key, reader storage.LazySectionReader) {
+	w.Header().Set("ETag", fmt.Sprintf("%q", key.String()))
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	http.ServeContent(w, &r.Request, "", time.Time{}, reader)
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -587,6 +710,15 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	s.logDebug("%s request received for %s", r.Method, uri)
 
+	if uri.Upload() {
+		s.HandleUpload(w, req)
+		return
+	}
+	if uri.Feed() {
+		s.HandleFeed(w, req)
+		return
+	}
+
 	switch r.Method {
 	case "POST":
 		if uri.Raw() {
@@ -596,17 +728,11 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case "PUT":
-		// DEPRECATED:
-		//   clients should send a POST request (the request creates a
-		//   new manifest leaving the existing one intact, so it isn't
-		//   strictly a traditional PUT request which replaces content
-		//   at a URI, and POST is more ubiquitous)
 		if uri.Raw() {
 			ShowError(w, r, fmt.Sprintf("No PUT to %s allowed.", uri), http.StatusBadRequest)
 			return
-		} else {
-			s.HandlePostFiles(w, req)
 		}
+		s.HandlePutFiles(w, req)
 
 	case "DELETE":
 		if uri.Raw() {
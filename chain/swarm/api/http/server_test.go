@@ -194,3 +194,150 @@ func TestBzzRootRedirect(t *testing.T) {
 		t.Fatalf("expected response to equal %q, got %q", data, gotData)
 	}
 }
+
+// TestBzzGetRange tests that range requests to files and raw content return
+// partial content, and that the ETag allows conditional requests.
+func TestBzzGetRange(t *testing.T) {
+	srv := testutil.NewTestSwarmServer(t)
+	defer srv.Close()
+
+	// upload a file spanning several chunks
+	client := swarm.NewClient(srv.URL)
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	file := &swarm.File{
+		ReadCloser: ioutil.NopCloser(bytes.NewReader(data)),
+		ManifestEntry: api.ManifestEntry{
+			Path:        "video.mp4",
+			ContentType: "video/mp4",
+			Size:        int64(len(data)),
+		},
+	}
+	hash, err := client.Upload(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := client.UploadRaw(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(url string, header map[string]string) *http.Response {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	for _, url := range []string{srv.URL + "/bzz:/" + hash + "/video.mp4", srv.URL + "/bzzr:/" + raw} {
+		// the whole content
+		res := get(url, nil)
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+			t.Fatalf("%s: unexpected full response: status %s, %d bytes", url, res.Status, len(body))
+		}
+		if res.Header.Get("Content-Length") != fmt.Sprint(len(data)) || res.Header.Get("Accept-Ranges") != "bytes" {
+			t.Fatalf("%s: unexpected headers %v", url, res.Header)
+		}
+		etag := res.Header.Get("ETag")
+		if etag == "" {
+			t.Fatalf("%s: missing ETag", url)
+		}
+
+		// ranges within and crossing chunk boundaries and at the end
+		for _, rng := range [][2]int{{2, 5}, {4000, 4200}, {len(data) - 10, len(data) - 1}} {
+			res := get(url, map[string]string{"Range": fmt.Sprintf("bytes=%d-%d", rng[0], rng[1])})
+			body, _ := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if res.StatusCode != http.StatusPartialContent {
+				t.Fatalf("%s: range %v: expected status 206, got %s", url, rng, res.Status)
+			}
+			if expected := fmt.Sprintf("bytes %d-%d/%d", rng[0], rng[1], len(data)); res.Header.Get("Content-Range") != expected {
+				t.Fatalf("%s: range %v: expected Content-Range %q, got %q", url, rng, expected, res.Header.Get("Content-Range"))
+			}
+			if !bytes.Equal(body, data[rng[0]:rng[1]+1]) {
+				t.Fatalf("%s: range %v: unexpected body %q", url, rng, body)
+			}
+		}
+
+		// an open ended range
+		res = get(url, map[string]string{"Range": "bytes=-7"})
+		body, _ = ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusPartialContent || !bytes.Equal(body, data[len(data)-7:]) {
+			t.Fatalf("%s: suffix range: unexpected response: status %s, body %q", url, res.Status, body)
+		}
+
+		// unsatisfiable ranges and conditional requests
+		res = get(url, map[string]string{"Range": fmt.Sprintf("bytes=%d-", len(data))})
+		res.Body.Close()
+		if res.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatalf("%s: expected status 416, got %s", url, res.Status)
+		}
+		res = get(url, map[string]string{"If-None-Match": etag})
+		res.Body.Close()
+		if res.StatusCode != http.StatusNotModified {
+			t.Fatalf("%s: expected status 304, got %s", url, res.Status)
+		}
+	}
+}
+
+// testResolver resolves names from a static map
+type testResolver map[string]common.Hash
+
+func (r testResolver) Resolve(name string) (common.Hash, error) {
+	hash, ok := r[name]
+	if !ok {
+		return common.Hash{}, fmt.Errorf("name %q not registered", name)
+	}
+	return hash, nil
+}
+
+// TestBzzResolveName tests that names in bzz URLs are resolved to content
+// hashes by the resolver of the server
+func TestBzzResolveName(t *testing.T) {
+	resolver := make(testResolver)
+	srv := testutil.NewTestSwarmServerWithResolver(t, resolver)
+	defer srv.Close()
+
+	client := swarm.NewClient(srv.URL)
+	data := []byte("channel page")
+	hash, err := client.Upload(&swarm.File{
+		ReadCloser:    ioutil.NopCloser(bytes.NewReader(data)),
+		ManifestEntry: api.ManifestEntry{Path: "index.html", ContentType: "text/html", Size: int64(len(data))},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver["channel.boker"] = common.HexToHash(hash)
+
+	res, err := http.Get(srv.URL + "/bzz:/channel.boker/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	gotData, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || !bytes.Equal(gotData, data) {
+		t.Fatalf("expected %q, got %s: %q", data, res.Status, gotData)
+	}
+
+	// unregistered names fail to resolve
+	res, err = http.Get(srv.URL + "/bzz:/unknown.boker/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected status %d for an unregistered name, got %s", http.StatusInternalServerError, res.Status)
+	}
+}
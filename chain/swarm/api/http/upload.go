@@ -0,0 +1,341 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/swarm/api"
+	"github.com/Bokerchain/Boker/chain/swarm/storage"
+)
+
+/*
+Resumable uploads let clients send large files in several requests so an
+interrupted upload can be continued instead of restarted:
+
+	POST   /bzzu:/                    begins a session, returns its id
+	PUT    /bzzu:/<id>?offset=<n>     appends the body at offset n, returns the new size
+	GET    /bzzu:/<id>                returns the number of bytes received so far
+	POST   /bzzu:/<id>/<path>         stores the data under <path> of the manifest given
+	                                  by the manifest query parameter (or a new one)
+	                                  and returns the manifest hash
+	DELETE /bzzu:/<id>                aborts the session
+
+The data received so far is kept in a file per session, so sessions survive
+restarts of the node. Sessions which have not been appended to for
+uploadSessionTTL are removed.
+*/
+
+const (
+	// uploadSessionTTL is the time after which idle upload sessions are removed
+	uploadSessionTTL = 24 * time.Hour
+
+	// UploadSizeHeader is the response header containing the number of bytes
+	// received by an upload session
+	UploadSizeHeader = "X-Swarm-Upload-Size"
+)
+
+var (
+	errUploadNotFound = errors.New("upload session not found")
+	errUploadOffset   = errors.New("upload offset does not match the received size")
+
+	uploadIDMatcher = regexp.MustCompile("^[0-9a-f]{32}$")
+)
+
+// uploadSessions stores the data of the resumable upload sessions in a
+// directory
+type uploadSessions struct {
+	dir   string
+	locks map[string]*sync.Mutex
+	mu    sync.Mutex
+}
+
+func newUploadSessions(dir string) *uploadSessions {
+	return &uploadSessions{
+		dir:   dir,
+		locks: make(map[string]*sync.Mutex),
+	}
+}
+
+// defaultUploadDir is the session directory used if none is configured
+func defaultUploadDir() string {
+	return filepath.Join(os.TempDir(), "swarm-uploads")
+}
+
+// lock locks the session with the given id, returning the unlock function
+func (u *uploadSessions) lock(id string) func() {
+	u.mu.Lock()
+	l, ok := u.locks[id]
+	if !ok {
+		l = new(sync.Mutex)
+		u.locks[id] = l
+	}
+	u.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+func (u *uploadSessions) path(id string) (string, error) {
+	if !uploadIDMatcher.MatchString(id) {
+		return "", errUploadNotFound
+	}
+	return filepath.Join(u.dir, id), nil
+}
+
+// begin creates a new, empty session and removes expired ones
+func (u *uploadSessions) begin() (string, error) {
+	if err := os.MkdirAll(u.dir, 0700); err != nil {
+		return "", err
+	}
+	u.expire()
+
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+	f, err := os.OpenFile(filepath.Join(u.dir, id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	return id, f.Close()
+}
+
+// size returns the number of bytes received by the session
+func (u *uploadSessions) size(id string) (int64, error) {
+	path, err := u.path(id)
+	if err != nil {
+		return 0, err
+	}
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return 0, errUploadNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	return stat.Size(), nil
+}
+
+// append appends data to the session, the offset must match the number of
+// bytes received so far. If reading the data fails the part read before the
+// failure is kept, so the client can resume from the returned size
+func (u *uploadSessions) append(id string, offset int64, data io.Reader) (int64, error) {
+	defer u.lock(id)()
+
+	size, err := u.size(id)
+	if err != nil {
+		return 0, err
+	}
+	if offset != size {
+		return size, errUploadOffset
+	}
+	path, _ := u.path(id)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return size, err
+	}
+	n, err := io.Copy(f, data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return size + n, err
+}
+
+// open opens the data of the session for reading, the caller must hold the
+// session lock
+func (u *uploadSessions) open(id string) (*os.File, int64, error) {
+	size, err := u.size(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	path, _ := u.path(id)
+	f, err := os.Open(path)
+	return f, size, err
+}
+
+// remove deletes the session, the caller must hold the session lock
+func (u *uploadSessions) remove(id string) error {
+	path, err := u.path(id)
+	if err != nil {
+		return err
+	}
+	u.mu.Lock()
+	delete(u.locks, id)
+	u.mu.Unlock()
+
+	if err := os.Remove(path); os.IsNotExist(err) {
+		return errUploadNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// expire removes the sessions which have been idle for uploadSessionTTL
+func (u *uploadSessions) expire() {
+	files, err := ioutil.ReadDir(u.dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if uploadIDMatcher.MatchString(file.Name()) && time.Since(file.ModTime()) > uploadSessionTTL {
+			os.Remove(filepath.Join(u.dir, file.Name()))
+		}
+	}
+}
+
+// HandleUpload handles the requests to bzzu:/ URIs which manage resumable
+// upload sessions
+func (s *Server) HandleUpload(w http.ResponseWriter, r *Request) {
+	id := r.uri.Addr
+
+	switch {
+	case r.Method == "POST" && id == "":
+		id, err := s.uploads.begin()
+		if err != nil {
+			s.Error(w, r, fmt.Errorf("error creating upload session: %s", err))
+			return
+		}
+		s.logDebug("upload session %s created", id)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, id)
+
+	case r.Method == "PUT" && r.uri.Path == "":
+		offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+		if err != nil {
+			s.BadRequest(w, r, "invalid or missing offset")
+			return
+		}
+		size, err := s.uploads.append(id, offset, r.Body)
+		s.writeUploadSize(w, r, size, err)
+
+	case r.Method == "GET" && r.uri.Path == "":
+		size, err := s.uploads.size(id)
+		s.writeUploadSize(w, r, size, err)
+
+	case r.Method == "POST":
+		s.handleUploadCommit(w, r)
+
+	case r.Method == "DELETE" && r.uri.Path == "":
+		unlock := s.uploads.lock(id)
+		err := s.uploads.remove(id)
+		unlock()
+		if err != nil {
+			s.uploadError(w, r, err)
+			return
+		}
+		s.logDebug("upload session %s aborted", id)
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		ShowError(w, &r.Request, fmt.Sprintf("Method %s is not supported for %s.", r.Method, r.uri), http.StatusMethodNotAllowed)
+	}
+}
+
+// handleUploadCommit stores the data of the session under the requested path
+// of a manifest and removes the session
+func (s *Server) handleUploadCommit(w http.ResponseWriter, r *Request) {
+	id := r.uri.Addr
+	defer s.uploads.lock(id)()
+
+	f, size, err := s.uploads.open(id)
+	if err != nil {
+		s.uploadError(w, r, err)
+		return
+	}
+	defer f.Close()
+	if size == 0 {
+		s.BadRequest(w, r, "upload session is empty")
+		return
+	}
+
+	var key storage.Key
+	if manifest := r.URL.Query().Get("manifest"); manifest != "" {
+		key, err = s.api.Resolve(&api.URI{Scheme: "bzz", Addr: manifest})
+		if err != nil {
+			s.Error(w, r, fmt.Errorf("error resolving %s: %s", manifest, err))
+			return
+		}
+	} else {
+		key, err = s.api.NewManifest()
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+	}
+	newKey, err := s.updateManifest(key, func(mw *api.ManifestWriter) error {
+		_, err := mw.AddEntry(f, &api.ManifestEntry{
+			Path:        r.uri.Path,
+			ContentType: r.Header.Get("Content-Type"),
+			Mode:        0644,
+			Size:        size,
+			ModTime:     time.Now(),
+		})
+		return err
+	})
+	if err != nil {
+		s.Error(w, r, fmt.Errorf("error creating manifest: %s", err))
+		return
+	}
+	if err := s.uploads.remove(id); err != nil {
+		s.logError("failed to remove upload session %s: %s", id, err)
+	}
+	s.logDebug("upload session %s stored in manifest %s", id, newKey.Log())
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, newKey)
+}
+
+// writeUploadSize responds with the number of bytes received by a session,
+// both in the body and in the UploadSizeHeader header
+func (s *Server) writeUploadSize(w http.ResponseWriter, r *Request, size int64, err error) {
+	if err != nil && err != errUploadOffset {
+		s.uploadError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set(UploadSizeHeader, strconv.FormatInt(size, 10))
+	if err == errUploadOffset {
+		w.WriteHeader(http.StatusConflict)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	fmt.Fprint(w, size)
+}
+
+func (s *Server) uploadError(w http.ResponseWriter, r *Request, err error) {
+	if err == errUploadNotFound {
+		s.NotFound(w, r, err)
+		return
+	}
+	s.Error(w, r, err)
+}
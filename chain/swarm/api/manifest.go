@@ -68,6 +68,17 @@ func (a *Api) NewManifest() (storage.Key, error) {
 	return a.Store(bytes.NewReader(data), int64(len(data)), &sync.WaitGroup{})
 }
 
+// NewEncryptedManifest creates and stores a new, empty encrypted manifest,
+// content added to it using a ManifestWriter is encrypted as well
+func (a *Api) NewEncryptedManifest() (storage.Key, error) {
+	var manifest Manifest
+	data, err := json.Marshal(&manifest)
+	if err != nil {
+		return nil, err
+	}
+	return a.StoreEncrypted(bytes.NewReader(data), int64(len(data)), &sync.WaitGroup{})
+}
+
 // ManifestWriter is used to add and remove entries from an underlying manifest
 type ManifestWriter struct {
 	api   *Api
@@ -83,9 +94,14 @@ func (a *Api) NewManifestWriter(key storage.Key, quitC chan bool) (*ManifestWrit
 	return &ManifestWriter{a, trie, quitC}, nil
 }
 
-// AddEntry stores the given data and adds the resulting key to the manifest
+// AddEntry stores the given data and adds the resulting key to the manifest,
+// the data is encrypted if the manifest is encrypted
 func (m *ManifestWriter) AddEntry(data io.Reader, e *ManifestEntry) (storage.Key, error) {
-	key, err := m.api.Store(data, e.Size, nil)
+	store := m.api.Store
+	if m.trie.encrypt {
+		store = m.api.StoreEncrypted
+	}
+	key, err := store(data, e.Size, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +111,16 @@ func (m *ManifestWriter) AddEntry(data io.Reader, e *ManifestEntry) (storage.Key
 	return key, nil
 }
 
+// SetEntry adds an entry referencing content which is already stored in
+// swarm, replacing any existing entry with the same path
+func (m *ManifestWriter) SetEntry(e *ManifestEntry) error {
+	if key := storage.Key(common.FromHex(e.Hash)); len(key) != common.HashLength && !storage.IsEncryptedKey(key) {
+		return fmt.Errorf("invalid content hash %q for %s", e.Hash, e.Path)
+	}
+	m.trie.addEntry(newManifestTrieEntry(e, nil), m.quitC)
+	return nil
+}
+
 // RemoveEntry removes the given path from the manifest
 func (m *ManifestWriter) RemoveEntry(path string) error {
 	m.trie.deleteEntry(path, m.quitC)
@@ -166,6 +192,7 @@ type manifestTrie struct {
 	dpa     *storage.DPA
 	entries [257]*manifestTrieEntry // indexed by first character of basePath, entries[256] is the empty basePath entry
 	hash    storage.Key             // if hash != nil, it is stored
+	encrypt bool                    // store the manifest and its submanifests encrypted
 }
 
 func newManifestTrieEntry(entry *ManifestEntry, subtrie *manifestTrie) *manifestTrieEntry {
@@ -222,7 +249,8 @@ func readManifest(manifestReader storage.LazySectionReader, hash storage.Key, dp
 	log.Trace(fmt.Sprintf("Manifest %v has %d entries.", hash.Log(), len(man.Entries)))
 
 	trie = &manifestTrie{
-		dpa: dpa,
+		dpa:     dpa,
+		encrypt: storage.IsEncryptedKey(hash),
 	}
 	for _, entry := range man.Entries {
 		trie.addEntry(entry, quitC)
@@ -263,7 +291,8 @@ func (self *manifestTrie) addEntry(entry *manifestTrieEntry, quitC chan bool) {
 	commonPrefix := entry.Path[:cpl]
 
 	subtrie := &manifestTrie{
-		dpa: self.dpa,
+		dpa:     self.dpa,
+		encrypt: self.encrypt,
 	}
 	entry.Path = entry.Path[cpl:]
 	oldentry.Path = oldentry.Path[cpl:]
@@ -352,7 +381,11 @@ func (self *manifestTrie) recalcAndStore() error {
 
 	sr := bytes.NewReader(manifest)
 	wg := &sync.WaitGroup{}
-	key, err2 := self.dpa.Store(sr, int64(len(manifest)), wg, nil)
+	store := self.dpa.Store
+	if self.encrypt {
+		store = self.dpa.StoreEncrypted
+	}
+	key, err2 := store(sr, int64(len(manifest)), wg, nil)
 	wg.Wait()
 	self.hash = key
 	return err2
@@ -30,6 +30,9 @@ type URI struct {
 	// * bzzr - raw swarm content
 	// * bzzi - immutable URI of an entry in a swarm manifest
 	//          (address is not resolved)
+	// * bzzu - resumable upload session
+	// * bzz-feed - the latest (or a given) version of a feed, the address
+	//              being the feed id
 	Scheme string
 
 	// Addr is either a hexadecimal storage key or it an address which
@@ -50,7 +53,7 @@ type URI struct {
 // * <scheme>://<addr>
 // * <scheme>://<addr>/<path>
 //
-// with scheme one of bzz, bzzr or bzzi
+// with scheme one of bzz, bzzr, bzzi, bzzu or bzz-feed
 func Parse(rawuri string) (*URI, error) {
 	u, err := url.Parse(rawuri)
 	if err != nil {
@@ -60,7 +63,7 @@ func Parse(rawuri string) (*URI, error) {
 
 	// check the scheme is valid
 	switch uri.Scheme {
-	case "bzz", "bzzi", "bzzr":
+	case "bzz", "bzzi", "bzzr", "bzzu", "bzz-feed":
 	default:
 		return nil, fmt.Errorf("unknown scheme %q", u.Scheme)
 	}
@@ -91,6 +94,14 @@ func (u *URI) Immutable() bool {
 	return u.Scheme == "bzzi"
 }
 
+func (u *URI) Upload() bool {
+	return u.Scheme == "bzzu"
+}
+
+func (u *URI) Feed() bool {
+	return u.Scheme == "bzz-feed"
+}
+
 func (u *URI) String() string {
 	return u.Scheme + ":/" + u.Addr + "/" + u.Path
 }
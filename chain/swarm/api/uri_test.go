@@ -28,6 +28,8 @@ func TestParseURI(t *testing.T) {
 		expectErr       bool
 		expectRaw       bool
 		expectImmutable bool
+		expectUpload    bool
+		expectFeed      bool
 	}
 	tests := []test{
 		{
@@ -95,6 +97,16 @@ func TestParseURI(t *testing.T) {
 			uri:       "bzz://abc123/path/to/entry",
 			expectURI: &URI{Scheme: "bzz", Addr: "abc123", Path: "path/to/entry"},
 		},
+		{
+			uri:          "bzzu:/abc123/path/to/entry",
+			expectURI:    &URI{Scheme: "bzzu", Addr: "abc123", Path: "path/to/entry"},
+			expectUpload: true,
+		},
+		{
+			uri:        "bzz-feed:/abc123/path/to/entry",
+			expectURI:  &URI{Scheme: "bzz-feed", Addr: "abc123", Path: "path/to/entry"},
+			expectFeed: true,
+		},
 	}
 	for _, x := range tests {
 		actual, err := Parse(x.uri)
@@ -116,5 +128,11 @@ func TestParseURI(t *testing.T) {
 		if actual.Immutable() != x.expectImmutable {
 			t.Fatalf("expected %s immutable to be %t, got %t", x.uri, x.expectImmutable, actual.Immutable())
 		}
+		if actual.Upload() != x.expectUpload {
+			t.Fatalf("expected %s upload to be %t, got %t", x.uri, x.expectUpload, actual.Upload())
+		}
+		if actual.Feed() != x.expectFeed {
+			t.Fatalf("expected %s feed to be %t, got %t", x.uri, x.expectFeed, actual.Feed())
+		}
 	}
 }
@@ -359,6 +359,14 @@ func (self *LazyChunkReader) ReadAt(b []byte, off int64) (read int, err error) {
 	if err != nil {
 		return 0, err
 	}
+	// only read up to the end of the content, so the reader can be used to
+	// serve byte ranges after seeking
+	if off >= size {
+		return 0, io.EOF
+	}
+	if off+int64(len(b)) > size {
+		b = b[:size-off]
+	}
 
 	errC := make(chan error)
 
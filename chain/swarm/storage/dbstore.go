@@ -364,11 +364,8 @@ func (s *DbStore) Cleanup() {
 			s.delete(index.Idx, getIndexKey(key[1:]))
 			errorsFound++
 		} else {
-			hasher := s.hashfunc()
-			hasher.Write(data)
-			hash := hasher.Sum(nil)
-			if !bytes.Equal(hash, key[1:]) {
-				log.Warn(fmt.Sprintf("Found invalid chunk. Hash mismatch. hash=%x, key=%x", hash, key[:]))
+			if !ValidChunk(s.hashfunc, Key(key[1:]), data) {
+				log.Warn(fmt.Sprintf("Found invalid chunk. key=%x", key[:]))
 				s.delete(index.Idx, getIndexKey(key[1:]))
 				errorsFound++
 			}
@@ -476,10 +473,7 @@ func (s *DbStore) Get(key Key) (chunk *Chunk, err error) {
 			return
 		}
 
-		hasher := s.hashfunc()
-		hasher.Write(data)
-		hash := hasher.Sum(nil)
-		if !bytes.Equal(hash, key) {
+		if !ValidChunk(s.hashfunc, key, data) {
 			s.delete(index.Idx, getIndexKey(key))
 			log.Warn("Invalid Chunk in Database. Please repair with command: 'swarm cleandb'")
 		}
@@ -90,7 +90,13 @@ func NewDPA(store ChunkStore, params *ChunkerParams) *DPA {
 // FS-aware API and httpaccess
 // Chunk retrieval blocks on netStore requests with a timeout so reader will
 // report error if retrieval of chunks within requested range time out.
+// Retrieve returns a lazy reader of the content with the given key, keys of
+// encrypted content (see StoreEncrypted) are transparently decrypted
 func (self *DPA) Retrieve(key Key) LazySectionReader {
+	if IsEncryptedKey(key) {
+		rootKey, encKey := splitEncryptedKey(key)
+		return newDecryptingReader(self.Chunker.Join(rootKey, self.retrieveC), encKey)
+	}
 	return self.Chunker.Join(key, self.retrieveC)
 }
 
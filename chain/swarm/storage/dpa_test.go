@@ -141,3 +141,61 @@ func TestDPA_capacity(t *testing.T) {
 		t.Errorf("Comparison error after clearing memStore.")
 	}
 }
+
+func TestDPAEncrypted(t *testing.T) {
+	dbStore := initDbStore(t)
+	memStore := NewMemStore(dbStore, defaultCacheCapacity)
+	localStore := &LocalStore{
+		memStore,
+		dbStore,
+	}
+	chunker := NewTreeChunker(NewChunkerParams())
+	dpa := &DPA{
+		Chunker:    chunker,
+		ChunkStore: localStore,
+	}
+	dpa.Start()
+	defer dpa.Stop()
+	defer os.RemoveAll("/tmp/bzz")
+
+	size := 3*4096 + 17
+	reader, slice := testDataReaderAndSlice(size)
+	wg := &sync.WaitGroup{}
+	key, err := dpa.StoreEncrypted(reader, int64(size), wg, nil)
+	if err != nil {
+		t.Fatalf("Store error: %v", err)
+	}
+	wg.Wait()
+	if !IsEncryptedKey(key) {
+		t.Fatalf("Key length error got %d, expected %d.", len(key), len(ZeroKey)+EncryptionKeyLength)
+	}
+
+	// the stored chunks only contain ciphertext
+	rootKey, _ := splitEncryptedKey(key)
+	cipherSlice := make([]byte, size)
+	if n, _ := dpa.Retrieve(rootKey).ReadAt(cipherSlice, 0); n != size {
+		t.Fatalf("Ciphertext size error got %d, expected %d.", n, size)
+	}
+	if bytes.Equal(slice, cipherSlice) {
+		t.Errorf("Content stored unencrypted.")
+	}
+
+	// the content is decrypted as a whole and in unaligned sections
+	resultSlice, err := ioutil.ReadAll(dpa.Retrieve(key))
+	if err != nil && err != io.EOF {
+		t.Fatalf("Retrieve error: %v", err)
+	}
+	if !bytes.Equal(slice, resultSlice) {
+		t.Errorf("Comparison error.")
+	}
+	resultReader := dpa.Retrieve(key)
+	for _, off := range []int{0, 5, 4095, 4096, 10001} {
+		section := make([]byte, 100)
+		if _, err := resultReader.ReadAt(section, int64(off)); err != nil && err != io.EOF {
+			t.Fatalf("Retrieve error at offset %d: %v", off, err)
+		}
+		if !bytes.Equal(slice[off:off+100], section) {
+			t.Errorf("Comparison error at offset %d.", off)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+/*
+Encrypted content is stored by encrypting the data stream with AES-256 in
+counter mode before it is handed to the chunker, so every chunk stored in
+(and synced through) swarm only contains ciphertext. A fresh random key is
+generated for every upload and appended to the key of the root chunk, the
+resulting reference being twice as long as a plain one:
+
+	<root chunk key (32 bytes)><encryption key (32 bytes)>
+
+Whoever knows the reference can retrieve and decrypt the content, while the
+nodes storing the chunks cannot. As every key is used for a single upload the
+counter starts at zero, which also allows decrypting any section of the
+content independently for random access.
+*/
+
+const (
+	// EncryptionKeyLength is the length of the symmetric key appended to the
+	// root chunk key of encrypted content
+	EncryptionKeyLength = 32
+)
+
+// IsEncryptedKey reports whether key refers to encrypted content, i.e. it
+// contains the encryption key after the root chunk key
+func IsEncryptedKey(key Key) bool {
+	return len(key) == len(ZeroKey)+EncryptionKeyLength
+}
+
+// splitEncryptedKey splits an encrypted reference into the root chunk key and
+// the encryption key
+func splitEncryptedKey(key Key) (Key, []byte) {
+	return key[:len(ZeroKey)], key[len(ZeroKey):]
+}
+
+// StoreEncrypted stores the data encrypted with a random key and returns a
+// reference containing both the root chunk key and the encryption key
+func (self *DPA) StoreEncrypted(data io.Reader, size int64, swg *sync.WaitGroup, wwg *sync.WaitGroup) (Key, error) {
+	encKey := make([]byte, EncryptionKeyLength)
+	if _, err := io.ReadFull(rand.Reader, encKey); err != nil {
+		return nil, fmt.Errorf("error generating encryption key: %v", err)
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	reader := &cipher.StreamReader{S: newCTRStream(block, 0), R: data}
+	key, err := self.Store(reader, size, swg, wwg)
+	if err != nil {
+		return nil, err
+	}
+	return append(key, encKey...), nil
+}
+
+// newCTRStream returns the key stream of the given block cipher starting at
+// the given offset of the content
+func newCTRStream(block cipher.Block, off int64) cipher.Stream {
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[aes.BlockSize-8:], uint64(off/aes.BlockSize))
+	stream := cipher.NewCTR(block, iv)
+
+	// skip the part of the first block preceding the offset
+	if skip := off % aes.BlockSize; skip > 0 {
+		pad := make([]byte, skip)
+		stream.XORKeyStream(pad, pad)
+	}
+	return stream
+}
+
+// decryptingReader is a LazySectionReader which transparently decrypts
+// content retrieved from swarm
+type decryptingReader struct {
+	LazySectionReader
+	block cipher.Block
+	off   int64
+}
+
+// newDecryptingReader wraps a reader of encrypted content, the key length
+// is guaranteed to be valid by IsEncryptedKey
+func newDecryptingReader(reader LazySectionReader, encKey []byte) LazySectionReader {
+	block, _ := aes.NewCipher(encKey)
+	return &decryptingReader{LazySectionReader: reader, block: block}
+}
+
+func (self *decryptingReader) ReadAt(b []byte, off int64) (int, error) {
+	read, err := self.LazySectionReader.ReadAt(b, off)
+	if read > 0 {
+		newCTRStream(self.block, off).XORKeyStream(b[:read], b[:read])
+	}
+	return read, err
+}
+
+// Read keeps a cursor so cannot be called simulateously, see ReadAt
+func (self *decryptingReader) Read(b []byte) (int, error) {
+	read, err := self.ReadAt(b, self.off)
+	self.off += int64(read)
+	return read, err
+}
+
+func (self *decryptingReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	default:
+		return 0, errWhence
+	case 0:
+	case 1:
+		offset += self.off
+	case 2:
+		size, err := self.Size(nil)
+		if err != nil {
+			return 0, fmt.Errorf("can't get size: %v", err)
+		}
+		offset += size
+	}
+	if offset < 0 {
+		return 0, errOffset
+	}
+	self.off = offset
+	return offset, nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/crypto"
+)
+
+/*
+Feeds are mutable resources: a publisher identified by its address publishes
+a sequence of updates under a topic, and every update is stored in a single
+chunk whose key is derived from the feed and the version of the update
+instead of from the chunk data:
+
+	key  = keccak256(<topic (32 bytes)><user (20 bytes)><version (8 bytes)>)
+	data = <topic><user><version><time (8 bytes)><content><signature (65 bytes)>
+
+The signature of the user over all the preceding fields proves the update
+authentic, which lets nodes validate feed chunks even though they are not
+content addressed. Versions start at 1 and are consecutive, so the latest
+update can be found by probing the keys of increasing versions.
+*/
+
+const (
+	// FeedIDLength is the length of a feed identifier, the topic followed by
+	// the address of the user
+	FeedIDLength = common.HashLength + common.AddressLength
+
+	feedHeaderLength    = FeedIDLength + 16 // feed id, version and time
+	feedSignatureLength = 65
+	feedMaxChunkLength  = 4096
+
+	// FeedMaxContentLength is the maximum length of the content of an update
+	FeedMaxContentLength = feedMaxChunkLength - feedHeaderLength - feedSignatureLength
+)
+
+var (
+	errFeedChunk     = errors.New("invalid feed update chunk")
+	errFeedSignature = errors.New("feed update not signed by the feed user")
+)
+
+// Feed identifies a feed by its topic and the address of its publisher
+type Feed struct {
+	Topic common.Hash    `json:"topic"`
+	User  common.Address `json:"user"`
+}
+
+// NewFeedTopic returns the topic for a human readable feed name
+func NewFeedTopic(name string) common.Hash {
+	return crypto.Keccak256Hash([]byte(name))
+}
+
+// ParseFeed parses a feed identifier as returned by Hex
+func ParseFeed(s string) (*Feed, error) {
+	id, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(id) != FeedIDLength {
+		return nil, fmt.Errorf("invalid feed id %q", s)
+	}
+	feed := new(Feed)
+	copy(feed.Topic[:], id[:common.HashLength])
+	copy(feed.User[:], id[common.HashLength:])
+	return feed, nil
+}
+
+// Hex returns the feed identifier, which is the hex encoding of the topic
+// followed by the user address
+func (self *Feed) Hex() string {
+	return hex.EncodeToString(self.Topic[:]) + hex.EncodeToString(self.User[:])
+}
+
+func (self *Feed) String() string {
+	return self.Hex()
+}
+
+// UpdateKey returns the key of the chunk containing the given version of
+// the feed
+func (self *Feed) UpdateKey(version uint64) Key {
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], version)
+	return Key(crypto.Keccak256(self.Topic[:], self.User[:], v[:]))
+}
+
+// FeedUpdate is a single signed version of a feed
+type FeedUpdate struct {
+	Feed
+	Version   uint64 `json:"version"`
+	Time      uint64 `json:"time"`
+	Content   []byte `json:"content"`
+	Signature []byte `json:"signature"`
+}
+
+// payload returns the signed part of the chunk data
+func (self *FeedUpdate) payload() []byte {
+	buf := make([]byte, feedHeaderLength, feedHeaderLength+len(self.Content)+feedSignatureLength)
+	copy(buf, self.Topic[:])
+	copy(buf[common.HashLength:], self.User[:])
+	binary.BigEndian.PutUint64(buf[FeedIDLength:], self.Version)
+	binary.BigEndian.PutUint64(buf[FeedIDLength+8:], self.Time)
+	return append(buf, self.Content...)
+}
+
+// Digest returns the hash signed by the publisher
+func (self *FeedUpdate) Digest() common.Hash {
+	return crypto.Keccak256Hash(self.payload())
+}
+
+// Sign signs the update with the key of the feed user
+func (self *FeedUpdate) Sign(prv *ecdsa.PrivateKey) error {
+	if crypto.PubkeyToAddress(prv.PublicKey) != self.User {
+		return errFeedSignature
+	}
+	sig, err := crypto.Sign(self.Digest().Bytes(), prv)
+	if err != nil {
+		return err
+	}
+	self.Signature = sig
+	return nil
+}
+
+// Verify checks the version, the content length and the signature of the update
+func (self *FeedUpdate) Verify() error {
+	if self.Version == 0 {
+		return errors.New("feed versions start at 1")
+	}
+	if len(self.Content) > FeedMaxContentLength {
+		return fmt.Errorf("feed update content too long (%d > %d bytes)", len(self.Content), FeedMaxContentLength)
+	}
+	if len(self.Signature) != feedSignatureLength {
+		return errFeedSignature
+	}
+	pub, err := crypto.SigToPub(self.Digest().Bytes(), self.Signature)
+	if err != nil || crypto.PubkeyToAddress(*pub) != self.User {
+		return errFeedSignature
+	}
+	return nil
+}
+
+// Chunk returns the chunk storing the verified update
+func (self *FeedUpdate) Chunk() (*Chunk, error) {
+	if err := self.Verify(); err != nil {
+		return nil, err
+	}
+	data := append(self.payload(), self.Signature...)
+	sdata := make([]byte, 8+len(data))
+	binary.LittleEndian.PutUint64(sdata, uint64(len(data)))
+	copy(sdata[8:], data)
+
+	chunk := NewChunk(self.UpdateKey(self.Version), nil)
+	chunk.SData = sdata
+	chunk.Size = int64(len(data))
+	return chunk, nil
+}
+
+// ParseFeedUpdate decodes and verifies the update stored in the chunk data
+// under the given key
+func ParseFeedUpdate(key Key, sdata []byte) (*FeedUpdate, error) {
+	if len(sdata) < 8+feedHeaderLength+feedSignatureLength {
+		return nil, errFeedChunk
+	}
+	data := sdata[8:]
+	if binary.LittleEndian.Uint64(sdata[:8]) != uint64(len(data)) {
+		return nil, errFeedChunk
+	}
+	update := &FeedUpdate{
+		Version:   binary.BigEndian.Uint64(data[FeedIDLength:]),
+		Time:      binary.BigEndian.Uint64(data[FeedIDLength+8:]),
+		Content:   common.CopyBytes(data[feedHeaderLength : len(data)-feedSignatureLength]),
+		Signature: common.CopyBytes(data[len(data)-feedSignatureLength:]),
+	}
+	copy(update.Topic[:], data[:common.HashLength])
+	copy(update.User[:], data[common.HashLength:FeedIDLength])
+	if !bytes.Equal(update.UpdateKey(update.Version), key) {
+		return nil, errFeedChunk
+	}
+	if err := update.Verify(); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// ValidChunk reports whether the chunk data is valid for the key, i.e. the
+// key is either the hash of the data or the data is a feed update signed by
+// the user of the feed the key belongs to
+func ValidChunk(hashfunc SwarmHasher, key Key, sdata []byte) bool {
+	hasher := hashfunc()
+	hasher.Write(sdata)
+	if bytes.Equal(hasher.Sum(nil), key) {
+		return true
+	}
+	_, err := ParseFeedUpdate(key, sdata)
+	return err == nil
+}
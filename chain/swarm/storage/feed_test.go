@@ -0,0 +1,73 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/crypto"
+)
+
+func TestFeedUpdateChunk(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := &FeedUpdate{
+		Feed:    Feed{Topic: NewFeedTopic("test"), User: crypto.PubkeyToAddress(key.PublicKey)},
+		Version: 3,
+		Time:    1514764800,
+		Content: []byte("content"),
+	}
+	if _, err := update.Chunk(); err != errFeedSignature {
+		t.Fatalf("expected unsigned update to fail with %v, got %v", errFeedSignature, err)
+	}
+	if err := update.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	chunk, err := update.Chunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasher := MakeHashFunc("SHA3")
+	if !ValidChunk(hasher, chunk.Key, chunk.SData) {
+		t.Fatal("expected feed update chunk to be valid")
+	}
+	parsed, err := ParseFeedUpdate(chunk.Key, chunk.SData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Feed != update.Feed || parsed.Version != 3 || parsed.Time != update.Time || string(parsed.Content) != "content" {
+		t.Fatalf("parsed update %+v does not match %+v", parsed, update)
+	}
+
+	// the chunk is invalid under the key of another version
+	if ValidChunk(hasher, update.UpdateKey(4), chunk.SData) {
+		t.Fatal("expected feed update chunk to be invalid under another key")
+	}
+	// and so is tampered content
+	chunk.SData[8+feedHeaderLength] ^= 1
+	if ValidChunk(hasher, chunk.Key, chunk.SData) {
+		t.Fatal("expected tampered feed update chunk to be invalid")
+	}
+
+	// the feed id round trips
+	feed, err := ParseFeed(update.Feed.Hex())
+	if err != nil || *feed != update.Feed {
+		t.Fatalf("expected feed %v, got %v (%v)", &update.Feed, feed, err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"net"
+	"path/filepath"
 
 	"github.com/Bokerchain/Boker/chain/accounts/abi/bind"
 	"github.com/Bokerchain/Boker/chain/common"
@@ -137,7 +138,7 @@ func NewSwarm(ctx *node.ServiceContext, backend chequebook.Backend, ensClient *e
 	transactOpts := bind.NewKeyedTransactor(self.privateKey)
 
 	if ensClient == nil {
-		log.Warn("No ENS, please specify non-empty --ens-api to use domain name resolution")
+		log.Warn("No ENS, please specify non-empty --swarm.ens-endpoint to use domain name resolution")
 	} else {
 		self.dns, err = ens.NewENS(transactOpts, config.EnsRoot, ensClient)
 		if err != nil {
@@ -202,10 +203,19 @@ func (self *Swarm) Start(srv *p2p.Server) error {
 	// start swarm http proxy server
 	if self.config.Port != "" {
 		addr := net.JoinHostPort(self.config.ListenAddr, self.config.Port)
-		go httpapi.StartHttpServer(self.api, &httpapi.ServerConfig{
+		serverConfig := &httpapi.ServerConfig{
 			Addr:       addr,
 			CorsString: self.corsString,
-		})
+			UploadDir:  filepath.Join(self.config.Path, "uploads"),
+		}
+		if self.config.ByteRateLimit > 0 || self.config.RequestRateLimit > 0 {
+			serverConfig.Meter = &httpapi.MeterConfig{
+				ByteRate:    self.config.ByteRateLimit,
+				RequestRate: self.config.RequestRateLimit,
+			}
+			log.Info("Swarm http proxy rate limits enabled", "bytes/s", self.config.ByteRateLimit, "requests/s", self.config.RequestRateLimit)
+		}
+		go httpapi.StartHttpServer(self.api, serverConfig)
 		log.Info(fmt.Sprintf("Swarm http proxy started on %v", addr))
 
 		if self.corsString != "" {
@@ -28,6 +28,12 @@ import (
 )
 
 func NewTestSwarmServer(t *testing.T) *TestSwarmServer {
+	return NewTestSwarmServerWithResolver(t, nil)
+}
+
+// NewTestSwarmServerWithResolver creates a test server which resolves names
+// in bzz URLs with the given resolver
+func NewTestSwarmServerWithResolver(t *testing.T, resolver api.Resolver) *TestSwarmServer {
 	dir, err := ioutil.TempDir("", "swarm-storage-test")
 	if err != nil {
 		t.Fatal(err)
@@ -49,7 +55,7 @@ func NewTestSwarmServer(t *testing.T) *TestSwarmServer {
 		ChunkStore: localStore,
 	}
 	dpa.Start()
-	a := api.NewApi(dpa, nil)
+	a := api.NewApi(dpa, resolver)
 	srv := httptest.NewServer(httpapi.NewServer(a))
 	return &TestSwarmServer{
 		Server: srv,
@@ -0,0 +1,215 @@
+package trie
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+	"github.com/Bokerchain/Boker/chain/log"
+	"github.com/Bokerchain/Boker/chain/metrics"
+)
+
+//节点缓存的命中、未命中以及批量写入的度量标准
+var (
+	nodeCleanHitMeter   = metrics.NewMeter("trie/nodecache/clean/hit")
+	nodeDirtyHitMeter   = metrics.NewMeter("trie/nodecache/dirty/hit")
+	nodeMissMeter       = metrics.NewMeter("trie/nodecache/miss")
+	nodeDedupMeter      = metrics.NewMeter("trie/nodecache/dedup")
+	nodeCommitNodeMeter = metrics.NewMeter("trie/nodecache/commit/nodes")
+	nodeCommitSizeMeter = metrics.NewMeter("trie/nodecache/commit/size")
+	nodeCommitTimer     = metrics.NewTimer("trie/nodecache/commit/time")
+)
+
+//clean缓存中的一个节点
+type cleanNode struct {
+	key   string
+	value []byte
+}
+
+//带容量限制的trie节点缓存，多棵树共享同一个缓存。
+//clean缓存按最近使用的顺序保存已经写入磁盘的节点，dirty缓存保存尚未写入磁盘的节点，
+//dirty缓存超过容量限制或者显式提交时，按批量写入磁盘后转入clean缓存
+type NodeCache struct {
+	diskdb ethdb.Database
+
+	lock       sync.Mutex
+	clean      map[string]*list.Element
+	cleanList  *list.List //最近使用的节点在前
+	cleanSize  int
+	cleanLimit int
+	dirty      map[string][]byte
+	dirtySize  int
+	dirtyLimit int
+
+	commitLock sync.Mutex //同一时间只允许一个批量写入
+}
+
+//创建节点缓存，cleanLimit和dirtyLimit是clean缓存和dirty缓存的字节数上限
+func NewNodeCache(diskdb ethdb.Database, cleanLimit, dirtyLimit int) *NodeCache {
+	return &NodeCache{
+		diskdb:     diskdb,
+		clean:      make(map[string]*list.Element),
+		cleanList:  list.New(),
+		cleanLimit: cleanLimit,
+		dirty:      make(map[string][]byte),
+		dirtyLimit: dirtyLimit,
+	}
+}
+
+//返回底层的磁盘数据库
+func (c *NodeCache) DiskDB() ethdb.Database { return c.diskdb }
+
+//调整缓存的容量限制，超出的clean节点立即淘汰，超出的dirty节点在下次写入时提交
+func (c *NodeCache) SetLimits(cleanLimit, dirtyLimit int) {
+	c.lock.Lock()
+	c.cleanLimit, c.dirtyLimit = cleanLimit, dirtyLimit
+	c.evictLocked()
+	c.lock.Unlock()
+}
+
+//依次从dirty缓存、clean缓存以及磁盘读取节点
+func (c *NodeCache) Get(key []byte) ([]byte, error) {
+
+	c.lock.Lock()
+	if value, ok := c.dirty[string(key)]; ok {
+		c.lock.Unlock()
+		nodeDirtyHitMeter.Mark(1)
+		return value, nil
+	}
+	if elem, ok := c.clean[string(key)]; ok {
+		c.cleanList.MoveToFront(elem)
+		c.lock.Unlock()
+		nodeCleanHitMeter.Mark(1)
+		return elem.Value.(*cleanNode).value, nil
+	}
+	c.lock.Unlock()
+
+	nodeMissMeter.Mark(1)
+	value, err := c.diskdb.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	if _, ok := c.dirty[string(key)]; !ok {
+		c.addCleanLocked(string(key), value)
+	}
+	c.lock.Unlock()
+	return value, nil
+}
+
+func (c *NodeCache) Has(key []byte) (bool, error) {
+
+	c.lock.Lock()
+	_, dirty := c.dirty[string(key)]
+	_, clean := c.clean[string(key)]
+	c.lock.Unlock()
+
+	if dirty || clean {
+		return true, nil
+	}
+	return c.diskdb.Has(key)
+}
+
+//将节点写入dirty缓存，已经缓存的节点不会重复写入，dirty缓存超过容量限制时提交到磁盘
+func (c *NodeCache) Put(key, value []byte) error {
+
+	c.lock.Lock()
+	_, dirty := c.dirty[string(key)]
+	_, clean := c.clean[string(key)]
+	if dirty || clean {
+		c.lock.Unlock()
+		nodeDedupMeter.Mark(1)
+		return nil
+	}
+	c.dirty[string(key)] = common.CopyBytes(value)
+	c.dirtySize += len(key) + len(value)
+	flush := c.dirtySize > c.dirtyLimit
+	c.lock.Unlock()
+
+	if flush {
+		return c.Commit()
+	}
+	return nil
+}
+
+//将dirty缓存中的全部节点按批量写入磁盘，写入完成后节点转入clean缓存
+func (c *NodeCache) Commit() error {
+
+	c.commitLock.Lock()
+	defer c.commitLock.Unlock()
+
+	start := time.Now()
+
+	//写入磁盘期间不持有缓存锁，节点在写入完成之前仍然可以从dirty缓存中读取
+	c.lock.Lock()
+	nodes := make(map[string][]byte, len(c.dirty))
+	for key, value := range c.dirty {
+		nodes[key] = value
+	}
+	c.lock.Unlock()
+
+	if len(nodes) == 0 {
+		return nil
+	}
+	var (
+		batch = c.diskdb.NewBatch()
+		size  int
+	)
+	for key, value := range nodes {
+		if err := batch.Put([]byte(key), value); err != nil {
+			return err
+		}
+		size += len(key) + len(value)
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch = c.diskdb.NewBatch()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	for key, value := range nodes {
+		delete(c.dirty, key)
+		c.dirtySize -= len(key) + len(value)
+		c.addCleanLocked(key, value)
+	}
+	c.lock.Unlock()
+
+	nodeCommitNodeMeter.Mark(int64(len(nodes)))
+	nodeCommitSizeMeter.Mark(int64(size))
+	nodeCommitTimer.UpdateSince(start)
+
+	log.Debug("Committed trie nodes", "nodes", len(nodes), "size", size, "elapsed", time.Since(start))
+	return nil
+}
+
+//返回clean缓存和dirty缓存当前占用的字节数
+func (c *NodeCache) Size() (clean, dirty int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cleanSize, c.dirtySize
+}
+
+func (c *NodeCache) addCleanLocked(key string, value []byte) {
+	if _, ok := c.clean[key]; ok {
+		return
+	}
+	c.clean[key] = c.cleanList.PushFront(&cleanNode{key: key, value: value})
+	c.cleanSize += len(key) + len(value)
+	c.evictLocked()
+}
+
+//淘汰最久未使用的clean节点直到不超过容量限制
+func (c *NodeCache) evictLocked() {
+	for c.cleanSize > c.cleanLimit && c.cleanList.Len() > 0 {
+		node := c.cleanList.Remove(c.cleanList.Back()).(*cleanNode)
+		delete(c.clean, node.key)
+		c.cleanSize -= len(node.key) + len(node.value)
+	}
+}
@@ -0,0 +1,96 @@
+package trie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Bokerchain/Boker/chain/common"
+	"github.com/Bokerchain/Boker/chain/ethdb"
+)
+
+//测试节点在提交之前只保存在dirty缓存中，提交后写入磁盘并转入clean缓存
+func TestNodeCacheCommit(t *testing.T) {
+	diskdb, _ := ethdb.NewMemDatabase()
+	cache := NewNodeCache(diskdb, 1024*1024, 1024*1024)
+
+	trie, _ := New(common.Hash{}, cache)
+	for i := byte(0); i < 100; i++ {
+		trie.Update([]byte{i}, bytes.Repeat([]byte{i}, 40))
+	}
+	root, err := trie.CommitTo(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, dirty := cache.Size(); dirty == 0 {
+		t.Fatalf("no dirty nodes after trie commit")
+	}
+	if ok, _ := diskdb.Has(root[:]); ok {
+		t.Fatalf("root written to disk before cache commit")
+	}
+	//未提交的节点可以通过缓存读取
+	reopened, err := New(root, cache)
+	if err != nil {
+		t.Fatalf("failed to open trie from dirty cache: %v", err)
+	}
+	if val := reopened.Get([]byte{7}); !bytes.Equal(val, bytes.Repeat([]byte{7}, 40)) {
+		t.Fatalf("value mismatch: have %x", val)
+	}
+	if err := cache.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if clean, dirty := cache.Size(); dirty != 0 || clean == 0 {
+		t.Fatalf("cache size mismatch after commit: clean %d, dirty %d", clean, dirty)
+	}
+	if _, err := New(root, diskdb); err != nil {
+		t.Fatalf("failed to open committed trie from disk: %v", err)
+	}
+}
+
+//测试dirty缓存超过容量后自动提交，以及clean缓存按最近使用的顺序淘汰
+func TestNodeCacheLimits(t *testing.T) {
+	diskdb, _ := ethdb.NewMemDatabase()
+	cache := NewNodeCache(diskdb, 100, 100)
+
+	for i := byte(0); i < 3; i++ {
+		cache.Put([]byte{i}, make([]byte, 39))
+	}
+	//第三个节点使dirty缓存超过容量
+	if _, dirty := cache.Size(); dirty != 0 {
+		t.Fatalf("dirty cache not flushed: %d bytes", dirty)
+	}
+	for i := byte(0); i < 3; i++ {
+		if ok, _ := diskdb.Has([]byte{i}); !ok {
+			t.Errorf("node %d not written to disk", i)
+		}
+	}
+	//clean缓存只能容纳两个节点
+	if clean, _ := cache.Size(); clean != 80 || len(cache.clean) != 2 {
+		t.Fatalf("clean cache size mismatch: have %d bytes, %d nodes", clean, len(cache.clean))
+	}
+	var evicted, recent byte
+	for i := byte(0); i < 3; i++ {
+		if _, ok := cache.clean[string([]byte{i})]; !ok {
+			evicted = i
+		} else {
+			recent = i
+		}
+	}
+	//读取被淘汰的节点后重新进入clean缓存，淘汰当前最久未使用的节点
+	if _, err := cache.Get([]byte{recent}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get([]byte{evicted}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.clean[string([]byte{recent})]; !ok {
+		t.Errorf("recently used node evicted")
+	}
+	if _, ok := cache.clean[string([]byte{evicted})]; !ok {
+		t.Errorf("reloaded node not cached")
+	}
+	//已经缓存的节点不会重复写入
+	cache.Put([]byte{recent}, make([]byte, 39))
+	if _, dirty := cache.Size(); dirty != 0 {
+		t.Errorf("cached node written again")
+	}
+}
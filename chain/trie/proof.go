@@ -35,7 +35,13 @@ import (
 // contains all nodes of the longest existing prefix of the key
 // (at least the root node), ending with the node that proves the
 // absence of the key.
+//
+// Keys of tries with a prefix are prefixed like in TryGet, so the proof must
+// be verified against the prefixed key.
 func (t *Trie) Prove(key []byte, fromLevel uint, proofDb DatabaseWriter) error {
+	if t.prefix != nil {
+		key = append(t.prefix, key...)
+	}
 	// Collect all nodes on the path to key.
 	key = keybytesToHex(key)
 	nodes := []node{}
@@ -67,6 +67,43 @@ func TestOneElementProof(t *testing.T) {
 	}
 }
 
+func TestPrefixProof(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	trie, _ := NewTrieWithPrefix(common.Hash{}, []byte("prefix-"), db)
+	updateString(trie, "k", "v")
+	updateString(trie, "kk", "vv")
+
+	proofs, _ := ethdb.NewMemDatabase()
+	if err := trie.Prove([]byte("kk"), 0, proofs); err != nil {
+		t.Fatal(err)
+	}
+	val, err, _ := VerifyProof(trie.Hash(), []byte("prefix-kk"), proofs)
+	if err != nil {
+		t.Fatalf("VerifyProof error: %v\nproof hashes: %v", err, proofs.Keys())
+	}
+	if !bytes.Equal(val, []byte("vv")) {
+		t.Fatalf("VerifyProof returned wrong value: got %x, want 'vv'", val)
+	}
+}
+
+func TestSecureProof(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	trie, _ := NewSecure(common.Hash{}, db, 0)
+	trie.Update([]byte("k"), []byte("v"))
+
+	proofs, _ := ethdb.NewMemDatabase()
+	if err := trie.Prove([]byte("k"), 0, proofs); err != nil {
+		t.Fatal(err)
+	}
+	val, err, _ := VerifyProof(trie.Hash(), crypto.Keccak256([]byte("k")), proofs)
+	if err != nil {
+		t.Fatalf("VerifyProof error: %v\nproof hashes: %v", err, proofs.Keys())
+	}
+	if !bytes.Equal(val, []byte("v")) {
+		t.Fatalf("VerifyProof returned wrong value: got %x, want 'v'", val)
+	}
+}
+
 func TestVerifyBadProof(t *testing.T) {
 	trie, vals := randomTrie(800)
 	root := trie.Hash()
@@ -129,6 +129,12 @@ func (t *SecureTrie) TryDelete(key []byte) error {
 	return t.trie.TryDelete(hk)
 }
 
+// Prove constructs a merkle proof for key, which is hashed before being
+// looked up like in all other operations of the trie. See Trie.Prove.
+func (t *SecureTrie) Prove(key []byte, fromLevel uint, proofDb DatabaseWriter) error {
+	return t.trie.Prove(t.hashKey(key), fromLevel, proofDb)
+}
+
 // GetKey returns the sha3 preimage of a hashed key that was
 // previously used to store a value.
 func (t *SecureTrie) GetKey(shaKey []byte) []byte {